@@ -1,12 +1,13 @@
-const CACHE_NAME = 'crapp-v1';
+// Rendered by the server; the version changes with every deploy or rebuild
+const CACHE_NAME = 'crapp-{{ .Version }}';
 
-// Cache files list
-const CACHE_FILES = [
-  '/',
-  '/static/icons/icon-192x192.png',
-  '/static/icons/icon-512x512.png',
-  '/static/icons/badge-96x96.png',
-];
+// Cache files list, including the current build of the app
+const CACHE_FILES = {{ .Precache }};
+
+// How API requests are handled: 'network-only' never caches them, while
+// 'network-first' keeps the listed GET endpoints for offline use
+const API_STRATEGY = {{ .APIStrategy }};
+const API_CACHE_PATHS = {{ .APICachePaths }};
 
 // Install event
 self.addEventListener('install', (event) => {   
@@ -59,8 +60,28 @@ self.addEventListener('fetch', (event) => {
     return;
   }
   
-  // Skip API requests - don't cache them
-  if (event.request.url.includes('/api/')) {
+  // API requests go to the network; listed endpoints fall back to their
+  // last response when offline
+  const url = new URL(event.request.url);
+  if (url.pathname.startsWith('/api/')) {
+    const cacheable = API_STRATEGY === 'network-first' &&
+      event.request.method === 'GET' &&
+      API_CACHE_PATHS.some(path => url.pathname.startsWith(path));
+    if (!cacheable) {
+      return;
+    }
+
+    event.respondWith(
+      fetch(event.request)
+        .then(response => {
+          if (response.status === 200) {
+            const responseToCache = response.clone();
+            caches.open(CACHE_NAME).then(cache => cache.put(event.request, responseToCache));
+          }
+          return response;
+        })
+        .catch(() => caches.match(event.request))
+    );
     return;
   }
 
@@ -122,7 +143,12 @@ self.addEventListener('push', (event) => {
       body: data.body,
       icon: data.icon,
       badge: data.badge,
-      data: { url: data.url || '/' }
+      actions: data.actions || [],
+      data: {
+        url: data.data?.url || '/',
+        snoozeUrl: data.data?.snooze_url,
+        trackUrl: data.data?.track_url
+      }
     })
     .catch(error => {
       console.error('[ServiceWorker] Show notification error:', error);
@@ -134,20 +160,48 @@ self.addEventListener('push', (event) => {
 // Notification click event
 self.addEventListener('notificationclick', (event) => {   
   event.notification.close();
+
+  const { url = '/', snoozeUrl, trackUrl } = event.notification.data || {};
+
+  // Count the click, whichever action was taken
+  if (trackUrl) {
+    event.waitUntil(
+      fetch(trackUrl, { method: 'POST', keepalive: true })
+        .catch(error => {
+          console.error('[ServiceWorker] Click tracking error:', error);
+        })
+    );
+  }
+
+  // Snooze asks the server to send the reminder again later
+  if (event.action === 'snooze' && snoozeUrl) {
+    event.waitUntil(
+      fetch(snoozeUrl, {
+        method: 'POST',
+        credentials: 'include',
+        headers: { 'Content-Type': 'application/json' },
+        body: '{}'
+      })
+        .catch(error => {
+          console.error('[ServiceWorker] Snooze request error:', error);
+        })
+    );
+    return;
+  }
   
   // IMPORTANT: Use waitUntil here too
   event.waitUntil(
     clients.matchAll({ type: 'window' })
       .then(windowClients => {
-        // Find existing window to focus
+        // Reuse an open window, moving it to the deep-linked form
         for (const client of windowClients) {
-          if (client.url === '/' && 'focus' in client) {
-            return client.focus();
+          if ('focus' in client && 'navigate' in client) {
+            return client.navigate(url).then(c => (c || client).focus());
           }
         }
         // If no window found, open a new one
         if (clients.openWindow) {
-          return clients.openWindow('/');
+          return clients.openWindow(url);
         }
       })
       .catch(error => {
@@ -10,6 +10,8 @@ import { NotificationProvider } from './context/NotificationContext';
 import Header from './components/layout/Header';
 import Footer from './components/layout/Footer';
 import Message from './components/layout/Message';
+import DemoBanner from './components/layout/DemoBanner';
+import ReminderBanner from './components/layout/ReminderBanner';
 import ProtectedRouteLayout from './components/layout/ProtectedRouteLayout';
 import AdminRouteLayout from './components/layout/AdminRouteLayout'; 
 
@@ -20,6 +22,7 @@ import Register from './components/auth/Register';
 import ForgotPassword from './components/auth/ForgotPassword';
 import ResetPassword from './components/auth/ResetPassword';
 import Profile from './components/pages/Profile';
+import TodayAnswers from './components/pages/TodayAnswers';
 import AdminUsers from './components/admin/AdminUsers';
 import UserCharts from './components/charts/UserCharts';
 import NotFound from './components/pages/NotFound';
@@ -36,6 +39,8 @@ const App = () => {
             <div className="app">
               <div className="container">
                 <Header />
+                <DemoBanner />
+                <ReminderBanner />
                 <Message />
                 <Routes>
                   {/* Public routes */}
@@ -49,6 +54,7 @@ const App = () => {
                     <Route path="/" element={<Form />} />
                     <Route path="/my-data" element={<UserCharts />} />
                     <Route path="/profile" element={<Profile />} />
+                    <Route path="/today" element={<TodayAnswers />} />
                   </Route>
 
                   {/* Admin routes */}
@@ -2,6 +2,7 @@
 import React, { useState, useEffect } from 'react';
 import { Link } from 'react-router-dom';
 import api from '../../services/api';
+import NotificationEngagement from './NotificationEngagement';
 import { formatDate } from '../../utils/utils';
 
 const AdminUsers = () => {
@@ -84,16 +85,39 @@ const AdminUsers = () => {
     }
   };
 
+  // Wait for a reminder job to finish, checking once a second
+  const waitForReminder = async (jobId) => {
+    for (let attempt = 0; attempt < 15; attempt++) {
+      await new Promise(resolve => setTimeout(resolve, 1000));
+      const { job, deliveries } = await api.get(`/admin/api/send-reminder/${jobId}`);
+      if (job.status === 'done') {
+        return deliveries[0];
+      }
+    }
+    return null;
+  };
+
   // Send reminder
   const sendReminder = async (userEmail, method) => {
+    const label = method.charAt(0).toUpperCase() + method.slice(1);
     try {
       setSuccessMessage('');
-      await api.post('/admin/api/send-reminder', {
+      const job = await api.post('/admin/api/send-reminder', {
         email: userEmail,
-        method: method // 'email' or 'push'
+        channels: [method] // 'email' or 'push'
       });
-      
-      setSuccessMessage(`${method.charAt(0).toUpperCase() + method.slice(1)} reminder sent to ${userEmail}`);
+
+      const delivery = await waitForReminder(job.id);
+      if (delivery && delivery.status === 'failed') {
+        throw new Error(delivery.error || 'delivery failed');
+      }
+      if (delivery && delivery.status === 'skipped') {
+        setSuccessMessage(`${label} reminder skipped for ${userEmail}; they may have turned off ${method} notifications`);
+      } else if (delivery) {
+        setSuccessMessage(`${label} reminder sent to ${userEmail}`);
+      } else {
+        setSuccessMessage(`${label} reminder queued for ${userEmail}`);
+      }
       
       // Clear success message after 5 seconds
       setTimeout(() => {
@@ -128,6 +152,8 @@ const AdminUsers = () => {
         </div>
       )}
       
+      <NotificationEngagement />
+
       <div className="search-container">
         <form onSubmit={handleSearch}>
           <input 
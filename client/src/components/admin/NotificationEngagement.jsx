@@ -0,0 +1,52 @@
+// src/components/admin/NotificationEngagement.jsx
+import React, { useState, useEffect } from 'react';
+import api from '../../services/api';
+
+const channelLabels = { email: 'Email', push: 'Push' };
+
+const percent = (rate) => (rate === undefined ? '-' : `${Math.round(rate * 100)}%`);
+
+// Compares how often reminders on each channel are opened and clicked
+const NotificationEngagement = ({ days = 30 }) => {
+  const [channels, setChannels] = useState([]);
+
+  useEffect(() => {
+    api.get(`/admin/api/notifications/engagement?days=${days}`)
+      .then(data => setChannels(data.channels || []))
+      .catch(err => console.error('Error loading reminder engagement:', err));
+  }, [days]);
+
+  if (channels.length === 0) return null;
+
+  return (
+    <div className="users-table-container">
+      <h3>Reminder engagement, last {days} days</h3>
+      <table className="users-table">
+        <thead>
+          <tr>
+            <th>Channel</th>
+            <th>Sent</th>
+            <th>Opened</th>
+            <th>Clicked</th>
+            <th>Open rate</th>
+            <th>Click rate</th>
+          </tr>
+        </thead>
+        <tbody>
+          {channels.map(channel => (
+            <tr key={channel.channel}>
+              <td>{channelLabels[channel.channel] || channel.channel}</td>
+              <td>{channel.sent}</td>
+              <td>{channel.opened}</td>
+              <td>{channel.clicked}</td>
+              <td>{percent(channel.open_rate)}</td>
+              <td>{percent(channel.click_rate)}</td>
+            </tr>
+          ))}
+        </tbody>
+      </table>
+    </div>
+  );
+};
+
+export default NotificationEngagement;
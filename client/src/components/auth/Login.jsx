@@ -1,6 +1,7 @@
 import React, { useState, useEffect } from 'react';
 import { useNavigate, useLocation, Link } from 'react-router-dom';
 import { useAuth } from '../../context/AuthContext';
+import { fieldErrors } from '../../services/api';
 
 const Login = () => {
   const [formData, setFormData] = useState({
@@ -89,6 +90,8 @@ const Login = () => {
         },
         device_name: navigator.platform || 'Unknown Device',
         device_type: /Mobi|Android/i.test(navigator.userAgent) ? 'mobile' : 'desktop',
+        // Assessment days and reminders follow the participant's own time zone
+        timezone: Intl.DateTimeFormat().resolvedOptions().timeZone,
         remember_me: formData.rememberMe
       };
       
@@ -99,13 +102,9 @@ const Login = () => {
     } catch (error) {
       console.error('Login API Error Details:', error); 
       // Handle login error
-      if (error.data?.details) {
+      if (error.data?.errors?.length) {
         // Field-specific errors
-        const fieldErrors = {};
-        error.data.details.forEach(detail => {
-          fieldErrors[detail.field] = detail.message;
-        });
-        setErrors(fieldErrors);
+        setErrors(fieldErrors(error));
       } else {
         // General error
         setGeneralError(error.message || 'Login failed. Please check your credentials and try again.');
@@ -128,7 +128,7 @@ export default function Register() {
         navigate('/login');
         
       } catch (error) {
-        setError(error.message || 'Registration failed. Please try again.');
+        setError(error.data?.errors?.[0]?.message || error.message || 'Registration failed. Please try again.');
       } finally {
         setIsLoading(false);
       }
@@ -2,10 +2,12 @@
 const ChartControls = ({
     selectedSymptom,
     selectedMetric,
+    selectedBucket,
     availableMetrics,
     questionGroups,
     onSymptomChange,
-    onMetricChange
+    onMetricChange,
+    onBucketChange
   }) => {
     return (
       <div className="controls">
@@ -86,6 +88,20 @@ const ChartControls = ({
             ))}
           </select>
         </div>
+
+        <div className="control-group">
+          <label htmlFor="bucket-select">Resolution:</label>
+          <select
+            id="bucket-select"
+            value={selectedBucket}
+            onChange={onBucketChange}
+          >
+            <option value="">Every assessment</option>
+            <option value="day">Daily average</option>
+            <option value="week">Weekly average</option>
+            <option value="month">Monthly average</option>
+          </select>
+        </div>
       </div>
     );
   };
@@ -23,7 +23,15 @@ ChartJS.register(
 const CorrelationChart = ({ data }) => {
   if (!data) return null;
 
+  const missing = data.missing;
+
   return (
+    <>
+    {missing && missing.dropped_points > 0 && (
+      <p className="field-note">
+        {missing.dropped_points} of {missing.total_days} assessments are left out because a value is missing.
+      </p>
+    )}
     <div className="chart-container">
       <Scatter 
         data={data.data}
@@ -53,6 +61,7 @@ const CorrelationChart = ({ data }) => {
         }}
       />
     </div>
+    </>
   );
 };
 
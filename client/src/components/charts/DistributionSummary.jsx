@@ -0,0 +1,32 @@
+// src/components/charts/DistributionSummary.jsx
+
+// Describes a distribution by its most common answer. Answers withheld for
+// privacy are skipped.
+const describe = (dist) => {
+  if (!dist || dist.total === 0) return null;
+  const buckets = dist.buckets.filter((bucket) => !bucket.suppressed);
+  if (buckets.length === 0) return null;
+  const top = buckets.reduce((a, b) => (b.count > a.count ? b : a));
+  const label = top.label || top.value;
+  return `${label} on ${Math.round(top.percent)}% of days`;
+};
+
+const DistributionSummary = ({ distribution, isAdminView }) => {
+  if (!distribution) return null;
+
+  const userSummary = describe(distribution.user);
+  if (!userSummary) return null;
+  const cohortSummary = describe(distribution.cohort);
+
+  return (
+    <div className="context-display">
+      <p>
+        {isAdminView ? 'This user reports' : 'You report'} {userSummary}
+        {' '}({distribution.user.total} assessment{distribution.user.total === 1 ? '' : 's'}).
+      </p>
+      {cohortSummary && <p>Across all users: {cohortSummary}.</p>}
+    </div>
+  );
+};
+
+export default DistributionSummary;
@@ -25,10 +25,43 @@ ChartJS.register(
 const TimelineChart = ({ data }) => {
   if (!data) return null;
 
+  // Averaged points show the range and number of assessments they cover
+  const ranges = data.data?.ranges;
+  const rangeLabel = (context) => {
+    if (!ranges) return '';
+    const i = context.dataIndex;
+    const isMetric = context.dataset.label === data.metric;
+    const low = isMetric ? ranges.metric_min?.[i] : ranges.symptom_min?.[i];
+    const high = isMetric ? ranges.metric_max?.[i] : ranges.symptom_max?.[i];
+    if (low === undefined || high === undefined) return '';
+    return `Range ${low.toFixed(2)}–${high.toFixed(2)} over ${ranges.count[i]} assessment(s)`;
+  };
+
+  // Symptom points inside a threshold flag are highlighted
+  const thresholdFlags = data.data?.threshold_flags;
+  const isFlagged = (context) =>
+    context.dataset.yAxisID === 'y' && thresholdFlags?.[context.dataIndex]?.length > 0;
+  const chartData = !thresholdFlags ? data.data : {
+    ...data.data,
+    datasets: data.data.datasets.map((dataset) => dataset.yAxisID !== 'y' ? dataset : {
+      ...dataset,
+      pointRadius: thresholdFlags.map((names) => (names.length ? 6 : 3)),
+      pointBackgroundColor: thresholdFlags.map((names) => (names.length ? 'rgba(197, 48, 48, 1)' : dataset.backgroundColor)),
+      pointBorderColor: thresholdFlags.map((names) => (names.length ? 'rgba(197, 48, 48, 1)' : dataset.borderColor))
+    })
+  };
+  const afterLabel = (context) => {
+    const lines = [rangeLabel(context)].filter(Boolean);
+    if (isFlagged(context)) {
+      lines.push(...thresholdFlags[context.dataIndex].map((name) => `Threshold crossed: ${name}`));
+    }
+    return lines;
+  };
+
   return (
     <div className="chart-container">
       <Line 
-        data={data.data}
+        data={chartData}
         options={{
           responsive: true,
           maintainAspectRatio: false,
@@ -36,6 +69,11 @@ const TimelineChart = ({ data }) => {
             title: {
               display: true,
               text: data.title
+            },
+            tooltip: {
+              callbacks: {
+                afterLabel
+              }
             }
           },
           scales: {
@@ -9,6 +9,7 @@ import ChartControls from './ChartControls';
 import CorrelationChart from './CorrelationChart';
 import TimelineChart from './TimelineChart';
 import MetricsExplanation from './MetricsExplanation'; 
+import DistributionSummary from './DistributionSummary';
 import LoadingSpinner from '../common/LoadingSpinner'; 
 import NoDataMessage from '../common/NoDataMessage'; 
 
@@ -23,14 +24,17 @@ const UserCharts = () => {
         errorMessage,
         selectedSymptom,
         selectedMetric,
+        selectedBucket,
         availableMetrics,
         questionGroups,
         correlationData,
         timelineData,
+        distribution,
         metricsTypeForExplanation,
         shouldShowCorrelationChart,
         handleSymptomChange,
         handleMetricChange,
+        handleBucketChange,
         allQuestions // Get allQuestions if needed for context display
     } = useChartData();
     
@@ -60,10 +64,12 @@ const UserCharts = () => {
              <ChartControls
                 selectedSymptom={selectedSymptom} 
                 selectedMetric={selectedMetric} 
+                selectedBucket={selectedBucket}
                 availableMetrics={availableMetrics} 
                 questionGroups={questionGroups} 
                 onSymptomChange={handleSymptomChange} 
                 onMetricChange={handleMetricChange} 
+                onBucketChange={handleBucketChange}
             />
 
             {/* Context Display Logic (remains similar, uses state from hook) */}
@@ -87,6 +93,8 @@ const UserCharts = () => {
                 })()} 
             </div> 
 
+            <DistributionSummary distribution={distribution} isAdminView={isAdminView} />
+
             {/* Conditional Rendering based on hook state */}
              {isLoading ? ( 
                 <LoadingSpinner message={`Loading ${isAdminView ? 'chart' : 'your chart'} data...`} /> 
@@ -42,6 +42,13 @@ export default function CPTest({ onTestEnd, onTestStart, settings, questionId  }
     settings: testSettings
   });
   
+  // Long tasks and slow interactions while running are attributed to this test
+  useEffect(() => {
+    if (!isRunning || !window.perfReporter) return;
+    window.perfReporter.setContext('cpt');
+    return () => window.perfReporter.setContext('');
+  }, [isRunning]);
+
   useEffect(() => {
     setIsMobile(isMobileDevice());
   }, []);
@@ -101,6 +108,7 @@ export default function CPTest({ onTestEnd, onTestStart, settings, questionId  }
     
     // Initialize test data
     testDataRef.current = {
+      timeOrigin: performance.timeOrigin, // Converts performance.now() readings to wall-clock time
       testStartTime: startTime,
       testEndTime: 0,
       stimuliPresented: [],
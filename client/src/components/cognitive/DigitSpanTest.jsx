@@ -30,6 +30,7 @@ export default function DigitSpanTest({ onTestEnd, onTestStart, settings, questi
   const countdownRef = useRef(null); // For recall countdown timer
   const inputRef = useRef(null); // Ref for the input field
   const testDataRef = useRef({ // For collecting raw data
+    timeOrigin: performance.timeOrigin, // Converts performance.now() readings to wall-clock time
     testStartTime: 0,
     testEndTime: 0,
     results: [], // Will store { span, trial, sequence, input, correct }
@@ -65,6 +66,14 @@ const startTrial = useCallback((spanLength) => {
 
   // --- Effects ---
 
+  // Long tasks and slow interactions while running are attributed to this test
+  const isRunning = phase === 'presenting' || phase === 'recalling';
+  useEffect(() => {
+    if (!isRunning || !window.perfReporter) return;
+    window.perfReporter.setContext('digit_span');
+    return () => window.perfReporter.setContext('');
+  }, [isRunning]);
+
   // Effect to handle the presentation phase (showing digits one by one)
   useEffect(() => {
     if (phase === 'presenting') {
@@ -49,6 +49,13 @@ const TMTest = ({ onTestEnd, onTestStart, settings, questionId }) => {
   });
 
   // Set canvas size once on initial render
+  // Long tasks and slow interactions while running are attributed to this test
+  useEffect(() => {
+    if (!isRunning || !window.perfReporter) return;
+    window.perfReporter.setContext('tmt');
+    return () => window.perfReporter.setContext('');
+  }, [isRunning]);
+
   useEffect(() => {
     if (!canvasSizeFixed && canvasContainerRef.current) {
       // Get container dimensions
@@ -425,6 +432,7 @@ const TMTest = ({ onTestEnd, onTestStart, settings, questionId }) => {
     
     // Initialize test data
     testDataRef.current = {
+      timeOrigin: performance.timeOrigin, // Converts performance.now() readings to wall-clock time
       testStartTime: startTime,
       testEndTime: 0,
       partAStartTime: 0,
@@ -0,0 +1,26 @@
+// src/components/layout/DemoBanner.jsx
+import React, { useState, useEffect } from 'react';
+
+// Warns visitors to a demo deployment that their data isn't kept
+export default function DemoBanner() {
+  const [banner, setBanner] = useState(null);
+
+  useEffect(() => {
+    fetch('/api/features')
+      .then(response => (response.ok ? response.json() : null))
+      .then(features => {
+        if (features?.demo) {
+          setBanner(features.demo_banner);
+        }
+      })
+      .catch(() => {});
+  }, []);
+
+  if (!banner) return null;
+
+  return (
+    <div className="message demo show" role="status">
+      {banner}
+    </div>
+  );
+}
@@ -54,6 +54,7 @@ const Header = () => {
               </div>
               <div className={`user-dropdown ${dropdownOpen ? 'show' : ''}`}>
                 <Link to="/my-data">My Data</Link>
+                <Link to="/today">Today's Answers</Link>
                 <Link to="/profile">Profile</Link>
                 {user.is_admin && (
                   <Link to="/admin/users">Admin Dashboard</Link>
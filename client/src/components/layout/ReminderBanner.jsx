@@ -0,0 +1,31 @@
+// src/components/layout/ReminderBanner.jsx
+import React, { useState, useEffect } from 'react';
+import { Link, useLocation } from 'react-router-dom';
+import { useAuth } from '../../context/AuthContext';
+import api from '../../services/api';
+
+// Reminds a signed-in user that today's assessment is still to do, in their
+// study's wording. It isn't shown on the assessment itself.
+export default function ReminderBanner() {
+  const { isAuthenticated } = useAuth();
+  const location = useLocation();
+  const [reminder, setReminder] = useState(null);
+
+  useEffect(() => {
+    if (!isAuthenticated) {
+      setReminder(null);
+      return;
+    }
+    api.get('/api/reminders/message')
+      .then(message => setReminder(message?.due ? message : null))
+      .catch(() => setReminder(null));
+  }, [isAuthenticated, location.pathname]);
+
+  if (!reminder || location.pathname === '/') return null;
+
+  return (
+    <div className="message reminder show" role="status">
+      <strong>{reminder.title}</strong> {reminder.body} <Link to="/">Start now</Link>
+    </div>
+  );
+}
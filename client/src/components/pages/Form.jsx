@@ -17,6 +17,7 @@ export default function Form() {
     currentStep,
     totalSteps,
     currentQuestion,
+    prefillAnswer,
     isComplete,
     isLoading, // Use loading state from hook
     isSubmitting,
@@ -38,12 +39,18 @@ export default function Form() {
   // Or potentially fetch previous answer from the hook if needed.
   useEffect(() => {
       if (currentQuestion) {
+        // Start prefill questions from the last assessment's answer
+        if (prefillAnswer !== null) {
+            setAnswers(prev => prev[currentQuestion.id] === undefined
+                ? { ...prev, [currentQuestion.id]: prefillAnswer }
+                : prev);
+        }
         // Reset cognitive test state when moving to a non-test question
         if (!['cpt', 'tmt', 'digit_span'].includes(currentQuestion.type)) {
             setIsDoingCognitiveTest(false);
         }
       }
-  }, [currentQuestion]);
+  }, [currentQuestion, prefillAnswer]);
 
   // Handle answer changes locally
   const handleAnswerChange = (questionId, answer) => {
@@ -68,6 +75,10 @@ export default function Form() {
       };
   };
 
+  // Dropdowns report string values, so compare loosely
+  const keptPrefill = currentQuestion && prefillAnswer !== null &&
+      String(answers[currentQuestion.id]) === String(prefillAnswer);
+
   // --- Render Logic ---
 
   if (isLoading && !stateId) { // Show initial loading spinner
@@ -102,6 +113,15 @@ export default function Form() {
             </div>
        )}
 
+      {/* Show whether a carried-over answer has been changed */}
+      {prefillAnswer !== null && (
+          <div className={`prefill-note ${keptPrefill ? '' : 'changed'}`}>
+              {keptPrefill
+                  ? 'Pre-filled with your answer from last time. Change it if today is different.'
+                  : 'Changed from your answer last time.'}
+          </div>
+      )}
+
        {/* Use QuestionRenderer */}
       <QuestionRenderer
           question={currentQuestion}
@@ -209,6 +209,8 @@ export default function Profile() {
         setIsSaving(true); // Use isSaving to disable modal buttons too
 
         try {
+            // Deleting needs a recent password confirmation
+            await api.post('/api/auth/reauth', { password: deletePassword });
             await api.put('/api/user/delete', { password: deletePassword }); 
             // Logout and redirect logic (can be moved to AuthContext logout)
             alert('Account deleted successfully. Redirecting to login.'); 
@@ -6,6 +6,14 @@ import CPTest from '../cognitive/CPTest';
 import TMTest from '../cognitive/TMTest'; 
 import DigitSpanTest from '../cognitive/DigitSpanTest';
 
+// Counts user-perceived characters, so an emoji or accented letter counts once
+const countCharacters = (text) => {
+    if (typeof Intl !== 'undefined' && Intl.Segmenter) {
+        return [...new Intl.Segmenter().segment(text.normalize('NFC'))].length;
+    }
+    return [...text.normalize('NFC')].length;
+};
+
 // Helper function to parse settings (extracted from original Form.jsx logic)
 const parseTestSettings = (question, defaultSettings) => {
     let settings = { ...defaultSettings };
@@ -91,16 +99,37 @@ const QuestionRenderer = ({
         </select>
     );
 
-    const renderTextQuestion = () => ( 
-        <textarea
-            name={question.id}
-            value={answer || ''} 
-            onChange={(e) => onChange(question.id, e.target.value)} 
-            placeholder={question.placeholder || ''} 
-            maxLength={question.max_length} 
-            required={question.required} 
-        />
-    );
+    // The native maxLength counts UTF-16 units, so emoji count double. Limits
+    // are checked against user-perceived characters instead, as the server does.
+    const renderTextQuestion = () => {
+        const text = answer || '';
+        const length = countCharacters(text);
+        const words = text.trim() ? text.trim().split(/\s+/).length : 0;
+
+        return (
+            <>
+                <textarea
+                    name={question.id}
+                    value={text} 
+                    onChange={(e) => {
+                        const value = e.target.value;
+                        if (question.max_length && countCharacters(value) > question.max_length && value.length > text.length) {
+                            return;
+                        }
+                        onChange(question.id, value);
+                    }} 
+                    placeholder={question.placeholder || ''} 
+                    required={question.required} 
+                />
+                {(question.max_length || question.min_length || question.max_words || question.min_words) && (
+                    <div className="text-answer-count">
+                        {length}{question.max_length ? ` / ${question.max_length}` : ''} characters
+                        {(question.max_words || question.min_words) && `, ${words}${question.max_words ? ` / ${question.max_words}` : ''} words`}
+                    </div>
+                )}
+            </>
+        );
+    };
 
      const renderCPTest = () => { 
         const defaultSettings = { /* Default CPT settings */ }; // Define or import defaults
@@ -0,0 +1,140 @@
+// src/components/pages/TodayAnswers.jsx
+import React, { useState, useEffect, useCallback } from 'react';
+import { Helmet } from 'react-helmet-async';
+import api, { fieldErrors } from '../../services/api';
+import QuestionRenderer from './QuestionRenderer';
+import LoadingSpinner from '../common/LoadingSpinner';
+import NoDataMessage from '../common/NoDataMessage';
+
+const EDITABLE_TYPES = ['radio', 'dropdown', 'text'];
+
+// The value the form would have sent for a stored response
+const responseAnswer = (response) => {
+    switch (response.value_type) {
+        case 'number':
+            return response.numeric_value;
+        case 'boolean':
+            return response.numeric_value !== 0;
+        default:
+            return response.text_value;
+    }
+};
+
+// Lets the user amend answers they submitted during the current assessment day
+const TodayAnswers = () => {
+    const [assessments, setAssessments] = useState([]);
+    const [questions, setQuestions] = useState({});
+    const [drafts, setDrafts] = useState({});
+    const [errors, setErrors] = useState({});
+    const [editableUntil, setEditableUntil] = useState(null);
+    const [isLoading, setIsLoading] = useState(true);
+    const [savingKey, setSavingKey] = useState(null);
+
+    const load = useCallback(async () => {
+        setIsLoading(true);
+        try {
+            const [amendable, questionList] = await Promise.all([
+                api.get('/api/form/amendable'),
+                api.get('/api/questions'),
+            ]);
+            const byId = {};
+            (questionList || []).forEach(q => { byId[q.id] = q; });
+            setQuestions(byId);
+            setAssessments(amendable?.assessments || []);
+            setEditableUntil(amendable?.editable_until ? new Date(amendable.editable_until) : null);
+        } catch (error) {
+            if (window.showMessage) window.showMessage(error.message || 'Failed to load answers', 'error');
+        } finally {
+            setIsLoading(false);
+        }
+    }, []);
+
+    useEffect(() => { load(); }, [load]);
+
+    const save = async (assessmentId, questionId) => {
+        const key = `${assessmentId}:${questionId}`;
+        setSavingKey(key);
+        setErrors(prev => ({ ...prev, [key]: null }));
+        try {
+            const updated = await api.put(`/api/form/assessments/${assessmentId}/answers/${questionId}`, {
+                answer: drafts[key],
+            });
+            setAssessments(prev => prev.map(a => a.id !== assessmentId ? a : {
+                ...a,
+                responses: a.responses.map(r => r.question_id === questionId ? updated : r),
+            }));
+            setDrafts(prev => {
+                const next = { ...prev };
+                delete next[key];
+                return next;
+            });
+            if (window.showMessage) window.showMessage('Answer updated', 'success');
+        } catch (error) {
+            setErrors(prev => ({ ...prev, [key]: fieldErrors(error)[questionId] || error.message }));
+        } finally {
+            setSavingKey(null);
+        }
+    };
+
+    if (isLoading) {
+        return <LoadingSpinner message="Loading today's answers..." />;
+    }
+
+    const editable = assessments.flatMap(a => a.responses
+        .filter(r => EDITABLE_TYPES.includes(questions[r.question_id]?.type))
+        .map(r => ({ assessment: a, response: r })));
+
+    return (
+        <div className="today-answers">
+            <Helmet>
+                <title>Today's Answers - CRAPP</title>
+            </Helmet>
+            <h2>Today's Answers</h2>
+
+            {editable.length === 0 ? (
+                <NoDataMessage message="You haven't submitted any answers today." />
+            ) : (
+                <>
+                    {editableUntil && (
+                        <p className="today-answers-window">
+                            You can change these answers until {editableUntil.toLocaleString()}.
+                        </p>
+                    )}
+                    {editable.map(({ assessment, response }) => {
+                        const key = `${assessment.id}:${response.question_id}`;
+                        const changed = key in drafts;
+                        const answer = changed ? drafts[key] : responseAnswer(response);
+
+                        return (
+                            <div key={key} className="today-answer">
+                                <QuestionRenderer
+                                    question={questions[response.question_id]}
+                                    answer={answer}
+                                    onChange={(_, value) => setDrafts(prev => ({ ...prev, [key]: value }))}
+                                />
+                                {response.revision > 0 && (
+                                    <p className="today-answer-revised">
+                                        Changed {response.revision} {response.revision === 1 ? 'time' : 'times'}
+                                    </p>
+                                )}
+                                {errors[key] && <p className="validation-error">{errors[key]}</p>}
+                                {changed && (
+                                    <button
+                                        type="button"
+                                        className="submit-button"
+                                        onClick={() => save(assessment.id, response.question_id)}
+                                        disabled={savingKey === key}
+                                    >
+                                        {savingKey === key ? 'Saving...' : 'Save change'}
+                                    </button>
+                                )}
+                            </div>
+                        );
+                    })}
+                </>
+            )}
+        </div>
+    );
+};
+
+export default TodayAnswers;
@@ -1,160 +1,228 @@
 // src/components/pages/profile/NotificationForm.jsx
 import React from 'react';
-import { useNotifications } from '../../../context/NotificationContext'; // Adjust path as needed
-import LoadingSpinner from '../../common/LoadingSpinner'; //
+import { useNotifications, NOTIFICATION_TYPES } from '../../../context/NotificationContext';
+import LoadingSpinner from '../../common/LoadingSpinner';
 
-const NotificationForm = ({ showSectionMessage, onSave }) => {
+const CHANNELS = [
+    {
+        key: 'push',
+        label: 'Push Notifications',
+        note: 'Receive browser notifications on this device.'
+    },
+    {
+        key: 'email',
+        label: 'Email Notifications',
+        note: 'Receive notifications by email.'
+    }
+];
+
+const MAX_REMINDER_TIMES = 5;
+
+const NotificationForm = ({ showSectionMessage }) => {
     const {
         preferences,
         pushSupported,
         loading: notificationLoading,
-        savePreferences, // Use this directly
+        savePreferences,
+        snoozeReminders,
+        skipTodayReminders,
         requestPushPermission
-    } = useNotifications(); //
-
-    // Internal handlers that use the context methods
-    const handlePrefChange = async (e) => { //
-        const { name, checked } = e.target; //
-        const isEnablingPush = name === 'enable_notifications' && checked; //
-
-        const currentPrefs = preferences; // Get current prefs from context
-        let newPrefs = { //
-            ...currentPrefs, //
-            [name === 'enable_notifications' ? 'pushEnabled' : 'emailEnabled']: checked //
-        }; //
-
-        if (isEnablingPush) { //
-            const success = await requestPushPermission(); //
-            if (!success) { //
-                newPrefs.pushEnabled = false; //
-                showSectionMessage('Push notification permission was denied', 'error'); //
-            } else { //
-                showSectionMessage('Push notifications enabled successfully!', 'success'); //
-            } //
-        } //
-
-        // Save using context method
-        const saveSuccess = await savePreferences(newPrefs); //
+    } = useNotifications();
+
+    // Saves one channel's settings, leaving the others untouched
+    const saveChannel = async (channelKey, changes, successMessage = 'Notification preferences saved') => {
+        const newPrefs = {
+            ...preferences,
+            channels: {
+                ...preferences.channels,
+                [channelKey]: { ...preferences.channels[channelKey], ...changes }
+            }
+        };
+
+        const saveSuccess = await savePreferences(newPrefs);
         if (!saveSuccess) {
-             showSectionMessage('Failed to save notification preferences', 'error'); //
-        } else if (!isEnablingPush) {
-             // Show generic success if not the push enabling flow (which shows its own message)
-             showSectionMessage('Notification preferences saved', 'success'); //
+            showSectionMessage('Failed to save notification preferences', 'error');
+        } else if (successMessage) {
+            showSectionMessage(successMessage, 'success');
         }
-         // Optional: Call the onSave prop passed from parent if it needs to know
-        if (saveSuccess && onSave) {
-            // onSave(newPrefs); // Or just notify success
+    };
+
+    const handleChannelToggle = async (channelKey, checked) => {
+        if (channelKey === 'push' && checked) {
+            const success = await requestPushPermission();
+            if (!success) {
+                showSectionMessage('Push notification permission was denied', 'error');
+                return;
+            }
+            await saveChannel(channelKey, { enabled: true }, 'Push notifications enabled successfully!');
+            return;
+        }
+        await saveChannel(channelKey, { enabled: checked });
+    };
+
+    const handleTypeToggle = (channelKey, typeKey, checked) => {
+        const types = { ...preferences.channels[channelKey].types, [typeKey]: checked };
+        saveChannel(channelKey, { types });
+    };
+
+    const handleTimeChange = (channelKey, index, value) => {
+        const times = [...preferences.channels[channelKey].reminder_times];
+        times[index] = value;
+        saveChannel(channelKey, { reminder_times: times }, null);
+    };
+
+    const addReminderTime = (channelKey) => {
+        const times = preferences.channels[channelKey].reminder_times || [];
+        if (times.length < MAX_REMINDER_TIMES) {
+            saveChannel(channelKey, { reminder_times: [...times, '20:00'] }, null);
         }
-    }; //
+    };
 
-    const handleTimeChange = (index, value) => { //
-        const newTimes = [...preferences.reminderTimes]; //
-        newTimes[index] = value; //
-        savePreferences({ ...preferences, reminderTimes: newTimes }); // Save immediately via context
-    }; //
+    const removeReminderTime = (channelKey, index) => {
+        const times = preferences.channels[channelKey].reminder_times;
+        if (times.length > 1) {
+            saveChannel(channelKey, { reminder_times: times.filter((_, i) => i !== index) }, null);
+        }
+    };
 
-    const addReminderTime = () => { //
-        if (preferences.reminderTimes.length < 5) { // Add limit check
-            savePreferences({ ...preferences, reminderTimes: [...preferences.reminderTimes, '20:00'] }); //
+    const handleSnooze = async () => {
+        const result = await snoozeReminders(60);
+        if (!result) {
+            showSectionMessage('Failed to snooze reminders', 'error');
+            return;
         }
-    }; //
+        const until = new Date(result.remind_at).toLocaleTimeString([], { hour: '2-digit', minute: '2-digit' });
+        showSectionMessage(`Reminders snoozed until ${until}`, 'success');
+    };
 
-    const removeReminderTime = (index) => { //
-         if (preferences.reminderTimes.length > 1) { // Prevent removing the last one
-            savePreferences({ ...preferences, reminderTimes: preferences.reminderTimes.filter((_, i) => i !== index) }); //
+    const handleSkipToday = async () => {
+        const result = await skipTodayReminders();
+        if (!result) {
+            showSectionMessage('Failed to skip reminders', 'error');
+            return;
         }
-    }; //
+        showSectionMessage("No more reminders will be sent today", 'success');
+    };
+
+    const anyRemindersEnabled = CHANNELS.some(channel =>
+        preferences.channels[channel.key]?.enabled && preferences.channels[channel.key]?.types?.reminders);
 
     if (notificationLoading) {
         return <LoadingSpinner message="Loading preferences..." />;
     }
 
     return (
-        <>
-            <div className="notification-types"> 
-                {/* Push Notifications */}
-                <div className="notification-group"> 
-                    <div className="form-group checkbox-group"> 
-                        <input
-                            type="checkbox"
-                            id="enable_notifications"
-                            name="enable_notifications"
-                            checked={preferences.pushEnabled} //
-                            onChange={handlePrefChange} //
-                            disabled={!pushSupported} //
-                        /> 
-                        <label htmlFor="enable_notifications" style={!pushSupported ? { color: '#999' } : {}}> 
-                            Enable Push Notifications 
-                        </label> 
-                    </div> 
-                     <div className="field-note"> 
-                        Receive browser notifications when it's time to complete your assessment. 
-                    </div> 
-                    {!pushSupported && ( //
-                        <div style={{ color: '#e53e3e', padding: '10px', marginTop: '10px' }}> 
-                           Push notifications are not supported or enabled in your browser. 
-                        </div> //
-                    )} 
-                </div> 
-
-                {/* Email Notifications */}
-                 <div className="notification-group"> 
-                    <div className="form-group checkbox-group"> 
-                        <input
-                            type="checkbox"
-                            id="enable_email_notifications"
-                            name="enable_email_notifications"
-                            checked={preferences.emailEnabled} //
-                            onChange={handlePrefChange} //
-                        /> 
-                        <label htmlFor="enable_email_notifications">Enable Email Reminders</label> 
-                    </div> 
-                     <div className="field-note"> 
-                        Receive email reminders to complete your assessment. 
-                    </div> 
-                </div> 
-            </div> 
-
-            <div id="notification-settings" style={{ marginTop: '20px', display: (preferences.pushEnabled || preferences.emailEnabled) ? 'block' : 'none' }}> 
-                <label>Reminder Times:</label> 
-                 <p className="field-note">Set the times (in your local timezone) when you want to receive reminders.</p> 
-
-                <div id="reminder-times"> 
-                    {preferences.reminderTimes.map((time, index) => ( //
-                        <div key={index} style={{ display: 'flex', marginBottom: '10px', alignItems: 'center' }}> 
+        <div className="notification-types">
+            {CHANNELS.map(channel => {
+                const settings = preferences.channels[channel.key];
+                const unavailable = channel.key === 'push' && !pushSupported;
+                const reminderTimes = settings.reminder_times || [];
+
+                return (
+                    <div className="notification-group" key={channel.key}>
+                        <div className="form-group checkbox-group">
                             <input
-                                type="time"
-                                value={time} //
-                                onChange={(e) => handleTimeChange(index, e.target.value)} //
-                                style={{ marginRight: '10px', maxWidth: '150px' }} //
-                            /> 
-                            {preferences.reminderTimes.length > 1 && ( //
-                                <button
-                                    type="button"
-                                    onClick={() => removeReminderTime(index)} //
-                                    className="danger-button" // Use danger style for remove
-                                    style={{ width: 'auto', padding: '5px 10px', fontSize: '0.8rem' }} // Smaller button
-                                >
-                                    Remove 
-                                </button> //
-                            )} 
-                        </div> //
-                    ))} 
-                </div> 
-
-                {preferences.reminderTimes.length < 5 && ( //
-                     <button
-                        type="button"
-                        onClick={addReminderTime} //
-                        className="button" // Use standard button style
-                        style={{ width: 'auto', padding: '5px 10px', fontSize: '0.8rem', marginTop: '10px' }} // Smaller button
-                    >
-                        Add Reminder Time 
-                    </button> //
-                )} 
-            </div> 
-        </>
+                                type="checkbox"
+                                id={`enable_${channel.key}_notifications`}
+                                checked={settings.enabled}
+                                onChange={(e) => handleChannelToggle(channel.key, e.target.checked)}
+                                disabled={unavailable}
+                            />
+                            <label htmlFor={`enable_${channel.key}_notifications`} style={unavailable ? { color: '#999' } : {}}>
+                                Enable {channel.label}
+                            </label>
+                        </div>
+                        <div className="field-note">{channel.note}</div>
+                        {unavailable && (
+                            <div style={{ color: '#e53e3e', padding: '10px', marginTop: '10px' }}>
+                                Push notifications are not supported or enabled in your browser.
+                            </div>
+                        )}
+
+                        {settings.enabled && (
+                            <div style={{ marginTop: '10px', marginLeft: '20px' }}>
+                                {NOTIFICATION_TYPES.map(type => (
+                                    <div className="form-group checkbox-group" key={type.key}>
+                                        <input
+                                            type="checkbox"
+                                            id={`${channel.key}_${type.key}`}
+                                            checked={!!settings.types[type.key]}
+                                            onChange={(e) => handleTypeToggle(channel.key, type.key, e.target.checked)}
+                                        />
+                                        <label htmlFor={`${channel.key}_${type.key}`}>{type.label}</label>
+                                    </div>
+                                ))}
+
+                                {settings.types.reminders && (
+                                    <div style={{ marginTop: '10px' }}>
+                                        <label>Reminder Times:</label>
+                                        <p className="field-note">Set the times (in your local timezone) when you want to receive reminders.</p>
+
+                                        {reminderTimes.map((time, index) => (
+                                            <div key={index} style={{ display: 'flex', marginBottom: '10px', alignItems: 'center' }}>
+                                                <input
+                                                    type="time"
+                                                    value={time}
+                                                    onChange={(e) => handleTimeChange(channel.key, index, e.target.value)}
+                                                    style={{ marginRight: '10px', maxWidth: '150px' }}
+                                                />
+                                                {reminderTimes.length > 1 && (
+                                                    <button
+                                                        type="button"
+                                                        onClick={() => removeReminderTime(channel.key, index)}
+                                                        className="danger-button"
+                                                        style={{ width: 'auto', padding: '5px 10px', fontSize: '0.8rem' }}
+                                                    >
+                                                        Remove
+                                                    </button>
+                                                )}
+                                            </div>
+                                        ))}
+
+                                        {reminderTimes.length < MAX_REMINDER_TIMES && (
+                                            <button
+                                                type="button"
+                                                onClick={() => addReminderTime(channel.key)}
+                                                className="button"
+                                                style={{ width: 'auto', padding: '5px 10px', fontSize: '0.8rem', marginTop: '10px' }}
+                                            >
+                                                Add Reminder Time
+                                            </button>
+                                        )}
+                                    </div>
+                                )}
+                            </div>
+                        )}
+                    </div>
+                );
+            })}
+
+            {anyRemindersEnabled && (
+                <div className="notification-group">
+                    <label>Today's Reminders:</label>
+                    <p className="field-note">Hold off today's reminders for an hour, or stop them until tomorrow.</p>
+                    <div style={{ display: 'flex', gap: '10px' }}>
+                        <button
+                            type="button"
+                            onClick={handleSnooze}
+                            className="button"
+                            style={{ width: 'auto', padding: '5px 10px', fontSize: '0.8rem' }}
+                        >
+                            Snooze 1 Hour
+                        </button>
+                        <button
+                            type="button"
+                            onClick={handleSkipToday}
+                            className="button"
+                            style={{ width: 'auto', padding: '5px 10px', fontSize: '0.8rem' }}
+                        >
+                            Skip Today
+                        </button>
+                    </div>
+                </div>
+            )}
+        </div>
     );
 };
 
-export default NotificationForm;
\ No newline at end of file
+export default NotificationForm;
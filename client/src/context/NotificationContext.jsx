@@ -4,12 +4,26 @@ import { useAuth } from './AuthContext';
 
 const NotificationContext = createContext();
 
+// Notification types and the channels that can deliver them
+export const NOTIFICATION_TYPES = [
+  { key: 'reminders', label: 'Assessment reminders' },
+  { key: 'weekly_summary', label: 'Weekly summary' },
+  { key: 'security_alerts', label: 'Security alerts' },
+  { key: 'announcements', label: 'Announcements' }
+];
+
+const defaultChannel = () => ({
+  enabled: false,
+  types: { reminders: true, weekly_summary: false, security_alerts: true, announcements: true },
+  reminder_times: ['20:00']
+});
+
+const defaultPreferences = () => ({
+  channels: { push: defaultChannel(), email: defaultChannel() }
+});
+
 export function NotificationProvider({ children }) {
-  const [preferences, setPreferences] = useState({
-    pushEnabled: false,
-    emailEnabled: false,
-    reminderTimes: ['20:00']
-  });
+  const [preferences, setPreferences] = useState(defaultPreferences);
   const [pushSupported, setPushSupported] = useState(false);
   const [loading, setLoading] = useState(true);
 
@@ -32,11 +46,7 @@ export function NotificationProvider({ children }) {
         // If not authenticated, we're done loading for this context
         setLoading(false);
         // Optionally reset preferences to default if user logs out
-        setPreferences({
-          pushEnabled: false,
-          emailEnabled: false,
-          reminderTimes: ['20:00']
-        });
+        setPreferences(defaultPreferences());
       }
     }
     // Dependency array now includes auth state
@@ -45,17 +55,20 @@ export function NotificationProvider({ children }) {
   const fetchPreferences = async () => {
     setLoading(true);
     try {
-      const data = await api.get('/api/push/preferences');
-      
+      const data = await api.get('/api/notifications/preferences');
+      const defaults = defaultPreferences();
+
       setPreferences({
-        pushEnabled: data.push_enabled,
-        emailEnabled: data.email_enabled,
-        reminderTimes: data.reminder_times || ['20:00']
+        ...data,
+        channels: {
+          push: { ...defaults.channels.push, ...data.channels?.push },
+          email: { ...defaults.channels.email, ...data.channels?.email }
+        }
       });
     } catch (error) {
       console.error('Error loading notification preferences:', error);
       // Set default preferences
-      setPreferences({ pushEnabled: false, emailEnabled: false, reminderTimes: ['20:00'] });
+      setPreferences(defaultPreferences());
     } finally {
       setLoading(false);
     }
@@ -68,10 +81,9 @@ export function NotificationProvider({ children }) {
          console.warn("Attempted to save preferences while not authenticated.");
          return false;
       }
-      await api.put('/api/push/preferences', {
-        push_enabled: newPreferences.pushEnabled,
-        email_enabled: newPreferences.emailEnabled,
-        reminder_times: newPreferences.reminderTimes
+      await api.put('/api/notifications/preferences', {
+        channels: newPreferences.channels,
+        cutoff_time: newPreferences.cutoff_time
       });
 
       setPreferences(newPreferences);
@@ -82,6 +94,25 @@ export function NotificationProvider({ children }) {
     }
   };
   
+  // Snooze or skip the rest of today's reminders
+  const snoozeReminders = async (minutes) => {
+    try {
+      return await api.post('/api/reminders/snooze', { minutes });
+    } catch (error) {
+      console.error('Error snoozing reminders:', error);
+      return null;
+    }
+  };
+
+  const skipTodayReminders = async () => {
+    try {
+      return await api.post('/api/reminders/skip-today', {});
+    } catch (error) {
+      console.error('Error skipping reminders:', error);
+      return null;
+    }
+  };
+  
   const requestPushPermission = async () => {
     if (!pushSupported) {
       return false;
@@ -152,6 +183,8 @@ export function NotificationProvider({ children }) {
       loading,
       fetchPreferences,
       savePreferences,
+      snoozeReminders,
+      skipTodayReminders,
       requestPushPermission
     }}>
       {children}
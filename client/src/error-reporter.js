@@ -0,0 +1,100 @@
+// error-reporter.js - sends uncaught errors and unhandled promise rejections
+// to the server in batches for triage
+const ENDPOINT = '/api/client-errors';
+const FLUSH_INTERVAL_MS = 5000;
+const MAX_BATCH = 25;
+const MAX_QUEUE = 100;
+
+class ErrorReporter {
+    constructor() {
+        this.queue = [];
+        this.release = '';
+        this.seen = new Set(); // Skip repeats of the same error within a page load
+
+        window.addEventListener('error', (event) => this.handleError(event));
+        window.addEventListener('unhandledrejection', (event) => this.handleRejection(event));
+        // Send what is left when the page is hidden or closed
+        window.addEventListener('pagehide', () => this.flush(true));
+        document.addEventListener('visibilitychange', () => {
+            if (document.visibilityState === 'hidden') {
+                this.flush(true);
+            }
+        });
+        setInterval(() => this.flush(false), FLUSH_INTERVAL_MS);
+
+        fetch('/api/version')
+            .then((response) => (response.ok ? response.json() : null))
+            .then((info) => {
+                if (info) {
+                    this.release = info.version;
+                }
+            })
+            .catch(() => {});
+    }
+
+    handleError(event) {
+        // Failed script or image loads have no error object or message
+        if (!event.message && !event.error) {
+            return;
+        }
+        this.add({
+            kind: 'error',
+            message: String(event.message || event.error),
+            stack: event.error && event.error.stack ? String(event.error.stack) : '',
+            source: event.filename || '',
+            line: event.lineno || 0,
+            column: event.colno || 0,
+        });
+    }
+
+    handleRejection(event) {
+        const reason = event.reason;
+        this.add({
+            kind: 'unhandled_rejection',
+            message: reason instanceof Error ? reason.message : String(reason),
+            stack: reason instanceof Error && reason.stack ? String(reason.stack) : '',
+            source: '',
+            line: 0,
+            column: 0,
+        });
+    }
+
+    add(report) {
+        const key = `${report.kind}|${report.message}|${report.source}|${report.line}`;
+        if (this.seen.has(key) || this.queue.length >= MAX_QUEUE) {
+            return;
+        }
+        this.seen.add(key);
+        this.queue.push({
+            ...report,
+            message: report.message.slice(0, 2000),
+            stack: report.stack.slice(0, 10000),
+            source: report.source.slice(0, 1000),
+            route: window.location.pathname,
+            occurred_at: new Date().toISOString(),
+        });
+    }
+
+    flush(leaving) {
+        if (this.queue.length === 0) {
+            return;
+        }
+        const batch = this.queue.splice(0, MAX_BATCH);
+        fetch(ENDPOINT, {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify({
+                release: this.release,
+                user_agent: navigator.userAgent,
+                errors: batch,
+            }),
+            credentials: 'omit', // Reports are never linked to an account
+            keepalive: leaving,
+        }).catch(() => {
+            // Reporting is best effort; errors here must not be reported again
+        });
+    }
+}
+
+const errorReporter = new ErrorReporter();
+export default errorReporter;
@@ -12,7 +12,8 @@ const metricsByType = {
       // ... other mouse metrics
        { value: 'overshoot_rate', label: 'Overshoot Rate' }, 
        { value: 'average_velocity', label: 'Average Velocity' },
-       { value: 'velocity_variability', label: 'Velocity Variability' }
+       { value: 'velocity_variability', label: 'Velocity Variability' },
+       { value: 'question_duration', label: 'Time on Question (s)' }
     ],
     keyboard: [
       { value: 'typing_speed', label: 'Typing Speed' },
@@ -25,7 +26,8 @@ const metricsByType = {
        { value: 'pause_rate', label: 'Pause Rate' },
        { value: 'immediate_correction_tendency', label: 'Immediate Correction Tendency' },
        { value: 'deep_thinking_pause_rate', label: 'Deep Thinking Pause Rate' },
-       { value: 'keyboard_fluency', label: 'Keyboard Fluency Score' }
+       { value: 'keyboard_fluency', label: 'Keyboard Fluency Score' },
+       { value: 'question_duration', label: 'Time on Question (s)' }
     ],
     cpt: [ 
       { value: 'reaction_time', label: 'Reaction Time' },
@@ -49,6 +51,20 @@ const metricsByType = {
     ],
 };
 
+// Adds metrics the server knows about but the lists above don't, such as
+// admin-defined formulas. Timing metrics apply to every interaction type.
+const withServerMetrics = (definitions) => {
+    const known = new Set(Object.values(metricsByType).flat().map(m => m.value));
+    const merged = Object.fromEntries(Object.entries(metricsByType).map(([type, list]) => [type, [...list]]));
+    (definitions || []).filter(d => !known.has(d.key)).forEach(d => {
+        const types = d.group === 'timing' ? ['mouse', 'keyboard'] : [d.group];
+        types.forEach(type => {
+            if (merged[type]) merged[type].push({ value: d.key, label: d.label });
+        });
+    });
+    return merged;
+};
+
 // Helper to determine question metrics type (can be outside or inside hook)
 const getQuestionMetricsType = (question) => {
     if (!question) return 'mouse'; // Default
@@ -75,6 +91,8 @@ export function useChartData() {
     const [errorMessage, setErrorMessage] = useState(''); 
     const [correlationData, setCorrelationData] = useState(null); 
     const [timelineData, setTimelineData] = useState(null); 
+    const [selectedBucket, setSelectedBucket] = useState(''); // '', 'day', 'week' or 'month'
+    const [metricGroups, setMetricGroups] = useState(metricsByType);
 
     // Derived state: current metrics type based on selected symptom
     const currentMetricsType = useMemo(() => {
@@ -88,24 +106,38 @@ export function useChartData() {
             setIsLoading(true);
             setErrorMessage(''); // Clear previous errors
             try {
-                const questions = await api.get('/api/questions');
+                const [questions, definitions, views] = await Promise.all([
+                    api.get('/api/questions'),
+                    api.get('/api/metrics/definitions').catch(() => []),
+                    api.get('/api/chart-views').catch(() => []),
+                ]);
+                const groups = withServerMetrics(definitions);
+                setMetricGroups(groups);
                 setAllQuestions(questions); 
 
                 // Set initial default selections only if questions are loaded
                 if (questions.length > 0) { 
-                     const defaultQuestion = questions.find(q => // Find first suitable question
+                     // The viewer's saved default view wins when its question still exists
+                    const savedView = (views || []).find(v => v.is_default);
+                    const defaultQuestion = questions.find(q => q.id === savedView?.symptom) ||
+                        questions.find(q => // Find first suitable question
                         q.type === 'radio' || q.type === 'dropdown' || q.type === 'scale' || q.type === 'cpt' || q.type === 'tmt' || q.type === 'digit_span' // Include tests
                     ) || questions[0]; // Fallback to first question
 
                     if (defaultQuestion) {
                         const initialSymptomId = defaultQuestion.id;
                         const initialMetricsType = getQuestionMetricsType(defaultQuestion);
-                        const initialMetrics = metricsByType[initialMetricsType] || []; 
+                        const initialMetrics = groups[initialMetricsType] || []; 
 
                         setSelectedSymptom(initialSymptomId); 
                         setAvailableMetrics(initialMetrics); 
 
-                        if (initialMetrics.length > 0) { 
+                        const savedMetric = savedView?.symptom === initialSymptomId &&
+                            initialMetrics.find(m => m.value === savedView.metric);
+                        if (savedMetric) {
+                            setSelectedMetric(savedMetric.value);
+                            setSelectedBucket(savedView.bucket || '');
+                        } else if (initialMetrics.length > 0) { 
                             setSelectedMetric(initialMetrics[0].value); 
                         } else {
                             setSelectedMetric(''); // Reset if no metrics available
@@ -131,6 +163,19 @@ export function useChartData() {
         // Dependency: userId ensures questions reload if user changes via URL
     }, [userId]);
 
+    // Answer distribution for choice questions, summarized server-side
+    const [distribution, setDistribution] = useState(null);
+    useEffect(() => {
+        setDistribution(null);
+        const question = allQuestions.find(q => q.id === selectedSymptom);
+        if (!question || !['radio', 'dropdown'].includes(question.type)) return;
+
+        const userIdToUse = userId || user?.email || '';
+        api.get(`/api/questions/${selectedSymptom}/distribution?user_id=${userIdToUse}`)
+            .then(setDistribution)
+            .catch(error => console.warn('Could not load answer distribution:', error));
+    }, [selectedSymptom, allQuestions, userId, user]);
+
     // Update charts when selections or userId change
     useEffect(() => {
         const updateCharts = async () => { 
@@ -163,8 +208,9 @@ export function useChartData() {
                  }
 
                 // Fetch timeline data (always needed)
+                 const bucketParam = selectedBucket ? `&bucket=${selectedBucket}` : '';
                  const timelineResponse = await api.get(
-                    `/api/metrics/chart/timeline?user_id=${userIdToUse}&symptom=${selectedSymptom}&metric=${selectedMetric}`
+                    `/api/metrics/chart/timeline?user_id=${userIdToUse}&symptom=${selectedSymptom}&metric=${selectedMetric}${bucketParam}`
                  ); 
                  setTimelineData(timelineResponse); 
 
@@ -172,7 +218,7 @@ export function useChartData() {
                  if (currentMetricsType === 'mouse') { 
                     try { 
                          const correlationResponse = await api.get( 
-                            `/api/metrics/chart/correlation?user_id=${userIdToUse}&symptom=${selectedSymptom}&metric=${selectedMetric}` 
+                            `/api/metrics/chart/correlation?user_id=${userIdToUse}&symptom=${selectedSymptom}&metric=${selectedMetric}&missing=true` 
                          ); 
                          setCorrelationData(correlationResponse); 
                     } catch (corrError) { 
@@ -202,7 +248,7 @@ export function useChartData() {
         };
 
         updateCharts();
-    }, [selectedSymptom, selectedMetric, userId, allQuestions, currentMetricsType]); // Add allQuestions and currentMetricsType dependencies
+    }, [selectedSymptom, selectedMetric, selectedBucket, userId, allQuestions, currentMetricsType]); // Add allQuestions and currentMetricsType dependencies
 
     // Group questions (memoized for performance)
     const questionGroups = useMemo(() => { 
@@ -226,7 +272,7 @@ export function useChartData() {
         const question = allQuestions.find(q => q.id === symptomId); 
         if (question) { 
             const newMetricsType = getQuestionMetricsType(question); 
-            const newMetrics = metricsByType[newMetricsType] || []; 
+            const newMetrics = metricGroups[newMetricsType] || []; 
             setAvailableMetrics(newMetrics); 
 
             // Reset selected metric to the first available one
@@ -243,7 +289,7 @@ export function useChartData() {
              setAvailableMetrics([]);
              setSelectedMetric('');
         }
-    }, [allQuestions]); // Dependency on allQuestions
+    }, [allQuestions, metricGroups]);
 
     const handleMetricChange = useCallback((e) => { 
         setSelectedMetric(e.target.value); 
@@ -252,6 +298,10 @@ export function useChartData() {
          setNoData(false);
     }, []);
 
+    const handleBucketChange = useCallback((e) => {
+        setSelectedBucket(e.target.value);
+    }, []);
+
     // Determine if correlation chart should be shown
     const shouldShowCorrelationChart = useMemo(() => { 
         // Based on the derived currentMetricsType state
@@ -266,14 +316,17 @@ export function useChartData() {
         errorMessage,
         selectedSymptom,
         selectedMetric,
+        selectedBucket,
         availableMetrics,
         questionGroups, // Use the memoized group
         correlationData,
         timelineData,
+        distribution,
         metricsTypeForExplanation: currentMetricsType, // Use the derived state
         shouldShowCorrelationChart, // Use the memoized value
         handleSymptomChange,
         handleMetricChange,
+        handleBucketChange,
         // Optionally return allQuestions if needed directly in component
         allQuestions
     };
@@ -6,9 +6,11 @@ import api from '../services/api';
 
 export function useFormNavigation() {
   const [stateId, setStateId] = useState(null);
+  const [nonce, setNonce] = useState(''); // Issued with the form, seeds the interaction integrity hash
   const [currentStep, setCurrentStep] = useState(0);
   const [totalSteps, setTotalSteps] = useState(0);
   const [currentQuestion, setCurrentQuestion] = useState(null);
+  const [prefillAnswer, setPrefillAnswer] = useState(null); // Last assessment's answer, for prefill questions
   const [isComplete, setIsComplete] = useState(false);
   const [isSubmitting, setIsSubmitting] = useState(false);
   const [isLoading, setIsLoading] = useState(true); // Added loading state
@@ -27,6 +29,8 @@ export function useFormNavigation() {
       if (!data) throw new Error('Error loading form state'); 
 
       setCurrentQuestion(data.question); 
+      setPrefillAnswer(data.prefill_answer ?? null);
+      setNonce(data.nonce ?? '');
       setCurrentStep(data.current_step); 
       setTotalSteps(data.total_steps); 
       setIsComplete(data.state === 'complete'); 
@@ -59,7 +63,7 @@ export function useFormNavigation() {
 
     if (createNewForm) {
       try {
-        const data = await api.post('/api/form/init', { force_new: true }); 
+        const data = await api.post('/api/form/init', { force_new: true, client_time: Date.now() }); 
         if (data) {
           setStateId(data.id); 
           await loadCurrentQuestion(data.id); 
@@ -90,8 +94,23 @@ export function useFormNavigation() {
     if (!stateId) {
         const initialize = async () => {
             setIsLoading(true);
+
+            // Reminder notifications deep-link to the unfinished form
+            const linkedState = new URLSearchParams(window.location.search).get('state');
+            if (linkedState) {
+                nav('/', { replace: true });
+                try {
+                    await api.get(`/api/form/state/${linkedState}`);
+                    setStateId(linkedState);
+                    await loadCurrentQuestion(linkedState);
+                    return;
+                } catch (error) {
+                    console.warn('Linked form state unavailable, resuming latest form:', error);
+                }
+            }
+
             try {
-                const data = await api.post('/api/form/init', { force_new: false }); //
+                const data = await api.post('/api/form/init', { force_new: false, client_time: Date.now() }); //
                 if (!data) throw new Error('Error initializing form'); //
                 setStateId(data.id); //
                 await loadCurrentQuestion(data.id); //
@@ -129,8 +148,10 @@ export function useFormNavigation() {
 
     try {
       let interactionData = null;
+      let integrityHash = '';
       if (window.interactionTracker) {
         interactionData = window.interactionTracker.getData(); 
+        integrityHash = await window.interactionTracker.integrityHash(nonce, interactionData);
       }
 
       // Include cognitive results if available in currentAnswerData
@@ -139,9 +160,11 @@ export function useFormNavigation() {
         answer: currentAnswerData.answer, 
         direction: direction, 
         interaction_data: interactionData, 
+        integrity_hash: integrityHash,
         cpt_data: currentAnswerData.cptResults, 
         tmt_data: currentAnswerData.tmtResults, 
         digit_span_data: currentAnswerData.digitSpanResults, 
+        client_time: Date.now(), // Lets the server track clock drift
       };
 
       const data = await api.post(`/api/form/state/${stateId}/answer`, payload); 
@@ -157,7 +180,7 @@ export function useFormNavigation() {
       setIsLoading(false); // Stop loading on error
     }
     // Loading state is set to false within loadCurrentQuestion on success
-  }, [stateId, currentQuestion, loadCurrentQuestion]); // Add dependencies
+  }, [stateId, nonce, currentQuestion, loadCurrentQuestion]); // Add dependencies
 
   const handleSubmit = useCallback(async (finalAnswerData) => {
     if (!stateId) return;
@@ -172,6 +195,11 @@ export function useFormNavigation() {
         if (window.interactionTracker) {
             finalInteractionData = window.interactionTracker.getData(); 
         }
+        // Performance beacons let the server flag submissions from slow devices
+        let perfSessionId = '';
+        if (window.perfReporter) {
+            perfSessionId = await window.perfReporter.flushForSubmit();
+        }
 
         const payload = {
             interaction_data: finalInteractionData, 
@@ -182,6 +210,9 @@ export function useFormNavigation() {
             latitude: locationResults.latitude, 
             longitude: locationResults.longitude, 
             location_error: locationResults.error, 
+            perf_session_id: perfSessionId,
+            client_time: Date.now(),
+            nonce: nonce, // Echoed so the server can check the form's integrity
         };
 
         const data = await api.post(`/api/form/state/${stateId}/submit`, payload); 
@@ -198,7 +229,7 @@ export function useFormNavigation() {
     } finally {
         setIsSubmitting(false);
     }
-  }, [stateId, resetFormState]); // Add dependencies
+  }, [stateId, nonce, resetFormState]); // Add dependencies
 
   const handleReset = useCallback(() => {
     if (window.confirm('Are you sure you want to start over? All answers will be lost.')) { 
@@ -213,6 +244,7 @@ export function useFormNavigation() {
     currentStep,
     totalSteps,
     currentQuestion,
+    prefillAnswer,
     isComplete,
     isLoading, // Return loading state
     isSubmitting,
@@ -5,6 +5,9 @@ import App from './App';
 
 // Import interaction tracker
 import './interaction-tracker';
+// Report uncaught errors to the server
+import './error-reporter';
+import './perf-reporter';
 
 const root = ReactDOM.createRoot(document.getElementById('react-root'));
 root.render(
@@ -188,16 +188,41 @@ class InteractionTracker {
         });
     }
     
-    // Get data - no calculations, just raw data
+    // Get data - no calculations, just raw data. The arrays are copied so the
+    // integrity hash covers exactly what is sent.
     getData() {
         return {
-            movements: this.movements,
-            interactions: this.interactions,
-            keyboardEvents: this.keyboardEvents,
+            movements: [...this.movements],
+            interactions: [...this.interactions],
+            keyboardEvents: [...this.keyboardEvents],
             startTime: this.startTime
         };
     }
     
+    // Head of a hash chain over the events in data, seeded with the form's
+    // nonce. The server recomputes it to detect tampered payloads, so the
+    // links must match metrics.IntegrityHash exactly.
+    async integrityHash(nonce, data) {
+        if (!nonce || !data || !window.crypto?.subtle) return '';
+        
+        const encoder = new TextEncoder();
+        const sha256 = async (text) => {
+            const digest = await window.crypto.subtle.digest('SHA-256', encoder.encode(text));
+            return Array.from(new Uint8Array(digest), b => b.toString(16).padStart(2, '0')).join('');
+        };
+        
+        const events = [
+            ...data.movements.map(e => ['move', e.timestamp, e.questionId]),
+            ...data.interactions.map(e => ['click', e.timestamp, e.questionId]),
+            ...data.keyboardEvents.map(e => [e.type, e.timestamp, e.questionId]),
+        ];
+        let link = await sha256(nonce);
+        for (const [kind, timestamp, questionId] of events) {
+            link = await sha256([link, kind, String(timestamp), questionId ?? ''].join('|'));
+        }
+        return link;
+    }
+    
     reset() {
         // Clear data
         this.movements = [];
@@ -0,0 +1,143 @@
+// perf-reporter.js - sends Web Vitals and long task timings to the server so
+// slow devices can be flagged on the assessments they affect. Beacons carry a
+// random session ID, never the account.
+const ENDPOINT = '/api/perf-beacons';
+const FLUSH_INTERVAL_MS = 10000;
+const MAX_BATCH = 100;
+const MAX_QUEUE = 500;
+
+function randomSessionId() {
+    if (window.crypto && window.crypto.randomUUID) {
+        return window.crypto.randomUUID();
+    }
+    const bytes = new Uint8Array(16);
+    window.crypto.getRandomValues(bytes);
+    return Array.from(bytes, (b) => b.toString(16).padStart(2, '0')).join('');
+}
+
+class PerfReporter {
+    constructor() {
+        this.sessionId = randomSessionId();
+        this.queue = [];
+        this.release = '';
+        this.context = ''; // Cognitive test running, if any
+        this.maxInp = {}; // Slowest interaction per context
+
+        this.observe('largest-contentful-paint', (entry) => this.add('lcp', entry.startTime));
+        this.observe('paint', (entry) => {
+            if (entry.name === 'first-contentful-paint') {
+                this.add('fcp', entry.startTime);
+            }
+        });
+        this.observe('longtask', (entry) => this.add('long_task', entry.duration));
+        // INP is approximated by the slowest interaction seen in each context
+        this.observe('event', (entry) => {
+            if (!entry.interactionId) {
+                return;
+            }
+            const key = this.context;
+            if (entry.duration > (this.maxInp[key] || 0)) {
+                this.maxInp[key] = entry.duration;
+            }
+        }, { durationThreshold: 40 });
+        this.observe('navigation', (entry) => this.add('ttfb', entry.responseStart));
+
+        window.addEventListener('pagehide', () => this.flush(true));
+        document.addEventListener('visibilitychange', () => {
+            if (document.visibilityState === 'hidden') {
+                this.flush(true);
+            }
+        });
+        setInterval(() => this.flush(false), FLUSH_INTERVAL_MS);
+
+        fetch('/api/version')
+            .then((response) => (response.ok ? response.json() : null))
+            .then((info) => {
+                if (info) {
+                    this.release = info.version;
+                }
+            })
+            .catch(() => {});
+    }
+
+    observe(type, handle, options = {}) {
+        if (!window.PerformanceObserver || !PerformanceObserver.supportedEntryTypes
+            || !PerformanceObserver.supportedEntryTypes.includes(type)) {
+            return;
+        }
+        try {
+            const observer = new PerformanceObserver((list) => list.getEntries().forEach(handle));
+            observer.observe({ type, buffered: true, ...options });
+        } catch (e) {
+            // Unsupported options on older browsers; the metric is skipped
+        }
+    }
+
+    // setContext marks the cognitive test being taken ('cpt', 'tmt',
+    // 'digit_span'), or clears it with an empty string
+    setContext(context) {
+        this.recordInp();
+        this.context = context || '';
+    }
+
+    recordInp() {
+        Object.entries(this.maxInp).forEach(([context, value]) => {
+            this.queue.push(this.beacon('inp', value, context));
+        });
+        this.maxInp = {};
+    }
+
+    add(metric, value) {
+        if (this.queue.length >= MAX_QUEUE || !(value >= 0)) {
+            return;
+        }
+        this.queue.push(this.beacon(metric, value, this.context));
+    }
+
+    beacon(metric, value, context) {
+        return {
+            metric,
+            value: Math.round(value),
+            context,
+            route: window.location.pathname,
+            recorded_at: new Date().toISOString(),
+        };
+    }
+
+    flush(leaving) {
+        if (leaving) {
+            this.recordInp();
+        }
+        if (this.queue.length === 0) {
+            return Promise.resolve();
+        }
+        const batch = this.queue.splice(0, MAX_BATCH);
+        return fetch(ENDPOINT, {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify({
+                session_id: this.sessionId,
+                release: this.release,
+                beacons: batch,
+            }),
+            credentials: 'omit', // Beacons are never linked to an account
+            keepalive: leaving,
+        }).catch(() => {
+            // Beacons are best effort
+        });
+    }
+
+    // flushForSubmit sends everything measured so far so the server can judge
+    // the session when the assessment is submitted, and returns its ID
+    async flushForSubmit() {
+        this.recordInp();
+        while (this.queue.length > 0) {
+            await this.flush(false);
+        }
+        return this.sessionId;
+    }
+}
+
+const perfReporter = new PerfReporter();
+window.perfReporter = perfReporter;
+export default perfReporter;
@@ -111,6 +111,18 @@ const handleResponse = async (response) => {
   return data;
 };
 
+// Maps a validation failure to the first message for each field. Request and
+// form validation both return { valid, message, errors: [{ field, pointer, code, message }] }.
+export const fieldErrors = (error) => {
+  const result = {};
+  (error?.data?.errors || []).forEach(detail => {
+    if (detail.field && !result[detail.field]) {
+      result[detail.field] = detail.message;
+    }
+  });
+  return result;
+};
+
 // API methods
 export const api = {
   // GET request
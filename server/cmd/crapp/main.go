@@ -7,7 +7,9 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"time"
 
+	"github.com/andevellicus/crapp/internal/buildinfo"
 	"github.com/andevellicus/crapp/internal/config"
 	"github.com/andevellicus/crapp/internal/handlers"
 	"github.com/andevellicus/crapp/internal/logger"
@@ -15,14 +17,30 @@ import (
 	"github.com/andevellicus/crapp/internal/repository"
 	"github.com/andevellicus/crapp/internal/scheduler"
 	"github.com/andevellicus/crapp/internal/services"
+	"github.com/andevellicus/crapp/internal/storage"
+	"github.com/andevellicus/crapp/internal/upload"
 	"github.com/andevellicus/crapp/internal/utils"
 	"github.com/andevellicus/crapp/internal/validation"
 	"github.com/gin-gonic/gin"
 )
 
 func main() {
+	// Offline subcommands run without a server or database
+	if len(os.Args) > 1 && os.Args[1] == "validate-questions" {
+		os.Exit(validateQuestions(os.Args[2:]))
+	}
+	// Integrity checks need the database but not the server
+	if len(os.Args) > 1 && os.Args[1] == "verify" {
+		os.Exit(verifyIntegrity(os.Args[2:]))
+	}
+	// Restoring a backup replaces the database, so it runs without the server
+	if len(os.Args) > 1 && os.Args[1] == "restore" {
+		os.Exit(restoreBackup(os.Args[2:]))
+	}
+
 	// Parse command line flags
 	configPath := flag.String("config", "", "Path to configuration file")
+	migrateOnly := flag.Bool("migrate", false, "Apply database migrations to the shared and all tenant schemas, then exit")
 	flag.Parse()
 
 	// Load configuration
@@ -43,6 +61,30 @@ func main() {
 		MaxBackups: cfg.Logging.MaxBackups,
 		MaxAge:     cfg.Logging.MaxAge,
 		Compress:   cfg.Logging.Compress,
+		Level:      cfg.Logging.Level,
+		Modules:    cfg.Logging.Modules,
+		Format:     cfg.Logging.Format,
+		Stdout:     cfg.Logging.Stdout,
+		File:       cfg.Logging.File,
+		Syslog: logger.SyslogSink{
+			Enabled: cfg.Logging.Syslog.Enabled,
+			Network: cfg.Logging.Syslog.Network,
+			Address: cfg.Logging.Syslog.Address,
+			Tag:     cfg.Logging.Syslog.Tag,
+		},
+		Loki: logger.LokiSink{
+			Enabled:       cfg.Logging.Loki.Enabled,
+			URL:           cfg.Logging.Loki.URL,
+			TenantID:      cfg.Logging.Loki.TenantID,
+			Username:      cfg.Logging.Loki.Username,
+			Password:      cfg.Logging.Loki.Password,
+			Labels:        cfg.Logging.Loki.Labels,
+			BatchSize:     cfg.Logging.Loki.BatchSize,
+			FlushInterval: time.Duration(cfg.Logging.Loki.FlushIntervalSeconds) * time.Second,
+		},
+		InstanceID:  cfg.Logging.InstanceID,
+		Version:     buildinfo.Get().Version,
+		Environment: cfg.App.Environment,
 	}
 	if err := logger.InitLogger(cfg.Logging.Directory, isDevelopment, logConfig); err != nil {
 		panic("Failed to initialize logger: " + err.Error())
@@ -53,6 +95,7 @@ func main() {
 	logger.RedirectStdLog(log.Desugar())
 	log.Infof("Starting %s server with Gin", cfg.App.Name)
 	log.Infof("Environment: %s", cfg.App.Environment)
+	log.Infof("Version: %s (%s)", buildinfo.Version, buildinfo.Get().ShortCommit())
 
 	// Set Gin mode based on environment
 	if cfg.IsProduction() {
@@ -67,30 +110,92 @@ func main() {
 		log.Fatalf("Failed to load questions: %v", err)
 	}
 
+	// Organizations may bring their own questionnaires
+	questionRegistry := utils.NewQuestionRegistry(questionLoader)
+
+	// User-entered text is cleaned according to the configured policies
+	sanitizer, err := utils.NewSanitizer(cfg.Sanitizer.Answers, cfg.Sanitizer.Notes)
+	if err != nil {
+		log.Warnw("Invalid sanitizer settings", "error", err)
+	}
+
 	// Create repository
 	repo := repository.NewRepository(cfg, log, questionLoader)
+	if *migrateOnly {
+		// NewRepository has already migrated the shared and tenant schemas
+		log.Infow("Database migrations applied")
+		return
+	}
 
 	// Create auth service -- MUST BE DONE BEFORE SETTING UP ROUTES AND MIDDLEWARE
 	// BECAUSE JWT GETS INITIALIZED
-	authService := services.NewAuthService(repo, &cfg.JWT)
+	authService := services.NewAuthService(repo, &cfg.JWT, &cfg.PasswordReset)
+
+	// Load the versioned terms and privacy documents
+	legalService := services.NewLegalService(repo, log, &cfg.Legal)
+	// Geolocate logins and flag suspicious ones
+	loginSecurityService := services.NewLoginSecurityService(repo, log, &cfg.LoginSecurity)
+	// Screen sign-ups for abuse
+	registrationGuard := services.NewRegistrationGuard(repo, log, &cfg.Registration, cfg.App.Environment)
+
+	// A demo deployment never emails or pushes to anyone
+	if cfg.Demo.Enabled {
+		cfg.Email.Enabled = false
+		cfg.PWA.VAPIDPublicKey = ""
+		cfg.PWA.VAPIDPrivateKey = ""
+		log.Infow("Demo mode enabled: sign-ups are sandboxed and purged nightly")
+	}
 
+	// Counts reminder opens and clicks
+	notificationTracker := services.NewNotificationTracker(repo, log, cfg.JWT.Secret, &cfg.Reminders)
 	// Initialize email service if enabled
 	var emailService *services.EmailService
 	if cfg.Email.Enabled {
-		emailService = services.NewEmailService(&cfg.Email, log)
+		emailService = services.NewEmailService(repo, &cfg.Email, &cfg.Branding, &cfg.Resilience.Email, log, notificationTracker)
 		log.Infow("Email service initialized", "host", cfg.Email.SMTPHost)
 	} else {
 		log.Infow("Email service disabled")
 	}
+	// Reminder copy, personalized from each study's templates
+	reminderRenderer := services.NewReminderRenderer(repo, log, &cfg.Branding)
 	// Initialize push service
-	pushService := services.NewPushService(repo, log, cfg.PWA.VAPIDPublicKey, cfg.PWA.VAPIDPrivateKey)
+	pushService := services.NewPushService(repo, log, cfg.PWA.VAPIDPublicKey, cfg.PWA.VAPIDPrivateKey, &cfg.Resilience.Push, &cfg.Reminders, reminderRenderer, notificationTracker)
+	// Storage for exports and other generated files
+	fileStore, err := storage.New(&cfg.Storage, cfg.JWT.Secret, log)
+	if err != nil {
+		log.Fatalw("Failed to initialize storage", "error", err)
+	}
+	repo.SetFileStore(fileStore)
+	// Download manifests are signed so their origin can be verified
+	bundleSigner, err := utils.NewBundleSigner(cfg.Exports.SigningKey, cfg.JWT.Secret)
+	if err != nil {
+		log.Fatalw("Failed to initialize export signing key", "error", err)
+	}
+	utils.SetBundleSigner(bundleSigner)
+	// Uploaded files are validated and scanned, with rejected files quarantined in storage
+	uploadPipeline, err := upload.New(&cfg.Uploads, fileStore, log)
+	if err != nil {
+		log.Fatalw("Failed to initialize upload scanning", "error", err)
+	}
+	// Database backups to storage
+	backupService := services.NewBackupService(fileStore, &cfg.Backup, cfg.Database.URL, log)
 	// Initialize the reminder scheduler
-	reminderScheduler := scheduler.NewReminderScheduler(repo, log, cfg, pushService, emailService)
+	reminderScheduler := scheduler.NewReminderScheduler(repo, log, cfg, pushService, emailService, reminderRenderer)
+	// Recomputes custom metric values after formula changes
+	customMetricScheduler := scheduler.NewCustomMetricScheduler(repo, log)
 
 	// Create Gin router
 	router := gin.New()
 
-	t, err := handlers.SetupTemplates()
+	router.UseH2C = cfg.Server.H2C
+
+	// Built assets are linked by content hash so browsers can cache them forever
+	assets := utils.NewAssetVersions(map[string]string{
+		"/main.js":      filepath.Join("client", "dist", "main.js"),
+		"/css/main.css": filepath.Join("client", "dist", "css", "main.css"),
+	})
+
+	t, err := handlers.SetupTemplates(assets)
 	if err != nil {
 		log.Fatalw("Error setting up templates", "error", err)
 	} else {
@@ -98,24 +203,85 @@ func main() {
 		router.SetHTMLTemplate(t)
 	}
 
-	router.Static("/static", filepath.Join("client", "public"))
-	router.Static("/css", filepath.Join("client", "dist", "css"))
-	router.StaticFile("/main.js", filepath.Join("client", "dist", "main.js"))
+	static := router.Group("", middleware.StaticCacheMiddleware(&cfg.Static, assets))
+	static.Static("/static", filepath.Join("client", "public"))
+	static.Static("/css", filepath.Join("client", "dist", "css"))
+	static.StaticFile("/main.js", filepath.Join("client", "dist", "main.js"))
 
 	// Initialize handlers
-	apiHandler := handlers.NewAPIHandler(repo, log, questionLoader)
+	viewHandler := handlers.NewViewHandler(repo, &cfg.Branding)
+	apiHandler := handlers.NewAPIHandler(repo, log, questionRegistry, &cfg.Privacy)
 	// Create auth handler
-	authHandler := handlers.NewAuthHandler(repo, log, authService)
+	var demoService *services.DemoService
+	if cfg.Demo.Enabled {
+		demoService = services.NewDemoService(repo, log, questionLoader, &cfg.Demo)
+	}
+	authHandler := handlers.NewAuthHandler(repo, log, authService, legalService, loginSecurityService, registrationGuard, sanitizer, demoService, reminderScheduler)
 	// Create form handler
-	formHandler := handlers.NewFormHandler(repo, log, questionLoader)
+	replayHandler := handlers.NewReplayHandler(repo, log)
+	perfBeaconHandler := handlers.NewPerfBeaconHandler(repo, log, &cfg.Performance)
+	alertDispatcher := services.NewAlertDispatcher(repo, log, pushService, emailService)
+	achievementService := services.NewAchievementService(repo, log)
+	formHandler := handlers.NewFormHandler(repo, log, questionRegistry, &cfg.Assessment, sanitizer, perfBeaconHandler, alertDispatcher, &cfg.Feedback, achievementService, &cfg.Accessibility, authService)
+	achievementHandler := handlers.NewAchievementHandler(achievementService, log)
 	// Create admin handler
-	adminHandler := handlers.NewAdminHandler(repo, log, pushService, emailService)
+	adminReminderScheduler := scheduler.NewAdminReminderScheduler(repo, log, emailService, pushService, reminderRenderer)
+	adminHandler := handlers.NewAdminHandler(repo, log, pushService, emailService, &cfg.Privacy, adminReminderScheduler)
 	// Initialize Push handler
 	pushHandler := handlers.NewPushHandler(repo, log, pushService, reminderScheduler)
+	reminderHandler := handlers.NewReminderHandler(repo, log, reminderScheduler, reminderRenderer)
+	reminderTemplateHandler := handlers.NewReminderTemplateHandler(repo, log)
+	// Create kiosk handler
+	kioskHandler := handlers.NewKioskHandler(repo, log, authService, &cfg.Kiosk)
+	// Create admin impersonation handler
+	impersonationHandler := handlers.NewImpersonationHandler(repo, log, authService, &cfg.Impersonation)
+	versionHandler := handlers.NewVersionHandler(repo, log, cfg)
+	featuresHandler := handlers.NewFeaturesHandler(cfg)
+	var statusPush *services.PushService
+	if cfg.PWA.Enabled && cfg.PWA.VAPIDPublicKey != "" {
+		statusPush = pushService
+	}
+	statusHandler := handlers.NewStatusHandler(repo, log, emailService, statusPush, &cfg.Status)
+	clientErrorHandler := handlers.NewClientErrorHandler(repo, log, &cfg.ClientErrors)
+	metricsHandler := handlers.NewMetricsHandler(repo, log, &cfg.Metrics, clientErrorHandler)
+	loggingHandler := handlers.NewLoggingHandler(log, &cfg.Logging.BodyLogging)
+	// Create caregiver handler
+	caregiverHandler := handlers.NewCaregiverHandler(repo, log)
+	// Create bulk participant import handler
+	importHandler := handlers.NewImportHandler(repo, log, services.NewUserImportService(repo, log, emailService), uploadPipeline)
+	// Create organization handler
+	organizationHandler := handlers.NewOrganizationHandler(repo, log)
+	// Create study arm randomization handler
+	randomizationHandler := handlers.NewRandomizationHandler(repo, log, services.NewRandomizationService(repo, log))
+	// Create protocol deviation and adverse event handler
+	clinicalEventHandler := handlers.NewClinicalEventHandler(repo, log, sanitizer)
+	// Create study withdrawal handler
+	withdrawalHandler := handlers.NewWithdrawalHandler(repo, log, sanitizer)
+	attachmentHandler := handlers.NewAttachmentHandler(repo, log, uploadPipeline, fileStore, &cfg.Uploads.Attachments)
+	// Create symptom threshold handler
+	thresholdHandler := handlers.NewThresholdHandler(repo, log, questionRegistry)
+	insightsHandler := handlers.NewInsightsHandler(repo, log, questionRegistry, &cfg.Insights)
+	// Create saved chart view handler
+	chartViewHandler := handlers.NewChartViewHandler(repo, log)
+	// Create custom metric handler
+	customMetricHandler := handlers.NewCustomMetricHandler(repo, log, customMetricScheduler)
+	// Create trial review handler
+	reviewHandler := handlers.NewReviewHandler(repo, log, questionRegistry, fileStore, time.Duration(cfg.Storage.SignedURLTTLMinutes)*time.Minute)
+	// Create background export job handler
+	exportJobHandler := handlers.NewExportJobHandler(repo, log, questionRegistry, fileStore, time.Duration(cfg.Storage.SignedURLTTLMinutes)*time.Minute, &cfg.Exports)
+	// Create legal documents handler
+	legalHandler := handlers.NewLegalHandler(log, legalService)
+	backupHandler := handlers.NewBackupHandler(backupService, log)
+	// Create service worker handler
+	serviceWorkerHandler, err := handlers.NewServiceWorkerHandler(log, &cfg.ServiceWorker, assets)
+	if err != nil {
+		log.Fatalw("Error loading service worker template", "error", err)
+	}
 
 	// Apply middleware
 	router.Use(gin.Recovery())
-	router.Use(middleware.GinLogger(log))
+	router.Use(middleware.GinLogger(log, &cfg.Logging.Sampling))
+	router.Use(middleware.BodyLoggerMiddleware(log, &cfg.Logging.BodyLogging))
 	router.Use(middleware.SecurityHeadersMiddleware())
 	router.Use(middleware.SetCSRFTokenMiddleware())
 	// Add email service middleware to make it available in handlers
@@ -127,104 +293,404 @@ func main() {
 	})
 
 	// Add BEFORE other routes
-	router.GET("/service-worker.js", func(c *gin.Context) {
-		// Set proper MIME type
-		c.Header("Content-Type", "application/javascript")
-
-		// Prevent caching for development
-		c.Header("Cache-Control", "no-cache, no-store, must-revalidate, max-age=0")
-		c.Header("Pragma", "no-cache")
-		c.Header("Expires", "0")
-
-		// Allow service worker to control the whole origin
-		c.Header("Service-Worker-Allowed", "/")
-
-		c.File("./client/public/service-worker.js")
-	})
+	router.GET("/service-worker.js", serviceWorkerHandler.ServeServiceWorker)
 
 	// View routes
 	// Serve React app for all frontend routes
-	router.GET("/", handlers.ServeReactApp)
-	router.GET("/login", handlers.ServeReactApp)
-	router.GET("/register", handlers.ServeReactApp)
-	router.GET("/profile", handlers.ServeReactApp)
-	router.GET("/devices", handlers.ServeReactApp)
-	router.GET("/forgot-password", handlers.ServeReactApp)
-	router.GET("/reset-password", handlers.ServeReactApp)
+	router.GET("/", viewHandler.ServeReactApp)
+	router.GET("/login", viewHandler.ServeReactApp)
+	router.GET("/register", viewHandler.ServeReactApp)
+	router.GET("/profile", viewHandler.ServeReactApp)
+	router.GET("/devices", viewHandler.ServeReactApp)
+	router.GET("/forgot-password", viewHandler.ServeReactApp)
+	router.GET("/reset-password", viewHandler.ServeReactApp)
+	router.GET("/caregiver/accept", viewHandler.ServeReactApp)
+
+	// Charts show a participant's data to themselves, admins, and their
+	// organization's admins and blinded reviewers, who get symptom values
+	// masked. Raw answer distributions are not shown to blinded reviewers.
+	chartAccess := middleware.RequireSelfOrRole(repo, middleware.OwnerFromQuery("user_id"),
+		middleware.RoleAdmin, middleware.RoleOrgAdmin, middleware.RoleBlindedReviewer)
+	answerAccess := middleware.RequireSelfOrRole(repo, middleware.OwnerFromQuery("user_id"),
+		middleware.RoleAdmin, middleware.RoleOrgAdmin)
 
 	// Protected API routes
 	api := router.Group("/api")
-	api.Use(middleware.AuthMiddleware(authService), middleware.CSRFMiddleware(), middleware.ValidateJSON())
+	api.Use(middleware.AuthMiddleware(authService), middleware.KioskRestrictionMiddleware(), middleware.PolicyAcceptanceMiddleware(legalService), middleware.CSRFMiddleware(), middleware.ValidateJSON())
 	{
 		// User routes
 		api.GET("/user", authHandler.GetCurrentUser)
+		api.GET("/user/export", middleware.ReauthMiddleware(), middleware.RateLimiterMiddleware(&cfg.RateLimit, "export"), authHandler.ExportUserData)
+		api.GET("/user/activity", authHandler.GetActivity)
+		api.GET("/user/achievements", achievementHandler.GetAchievements)
+		api.GET("/user/accessibility", authHandler.GetAccessibility)
+		api.PUT("/user/accessibility", middleware.ValidateRequest(validation.AccessibilityPreferencesRequest{}), authHandler.UpdateAccessibility)
 		api.PUT("/user", middleware.ValidateRequest(validation.UpdateUserRequest{}), authHandler.UpdateUser)
-		api.PUT("/user/delete", middleware.ValidateRequest(validation.DeleteAccountRequest{}), authHandler.DeleteAccount)
+		api.POST("/user/deactivate", middleware.NoImpersonationMiddleware(), authHandler.DeactivateAccount)
+		api.GET("/user/withdrawal", withdrawalHandler.GetWithdrawal)
+		api.POST("/user/withdraw", middleware.NoImpersonationMiddleware(), middleware.ValidateRequest(validation.WithdrawStudyRequest{}), withdrawalHandler.Withdraw)
+		api.PUT("/user/delete", middleware.NoImpersonationMiddleware(), middleware.ReauthMiddleware(), middleware.ValidateRequest(validation.DeleteAccountRequest{}), authHandler.DeleteAccount)
+
+		// Impersonation consent and exit
+		api.GET("/user/impersonation-requests", impersonationHandler.GetPendingRequests)
+		api.PUT("/user/impersonation-requests/:id",
+			middleware.NoImpersonationMiddleware(),
+			middleware.ValidateRequest(validation.ImpersonationConsentRequest{}),
+			impersonationHandler.RespondToRequest)
+		api.POST("/impersonation/end", impersonationHandler.EndSession)
 
 		// Device routes
 		api.GET("/devices", authHandler.GetUserDevices)
 		api.POST("/devices/register", middleware.ValidateRequest(validation.RegisterDeviceRequest{}), authHandler.RegisterDevice)
-		api.DELETE("/devices/:deviceId", authHandler.RemoveDevice)
-		api.POST("/devices/:deviceId/rename", middleware.ValidateRequest(validation.RenameDeviceRequest{}), authHandler.RenameDevice)
+		api.DELETE("/devices/:deviceId", middleware.RequireSelfOrRole(repo, authHandler.DeviceOwner), authHandler.RemoveDevice)
+		api.POST("/devices/:deviceId/rename",
+			middleware.RequireSelfOrRole(repo, authHandler.DeviceOwner),
+			middleware.ValidateRequest(validation.RenameDeviceRequest{}),
+			authHandler.RenameDevice)
+
+		// Caregiver routes
+		api.GET("/caregivers", caregiverHandler.GetLinks)
+		api.POST("/caregivers/invite", middleware.ValidateRequest(validation.InviteCaregiverRequest{}), caregiverHandler.Invite)
+		api.POST("/caregivers/accept", middleware.ValidateRequest(validation.AcceptCaregiverInviteRequest{}), caregiverHandler.Accept)
+		api.DELETE("/caregivers/:linkId", caregiverHandler.Revoke)
+
+		// Raw keystroke and mouse data of the caller's own assessments
+		api.GET("/assessments/:assessmentId/raw-data",
+			middleware.RateLimiterMiddleware(&cfg.RateLimit, "raw_data"),
+			middleware.NoImpersonationMiddleware(),
+			middleware.RequireSelfOrRole(repo, formHandler.AssessmentOwner),
+			replayHandler.GetOwnRawData)
 
 		// Question routes
 		api.GET("/questions", apiHandler.GetQuestions)
 		api.GET("/questions/symptoms", apiHandler.GetSymptomQuestions)
+		api.GET("/questions/:id/help", apiHandler.GetQuestionHelp)
+		api.GET("/questions/:id/distribution", answerAccess, apiHandler.GetQuestionDistribution)
 
 		// Metric routes
-		api.GET("/metrics/chart/correlation", apiHandler.GetChartCorrelationData)
-		api.GET("/metrics/chart/timeline", apiHandler.GetChartTimelineData)
+		api.GET("/metrics/chart/correlation", chartAccess, apiHandler.GetChartCorrelationData)
+		api.GET("/metrics/chart/timeline", chartAccess, apiHandler.GetChartTimelineData)
+		api.GET("/metrics/changepoints", chartAccess, apiHandler.GetChangePoints)
+		api.GET("/metrics/compare", chartAccess, apiHandler.GetPeriodComparison)
+		api.GET("/metrics/definitions", customMetricHandler.ListDefinitions)
+
+		// Plain-language summaries of the user's own trends
+		api.GET("/insights", insightsHandler.GetInsights)
+
+		// Saved chart views, for the viewer's own dashboards
+		api.GET("/chart-views", chartViewHandler.ListViews)
+		api.POST("/chart-views", middleware.ValidateRequest(validation.ChartViewRequest{}), chartViewHandler.CreateView)
+		api.PUT("/chart-views/:id", middleware.ValidateRequest(validation.ChartViewRequest{}), chartViewHandler.UpdateView)
+		api.DELETE("/chart-views/:id", chartViewHandler.DeleteView)
+
+		// Self-description for exports, SDKs and dashboards
+		api.GET("/meta/data-dictionary", apiHandler.GetDataDictionary)
+	}
+
+	// Terms of service and privacy policy
+	router.GET("/api/legal/documents/:document", legalHandler.GetDocument)
+
+	// Signed download links for locally stored files
+	if localStore, ok := fileStore.(*storage.LocalStorage); ok {
+		router.GET(storage.LocalURLPath+"*key", handlers.NewStorageHandler(localStore, log).Download)
+	}
+
+	// Public key that verifies signed download manifests
+	router.GET("/.well-known/crapp-signing-keys.json", handlers.NewSigningKeyHandler(bundleSigner).GetPublicKey)
+
+	// Deployed version, public and minimal
+	router.GET("/api/version", versionHandler.GetVersion)
+	// Optional features turned on, such as demo mode and its banner
+	router.GET("/api/features", featuresHandler.GetFeatures)
+
+	// Reminder open and click tracking, reached from emails and notifications
+	notificationTrackingHandler := handlers.NewNotificationTrackingHandler(notificationTracker, log)
+	trackingRoutes := router.Group("/api/notifications/track")
+	trackingRoutes.Use(middleware.RateLimiterMiddleware(&cfg.RateLimit, "tracking"))
+	{
+		trackingRoutes.GET("/click", notificationTrackingHandler.TrackClick)
+		trackingRoutes.POST("/click", notificationTrackingHandler.TrackClick)
+		trackingRoutes.GET("/open", notificationTrackingHandler.TrackOpen)
+	}
+
+	// JavaScript errors from the PWA, which may come before sign-in
+	router.POST("/api/client-errors",
+		middleware.RateLimiterMiddleware(&cfg.RateLimit, "client_errors"),
+		middleware.ValidateJSON(),
+		middleware.ValidateRequest(validation.ClientErrorBatchRequest{}),
+		clientErrorHandler.Report)
+	// Web Vitals and timing beacons, tied to a random session rather than an account
+	router.POST("/api/perf-beacons",
+		middleware.RateLimiterMiddleware(&cfg.RateLimit, "perf_beacons"),
+		middleware.ValidateJSON(),
+		middleware.ValidateRequest(validation.PerfBeaconBatchRequest{}),
+		perfBeaconHandler.Report)
+	// Service health for a status page, public and coarse
+	router.GET("/api/status", statusHandler.GetStatus)
+	if cfg.Metrics.Enabled {
+		router.GET(cfg.Metrics.Path, metricsHandler.GetMetrics)
+	}
+	legal := router.Group("/api/legal")
+	legal.Use(middleware.AuthMiddleware(authService), middleware.CSRFMiddleware(), middleware.ValidateJSON())
+	{
+		legal.GET("/status", legalHandler.GetStatus)
+		legal.POST("/accept", middleware.NoImpersonationMiddleware(), middleware.ValidateRequest(validation.AcceptPolicyRequest{}), legalHandler.Accept)
 	}
 
 	// Auth API routes
 	auth := router.Group("/api/auth")
-	auth.Use(middleware.RateLimiterMiddleware(), middleware.ValidateJSON())
+	auth.Use(middleware.RateLimiterMiddleware(&cfg.RateLimit, "auth"), middleware.ValidateJSON())
 	{
+		auth.GET("/register/config", authHandler.GetRegistrationConfig)
 		auth.POST("/register", middleware.ValidateRequest(validation.RegisterRequest{}), authHandler.Register)
 		auth.POST("/login", middleware.ValidateRequest(validation.LoginRequest{}), authHandler.Login)
+		auth.POST("/login/confirm", middleware.ValidateRequest(validation.ConfirmLoginRequest{}), authHandler.ConfirmLogin)
+		auth.POST("/reactivate", middleware.ValidateRequest(validation.ReactivateAccountRequest{}), authHandler.ReactivateAccount)
 		auth.POST("/refresh", authHandler.RefreshToken)
 		auth.POST("/logout", middleware.AuthMiddleware(authService), authHandler.Logout)
+		auth.POST("/reauth", middleware.AuthMiddleware(authService), middleware.NoImpersonationMiddleware(), middleware.CSRFMiddleware(), middleware.ValidateRequest(validation.ReauthRequest{}), authHandler.Reauthenticate)
 		// Password reset API endpoints
-		auth.POST("/forgot-password", middleware.ValidateRequest(validation.ForgotPasswordRequest{}), authHandler.ForgotPassword)
+		auth.POST("/forgot-password",
+			middleware.RateLimiterMiddleware(&cfg.RateLimit, "password_reset"),
+			middleware.ValidateRequest(validation.ForgotPasswordRequest{}),
+			authHandler.ForgotPassword)
 		auth.GET("/validate-reset-token", authHandler.ValidateResetToken)
 		auth.POST("/reset-password", middleware.ValidateRequest(validation.ResetPasswordRequest{}), authHandler.ResetPassword)
 	}
 
 	form := router.Group("/api/form")
-	form.Use(middleware.AuthMiddleware(authService))
+	form.Use(middleware.AuthMiddleware(authService), middleware.PolicyAcceptanceMiddleware(legalService))
 	{
 		form.POST("/init", formHandler.InitForm)
-		form.GET("/state/:stateId", formHandler.GetCurrentQuestion)
-		form.POST("/state/:stateId/answer", middleware.ValidateRequest(validation.SaveAnswerRequest{}), formHandler.SaveAnswer)
-		form.POST("/state/:stateId/submit", formHandler.SubmitForm)
+		// Form states are only ever reached by the user filling them in
+		requireFormOwner := middleware.RequireSelfOrRole(repo, formHandler.FormStateOwner)
+		// Routes that show or change past assessments are closed to kiosk
+		// sessions, which only fill in the day's form
+		noKiosk := middleware.KioskRestrictionMiddleware()
+		form.GET("/state/:stateId", requireFormOwner, formHandler.GetCurrentQuestion)
+		form.POST("/state/:stateId/answer", requireFormOwner, middleware.ValidateRequest(validation.SaveAnswerRequest{}), formHandler.SaveAnswer)
+		form.POST("/state/:stateId/submit", middleware.RateLimiterMiddleware(&cfg.RateLimit, "form_submit"), requireFormOwner, formHandler.SubmitForm)
+		form.POST("/kiosk/end", kioskHandler.EndSession)
+
+		// Questions that aren't asked every day, and when they're next due
+		form.GET("/schedule", noKiosk, formHandler.GetQuestionSchedule)
+
+		// Answers can be amended on the day they were submitted
+		form.GET("/amendable", noKiosk, formHandler.GetAmendableAnswers)
+		form.PUT("/assessments/:assessmentId/answers/:questionId",
+			noKiosk,
+			middleware.CSRFMiddleware(),
+			middleware.RequireSelfOrRole(repo, formHandler.AssessmentOwner),
+			middleware.ValidateJSON(),
+			middleware.ValidateRequest(validation.AmendAnswerRequest{}),
+			formHandler.AmendAnswer)
+
+		// One optional photo or document per assessment, managed by its owner
+		requireAttachmentOwner := middleware.RequireSelfOrRole(repo, attachmentHandler.AssessmentOwner)
+		form.POST("/assessments/:assessmentId/attachment", middleware.CSRFMiddleware(), requireAttachmentOwner, attachmentHandler.Upload)
+		form.GET("/assessments/:assessmentId/attachment", noKiosk, requireAttachmentOwner, attachmentHandler.GetAttachment)
+		form.GET("/assessments/:assessmentId/attachment/file", noKiosk, requireAttachmentOwner, attachmentHandler.DownloadFile)
+		form.DELETE("/assessments/:assessmentId/attachment", noKiosk, middleware.CSRFMiddleware(), requireAttachmentOwner, attachmentHandler.DeleteAttachment)
+		form.PUT("/assessments/:assessmentId/attachment/exports",
+			noKiosk,
+			middleware.CSRFMiddleware(),
+			requireAttachmentOwner,
+			middleware.ValidateJSON(),
+			middleware.ValidateRequest(validation.AttachmentExportsRequest{}),
+			attachmentHandler.SetExportFlag)
 	}
 
 	// Add push notification routes
 	pushRoutes := router.Group("/api/push")
-	pushRoutes.Use(middleware.AuthMiddleware(authService))
+	pushRoutes.Use(middleware.AuthMiddleware(authService), middleware.KioskRestrictionMiddleware(), middleware.PolicyAcceptanceMiddleware(legalService))
 	{
 		pushRoutes.GET("/vapid-public-key", pushHandler.GetVAPIDPublicKey)
 		pushRoutes.POST("/subscribe", middleware.ValidateRequest(validation.PushSubscriptionRequest{}), pushHandler.SubscribeUser)
-		pushRoutes.GET("/preferences", pushHandler.GetPreferences)
-		pushRoutes.PUT("/preferences", middleware.ValidateRequest(validation.NotificationPreferencesRequest{}), pushHandler.UpdatePreferences)
+	}
+
+	// Users can snooze or skip today's reminders from the app or a push action
+	reminderRoutes := router.Group("/api/reminders")
+	reminderRoutes.Use(middleware.AuthMiddleware(authService), middleware.KioskRestrictionMiddleware(), middleware.PolicyAcceptanceMiddleware(legalService))
+	{
+		reminderRoutes.POST("/snooze", middleware.ValidateRequest(validation.SnoozeReminderRequest{}), reminderHandler.Snooze)
+		reminderRoutes.POST("/skip-today", reminderHandler.SkipToday)
+		reminderRoutes.GET("/message", reminderHandler.GetMessage)
+	}
+
+	// Notification preferences cover every channel and notification type
+	notificationRoutes := router.Group("/api/notifications")
+	notificationRoutes.Use(middleware.AuthMiddleware(authService), middleware.KioskRestrictionMiddleware(), middleware.PolicyAcceptanceMiddleware(legalService))
+	{
+		notificationRoutes.GET("/preferences", pushHandler.GetPreferences)
+		notificationRoutes.PUT("/preferences", middleware.ValidateRequest(validation.NotificationPreferencesRequest{}), pushHandler.UpdatePreferences)
 	}
 
 	// Admin routes
 	admin := router.Group("/admin")
-	admin.Use(middleware.AuthMiddleware(authService), middleware.AdminMiddleware())
+	admin.Use(middleware.AuthMiddleware(authService), middleware.OrgAdminMiddleware(repo))
 	{
 		// Admin endpoints can be added here
-		admin.GET("/charts", handlers.ServeReactApp)
-		admin.GET("/users", handlers.ServeReactApp)
+		admin.GET("/charts", viewHandler.ServeReactApp)
+		admin.GET("/users", viewHandler.ServeReactApp)
 		admin.GET("/api/users/search", adminHandler.SearchUsers)
 		admin.POST("/api/send-reminder",
 			middleware.ValidateJSON(),
 			middleware.ValidateRequest(validation.AdminReminderRequest{}),
 			adminHandler.SendReminder)
+		admin.GET("/api/send-reminder/:id", adminHandler.GetReminderJob)
+		admin.GET("/api/kiosk/sessions", kioskHandler.GetSessions)
+		admin.POST("/api/kiosk/sessions",
+			middleware.ValidateJSON(),
+			middleware.ValidateRequest(validation.StartKioskSessionRequest{}),
+			kioskHandler.StartSession)
+		admin.GET("/api/audit", adminHandler.SearchAuditEvents)
+		admin.GET("/api/impersonation", middleware.AdminMiddleware(), impersonationHandler.ListSessions)
+		admin.POST("/api/impersonation",
+			middleware.AdminMiddleware(),
+			middleware.ValidateJSON(),
+			middleware.ValidateRequest(validation.StartImpersonationRequest{}),
+			impersonationHandler.RequestSession)
+		admin.POST("/api/impersonation/:id/start", middleware.AdminMiddleware(), impersonationHandler.StartSession)
+		admin.DELETE("/api/impersonation/:id", middleware.AdminMiddleware(), impersonationHandler.RevokeSession)
+		admin.GET("/api/version", middleware.AdminMiddleware(), versionHandler.GetBuildDetails)
+		admin.GET("/api/logging/levels", middleware.AdminMiddleware(), loggingHandler.GetLevels)
+		admin.PUT("/api/logging/levels",
+			middleware.AdminMiddleware(),
+			middleware.ValidateJSON(),
+			middleware.ValidateRequest(validation.SetLogLevelRequest{}),
+			loggingHandler.SetLevel)
+		admin.DELETE("/api/logging/levels", middleware.AdminMiddleware(), loggingHandler.ResetLevel)
+		admin.GET("/api/logging/bodies", middleware.AdminMiddleware(), loggingHandler.GetBodyLogging)
+		admin.PUT("/api/logging/bodies",
+			middleware.AdminMiddleware(),
+			middleware.ValidateJSON(),
+			middleware.ValidateRequest(validation.SetBodyLoggingRequest{}),
+			loggingHandler.EnableBodyLogging)
+		admin.DELETE("/api/logging/bodies", middleware.AdminMiddleware(), loggingHandler.DisableBodyLogging)
+		admin.GET("/api/signups/metrics", middleware.AdminMiddleware(), adminHandler.GetSignupMetrics)
+		admin.GET("/api/questions/analytics", middleware.AdminMiddleware(), adminHandler.GetQuestionAnalytics)
+		admin.GET("/api/notifications/engagement", adminHandler.GetNotificationEngagement)
+		admin.POST("/api/users/import", importHandler.ImportUsers)
+		admin.GET("/api/users/import/:id", importHandler.GetImportJob)
+		admin.PUT("/api/users/lifecycle",
+			middleware.ValidateJSON(),
+			middleware.ValidateRequest(validation.LifecycleOverrideRequest{}),
+			adminHandler.UpdateLifecycleOverride)
+		admin.PUT("/api/users/retention",
+			middleware.ValidateJSON(),
+			middleware.ValidateRequest(validation.RetentionOverrideRequest{}),
+			adminHandler.UpdateRetentionOverride)
+		admin.PUT("/api/users/clinician",
+			middleware.ValidateJSON(),
+			middleware.ValidateRequest(validation.AssignClinicianRequest{}),
+			adminHandler.AssignClinician)
+		admin.GET("/api/organizations", middleware.AdminMiddleware(), organizationHandler.ListOrganizations)
+		admin.POST("/api/organizations",
+			middleware.AdminMiddleware(),
+			middleware.ValidateJSON(),
+			middleware.ValidateRequest(validation.CreateOrganizationRequest{}),
+			organizationHandler.CreateOrganization)
+		admin.GET("/api/organizations/:id/studies", organizationHandler.ListStudies)
+		admin.POST("/api/organizations/:id/studies",
+			middleware.ValidateJSON(),
+			middleware.ValidateRequest(validation.CreateStudyRequest{}),
+			organizationHandler.CreateStudy)
+		admin.GET("/api/studies/:id/randomization", randomizationHandler.GetScheme)
+		admin.PUT("/api/studies/:id/randomization",
+			middleware.ValidateJSON(),
+			middleware.ValidateRequest(validation.ConfigureRandomizationRequest{}),
+			randomizationHandler.ConfigureScheme)
+		admin.GET("/api/studies/:id/reminder-templates", reminderTemplateHandler.ListTemplates)
+		admin.PUT("/api/studies/:id/reminder-templates/:channel",
+			middleware.ValidateJSON(),
+			middleware.ValidateRequest(validation.ReminderTemplateRequest{}),
+			reminderTemplateHandler.SaveTemplate)
+		admin.DELETE("/api/studies/:id/reminder-templates/:channel", reminderTemplateHandler.DeleteTemplate)
+		admin.GET("/api/studies/:id/allocations", randomizationHandler.ListAllocations)
+		admin.POST("/api/studies/:id/allocations",
+			middleware.ValidateJSON(),
+			middleware.ValidateRequest(validation.RandomizeParticipantRequest{}),
+			randomizationHandler.Randomize)
+		admin.GET("/api/clinical-events", clinicalEventHandler.SearchEvents)
+		admin.GET("/api/clinical-events/summary", clinicalEventHandler.GetSummary)
+		admin.POST("/api/clinical-events",
+			middleware.ValidateJSON(),
+			middleware.ValidateRequest(validation.ReportClinicalEventRequest{}),
+			clinicalEventHandler.ReportEvent)
+		admin.PUT("/api/clinical-events/:id",
+			middleware.ValidateJSON(),
+			middleware.ValidateRequest(validation.UpdateClinicalEventRequest{}),
+			clinicalEventHandler.UpdateEvent)
+		admin.GET("/api/thresholds", thresholdHandler.ListThresholds)
+		admin.POST("/api/thresholds",
+			middleware.ValidateJSON(),
+			middleware.ValidateRequest(validation.SymptomThresholdRequest{}),
+			thresholdHandler.CreateThreshold)
+		admin.PUT("/api/thresholds/:id",
+			middleware.ValidateJSON(),
+			middleware.ValidateRequest(validation.SymptomThresholdRequest{}),
+			thresholdHandler.UpdateThreshold)
+		admin.DELETE("/api/thresholds/:id", thresholdHandler.DeleteThreshold)
+		admin.GET("/api/custom-metrics", customMetricHandler.ListCustomMetrics)
+		admin.POST("/api/custom-metrics",
+			middleware.AdminMiddleware(),
+			middleware.ValidateJSON(),
+			middleware.ValidateRequest(validation.CustomMetricRequest{}),
+			customMetricHandler.CreateCustomMetric)
+		admin.PUT("/api/custom-metrics/:id",
+			middleware.AdminMiddleware(),
+			middleware.ValidateJSON(),
+			middleware.ValidateRequest(validation.CustomMetricRequest{}),
+			customMetricHandler.UpdateCustomMetric)
+		admin.DELETE("/api/custom-metrics/:id", middleware.AdminMiddleware(), customMetricHandler.DeleteCustomMetric)
+		admin.GET("/api/response-revisions",
+			middleware.RequireSelfOrRole(repo, middleware.OwnerFromQuery("email"), middleware.RoleAdmin, middleware.RoleOrgAdmin),
+			adminHandler.SearchResponseRevisions)
+		admin.GET("/api/threshold-flags", thresholdHandler.SearchFlags)
+		admin.GET("/api/red-flag-alerts", thresholdHandler.SearchRedFlagAlerts)
+		admin.GET("/api/client-errors", middleware.AdminMiddleware(), clientErrorHandler.ListErrors)
+		admin.GET("/api/client-errors/:fingerprint", middleware.AdminMiddleware(), clientErrorHandler.GetError)
+		// Attachments of a participant's assessments, named with ?email=
+		staffAttachmentAccess := middleware.RequireSelfOrRole(repo, attachmentHandler.AssessmentOwner, middleware.RoleAdmin, middleware.RoleOrgAdmin)
+		admin.GET("/api/assessments/:assessmentId/attachment", staffAttachmentAccess, attachmentHandler.GetAttachment)
+		admin.GET("/api/assessments/:assessmentId/attachment/file", staffAttachmentAccess, attachmentHandler.DownloadFile)
+		admin.GET("/api/assessments/:assessmentId/attachment/thumbnail", staffAttachmentAccess, attachmentHandler.DownloadThumbnail)
+		admin.PUT("/api/assessments/:assessmentId/attachment/exports",
+			staffAttachmentAccess,
+			middleware.ValidateJSON(),
+			middleware.ValidateRequest(validation.AttachmentExportsRequest{}),
+			attachmentHandler.SetExportFlag)
+		// Recalculates an assessment's metrics from its raw data, named with ?email=
+		admin.GET("/api/assessments/:assessmentId/replay", middleware.AdminMiddleware(), replayHandler.ReplayAssessment)
+		admin.PUT("/api/threshold-flags/:id/acknowledge", thresholdHandler.AcknowledgeFlag)
+		admin.PUT("/api/red-flag-alerts/:id/acknowledge", thresholdHandler.AcknowledgeRedFlagAlert)
+		admin.PUT("/api/users/organization",
+			middleware.AdminMiddleware(),
+			middleware.ValidateJSON(),
+			middleware.ValidateRequest(validation.AssignOrganizationRequest{}),
+			organizationHandler.AssignUser)
+		admin.GET("/api/integrity", middleware.AdminMiddleware(), adminHandler.CheckIntegrity)
+		admin.POST("/api/integrity/repair", middleware.AdminMiddleware(), adminHandler.RepairIntegrity)
+		admin.GET("/api/backups", middleware.AdminMiddleware(), backupHandler.ListBackups)
+		admin.POST("/api/backups", middleware.AdminMiddleware(), backupHandler.TriggerBackup)
+		admin.POST("/api/backups/:name/verify", middleware.AdminMiddleware(), backupHandler.VerifyBackup)
+	}
+
+	// Read-only trial review routes, masked for blinded reviewers
+	review := router.Group("/review/api")
+	review.Use(middleware.AuthMiddleware(authService), middleware.KioskRestrictionMiddleware(), middleware.ReviewerMiddleware(repo))
+	{
+		review.GET("/adherence", reviewHandler.GetAdherence)
+		review.GET("/data-quality", reviewHandler.GetDataQuality)
+		review.GET("/withdrawals", withdrawalHandler.ListWithdrawals)
+		review.GET("/export", middleware.RateLimiterMiddleware(&cfg.RateLimit, "export"), reviewHandler.ExportResponses)
+		review.POST("/export-jobs", middleware.CSRFMiddleware(), middleware.RateLimiterMiddleware(&cfg.RateLimit, "export"), exportJobHandler.CreateJob)
+		review.GET("/export-jobs", exportJobHandler.ListJobs)
+		review.GET("/export-jobs/:id", exportJobHandler.GetJob)
 	}
 
 	// Handle all other routes to serve the React app for client-side routing
-	router.NoRoute(handlers.ServeReactApp)
+	router.NoRoute(viewHandler.ServeReactApp)
 
 	// Start the reminder scheduler
 	if err := reminderScheduler.Start(); err != nil {
@@ -245,10 +711,60 @@ func main() {
 	}
 
 	// Add token cleanup scheduler
-	tokenCleanupScheduler := scheduler.NewTokenCleanupScheduler(repo, log)
+	tokenCleanupScheduler := scheduler.NewTokenCleanupScheduler(repo, log, &cfg.JWT)
 	tokenCleanupScheduler.Start()
 
 	defer tokenCleanupScheduler.Stop()
+
+	// Aggregate form navigation into per-question analytics nightly
+	questionAnalyticsScheduler := scheduler.NewQuestionAnalyticsScheduler(repo, log)
+	questionAnalyticsScheduler.Start()
+	defer questionAnalyticsScheduler.Stop()
+
+	// Summarize assessments for charts that the post-submit refresh missed
+	chartSummaryScheduler := scheduler.NewChartSummaryScheduler(repo, log)
+	chartSummaryScheduler.Start()
+	defer chartSummaryScheduler.Stop()
+
+	// Flag participants past a symptom threshold once each day closes
+	thresholdScheduler := scheduler.NewThresholdScheduler(repo, log, emailService)
+	thresholdScheduler.Start()
+	defer thresholdScheduler.Stop()
+
+	// Send admin reminders when their time comes
+	adminReminderScheduler.Start()
+	defer adminReminderScheduler.Stop()
+
+	// Build queued analysis exports in the background
+	exportJobHandler.Start()
+	defer exportJobHandler.Stop()
+	attachmentHandler.Start()
+	defer attachmentHandler.Stop()
+
+	// Load custom metrics into the registry and keep their values current
+	customMetricScheduler.Start()
+	defer customMetricScheduler.Stop()
+
+	// Apply the account inactivity policy
+	if cfg.Lifecycle.Enabled {
+		lifecycleScheduler := scheduler.NewLifecycleScheduler(repo, log, &cfg.Lifecycle, emailService)
+		lifecycleScheduler.Start()
+		defer lifecycleScheduler.Stop()
+	}
+
+	// Purge the demo's sandbox accounts nightly
+	if demoService != nil {
+		demoScheduler := scheduler.NewDemoScheduler(repo, log, demoService)
+		demoScheduler.Start()
+		defer demoScheduler.Stop()
+	}
+
+	// Back up the database on schedule
+	if cfg.Backup.Enabled {
+		backupScheduler := scheduler.NewBackupScheduler(backupService, log, cfg.Backup.IntervalHours)
+		backupScheduler.Start()
+		defer backupScheduler.Stop()
+	}
 	// Make sure to stop the scheduler when the application shuts down
 	defer reminderScheduler.Stop()
 
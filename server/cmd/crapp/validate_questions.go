@@ -0,0 +1,48 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/andevellicus/crapp/internal/config"
+	"github.com/andevellicus/crapp/internal/utils"
+)
+
+// validateQuestions checks questions files without starting the server or
+// touching the database. With no file arguments, it checks the questions file
+// from the configuration. Returns the process exit code.
+func validateQuestions(args []string) int {
+	fs := flag.NewFlagSet("validate-questions", flag.ExitOnError)
+	configPath := fs.String("config", "", "Path to configuration file")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: crapp validate-questions [-config path] [questions.yaml ...]")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	files := fs.Args()
+	if len(files) == 0 {
+		cfg, err := config.LoadConfig(*configPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to load configuration: %v\n", err)
+			return 2
+		}
+		files = []string{cfg.App.QuestionsFile}
+	}
+
+	failed := false
+	for _, file := range files {
+		if err := utils.CheckQuestionsFile(file); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			failed = true
+			continue
+		}
+		fmt.Printf("%s: OK\n", file)
+	}
+
+	if failed {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/andevellicus/crapp/internal/config"
+	"github.com/andevellicus/crapp/internal/logger"
+	"github.com/andevellicus/crapp/internal/repository"
+)
+
+// verifyIntegrity checks the database for orphaned and inconsistent rows,
+// such as after restoring a backup, and optionally deletes the repairable
+// ones. Returns 1 when problems remain.
+func verifyIntegrity(args []string) int {
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	configPath := fs.String("config", "", "Path to configuration file")
+	repair := fs.Bool("repair", false, "Delete rows found by repairable checks")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: crapp verify [-config path] [-repair]")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	cfg, err := config.LoadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load configuration: %v\n", err)
+		return 2
+	}
+	if err := logger.InitLogger(cfg.Logging.Directory, false, &logger.LogConfig{Level: "error", Stdout: true}); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to initialize logger: %v\n", err)
+		return 2
+	}
+
+	repo := repository.NewRepository(cfg, logger.Sugar, nil)
+	reports, err := repo.CheckIntegrity(*repair)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Integrity check failed: %v\n", err)
+		return 1
+	}
+
+	found, remaining := 0, 0
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SCHEMA\tCHECK\tROWS\tREPAIRED\tSAMPLE")
+	for _, report := range reports {
+		schema := report.Organization
+		if schema == "" {
+			schema = "shared"
+		}
+		for _, issue := range report.Issues {
+			found++
+			if issue.Repaired < issue.Count {
+				remaining++
+			}
+			fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%s\n", schema, issue.Check, issue.Count, issue.Repaired, strings.Join(issue.Sample, ", "))
+		}
+	}
+	if found == 0 {
+		fmt.Println("No integrity problems found.")
+		return 0
+	}
+	w.Flush()
+
+	if remaining > 0 {
+		if !*repair {
+			fmt.Println("\nRun with -repair to delete rows from repairable checks.")
+		}
+		return 1
+	}
+	fmt.Println("\nAll problems were repaired.")
+	return 0
+}
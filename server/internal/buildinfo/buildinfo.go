@@ -0,0 +1,75 @@
+// Package buildinfo describes the running build. The variables are set at
+// build time, e.g.
+//
+//	go build -ldflags "-X github.com/andevellicus/crapp/internal/buildinfo.Version=1.4.0 \
+//	  -X github.com/andevellicus/crapp/internal/buildinfo.Commit=$(git rev-parse HEAD) \
+//	  -X github.com/andevellicus/crapp/internal/buildinfo.BuildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+package buildinfo
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+var (
+	// Version is the release version
+	Version = "dev"
+	// Commit is the git commit the binary was built from
+	Commit = ""
+	// BuildTime is when the binary was built, in RFC 3339
+	BuildTime = ""
+	// SchemaVersion is the database schema version the build expects
+	SchemaVersion = ""
+)
+
+// Info is the build description reported by the version endpoints
+type Info struct {
+	Version       string `json:"version"`
+	Commit        string `json:"commit"`
+	BuildTime     string `json:"build_time,omitempty"`
+	SchemaVersion string `json:"schema_version,omitempty"`
+	GoVersion     string `json:"go_version"`
+	Modified      bool   `json:"modified,omitempty"`
+}
+
+// Get returns the build description. Without ldflags, the commit and time
+// come from the VCS stamp Go embeds when building inside a repository.
+func Get() Info {
+	info := Info{
+		Version:       Version,
+		Commit:        Commit,
+		BuildTime:     BuildTime,
+		SchemaVersion: SchemaVersion,
+		GoVersion:     runtime.Version(),
+	}
+
+	if build, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range build.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				if info.Commit == "" {
+					info.Commit = setting.Value
+				}
+			case "vcs.time":
+				if info.BuildTime == "" {
+					info.BuildTime = setting.Value
+				}
+			case "vcs.modified":
+				info.Modified = setting.Value == "true"
+			}
+		}
+	}
+
+	if info.Commit == "" {
+		info.Commit = "unknown"
+	}
+	return info
+}
+
+// ShortCommit returns the first 12 characters of the commit hash
+func (i Info) ShortCommit() string {
+	if len(i.Commit) > 12 {
+		return i.Commit[:12]
+	}
+	return i.Commit
+}
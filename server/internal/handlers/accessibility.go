@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/andevellicus/crapp/internal/config"
+	"github.com/andevellicus/crapp/internal/models"
+	"github.com/andevellicus/crapp/internal/utils"
+	"github.com/andevellicus/crapp/internal/validation"
+	"github.com/gin-gonic/gin"
+)
+
+// GetAccessibility returns the current user's accessibility preferences
+func (h *AuthHandler) GetAccessibility(c *gin.Context) {
+	userEmail := c.GetString("userEmail")
+	user, err := h.repo.Users.GetByEmail(userEmail)
+	if err != nil || user == nil {
+		h.log.Errorw("Error retrieving user", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error retrieving accessibility preferences"})
+		return
+	}
+
+	c.JSON(http.StatusOK, user.Accessibility)
+}
+
+// UpdateAccessibility replaces the current user's accessibility preferences.
+// Cognitive tests served from then on are adjusted to them.
+func (h *AuthHandler) UpdateAccessibility(c *gin.Context) {
+	userEmail := c.GetString("userEmail")
+	req := c.MustGet("validatedRequest").(*validation.AccessibilityPreferencesRequest)
+
+	preferences := models.AccessibilityPreferences{
+		LargeText:        req.LargeText,
+		ReducedMotion:    req.ReducedMotion,
+		ExtendedTimeouts: req.ExtendedTimeouts,
+	}
+	if err := h.repo.Users.SetAccessibility(userEmail, preferences); err != nil {
+		h.log.Errorw("Failed to save accessibility preferences", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save accessibility preferences"})
+		return
+	}
+
+	recordAudit(h.repo, h.log, c, userEmail, models.AuditPreferencesChange, "", map[string]any{
+		"accessibility": preferences,
+	})
+
+	c.JSON(http.StatusOK, preferences)
+}
+
+// cognitiveAdjustment returns how cognitive tests are adjusted for a user's
+// accessibility preferences
+func cognitiveAdjustment(cfg *config.AccessibilityConfig, preferences models.AccessibilityPreferences) utils.CognitiveAdjustment {
+	adjustment := utils.CognitiveAdjustment{ReducedMotion: preferences.ReducedMotion}
+	if preferences.ExtendedTimeouts {
+		adjustment.TimeoutFactor = cfg.TimeoutFactor
+	}
+	if preferences.LargeText {
+		adjustment.TargetScale = cfg.LargeTargetScale
+	}
+	return adjustment
+}
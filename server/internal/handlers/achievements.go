@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/andevellicus/crapp/internal/services"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// AchievementHandler shows users the badges and milestones they earned
+type AchievementHandler struct {
+	achievements *services.AchievementService
+	log          *zap.SugaredLogger
+}
+
+// NewAchievementHandler creates a new achievement handler
+func NewAchievementHandler(achievements *services.AchievementService, log *zap.SugaredLogger) *AchievementHandler {
+	return &AchievementHandler{
+		achievements: achievements,
+		log:          log.Named("achievements"),
+	}
+}
+
+// GetAchievements lists every achievement with whether the current user
+// earned it and their progress towards it
+func (h *AchievementHandler) GetAchievements(c *gin.Context) {
+	userEmail := c.GetString("userEmail")
+
+	progress, streak, err := h.achievements.Progress(userEmail)
+	if err != nil {
+		h.log.Errorw("Error loading achievements", "error", err, "user", userEmail)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error retrieving achievements"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"streak":       streak,
+		"achievements": progress,
+	})
+}
@@ -0,0 +1,131 @@
+// internal/handlers/activity.go
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/andevellicus/crapp/internal/models"
+	"github.com/andevellicus/crapp/internal/repository"
+	"github.com/andevellicus/crapp/internal/services"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// recordAudit adds an event to the user's audit log. Failures are logged but
+// never block the request that triggered them.
+func recordAudit(repo *repository.Repository, log *zap.SugaredLogger, c *gin.Context, email, eventType, deviceID string, details map[string]any) {
+	if deviceID == "" {
+		deviceID = getDeviceID(c)
+	}
+
+	event := &models.AuditEvent{
+		UserEmail: email,
+		EventType: eventType,
+		DeviceID:  deviceID,
+		IPAddress: c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	}
+	if err := repo.AuditEvents.Record(event, details); err != nil {
+		log.Warnw("Failed to record audit event", "error", err, "email", email, "type", eventType)
+	}
+}
+
+// recordLoginAudit adds a login event with the geolocation and risk assessment attached
+func recordLoginAudit(repo *repository.Repository, log *zap.SugaredLogger, c *gin.Context, email, eventType, deviceID string, risk *services.LoginRisk, details map[string]any) {
+	event := &models.AuditEvent{
+		UserEmail: email,
+		EventType: eventType,
+		DeviceID:  deviceID,
+		IPAddress: c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	}
+
+	if risk != nil {
+		if risk.Location != nil {
+			event.Country = risk.Location.Country
+			event.City = risk.Location.City
+			event.Latitude = &risk.Location.Latitude
+			event.Longitude = &risk.Location.Longitude
+		}
+		if risk.Suspicious {
+			event.Suspicious = true
+			if details == nil {
+				details = map[string]any{}
+			}
+			details["reasons"] = risk.Reasons
+		}
+	}
+
+	if err := repo.AuditEvents.Record(event, details); err != nil {
+		log.Warnw("Failed to record audit event", "error", err, "email", email, "type", eventType)
+	}
+}
+
+// GetActivity returns a paginated timeline of the current user's security events
+func (h *AuthHandler) GetActivity(c *gin.Context) {
+	userEmail, exists := c.Get("userEmail")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	skip := 0
+	limit := 20
+
+	if skipParam := c.Query("skip"); skipParam != "" {
+		if val, err := strconv.Atoi(skipParam); err == nil && val >= 0 {
+			skip = val
+		}
+	}
+
+	if limitParam := c.Query("limit"); limitParam != "" {
+		if val, err := strconv.Atoi(limitParam); err == nil && val > 0 && val <= 100 {
+			limit = val
+		}
+	}
+
+	events, total, err := h.repo.AuditEvents.GetForUser(userEmail.(string), skip, limit)
+	if err != nil {
+		h.log.Errorw("Error retrieving activity timeline", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error retrieving activity"})
+		return
+	}
+
+	// Attach device names so the timeline reads naturally
+	deviceNames := make(map[string]string)
+	if devices, err := h.repo.Devices.GetUserDevices(userEmail.(string)); err == nil {
+		for _, device := range devices {
+			deviceNames[device.ID] = device.DeviceName
+		}
+	}
+
+	timeline := make([]gin.H, 0, len(events))
+	for _, event := range events {
+		var details any
+		if event.Details != "" {
+			details = json.RawMessage(event.Details)
+		}
+		timeline = append(timeline, gin.H{
+			"id":          event.ID,
+			"event_type":  event.EventType,
+			"device_id":   event.DeviceID,
+			"device_name": deviceNames[event.DeviceID],
+			"ip_address":  event.IPAddress,
+			"user_agent":  event.UserAgent,
+			"country":     event.Country,
+			"city":        event.City,
+			"suspicious":  event.Suspicious,
+			"details":     details,
+			"created_at":  event.CreatedAt,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"events": timeline,
+		"total":  total,
+		"skip":   skip,
+		"limit":  limit,
+	})
+}
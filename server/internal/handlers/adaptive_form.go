@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/andevellicus/crapp/internal/utils"
+)
+
+// Adaptive form variants recorded on form states and assessments
+const (
+	formVariantFull  = "full"
+	formVariantShort = "short"
+)
+
+// formVariant decides which form adaptive mode serves on a day. The short
+// form is served when the user has an assessment on each of the last
+// stable_days days, none of their numeric answers over those days changed by
+// more than max_change, and the full form was served within full_every_days.
+// It returns "" when the questions file doesn't enable adaptive mode.
+func (h *FormHandler) formVariant(email string, adaptive *utils.AdaptiveConfig, questions []utils.Question, day time.Time) (string, error) {
+	if adaptive == nil || adaptive.StableDays <= 0 {
+		return "", nil
+	}
+	fullEvery := adaptive.FullEveryDays
+	if fullEvery <= 0 {
+		fullEvery = adaptive.StableDays
+	}
+
+	var symptomIDs []string
+	for _, q := range questions {
+		if q.Type == "radio" || q.Type == "dropdown" {
+			symptomIDs = append(symptomIDs, q.ID)
+		}
+	}
+
+	since := day.AddDate(0, 0, -adaptive.StableDays)
+	stability, err := h.repo.ForUser(email).Assessments.GetAnswerStability(email, since, symptomIDs)
+	if err != nil {
+		return "", err
+	}
+	if stability.Days < adaptive.StableDays || stability.LastFull == nil {
+		return formVariantFull, nil
+	}
+	// Dates come back at UTC midnight; move them onto the day's calendar
+	last := *stability.LastFull
+	if fullDue := time.Date(last.Year(), last.Month(), last.Day()+fullEvery, 0, 0, 0, 0, day.Location()); !fullDue.After(day) {
+		return formVariantFull, nil
+	}
+	for _, spread := range stability.Spread {
+		if spread > adaptive.MaxChange {
+			return formVariantFull, nil
+		}
+	}
+	return formVariantShort, nil
+}
+
+// coreQuestions narrows the indexes of a form's questions to the core ones.
+// If none of them are core, all are kept.
+func coreQuestions(questions []utils.Question, indexes []int) []int {
+	core := make([]int, 0, len(indexes))
+	for _, index := range indexes {
+		if questions[index].Core {
+			core = append(core, index)
+		}
+	}
+	if len(core) == 0 {
+		return indexes
+	}
+	return core
+}
@@ -3,13 +3,19 @@ package handlers
 
 import (
 	"net/http"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/andevellicus/crapp/internal/config"
+	"github.com/andevellicus/crapp/internal/models"
 	"github.com/andevellicus/crapp/internal/repository"
+	"github.com/andevellicus/crapp/internal/scheduler"
 	"github.com/andevellicus/crapp/internal/services"
 	"github.com/andevellicus/crapp/internal/validation"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"go.uber.org/zap"
 )
 
@@ -19,6 +25,8 @@ type AdminHandler struct {
 	log          *zap.SugaredLogger
 	pushService  *services.PushService
 	emailService *services.EmailService
+	privacy      *config.PrivacyConfig
+	reminders    *scheduler.AdminReminderScheduler
 }
 
 // NewAdminHandler creates a new admin handler
@@ -27,104 +35,115 @@ func NewAdminHandler(
 	log *zap.SugaredLogger,
 	pushService *services.PushService,
 	emailService *services.EmailService,
+	privacy *config.PrivacyConfig,
+	reminders *scheduler.AdminReminderScheduler,
 ) *AdminHandler {
 	return &AdminHandler{
 		repo:         repo,
 		log:          log.Named("admin"),
 		pushService:  pushService,
 		emailService: emailService,
+		privacy:      privacy,
+		reminders:    reminders,
 	}
 }
 
-// SendReminder sends a reminder to a user via email or push notification
+// SendReminder schedules a reminder to the chosen users through the chosen
+// channels, by default every channel the server can send through. The
+// reminder is sent in the background at its send time, and the job it
+// returns can be polled for each delivery's status.
 func (h *AdminHandler) SendReminder(c *gin.Context) {
-	// Verify admin access (middleware should already handle this)
-	isAdmin, exists := c.Get("isAdmin")
-	if !exists || !isAdmin.(bool) {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
-		return
-	}
-
 	req := c.MustGet("validatedRequest").(*validation.AdminReminderRequest)
-	normalizedEmail := strings.ToLower(req.Email)
 
-	// Get user
-	user, err := h.repo.Users.GetByEmail(normalizedEmail)
-	if err != nil || user == nil {
-		h.log.Errorw("Error getting user for reminder", "error", err, "email", normalizedEmail)
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+	if req.Email == "" && len(req.Emails) == 0 && req.Filter == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Choose recipients by email or filter"})
 		return
 	}
 
-	// Get notification preferences
-	prefs, err := h.repo.Users.GetNotificationPreferences(normalizedEmail)
-	if err != nil {
-		h.log.Warnw("Error getting notification preferences", "error", err, "email", normalizedEmail)
-		// Continue anyway since this is an admin-initiated reminder
-	}
-
-	var success bool
-	var errorMsg string
-
-	// Send reminder based on method
-	switch req.Method {
-	case "email":
-		// Send email reminder
-		if h.emailService != nil {
-			err = h.emailService.SendReminderEmail(user.Email, user.FirstName)
-			if err != nil {
-				h.log.Warnw("Failed to send email reminder", "error", err, "email", normalizedEmail)
-				errorMsg = "Failed to send email reminder: " + err.Error()
-			} else {
-				success = true
-				h.log.Infow("Sent admin-initiated email reminder", "email", normalizedEmail)
+	available := map[string]bool{"email": h.emailService != nil, "push": h.pushService != nil}
+	channels := req.Channels
+	if len(channels) == 0 && req.Method != "" {
+		channels = []string{req.Method}
+	}
+	if len(channels) == 0 {
+		for _, channel := range []string{"email", "push"} {
+			if available[channel] {
+				channels = append(channels, channel)
 			}
-		} else {
-			errorMsg = "Email service not available"
 		}
-
-	case "push":
-		// Send push notification
-		if h.pushService == nil {
-			errorMsg = "Push notification service not available"
-			break
-		} else if prefs.PushEnabled {
-			err = h.pushService.SendNotification(
-				user.Email,
-				"Daily Assessment Reminder",
-				"This is a reminder to complete your daily symptom assessment.",
-			)
-			if err != nil {
-				h.log.Errorw("Failed to send push reminder", "error", err, "email", normalizedEmail)
-				errorMsg = "Failed to send push reminder: " + err.Error()
-			} else {
-				success = true
-				h.log.Infow("Sent admin-initiated push reminder", "email", normalizedEmail)
-			}
-		} else {
-			c.JSON(http.StatusForbidden, gin.H{
-				"success": false,
-				"error":   "User has disabled push notifications",
-			})
+	}
+	if len(channels) == 0 {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "No reminder channels are available"})
+		return
+	}
+	channels = slices.Compact(slices.Sorted(slices.Values(channels)))
+	for _, channel := range channels {
+		if !available[channel] {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "The " + channel + " channel is not available"})
 			return
 		}
+	}
 
-	default:
-		errorMsg = "Invalid reminder method"
+	// Organization admins may only contact their own participants
+	targets := repository.ReminderTargets{Emails: req.Emails}
+	if req.Email != "" {
+		targets.Emails = append(targets.Emails, req.Email)
+	}
+	if req.Filter != nil {
+		targets.StudyID = req.Filter.StudyID
+		targets.InactiveOnly = req.Filter.InactiveOnly
+	}
+	recipients, err := h.repo.Users.GetReminderTargets(orgScope(c), targets)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error selecting recipients"})
+		return
+	}
+	if len(recipients) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No users match the reminder's recipients"})
+		return
 	}
 
-	// Return response
-	if success {
-		c.JSON(http.StatusOK, gin.H{
-			"success": true,
-			"message": "Reminder sent successfully",
-		})
-	} else {
-		c.JSON(http.StatusServiceUnavailable, gin.H{
-			"success": false,
-			"error":   errorMsg,
-		})
+	sendAt := time.Now()
+	if req.SendAt != nil && req.SendAt.After(sendAt) {
+		sendAt = *req.SendAt
+	}
+	job := &models.ReminderJob{
+		ID:             uuid.NewString(),
+		CreatedBy:      c.GetString("userEmail"),
+		OrganizationID: orgScope(c),
+		Channels:       strings.Join(channels, ","),
+		SkipCompleted:  req.SkipCompleted,
+		SendAt:         sendAt,
+	}
+	if err := h.repo.ReminderJobs.Create(job, recipients, channels); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error scheduling reminder"})
+		return
+	}
+	if !job.SendAt.After(time.Now()) {
+		h.reminders.Wake()
 	}
+
+	h.log.Infow("Scheduled admin reminder", "id", job.ID, "admin", job.CreatedBy, "org", job.OrganizationID,
+		"recipients", job.Recipients, "channels", job.Channels, "send_at", job.SendAt)
+	c.JSON(http.StatusAccepted, job)
+}
+
+// GetReminderJob returns a reminder job with its deliveries, optionally only
+// those with the status given by the status query parameter
+func (h *AdminHandler) GetReminderJob(c *gin.Context) {
+	job, err := h.repo.ReminderJobs.Get(c.Param("id"))
+	// Organization admins only see their organization's jobs
+	if err != nil || (orgScope(c) != "" && job.OrganizationID != orgScope(c)) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Reminder job not found"})
+		return
+	}
+
+	deliveries, err := h.repo.ReminderJobs.ListDeliveries(job.ID, c.Query("status"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error loading deliveries"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"job": job, "deliveries": deliveries})
 }
 
 // SearchUsers handles admin search for users
@@ -145,7 +164,7 @@ func (h *AdminHandler) SearchUsers(c *gin.Context) {
 		}
 	}
 
-	users, total, err := h.repo.Users.SearchUsers(query, skip, limit)
+	users, total, err := h.repo.Users.SearchUsers(orgScope(c), query, skip, limit)
 	if err != nil {
 		h.log.Errorw("Error searching users", "error", err, "query", query)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error searching users"})
@@ -159,3 +178,287 @@ func (h *AdminHandler) SearchUsers(c *gin.Context) {
 		"limit": limit,
 	})
 }
+
+// UpdateLifecycleOverride sets a user's study and exempts them from the inactivity policy
+func (h *AdminHandler) UpdateLifecycleOverride(c *gin.Context) {
+	req := c.MustGet("validatedRequest").(*validation.LifecycleOverrideRequest)
+	normalizedEmail := strings.ToLower(req.Email)
+
+	if !userInOrgScope(c, h.repo, normalizedEmail) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	// Organization admins may only assign their organization's studies
+	if scope := orgScope(c); scope != "" && req.StudyID != "" {
+		exists, err := h.repo.Organizations.StudyExists(scope, req.StudyID)
+		if err != nil || !exists {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown study"})
+			return
+		}
+	}
+
+	if err := h.repo.Users.UpdateLifecycleOverride(normalizedEmail, req.StudyID, req.Exempt); err != nil {
+		h.log.Errorw("Error updating lifecycle override", "error", err, "email", normalizedEmail)
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	// Clear any existing flag so the next run re-evaluates under the new policy
+	if err := h.repo.Users.SetInactive(normalizedEmail, nil); err != nil {
+		h.log.Warnw("Error clearing inactivity flag", "error", err, "email", normalizedEmail)
+	}
+
+	h.log.Infow("Updated lifecycle override", "email", normalizedEmail, "study_id", req.StudyID, "exempt", req.Exempt)
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"study_id": req.StudyID,
+		"exempt":   req.Exempt,
+	})
+}
+
+// UpdateRetentionOverride places or releases a user's legal hold and sets
+// their own retention period. Holds placed and released, and changes to the
+// retention period, are recorded in the user's audit trail.
+func (h *AdminHandler) UpdateRetentionOverride(c *gin.Context) {
+	req := c.MustGet("validatedRequest").(*validation.RetentionOverrideRequest)
+	normalizedEmail := strings.ToLower(req.Email)
+	admin := c.GetString("userEmail")
+
+	if req.RetentionDays > 0 && req.RetentionAction == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A retention period needs a retention action"})
+		return
+	}
+	if !userInOrgScope(c, h.repo, normalizedEmail) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	user, err := h.repo.Users.GetByEmail(normalizedEmail)
+	if err != nil || user == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	if err := h.repo.Users.SetRetentionOverride(normalizedEmail, req.LegalHold, req.RetentionDays, req.RetentionAction); err != nil {
+		h.log.Errorw("Error updating retention override", "error", err, "email", normalizedEmail)
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	reason := strings.TrimSpace(req.Reason)
+	if req.LegalHold != user.LegalHold {
+		eventType := models.AuditLegalHoldReleased
+		if req.LegalHold {
+			eventType = models.AuditLegalHoldPlaced
+		}
+		recordAudit(h.repo, h.log, c, normalizedEmail, eventType, "", map[string]any{
+			"admin":  admin,
+			"reason": reason,
+		})
+	}
+	if req.RetentionDays != user.RetentionDays || (req.RetentionDays > 0 && req.RetentionAction != user.RetentionAction) {
+		recordAudit(h.repo, h.log, c, normalizedEmail, models.AuditRetentionOverridden, "", map[string]any{
+			"admin":            admin,
+			"reason":           reason,
+			"retention_days":   req.RetentionDays,
+			"retention_action": req.RetentionAction,
+		})
+	}
+
+	h.log.Infow("Updated retention override", "email", normalizedEmail, "legal_hold", req.LegalHold,
+		"retention_days", req.RetentionDays, "admin", admin)
+	c.JSON(http.StatusOK, gin.H{
+		"success":          true,
+		"legal_hold":       req.LegalHold,
+		"retention_days":   req.RetentionDays,
+		"retention_action": req.RetentionAction,
+	})
+}
+
+// AssignClinician sets the clinician notified first about a user's red-flag
+// answers. Organization admins can only assign their own organization's staff.
+func (h *AdminHandler) AssignClinician(c *gin.Context) {
+	req := c.MustGet("validatedRequest").(*validation.AssignClinicianRequest)
+	normalizedEmail := strings.ToLower(req.Email)
+	clinician := strings.ToLower(req.ClinicianEmail)
+
+	if !userInOrgScope(c, h.repo, normalizedEmail) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if clinician != "" {
+		if user, err := h.repo.Users.GetByEmail(clinician); err != nil || user == nil || !userInOrgScope(c, h.repo, clinician) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown clinician"})
+			return
+		}
+	}
+
+	if err := h.repo.Users.SetClinician(normalizedEmail, clinician); err != nil {
+		h.log.Errorw("Error assigning clinician", "error", err, "email", normalizedEmail)
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	h.log.Infow("Assigned clinician", "email", normalizedEmail, "clinician", clinician, "admin", c.GetString("userEmail"))
+	c.JSON(http.StatusOK, gin.H{
+		"success":         true,
+		"clinician_email": clinician,
+	})
+}
+
+// SearchAuditEvents lists audit events across users, optionally only suspicious ones
+func (h *AdminHandler) SearchAuditEvents(c *gin.Context) {
+	email := c.Query("email")
+	suspiciousOnly := c.Query("suspicious") == "true"
+	skip := 0
+	limit := 50
+
+	if skipParam := c.Query("skip"); skipParam != "" {
+		if val, err := strconv.Atoi(skipParam); err == nil && val >= 0 {
+			skip = val
+		}
+	}
+
+	if limitParam := c.Query("limit"); limitParam != "" {
+		if val, err := strconv.Atoi(limitParam); err == nil && val > 0 && val <= 200 {
+			limit = val
+		}
+	}
+
+	events, total, err := h.repo.AuditEvents.Search(orgScope(c), email, suspiciousOnly, skip, limit)
+	if err != nil {
+		h.log.Errorw("Error searching audit events", "error", err, "email", email)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error searching audit events"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"events": events,
+		"total":  total,
+		"skip":   skip,
+		"limit":  limit,
+	})
+}
+
+// GetSignupMetrics summarizes accepted and rejected sign-ups over recent days
+func (h *AdminHandler) GetSignupMetrics(c *gin.Context) {
+	days := 30
+	if daysParam := c.Query("days"); daysParam != "" {
+		if val, err := strconv.Atoi(daysParam); err == nil && val > 0 && val <= 365 {
+			days = val
+		}
+	}
+
+	accepted, rejected, byReason, err := h.repo.SignupAttempts.GetMetrics(time.Now().AddDate(0, 0, -days))
+	if err != nil {
+		h.log.Errorw("Error getting sign-up metrics", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error getting sign-up metrics"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"days":               days,
+		"accepted":           accepted,
+		"rejected":           rejected,
+		"rejected_by_reason": byReason,
+	})
+}
+
+// GetNotificationEngagement compares how often reminders sent on each
+// channel over the last days (default 30) were opened and clicked
+func (h *AdminHandler) GetNotificationEngagement(c *gin.Context) {
+	days := 30
+	if daysParam := c.Query("days"); daysParam != "" {
+		if val, err := strconv.Atoi(daysParam); err == nil && val > 0 && val <= 365 {
+			days = val
+		}
+	}
+
+	stats, err := h.repo.NotificationEvents.Engagement(orgScope(c), time.Now().AddDate(0, 0, -days))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error getting notification engagement"})
+		return
+	}
+
+	channels := make([]gin.H, 0, len(stats))
+	for _, stat := range stats {
+		channel := gin.H{
+			"channel": stat.Channel,
+			"sent":    stat.Sent,
+			"opened":  stat.Opened,
+			"clicked": stat.Clicked,
+		}
+		if stat.Sent > 0 {
+			channel["open_rate"] = float64(stat.Opened) / float64(stat.Sent)
+			channel["click_rate"] = float64(stat.Clicked) / float64(stat.Sent)
+		}
+		channels = append(channels, channel)
+	}
+	c.JSON(http.StatusOK, gin.H{"days": days, "channels": channels})
+}
+
+// GetQuestionAnalytics returns per-question views, answers, back navigation,
+// abandonment, and average time over the last days (default 30)
+func (h *AdminHandler) GetQuestionAnalytics(c *gin.Context) {
+	days := 30
+	if daysParam := c.Query("days"); daysParam != "" {
+		if val, err := strconv.Atoi(daysParam); err == nil && val > 0 && val <= 365 {
+			days = val
+		}
+	}
+
+	assessmentDays := h.repo.AssessmentDay()
+	to := assessmentDays.Today()
+	from := assessmentDays.AddDays(to, -(days - 1))
+
+	questions, err := h.repo.QuestionAnalytics.Summarize(from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error getting question analytics"})
+		return
+	}
+	repository.SuppressSmallQuestionAnalytics(questions, h.privacy.MinCellSize)
+
+	c.JSON(http.StatusOK, gin.H{
+		"days":      days,
+		"from":      from.Format("2006-01-02"),
+		"to":        to.Format("2006-01-02"),
+		"questions": questions,
+	})
+}
+
+// CheckIntegrity reports data integrity problems in every schema
+func (h *AdminHandler) CheckIntegrity(c *gin.Context) {
+	reports, err := h.repo.CheckIntegrity(false)
+	if err != nil {
+		h.log.Errorw("Error checking data integrity", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error checking data integrity"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"reports": reports})
+}
+
+// RepairIntegrity deletes rows found by the repairable integrity checks and
+// reports what was removed
+func (h *AdminHandler) RepairIntegrity(c *gin.Context) {
+	admin := c.GetString("userEmail")
+
+	reports, err := h.repo.CheckIntegrity(true)
+	if err != nil {
+		h.log.Errorw("Error repairing data integrity", "error", err, "admin", admin)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error repairing data integrity"})
+		return
+	}
+
+	repaired := map[string]int64{}
+	for _, report := range reports {
+		for _, issue := range report.Issues {
+			if issue.Repaired > 0 {
+				repaired[issue.Check] += issue.Repaired
+			}
+		}
+	}
+	recordAudit(h.repo, h.log, c, admin, models.AuditIntegrityRepair, "", map[string]any{"repaired": repaired})
+	h.log.Infow("Repaired data integrity issues", "admin", admin, "repaired", repaired)
+
+	c.JSON(http.StatusOK, gin.H{"reports": reports})
+}
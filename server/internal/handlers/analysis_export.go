@@ -0,0 +1,586 @@
+// internal/handlers/analysis_export.go
+package handlers
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/andevellicus/crapp/internal/metrics"
+	"github.com/andevellicus/crapp/internal/repository"
+	"github.com/andevellicus/crapp/internal/storage"
+	"github.com/andevellicus/crapp/internal/utils"
+)
+
+// Analysis-ready export layouts
+const (
+	exportFormatLong = "long" // One row per response or metric
+	exportFormatWide = "wide" // One row per assessment, a column per question and metric
+)
+
+// assessmentColumns identify the assessment each exported row belongs to
+var assessmentColumns = []string{"participant", "study_id", "arm", "assessment_id", "submitted_at", "is_retrospective", "slow_device", "clock_drift", "order_scheme", "order_seed", "order_row", "form_variant"}
+
+// analysisExportKind names analysis exports in their bundle manifest
+const analysisExportKind = "analysis_export"
+
+// dictionaryHeader lists the data dictionary's columns
+var dictionaryHeader = []string{"variable", "label", "source", "question_id", "metric_key", "type", "values", "description"}
+
+// analysisExport holds the rows of a reviewer export, the question
+// definitions used to describe them, and the filters recorded in its manifest
+type analysisExport struct {
+	responses []repository.ReviewResponse
+	metrics   []repository.ReviewMetric
+	questions []utils.Question
+	filters   map[string]any
+
+	// Attachments to include, with the storage their files are read from.
+	// Nil leaves attachments out.
+	attachments []repository.ExportAttachment
+	files       storage.Storage
+}
+
+// writeZip writes the export in the given layout, with its data dictionary,
+// as a bundle of CSV files
+func (e *analysisExport) writeZip(w io.Writer, format string) error {
+	archive := utils.NewBundle(w, analysisExportKind, e.bundleFilters(format))
+
+	data, err := archive.Create(fmt.Sprintf("responses_%s.csv", format))
+	if err != nil {
+		return err
+	}
+	if format == exportFormatWide {
+		err = e.writeWide(data)
+	} else {
+		err = e.writeLong(data)
+	}
+	if err != nil {
+		return err
+	}
+
+	dictionary, err := archive.Create("data_dictionary.csv")
+	if err != nil {
+		return err
+	}
+	if err := e.writeDictionary(dictionary, format); err != nil {
+		return err
+	}
+
+	entries := []map[string]string{}
+	for _, row := range e.dictionaryRows(format) {
+		entry := make(map[string]string, len(row))
+		for i, column := range dictionaryHeader {
+			entry[column] = row[i]
+		}
+		entries = append(entries, entry)
+	}
+	archive.SetDataDictionary(entries)
+
+	if err := e.writeAttachments(archive); err != nil {
+		return err
+	}
+	return archive.Close()
+}
+
+// bundleFilters records the export's options in its manifest
+func (e *analysisExport) bundleFilters(format string) map[string]any {
+	filters := map[string]any{"format": format, "include_attachments": e.attachments != nil}
+	for key, value := range e.filters {
+		filters[key] = value
+	}
+	return filters
+}
+
+// writeAttachments adds attachments.csv, listing each attachment with the
+// assessment it belongs to, and the files themselves under
+// attachments/<assessment_id>/
+func (e *analysisExport) writeAttachments(archive *utils.Bundle) error {
+	if e.attachments == nil {
+		return nil
+	}
+
+	index, err := archive.Create("attachments.csv")
+	if err != nil {
+		return err
+	}
+	w := csv.NewWriter(index)
+	w.Write([]string{"participant", "assessment_id", "submitted_at", "file", "file_name", "content_type", "size_bytes", "sha256"})
+	files := make([]string, len(e.attachments))
+	for i, a := range e.attachments {
+		files[i] = fmt.Sprintf("attachments/%d/%s", a.AssessmentID, a.FileName)
+		w.Write([]string{a.UserEmail, strconv.FormatUint(uint64(a.AssessmentID), 10), a.SubmittedAt.UTC().Format(time.RFC3339),
+			files[i], a.FileName, a.ContentType, strconv.FormatInt(a.SizeBytes, 10), a.SHA256})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return err
+	}
+
+	for i, a := range e.attachments {
+		object, err := e.files.Get(context.Background(), a.StorageKey)
+		if err != nil {
+			return fmt.Errorf("failed to read attachment of assessment %d: %w", a.AssessmentID, err)
+		}
+		file, err := archive.Create(files[i])
+		if err == nil {
+			_, err = io.Copy(file, object)
+		}
+		object.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeFormat writes the export as a zip archive in any analysis format
+func (e *analysisExport) writeFormat(w io.Writer, format string) error {
+	if format == exportFormatParquet {
+		return e.writeParquetZip(w)
+	}
+	return e.writeZip(w, format)
+}
+
+// analysisExportFilename names an analysis export created today
+func analysisExportFilename(format string) string {
+	return fmt.Sprintf("crapp-export-%s-%s.zip", format, time.Now().Format("2006-01-02"))
+}
+
+// writeLong writes one row per response and per metric
+func (e *analysisExport) writeLong(w io.Writer) error {
+	out := csv.NewWriter(w)
+	header := append(append([]string{}, assessmentColumns...), "source", "question_id", "metric_key", "value", "changed_from_previous", "position")
+	if err := out.Write(header); err != nil {
+		return err
+	}
+
+	for _, r := range e.responses {
+		row := assessmentRow(r.ReviewAssessment)
+		row = append(row, "question", r.QuestionID, "", responseCell(r), optionalBool(r.ChangedFromPrevious), optionalInt(r.Position))
+		if err := out.Write(row); err != nil {
+			return err
+		}
+	}
+	for _, m := range e.metrics {
+		row := assessmentRow(m.ReviewAssessment)
+		row = append(row, "metric", m.QuestionID, m.MetricKey, formatFloat(m.MetricValue), "", "")
+		if err := out.Write(row); err != nil {
+			return err
+		}
+	}
+
+	out.Flush()
+	return out.Error()
+}
+
+// writeWide writes one row per assessment with a column for each question
+// answered and each metric recorded in the export
+func (e *analysisExport) writeWide(w io.Writer) error {
+	type assessmentRecord struct {
+		meta   []string
+		values map[string]string
+	}
+	records := make(map[uint]*assessmentRecord)
+	var order []uint
+	record := func(id uint, meta func() []string) *assessmentRecord {
+		if rec, ok := records[id]; ok {
+			return rec
+		}
+		rec := &assessmentRecord{meta: meta(), values: make(map[string]string)}
+		records[id] = rec
+		order = append(order, id)
+		return rec
+	}
+
+	for _, r := range e.responses {
+		rec := record(r.AssessmentID, func() []string {
+			return assessmentRow(r.ReviewAssessment)
+		})
+		rec.values[r.QuestionID] = responseCell(r)
+	}
+	for _, m := range e.metrics {
+		rec := record(m.AssessmentID, func() []string {
+			return assessmentRow(m.ReviewAssessment)
+		})
+		rec.values[metrics.ColumnName(m.QuestionID, m.MetricKey)] = formatFloat(m.MetricValue)
+	}
+
+	columns := e.wideColumns()
+	out := csv.NewWriter(w)
+	if err := out.Write(append(append([]string{}, assessmentColumns...), columns...)); err != nil {
+		return err
+	}
+	for _, id := range order {
+		rec := records[id]
+		row := rec.meta
+		for _, column := range columns {
+			row = append(row, rec.values[column])
+		}
+		if err := out.Write(row); err != nil {
+			return err
+		}
+	}
+
+	out.Flush()
+	return out.Error()
+}
+
+// wideColumns lists answered questions in questionnaire order, then each
+// metric variable found in the export
+func (e *analysisExport) wideColumns() []string {
+	answered := make(map[string]bool)
+	for _, r := range e.responses {
+		answered[r.QuestionID] = true
+	}
+
+	var columns []string
+	for _, q := range e.questions {
+		if answered[q.ID] {
+			columns = append(columns, q.ID)
+			delete(answered, q.ID)
+		}
+	}
+	// Answers to questions no longer in the questionnaire go last
+	var retired []string
+	for id := range answered {
+		retired = append(retired, id)
+	}
+	sort.Strings(retired)
+	columns = append(columns, retired...)
+
+	return append(columns, e.metricVariables()...)
+}
+
+// metricVariables lists each distinct question and metric pair, sorted
+func (e *analysisExport) metricVariables() []string {
+	seen := make(map[string]bool)
+	var variables []string
+	for _, m := range e.metrics {
+		name := metrics.ColumnName(m.QuestionID, m.MetricKey)
+		if !seen[name] {
+			seen[name] = true
+			variables = append(variables, name)
+		}
+	}
+	sort.Strings(variables)
+	return variables
+}
+
+// writeDictionary describes every variable in the export: the assessment
+// columns, each question from the question registry, and each metric
+func (e *analysisExport) writeDictionary(w io.Writer, format string) error {
+	out := csv.NewWriter(w)
+	if err := out.Write(dictionaryHeader); err != nil {
+		return err
+	}
+	if err := out.WriteAll(e.dictionaryRows(format)); err != nil {
+		return err
+	}
+	return out.Error()
+}
+
+// dictionaryRows lists the data dictionary in the order of dictionaryHeader
+func (e *analysisExport) dictionaryRows(format string) [][]string {
+	rows := [][]string{
+		{"participant", "Participant email", "assessment", "", "", "string", "", ""},
+		{"study_id", "Study the participant is enrolled in", "assessment", "", "", "string", "", ""},
+		{"arm", "Study arm", "assessment", "", "", "string", "", "Empty when the study blinds arms"},
+		{"assessment_id", "Assessment ID", "assessment", "", "", "numeric", "", ""},
+		{"submitted_at", "Submission time", "assessment", "", "", "datetime", "", "RFC 3339, UTC"},
+		{"is_retrospective", "Entered from recall for an earlier day", "assessment", "", "", "boolean", "true; false", ""},
+		{"slow_device", "Device was too slow for reliable reaction times", "assessment", "", "", "boolean", "true; false", "From performance beacons sent during cognitive tests"},
+		{"clock_drift", "Device clock drifted while the form was filled in", "assessment", "", "", "boolean", "true; false", "Cognitive test times are normalized to the server clock"},
+		{"order_scheme", "How the question order was chosen", "assessment", "", "", "string", "random; fixed; latin_square", "Empty for assessments started before the scheme was kept"},
+		{"order_seed", "Seed the question order was shuffled from", "assessment", "", "", "numeric", "", "Random orders only; see the README for how to reproduce the order"},
+		{"order_row", "Latin square row the question order used", "assessment", "", "", "numeric", "", "Latin square orders only; starts at 0"},
+		{"form_variant", "Adaptive form served", "assessment", "", "", "string", "full; short", "Short forms ask only core questions; empty when adaptive mode was off"},
+	}
+	if format == exportFormatLong {
+		rows = append(rows,
+			[]string{"source", "Row type", "long", "", "", "string", "question; metric", ""},
+			[]string{"question_id", "Question the row belongs to", "long", "", "", "string", "", "Cognitive test metrics use the test type"},
+			[]string{"metric_key", "Metric recorded", "long", "", "", "string", "", "Empty for question responses"},
+			[]string{"value", "Answer or metric value", "long", "", "", "mixed", "", "Empty when masked for blinded reviewers"},
+			[]string{"changed_from_previous", "Pre-filled answer was changed", "long", "", "", "boolean", "true; false", "Empty for questions that aren't pre-filled"},
+			[]string{"position", "Step the question was shown at", "long", "", "", "numeric", "", "Starts at 1; empty for metrics and for assessments started before the order was kept"},
+		)
+	}
+
+	for _, q := range e.questions {
+		valueType := "numeric"
+		if q.Type == "text" {
+			valueType = "string"
+		}
+		rows = append(rows, []string{q.ID, q.Title, "question", q.ID, "", valueType, optionCodes(q.Options), q.Description})
+	}
+
+	for _, name := range e.metricVariables() {
+		questionID, metricKey, _ := strings.Cut(name, "__")
+		rows = append(rows, []string{name, getMetricLabel(metricKey), "metric", questionID, metricKey, "numeric", "", ""})
+	}
+	return rows
+}
+
+// assessmentRow formats the assessment columns shared by every export row
+func assessmentRow(a repository.ReviewAssessment) []string {
+	seed := ""
+	if a.OrderSeed != nil {
+		seed = strconv.FormatInt(*a.OrderSeed, 10)
+	}
+	return []string{
+		a.UserEmail,
+		a.StudyID,
+		a.Arm,
+		strconv.FormatUint(uint64(a.AssessmentID), 10),
+		a.SubmittedAt.UTC().Format(time.RFC3339),
+		strconv.FormatBool(a.IsRetrospective),
+		strconv.FormatBool(a.SlowDevice),
+		strconv.FormatBool(a.ClockDrift),
+		a.OrderScheme,
+		seed,
+		optionalInt(a.OrderRow),
+		a.FormVariant,
+	}
+}
+
+// responseCell formats an answer, leaving masked values empty
+func responseCell(r repository.ReviewResponse) string {
+	switch {
+	case r.Masked:
+		return ""
+	case r.ValueType == "string" && r.TextValue != nil:
+		return *r.TextValue
+	case r.NumericValue != nil:
+		return formatFloat(*r.NumericValue)
+	}
+	return ""
+}
+
+// optionCodes lists a question's answer codes as "value=label" pairs
+func optionCodes(options []utils.QuestionOption) string {
+	codes := make([]string, 0, len(options))
+	for _, opt := range options {
+		codes = append(codes, fmt.Sprintf("%v=%s", opt.Value, opt.Label))
+	}
+	return strings.Join(codes, "; ")
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+func optionalBool(b *bool) string {
+	if b == nil {
+		return ""
+	}
+	return strconv.FormatBool(*b)
+}
+
+func optionalInt(i *int) string {
+	if i == nil {
+		return ""
+	}
+	return strconv.Itoa(*i)
+}
+
+// exportFormatParquet writes typed assessment, response, and metric tables
+// for pandas and other dataframe tools
+const exportFormatParquet = "parquet"
+
+// parquetTable is one Parquet file in the export with its documented columns
+type parquetTable struct {
+	Name        string                `json:"name"`
+	File        string                `json:"file"`
+	Description string                `json:"description"`
+	Columns     []parquetColumnSchema `json:"columns"`
+	columns     []utils.ParquetColumn
+	rows        [][]any
+}
+
+// parquetColumnSchema documents a column in schema.json
+type parquetColumnSchema struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Nullable    bool   `json:"nullable"`
+	Description string `json:"description"`
+}
+
+var parquetTypeNames = map[utils.ParquetType]string{
+	utils.ParquetString:    "string",
+	utils.ParquetInt64:     "int64",
+	utils.ParquetDouble:    "double",
+	utils.ParquetBool:      "bool",
+	utils.ParquetTimestamp: "timestamp[ms, UTC]",
+}
+
+// column adds a documented column to the table
+func (t *parquetTable) column(name string, typ utils.ParquetType, optional bool, description string) {
+	t.columns = append(t.columns, utils.ParquetColumn{Name: name, Type: typ, Optional: optional})
+	t.Columns = append(t.Columns, parquetColumnSchema{
+		Name:        name,
+		Type:        parquetTypeNames[typ],
+		Nullable:    optional,
+		Description: description,
+	})
+}
+
+// writeParquetZip writes the assessment, response, and metric tables as
+// Parquet files with a schema.json describing every column, as a bundle
+func (e *analysisExport) writeParquetZip(w io.Writer) error {
+	tables := []*parquetTable{e.assessmentTable(), e.responseTable(), e.metricTable()}
+
+	archive := utils.NewBundle(w, analysisExportKind, e.bundleFilters(exportFormatParquet))
+	for _, table := range tables {
+		tableSchema, err := json.Marshal(table)
+		if err != nil {
+			return err
+		}
+		file, err := archive.Create(table.File)
+		if err != nil {
+			return err
+		}
+		if err := utils.WriteParquet(file, table.columns, table.rows, map[string]string{"crapp.schema": string(tableSchema)}); err != nil {
+			return err
+		}
+	}
+
+	schema, err := archive.Create("schema.json")
+	if err != nil {
+		return err
+	}
+	doc := map[string]any{
+		"naming": "Metric columns are named <question_id>__<metric_key>. The question ID is " +
+			"\"global\" for whole-assessment metrics and the test type (cpt, tmt, digit_span) for " +
+			"cognitive test scores. Metric keys are listed under metrics and do not change between releases.",
+		"tables":  tables,
+		"metrics": metrics.All(),
+	}
+	encoder := json.NewEncoder(schema)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return err
+	}
+	archive.SetDataDictionary(doc)
+
+	if err := e.writeAttachments(archive); err != nil {
+		return err
+	}
+	return archive.Close()
+}
+
+// assessmentTable has one row per assessment in the export
+func (e *analysisExport) assessmentTable() *parquetTable {
+	t := &parquetTable{Name: "assessments", File: "assessments.parquet", Description: "One row per assessment"}
+	t.column("assessment_id", utils.ParquetInt64, false, "Assessment ID")
+	t.column("participant", utils.ParquetString, false, "Participant email")
+	t.column("study_id", utils.ParquetString, true, "Study the participant is enrolled in")
+	t.column("arm", utils.ParquetString, true, "Study arm; null when the study blinds arms")
+	t.column("submitted_at", utils.ParquetTimestamp, false, "Submission time")
+	t.column("is_retrospective", utils.ParquetBool, false, "Entered from recall for an earlier day")
+	t.column("slow_device", utils.ParquetBool, false, "Device was too slow for reliable reaction times")
+	t.column("clock_drift", utils.ParquetBool, false, "Device clock drifted while the form was filled in")
+	t.column("order_scheme", utils.ParquetString, true, "How the question order was chosen: random, fixed, or latin_square")
+	t.column("order_seed", utils.ParquetInt64, true, "Seed a random question order was shuffled from")
+	t.column("order_row", utils.ParquetInt64, true, "Latin square row a counterbalanced question order used")
+	t.column("form_variant", utils.ParquetString, true, "Adaptive form served: full or short; null when adaptive mode was off")
+
+	seen := make(map[uint]bool)
+	add := func(a repository.ReviewAssessment) {
+		if seen[a.AssessmentID] {
+			return
+		}
+		seen[a.AssessmentID] = true
+		var seed, row any
+		if a.OrderSeed != nil {
+			seed = *a.OrderSeed
+		}
+		if a.OrderRow != nil {
+			row = int64(*a.OrderRow)
+		}
+		t.rows = append(t.rows, []any{int64(a.AssessmentID), a.UserEmail, optionalString(a.StudyID), optionalString(a.Arm),
+			a.SubmittedAt, a.IsRetrospective, a.SlowDevice, a.ClockDrift, optionalString(a.OrderScheme), seed, row, optionalString(a.FormVariant)})
+	}
+	for _, r := range e.responses {
+		add(r.ReviewAssessment)
+	}
+	for _, m := range e.metrics {
+		add(m.ReviewAssessment)
+	}
+	return t
+}
+
+// responseTable has one row per answered question
+func (e *analysisExport) responseTable() *parquetTable {
+	t := &parquetTable{Name: "responses", File: "responses.parquet", Description: "One row per answered question"}
+	t.column("assessment_id", utils.ParquetInt64, false, "Assessment ID, joins to assessments")
+	t.column("question_id", utils.ParquetString, false, "Question ID from the questionnaire")
+	t.column("value_type", utils.ParquetString, false, "number, boolean, or string")
+	t.column("numeric_value", utils.ParquetDouble, true, "Answer for number and boolean questions; null when masked")
+	t.column("text_value", utils.ParquetString, true, "Answer for string questions; null when masked")
+	t.column("changed_from_previous", utils.ParquetBool, true, "Whether a pre-filled answer was changed; null for other questions")
+	t.column("position", utils.ParquetInt64, true, "1-based step the question was shown at; null for older assessments")
+
+	for _, r := range e.responses {
+		var numeric, text, changed, position any
+		if r.NumericValue != nil && r.ValueType != "string" {
+			numeric = *r.NumericValue
+		}
+		if r.TextValue != nil && r.ValueType == "string" {
+			text = *r.TextValue
+		}
+		if r.ChangedFromPrevious != nil {
+			changed = *r.ChangedFromPrevious
+		}
+		if r.Position != nil {
+			position = int64(*r.Position)
+		}
+		t.rows = append(t.rows, []any{int64(r.AssessmentID), r.QuestionID, r.ValueType, numeric, text, changed, position})
+	}
+	return t
+}
+
+// metricTable has one row per assessment and a double column per metric,
+// named by metrics.ColumnName
+func (e *analysisExport) metricTable() *parquetTable {
+	t := &parquetTable{Name: "metrics", File: "metrics.parquet", Description: "One row per assessment with a column per metric"}
+	t.column("assessment_id", utils.ParquetInt64, false, "Assessment ID, joins to assessments")
+
+	columns := e.metricVariables()
+	index := make(map[string]int, len(columns))
+	for i, name := range columns {
+		index[name] = i + 1
+		questionID, metricKey, _ := strings.Cut(name, "__")
+		t.column(name, utils.ParquetDouble, true, fmt.Sprintf("%s for %s", metrics.Label(metricKey), questionID))
+	}
+
+	rows := make(map[uint][]any)
+	var order []uint
+	for _, m := range e.metrics {
+		row, ok := rows[m.AssessmentID]
+		if !ok {
+			row = make([]any, len(columns)+1)
+			row[0] = int64(m.AssessmentID)
+			rows[m.AssessmentID] = row
+			order = append(order, m.AssessmentID)
+		}
+		row[index[metrics.ColumnName(m.QuestionID, m.MetricKey)]] = m.MetricValue
+	}
+	for _, id := range order {
+		t.rows = append(t.rows, rows[id])
+	}
+	return t
+}
+
+func optionalString(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
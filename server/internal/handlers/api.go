@@ -3,6 +3,7 @@ package handlers
 import (
 	"net/http"
 
+	"github.com/andevellicus/crapp/internal/config"
 	"github.com/andevellicus/crapp/internal/repository"
 	"github.com/andevellicus/crapp/internal/utils"
 	"github.com/gin-gonic/gin"
@@ -13,26 +14,55 @@ import (
 type GinAPIHandler struct {
 	repo           *repository.Repository
 	questionLoader *utils.QuestionLoader
+	questions      *utils.QuestionRegistry
+	privacy        *config.PrivacyConfig
 	log            *zap.SugaredLogger
 }
 
 // NewAPIHandler creates a new API handler for Gin
-func NewAPIHandler(repo *repository.Repository, log *zap.SugaredLogger, questionLoader *utils.QuestionLoader) *GinAPIHandler {
+func NewAPIHandler(repo *repository.Repository, log *zap.SugaredLogger, questions *utils.QuestionRegistry, privacy *config.PrivacyConfig) *GinAPIHandler {
 	return &GinAPIHandler{
 		repo:           repo,
-		questionLoader: questionLoader,
+		questionLoader: questions.Default(),
+		questions:      questions,
+		privacy:        privacy,
 		log:            log.Named("api"),
 	}
 }
 
-// GetQuestions returns all questions
+// GetQuestions returns all questions for the user's organization
 func (h *GinAPIHandler) GetQuestions(c *gin.Context) {
-	questions := h.questionLoader.GetQuestions()
-	c.JSON(http.StatusOK, questions)
+	userEmail := c.GetString("userEmail")
+	loader := questionsForUser(h.repo, h.questions, h.log, userEmail)
+	if notModified(c, weakETag("questions", loader.Checksum)) {
+		return
+	}
+	c.JSON(http.StatusOK, loader.GetQuestions())
 }
 
 // GetSymptomQuestions returns only the symptom questions (radio type)
 func (h *GinAPIHandler) GetSymptomQuestions(c *gin.Context) {
-	questions := h.questionLoader.GetRadioQuestions()
-	c.JSON(http.StatusOK, questions)
+	userEmail := c.GetString("userEmail")
+	loader := questionsForUser(h.repo, h.questions, h.log, userEmail)
+	if notModified(c, weakETag("symptom-questions", loader.Checksum)) {
+		return
+	}
+	c.JSON(http.StatusOK, loader.GetRadioQuestions())
+}
+
+// GetQuestionHelp returns a question's help text, example images and
+// instruction videos. Help is also included with each question; this serves
+// it on its own for screens that load it when asked.
+func (h *GinAPIHandler) GetQuestionHelp(c *gin.Context) {
+	userEmail := c.GetString("userEmail")
+	loader := questionsForUser(h.repo, h.questions, h.log, userEmail)
+	question := loader.GetQuestionByID(c.Param("id"))
+	if question == nil || question.Help == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No help for this question"})
+		return
+	}
+	if notModified(c, weakETag("question-help", question.ID, loader.Checksum)) {
+		return
+	}
+	c.JSON(http.StatusOK, question.Help)
 }
@@ -0,0 +1,345 @@
+// internal/handlers/attachment.go
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/andevellicus/crapp/internal/config"
+	"github.com/andevellicus/crapp/internal/models"
+	"github.com/andevellicus/crapp/internal/repository"
+	"github.com/andevellicus/crapp/internal/storage"
+	"github.com/andevellicus/crapp/internal/upload"
+	"github.com/andevellicus/crapp/internal/utils"
+	"github.com/andevellicus/crapp/internal/validation"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// attachmentSweepBatch caps how many orphaned attachments are removed per sweep
+const attachmentSweepBatch = 200
+
+// attachmentView is an attachment as returned by the API
+type attachmentView struct {
+	*models.AssessmentAttachment
+	HasThumbnail bool `json:"has_thumbnail"`
+}
+
+// AttachmentHandler handles the optional photo or document attached to an
+// assessment. Files are only ever streamed through these authenticated
+// routes, never through signed links, so every download is authorized.
+type AttachmentHandler struct {
+	repo     *repository.Repository
+	uploads  *upload.Pipeline
+	store    storage.Storage
+	cfg      *config.AttachmentsConfig
+	log      *zap.SugaredLogger
+	stopChan chan struct{}
+}
+
+// NewAttachmentHandler creates a new attachment handler. Uploads go through
+// the pipeline with the attachment size and type limits.
+func NewAttachmentHandler(repo *repository.Repository, log *zap.SugaredLogger, uploads *upload.Pipeline, store storage.Storage, cfg *config.AttachmentsConfig) *AttachmentHandler {
+	return &AttachmentHandler{
+		repo:     repo,
+		uploads:  uploads.WithLimits(cfg.MaxBytes, cfg.AllowedTypes),
+		store:    store,
+		cfg:      cfg,
+		log:      log.Named("attachment"),
+		stopChan: make(chan struct{}),
+	}
+}
+
+// AssessmentOwner is the middleware.OwnerFunc for attachment routes. Admins
+// name the participant with ?email= so the assessment is found in their
+// organization's data.
+func (h *AttachmentHandler) AssessmentOwner(c *gin.Context) (string, error) {
+	id, err := strconv.ParseUint(c.Param("assessmentId"), 10, 64)
+	if err != nil {
+		return "", err
+	}
+	email := strings.ToLower(c.Query("email"))
+	if email == "" {
+		email = c.GetString("userEmail")
+	}
+	assessment, err := h.repo.ForUser(email).Assessments.GetByID(uint(id))
+	if err != nil {
+		return "", err
+	}
+	c.Set("assessment", assessment)
+	return assessment.UserEmail, nil
+}
+
+// Upload attaches a photo or document, sent as a multipart "file" field, to
+// one of the user's assessments, replacing any earlier attachment. Set
+// "exclude_from_exports" to keep it out of analysis exports.
+func (h *AttachmentHandler) Upload(c *gin.Context) {
+	assessment := c.MustGet("assessment").(*models.Assessment)
+	if h.store == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Attachment storage is not configured"})
+		return
+	}
+
+	user, err := h.repo.Users.GetByEmail(assessment.UserEmail)
+	if err != nil || user == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	if user.WithdrawnAt != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Withdrawn from the study; no further data is accepted"})
+		return
+	}
+
+	// Allow room for the multipart headers; the pipeline checks the file itself
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.cfg.MaxBytes+1<<20)
+	file, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "file is required and must fit the size limit"})
+		return
+	}
+	opened, err := file.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unable to read uploaded file"})
+		return
+	}
+	defer opened.Close()
+
+	ctx := c.Request.Context()
+	accepted, err := h.uploads.Accept(ctx, "attachments", file.Filename, opened)
+	if err != nil {
+		rejectUpload(c, h.log, err)
+		return
+	}
+	defer accepted.Close()
+
+	key, err := h.uploads.Store(ctx, "attachments", accepted)
+	if err != nil {
+		h.log.Errorw("Error storing attachment", "error", err, "assessment", assessment.ID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error storing attachment"})
+		return
+	}
+
+	attachment := &models.AssessmentAttachment{
+		AssessmentID:       assessment.ID,
+		UserEmail:          assessment.UserEmail,
+		FileName:           accepted.Name,
+		ContentType:        accepted.ContentType,
+		SizeBytes:          accepted.Size,
+		SHA256:             accepted.SHA256,
+		StorageKey:         key,
+		ThumbnailKey:       h.storeThumbnail(ctx, key, accepted),
+		ExcludeFromExports: c.PostForm("exclude_from_exports") == "true",
+	}
+	previous, err := h.repo.ForUser(assessment.UserEmail).Attachments.Replace(attachment)
+	if err != nil {
+		h.deleteFiles(ctx, attachment)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error saving attachment"})
+		return
+	}
+	if previous != nil {
+		h.deleteFiles(ctx, previous)
+	}
+
+	h.log.Infow("Attachment uploaded", "assessment", assessment.ID, "type", attachment.ContentType, "size", attachment.SizeBytes)
+	c.JSON(http.StatusCreated, attachmentView{attachment, attachment.ThumbnailKey != ""})
+}
+
+// GetAttachment describes an assessment's attachment
+func (h *AttachmentHandler) GetAttachment(c *gin.Context) {
+	attachment, ok := h.find(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, attachmentView{attachment, attachment.ThumbnailKey != ""})
+}
+
+// DownloadFile streams an assessment's attachment
+func (h *AttachmentHandler) DownloadFile(c *gin.Context) {
+	attachment, ok := h.find(c)
+	if !ok {
+		return
+	}
+	h.stream(c, attachment.StorageKey, attachment.ContentType, attachment.FileName)
+}
+
+// DownloadThumbnail streams the thumbnail of an image attachment
+func (h *AttachmentHandler) DownloadThumbnail(c *gin.Context) {
+	attachment, ok := h.find(c)
+	if !ok {
+		return
+	}
+	if attachment.ThumbnailKey == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Attachment has no thumbnail"})
+		return
+	}
+	h.stream(c, attachment.ThumbnailKey, "image/jpeg", "")
+}
+
+// DeleteAttachment removes an assessment's attachment
+func (h *AttachmentHandler) DeleteAttachment(c *gin.Context) {
+	attachment, ok := h.find(c)
+	if !ok {
+		return
+	}
+	if err := h.repo.ForUser(attachment.UserEmail).Attachments.Delete(attachment.ID); err != nil {
+		h.log.Errorw("Error deleting attachment", "error", err, "id", attachment.ID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error deleting attachment"})
+		return
+	}
+	h.deleteFiles(c.Request.Context(), attachment)
+	c.JSON(http.StatusOK, gin.H{"message": "Attachment deleted"})
+}
+
+// SetExportFlag includes an attachment in analysis exports or leaves it out
+func (h *AttachmentHandler) SetExportFlag(c *gin.Context) {
+	req := c.MustGet("validatedRequest").(*validation.AttachmentExportsRequest)
+	attachment, ok := h.find(c)
+	if !ok {
+		return
+	}
+	if err := h.repo.ForUser(attachment.UserEmail).Attachments.SetExcludeFromExports(attachment.ID, *req.ExcludeFromExports); err != nil {
+		h.log.Errorw("Error updating attachment export flag", "error", err, "id", attachment.ID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error updating attachment"})
+		return
+	}
+	attachment.ExcludeFromExports = *req.ExcludeFromExports
+
+	h.log.Infow("Attachment export flag changed", "id", attachment.ID, "by", c.GetString("userEmail"), "exclude", attachment.ExcludeFromExports)
+	c.JSON(http.StatusOK, attachmentView{attachment, attachment.ThumbnailKey != ""})
+}
+
+// find loads the attachment of the assessment set by AssessmentOwner,
+// answering 404 when there is none
+func (h *AttachmentHandler) find(c *gin.Context) (*models.AssessmentAttachment, bool) {
+	assessment := c.MustGet("assessment").(*models.Assessment)
+	attachment, err := h.repo.ForUser(assessment.UserEmail).Attachments.GetForAssessment(assessment.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return nil, false
+	}
+	if attachment == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Assessment has no attachment"})
+		return nil, false
+	}
+	return attachment, true
+}
+
+// stream copies a stored file to the response. Files are served as
+// downloads, with a filename, or inline for thumbnails.
+func (h *AttachmentHandler) stream(c *gin.Context, key, contentType, filename string) {
+	if h.store == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Attachment storage is not configured"})
+		return
+	}
+	object, err := h.store.Get(c.Request.Context(), key)
+	if err != nil {
+		h.log.Errorw("Error opening attachment", "error", err, "key", key)
+		c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+		return
+	}
+	defer object.Close()
+
+	if requester := c.GetString("userEmail"); requester != c.GetString("resourceOwner") {
+		h.log.Infow("Attachment viewed by staff", "viewer", requester, "owner", c.GetString("resourceOwner"), "key", key)
+	}
+
+	c.Header("Content-Type", contentType)
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Header("Cache-Control", "private, no-store")
+	if filename != "" {
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	}
+	if _, err := io.Copy(c.Writer, object); err != nil {
+		h.log.Errorw("Error streaming attachment", "error", err, "key", key)
+	}
+}
+
+// storeThumbnail saves a thumbnail of an image attachment next to it and
+// returns its key. Documents, and images that fail to decode, get none.
+func (h *AttachmentHandler) storeThumbnail(ctx context.Context, key string, file *upload.File) string {
+	if !strings.HasPrefix(file.ContentType, "image/") {
+		return ""
+	}
+	reader, err := file.Reader()
+	if err != nil {
+		return ""
+	}
+	thumbnail, err := utils.Thumbnail(reader, h.cfg.ThumbnailWidth)
+	if err != nil {
+		h.log.Warnw("Could not create attachment thumbnail", "error", err, "key", key)
+		return ""
+	}
+	thumbnailKey := key + ".thumb.jpg"
+	if err := h.store.Put(ctx, thumbnailKey, bytes.NewReader(thumbnail), "image/jpeg"); err != nil {
+		h.log.Warnw("Could not store attachment thumbnail", "error", err, "key", key)
+		return ""
+	}
+	return thumbnailKey
+}
+
+// deleteFiles removes an attachment's file and thumbnail from storage
+func (h *AttachmentHandler) deleteFiles(ctx context.Context, attachment *models.AssessmentAttachment) {
+	for _, key := range []string{attachment.StorageKey, attachment.ThumbnailKey} {
+		if key == "" {
+			continue
+		}
+		if err := h.store.Delete(ctx, key); err != nil {
+			h.log.Warnw("Failed to delete attachment file", "error", err, "key", key)
+		}
+	}
+}
+
+// Start launches the sweep that removes the files of attachments whose
+// assessments were deleted without them. Erasing an account removes its
+// files itself; the sweep catches anything left behind.
+func (h *AttachmentHandler) Start() {
+	if h.store == nil {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(time.Hour)
+		defer ticker.Stop()
+
+		for {
+			h.sweep()
+
+			select {
+			case <-ticker.C:
+			case <-h.stopChan:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the sweep
+func (h *AttachmentHandler) Stop() {
+	close(h.stopChan)
+}
+
+// sweep removes orphaned attachments in every organization's data
+func (h *AttachmentHandler) sweep() {
+	ctx := context.Background()
+	for _, repo := range h.repo.DataRepositories() {
+		orphans, err := repo.Attachments.ListOrphaned(attachmentSweepBatch)
+		if err != nil {
+			h.log.Errorw("Error listing orphaned attachments", "error", err)
+			continue
+		}
+		for i := range orphans {
+			h.deleteFiles(ctx, &orphans[i])
+			if err := repo.Attachments.Delete(orphans[i].ID); err != nil {
+				h.log.Errorw("Error deleting orphaned attachment", "error", err, "id", orphans[i].ID)
+			}
+		}
+		if len(orphans) > 0 {
+			h.log.Infow("Removed attachments of deleted assessments", "count", len(orphans))
+		}
+	}
+}
@@ -2,24 +2,35 @@
 package handlers
 
 import (
+	"encoding/json"
+	"errors"
 	"net/http"
 	"strings"
 	"time"
 
 	"github.com/andevellicus/crapp/internal/models"
 	"github.com/andevellicus/crapp/internal/repository"
+	"github.com/andevellicus/crapp/internal/scheduler"
 	"github.com/andevellicus/crapp/internal/services"
+	"github.com/andevellicus/crapp/internal/utils"
 	"github.com/andevellicus/crapp/internal/validation"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"go.uber.org/zap"
 	"golang.org/x/crypto/bcrypt"
 )
 
 // AuthHandler handles authentication-related endpoints
 type AuthHandler struct {
-	repo        *repository.Repository
-	log         *zap.SugaredLogger
-	authService *services.AuthService
+	repo          *repository.Repository
+	log           *zap.SugaredLogger
+	authService   *services.AuthService
+	legalService  *services.LegalService
+	loginSecurity *services.LoginSecurityService
+	signupGuard   *services.RegistrationGuard
+	sanitizer     *utils.Sanitizer
+	demo          *services.DemoService // Set in demo mode only
+	reminders     *scheduler.ReminderScheduler
 }
 
 // AuthResponse represents the response for login/register
@@ -34,11 +45,25 @@ type AuthResponse struct {
 }
 
 // NewAuthHandler creates a new authentication handler
-func NewAuthHandler(repo *repository.Repository, log *zap.SugaredLogger, authService *services.AuthService) *AuthHandler {
+func NewAuthHandler(repo *repository.Repository,
+	log *zap.SugaredLogger,
+	authService *services.AuthService,
+	legalService *services.LegalService,
+	loginSecurity *services.LoginSecurityService,
+	signupGuard *services.RegistrationGuard,
+	sanitizer *utils.Sanitizer,
+	demo *services.DemoService,
+	reminders *scheduler.ReminderScheduler) *AuthHandler {
 	return &AuthHandler{
-		repo:        repo,
-		log:         log.Named("auth"),
-		authService: authService,
+		repo:          repo,
+		log:           log.Named("auth"),
+		authService:   authService,
+		legalService:  legalService,
+		loginSecurity: loginSecurity,
+		signupGuard:   signupGuard,
+		sanitizer:     sanitizer,
+		demo:          demo,
+		reminders:     reminders,
 	}
 }
 
@@ -62,6 +87,29 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		return
 	}
 
+	// Screen for disposable emails, signup floods, and bots
+	if err := h.signupGuard.Check(email, c.ClientIP(), req.CaptchaToken); err != nil {
+		var rejection *services.SignupRejection
+		errors.As(err, &rejection)
+
+		switch {
+		case rejection != nil && rejection.Reason == services.SignupReasonIPLimit:
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many accounts created from this network. Try again later."})
+		case rejection != nil && rejection.Reason == services.SignupReasonDisposableEmail:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Please use a permanent email address"})
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Verification failed. Please try again."})
+		}
+		return
+	}
+
+	if req.OrganizationID != "" {
+		if _, err := h.repo.Organizations.Get(req.OrganizationID); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown organization"})
+			return
+		}
+	}
+
 	// Hash password
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 	if err != nil {
@@ -72,13 +120,15 @@ func (h *AuthHandler) Register(c *gin.Context) {
 
 	// Create user
 	newUser := &models.User{
-		Email:     email,
-		Password:  hashedPassword,
-		FirstName: req.FirstName,
-		LastName:  req.LastName,
-		IsAdmin:   false, // Default to non-admin
-		CreatedAt: time.Now(),
-		LastLogin: time.Now(),
+		Email:          email,
+		Password:       hashedPassword,
+		FirstName:      req.FirstName,
+		LastName:       req.LastName,
+		OrganizationID: strings.ToLower(req.OrganizationID),
+		IsAdmin:        false, // Default to non-admin
+		CreatedAt:      time.Now(),
+		LastLogin:      time.Now(),
+		Sandbox:        h.demo != nil, // Demo sign-ups are purged nightly
 	}
 
 	// Save user to database
@@ -88,6 +138,28 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		return
 	}
 
+	// Give demo sign-ups some history to explore
+	if h.demo != nil {
+		if err := h.demo.Seed(newUser.Email); err != nil {
+			h.log.Warnw("Error seeding demo account", "error", err)
+		}
+	}
+
+	h.signupGuard.RecordAccepted(newUser.Email, c.ClientIP())
+
+	// Record the documents accepted on the sign-up form
+	for documentType, version := range map[string]string{
+		models.PolicyTerms:   req.TermsVersion,
+		models.PolicyPrivacy: req.PrivacyVersion,
+	} {
+		if version == "" {
+			continue
+		}
+		if err := h.legalService.Accept(newUser.Email, documentType, version, c.ClientIP(), c.Request.UserAgent()); err != nil {
+			h.log.Warnw("Error recording policy acceptance at registration", "error", err, "document", documentType)
+		}
+	}
+
 	if emailService, exists := c.Get("emailService"); exists && emailService != nil {
 		go emailService.(*services.EmailService).SendWelcomeEmail(newUser.Email, newUser.FirstName)
 	}
@@ -103,13 +175,22 @@ func (h *AuthHandler) Register(c *gin.Context) {
 	})
 }
 
+// GetRegistrationConfig returns the public settings the sign-up form needs
+func (h *AuthHandler) GetRegistrationConfig(c *gin.Context) {
+	provider, siteKey := h.signupGuard.CaptchaConfig()
+	c.JSON(http.StatusOK, gin.H{
+		"captcha_provider": provider,
+		"captcha_site_key": siteKey,
+	})
+}
+
 // Login handles user login
 func (h *AuthHandler) Login(c *gin.Context) {
 	req := c.MustGet("validatedRequest").(*validation.LoginRequest)
 
 	email := strings.ToLower(req.Email)
 
-	user, device, tokenPair, err := h.authService.Authenticate(email, req.Password, req.DeviceInfo)
+	user, err := h.authService.VerifyCredentials(email, req.Password)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid email or password"})
 		h.log.Warnw("Error during authentication", "error", err, "email", email)
@@ -119,59 +200,201 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "User does not exist"})
 		return
 	}
-	if device == nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Error registering device"})
+
+	h.sanitizeDeviceInfo(req.DeviceInfo)
+	remember := req.RememberMe == nil || *req.RememberMe
+
+	if h.holdPausedLogin(c, user) {
 		return
 	}
-	if tokenPair == nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Error generating token pair"})
+
+	// Hold suspicious logins until the user confirms them by email
+	risk := h.loginSecurity.Assess(email, c.ClientIP())
+	if risk.Suspicious && h.loginSecurity.RequiresConfirmation() {
+		if h.challengeLogin(c, user, req.DeviceInfo, remember, risk) {
+			return
+		}
+		// Without email there is no way to confirm, so let the login through flagged
+	}
+
+	h.completeLogin(c, user, req.DeviceInfo, remember, risk, nil)
+}
+
+// ConfirmLogin completes a login that was held for email confirmation
+func (h *AuthHandler) ConfirmLogin(c *gin.Context) {
+	req := c.MustGet("validatedRequest").(*validation.ConfirmLoginRequest)
+
+	challenge, err := h.repo.LoginChallenges.Consume(req.Token)
+	if err != nil {
+		h.log.Warnw("Invalid login confirmation token", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired confirmation link"})
 		return
 	}
 
-	// Get cookie settings
-	cookieConfig := h.authService.GetCookieConfig()
+	user, err := h.repo.Users.GetByEmail(challenge.UserEmail)
+	if err != nil || user == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired confirmation link"})
+		return
+	}
+	if h.holdPausedLogin(c, user) {
+		return
+	}
 
-	// Set auth token cookie
-	c.SetCookie(
-		"auth_token",
-		tokenPair.AccessToken,
-		tokenPair.ExpiresIn,
-		cookieConfig.Path,
-		cookieConfig.Domain,
-		cookieConfig.Secure,
-		cookieConfig.HttpOnly,
-	)
+	var deviceInfo map[string]any
+	if challenge.DeviceInfo != "" {
+		if err := json.Unmarshal([]byte(challenge.DeviceInfo), &deviceInfo); err != nil {
+			h.log.Warnw("Error decoding challenged device info", "error", err)
+		}
+	}
 
-	// Set refresh token cookie - longer expiration
-	// Convert refresh token TTL from days to seconds
-	refreshExpiresIn := h.authService.JWTConfig.RefreshExpires * 24 * 60 * 60
-	c.SetCookie(
-		"refresh_token",
-		tokenPair.RefreshToken,
-		refreshExpiresIn,
-		cookieConfig.Path,
-		cookieConfig.Domain,
-		cookieConfig.Secure,
-		cookieConfig.HttpOnly,
-	)
+	// Re-locate the original attempt so the timeline keeps its location
+	risk := h.loginSecurity.Assess(challenge.UserEmail, challenge.IPAddress)
+	risk.Suspicious = true
+	risk.Reasons = strings.Split(challenge.Reasons, ",")
+
+	h.completeLogin(c, user, deviceInfo, challenge.RememberMe, risk, map[string]any{"confirmed_by_email": true})
+}
+
+// reactivationTTL is how long an emailed reactivation link stays valid
+const reactivationTTL = 24 * time.Hour
+
+// holdPausedLogin stops a login to a paused account and emails a link to
+// reactivate it, returning true if the login was held. Without email there
+// is no way to send the link, so the account is reactivated and the login
+// continues.
+func (h *AuthHandler) holdPausedLogin(c *gin.Context, user *models.User) bool {
+	if user.DeactivatedAt == nil {
+		return false
+	}
+
+	emailService, exists := c.Get("emailService")
+	if !exists || emailService == nil || emailService.(*services.EmailService) == nil {
+		h.log.Warnw("Email service not available, reactivating paused account on login", "email", user.Email)
+		if err := h.repo.Users.SetPaused(user.Email, false); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error reactivating account"})
+			return true
+		}
+		recordAudit(h.repo, h.log, c, user.Email, models.AuditAccountResumed, "", nil)
+		return false
+	}
+
+	token, err := h.repo.ReactivationTokens.Create(user.Email, reactivationTTL)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error sending reactivation link"})
+		return true
+	}
+	if err := emailService.(*services.EmailService).SendReactivationEmail(user.Email, user.FirstName, token.Token); err != nil {
+		h.log.Errorw("Failed to send reactivation email", "error", err, "email", user.Email)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error sending reactivation link"})
+		return true
+	}
+
+	c.JSON(http.StatusForbidden, gin.H{
+		"account_paused": true,
+		"error":          "This account is paused. We sent a link to your email to reactivate it.",
+	})
+	return true
+}
 
-	// Also set the device ID in a cookie (not httpOnly)
+// ReactivateAccount resumes a paused account from the emailed link. The
+// user then logs in as usual.
+func (h *AuthHandler) ReactivateAccount(c *gin.Context) {
+	req := c.MustGet("validatedRequest").(*validation.ReactivateAccountRequest)
+
+	token, err := h.repo.ReactivationTokens.Consume(req.Token)
+	if err != nil {
+		h.log.Warnw("Invalid reactivation token", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired reactivation link"})
+		return
+	}
+	if err := h.repo.Users.SetPaused(token.UserEmail, false); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error reactivating account"})
+		return
+	}
+	recordAudit(h.repo, h.log, c, token.UserEmail, models.AuditAccountResumed, "", nil)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Your account is active again. Please log in."})
+}
+
+// challengeLogin emails a confirmation link for a suspicious login. Returns
+// false if the challenge could not be sent.
+func (h *AuthHandler) challengeLogin(c *gin.Context, user *models.User, deviceInfo map[string]any, remember bool, risk *services.LoginRisk) bool {
+	emailService, exists := c.Get("emailService")
+	if !exists || emailService == nil || emailService.(*services.EmailService) == nil {
+		h.log.Warnw("Email service not available, cannot confirm suspicious login", "email", user.Email)
+		return false
+	}
+
+	deviceJSON, _ := json.Marshal(deviceInfo)
+	challenge := &models.LoginChallenge{
+		Token:      uuid.NewString(),
+		UserEmail:  user.Email,
+		DeviceInfo: string(deviceJSON),
+		IPAddress:  c.ClientIP(),
+		Reasons:    strings.Join(risk.Reasons, ","),
+		RememberMe: remember,
+		ExpiresAt:  time.Now().Add(h.loginSecurity.ConfirmationTTL()),
+	}
+	if err := h.repo.LoginChallenges.Create(challenge); err != nil {
+		return false
+	}
+
+	location := risk.Location.Country
+	if risk.Location.City != "" {
+		location = risk.Location.City + ", " + location
+	}
+	if err := emailService.(*services.EmailService).SendLoginConfirmationEmail(user.Email, user.FirstName, location, challenge.Token); err != nil {
+		h.log.Errorw("Failed to send login confirmation email", "error", err, "email", user.Email)
+		return false
+	}
+
+	recordLoginAudit(h.repo, h.log, c, user.Email, models.AuditLoginChallenged, "", risk, nil)
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"confirmation_required": true,
+		"message":               "We sent a confirmation link to your email to verify this sign-in",
+	})
+	return true
+}
+
+// completeLogin issues tokens, sets the auth cookies, and records the login
+func (h *AuthHandler) completeLogin(c *gin.Context, user *models.User, deviceInfo map[string]any, remember bool, risk *services.LoginRisk, details map[string]any) {
+	device, tokenPair, err := h.authService.CompleteLogin(user, deviceInfo, remember)
+	if err != nil {
+		h.log.Errorw("Error completing login", "error", err, "email", user.Email)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Error registering device"})
+		return
+	}
+	h.recordTimezone(user.Email, device)
+
+	h.setTokenCookies(c, tokenPair)
+	// Also set the device ID in a cookie (not httpOnly), with the same
+	// lifespan as the refresh token
+	cookieConfig := h.authService.GetCookieConfig()
 	c.SetCookie(
 		"device_id",
 		device.ID,
-		refreshExpiresIn, // Same lifespan as refresh token
+		cookieMaxAge(tokenPair, tokenPair.RefreshExpiresIn),
 		cookieConfig.Path,
 		cookieConfig.Domain,
 		cookieConfig.Secure,
 		false, // Not HttpOnly so JS can access
 	)
 
+	if details == nil {
+		details = map[string]any{}
+	}
+	details["device_name"] = device.DeviceName
+	details["device_type"] = device.DeviceType
+	recordLoginAudit(h.repo, h.log, c, user.Email, models.AuditLogin, device.ID, risk, details)
+
 	// Return response without tokens
 	c.JSON(http.StatusOK, gin.H{
 		"message":    "Login successful",
 		"user":       *user,
 		"device_id":  device.ID,
 		"expires_in": tokenPair.ExpiresIn,
+		"remembered": tokenPair.Remembered,
 	})
 }
 
@@ -212,6 +435,8 @@ func (h *AuthHandler) Logout(c *gin.Context) {
 	c.SetCookie("refresh_token", "", -1, cookieConfig.Path, cookieConfig.Domain, cookieConfig.Secure, cookieConfig.HttpOnly)
 	//c.SetCookie("device_id", "", -1, cookieConfig.Path, cookieConfig.Domain, cookieConfig.Secure, false)
 
+	recordAudit(h.repo, h.log, c, userEmail.(string), models.AuditLogout, "", nil)
+
 	h.log.Infow("Logout successful", "userEmail", userEmail)
 	c.JSON(http.StatusOK, gin.H{"message": "Successfully logged out"})
 }
@@ -236,39 +461,93 @@ func (h *AuthHandler) RefreshToken(c *gin.Context) {
 	tokenPair, err := h.authService.RefreshToken(refreshToken, deviceID)
 	if err != nil {
 		h.log.Warnw("Token refresh failed", "error", err)
+		if errors.Is(err, services.ErrSessionExpired) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Session expired, please log in again"})
+			return
+		}
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired refresh token"})
 		return
 	}
 
-	// Get cookie settings
+	h.setTokenCookies(c, tokenPair)
+
+	// Return success
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Token refreshed successfully",
+		"expires_in": tokenPair.ExpiresIn,
+	})
+}
+
+// Reauthenticate confirms the current user's password and replaces their
+// access token with one that allows sensitive actions for a few minutes
+func (h *AuthHandler) Reauthenticate(c *gin.Context) {
+	req := c.MustGet("validatedRequest").(*validation.ReauthRequest)
+	email := c.GetString("userEmail")
+
+	accessToken, expiresIn, remembered, err := h.authService.Reauthenticate(email, c.GetString("tokenID"), req.Password)
+	if errors.Is(err, services.ErrReauthUnavailable) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Please log in again to continue"})
+		return
+	}
+	if err != nil {
+		h.log.Warnw("Re-authentication failed", "error", err, "email", email)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Incorrect password"})
+		return
+	}
+
+	maxAge := expiresIn
+	if !remembered {
+		maxAge = 0
+	}
 	cookieConfig := h.authService.GetCookieConfig()
+	c.SetCookie(
+		"auth_token",
+		accessToken,
+		maxAge,
+		cookieConfig.Path,
+		cookieConfig.Domain,
+		cookieConfig.Secure,
+		cookieConfig.HttpOnly,
+	)
 
-	// Set new cookies
+	recordAudit(h.repo, h.log, c, email, models.AuditReauthenticated, "", nil)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "Password confirmed",
+		"reauth_minutes": h.authService.JWTConfig.ReauthMinutes,
+		"expires_in":     expiresIn,
+	})
+}
+
+// setTokenCookies sets the auth and refresh token cookies. Sessions that
+// aren't remembered get browser session cookies.
+func (h *AuthHandler) setTokenCookies(c *gin.Context, tokenPair *services.TokenPair) {
+	cookieConfig := h.authService.GetCookieConfig()
 	c.SetCookie(
 		"auth_token",
 		tokenPair.AccessToken,
-		tokenPair.ExpiresIn,
+		cookieMaxAge(tokenPair, tokenPair.ExpiresIn),
 		cookieConfig.Path,
 		cookieConfig.Domain,
 		cookieConfig.Secure,
 		cookieConfig.HttpOnly,
 	)
-
-	// Set refresh token cookie - longer expiration
-	refreshExpiresIn := h.authService.JWTConfig.RefreshExpires * 24 * 60 * 60
 	c.SetCookie(
 		"refresh_token",
 		tokenPair.RefreshToken,
-		refreshExpiresIn,
+		cookieMaxAge(tokenPair, tokenPair.RefreshExpiresIn),
 		cookieConfig.Path,
 		cookieConfig.Domain,
 		cookieConfig.Secure,
 		cookieConfig.HttpOnly,
 	)
+}
 
-	// Return success
-	c.JSON(http.StatusOK, gin.H{
-		"message":    "Token refreshed successfully",
-		"expires_in": tokenPair.ExpiresIn,
-	})
+// cookieMaxAge returns a cookie's max age in seconds, or 0 for a browser
+// session cookie when the session isn't remembered
+func cookieMaxAge(tokenPair *services.TokenPair, seconds int) int {
+	if !tokenPair.Remembered {
+		return 0
+	}
+	return seconds
 }
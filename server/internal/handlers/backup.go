@@ -0,0 +1,72 @@
+// internal/handlers/backup.go
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/andevellicus/crapp/internal/services"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// BackupHandler lets admins list, trigger, and verify database backups
+type BackupHandler struct {
+	backups *services.BackupService
+	log     *zap.SugaredLogger
+}
+
+// NewBackupHandler creates a new backup handler
+func NewBackupHandler(backups *services.BackupService, log *zap.SugaredLogger) *BackupHandler {
+	return &BackupHandler{
+		backups: backups,
+		log:     log.Named("backup"),
+	}
+}
+
+// ListBackups returns stored backups, newest first
+func (h *BackupHandler) ListBackups(c *gin.Context) {
+	manifests, err := h.backups.List(c.Request.Context())
+	if err != nil {
+		h.log.Errorw("Error listing backups", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error listing backups"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"backups": manifests})
+}
+
+// TriggerBackup starts a backup in the background. Dumps can take a while, so
+// the result shows up in the list once it finishes.
+func (h *BackupHandler) TriggerBackup(c *gin.Context) {
+	admin := c.GetString("userEmail")
+
+	err := h.backups.Start(services.BackupManual, func(manifest *services.BackupManifest, err error) {
+		if err != nil {
+			h.log.Errorw("Manual backup failed", "admin", admin, "error", err)
+			return
+		}
+		h.log.Infow("Manual backup finished", "admin", admin, "backup", manifest.Name, "verified", manifest.Verified)
+	})
+	if errors.Is(err, services.ErrBackupRunning) {
+		c.JSON(http.StatusConflict, gin.H{"error": "A backup is already running"})
+		return
+	}
+
+	h.log.Infow("Manual backup requested", "admin", admin)
+	c.JSON(http.StatusAccepted, gin.H{"message": "Backup started"})
+}
+
+// VerifyBackup downloads a backup and checks it again
+func (h *BackupHandler) VerifyBackup(c *gin.Context) {
+	manifest, err := h.backups.Verify(c.Request.Context(), c.Param("name"))
+	if errors.Is(err, services.ErrBackupNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Backup not found"})
+		return
+	}
+	if err != nil {
+		h.log.Errorw("Error verifying backup", "backup", c.Param("name"), "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error verifying backup"})
+		return
+	}
+	c.JSON(http.StatusOK, manifest)
+}
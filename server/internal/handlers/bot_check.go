@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"encoding/json"
+
+	"github.com/andevellicus/crapp/internal/metrics"
+	"github.com/andevellicus/crapp/internal/models"
+	"github.com/andevellicus/crapp/internal/utils"
+)
+
+// Signals that a form was submitted by a script rather than a person
+const (
+	botSignalHoneypot       = "honeypot"        // A honeypot question was answered
+	botSignalImpossibleTime = "impossible_time" // Finished faster than anyone could read the questions
+	botSignalNoInteractions = "no_interactions" // Every question answered without a single input event
+)
+
+// botSignals returns the signals that a form was submitted by a script. shown
+// is the number of questions the form served.
+func (h *FormHandler) botSignals(formState *models.FormState, questions []utils.Question, shown int, durationSeconds float64) []string {
+	var signals []string
+
+	for _, question := range questions {
+		if _, answered := formState.Answers[question.ID]; answered && question.Honeypot {
+			signals = append(signals, botSignalHoneypot)
+			break
+		}
+	}
+
+	if h.config.BotSecondsPerQuestion > 0 && durationSeconds < h.config.BotSecondsPerQuestion*float64(shown) {
+		signals = append(signals, botSignalImpossibleTime)
+	}
+
+	answered := 0
+	for _, question := range questions {
+		if _, ok := formState.Answers[question.ID]; ok && !question.Honeypot {
+			answered++
+		}
+	}
+	if shown > 0 && answered >= shown && interactionEvents(formState.InteractionData) == 0 {
+		signals = append(signals, botSignalNoInteractions)
+	}
+
+	return signals
+}
+
+// interactionEvents counts the mouse movements, clicks and key events in
+// saved interaction data
+func interactionEvents(data []byte) int {
+	if len(data) == 0 {
+		return 0
+	}
+	decompressedData, err := utils.DecompressData(data)
+	if err != nil {
+		decompressedData = data
+	}
+	var interactions metrics.InteractionData
+	if err := json.Unmarshal(decompressedData, &interactions); err != nil {
+		return 0
+	}
+	return len(interactions.MouseMovements) + len(interactions.MouseInteractions) + len(interactions.KeyboardEvents)
+}
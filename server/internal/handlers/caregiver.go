@@ -0,0 +1,139 @@
+// internal/handlers/caregiver.go
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/andevellicus/crapp/internal/repository"
+	"github.com/andevellicus/crapp/internal/services"
+	"github.com/andevellicus/crapp/internal/validation"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// CaregiverHandler handles caregiver linkage endpoints
+type CaregiverHandler struct {
+	repo *repository.Repository
+	log  *zap.SugaredLogger
+}
+
+// NewCaregiverHandler creates a new caregiver handler
+func NewCaregiverHandler(repo *repository.Repository, log *zap.SugaredLogger) *CaregiverHandler {
+	return &CaregiverHandler{
+		repo: repo,
+		log:  log.Named("caregiver"),
+	}
+}
+
+// GetLinks returns caregiver links where the user is either the patient or the caregiver
+func (h *CaregiverHandler) GetLinks(c *gin.Context) {
+	userEmail, exists := c.Get("userEmail")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	links, err := h.repo.CaregiverLinks.GetForUser(userEmail.(string))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error retrieving caregivers"})
+		return
+	}
+
+	c.JSON(http.StatusOK, links)
+}
+
+// Invite creates a pending caregiver link and emails the caregiver
+func (h *CaregiverHandler) Invite(c *gin.Context) {
+	req := c.MustGet("validatedRequest").(*validation.InviteCaregiverRequest)
+
+	userEmail, exists := c.Get("userEmail")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	caregiverEmail := strings.ToLower(req.CaregiverEmail)
+	if caregiverEmail == userEmail.(string) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot be your own caregiver"})
+		return
+	}
+
+	user, err := h.repo.Users.GetByEmail(userEmail.(string))
+	if err != nil || user == nil {
+		h.log.Errorw("Error retrieving user for caregiver invite", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error retrieving user"})
+		return
+	}
+
+	link, err := h.repo.CaregiverLinks.CreateInvite(user.Email, caregiverEmail)
+	if err != nil {
+		h.log.Warnw("Error creating caregiver invite", "error", err, "caregiver", caregiverEmail)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	emailService, exists := c.Get("emailService")
+	if !exists || emailService == nil {
+		h.log.Errorw("Email service not available for caregiver invite", "caregiver", caregiverEmail)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Email service not available"})
+		return
+	}
+
+	patientName := strings.TrimSpace(user.FirstName + " " + user.LastName)
+	if patientName == "" {
+		patientName = user.Email
+	}
+	go emailService.(*services.EmailService).SendCaregiverInviteEmail(caregiverEmail, patientName, link.InviteToken)
+
+	c.JSON(http.StatusCreated, link)
+}
+
+// Accept records the caregiver's explicit consent to a pending invite
+func (h *CaregiverHandler) Accept(c *gin.Context) {
+	req := c.MustGet("validatedRequest").(*validation.AcceptCaregiverInviteRequest)
+
+	userEmail, exists := c.Get("userEmail")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	if !req.Consent {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Consent is required"})
+		return
+	}
+
+	link, err := h.repo.CaregiverLinks.Accept(req.Token, userEmail.(string))
+	if err != nil {
+		h.log.Warnw("Error accepting caregiver invite", "error", err, "user", userEmail)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.log.Infow("Caregiver link activated", "patient", link.PatientEmail, "caregiver", link.CaregiverEmail)
+	c.JSON(http.StatusOK, link)
+}
+
+// Revoke ends a caregiver link from either side
+func (h *CaregiverHandler) Revoke(c *gin.Context) {
+	userEmail, exists := c.Get("userEmail")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	linkID, err := strconv.ParseUint(c.Param("linkId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid link ID"})
+		return
+	}
+
+	if err := h.repo.CaregiverLinks.Revoke(uint(linkID), userEmail.(string)); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Caregiver link not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Caregiver link revoked"})
+}
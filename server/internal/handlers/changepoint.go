@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/andevellicus/crapp/internal/repository"
+	"github.com/andevellicus/crapp/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// detectedChangePoint is a change point placed on the timeline
+type detectedChangePoint struct {
+	services.ChangePoint
+	Date      time.Time `json:"date"`
+	Direction string    `json:"direction"` // increase or decrease
+}
+
+// detectedSegment is a segment placed on the timeline
+type detectedSegment struct {
+	services.Segment
+	StartDate time.Time `json:"start_date"`
+	EndDate   time.Time `json:"end_date"` // Last day of the segment
+}
+
+// GetChangePoints detects when the level of a user's symptom or metric
+// series shifted, such as when a deterioration started. The series is
+// averaged to one point per assessment day first.
+func (h *GinAPIHandler) GetChangePoints(c *gin.Context) {
+	// Access is checked by RequireSelfOrRole
+	userID := c.GetString("resourceOwner")
+	blinded := c.GetBool("blinded")
+	symptomKey := c.Query("symptom")
+	metricKey := c.Query("metric")
+	series := c.DefaultQuery("series", "symptom")
+
+	if series != "symptom" && series != "metric" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "series must be symptom or metric"})
+		return
+	}
+	questions := questionsForUser(h.repo, h.questions, h.log, userID)
+	questionType := questionTypeOf(questions, symptomKey)
+	if series == "symptom" {
+		// Blinded viewers only get the metric series
+		if blinded {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Not allowed to view this user's answers"})
+			return
+		}
+		if slices.Contains(nonNumericQuestionTypes, questionType) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "This question has no numeric answers to analyse"})
+			return
+		}
+	} else if metricKey == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "metric is required for the metric series"})
+		return
+	}
+
+	var penalty float64
+	if param := c.Query("penalty"); param != "" {
+		val, err := strconv.ParseFloat(param, 64)
+		if err != nil || val <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "penalty must be a positive number"})
+			return
+		}
+		penalty = val
+	}
+	minSegment := services.DefaultMinSegment
+	if param := c.Query("min_segment"); param != "" {
+		val, err := strconv.Atoi(param)
+		if err != nil || val < 1 || val > 90 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "min_segment must be between 1 and 90"})
+			return
+		}
+		minSegment = val
+	}
+
+	includeRetrospective := c.Query("include_retrospective") == "true"
+
+	var timelineData []repository.TimelineDataPoint
+	var err error
+	if series == "symptom" && metricKey == "" {
+		timelineData, err = h.repo.ForUser(userID).Assessments.GetSymptomTimeline(userID, symptomKey, includeRetrospective)
+	} else {
+		timelineData, err = h.loadTimeline(userID, symptomKey, metricKey, questionType, repository.ChartFilter{IncludeRetrospective: includeRetrospective})
+	}
+	if err != nil {
+		h.log.Errorw("Error retrieving series for change-point detection", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error retrieving data"})
+		return
+	}
+	timelineData = repository.BucketTimeline(timelineData, repository.TimelineBucketDay)
+
+	values := make([]float64, len(timelineData))
+	for i, point := range timelineData {
+		values[i] = point.SymptomValue
+		if series == "metric" {
+			values[i] = point.MetricValue
+		}
+	}
+	result := services.DetectChangePoints(values, penalty, minSegment)
+
+	changePoints := make([]detectedChangePoint, len(result.ChangePoints))
+	for i, cp := range result.ChangePoints {
+		direction := "increase"
+		if cp.Change < 0 {
+			direction = "decrease"
+		}
+		changePoints[i] = detectedChangePoint{ChangePoint: cp, Date: timelineData[cp.Index].Date, Direction: direction}
+	}
+	segments := make([]detectedSegment, len(result.Segments))
+	for i, segment := range result.Segments {
+		segments[i] = detectedSegment{
+			Segment:   segment,
+			StartDate: timelineData[segment.Start].Date,
+			EndDate:   timelineData[segment.End-1].Date,
+		}
+	}
+
+	label := getMetricLabel(metricKey)
+	if series == "symptom" {
+		label = questionTitle(questions, symptomKey)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"series":        series,
+		"label":         label,
+		"points":        len(values),
+		"method":        result.Method,
+		"penalty":       result.Penalty,
+		"min_segment":   result.MinSegment,
+		"sigma":         result.Sigma,
+		"change_points": changePoints,
+		"segments":      segments,
+	})
+}
@@ -0,0 +1,171 @@
+// internal/handlers/chart_view.go
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/andevellicus/crapp/internal/models"
+	"github.com/andevellicus/crapp/internal/repository"
+	"github.com/andevellicus/crapp/internal/validation"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// ChartViewHandler manages saved chart configurations
+type ChartViewHandler struct {
+	repo *repository.Repository
+	log  *zap.SugaredLogger
+}
+
+// NewChartViewHandler creates a new chart view handler
+func NewChartViewHandler(repo *repository.Repository, log *zap.SugaredLogger) *ChartViewHandler {
+	return &ChartViewHandler{
+		repo: repo,
+		log:  log.Named("chart-view"),
+	}
+}
+
+// ListViews returns the user's saved chart views
+func (h *ChartViewHandler) ListViews(c *gin.Context) {
+	views, err := h.repo.ChartViews.List(c.GetString("userEmail"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error retrieving chart views"})
+		return
+	}
+	c.JSON(http.StatusOK, views)
+}
+
+// CreateView saves a new chart view
+func (h *ChartViewHandler) CreateView(c *gin.Context) {
+	req := c.MustGet("validatedRequest").(*validation.ChartViewRequest)
+
+	view := &models.ChartView{UserEmail: c.GetString("userEmail")}
+	if !h.apply(c, view, req) {
+		return
+	}
+	if err := h.repo.ChartViews.Save(view); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error saving chart view"})
+		return
+	}
+	c.JSON(http.StatusCreated, view)
+}
+
+// UpdateView changes a saved chart view
+func (h *ChartViewHandler) UpdateView(c *gin.Context) {
+	req := c.MustGet("validatedRequest").(*validation.ChartViewRequest)
+
+	view, ok := h.lookup(c)
+	if !ok {
+		return
+	}
+	if !h.apply(c, view, req) {
+		return
+	}
+	if err := h.repo.ChartViews.Save(view); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error saving chart view"})
+		return
+	}
+	c.JSON(http.StatusOK, view)
+}
+
+// DeleteView removes a saved chart view
+func (h *ChartViewHandler) DeleteView(c *gin.Context) {
+	view, ok := h.lookup(c)
+	if !ok {
+		return
+	}
+	if err := h.repo.ChartViews.Delete(view); err != nil {
+		h.log.Errorw("Error deleting chart view", "error", err, "id", view.ID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error deleting chart view"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+// lookup loads one of the user's views named in the path
+func (h *ChartViewHandler) lookup(c *gin.Context) (*models.ChartView, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid chart view ID"})
+		return nil, false
+	}
+
+	view, err := h.repo.ChartViews.Get(c.GetString("userEmail"), uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Chart view not found"})
+		return nil, false
+	}
+	return view, true
+}
+
+// apply copies a request onto a view
+func (h *ChartViewHandler) apply(c *gin.Context, view *models.ChartView, req *validation.ChartViewRequest) bool {
+	if req.StartDate != "" && req.EndDate != "" && req.StartDate > req.EndDate {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "start_date must not be after end_date"})
+		return false
+	}
+
+	view.Name = req.Name
+	view.Symptom = req.Symptom
+	view.Metric = req.Metric
+	view.RangeDays = req.RangeDays
+	view.StartDate = req.StartDate
+	view.EndDate = req.EndDate
+	view.Bucket = req.Bucket
+	view.IncludeRetrospective = req.IncludeRetrospective
+	view.IsDefault = req.IsDefault
+	return true
+}
+
+// chartQuery returns a chart request's parameters with a saved view filled
+// in: the view named by the view parameter, or else the viewer's default
+// view when the request names no symptom or metric. Parameters in the
+// request take precedence over the view's.
+func chartQuery(c *gin.Context, repo *repository.Repository) (url.Values, bool) {
+	query := c.Request.URL.Query()
+	viewer := c.GetString("userEmail")
+
+	var view *models.ChartView
+	var err error
+	switch {
+	case query.Get("view") != "":
+		id, parseErr := strconv.ParseUint(query.Get("view"), 10, 64)
+		if parseErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid chart view ID"})
+			return nil, false
+		}
+		if view, err = repo.ChartViews.Get(viewer, uint(id)); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Chart view not found"})
+			return nil, false
+		}
+	case query.Get("symptom") == "" && query.Get("metric") == "":
+		// A missing default just leaves the request as it is
+		if view, err = repo.ChartViews.GetDefault(viewer); err != nil {
+			view = nil
+		}
+	}
+	if view == nil {
+		return query, true
+	}
+
+	setDefault := func(key, value string) {
+		if value != "" && !query.Has(key) {
+			query.Set(key, value)
+		}
+	}
+	setDefault("symptom", view.Symptom)
+	setDefault("metric", view.Metric)
+	setDefault("bucket", view.Bucket)
+	if view.RangeDays > 0 && !query.Has("from") && !query.Has("to") {
+		setDefault("days", strconv.Itoa(view.RangeDays))
+	} else if !query.Has("days") {
+		setDefault("from", view.StartDate)
+		setDefault("to", view.EndDate)
+	}
+	if view.IncludeRetrospective {
+		setDefault("include_retrospective", "true")
+	}
+	return query, true
+}
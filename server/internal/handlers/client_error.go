@@ -0,0 +1,228 @@
+// internal/handlers/client_error.go
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/andevellicus/crapp/internal/buildinfo"
+	"github.com/andevellicus/crapp/internal/config"
+	"github.com/andevellicus/crapp/internal/models"
+	"github.com/andevellicus/crapp/internal/repository"
+	"github.com/andevellicus/crapp/internal/validation"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// maxClientUserAgent caps the stored user agent
+const maxClientUserAgent = 500
+
+// Personal data scrubbed from client error reports
+var (
+	clientEmails  = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+	clientJWTs    = regexp.MustCompile(`eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`)
+	clientSecrets = regexp.MustCompile(`\b[A-Za-z0-9_-]{40,}\b`) // Opaque tokens
+	clientNumbers = regexp.MustCompile(`\b\d{7,}\b`)             // Phone and record numbers
+	clientQueries = regexp.MustCompile(`\?[^\s)'":#]*`)          // Query strings, which can carry tokens
+	clientDigits  = regexp.MustCompile(`\d+`)
+)
+
+// clientErrorKey labels the reported error counter
+type clientErrorKey struct {
+	kind    string
+	release string
+}
+
+// ClientErrorHandler takes in JavaScript errors reported by the PWA, scrubs
+// them of personal data, and keeps them for admin triage
+type ClientErrorHandler struct {
+	repo *repository.Repository
+	log  *zap.SugaredLogger
+	cfg  *config.ClientErrorsConfig
+
+	mu         sync.Mutex
+	counts     map[clientErrorKey]uint64
+	lastPruned time.Time
+}
+
+// NewClientErrorHandler creates a new client error handler
+func NewClientErrorHandler(repo *repository.Repository, log *zap.SugaredLogger, cfg *config.ClientErrorsConfig) *ClientErrorHandler {
+	return &ClientErrorHandler{
+		repo:   repo,
+		log:    log.Named("client-errors"),
+		cfg:    cfg,
+		counts: make(map[clientErrorKey]uint64),
+	}
+}
+
+// Report stores a batch of errors. Reports need no account, since errors
+// can happen before sign-in, and are never linked to one.
+func (h *ClientErrorHandler) Report(c *gin.Context) {
+	req := c.MustGet("validatedRequest").(*validation.ClientErrorBatchRequest)
+	if !h.cfg.Enabled {
+		c.Status(http.StatusNoContent)
+		return
+	}
+
+	userAgent := req.UserAgent
+	if userAgent == "" {
+		userAgent = c.Request.UserAgent()
+	}
+	if len(userAgent) > maxClientUserAgent {
+		userAgent = userAgent[:maxClientUserAgent]
+	}
+	release := scrubClientText(req.Release)
+
+	now := time.Now()
+	reports := make([]models.ClientError, 0, len(req.Errors))
+	for _, e := range req.Errors {
+		occurredAt := e.OccurredAt
+		// Trust the browser's clock only roughly
+		if occurredAt.IsZero() || occurredAt.After(now) || now.Sub(occurredAt) > 24*time.Hour {
+			occurredAt = now
+		}
+		report := models.ClientError{
+			Kind:       e.Kind,
+			Message:    scrubClientText(e.Message),
+			Stack:      scrubClientText(e.Stack),
+			Source:     scrubClientURL(e.Source),
+			Line:       e.Line,
+			Column:     e.Column,
+			Route:      scrubClientURL(e.Route),
+			Release:    release,
+			UserAgent:  userAgent,
+			OccurredAt: occurredAt,
+		}
+		report.Fingerprint = clientErrorFingerprint(&report)
+		reports = append(reports, report)
+	}
+
+	if err := h.repo.ClientErrors.CreateBatch(reports); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error storing reports"})
+		return
+	}
+	h.count(reports)
+	h.pruneExpired()
+
+	c.Status(http.StatusAccepted)
+}
+
+// ListErrors summarizes the errors reported over the last days, 7 by
+// default, optionally for one release
+func (h *ClientErrorHandler) ListErrors(c *gin.Context) {
+	days := 7
+	if param := c.Query("days"); param != "" {
+		if val, err := strconv.Atoi(param); err == nil && val > 0 && val <= 365 {
+			days = val
+		}
+	}
+
+	groups, err := h.repo.ClientErrors.ListGroups(time.Now().AddDate(0, 0, -days), c.Query("release"), 100)
+	if err != nil {
+		h.log.Errorw("Error listing client errors", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error retrieving client errors"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"days": days, "errors": groups})
+}
+
+// GetError returns the latest reports of one error
+func (h *ClientErrorHandler) GetError(c *gin.Context) {
+	reports, err := h.repo.ClientErrors.ListByFingerprint(c.Param("fingerprint"), 20)
+	if err != nil {
+		h.log.Errorw("Error retrieving client error reports", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error retrieving client errors"})
+		return
+	}
+	if len(reports) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Client error not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"reports": reports})
+}
+
+// Counts returns how many errors were reported since startup, by kind and
+// release. Releases other than the running one are counted together as
+// "other" so clients can't add labels at will.
+func (h *ClientErrorHandler) Counts() map[clientErrorKey]uint64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	counts := make(map[clientErrorKey]uint64, len(h.counts))
+	for key, count := range h.counts {
+		counts[key] = count
+	}
+	return counts
+}
+
+func (h *ClientErrorHandler) count(reports []models.ClientError) {
+	current := buildinfo.Get().Version
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, report := range reports {
+		release := "other"
+		if report.Release == current {
+			release = current
+		}
+		h.counts[clientErrorKey{kind: report.Kind, release: release}]++
+	}
+}
+
+// pruneExpired removes reports past their retention, at most hourly
+func (h *ClientErrorHandler) pruneExpired() {
+	if h.cfg.RetentionDays <= 0 {
+		return
+	}
+	h.mu.Lock()
+	if time.Since(h.lastPruned) < time.Hour {
+		h.mu.Unlock()
+		return
+	}
+	h.lastPruned = time.Now()
+	h.mu.Unlock()
+
+	deleted, err := h.repo.ClientErrors.DeleteBefore(time.Now().AddDate(0, 0, -h.cfg.RetentionDays))
+	if err != nil {
+		h.log.Warnw("Failed to prune client errors", "error", err)
+	} else if deleted > 0 {
+		h.log.Infow("Pruned client errors", "count", deleted)
+	}
+}
+
+// scrubClientText removes email addresses, tokens, long numbers and query
+// strings from reported text
+func scrubClientText(text string) string {
+	text = clientEmails.ReplaceAllString(text, "[email]")
+	text = clientJWTs.ReplaceAllString(text, "[token]")
+	text = clientSecrets.ReplaceAllString(text, "[token]")
+	text = clientNumbers.ReplaceAllString(text, "[number]")
+	return clientQueries.ReplaceAllString(text, "")
+}
+
+// scrubClientURL keeps the path of a reported URL and drops its query and
+// fragment
+func scrubClientURL(raw string) string {
+	if parsed, err := url.Parse(raw); err == nil {
+		parsed.RawQuery = ""
+		parsed.Fragment = ""
+		parsed.User = nil
+		raw = parsed.String()
+	}
+	return scrubClientText(raw)
+}
+
+// clientErrorFingerprint identifies an error across reports. Numbers in the
+// message are ignored, so "index 3" and "index 4" group together.
+func clientErrorFingerprint(report *models.ClientError) string {
+	sum := sha256.Sum256([]byte(report.Kind + "\n" +
+		clientDigits.ReplaceAllString(report.Message, "#") + "\n" +
+		report.Source + ":" + strconv.Itoa(report.Line)))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,181 @@
+// internal/handlers/clinical_event.go
+package handlers
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/andevellicus/crapp/internal/models"
+	"github.com/andevellicus/crapp/internal/repository"
+	"github.com/andevellicus/crapp/internal/utils"
+	"github.com/andevellicus/crapp/internal/validation"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// clinicalEventSeverities lists the severity grades allowed for each event type
+var clinicalEventSeverities = map[string][]string{
+	models.ClinicalEventAdverseEvent: {"mild", "moderate", "severe", "life_threatening", "fatal"},
+	models.ClinicalEventDeviation:    {"minor", "major"},
+}
+
+// ClinicalEventHandler handles protocol deviation and adverse event reporting
+type ClinicalEventHandler struct {
+	repo      *repository.Repository
+	log       *zap.SugaredLogger
+	sanitizer *utils.Sanitizer
+}
+
+// NewClinicalEventHandler creates a new clinical event handler
+func NewClinicalEventHandler(repo *repository.Repository, log *zap.SugaredLogger, sanitizer *utils.Sanitizer) *ClinicalEventHandler {
+	return &ClinicalEventHandler{
+		repo:      repo,
+		log:       log.Named("clinical-event"),
+		sanitizer: sanitizer,
+	}
+}
+
+// ReportEvent logs a protocol deviation or adverse event for a participant
+func (h *ClinicalEventHandler) ReportEvent(c *gin.Context) {
+	req := c.MustGet("validatedRequest").(*validation.ReportClinicalEventRequest)
+	email := strings.ToLower(req.Email)
+
+	severity := strings.ToLower(req.Severity)
+	if !slices.Contains(clinicalEventSeverities[req.EventType], severity) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "severity must be one of " + strings.Join(clinicalEventSeverities[req.EventType], ", "),
+		})
+		return
+	}
+
+	eventDate, err := time.ParseInLocation("2006-01-02", req.EventDate, time.Local)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "event_date must be in YYYY-MM-DD format"})
+		return
+	}
+	if eventDate.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "event_date cannot be in the future"})
+		return
+	}
+
+	if !userInOrgScope(c, h.repo, email) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Participant not found"})
+		return
+	}
+	participant, err := h.repo.Users.GetByEmail(email)
+	if err != nil || participant == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Participant not found"})
+		return
+	}
+
+	event := &models.ClinicalEvent{
+		EventType:      req.EventType,
+		UserEmail:      email,
+		OrganizationID: participant.OrganizationID,
+		EventDate:      eventDate,
+		Severity:       severity,
+		Description:    h.sanitizer.Note(req.Description),
+		FollowUpStatus: models.FollowUpOpen,
+		ReportedBy:     c.GetString("userEmail"),
+	}
+	if err := h.repo.ClinicalEvents.Create(event); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error recording event"})
+		return
+	}
+
+	h.log.Infow("Clinical event reported", "id", event.ID, "type", event.EventType, "severity", severity, "reported_by", event.ReportedBy)
+	c.JSON(http.StatusCreated, event)
+}
+
+// UpdateEvent records follow-up on a clinical event
+func (h *ClinicalEventHandler) UpdateEvent(c *gin.Context) {
+	req := c.MustGet("validatedRequest").(*validation.UpdateClinicalEventRequest)
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid event ID"})
+		return
+	}
+
+	event, err := h.repo.ClinicalEvents.Get(orgScope(c), uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+		return
+	}
+
+	if req.Severity != "" {
+		severity := strings.ToLower(req.Severity)
+		if !slices.Contains(clinicalEventSeverities[event.EventType], severity) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "severity must be one of " + strings.Join(clinicalEventSeverities[event.EventType], ", "),
+			})
+			return
+		}
+		event.Severity = severity
+	}
+	event.FollowUpStatus = req.FollowUpStatus
+	event.FollowUpNotes = h.sanitizer.Note(req.FollowUpNotes)
+
+	if err := h.repo.ClinicalEvents.Update(event); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error updating event"})
+		return
+	}
+
+	h.log.Infow("Clinical event updated", "id", event.ID, "status", event.FollowUpStatus, "admin", c.GetString("userEmail"))
+	c.JSON(http.StatusOK, event)
+}
+
+// SearchEvents lists clinical events, optionally filtered by type, follow-up status, or participant
+func (h *ClinicalEventHandler) SearchEvents(c *gin.Context) {
+	skip := 0
+	limit := 50
+
+	if skipParam := c.Query("skip"); skipParam != "" {
+		if val, err := strconv.Atoi(skipParam); err == nil && val >= 0 {
+			skip = val
+		}
+	}
+
+	if limitParam := c.Query("limit"); limitParam != "" {
+		if val, err := strconv.Atoi(limitParam); err == nil && val > 0 && val <= 200 {
+			limit = val
+		}
+	}
+
+	events, total, err := h.repo.ClinicalEvents.Search(orgScope(c), c.Query("type"), c.Query("status"), c.Query("email"), skip, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error searching events"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"events": events,
+		"total":  total,
+		"skip":   skip,
+		"limit":  limit,
+	})
+}
+
+// GetSummary counts clinical events for the admin dashboard
+func (h *ClinicalEventHandler) GetSummary(c *gin.Context) {
+	counts, err := h.repo.ClinicalEvents.Summary(orgScope(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error summarizing events"})
+		return
+	}
+
+	var open int64
+	for _, count := range counts {
+		if count.FollowUpStatus != models.FollowUpResolved {
+			open += count.Count
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"counts":          counts,
+		"open_follow_ups": open,
+	})
+}
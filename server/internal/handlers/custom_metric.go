@@ -0,0 +1,199 @@
+// internal/handlers/custom_metric.go
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+
+	"github.com/andevellicus/crapp/internal/metrics"
+	"github.com/andevellicus/crapp/internal/models"
+	"github.com/andevellicus/crapp/internal/repository"
+	"github.com/andevellicus/crapp/internal/scheduler"
+	"github.com/andevellicus/crapp/internal/validation"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// metricKeyPattern matches keys that are safe as export column names
+var metricKeyPattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// formulaInputGroups are the groups whose metrics are stored per question
+// and so can feed a formula
+var formulaInputGroups = []string{metrics.GroupMouse, metrics.GroupKeyboard, metrics.GroupTiming}
+
+// CustomMetricHandler manages admin-defined metric formulas
+type CustomMetricHandler struct {
+	repo      *repository.Repository
+	log       *zap.SugaredLogger
+	scheduler *scheduler.CustomMetricScheduler
+}
+
+// NewCustomMetricHandler creates a new custom metric handler
+func NewCustomMetricHandler(repo *repository.Repository, log *zap.SugaredLogger, scheduler *scheduler.CustomMetricScheduler) *CustomMetricHandler {
+	return &CustomMetricHandler{
+		repo:      repo,
+		log:       log.Named("custom-metric"),
+		scheduler: scheduler,
+	}
+}
+
+// ListDefinitions returns every metric the app records, built-in and custom
+func (h *CustomMetricHandler) ListDefinitions(c *gin.Context) {
+	c.JSON(http.StatusOK, metrics.All())
+}
+
+// ListCustomMetrics returns the admin-defined metrics
+func (h *CustomMetricHandler) ListCustomMetrics(c *gin.Context) {
+	customMetrics, err := h.repo.CustomMetrics.List()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error retrieving custom metrics"})
+		return
+	}
+	c.JSON(http.StatusOK, customMetrics)
+}
+
+// CreateCustomMetric defines a new metric. Values for past assessments are
+// filled in by a background recompute.
+func (h *CustomMetricHandler) CreateCustomMetric(c *gin.Context) {
+	req := c.MustGet("validatedRequest").(*validation.CustomMetricRequest)
+
+	if !metricKeyPattern.MatchString(req.Key) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Key must be lowercase letters, digits and underscores, starting with a letter"})
+		return
+	}
+	if metrics.IsBuiltIn(req.Key) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Key is already used by a built-in metric"})
+		return
+	}
+	if _, ok := metrics.Lookup(req.Key); ok {
+		c.JSON(http.StatusConflict, gin.H{"error": "A custom metric with this key already exists"})
+		return
+	}
+
+	metric := &models.CustomMetric{Key: req.Key, CreatedBy: c.GetString("userEmail")}
+	if !h.apply(c, metric, req) {
+		return
+	}
+	if err := h.repo.CustomMetrics.Create(metric); err != nil {
+		if repository.IsUniqueViolation(err) {
+			c.JSON(http.StatusConflict, gin.H{"error": "A custom metric with this key already exists"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creating custom metric"})
+		return
+	}
+
+	h.changed()
+	h.log.Infow("Custom metric created", "key", metric.Key, "formula", metric.Formula, "admin", metric.CreatedBy)
+	c.JSON(http.StatusCreated, metric)
+}
+
+// UpdateCustomMetric changes a metric's label, group or formula
+func (h *CustomMetricHandler) UpdateCustomMetric(c *gin.Context) {
+	req := c.MustGet("validatedRequest").(*validation.CustomMetricRequest)
+
+	metric, ok := h.lookup(c)
+	if !ok {
+		return
+	}
+	if req.Key != metric.Key {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A custom metric's key can't be changed"})
+		return
+	}
+	if !h.apply(c, metric, req) {
+		return
+	}
+	if err := h.repo.CustomMetrics.Update(metric); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error updating custom metric"})
+		return
+	}
+
+	h.changed()
+	h.log.Infow("Custom metric updated", "key", metric.Key, "formula", metric.Formula, "admin", c.GetString("userEmail"))
+	c.JSON(http.StatusOK, metric)
+}
+
+// DeleteCustomMetric removes a metric and every value stored for it
+func (h *CustomMetricHandler) DeleteCustomMetric(c *gin.Context) {
+	metric, ok := h.lookup(c)
+	if !ok {
+		return
+	}
+	if err := h.repo.CustomMetrics.Delete(metric); err != nil {
+		h.log.Errorw("Error deleting custom metric", "error", err, "key", metric.Key)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error deleting custom metric"})
+		return
+	}
+	h.changed()
+
+	for _, repo := range h.repo.DataRepositories() {
+		if err := repo.DeleteMetricValues(metric.Key); err != nil {
+			h.log.Errorw("Error deleting custom metric values", "error", err, "key", metric.Key)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Custom metric deleted, but some of its values remain"})
+			return
+		}
+	}
+
+	h.log.Infow("Custom metric deleted", "key", metric.Key, "admin", c.GetString("userEmail"))
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+// lookup loads the custom metric named in the path
+func (h *CustomMetricHandler) lookup(c *gin.Context) (*models.CustomMetric, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid custom metric ID"})
+		return nil, false
+	}
+
+	metric, err := h.repo.CustomMetrics.Get(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Custom metric not found"})
+		return nil, false
+	}
+	return metric, true
+}
+
+// apply checks a request's formula and copies the request onto the metric
+func (h *CustomMetricHandler) apply(c *gin.Context, metric *models.CustomMetric, req *validation.CustomMetricRequest) bool {
+	formula, err := metrics.ParseFormula(req.Formula)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid formula: %v", err)})
+		return false
+	}
+	if len(formula.Keys()) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Formula must use at least one metric"})
+		return false
+	}
+	for _, key := range formula.Keys() {
+		def, ok := metrics.Lookup(key)
+		if !ok || !metrics.IsBuiltIn(key) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Unknown metric %q in formula", key)})
+			return false
+		}
+		if !slices.Contains(formulaInputGroups, def.Group) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("%s metrics can't be used in formulas", def.Group)})
+			return false
+		}
+	}
+
+	metric.Label = req.Label
+	metric.Group = req.Group
+	metric.Formula = formula.String()
+	return true
+}
+
+// changed updates this server's registry straight away and queues a
+// recompute of stored values
+func (h *CustomMetricHandler) changed() {
+	defs, err := h.repo.CustomMetrics.Definitions()
+	if err != nil {
+		h.log.Warnw("Failed to reload custom metrics", "error", err)
+	} else {
+		metrics.SetCustom(defs)
+	}
+	h.scheduler.Trigger()
+}
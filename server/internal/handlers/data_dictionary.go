@@ -0,0 +1,146 @@
+// internal/handlers/data_dictionary.go
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/andevellicus/crapp/internal/metrics"
+	"github.com/andevellicus/crapp/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// DictionaryQuestion describes a question and the values its answers take
+type DictionaryQuestion struct {
+	ID          string                 `json:"id"`
+	Title       string                 `json:"title"`
+	Description string                 `json:"description,omitempty"`
+	Type        string                 `json:"type"`
+	MetricsType string                 `json:"metrics_type,omitempty"`
+	ValueType   string                 `json:"value_type"`
+	Required    bool                   `json:"required"`
+	Options     []utils.QuestionOption `json:"options,omitempty"`
+	Scale       *DictionaryScale       `json:"scale,omitempty"`
+}
+
+// DictionaryScale is the range of a question's numeric answer codes
+type DictionaryScale struct {
+	Min float64 `json:"min"`
+	Max float64 `json:"max"`
+}
+
+// DictionaryMetric describes a metric key and its values
+type DictionaryMetric struct {
+	metrics.Definition
+	metrics.Properties
+	Custom bool `json:"custom"`
+}
+
+// DictionaryTest describes the results a cognitive test stores
+type DictionaryTest struct {
+	Type    string              `json:"type"`
+	Metrics []string            `json:"metrics"` // Keys charted for the test
+	Fields  []metrics.TestField `json:"fields"`
+}
+
+// GetDataDictionary describes every question the user's organization asks,
+// every metric key and every cognitive test's stored results, so exports and
+// dashboards can label data without their own copies
+func (h *GinAPIHandler) GetDataDictionary(c *gin.Context) {
+	loader := questionsForUser(h.repo, h.questions, h.log, c.GetString("userEmail"))
+	custom, err := h.repo.CustomMetrics.Version()
+	if err != nil {
+		h.log.Errorw("Error reading custom metric version", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error building data dictionary"})
+		return
+	}
+	if notModified(c, weakETag("data-dictionary", loader.Checksum, custom)) {
+		return
+	}
+
+	questions := []DictionaryQuestion{}
+	for _, q := range loader.GetQuestions() {
+		scale := optionScale(q.Options)
+		questions = append(questions, DictionaryQuestion{
+			ID:          q.ID,
+			Title:       q.Title,
+			Description: q.Description,
+			Type:        q.Type,
+			MetricsType: q.MetricsType,
+			ValueType:   questionValueType(q, scale),
+			Required:    q.Required,
+			Options:     q.Options,
+			Scale:       scale,
+		})
+	}
+
+	dictionaryMetrics := []DictionaryMetric{}
+	testMetrics := map[string][]string{}
+	for _, def := range metrics.All() {
+		dictionaryMetrics = append(dictionaryMetrics, DictionaryMetric{
+			Definition: def,
+			Properties: metrics.Describe(def.Key),
+			Custom:     !metrics.IsBuiltIn(def.Key),
+		})
+		testMetrics[def.Group] = append(testMetrics[def.Group], def.Key)
+	}
+
+	tests := []DictionaryTest{}
+	for _, testType := range []string{metrics.GroupCPT, metrics.GroupTMT, metrics.GroupDigitSpan} {
+		tests = append(tests, DictionaryTest{
+			Type:    testType,
+			Metrics: testMetrics[testType],
+			Fields:  metrics.TestFields[testType],
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"questions": questions,
+		"metrics":   dictionaryMetrics,
+		"tests":     tests,
+	})
+}
+
+// questionValueType is how a question's answers are stored: cognitive tests
+// store results rather than an answer, and choices with non-numeric values
+// are stored as strings
+func questionValueType(q utils.Question, scale *DictionaryScale) string {
+	switch {
+	case q.Type == "text":
+		return "string"
+	case q.Type == "cpt" || q.Type == "tmt" || q.Type == "digit_span":
+		return "test"
+	case len(q.Options) > 0 && scale == nil:
+		return "string"
+	}
+	return "number"
+}
+
+// optionScale is the lowest and highest option value, or nil when any option
+// isn't numeric. Numeric strings count, as they are stored as numbers.
+func optionScale(options []utils.QuestionOption) *DictionaryScale {
+	var scale *DictionaryScale
+	for _, opt := range options {
+		var value float64
+		switch v := opt.Value.(type) {
+		case int:
+			value = float64(v)
+		case float64:
+			value = v
+		case string:
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return nil
+			}
+			value = f
+		default:
+			return nil
+		}
+		if scale == nil {
+			scale = &DictionaryScale{Min: value, Max: value}
+		}
+		scale.Min = min(scale.Min, value)
+		scale.Max = max(scale.Max, value)
+	}
+	return scale
+}
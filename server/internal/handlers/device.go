@@ -1,13 +1,31 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
+	"time"
 
+	"github.com/andevellicus/crapp/internal/models"
 	"github.com/andevellicus/crapp/internal/validation"
 	"github.com/gin-gonic/gin"
 )
 
-// GetUserDevices returns all devices for the authenticated user
+// deviceSession describes the login session active on a device
+type deviceSession struct {
+	StartedAt       time.Time `json:"started_at"`
+	LastRefreshedAt time.Time `json:"last_refreshed_at"`
+	ExpiresAt       time.Time `json:"expires_at"`
+	Remembered      bool      `json:"remembered"`
+}
+
+// userDevice is a device with its active session, if it has one
+type userDevice struct {
+	models.Device
+	Session *deviceSession `json:"session"`
+}
+
+// GetUserDevices returns all devices for the authenticated user, with the
+// session each is logged in with
 func (h *AuthHandler) GetUserDevices(c *gin.Context) {
 	// Get user email from context
 	userEmail, exists := c.Get("userEmail")
@@ -24,7 +42,38 @@ func (h *AuthHandler) GetUserDevices(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, devices)
+	tokens, err := h.repo.RefreshTokens.GetAllActiveForUser(userEmail.(string))
+	if err != nil {
+		h.log.Errorw("Error retrieving user sessions", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error retrieving devices"})
+		return
+	}
+	// The most recent live refresh token on each device is its session
+	sessions := make(map[string]*deviceSession)
+	for _, token := range tokens {
+		if token.ExpiresAt.Before(time.Now()) {
+			continue
+		}
+		if current, ok := sessions[token.DeviceID]; ok && current.LastRefreshedAt.After(token.CreatedAt) {
+			continue
+		}
+		startedAt := token.SessionStartedAt
+		if startedAt.IsZero() {
+			startedAt = token.CreatedAt
+		}
+		sessions[token.DeviceID] = &deviceSession{
+			StartedAt:       startedAt,
+			LastRefreshedAt: token.CreatedAt,
+			ExpiresAt:       token.ExpiresAt,
+			Remembered:      !token.Ephemeral,
+		}
+	}
+
+	result := make([]userDevice, len(devices))
+	for i, device := range devices {
+		result[i] = userDevice{Device: device, Session: sessions[device.ID]}
+	}
+	c.JSON(http.StatusOK, result)
 }
 
 // RegisterDevice handles registration of a new device
@@ -44,7 +93,9 @@ func (h *AuthHandler) RegisterDevice(c *gin.Context) {
 		"device_type": req.DeviceType,
 		"user_agent":  req.UserAgent,
 		"screen_size": req.ScreenSize,
+		"timezone":    req.Timezone,
 	}
+	h.sanitizeDeviceInfo(deviceInfo)
 
 	// Register device
 	device, err := h.repo.Devices.RegisterDevice(userEmail.(string), deviceInfo)
@@ -53,6 +104,7 @@ func (h *AuthHandler) RegisterDevice(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error registering device"})
 		return
 	}
+	h.recordTimezone(userEmail.(string), device)
 
 	c.JSON(http.StatusOK, gin.H{
 		"device_id": device.ID,
@@ -70,14 +122,14 @@ func (h *AuthHandler) RemoveDevice(c *gin.Context) {
 	}
 
 	// Get user email from context
-	_, exists := c.Get("userEmail")
+	userEmail, exists := c.Get("userEmail")
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
 		return
 	}
 
-	// Delete device
-	err := h.repo.Devices.Delete(deviceID)
+	// Delete device, detaching it from research data in the user's schema
+	err := h.repo.ForUser(userEmail.(string)).Devices.Delete(deviceID)
 	if err != nil {
 		h.log.Errorw("Error removing device", "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error removing device"})
@@ -106,7 +158,7 @@ func (h *AuthHandler) RenameDevice(c *gin.Context) {
 	}
 
 	// Update device name
-	err := h.repo.Devices.UpdateDeviceName(deviceID, userEmail.(string), req.DeviceName)
+	err := h.repo.Devices.UpdateDeviceName(deviceID, userEmail.(string), h.sanitizer.Text(req.DeviceName))
 	if err != nil {
 		h.log.Errorw("Error renaming device", "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error renaming device"})
@@ -116,6 +168,44 @@ func (h *AuthHandler) RenameDevice(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Device renamed successfully"})
 }
 
+// sanitizeDeviceInfo cleans the client-supplied text fields of device info
+func (h *AuthHandler) sanitizeDeviceInfo(deviceInfo map[string]any) {
+	for _, key := range []string{"device_name", "device_type", "user_agent", "os"} {
+		if value, ok := deviceInfo[key].(string); ok {
+			deviceInfo[key] = h.sanitizer.Text(value)
+		}
+	}
+}
+
+// recordTimezone moves a user's assessment days and reminders to the time
+// zone detected on a device they use
+func (h *AuthHandler) recordTimezone(email string, device *models.Device) {
+	changed, err := h.repo.Users.UpdateTimezone(email, device.Timezone)
+	if err != nil || !changed {
+		return
+	}
+	h.log.Infow("User time zone changed", "email", email, "timezone", device.Timezone)
+
+	// Reminders are scheduled per time zone
+	if h.reminders != nil {
+		if err := h.reminders.UpdateSchedules(); err != nil {
+			h.log.Warnw("Failed to update reminder schedules", "error", err)
+		}
+	}
+}
+
+// DeviceOwner is the middleware.OwnerFunc for device routes
+func (h *AuthHandler) DeviceOwner(c *gin.Context) (string, error) {
+	device, err := h.repo.Devices.GetByID(c.Param("deviceId"))
+	if err != nil {
+		return "", err
+	}
+	if device == nil {
+		return "", errors.New("device not found")
+	}
+	return device.UserEmail, nil
+}
+
 func getDeviceID(c *gin.Context) string {
 	// Get device ID from cookie
 	deviceID, err := c.Cookie("device_id")
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/andevellicus/crapp/internal/repository"
+	"github.com/andevellicus/crapp/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// GetQuestionDistribution returns how often a user gave each answer to a
+// question, such as the share of days they reported each fatigue level.
+// Admins also get the distribution across all users.
+func (h *GinAPIHandler) GetQuestionDistribution(c *gin.Context) {
+	questionID := c.Param("id")
+
+	// Access is checked by RequireSelfOrRole; blinded reviewers aren't admitted
+	userID := c.GetString("resourceOwner")
+
+	question := questionsForUser(h.repo, h.questions, h.log, userID).GetQuestionByID(questionID)
+	if question == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Question not found"})
+		return
+	}
+	if question.Type != "radio" && question.Type != "dropdown" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Distributions are only available for choice questions"})
+		return
+	}
+
+	// Takes the chart filter's include_retrospective, include_proxy and
+	// reported_by
+	filter, err := chartFilter(c.Request.URL.Query())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userDist, err := h.repo.ForUser(userID).Assessments.GetResponseDistribution(userID, questionID, filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error retrieving data"})
+		return
+	}
+	labelDistribution(userDist, question)
+
+	response := gin.H{
+		"question_id": question.ID,
+		"title":       question.Title,
+		"user":        userDist,
+	}
+
+	// The cohort spans every organization's data, so answers given by too
+	// few users are withheld
+	if c.GetBool("isAdmin") {
+		var buckets []repository.DistributionBucket
+		var users int64
+		for _, repo := range h.repo.DataRepositories() {
+			dist, err := repo.Assessments.GetResponseDistribution("", questionID, filter)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Error retrieving data"})
+				return
+			}
+			buckets = append(buckets, dist.Buckets...)
+			users += dist.Users
+		}
+		cohortDist := repository.NewResponseDistribution(buckets)
+		cohortDist.Users = users
+		cohortDist.SuppressSmallCells(h.privacy.MinCellSize)
+		labelDistribution(cohortDist, question)
+		response["cohort"] = cohortDist
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// labelDistribution names each bucket after the question option with its value
+func labelDistribution(dist *repository.ResponseDistribution, question *utils.Question) {
+	labels := make(map[float64]string, len(question.Options))
+	for _, opt := range question.Options {
+		switch v := opt.Value.(type) {
+		case int:
+			labels[float64(v)] = opt.Label
+		case float64:
+			labels[v] = opt.Label
+		case string:
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				labels[f] = opt.Label
+			}
+		}
+	}
+	for i := range dist.Buckets {
+		dist.Buckets[i].Label = labels[dist.Buckets[i].Value]
+	}
+}
@@ -0,0 +1,34 @@
+// internal/handlers/etag.go
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// weakETag builds a weak entity tag from the values a response depends on
+func weakETag(parts ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return `W/"` + hex.EncodeToString(sum[:12]) + `"`
+}
+
+// notModified sets the ETag for a response and answers 304 when the client
+// already has it. Responses stay private and are revalidated on every use.
+func notModified(c *gin.Context, etag string) bool {
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", "private, no-cache")
+
+	for _, candidate := range strings.Split(c.GetHeader("If-None-Match"), ",") {
+		candidate = strings.TrimSpace(candidate)
+		// Weak comparison: W/"x" matches "x"
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			c.Status(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
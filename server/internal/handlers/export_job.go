@@ -0,0 +1,419 @@
+// internal/handlers/export_job.go
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/andevellicus/crapp/internal/config"
+	"github.com/andevellicus/crapp/internal/models"
+	"github.com/andevellicus/crapp/internal/repository"
+	"github.com/andevellicus/crapp/internal/storage"
+	"github.com/andevellicus/crapp/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// exportJobListLimit caps how many of a user's jobs are listed
+const exportJobListLimit = 20
+
+// exportJobStaleAfter is how long a running job may go without finishing a
+// chunk before it is queued again, such as after a restart
+const exportJobStaleAfter = 15 * time.Minute
+
+// exportChunk holds the rows for one slice of an export job's window until
+// the job assembles its file
+type exportChunk struct {
+	Responses []repository.ReviewResponse `json:"responses"`
+	Metrics   []repository.ReviewMetric   `json:"metrics"`
+}
+
+// ExportJobHandler runs large analysis exports in the background. Each job
+// is built a few days at a time, with every finished chunk saved to storage,
+// so a job cut off by a restart resumes where it stopped.
+type ExportJobHandler struct {
+	repo      *repository.Repository
+	questions *utils.QuestionRegistry
+	store     storage.Storage
+	linkTTL   time.Duration
+	cfg       *config.ExportsConfig
+	log       *zap.SugaredLogger
+	wakeChan  chan struct{}
+	stopChan  chan struct{}
+}
+
+// NewExportJobHandler creates a new export job handler. Finished exports are
+// saved to the store and downloaded through a signed link.
+func NewExportJobHandler(repo *repository.Repository, log *zap.SugaredLogger, questions *utils.QuestionRegistry, store storage.Storage, linkTTL time.Duration, cfg *config.ExportsConfig) *ExportJobHandler {
+	return &ExportJobHandler{
+		repo:      repo,
+		questions: questions,
+		store:     store,
+		linkTTL:   linkTTL,
+		cfg:       cfg,
+		log:       log.Named("export-jobs"),
+		wakeChan:  make(chan struct{}, 1),
+		stopChan:  make(chan struct{}),
+	}
+}
+
+// CreateJob queues an analysis export of the last days of responses and
+// metrics in the long, wide, or parquet format
+func (h *ExportJobHandler) CreateJob(c *gin.Context) {
+	if h.store == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Export storage is not configured"})
+		return
+	}
+
+	format := c.DefaultQuery("format", exportFormatLong)
+	if format != exportFormatLong && format != exportFormatWide && format != exportFormatParquet {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be long, wide, or parquet"})
+		return
+	}
+
+	userEmail := c.GetString("userEmail")
+	active, err := h.repo.ExportJobs.CountActive(userEmail)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error checking export jobs"})
+		return
+	}
+	if h.cfg.MaxConcurrentPerUser > 0 && active >= int64(h.cfg.MaxConcurrentPerUser) {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many exports in progress; wait for one to finish"})
+		return
+	}
+
+	_, since := reviewWindow(c, h.repo.AssessmentDay())
+	until := time.Now()
+	chunkLen := h.chunkLength()
+	chunks := int((until.Sub(since) + chunkLen - 1) / chunkLen)
+
+	job := &models.ExportJob{
+		ID:             uuid.NewString(),
+		CreatedBy:      userEmail,
+		OrganizationID: orgScope(c),
+		Format:         format,
+		Blinded:        isBlinded(c),
+		Since:          since,
+		Until:          until,
+		Status:         models.ExportQueued,
+		ChunksTotal:    max(chunks, 1),
+		Filename:       analysisExportFilename(format),
+		// Attachments are never shown to blinded reviewers
+		IncludeAttachments: c.Query("include_attachments") == "true" && !isBlinded(c),
+	}
+	if err := h.repo.ExportJobs.Create(job); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creating export job"})
+		return
+	}
+	h.wake()
+
+	h.log.Infow("Queued export job", "id", job.ID, "reviewer", userEmail, "org", job.OrganizationID,
+		"format", format, "chunks", job.ChunksTotal, "blinded", job.Blinded)
+	c.JSON(http.StatusAccepted, job)
+}
+
+// ListJobs returns the current user's recent export jobs
+func (h *ExportJobHandler) ListJobs(c *gin.Context) {
+	jobs, err := h.repo.ExportJobs.ListForUser(c.GetString("userEmail"), exportJobListLimit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error retrieving export jobs"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"jobs": jobs})
+}
+
+// GetJob returns an export job's progress. Once it is done, and until its file
+// expires, the response includes a freshly signed download link.
+func (h *ExportJobHandler) GetJob(c *gin.Context) {
+	job, err := h.repo.ExportJobs.Get(c.Param("id"))
+	if err != nil || job.CreatedBy != c.GetString("userEmail") {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Export job not found"})
+		return
+	}
+
+	response := gin.H{"job": job}
+	if job.Status == models.ExportDone && job.StorageKey != "" && h.store != nil {
+		ttl := h.linkTTL
+		if job.ExpiresAt != nil {
+			ttl = min(ttl, time.Until(*job.ExpiresAt))
+		}
+		if ttl > 0 {
+			url, err := h.store.SignedURL(job.StorageKey, ttl)
+			if err != nil {
+				h.log.Errorw("Error signing export link", "error", err, "id", job.ID)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creating download link"})
+				return
+			}
+			response["url"] = url
+			response["url_expires_at"] = time.Now().Add(ttl)
+		}
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// Start launches the export workers and the upkeep that requeues stalled
+// jobs and removes expired files
+func (h *ExportJobHandler) Start() {
+	if h.store == nil {
+		h.log.Warn("Export storage is not configured; export jobs will not run")
+		return
+	}
+
+	workers := max(h.cfg.Workers, 1)
+	for range workers {
+		go h.work()
+	}
+	go h.upkeep()
+
+	h.log.Infow("Export workers started", "workers", workers)
+}
+
+// Stop stops the workers. A job in progress stops after its current chunk and
+// resumes from there once it is picked up again.
+func (h *ExportJobHandler) Stop() {
+	close(h.stopChan)
+	h.log.Info("Export workers stopped")
+}
+
+// wake tells an idle worker that a job is waiting
+func (h *ExportJobHandler) wake() {
+	select {
+	case h.wakeChan <- struct{}{}:
+	default:
+	}
+}
+
+// work runs queued jobs until the queue is empty, then waits to be woken.
+// Workers also check the queue every minute, which picks up requeued jobs.
+func (h *ExportJobHandler) work() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		for !h.stopping() {
+			job, err := h.repo.ExportJobs.ClaimNext()
+			if err != nil || job == nil {
+				break
+			}
+			h.run(job)
+		}
+
+		select {
+		case <-h.wakeChan:
+		case <-ticker.C:
+		case <-h.stopChan:
+			return
+		}
+	}
+}
+
+func (h *ExportJobHandler) stopping() bool {
+	select {
+	case <-h.stopChan:
+		return true
+	default:
+		return false
+	}
+}
+
+// upkeep requeues stalled jobs and removes expired files
+func (h *ExportJobHandler) upkeep() {
+	ticker := time.NewTicker(5 * time.Minute)
+	defer ticker.Stop()
+
+	for {
+		if requeued, err := h.repo.ExportJobs.RequeueStale(time.Now().Add(-exportJobStaleAfter)); err == nil && requeued > 0 {
+			h.log.Infow("Requeued stalled export jobs", "count", requeued)
+			h.wake()
+		}
+		h.removeExpired()
+
+		select {
+		case <-ticker.C:
+		case <-h.stopChan:
+			return
+		}
+	}
+}
+
+// run builds the job's remaining chunks and then its file
+func (h *ExportJobHandler) run(job *models.ExportJob) {
+	defer func() {
+		if r := recover(); r != nil {
+			h.log.Errorw("Export job panicked", "id", job.ID, "panic", r)
+			h.fail(job, fmt.Errorf("%v", r))
+		}
+	}()
+
+	ctx := context.Background()
+	chunkLen := h.chunkLength()
+	h.log.Infow("Running export job", "id", job.ID, "from_chunk", job.ChunksDone, "chunks", job.ChunksTotal)
+
+	for job.ChunksDone < job.ChunksTotal {
+		if h.stopping() {
+			return
+		}
+
+		from := job.Since.Add(time.Duration(job.ChunksDone) * chunkLen)
+		to := from.Add(chunkLen)
+		if job.ChunksDone == job.ChunksTotal-1 {
+			to = job.Until
+		}
+		if err := h.writeChunk(ctx, job, job.ChunksDone, from, to); err != nil {
+			h.fail(job, err)
+			return
+		}
+
+		job.ChunksDone++
+		// Assembling the file counts as the last step
+		job.Progress = job.ChunksDone * 100 / (job.ChunksTotal + 1)
+		if err := h.repo.ExportJobs.UpdateProgress(job); err != nil {
+			h.log.Warnw("Failed to save export progress", "error", err, "id", job.ID)
+		}
+	}
+
+	if err := h.assemble(ctx, job); err != nil {
+		h.fail(job, err)
+		return
+	}
+
+	now := time.Now()
+	expires := now.Add(time.Duration(h.cfg.RetentionHours) * time.Hour)
+	job.Status = models.ExportDone
+	job.Progress = 100
+	job.CompletedAt = &now
+	job.ExpiresAt = &expires
+	if err := h.repo.ExportJobs.UpdateProgress(job); err != nil {
+		h.log.Errorw("Failed to save finished export job", "error", err, "id", job.ID)
+	}
+	h.deleteChunks(ctx, job)
+
+	h.log.Infow("Export job completed", "id", job.ID, "key", job.StorageKey)
+}
+
+// writeChunk saves the responses and metrics submitted between from and to
+func (h *ExportJobHandler) writeChunk(ctx context.Context, job *models.ExportJob, index int, from, to time.Time) error {
+	assessments := h.repo.ForOrganization(job.OrganizationID).Assessments
+	responses, err := assessments.GetResponsesForReview(job.OrganizationID, from, to)
+	if err != nil {
+		return err
+	}
+	metrics, err := assessments.GetMetricsForReview(job.OrganizationID, from, to)
+	if err != nil {
+		return err
+	}
+
+	// Redact now, since which arms are blinded isn't kept in the chunk
+	if job.Blinded {
+		redactResponses(responses)
+		redactMetricArms(metrics)
+	}
+
+	data, err := json.Marshal(exportChunk{Responses: responses, Metrics: metrics})
+	if err != nil {
+		return err
+	}
+	if err := h.store.Put(ctx, exportChunkKey(job, index), bytes.NewReader(data), "application/json"); err != nil {
+		return fmt.Errorf("failed to store chunk %d: %w", index, err)
+	}
+	return nil
+}
+
+// assemble combines the job's chunks into the export file
+func (h *ExportJobHandler) assemble(ctx context.Context, job *models.ExportJob) error {
+	export := &analysisExport{
+		questions: questionsForOrganization(h.repo, h.questions, h.log, job.OrganizationID).GetQuestions(),
+		filters:   exportFilters(job.OrganizationID, job.Since, job.Until, job.Blinded),
+	}
+	for i := range job.ChunksTotal {
+		object, err := h.store.Get(ctx, exportChunkKey(job, i))
+		if err != nil {
+			return fmt.Errorf("failed to read chunk %d: %w", i, err)
+		}
+		var chunk exportChunk
+		err = json.NewDecoder(object).Decode(&chunk)
+		object.Close()
+		if err != nil {
+			return fmt.Errorf("failed to decode chunk %d: %w", i, err)
+		}
+		export.responses = append(export.responses, chunk.Responses...)
+		export.metrics = append(export.metrics, chunk.Metrics...)
+	}
+	if job.IncludeAttachments {
+		attachments, err := h.repo.ForOrganization(job.OrganizationID).Attachments.ListForExport(job.OrganizationID, job.Since, job.Until)
+		if err != nil {
+			return err
+		}
+		export.attachments = attachments
+		export.files = h.store
+	}
+
+	var buf bytes.Buffer
+	if err := export.writeFormat(&buf, job.Format); err != nil {
+		return err
+	}
+	key := exportKey(job.OrganizationID, job.Filename)
+	if err := h.store.Put(ctx, key, &buf, "application/zip"); err != nil {
+		return fmt.Errorf("failed to store export: %w", err)
+	}
+	job.StorageKey = key
+	return nil
+}
+
+// fail records a job's error and drops its chunks
+func (h *ExportJobHandler) fail(job *models.ExportJob, err error) {
+	h.log.Errorw("Export job failed", "id", job.ID, "error", err)
+
+	now := time.Now()
+	job.Status = models.ExportFailed
+	job.Error = err.Error()
+	job.CompletedAt = &now
+	if err := h.repo.ExportJobs.UpdateProgress(job); err != nil {
+		h.log.Errorw("Failed to save failed export job", "error", err, "id", job.ID)
+	}
+	h.deleteChunks(context.Background(), job)
+}
+
+// deleteChunks removes a job's stored chunks
+func (h *ExportJobHandler) deleteChunks(ctx context.Context, job *models.ExportJob) {
+	for i := range job.ChunksTotal {
+		if err := h.store.Delete(ctx, exportChunkKey(job, i)); err != nil {
+			h.log.Warnw("Failed to delete export chunk", "error", err, "id", job.ID, "chunk", i)
+		}
+	}
+}
+
+// removeExpired deletes the files of jobs past their retention
+func (h *ExportJobHandler) removeExpired() {
+	jobs, err := h.repo.ExportJobs.ListExpired(time.Now())
+	if err != nil {
+		h.log.Errorw("Failed to list expired exports", "error", err)
+		return
+	}
+	for _, job := range jobs {
+		if err := h.store.Delete(context.Background(), job.StorageKey); err != nil {
+			h.log.Warnw("Failed to delete expired export", "error", err, "id", job.ID, "key", job.StorageKey)
+			continue
+		}
+		if err := h.repo.ExportJobs.ClearFile(job.ID); err != nil {
+			h.log.Warnw("Failed to record expired export", "error", err, "id", job.ID)
+		}
+	}
+	if len(jobs) > 0 {
+		h.log.Infow("Removed expired exports", "count", len(jobs))
+	}
+}
+
+func (h *ExportJobHandler) chunkLength() time.Duration {
+	return time.Duration(max(h.cfg.ChunkDays, 1)) * 24 * time.Hour
+}
+
+// exportChunkKey is where one chunk of a job is stored while it runs
+func exportChunkKey(job *models.ExportJob, index int) string {
+	return fmt.Sprintf("exports/jobs/%s/chunk-%04d.json", job.ID, index)
+}
@@ -0,0 +1,34 @@
+// internal/handlers/features.go
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/andevellicus/crapp/internal/config"
+	"github.com/gin-gonic/gin"
+)
+
+// FeaturesHandler tells the client which optional features the deployment
+// has turned on
+type FeaturesHandler struct {
+	config *config.Config
+}
+
+// NewFeaturesHandler creates a new features handler
+func NewFeaturesHandler(cfg *config.Config) *FeaturesHandler {
+	return &FeaturesHandler{config: cfg}
+}
+
+// GetFeatures reports demo mode, with the banner to show in it, and whether
+// emails and push notifications can be sent
+func (h *FeaturesHandler) GetFeatures(c *gin.Context) {
+	features := gin.H{
+		"demo":  h.config.Demo.Enabled,
+		"email": h.config.Email.Enabled,
+		"push":  h.config.PWA.Enabled && h.config.PWA.VAPIDPublicKey != "",
+	}
+	if h.config.Demo.Enabled {
+		features["demo_banner"] = h.config.Demo.Banner
+	}
+	c.JSON(http.StatusOK, features)
+}
@@ -6,35 +6,58 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"math/rand"
+	"hash/fnv"
+	"math"
+	"math/rand/v2"
 	"net/http"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/andevellicus/crapp/internal/config"
 	"github.com/andevellicus/crapp/internal/metrics"
 	"github.com/andevellicus/crapp/internal/models"
 	"github.com/andevellicus/crapp/internal/repository"
+	"github.com/andevellicus/crapp/internal/services"
 	"github.com/andevellicus/crapp/internal/utils"
 	"github.com/andevellicus/crapp/internal/validation"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type FormHandler struct {
 	questionLoader *utils.QuestionLoader
+	questions      *utils.QuestionRegistry
 	repo           *repository.Repository
 	log            *zap.SugaredLogger
 	validator      *validation.FormValidator
+	config         *config.AssessmentConfig
+	sanitizer      *utils.Sanitizer
+	perf           *PerfBeaconHandler
+	alerts         *services.AlertDispatcher
+	feedback       *config.FeedbackConfig
+	achievements   *services.AchievementService
+	accessibility  *config.AccessibilityConfig
+	authService    *services.AuthService
 }
 
-func NewFormHandler(repo *repository.Repository, log *zap.SugaredLogger, questionLoader *utils.QuestionLoader) *FormHandler {
+func NewFormHandler(repo *repository.Repository, log *zap.SugaredLogger, questions *utils.QuestionRegistry, cfg *config.AssessmentConfig, sanitizer *utils.Sanitizer, perf *PerfBeaconHandler, alerts *services.AlertDispatcher, feedback *config.FeedbackConfig, achievements *services.AchievementService, accessibility *config.AccessibilityConfig, authService *services.AuthService) *FormHandler {
 	return &FormHandler{
-		questionLoader: questionLoader,
+		questionLoader: questions.Default(),
+		questions:      questions,
 		repo:           repo,
 		log:            log.Named("form"),
-		validator:      validation.NewFormValidator(questionLoader),
+		validator:      validation.NewFormValidator(questions.Default()),
+		config:         cfg,
+		sanitizer:      sanitizer,
+		perf:           perf,
+		alerts:         alerts,
+		feedback:       feedback,
+		achievements:   achievements,
+		accessibility:  accessibility,
+		authService:    authService,
 	}
 }
 
@@ -49,16 +72,55 @@ func (h *FormHandler) InitForm(c *gin.Context) {
 
 	// Check if we should force a new form state
 	var req struct {
-		ForceNew bool `json:"force_new"`
+		ForceNew       bool    `json:"force_new"`
+		OnBehalfOf     string  `json:"on_behalf_of"`
+		AssessmentDate string  `json:"assessment_date"` // YYYY-MM-DD, for retrospective entries
+		ClientTime     float64 `json:"client_time"`     // Device clock, in epoch milliseconds
 	}
-	if err := c.ShouldBindJSON(&req); err == nil && req.ForceNew {
+	bindErr := c.ShouldBindJSON(&req)
+
+	// Caregivers may fill in the form for a linked participant
+	subjectEmail := userEmail.(string)
+	var scope repository.FormStateScope
+	if bindErr == nil && req.OnBehalfOf != "" {
+		patientEmail := strings.ToLower(req.OnBehalfOf)
+		linked, err := h.repo.CaregiverLinks.IsActiveCaregiver(patientEmail, subjectEmail)
+		if err != nil {
+			h.log.Errorw("Database error checking caregiver link", "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+			return
+		}
+		if !linked {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Not an active caregiver for this user"})
+			return
+		}
+		caregiverEmail := subjectEmail
+		scope.ReportedBy = &caregiverEmail
+		subjectEmail = patientEmail
+	}
+
+	if h.rejectWithdrawn(c, subjectEmail) {
+		return
+	}
+
+	// Missed days may be backfilled from recall within the configured window
+	if bindErr == nil && req.AssessmentDate != "" {
+		assessmentDate, err := h.validateBackfillDate(subjectEmail, req.AssessmentDate)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		scope.AssessmentDate = assessmentDate
+	}
+
+	if bindErr == nil && req.ForceNew {
 		// If force_new is true, don't check for existing state
-		h.createNewFormState(c, userEmail.(string))
+		h.createNewFormState(c, subjectEmail, scope, req.ClientTime)
 		return
 	}
 
 	// Check if user has an active form state
-	existingState, err := h.repo.FormStates.GetUserActiveFormState(userEmail.(string))
+	existingState, err := h.repo.ForUser(subjectEmail).FormStates.GetUserActiveFormState(subjectEmail, scope)
 	if err != nil {
 		// Only create new state if error is NOT a "not found" error
 		if !errors.Is(err, gorm.ErrRecordNotFound) {
@@ -71,57 +133,290 @@ func (h *FormHandler) InitForm(c *gin.Context) {
 	} else if existingState != nil {
 		// Return existing form state
 		h.log.Infow("Using existing form state", "user", userEmail.(string), "stateId", existingState.ID)
+		h.recordClockSample(existingState, req.ClientTime)
 		c.JSON(http.StatusOK, existingState)
 		return
 	}
 
 	// Create new form state
-	h.createNewFormState(c, userEmail.(string))
+	h.createNewFormState(c, subjectEmail, scope, req.ClientTime)
+}
+
+// rejectWithdrawn stops assessments for a participant who has withdrawn
+// from their study, responding with 403. Reports whether it did.
+func (h *FormHandler) rejectWithdrawn(c *gin.Context, subjectEmail string) bool {
+	subject, err := h.repo.Users.GetByEmail(subjectEmail)
+	if err != nil || subject == nil {
+		h.log.Errorw("Error retrieving assessment subject", "error", err, "email", subjectEmail)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return true
+	}
+	if subject.WithdrawnAt != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Withdrawn from the study; no further assessments are accepted"})
+		return true
+	}
+	return false
+}
+
+// liveDataSkipReason says why a form's interaction and cognitive test data
+// are not recorded, or returns "" when they are. The data describe whoever
+// held the device while filling the form in, which for a caregiver report is
+// not the participant, and for an entry recalled for an earlier day is not
+// how they were on that day.
+func liveDataSkipReason(formState *models.FormState) string {
+	switch {
+	case formState.ReportedBy != nil:
+		return "caregiver report"
+	case formState.AssessmentDate != nil:
+		return "retrospective entry"
+	}
+	return ""
+}
+
+// validateBackfillDate checks that a retrospective entry targets a missed day within the backfill window
+func (h *FormHandler) validateBackfillDate(userEmail, dateStr string) (*time.Time, error) {
+	if h.config.BackfillDays <= 0 {
+		return nil, fmt.Errorf("retrospective entries are disabled")
+	}
+
+	day := h.repo.AssessmentDay()
+	if user, err := h.repo.Users.GetByEmail(userEmail); err == nil && user != nil {
+		day = h.repo.Users.AssessmentDayFor(user)
+	}
+
+	assessmentDate, err := day.Date(dateStr)
+	if err != nil {
+		return nil, fmt.Errorf("assessment_date must be in YYYY-MM-DD format")
+	}
+
+	today := day.Today()
+	if !assessmentDate.Before(today) {
+		return nil, fmt.Errorf("retrospective entries must be for a previous day")
+	}
+	if assessmentDate.Before(day.AddDays(today, -h.config.BackfillDays)) {
+		return nil, fmt.Errorf("retrospective entries are limited to the last %d days", h.config.BackfillDays)
+	}
+
+	exists, err := h.repo.ForUser(userEmail).Assessments.HasAssessmentForDate(userEmail, assessmentDate)
+	if err != nil {
+		h.log.Errorw("Error checking existing assessment for date", "error", err)
+		return nil, fmt.Errorf("unable to verify assessment date")
+	}
+	if exists {
+		return nil, fmt.Errorf("an assessment already exists for %s", dateStr)
+	}
+
+	return &assessmentDate, nil
+}
+
+// getFormState loads a form state from the requesting user's organization.
+// Caregivers therefore reach only participants whose data shares their schema.
+// The state loaded by FormStateOwner is reused.
+func (h *FormHandler) getFormState(c *gin.Context, stateID string) (*models.FormState, error) {
+	if cached, ok := c.Get("formState"); ok {
+		return cached.(*models.FormState), nil
+	}
+	return h.repo.ForUser(c.GetString("userEmail")).FormStates.GetByID(stateID)
+}
+
+// FormStateOwner is the middleware.OwnerFunc for form state routes.
+// Caregiver-started forms belong to the caregiver until submitted.
+func (h *FormHandler) FormStateOwner(c *gin.Context) (string, error) {
+	formState, err := h.getFormState(c, c.Param("stateId"))
+	if err != nil {
+		return "", err
+	}
+	if formState == nil {
+		return "", errors.New("form state not found")
+	}
+	c.Set("formState", formState)
+
+	if formState.ReportedBy != nil {
+		return *formState.ReportedBy, nil
+	}
+	return formState.UserEmail, nil
 }
 
 // Helper function to create a new form state
-func (h *FormHandler) createNewFormState(c *gin.Context, userEmail string) {
+func (h *FormHandler) createNewFormState(c *gin.Context, userEmail string, scope repository.FormStateScope, clientTime float64) {
 	// Get all questions
-	questions := h.questionLoader.GetQuestions()
+	loader := questionsForUser(h.repo, h.questions, h.log, userEmail)
+	questions := loader.GetQuestions()
 
-	// Create randomized question order
-	questionOrder := make([]int, len(questions))
-	for i := range questionOrder {
-		questionOrder[i] = i
+	// Questions with an every_days rule are left out on days they aren't due
+	day := h.repo.AssessmentDay()
+	if user, err := h.repo.Users.GetByEmail(userEmail); err == nil && user != nil {
+		day = h.repo.Users.AssessmentDayFor(user)
+	}
+	formDay := day.Today()
+	if scope.AssessmentDate != nil {
+		formDay = *scope.AssessmentDate
+	}
+	included, err := h.dueQuestions(userEmail, questions, formDay)
+	if err != nil {
+		h.log.Errorw("Error checking question schedule", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error initializing form"})
+		return
+	}
+
+	// Adaptive mode serves only the core questions while answers are stable
+	variant, err := h.formVariant(userEmail, loader.Config.Adaptive, questions, formDay)
+	if err != nil {
+		h.log.Errorw("Error choosing adaptive form variant", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error initializing form"})
+		return
+	}
+	if variant == formVariantShort {
+		included = coreQuestions(questions, included)
+	}
+
+	order, err := h.questionOrder(userEmail, loader.Ordering(), len(included))
+	if err != nil {
+		h.log.Errorw("Error choosing question order", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error initializing form"})
+		return
+	}
+	order.Variant = variant
+	for i, index := range order.Indexes {
+		order.Indexes[i] = included[index]
 	}
-	rand.Shuffle(len(questionOrder), func(i, j int) {
-		questionOrder[i], questionOrder[j] = questionOrder[j], questionOrder[i]
-	})
 
 	// Create new form state
-	formState, err := h.repo.FormStates.Create(userEmail, questionOrder)
+	formState, err := h.repo.ForUser(userEmail).FormStates.Create(userEmail, scope, order)
 	if err != nil {
 		h.log.Errorw("Error creating form state", "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error initializing form"})
 		return
 	}
 
+	h.recordQuestionEvent(formState.ID, models.QuestionEventStart, "", questionAtStep(questions, order.Indexes, 0), 0)
+	h.recordClockSample(formState, clientTime)
+
 	c.JSON(http.StatusOK, formState)
 }
 
+// recordClockSample compares the device clock, in epoch milliseconds, with
+// the server's. The first sample sets the form's skew, and later ones track
+// the largest drift from it. Network latency is part of every sample, so
+// small drifts are noise. Failures are logged and never interrupt the form.
+func (h *FormHandler) recordClockSample(formState *models.FormState, clientTime float64) {
+	if clientTime <= 0 {
+		return
+	}
+	sample := clientTime - float64(time.Now().UnixMilli())
+	if formState.ClockSkewMs == nil {
+		formState.ClockSkewMs = &sample
+	} else {
+		drift := math.Abs(sample - *formState.ClockSkewMs)
+		if drift <= formState.ClockDriftMs {
+			return
+		}
+		formState.ClockDriftMs = drift
+	}
+
+	if err := h.repo.ForUser(formState.UserEmail).FormStates.UpdateClock(formState.ID, formState.ClockSkewMs, formState.ClockDriftMs); err != nil {
+		h.log.Warnw("Failed to record clock sample", "error", err, "stateId", formState.ID)
+	}
+}
+
+// clockSkew is how far ahead of the server a form's device clock was
+func clockSkew(formState *models.FormState) time.Duration {
+	if formState.ClockSkewMs == nil {
+		return 0
+	}
+	return time.Duration(*formState.ClockSkewMs * float64(time.Millisecond))
+}
+
+// errFormAlreadySubmitted means a concurrent request submitted the form first
+var errFormAlreadySubmitted = errors.New("form already submitted")
+
+// maxQuestionDuration caps the time recorded for one question, so forms left
+// open in a background tab don't skew the averages
+const maxQuestionDuration = 30 * time.Minute
+
+// recordQuestionEvent stores a navigation event for question analytics.
+// Failures are logged and never interrupt the form.
+func (h *FormHandler) recordQuestionEvent(formStateID, event, questionID, shownQuestionID string, duration time.Duration) {
+	err := h.repo.QuestionAnalytics.RecordEvent(&models.QuestionEvent{
+		FormStateID:     formStateID,
+		Event:           event,
+		QuestionID:      questionID,
+		ShownQuestionID: shownQuestionID,
+		DurationMs:      min(duration, maxQuestionDuration).Milliseconds(),
+	})
+	if err != nil {
+		h.log.Warnw("Failed to record question event", "error", err, "event", event)
+	}
+}
+
+// questionOrder chooses the order of a new form's n included questions under
+// the questions file's scheme. Random orders are shuffled from a seed kept
+// with the form, so they can be reproduced for an audit. Latin square orders
+// step through the square's rows with each assessment the user submits,
+// starting from a row picked by their email so that users don't all begin on
+// the same one.
+func (h *FormHandler) questionOrder(email, scheme string, n int) (repository.FormOrder, error) {
+	switch scheme {
+	case utils.OrderingFixed:
+		indexes := make([]int, n)
+		for i := range indexes {
+			indexes[i] = i
+		}
+		return repository.FormOrder{Indexes: indexes, Scheme: scheme}, nil
+
+	case utils.OrderingLatinSquare:
+		submitted, err := h.repo.ForUser(email).Assessments.CountByUser(email)
+		if err != nil {
+			return repository.FormOrder{}, err
+		}
+		start := fnv.New32a()
+		start.Write([]byte(strings.ToLower(email)))
+		row := int((uint64(start.Sum32()) + uint64(submitted)) % uint64(utils.LatinSquareRows(n)))
+		return repository.FormOrder{Indexes: utils.LatinSquareOrder(n, row), Scheme: scheme, Row: &row}, nil
+	}
+
+	seed := rand.Int64()
+	return repository.FormOrder{Indexes: shuffledQuestionOrder(n, seed), Scheme: utils.OrderingRandom, Seed: &seed}, nil
+}
+
+// shuffledQuestionOrder returns the indexes of n questions shuffled by a PCG
+// source seeded with (seed, 0). The same seed and question count always give
+// the same order.
+func shuffledQuestionOrder(n int, seed int64) []int {
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+	rng := rand.New(rand.NewPCG(uint64(seed), 0))
+	rng.Shuffle(n, func(i, j int) {
+		order[i], order[j] = order[j], order[i]
+	})
+	return order
+}
+
+// questionAtStep returns the ID of the question shown at a step, or an empty
+// string once every question has been shown
+func questionAtStep(questions []utils.Question, questionOrder []int, step int) string {
+	if step < 0 || step >= len(questionOrder) {
+		return ""
+	}
+	if index := questionOrder[step]; index >= 0 && index < len(questions) {
+		return questions[index].ID
+	}
+	return ""
+}
+
 // GetCurrentQuestion gets the current question for a form state
 func (h *FormHandler) GetCurrentQuestion(c *gin.Context) {
 	stateID := c.Param("stateId")
 
 	// Get form state
-	formState, err := h.repo.FormStates.GetByID(stateID)
+	formState, err := h.getFormState(c, stateID)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Form state not found"})
 		return
 	}
 
-	// Verify user owns this form state
-	userEmail, _ := c.Get("userEmail")
-	if formState.UserEmail != userEmail.(string) {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
-		return
-	}
-
 	// Parse the question order from JSON string
 	var questionOrder []int
 	if err := json.Unmarshal([]byte(formState.QuestionOrder), &questionOrder); err != nil {
@@ -131,7 +426,7 @@ func (h *FormHandler) GetCurrentQuestion(c *gin.Context) {
 	}
 
 	// Get all questions
-	questions := h.questionLoader.GetQuestions()
+	questions := questionsForUser(h.repo, h.questions, h.log, formState.UserEmail).GetQuestions()
 
 	// Check if we've shown all questions
 	if formState.CurrentStep >= len(questionOrder) {
@@ -141,6 +436,7 @@ func (h *FormHandler) GetCurrentQuestion(c *gin.Context) {
 			"message":  "All questions answered",
 			"question": questions[questionOrder[len(questionOrder)-1]],
 			"answers":  formState.Answers,
+			"nonce":    formState.Nonce,
 		})
 		return
 	}
@@ -158,8 +454,8 @@ func (h *FormHandler) GetCurrentQuestion(c *gin.Context) {
 		return
 	}
 
-	// Get the question
-	question := questions[questionIndex]
+	// Get the question, with cognitive tests set up for the user's study and preferences
+	question := h.servedQuestion(formState.UserEmail, questions[questionIndex])
 
 	// Get previous answer if available
 	var previousAnswer any
@@ -167,12 +463,26 @@ func (h *FormHandler) GetCurrentQuestion(c *gin.Context) {
 		previousAnswer = val
 	}
 
+	// Some questions start from the user's answer in their last assessment
+	var prefillAnswer any
+	if question.PrefillPrevious {
+		previous, err := h.repo.ForUser(formState.UserEmail).Assessments.
+			GetPreviousResponses(formState.UserEmail, []string{question.ID}, 0)
+		if err != nil {
+			h.log.Warnw("Failed to load previous answer for prefill", "error", err, "question_id", question.ID)
+		} else if response, ok := previous[question.ID]; ok {
+			prefillAnswer = responseAnswer(response)
+		}
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"state":           "question",
 		"current_step":    formState.CurrentStep + 1,
 		"total_steps":     len(questionOrder),
 		"question":        question,
 		"previous_answer": previousAnswer,
+		"prefill_answer":  prefillAnswer,
+		"nonce":           formState.Nonce,
 	})
 }
 
@@ -184,22 +494,17 @@ func (h *FormHandler) SaveAnswer(c *gin.Context) {
 	req := c.MustGet("validatedRequest").(*validation.SaveAnswerRequest)
 
 	if answer, ok := req.Answer.(string); ok {
-		req.Answer = utils.SanitizeHTML(answer)
+		req.Answer = h.sanitizer.Answer(answer)
 	}
 
 	// Get form state
-	formState, err := h.repo.FormStates.GetByID(stateID)
+	formState, err := h.getFormState(c, stateID)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Form state not found"})
 		return
 	}
 
-	// Verify user owns this form state
-	userEmail, _ := c.Get("userEmail")
-	if formState.UserEmail != userEmail.(string) {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
-		return
-	}
+	h.recordClockSample(formState, req.ClientTime)
 
 	questionId := req.QuestionID
 	answer := req.Answer
@@ -217,6 +522,7 @@ func (h *FormHandler) SaveAnswer(c *gin.Context) {
 		} else {
 			formState.InteractionData = compressed
 		}
+		formState.IntegrityHash = req.IntegrityHash
 	}
 
 	// If CPT data is provided, save it as raw data
@@ -260,6 +566,16 @@ func (h *FormHandler) SaveAnswer(c *gin.Context) {
 		return
 	}
 
+	// Time on this question runs from the previous save, or the form's start
+	timeOnQuestion := min(time.Since(formState.LastUpdatedAt), maxQuestionDuration)
+	previousStep := formState.CurrentStep
+
+	if formState.StepDurations == nil {
+		formState.StepDurations = models.JSON{}
+	}
+	spent, _ := formState.StepDurations[questionId].(float64)
+	formState.StepDurations[questionId] = spent + timeOnQuestion.Seconds()
+
 	// Update step based on direction
 	if direction == "next" && formState.CurrentStep < len(questionOrder) {
 		formState.CurrentStep++
@@ -269,11 +585,17 @@ func (h *FormHandler) SaveAnswer(c *gin.Context) {
 	}
 
 	// Save form state
-	if err := h.repo.FormStates.Update(formState); err != nil {
+	if err := h.repo.ForUser(formState.UserEmail).FormStates.Update(formState); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error saving answer"})
 		return
 	}
 
+	if formState.CurrentStep != previousStep {
+		questions := questionsForUser(h.repo, h.questions, h.log, formState.UserEmail).GetQuestions()
+		h.recordQuestionEvent(formState.ID, direction, questionId,
+			questionAtStep(questions, questionOrder, formState.CurrentStep), timeOnQuestion)
+	}
+
 	// Return the updated form state
 	c.JSON(http.StatusOK, gin.H{
 		"success":   true,
@@ -304,19 +626,12 @@ func (h *FormHandler) SubmitForm(c *gin.Context) {
 	}
 
 	// Get form state
-	formState, err := h.repo.FormStates.GetByID(stateId)
+	formState, err := h.getFormState(c, stateId)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Form state not found"})
 		return
 	}
 
-	// Verify user owns this form state
-	userEmail, _ := c.Get("userEmail")
-	if formState.UserEmail != userEmail.(string) {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
-		return
-	}
-
 	// Get device ID
 	deviceID := getDeviceID(c)
 	if deviceID == "" {
@@ -324,9 +639,111 @@ func (h *FormHandler) SubmitForm(c *gin.Context) {
 		return
 	}
 
+	// Attribute proctored assessments to the supervising clinician
+	var supervisedBy sql.NullString
+	kioskSessionID, isKiosk := c.Get("kioskSessionID")
+	if isKiosk {
+		session, err := h.repo.KioskSessions.GetActive(kioskSessionID.(string))
+		if err != nil {
+			h.log.Warnw("Kiosk session not active at submission", "error", err, "session_id", kioskSessionID)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Kiosk session has ended"})
+			return
+		}
+		supervisedBy = sql.NullString{String: session.ClinicianEmail, Valid: true}
+	}
+
+	// Caregiver submissions require the link to still be active
+	subjectEmail := formState.UserEmail
+	isProxy := formState.ReportedBy != nil
+	if isProxy {
+		linked, err := h.repo.CaregiverLinks.IsActiveCaregiver(subjectEmail, *formState.ReportedBy)
+		if err != nil || !linked {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Not an active caregiver for this user"})
+			return
+		}
+	}
+
+	if formState.AssessmentID != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "This form has already been submitted"})
+		return
+	}
+	if h.rejectWithdrawn(c, subjectEmail) {
+		return
+	}
+
+	isRetrospective := formState.AssessmentDate != nil
+
+	// Completion timing; unusually fast forms are flagged for data quality
+	submittedAt := time.Now()
+	durationSeconds := submittedAt.Sub(formState.StartedAt).Seconds()
+	var questionOrder []int
+	json.Unmarshal([]byte(formState.QuestionOrder), &questionOrder)
+	fastCompletion := h.config.MinSecondsPerQuestion > 0 &&
+		durationSeconds < h.config.MinSecondsPerQuestion*float64(len(questionOrder))
+	// Keep the shown order by question ID, so it survives changes to the
+	// question set
+	questions := questionsForUser(h.repo, h.questions, h.log, formState.UserEmail).GetQuestions()
+	shownOrder := make([]string, 0, len(questionOrder))
+	for step := range questionOrder {
+		shownOrder = append(shownOrder, questionAtStep(questions, questionOrder, step))
+	}
+	shownOrderJSON, _ := json.Marshal(shownOrder)
+	// Reaction times from a device that was struggling are flagged
+	slowDevice, performance := h.perf.Assess(req.PerfSessionID)
+	// So are forms whose device clock drifted while they were filled in
+	h.recordClockSample(formState, req.ClientTime)
+	var clockDriftMs *float64
+	if formState.ClockSkewMs != nil {
+		clockDriftMs = &formState.ClockDriftMs
+	}
+	clockDrift := h.config.MaxClockDriftMs > 0 && formState.ClockDriftMs > h.config.MaxClockDriftMs
+	skew := clockSkew(formState)
+	// Interaction data that fails the integrity checks may have been tampered with
+	integrity := h.checkIntegrity(formState, req.Nonce, questions, submittedAt)
+	if integrity.Verdict == metrics.IntegritySuspect {
+		h.log.Warnw("Interaction data failed integrity checks", "stateId", formState.ID, "issues", integrity.Issues)
+	}
+	// Likely bots are kept but left out of analytics
+	botSignals := h.botSignals(formState, questions, len(questionOrder), durationSeconds)
+	if len(botSignals) > 0 {
+		h.log.Warnw("Submission looks automated", "stateId", formState.ID, "signals", botSignals)
+	}
+
+	// Live entries count towards the subject's current assessment day
+	assessmentDay := formState.AssessmentDate
+	if !isRetrospective {
+		day := h.repo.AssessmentDay()
+		if subject, err := h.repo.Users.GetByEmail(subjectEmail); err == nil && subject != nil {
+			day = h.repo.Users.AssessmentDayFor(subject)
+		}
+		today := day.Of(submittedAt)
+		assessmentDay = &today
+	}
+
+	// Custom metrics are computed from the metrics stored below; a failure
+	// here leaves them for the next recompute rather than failing the submit
+	customFormulas, err := h.repo.CustomMetrics.Formulas()
+	if err != nil {
+		h.log.Warnw("Error loading custom metric formulas", "error", err)
+	}
+
 	// Use a transaction for the entire submission process
 	var assessmentID uint
-	err = h.repo.WithTransaction(func(tx *gorm.DB) error {
+	var answers []models.QuestionResponse
+	err = h.repo.ForUser(subjectEmail).WithTransaction(func(tx *gorm.DB) error {
+		// Lock the form state so a submission from another tab waits for this
+		// one, then finds the form already completed
+		var locked models.FormState
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("id", "assessment_id").
+			Where("id = ?", formState.ID).
+			First(&locked).Error; err != nil {
+			return err
+		}
+		if locked.AssessmentID != nil {
+			return errFormAlreadySubmitted
+		}
+
 		// Use sql.NullFloat64 and sql.NullString for nullable fields
 		var lat sql.NullFloat64
 		var lon sql.NullFloat64
@@ -339,19 +756,38 @@ func (h *FormHandler) SubmitForm(c *gin.Context) {
 			lon = sql.NullFloat64{Float64: *req.Longitude, Valid: true}
 		}
 		if req.LocationError != nil {
-			locErr = sql.NullString{String: *req.LocationError, Valid: true}
+			locErr = sql.NullString{String: h.sanitizer.Text(*req.LocationError), Valid: true}
 		}
 
 		// Create assessment using direct SQL for better performance
 		if err := tx.Raw(`
-            INSERT INTO assessments (user_email, device_id, submitted_at, location_permission, latitude, longitude, location_error)
-            VALUES (?, ?, ?, ?, ?, ?, ?)
+            INSERT INTO assessments (user_email, device_id, submitted_at, location_permission, latitude, longitude, location_error, supervised_by, reported_by, is_retrospective, assessment_date,
+                assessment_day, started_at, duration_seconds, step_durations, fast_completion, slow_device, performance,
+                clock_skew_ms, clock_drift_ms, clock_drift, order_scheme, order_seed, order_row, question_order, form_variant,
+                integrity_verdict, integrity_issues, likely_bot, bot_signals)
+            VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
             RETURNING id
-            `, userEmail.(string), deviceID, time.Now(), req.LocationPermission, lat, lon, locErr).
+            `, subjectEmail, deviceID, submittedAt, req.LocationPermission, lat, lon, locErr, supervisedBy, formState.ReportedBy,
+			isRetrospective, formState.AssessmentDate,
+			assessmentDay, formState.StartedAt, durationSeconds, formState.StepDurations, fastCompletion, slowDevice, performance,
+			formState.ClockSkewMs, clockDriftMs, clockDrift, formState.OrderScheme, formState.OrderSeed, formState.OrderRow, string(shownOrderJSON), formState.FormVariant,
+			integrity.Verdict, strings.Join(integrity.Issues, ","), len(botSignals) > 0, strings.Join(botSignals, ",")).
 			Scan(&assessmentID).Error; err != nil {
 			return err
 		}
 
+		if reason := liveDataSkipReason(formState); reason != "" {
+			h.log.Infow("Skipping interaction and cognitive data",
+				"assessment_id", assessmentID, "reason", reason)
+			formState.InteractionData = nil
+			formState.CPTData = nil
+			formState.TMTData = nil
+			formState.DigitSpanData = nil
+		} else if err := h.saveQuestionDurations(assessmentID, formState.StepDurations, tx); err != nil {
+			h.log.Warnw("Error saving question durations", "error", err)
+			return err
+		}
+
 		// Process interaction data if available
 		if len(formState.InteractionData) > 0 {
 			err := h.processInteractionData(assessmentID, formState.InteractionData, tx)
@@ -363,7 +799,7 @@ func (h *FormHandler) SubmitForm(c *gin.Context) {
 
 		// Process CPT data if available
 		if len(formState.CPTData) > 0 {
-			err := h.processCPTData(assessmentID, userEmail.(string), deviceID, formState.CPTData, tx)
+			err := h.processCPTData(assessmentID, subjectEmail, deviceID, formState.CPTData, h.testSettings(subjectEmail, "cpt"), skew, tx)
 			if err != nil {
 				h.log.Warnw("Error processing CPT data", "error", err)
 				return err
@@ -372,7 +808,7 @@ func (h *FormHandler) SubmitForm(c *gin.Context) {
 
 		// Process Trail Making Test data if available
 		if len(formState.TMTData) > 0 {
-			err := h.processTMTData(assessmentID, userEmail.(string), deviceID, formState.TMTData, tx)
+			err := h.processTMTData(assessmentID, subjectEmail, deviceID, formState.TMTData, h.testSettings(subjectEmail, "tmt"), skew, tx)
 			if err != nil {
 				h.log.Warnw("Error processing TMT data", "error", err)
 				return err
@@ -380,13 +816,18 @@ func (h *FormHandler) SubmitForm(c *gin.Context) {
 		}
 
 		if len(formState.DigitSpanData) > 0 {
-			err := h.processDigitSpanData(assessmentID, userEmail.(string), deviceID, formState.DigitSpanData, tx)
+			err := h.processDigitSpanData(assessmentID, subjectEmail, deviceID, formState.DigitSpanData, h.testSettings(subjectEmail, "digit_span"), skew, tx)
 			if err != nil {
 				h.log.Warnw("Error processing Digit Span data", "error", err)
 				return err
 			}
 		}
 
+		if err := h.repo.ForUser(subjectEmail).Assessments.ApplyCustomMetrics(tx, []uint{assessmentID}, customFormulas); err != nil {
+			h.log.Errorw("Error computing custom metrics", "error", err)
+			return err
+		}
+
 		// Process form answers and save as question responses
 		questionResponses, err := h.processFormAnswers(formState, assessmentID)
 		if err != nil {
@@ -394,14 +835,16 @@ func (h *FormHandler) SubmitForm(c *gin.Context) {
 			return err
 		}
 
+		answers = questionResponses
+
 		if len(questionResponses) > 0 {
 			// Use batch insert with VALUES clause for better performance
 			valueStrings := make([]string, 0, len(questionResponses))
-			valueArgs := make([]any, 0, len(questionResponses)*6)
+			valueArgs := make([]any, 0, len(questionResponses)*7)
 
 			for i, response := range questionResponses {
-				valueStrings = append(valueStrings, fmt.Sprintf("($%d, $%d, $%d, $%d, $%d, $%d)",
-					i*6+1, i*6+2, i*6+3, i*6+4, i*6+5, i*6+6))
+				valueStrings = append(valueStrings, fmt.Sprintf("($%d, $%d, $%d, $%d, $%d, $%d, $%d)",
+					i*7+1, i*7+2, i*7+3, i*7+4, i*7+5, i*7+6, i*7+7))
 
 				valueArgs = append(valueArgs,
 					response.AssessmentID,
@@ -409,10 +852,11 @@ func (h *FormHandler) SubmitForm(c *gin.Context) {
 					response.ValueType,
 					response.NumericValue,
 					response.TextValue,
+					response.ChangedFromPrevious,
 					response.CreatedAt)
 			}
 
-			stmt := fmt.Sprintf("INSERT INTO question_responses (assessment_id, question_id, value_type, numeric_value, text_value, created_at) VALUES %s",
+			stmt := fmt.Sprintf("INSERT INTO question_responses (assessment_id, question_id, value_type, numeric_value, text_value, changed_from_previous, created_at) VALUES %s",
 				strings.Join(valueStrings, ","))
 
 			if err := tx.Exec(stmt, valueArgs...).Error; err != nil {
@@ -429,9 +873,14 @@ func (h *FormHandler) SubmitForm(c *gin.Context) {
 			return err
 		}
 
+		// Backfilled days don't count towards today's completion
+		if isRetrospective {
+			return nil
+		}
+
 		// Set last assessment completed time to now
 		if err := tx.Model(&models.User{}).
-			Where("LOWER(email) = ?", userEmail.(string)).
+			Where("LOWER(email) = ?", subjectEmail).
 			Update("last_assessment_date", time.Now()).Error; err != nil {
 			return err
 		}
@@ -439,15 +888,160 @@ func (h *FormHandler) SubmitForm(c *gin.Context) {
 		return nil
 	})
 
-	if err != nil {
+	switch {
+	case errors.Is(err, errFormAlreadySubmitted):
+		c.JSON(http.StatusConflict, gin.H{"error": "This form has already been submitted"})
+		return
+	case repository.IsUniqueViolation(err):
+		h.log.Infow("Rejected second assessment for the same day", "stateId", stateId)
+		c.JSON(http.StatusConflict, gin.H{"error": "An assessment has already been submitted for this day"})
+		return
+	case err != nil:
 		h.log.Errorw("Error submitting form", "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error processing form submission"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"success":       true,
-		"assessment_id": assessmentID,
+	h.recordQuestionEvent(formState.ID, models.QuestionEventSubmit, "", "", 0)
+
+	// Charts fall back to live queries until this runs; the nightly refresh
+	// retries if it fails
+	h.repo.ForUser(subjectEmail).ChartSummaries.Refresh(assessmentID)
+
+	// Kiosk sessions end automatically on submit
+	if isKiosk {
+		endKioskSession(c, h.repo, h.log, h.authService.GetCookieConfig(), kioskSessionID.(string), &assessmentID)
+	}
+
+	response := gin.H{
+		"success":          true,
+		"assessment_id":    assessmentID,
+		"logged_out":       isKiosk,
+		"is_retrospective": isRetrospective,
+	}
+	loader := questionsForUser(h.repo, h.questions, h.log, subjectEmail)
+	feedback := h.submissionFeedback(subjectEmail, *assessmentDay, answers, loader.GetRadioQuestions())
+	response["feedback"] = feedback
+	awarded, err := h.achievements.Award(subjectEmail, assessmentID, feedback.Streak, loader.GetQuestions())
+	if err != nil {
+		h.log.Warnw("Error awarding achievements", "error", err, "assessment_id", assessmentID)
+	}
+	if awarded == nil {
+		awarded = []services.AchievementDefinition{}
+	}
+	response["achievements"] = awarded
+	if raised, showResources := h.raiseRedFlags(subjectEmail, assessmentID, answers, loader.GetQuestions()); raised {
+		response["red_flag"] = true
+		if showResources {
+			response["crisis_resources"] = loader.Config.CrisisResources
+		}
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// raiseRedFlags creates an alert for each answer at its question's red-flag
+// level and dispatches it in the background. It reports whether any were
+// raised, and whether any of their questions show crisis resources.
+func (h *FormHandler) raiseRedFlags(email string, assessmentID uint, answers []models.QuestionResponse, questions []utils.Question) (raised, showResources bool) {
+	flagged := make(map[string]utils.Question)
+	for _, q := range questions {
+		if q.RedFlag != nil {
+			flagged[q.ID] = q
+		}
+	}
+	if len(flagged) == 0 {
+		return false, false
+	}
+
+	orgID := ""
+	for _, answer := range answers {
+		q, ok := flagged[answer.QuestionID]
+		if !ok || answer.ValueType != "number" || !q.RedFlag.Raised(answer.NumericValue) {
+			continue
+		}
+		if !raised {
+			if user, err := h.repo.Users.GetByEmail(email); err == nil && user != nil {
+				orgID = user.OrganizationID
+			}
+		}
+		raised = true
+		showResources = showResources || q.RedFlag.ShowResources
+
+		alert := &models.RedFlagAlert{
+			UserEmail:      email,
+			OrganizationID: orgID,
+			AssessmentID:   assessmentID,
+			QuestionID:     q.ID,
+			QuestionTitle:  q.Title,
+			Value:          answer.NumericValue,
+		}
+		if err := h.repo.Thresholds.CreateRedFlagAlert(alert); err != nil {
+			h.log.Errorw("Error creating red-flag alert", "error", err, "assessment_id", assessmentID, "question_id", q.ID)
+			continue
+		}
+		h.log.Warnw("Red-flag answer submitted", "alert", alert.ID, "assessment_id", assessmentID, "question_id", q.ID)
+		if h.alerts != nil {
+			go h.alerts.DispatchRedFlag(alert)
+		}
+	}
+	return raised, showResources
+}
+
+// saveQuestionDurations stores the time spent on each question as a metric,
+// so it can be charted against symptoms like the interaction metrics
+func (h *FormHandler) saveQuestionDurations(assessmentID uint, durations models.JSON, tx *gorm.DB) error {
+	metrics := make([]models.AssessmentMetric, 0, len(durations))
+	for questionID, value := range durations {
+		seconds, ok := value.(float64)
+		if !ok {
+			continue
+		}
+		metrics = append(metrics, models.AssessmentMetric{
+			AssessmentID: assessmentID,
+			QuestionID:   questionID,
+			MetricKey:    models.MetricQuestionDuration,
+			MetricValue:  seconds,
+			SampleSize:   1,
+			CreatedAt:    time.Now(),
+		})
+	}
+	if len(metrics) == 0 {
+		return nil
+	}
+	return tx.Create(&metrics).Error
+}
+
+// checkIntegrity checks the interaction data saved with a form against the
+// form's nonce, its answers and how long it was open
+func (h *FormHandler) checkIntegrity(formState *models.FormState, echoedNonce string, questions []utils.Question, submittedAt time.Time) metrics.IntegrityResult {
+	var data *metrics.InteractionData
+	if len(formState.InteractionData) > 0 {
+		decompressedData, err := utils.DecompressData(formState.InteractionData)
+		if err != nil {
+			decompressedData = formState.InteractionData
+		}
+		var interactions metrics.InteractionData
+		if err := json.Unmarshal(decompressedData, &interactions); err != nil {
+			h.log.Warnw("Error parsing interaction data for integrity checks", "error", err)
+		} else {
+			data = &interactions
+		}
+	}
+
+	// Cognitive tests take their input on their own screens
+	answers := 0
+	for _, question := range questions {
+		if _, ok := formState.Answers[question.ID]; ok && !utils.IsCognitiveTest(question.Type) {
+			answers++
+		}
+	}
+
+	return metrics.CheckIntegrity(data, metrics.IntegrityInput{
+		Nonce:       formState.Nonce,
+		EchoedNonce: echoedNonce,
+		Hash:        formState.IntegrityHash,
+		Answers:     answers,
+		Elapsed:     submittedAt.Sub(formState.StartedAt),
 	})
 }
 
@@ -514,7 +1108,7 @@ func (h *FormHandler) processInteractionData(assessmentID uint, data []byte, tx
 	return nil
 }
 
-func (h *FormHandler) processCPTData(assessmentID uint, userEmail, deviceID string, data []byte, tx *gorm.DB) error {
+func (h *FormHandler) processCPTData(assessmentID uint, userEmail, deviceID string, data []byte, settings servedSettings, skew time.Duration, tx *gorm.DB) error {
 	// Decompress the CPT data first
 	decompressedData, err := utils.DecompressData(data)
 	if err != nil {
@@ -539,6 +1133,8 @@ func (h *FormHandler) processCPTData(assessmentID uint, userEmail, deviceID stri
 		cptResults.UserEmail = userEmail
 		cptResults.DeviceID = deviceID
 		cptResults.AssessmentID = assessmentID
+		cptResults.TestStartTime = cptResults.TestStartTime.Add(-skew)
+		cptResults.TestEndTime = cptResults.TestEndTime.Add(-skew)
 
 		// Save CPT results using direct SQL for better performance
 		if err := tx.Exec(`
@@ -548,14 +1144,14 @@ func (h *FormHandler) processCPTData(assessmentID uint, userEmail, deviceID stri
                             correct_detections, commission_errors, omission_errors,
                             average_reaction_time, reaction_time_sd,
                             detection_rate, omission_error_rate, commission_error_rate,
-                            raw_data, created_at
-                        ) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+                            raw_data, settings, settings_hash, created_at
+                        ) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
 			cptResults.UserEmail, cptResults.DeviceID, cptResults.AssessmentID,
 			cptResults.TestStartTime, cptResults.TestEndTime,
 			cptResults.CorrectDetections, cptResults.CommissionErrors, cptResults.OmissionErrors,
 			cptResults.AverageReactionTime, cptResults.ReactionTimeSD,
 			cptResults.DetectionRate, cptResults.OmissionErrorRate, cptResults.CommissionErrorRate,
-			cptResults.RawData, time.Now()).Error; err != nil {
+			cptResults.RawData, settings.JSON, settings.Hash, time.Now()).Error; err != nil {
 			h.log.Warnw("Error saving CPT results", "error", err)
 			return err
 
@@ -564,7 +1160,7 @@ func (h *FormHandler) processCPTData(assessmentID uint, userEmail, deviceID stri
 	return nil
 }
 
-func (h *FormHandler) processTMTData(assessmentID uint, userEmail, deviceID string, data []byte, tx *gorm.DB) error {
+func (h *FormHandler) processTMTData(assessmentID uint, userEmail, deviceID string, data []byte, settings servedSettings, skew time.Duration, tx *gorm.DB) error {
 	// Decompress the TMT data first
 	decompressedData, err := utils.DecompressData(data)
 	if err != nil {
@@ -589,6 +1185,8 @@ func (h *FormHandler) processTMTData(assessmentID uint, userEmail, deviceID stri
 		tmtResults.UserEmail = userEmail
 		tmtResults.DeviceID = deviceID
 		tmtResults.AssessmentID = assessmentID
+		tmtResults.TestStartTime = tmtResults.TestStartTime.Add(-skew)
+		tmtResults.TestEndTime = tmtResults.TestEndTime.Add(-skew)
 
 		// Save TMT results using direct SQL for better performance
 		if err := tx.Exec(`
@@ -597,13 +1195,13 @@ func (h *FormHandler) processTMTData(assessmentID uint, userEmail, deviceID stri
                     test_start_time, test_end_time,
                     part_a_completion_time, part_a_errors,
                     part_b_completion_time, part_b_errors,
-                    b_to_a_ratio, raw_data, created_at
-                ) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+                    b_to_a_ratio, raw_data, settings, settings_hash, created_at
+                ) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
 			tmtResults.UserEmail, tmtResults.DeviceID, tmtResults.AssessmentID,
 			tmtResults.TestStartTime, tmtResults.TestEndTime,
 			tmtResults.PartACompletionTime, tmtResults.PartAErrors,
 			tmtResults.PartBCompletionTime, tmtResults.PartBErrors,
-			tmtResults.BToARatio, tmtResults.RawData, time.Now()).Error; err != nil {
+			tmtResults.BToARatio, tmtResults.RawData, settings.JSON, settings.Hash, time.Now()).Error; err != nil {
 			h.log.Warnw("Error saving TMT results", "error", err)
 			return err
 
@@ -612,7 +1210,7 @@ func (h *FormHandler) processTMTData(assessmentID uint, userEmail, deviceID stri
 	return nil
 }
 
-func (h *FormHandler) processDigitSpanData(assessmentID uint, userEmail, deviceID string, data []byte, tx *gorm.DB) error {
+func (h *FormHandler) processDigitSpanData(assessmentID uint, userEmail, deviceID string, data []byte, settings servedSettings, skew time.Duration, tx *gorm.DB) error {
 	decompressedData, err := utils.DecompressData(data)
 	if err != nil {
 		h.log.Warnw("Failed to decompress Digit Span data, proceeding with raw bytes", "error", err, "assessment_id", assessmentID)
@@ -637,7 +1235,11 @@ func (h *FormHandler) processDigitSpanData(assessmentID uint, userEmail, deviceI
 		digitSpanResult.UserEmail = userEmail
 		digitSpanResult.DeviceID = deviceID
 		digitSpanResult.AssessmentID = assessmentID
+		digitSpanResult.TestStartTime = digitSpanResult.TestStartTime.Add(-skew)
+		digitSpanResult.TestEndTime = digitSpanResult.TestEndTime.Add(-skew)
 		digitSpanResult.RawData = decompressedData // Save the raw data
+		digitSpanResult.Settings = settings.JSON
+		digitSpanResult.SettingsHash = settings.Hash
 		digitSpanResult.CreatedAt = time.Now()
 
 		// --- Save using the transaction ---
@@ -654,7 +1256,7 @@ func (h *FormHandler) processDigitSpanData(assessmentID uint, userEmail, deviceI
 // ProcessFormAnswers converts formState.Answers map to a slice of QuestionResponse structs
 func (h *FormHandler) processFormAnswers(formState *models.FormState, assessmentID uint) ([]models.QuestionResponse, error) {
 	// Get question definitions to help determine value types
-	allQuestions := h.questionLoader.GetQuestions()
+	allQuestions := questionsForUser(h.repo, h.questions, h.log, formState.UserEmail).GetQuestions()
 	questionMap := make(map[string]utils.Question)
 	for _, q := range allQuestions {
 		questionMap[q.ID] = q
@@ -671,6 +1273,10 @@ func (h *FormHandler) processFormAnswers(formState *models.FormState, assessment
 			h.log.Warnw("Skipping answer for unknown question ID", "question_id", questionID)
 			continue
 		}
+		// Honeypot answers only mark the assessment as a likely bot
+		if question.Honeypot {
+			continue
+		}
 
 		// Check if it's a dropdown and apply default if answer is missing/nil
 		// Use the isEmptyAnswer helper from internal/validation/form_validation.go
@@ -720,64 +1326,32 @@ func (h *FormHandler) processFormAnswers(formState *models.FormState, assessment
 			CreatedAt:    now,
 		}
 
-		// Determine value type and set appropriate field
-		switch value := answerValue.(type) {
-		case float64:
-			// Handle float values (common from JSON)
-			response.ValueType = "number"
-			response.NumericValue = value
-
-		case int:
-			// Handle integer values
-			response.ValueType = "number"
-			response.NumericValue = float64(value)
-
-		case string:
-			// For string values, check if it should be a number
-			if questionExists && (question.Type == "radio" || question.Type == "dropdown") {
-				// Try to convert to float if this is a radio or dropdown
-				if numValue, err := strconv.ParseFloat(value, 64); err == nil {
-					response.ValueType = "number"
-					response.NumericValue = numValue
-				} else {
-					// If conversion fails, store as string
-					response.ValueType = "string"
-					response.TextValue = value
-				}
-			} else {
-				// Regular string answer
-				response.ValueType = "string"
-				response.TextValue = value
-			}
-
-		case bool:
-			// Convert boolean to numeric (1.0 or 0.0)
-			response.ValueType = "boolean"
-			if value {
-				response.NumericValue = 1.0
-			} else {
-				response.NumericValue = 0.0
-			}
+		if !h.setResponseValue(&response, question, answerValue) {
+			continue
+		}
 
-		default:
-			// For other types, convert to string via JSON marshaling
-			h.log.Warnw("Converting unexpected answer type to string",
-				"question_id", questionID,
-				"type", fmt.Sprintf("%T", value))
+		responses = append(responses, response)
+	}
 
-			if bytes, err := json.Marshal(value); err == nil {
-				response.ValueType = "string"
-				response.TextValue = string(bytes)
-			} else {
-				// Skip if we can't convert
-				h.log.Errorw("Failed to convert answer to string",
-					"question_id", questionID,
-					"error", err)
-				continue
+	// Record whether pre-filled answers were kept or actively changed
+	var prefilled []string
+	for _, response := range responses {
+		if questionMap[response.QuestionID].PrefillPrevious {
+			prefilled = append(prefilled, response.QuestionID)
+		}
+	}
+	if len(prefilled) > 0 {
+		previous, err := h.repo.ForUser(formState.UserEmail).Assessments.
+			GetPreviousResponses(formState.UserEmail, prefilled, assessmentID)
+		if err != nil {
+			return nil, err
+		}
+		for i := range responses {
+			if prev, ok := previous[responses[i].QuestionID]; ok {
+				changed := !sameResponse(prev, responses[i])
+				responses[i].ChangedFromPrevious = &changed
 			}
 		}
-
-		responses = append(responses, response)
 	}
 
 	h.log.Infow("Processed form answers",
@@ -786,3 +1360,90 @@ func (h *FormHandler) processFormAnswers(formState *models.FormState, assessment
 
 	return responses, nil
 }
+
+// setResponseValue stores an answer on a response with the value type its
+// question expects. Returns false when the answer can't be stored.
+func (h *FormHandler) setResponseValue(response *models.QuestionResponse, question utils.Question, answerValue any) bool {
+	// Determine value type and set appropriate field
+	switch value := answerValue.(type) {
+	case float64:
+		// Handle float values (common from JSON)
+		response.ValueType = "number"
+		response.NumericValue = value
+
+	case int:
+		// Handle integer values
+		response.ValueType = "number"
+		response.NumericValue = float64(value)
+
+	case string:
+		// For string values, check if it should be a number
+		if question.Type == "radio" || question.Type == "dropdown" {
+			// Try to convert to float if this is a radio or dropdown
+			if numValue, err := strconv.ParseFloat(value, 64); err == nil {
+				response.ValueType = "number"
+				response.NumericValue = numValue
+			} else {
+				// If conversion fails, store as string
+				response.ValueType = "string"
+				response.TextValue = h.sanitizer.Answer(value)
+			}
+		} else {
+			// Regular string answer, cleaned again in case it was saved
+			// under an older policy
+			response.ValueType = "string"
+			response.TextValue = h.sanitizer.Answer(value)
+		}
+
+	case bool:
+		// Convert boolean to numeric (1.0 or 0.0)
+		response.ValueType = "boolean"
+		if value {
+			response.NumericValue = 1.0
+		} else {
+			response.NumericValue = 0.0
+		}
+
+	default:
+		// For other types, convert to string via JSON marshaling
+		h.log.Warnw("Converting unexpected answer type to string",
+			"question_id", question.ID,
+			"type", fmt.Sprintf("%T", value))
+
+		if bytes, err := json.Marshal(value); err == nil {
+			response.ValueType = "string"
+			response.TextValue = string(bytes)
+		} else {
+			// Skip if we can't convert
+			h.log.Errorw("Failed to convert answer to string",
+				"question_id", question.ID,
+				"error", err)
+			return false
+		}
+	}
+	return true
+}
+
+// responseAnswer turns a stored response back into the answer value the
+// form sends
+func responseAnswer(response models.QuestionResponse) any {
+	switch response.ValueType {
+	case "number":
+		return response.NumericValue
+	case "boolean":
+		return response.NumericValue != 0
+	default:
+		return response.TextValue
+	}
+}
+
+// sameResponse reports whether two responses hold the same answer
+func sameResponse(a, b models.QuestionResponse) bool {
+	if a.ValueType != b.ValueType {
+		return false
+	}
+	if a.ValueType == "string" {
+		return a.TextValue == b.TextValue
+	}
+	return a.NumericValue == b.NumericValue
+}
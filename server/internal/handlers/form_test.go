@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/andevellicus/crapp/internal/models"
+)
+
+func TestLiveDataSkipReason(t *testing.T) {
+	carer := "carer@example.com"
+	yesterday := time.Date(2026, time.March, 7, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		state models.FormState
+		want  string
+	}{
+		{name: "live entry", state: models.FormState{}, want: ""},
+		{name: "caregiver report", state: models.FormState{ReportedBy: &carer}, want: "caregiver report"},
+		{name: "retrospective entry", state: models.FormState{AssessmentDate: &yesterday}, want: "retrospective entry"},
+		{name: "retrospective caregiver report", state: models.FormState{ReportedBy: &carer, AssessmentDate: &yesterday}, want: "caregiver report"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := liveDataSkipReason(&tt.state); got != tt.want {
+				t.Errorf("liveDataSkipReason = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
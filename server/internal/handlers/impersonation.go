@@ -0,0 +1,292 @@
+// internal/handlers/impersonation.go
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/andevellicus/crapp/internal/config"
+	"github.com/andevellicus/crapp/internal/models"
+	"github.com/andevellicus/crapp/internal/repository"
+	"github.com/andevellicus/crapp/internal/services"
+	"github.com/andevellicus/crapp/internal/validation"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// ImpersonationHandler lets support admins see the app as a participant
+type ImpersonationHandler struct {
+	repo        *repository.Repository
+	log         *zap.SugaredLogger
+	authService *services.AuthService
+	config      *config.ImpersonationConfig
+}
+
+// NewImpersonationHandler creates a new impersonation handler
+func NewImpersonationHandler(repo *repository.Repository, log *zap.SugaredLogger, authService *services.AuthService, cfg *config.ImpersonationConfig) *ImpersonationHandler {
+	return &ImpersonationHandler{
+		repo:        repo,
+		log:         log.Named("impersonation"),
+		authService: authService,
+		config:      cfg,
+	}
+}
+
+// RequestSession opens an impersonation session for a participant. Without a
+// consent requirement the session starts immediately; otherwise it waits for
+// the participant to approve it.
+func (h *ImpersonationHandler) RequestSession(c *gin.Context) {
+	if !h.config.Enabled {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Impersonation is disabled"})
+		return
+	}
+
+	req := c.MustGet("validatedRequest").(*validation.StartImpersonationRequest)
+	adminEmail := c.GetString("userEmail")
+	targetEmail := strings.ToLower(req.Email)
+
+	if req.WriteAccess && !h.config.AllowWrite {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Write access is not allowed for impersonation"})
+		return
+	}
+	if targetEmail == strings.ToLower(adminEmail) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot impersonate yourself"})
+		return
+	}
+
+	target, err := h.repo.Users.GetByEmail(targetEmail)
+	if err != nil || target == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	// Staff accounts are never impersonated, so no session can gain their rights
+	if target.IsAdmin || target.IsOrgAdmin || target.IsBlindedReviewer {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Staff accounts cannot be impersonated"})
+		return
+	}
+
+	session := &models.ImpersonationSession{
+		ID:              uuid.NewString(),
+		AdminEmail:      adminEmail,
+		TargetEmail:     targetEmail,
+		Reason:          req.Reason,
+		ReadOnly:        !req.WriteAccess,
+		ConsentRequired: h.config.RequireConsent,
+		Status:          models.ImpersonationApproved,
+	}
+	if session.ConsentRequired {
+		session.Status = models.ImpersonationPending
+	}
+	if err := h.repo.Impersonations.Create(session); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creating impersonation session"})
+		return
+	}
+
+	recordAudit(h.repo, h.log, c, targetEmail, models.AuditImpersonationRequested, "", map[string]any{
+		"impersonator":     adminEmail,
+		"impersonation_id": session.ID,
+		"reason":           req.Reason,
+		"read_only":        session.ReadOnly,
+	})
+
+	if session.ConsentRequired {
+		h.log.Infow("Impersonation awaiting consent", "session_id", session.ID, "admin", adminEmail)
+		c.JSON(http.StatusAccepted, session)
+		return
+	}
+
+	h.start(c, session)
+}
+
+// StartSession begins a session the participant has approved
+func (h *ImpersonationHandler) StartSession(c *gin.Context) {
+	if !h.config.Enabled {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Impersonation is disabled"})
+		return
+	}
+
+	session, err := h.repo.Impersonations.Get(c.Param("id"))
+	if err != nil || session.AdminEmail != strings.ToLower(c.GetString("userEmail")) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Impersonation session not found"})
+		return
+	}
+	if session.Status != models.ImpersonationApproved {
+		c.JSON(http.StatusConflict, gin.H{"error": "Impersonation session is " + session.Status})
+		return
+	}
+
+	h.start(c, session)
+}
+
+// start issues the impersonation token and swaps the admin's access cookie
+// for it. The admin's refresh cookie is kept so their own session resumes
+// when impersonation ends.
+func (h *ImpersonationHandler) start(c *gin.Context, session *models.ImpersonationSession) {
+	ttl := time.Duration(h.config.SessionMinutes) * time.Minute
+
+	tokenString, tokenID, err := h.authService.GenerateImpersonationToken(session, ttl)
+	if err != nil {
+		h.log.Errorw("Error generating impersonation token", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error starting impersonation session"})
+		return
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(ttl)
+	session.Status = models.ImpersonationActive
+	session.TokenID = tokenID
+	session.StartedAt = &now
+	session.ExpiresAt = &expiresAt
+	if err := h.repo.Impersonations.Update(session); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error starting impersonation session"})
+		return
+	}
+
+	recordAudit(h.repo, h.log, c, session.TargetEmail, models.AuditImpersonationStarted, "", map[string]any{
+		"impersonator":     session.AdminEmail,
+		"impersonation_id": session.ID,
+		"read_only":        session.ReadOnly,
+		"expires_at":       expiresAt,
+	})
+
+	cookieConfig := h.authService.GetCookieConfig()
+	c.SetCookie("auth_token", tokenString, int(ttl.Seconds()), cookieConfig.Path, cookieConfig.Domain, cookieConfig.Secure, cookieConfig.HttpOnly)
+
+	h.log.Infow("Impersonation started",
+		"session_id", session.ID,
+		"admin", session.AdminEmail,
+		"read_only", session.ReadOnly)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"session":    session,
+		"expires_in": int(ttl.Seconds()),
+	})
+}
+
+// RevokeSession ends or cancels any impersonation session
+func (h *ImpersonationHandler) RevokeSession(c *gin.Context) {
+	session, err := h.repo.Impersonations.Get(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Impersonation session not found"})
+		return
+	}
+
+	if !h.end(c, session, c.GetString("userEmail")) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Impersonation session is already " + session.Status})
+		return
+	}
+
+	c.JSON(http.StatusOK, session)
+}
+
+// EndSession ends the impersonation session the caller is using
+func (h *ImpersonationHandler) EndSession(c *gin.Context) {
+	sessionID := c.GetString("impersonationID")
+	if sessionID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Not an impersonation session"})
+		return
+	}
+
+	session, err := h.repo.Impersonations.Get(sessionID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Impersonation session not found"})
+		return
+	}
+
+	h.end(c, session, c.GetString("impersonator"))
+	cookieConfig := h.authService.GetCookieConfig()
+	c.SetCookie("auth_token", "", -1, cookieConfig.Path, cookieConfig.Domain, cookieConfig.Secure, cookieConfig.HttpOnly)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Impersonation ended"})
+}
+
+// end closes a session that has not finished yet and revokes its token,
+// reporting whether anything changed
+func (h *ImpersonationHandler) end(c *gin.Context, session *models.ImpersonationSession, endedBy string) bool {
+	switch session.Status {
+	case models.ImpersonationEnded, models.ImpersonationDenied:
+		return false
+	}
+
+	if session.TokenID != "" {
+		if err := h.repo.RevokedTokens.RevokeToken(session.TokenID, session.TargetEmail); err != nil {
+			h.log.Warnw("Error revoking impersonation token", "error", err, "session_id", session.ID)
+		}
+	}
+
+	now := time.Now()
+	session.Status = models.ImpersonationEnded
+	session.EndedAt = &now
+	session.EndedBy = strings.ToLower(endedBy)
+	if err := h.repo.Impersonations.Update(session); err != nil {
+		h.log.Warnw("Error ending impersonation session", "error", err, "session_id", session.ID)
+	}
+
+	recordAudit(h.repo, h.log, c, session.TargetEmail, models.AuditImpersonationEnded, "", map[string]any{
+		"impersonator":     session.AdminEmail,
+		"impersonation_id": session.ID,
+		"ended_by":         session.EndedBy,
+	})
+
+	h.log.Infow("Impersonation ended", "session_id", session.ID, "ended_by", session.EndedBy)
+	return true
+}
+
+// ListSessions lists recent impersonation sessions for oversight
+func (h *ImpersonationHandler) ListSessions(c *gin.Context) {
+	sessions, err := h.repo.Impersonations.List(c.Query("admin"), 100)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error retrieving impersonation sessions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, sessions)
+}
+
+// GetPendingRequests lists impersonation requests awaiting the current user's consent
+func (h *ImpersonationHandler) GetPendingRequests(c *gin.Context) {
+	sessions, err := h.repo.Impersonations.ListPendingForTarget(c.GetString("userEmail"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error retrieving impersonation requests"})
+		return
+	}
+
+	c.JSON(http.StatusOK, sessions)
+}
+
+// RespondToRequest records the current user's consent decision
+func (h *ImpersonationHandler) RespondToRequest(c *gin.Context) {
+	req := c.MustGet("validatedRequest").(*validation.ImpersonationConsentRequest)
+	userEmail := strings.ToLower(c.GetString("userEmail"))
+
+	session, err := h.repo.Impersonations.Get(c.Param("id"))
+	if err != nil || session.TargetEmail != userEmail {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Impersonation request not found"})
+		return
+	}
+	if session.Status != models.ImpersonationPending {
+		c.JSON(http.StatusConflict, gin.H{"error": "Impersonation request is already " + session.Status})
+		return
+	}
+
+	now := time.Now()
+	session.RespondedAt = &now
+	session.Status = models.ImpersonationDenied
+	if req.Approve {
+		session.Status = models.ImpersonationApproved
+	}
+	if err := h.repo.Impersonations.Update(session); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error saving response"})
+		return
+	}
+
+	recordAudit(h.repo, h.log, c, userEmail, models.AuditImpersonationConsent, "", map[string]any{
+		"impersonator":     session.AdminEmail,
+		"impersonation_id": session.ID,
+		"approved":         req.Approve,
+	})
+
+	c.JSON(http.StatusOK, session)
+}
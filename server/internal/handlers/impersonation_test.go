@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"database/sql/driver"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/andevellicus/crapp/internal/config"
+	"github.com/andevellicus/crapp/internal/models"
+	"github.com/andevellicus/crapp/internal/repository"
+	"github.com/andevellicus/crapp/internal/services"
+	"github.com/andevellicus/crapp/internal/testutil"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+func TestEndImpersonationClearsCookieWithConfig(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	log := zap.NewNop().Sugar()
+	recorder := testutil.NewRecorder()
+	recorder.Rows("impersonation_sessions",
+		[]string{"id", "admin_email", "target_email", "status", "token_id"},
+		[]driver.Value{"session-1", "admin@example.com", "participant@example.com", models.ImpersonationActive, "impersonation-token"})
+	db := testutil.OpenGorm(t, recorder.Respond)
+	repo := &repository.Repository{
+		Impersonations: repository.NewImpersonationRepository(db, log),
+		RevokedTokens:  repository.NewRevokedTokenRepository(db, log),
+		AuditEvents:    repository.NewAuditRepository(db, log),
+	}
+	authService := services.NewAuthService(repo, &config.JWTConfig{}, &config.PasswordResetConfig{})
+	handler := NewImpersonationHandler(repo, log, authService, &config.ImpersonationConfig{})
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/impersonation/end", nil)
+	c.Set("impersonationID", "session-1")
+	c.Set("impersonator", "admin@example.com")
+
+	handler.EndSession(c)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", w.Code, w.Body)
+	}
+	want := authService.GetCookieConfig()
+	cleared := (&http.Response{Header: w.Header()}).Cookies()
+	if len(cleared) != 1 || cleared[0].Name != "auth_token" {
+		t.Fatalf("cookies set = %v, want only auth_token", cleared)
+	}
+	cookie := cleared[0]
+	if cookie.MaxAge >= 0 {
+		t.Errorf("auth_token MaxAge = %d, want it expired", cookie.MaxAge)
+	}
+	if cookie.Path != want.Path || cookie.Domain != want.Domain || cookie.Secure != want.Secure || cookie.HttpOnly != want.HttpOnly {
+		t.Errorf("auth_token cleared with path %q, domain %q, secure %v, httponly %v; want the cookie config %+v",
+			cookie.Path, cookie.Domain, cookie.Secure, cookie.HttpOnly, want)
+	}
+}
@@ -0,0 +1,128 @@
+// internal/handlers/import.go
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/andevellicus/crapp/internal/models"
+	"github.com/andevellicus/crapp/internal/repository"
+	"github.com/andevellicus/crapp/internal/services"
+	"github.com/andevellicus/crapp/internal/upload"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// ImportHandler handles bulk participant onboarding
+type ImportHandler struct {
+	repo          *repository.Repository
+	log           *zap.SugaredLogger
+	importService *services.UserImportService
+	uploads       *upload.Pipeline
+}
+
+// NewImportHandler creates a new import handler
+func NewImportHandler(repo *repository.Repository, log *zap.SugaredLogger, importService *services.UserImportService, uploads *upload.Pipeline) *ImportHandler {
+	return &ImportHandler{
+		repo:          repo,
+		log:           log.Named("import"),
+		importService: importService,
+		uploads:       uploads,
+	}
+}
+
+// ImportUsers starts an asynchronous import from an uploaded CSV. The CSV can be
+// sent as a multipart "file" field or as a text/csv request body.
+func (h *ImportHandler) ImportUsers(c *gin.Context) {
+	adminEmail, _ := c.Get("userEmail")
+
+	mode := c.DefaultQuery("mode", models.ImportModeInvite)
+	if mode != models.ImportModeInvite && mode != models.ImportModeTemporaryPassword {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "mode must be invite or temporary_password"})
+		return
+	}
+
+	var body io.Reader
+	filename := "import.csv"
+	if file, err := c.FormFile("file"); err == nil {
+		opened, err := file.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unable to read uploaded file"})
+			return
+		}
+		defer opened.Close()
+		body = opened
+		filename = file.Filename
+	} else {
+		body = c.Request.Body
+	}
+
+	// Size, type and malware checks before the CSV is parsed
+	accepted, err := h.uploads.Accept(c.Request.Context(), "imports", filename, body)
+	if err != nil {
+		rejectUpload(c, h.log, err)
+		return
+	}
+	defer accepted.Close()
+	if body, err = accepted.Reader(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to read uploaded file"})
+		return
+	}
+
+	rows, err := h.importService.ParseCSV(body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	job, err := h.importService.Start(adminEmail.(string), orgScope(c), mode, rows)
+	if err != nil {
+		h.log.Errorw("Error starting user import", "error", err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.log.Infow("Started user import", "id", job.ID, "rows", job.TotalRows, "mode", mode, "admin", adminEmail)
+	c.JSON(http.StatusAccepted, job)
+}
+
+// GetImportJob returns an import job's progress and per-row results
+func (h *ImportHandler) GetImportJob(c *gin.Context) {
+	job, err := h.repo.UserImports.Get(c.Param("id"))
+	if err != nil || !userInOrgScope(c, h.repo, job.CreatedBy) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Import job not found"})
+		return
+	}
+
+	var results []models.UserImportRowResult
+	if job.Results != "" {
+		if err := json.Unmarshal([]byte(job.Results), &results); err != nil {
+			h.log.Warnw("Error decoding import results", "error", err, "id", job.ID)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"job":     job,
+		"results": results,
+	})
+}
+
+// rejectUpload answers an upload the pipeline refused
+func rejectUpload(c *gin.Context, log *zap.SugaredLogger, err error) {
+	switch {
+	case errors.Is(err, upload.ErrEmpty):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "File is empty"})
+	case errors.Is(err, upload.ErrTooLarge):
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "File is too large"})
+	case errors.Is(err, upload.ErrTypeNotAllowed):
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "File type is not allowed"})
+	case errors.Is(err, upload.ErrQuarantined):
+		log.Warnw("Upload quarantined", "error", err, "user", c.GetString("userEmail"))
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "File was rejected by the malware scanner"})
+	default:
+		log.Errorw("Error checking upload", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to read uploaded file"})
+	}
+}
@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/andevellicus/crapp/internal/config"
+	"github.com/andevellicus/crapp/internal/repository"
+	"github.com/andevellicus/crapp/internal/services"
+	"github.com/andevellicus/crapp/internal/utils"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// InsightsHandler turns a participant's recent trends into plain-language
+// statements about their own data
+type InsightsHandler struct {
+	repo      *repository.Repository
+	log       *zap.SugaredLogger
+	questions *utils.QuestionRegistry
+	config    *config.InsightsConfig
+}
+
+// NewInsightsHandler creates a new insights handler
+func NewInsightsHandler(repo *repository.Repository, log *zap.SugaredLogger, questions *utils.QuestionRegistry, cfg *config.InsightsConfig) *InsightsHandler {
+	return &InsightsHandler{
+		repo:      repo,
+		log:       log.Named("insights"),
+		questions: questions,
+		config:    cfg,
+	}
+}
+
+// GetInsights compares the user's recent days with the weeks before them and
+// describes each metric and symptom with enough data. The language comes
+// from the lang parameter or the Accept-Language header. A caregiver's
+// answers for the user count with include_proxy=true.
+func (h *InsightsHandler) GetInsights(c *gin.Context) {
+	userEmail := c.GetString("userEmail")
+	language := services.InsightLanguage(c.Query("lang"), c.GetHeader("Accept-Language"))
+	includeProxy, err := strconv.ParseBool(c.DefaultQuery("include_proxy", "false"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "include_proxy must be true or false"})
+		return
+	}
+
+	days := h.repo.AssessmentDay()
+	if user, err := h.repo.Users.GetByEmail(userEmail); err == nil && user != nil {
+		days = h.repo.Users.AssessmentDayFor(user)
+	}
+	today := days.Today()
+	recentStart := days.AddDays(today, -(h.config.RecentDays - 1))
+	baselineStart := days.AddDays(recentStart, -h.config.BaselineDays)
+
+	symptoms := questionsForUser(h.repo, h.questions, h.log, userEmail).GetRadioQuestions()
+	titles := make(map[string]string, len(symptoms))
+	questionIDs := make([]string, 0, len(symptoms))
+	for _, q := range symptoms {
+		titles[q.ID] = q.Title
+		questionIDs = append(questionIDs, q.ID)
+	}
+
+	values, err := h.repo.ForUser(userEmail).Assessments.GetDailySeries(userEmail, baselineStart, services.InsightMetrics, questionIDs, includeProxy)
+	if err != nil {
+		h.log.Errorw("Error loading insight series", "error", err, "user", userEmail)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	// Split each series into its windows. Dates come back at UTC midnight.
+	recentFrom := recentStart.Format("2006-01-02")
+	baseline := make(map[string][]float64)
+	recent := make(map[string][]float64)
+	for _, v := range values {
+		if v.Date.Format("2006-01-02") >= recentFrom {
+			recent[v.Series] = append(recent[v.Series], v.Value)
+		} else {
+			baseline[v.Series] = append(baseline[v.Series], v.Value)
+		}
+	}
+
+	series := make([]services.InsightSeries, 0, len(services.InsightMetrics)+len(symptoms))
+	for _, key := range services.InsightMetrics {
+		series = append(series, services.InsightSeries{Kind: services.InsightMetric, Key: key, Baseline: baseline[key], Recent: recent[key]})
+	}
+	for _, id := range questionIDs {
+		series = append(series, services.InsightSeries{Kind: services.InsightSymptom, Key: id, Label: titles[id], Baseline: baseline[id], Recent: recent[id]})
+	}
+
+	insights := []*services.Insight{}
+	for _, s := range series {
+		insight := services.DescribeTrend(s, h.config)
+		if insight == nil {
+			continue
+		}
+		insight.Statement = services.InsightStatement(insight, s.Label, h.config.RecentDays, language)
+		insights = append(insights, insight)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"language":   language,
+		"recent":     insightWindow(recentStart, today),
+		"baseline":   insightWindow(baselineStart, recentStart.AddDate(0, 0, -1)),
+		"insights":   insights,
+		"disclaimer": services.InsightDisclaimer(language),
+	})
+}
+
+// insightWindow is an inclusive range of assessment days
+func insightWindow(from, to time.Time) gin.H {
+	return gin.H{"from": from.Format("2006-01-02"), "to": to.Format("2006-01-02")}
+}
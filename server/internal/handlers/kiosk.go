@@ -0,0 +1,155 @@
+// internal/handlers/kiosk.go
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/andevellicus/crapp/internal/config"
+	"github.com/andevellicus/crapp/internal/models"
+	"github.com/andevellicus/crapp/internal/repository"
+	"github.com/andevellicus/crapp/internal/services"
+	"github.com/andevellicus/crapp/internal/validation"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// KioskHandler handles clinician-proctored assessment sessions on shared devices
+type KioskHandler struct {
+	repo        *repository.Repository
+	log         *zap.SugaredLogger
+	authService *services.AuthService
+	config      *config.KioskConfig
+}
+
+// NewKioskHandler creates a new kiosk handler
+func NewKioskHandler(repo *repository.Repository, log *zap.SugaredLogger, authService *services.AuthService, cfg *config.KioskConfig) *KioskHandler {
+	return &KioskHandler{
+		repo:        repo,
+		log:         log.Named("kiosk"),
+		authService: authService,
+		config:      cfg,
+	}
+}
+
+// StartSession launches a time-boxed assessment session for a patient on the clinician's device
+func (h *KioskHandler) StartSession(c *gin.Context) {
+	if !h.config.Enabled {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Kiosk mode is disabled"})
+		return
+	}
+
+	req := c.MustGet("validatedRequest").(*validation.StartKioskSessionRequest)
+	patientEmail := strings.ToLower(req.PatientEmail)
+
+	clinicianEmail, exists := c.Get("userEmail")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	deviceID := getDeviceID(c)
+	if deviceID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Device ID required"})
+		return
+	}
+
+	exists, err := h.repo.Users.UserExists(patientEmail)
+	if err != nil {
+		h.log.Errorw("Error checking patient existence", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	if !exists || !userInOrgScope(c, h.repo, patientEmail) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Patient not found"})
+		return
+	}
+
+	ttl := time.Duration(h.config.SessionMinutes) * time.Minute
+	sessionID := uuid.New().String()
+
+	tokenString, tokenID, err := h.authService.GenerateKioskToken(patientEmail, sessionID, ttl)
+	if err != nil {
+		h.log.Errorw("Error generating kiosk token", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error starting kiosk session"})
+		return
+	}
+
+	session := &models.KioskSession{
+		ID:             sessionID,
+		PatientEmail:   patientEmail,
+		ClinicianEmail: clinicianEmail.(string),
+		DeviceID:       deviceID,
+		TokenID:        tokenID,
+		ExpiresAt:      time.Now().Add(ttl),
+	}
+	if err := h.repo.KioskSessions.Create(session); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error starting kiosk session"})
+		return
+	}
+
+	// Swap the device over to the kiosk token. The clinician's refresh cookie is
+	// dropped so the patient cannot fall back to the clinician's session.
+	cookieConfig := h.authService.GetCookieConfig()
+	c.SetCookie("auth_token", tokenString, int(ttl.Seconds()), cookieConfig.Path, cookieConfig.Domain, cookieConfig.Secure, cookieConfig.HttpOnly)
+	c.SetCookie("refresh_token", "", -1, cookieConfig.Path, cookieConfig.Domain, cookieConfig.Secure, cookieConfig.HttpOnly)
+
+	h.log.Infow("Kiosk session started",
+		"session_id", sessionID,
+		"patient", patientEmail,
+		"clinician", clinicianEmail)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"session_id": sessionID,
+		"expires_at": session.ExpiresAt,
+		"expires_in": int(ttl.Seconds()),
+	})
+}
+
+// EndSession abandons the current kiosk session and logs the device out
+func (h *KioskHandler) EndSession(c *gin.Context) {
+	sessionID, isKiosk := c.Get("kioskSessionID")
+	if !isKiosk {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Not a kiosk session"})
+		return
+	}
+
+	endKioskSession(c, h.repo, h.log, h.authService.GetCookieConfig(), sessionID.(string), nil)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Kiosk session ended"})
+}
+
+// GetSessions lists kiosk sessions launched by the current clinician
+func (h *KioskHandler) GetSessions(c *gin.Context) {
+	clinicianEmail, exists := c.Get("userEmail")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	sessions, err := h.repo.KioskSessions.GetByClinician(clinicianEmail.(string), 50)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error retrieving kiosk sessions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, sessions)
+}
+
+// endKioskSession closes the session record, revokes its token and clears the auth cookie
+func endKioskSession(c *gin.Context, repo *repository.Repository, log *zap.SugaredLogger, cookieConfig services.CookieConfig, sessionID string, assessmentID *uint) {
+	if err := repo.KioskSessions.End(sessionID, assessmentID); err != nil {
+		log.Warnw("Error ending kiosk session", "error", err, "session_id", sessionID)
+	}
+
+	if tokenID, ok := c.Get("tokenID"); ok && tokenID != nil {
+		userEmail, _ := c.Get("userEmail")
+		if err := repo.RevokedTokens.RevokeToken(tokenID.(string), userEmail.(string)); err != nil {
+			log.Warnw("Error revoking kiosk token", "error", err, "session_id", sessionID)
+		}
+	}
+
+	c.SetCookie("auth_token", "", -1, cookieConfig.Path, cookieConfig.Domain, cookieConfig.Secure, cookieConfig.HttpOnly)
+}
@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/andevellicus/crapp/internal/repository"
+	"github.com/andevellicus/crapp/internal/services"
+	"github.com/andevellicus/crapp/internal/testutil"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+func TestEndKioskSessionClearsCookieWithConfig(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	log := zap.NewNop().Sugar()
+	recorder := testutil.NewRecorder()
+	db := testutil.OpenGorm(t, recorder.Respond)
+	repo := &repository.Repository{
+		KioskSessions: repository.NewKioskSessionRepository(db, log),
+		RevokedTokens: repository.NewRevokedTokenRepository(db, log),
+	}
+	cookies := services.CookieConfig{Path: "/crapp", Domain: "clinic.example.org", Secure: true, HttpOnly: true}
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/kiosk/end", nil)
+	c.Set("tokenID", "kiosk-token")
+	c.Set("userEmail", "participant@example.com")
+
+	endKioskSession(c, repo, log, cookies, "session-1", nil)
+
+	cleared := (&http.Response{Header: w.Header()}).Cookies()
+	if len(cleared) != 1 || cleared[0].Name != "auth_token" {
+		t.Fatalf("cookies set = %v, want only auth_token", cleared)
+	}
+	cookie := cleared[0]
+	if cookie.MaxAge >= 0 {
+		t.Errorf("auth_token MaxAge = %d, want it expired", cookie.MaxAge)
+	}
+	if cookie.Path != cookies.Path || cookie.Domain != cookies.Domain || cookie.Secure != cookies.Secure || cookie.HttpOnly != cookies.HttpOnly {
+		t.Errorf("auth_token cleared with path %q, domain %q, secure %v, httponly %v; want the cookie config %+v",
+			cookie.Path, cookie.Domain, cookie.Secure, cookie.HttpOnly, cookies)
+	}
+}
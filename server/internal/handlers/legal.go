@@ -0,0 +1,79 @@
+// internal/handlers/legal.go
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/andevellicus/crapp/internal/models"
+	"github.com/andevellicus/crapp/internal/services"
+	"github.com/andevellicus/crapp/internal/validation"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// LegalHandler serves terms and privacy documents and records acceptance
+type LegalHandler struct {
+	legalService *services.LegalService
+	log          *zap.SugaredLogger
+}
+
+// NewLegalHandler creates a new legal handler
+func NewLegalHandler(log *zap.SugaredLogger, legalService *services.LegalService) *LegalHandler {
+	return &LegalHandler{
+		legalService: legalService,
+		log:          log.Named("legal"),
+	}
+}
+
+// GetDocument returns the current version of the terms of service or privacy policy
+func (h *LegalHandler) GetDocument(c *gin.Context) {
+	doc, ok := h.legalService.GetDocument(c.Param("document"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Document not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, doc)
+}
+
+// GetStatus lists the documents the current user still needs to accept
+func (h *LegalHandler) GetStatus(c *gin.Context) {
+	userEmail, exists := c.Get("userEmail")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	pending, err := h.legalService.PendingDocuments(userEmail.(string))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error checking policy acceptance"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"pending":         pending,
+		"terms_version":   h.legalService.CurrentVersion(models.PolicyTerms),
+		"privacy_version": h.legalService.CurrentVersion(models.PolicyPrivacy),
+	})
+}
+
+// Accept records the current user's acceptance of a document version
+func (h *LegalHandler) Accept(c *gin.Context) {
+	req := c.MustGet("validatedRequest").(*validation.AcceptPolicyRequest)
+
+	userEmail, exists := c.Get("userEmail")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	err := h.legalService.Accept(userEmail.(string), req.DocumentType, req.Version, c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		h.log.Warnw("Error recording policy acceptance", "error", err, "user", userEmail)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.log.Infow("Policy accepted", "user", userEmail, "document", req.DocumentType, "version", req.Version)
+	c.JSON(http.StatusOK, gin.H{"message": "Acceptance recorded"})
+}
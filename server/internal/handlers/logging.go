@@ -0,0 +1,115 @@
+// internal/handlers/logging.go
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/andevellicus/crapp/internal/config"
+	"github.com/andevellicus/crapp/internal/logger"
+	"github.com/andevellicus/crapp/internal/middleware"
+	"github.com/andevellicus/crapp/internal/validation"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// LoggingHandler lets admins inspect and temporarily change log levels and
+// switch body logging on
+type LoggingHandler struct {
+	log         *zap.SugaredLogger
+	bodyLogging *config.BodyLoggingConfig
+}
+
+// NewLoggingHandler creates a new logging handler
+func NewLoggingHandler(log *zap.SugaredLogger, bodyLogging *config.BodyLoggingConfig) *LoggingHandler {
+	return &LoggingHandler{
+		log:         log.Named("logging"),
+		bodyLogging: bodyLogging,
+	}
+}
+
+// GetLevels returns the default level, configured module levels, and active overrides
+func (h *LoggingHandler) GetLevels(c *gin.Context) {
+	c.JSON(http.StatusOK, logger.Levels.Snapshot())
+}
+
+// SetLevel overrides a logger's level until the requested time runs out
+func (h *LoggingHandler) SetLevel(c *gin.Context) {
+	req := c.MustGet("validatedRequest").(*validation.SetLogLevelRequest)
+
+	var level zapcore.Level
+	if err := level.UnmarshalText([]byte(req.Level)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid log level"})
+		return
+	}
+
+	module := strings.TrimSpace(req.Module)
+	expiresAt := logger.Levels.Override(module, level, time.Duration(req.Minutes)*time.Minute)
+
+	h.log.Infow("Log level overridden",
+		"admin", c.GetString("userEmail"),
+		"module", module,
+		"level", level.String(),
+		"expires_at", expiresAt)
+
+	c.JSON(http.StatusOK, logger.Levels.Snapshot())
+}
+
+// ResetLevel removes a logger's override, given by the module query parameter
+func (h *LoggingHandler) ResetLevel(c *gin.Context) {
+	module := strings.TrimSpace(c.Query("module"))
+
+	if !logger.Levels.ClearOverride(module) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No override for this module"})
+		return
+	}
+
+	h.log.Infow("Log level override removed", "admin", c.GetString("userEmail"), "module", module)
+	c.JSON(http.StatusOK, logger.Levels.Snapshot())
+}
+
+// GetBodyLogging reports whether request/response bodies are being logged
+func (h *LoggingHandler) GetBodyLogging(c *gin.Context) {
+	h.respondBodyLogging(c)
+}
+
+// EnableBodyLogging logs redacted bodies for the requested number of minutes
+func (h *LoggingHandler) EnableBodyLogging(c *gin.Context) {
+	if !h.bodyLogging.Enabled {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Body logging is disabled"})
+		return
+	}
+
+	req := c.MustGet("validatedRequest").(*validation.SetBodyLoggingRequest)
+	if h.bodyLogging.MaxMinutes > 0 && req.Minutes > h.bodyLogging.MaxMinutes {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Body logging can be enabled for at most " + strconv.Itoa(h.bodyLogging.MaxMinutes) + " minutes"})
+		return
+	}
+
+	until := middleware.EnableBodyLogging(time.Duration(req.Minutes) * time.Minute)
+	h.log.Warnw("Body logging enabled", "admin", c.GetString("userEmail"), "until", until)
+	h.respondBodyLogging(c)
+}
+
+// DisableBodyLogging stops body logging immediately
+func (h *LoggingHandler) DisableBodyLogging(c *gin.Context) {
+	middleware.DisableBodyLogging()
+	h.log.Infow("Body logging disabled", "admin", c.GetString("userEmail"))
+	h.respondBodyLogging(c)
+}
+
+func (h *LoggingHandler) respondBodyLogging(c *gin.Context) {
+	response := gin.H{
+		"available": h.bodyLogging.Enabled,
+		"active":    false,
+		"routes":    h.bodyLogging.Routes,
+	}
+	if until := middleware.BodyLoggingUntil(); h.bodyLogging.Enabled && !until.IsZero() {
+		response["active"] = true
+		response["until"] = until
+	}
+	c.JSON(http.StatusOK, response)
+}
@@ -2,10 +2,17 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
+	"strconv"
+	"time"
 
+	"github.com/andevellicus/crapp/internal/metrics"
+	"github.com/andevellicus/crapp/internal/models"
 	"github.com/andevellicus/crapp/internal/repository"
+	"github.com/andevellicus/crapp/internal/utils"
 	"github.com/gin-gonic/gin"
 )
 
@@ -18,30 +25,92 @@ type ChartData struct {
 	Data     any    `json:"data"`
 	Question string `json:"question,omitempty"`
 	Metric   string `json:"metric,omitempty"`
+	Bucket   string `json:"bucket,omitempty"` // Timeline bucket size, if downsampled
+
+	// Assessments left out of a correlation, when requested
+	Missing *repository.MissingDataReport `json:"missing,omitempty"`
+
+	// Correlation with the metric shifted by each lag, when requested
+	Lags []repository.LagCorrelation `json:"lags,omitempty"`
 }
 
 // GetChartCorrelationData returns preformatted data for Chart.js scatter plot
 func (h *GinAPIHandler) GetChartCorrelationData(c *gin.Context) {
-	userID := c.Query("user_id")
-	symptomKey := c.Query("symptom")
-	metricKey := c.Query("metric")
-
-	// Auth checks
-	currentUserEmail, exists := c.Get("userEmail")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+	// Access is checked by RequireSelfOrRole
+	userID := c.GetString("resourceOwner")
+	blinded := c.GetBool("blinded")
+	query, ok := chartQuery(c, h.repo)
+	if !ok {
+		return
+	}
+	symptomKey := query.Get("symptom")
+	metricKey := query.Get("metric")
+
+	// A correlation cannot be shown without the symptom values it relates
+	if blinded {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Symptom values are masked for blinded reviewers"})
 		return
 	}
 
-	// Check access permissions
-	isAdmin, _ := c.Get("isAdmin")
-	if userID != currentUserEmail.(string) && (!isAdmin.(bool)) {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required to view other users' data"})
+	// Days missing either value are dropped. Callers may ask which days
+	// those were, and have missing symptom values filled in.
+	reportMissing := query.Get("missing") == "true"
+	impute := query.Get("impute")
+	if impute != "" && !repository.IsImputeMethod(impute) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "impute must be carry_forward or interpolate"})
 		return
 	}
 
-	// Get raw data
-	data, err := h.repo.Assessments.GetMetricsCorrelation(userID, symptomKey, metricKey)
+	// Symptoms may trail changes in a metric, so callers can ask for the
+	// correlation with the metric shifted up to this many days either way
+	maxLag := 0
+	if lagParam := query.Get("lag"); lagParam != "" {
+		val, err := strconv.Atoi(lagParam)
+		if err != nil || val < 0 || val > repository.MaxCorrelationLag {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("lag must be between 0 and %d days", repository.MaxCorrelationLag)})
+			return
+		}
+		maxLag = val
+	}
+
+	filter, err := chartFilter(query)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Charts only change when the user submits, so the PWA's polling is
+	// usually answered with 304
+	questions := questionsForUser(h.repo, h.questions, h.log, userID)
+	if h.chartNotModified(c, userID, questions, blinded, query) {
+		return
+	}
+
+	repo := h.repo.ForUser(userID)
+	var data *[]repository.CorrelationDataPoint
+	var missing *repository.MissingDataReport
+	var lags []repository.LagCorrelation
+	if reportMissing || impute != "" || maxLag > 0 {
+		var days []repository.CoverageDay
+		days, err = repo.Assessments.GetCorrelationCoverage(userID, symptomKey, metricKey, filter)
+		if err == nil {
+			points, report := repository.ImputeCorrelation(days, impute)
+			data = &points
+			if reportMissing || impute != "" {
+				missing = report
+			}
+			if maxLag > 0 {
+				lags = repository.CorrelateLags(days, maxLag)
+			}
+		}
+	} else {
+		// Read the precomputed points, falling back to the live join until the
+		// user's latest assessments are summarized
+		data, err = repo.ChartSummaries.GetCorrelation(userID, symptomKey, metricKey, filter)
+		if errors.Is(err, repository.ErrSummaryNotReady) {
+			data, err = repo.Assessments.GetMetricsCorrelation(userID, symptomKey, metricKey, filter)
+		}
+	}
 	if err != nil {
 		h.log.Errorw("Error retrieving metrics correlation", "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error retrieving data"})
@@ -54,50 +123,50 @@ func (h *GinAPIHandler) GetChartCorrelationData(c *gin.Context) {
 	}
 
 	// Get question and metric labels
-	questionLabel := h.getQuestionLabel(symptomKey)
+	questionLabel := questionTitle(questions, symptomKey)
 	metricLabel := getMetricLabel(metricKey)
 
 	// Format for Chart.js
 	chartData := formatCorrelationDataForChart(*data, questionLabel, metricLabel)
+	chartData.Missing = missing
+	chartData.Lags = lags
 
 	c.JSON(http.StatusOK, chartData)
 }
 
 // GetChartTimelineData returns preformatted data for Chart.js line chart
 func (h *GinAPIHandler) GetChartTimelineData(c *gin.Context) {
-	userID := c.Query("user_id")
-	symptomKey := c.Query("symptom")
-	metricKey := c.Query("metric")
-
-	// Auth checks
-	currentUserEmail, exists := c.Get("userEmail")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+	// Access is checked by RequireSelfOrRole
+	userID := c.GetString("resourceOwner")
+	blinded := c.GetBool("blinded")
+	query, ok := chartQuery(c, h.repo)
+	if !ok {
 		return
 	}
+	symptomKey := query.Get("symptom")
+	metricKey := query.Get("metric")
 
-	// Check access permissions
-	isAdmin, _ := c.Get("isAdmin")
-	if userID != currentUserEmail.(string) && (!isAdmin.(bool)) {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required to view other users' data"})
+	// Long timelines can be downsampled to one point per day, week, or month
+	bucket := query.Get("bucket")
+	if bucket != "" && !repository.IsTimelineBucket(bucket) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "bucket must be day, week, or month"})
 		return
 	}
 
-	questionType := h.getQuestionsType(symptomKey)
+	filter, err := chartFilter(query)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	var timelineData []repository.TimelineDataPoint
-	var err error
-	switch questionType {
-	case "tmt":
-		timelineData, err = h.repo.TMTResults.GetTMTTimelineData(userID, metricKey)
-	case "cpt":
-		timelineData, err = h.repo.CPTResults.GetCPTTimelineData(userID, metricKey)
-	case "digit_span":
-		timelineData, err = h.repo.DigitSpanResults.GetDigitSpanTimelineData(userID, metricKey)
-	default: // Assume interaction metrics for other question types
-		timelineData, err = h.repo.Assessments.GetMetricsTimeline(userID, symptomKey, metricKey)
+	questions := questionsForUser(h.repo, h.questions, h.log, userID)
+	if h.chartNotModified(c, userID, questions, blinded, query) {
+		return
 	}
 
+	questionType := questionTypeOf(questions, symptomKey)
+
+	timelineData, err := h.loadTimeline(userID, symptomKey, metricKey, questionType, filter)
 	if err != nil {
 		h.log.Errorw("Error retrieving metrics timeline", "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error retrieving data"})
@@ -108,35 +177,168 @@ func (h *GinAPIHandler) GetChartTimelineData(c *gin.Context) {
 	if len(timelineData) == 0 {
 		timelineData = []repository.TimelineDataPoint{}
 	}
+	timelineData = repository.BucketTimeline(timelineData, bucket)
 
 	// Get question and metric labels
 	var questionLabel string
-	if questionType == "cpt" || questionType == "tmt" || questionType == "digit_span" {
+	// Blinded viewers only get the metric series
+	if blinded ||
+		questionType == "cpt" || questionType == "tmt" || questionType == "digit_span" {
 		// For cognitive tests, use a generic label or the test title
-		questionLabel = h.getQuestionLabel(symptomKey) // Get title from questions.yaml
+		questionLabel = questionTitle(questions, symptomKey) // Get title from questions.yaml
 	} else {
-		questionLabel = h.getQuestionLabel(symptomKey) // Symptom question title
+		questionLabel = questionTitle(questions, symptomKey) // Symptom question title
 	}
 	metricLabel := getMetricLabel(metricKey)
 
+	// Mask symptom values for blinded reviewers
+	if blinded {
+		redactTimeline(timelineData)
+	}
+
+	// Mark points that fall inside a threshold flag
+	var markers [][]string
+	if !blinded {
+		flags, err := h.repo.Thresholds.FlagsForQuestion(userID, symptomKey)
+		if err != nil {
+			h.log.Warnw("Error retrieving threshold flags", "error", err)
+		} else if len(flags) > 0 {
+			markers = thresholdMarkers(timelineData, flags, bucket)
+		}
+	}
+
 	// Format for Chart.js
-	chartData := formatTimelineDataForChart(timelineData, questionLabel, questionType, metricLabel)
+	chartData := formatTimelineDataForChart(timelineData, questionLabel, questionType, metricLabel, blinded, bucket, markers)
 
 	c.JSON(http.StatusOK, chartData)
 }
 
-// Helper to get question label from ID
-func (h *GinAPIHandler) getQuestionLabel(questionID string) string {
-	question := h.questionLoader.GetQuestionByID(questionID)
+// loadTimeline reads a user's symptom and metric values by date, from the
+// cognitive test results for test questions and from assessment metrics
+// otherwise
+func (h *GinAPIHandler) loadTimeline(userID, symptomKey, metricKey, questionType string, filter repository.ChartFilter) ([]repository.TimelineDataPoint, error) {
+	repo := h.repo.ForUser(userID)
+	switch questionType {
+	case "tmt":
+		return repo.TMTResults.GetTMTTimelineData(userID, metricKey, filter)
+	case "cpt":
+		return repo.CPTResults.GetCPTTimelineData(userID, metricKey, filter)
+	case "digit_span":
+		return repo.DigitSpanResults.GetDigitSpanTimelineData(userID, metricKey, filter)
+	}
+
+	// Assume interaction metrics for other question types
+	timelineData, err := repo.ChartSummaries.GetTimeline(userID, symptomKey, metricKey, filter)
+	if errors.Is(err, repository.ErrSummaryNotReady) {
+		timelineData, err = repo.Assessments.GetMetricsTimeline(userID, symptomKey, metricKey, filter)
+	}
+	return timelineData, err
+}
+
+// questionTitle returns a question's title from a question set, or its ID
+// when the set doesn't have it
+func questionTitle(questions *utils.QuestionLoader, questionID string) string {
+	question := questions.GetQuestionByID(questionID)
 	if question == nil {
 		return questionID
 	}
 	return question.Title
 }
 
-// Helper to get question type from ID
-func (h *GinAPIHandler) getQuestionsType(questionID string) string {
-	question := h.questionLoader.GetQuestionByID(questionID)
+// chartNotModified answers a conditional chart request with 304 when nothing
+// the chart is drawn from has changed. The tag covers the user's assessments
+// and threshold flags, the query with any saved view applied, custom metric
+// formulas, the user's question set, which supplies the labels, and whether
+// values are masked.
+func (h *GinAPIHandler) chartNotModified(c *gin.Context, userID string, questions *utils.QuestionLoader, blinded bool, query url.Values) bool {
+	version, err := h.repo.ForUser(userID).Assessments.ChartVersion(userID)
+	if err != nil {
+		return false
+	}
+	flags, err := h.repo.Thresholds.FlagVersion(userID)
+	if err != nil {
+		return false
+	}
+	custom, err := h.repo.CustomMetrics.Version()
+	if err != nil {
+		return false
+	}
+	// A window of the last N days moves every day
+	window := ""
+	if query.Has("days") {
+		window = time.Now().Format("2006-01-02")
+	}
+	return notModified(c, weakETag("chart", query.Encode(), window, version, flags, custom,
+		questions.Checksum, strconv.FormatBool(blinded)))
+}
+
+// chartFilter reads the assessments a chart should use from its query:
+// from and to (inclusive YYYY-MM-DD assessment days) or days (the last N
+// days), device_id, include_retrospective, which exclude_retrospective
+// overrides, include_likely_bots, include_proxy, and reported_by (one
+// caregiver's reports). Retrospective entries carry no live interaction
+// data, likely bots no real answers, and a caregiver's reports the
+// caregiver's interactions, so all three are excluded unless asked for.
+func chartFilter(query url.Values) (repository.ChartFilter, error) {
+	var filter repository.ChartFilter
+
+	flags := map[string]bool{}
+	for _, name := range []string{"include_retrospective", "exclude_retrospective", "include_likely_bots", "include_proxy"} {
+		if param := query.Get(name); param != "" {
+			value, err := strconv.ParseBool(param)
+			if err != nil {
+				return filter, fmt.Errorf("%s must be true or false", name)
+			}
+			flags[name] = value
+		}
+	}
+	filter.IncludeRetrospective = flags["include_retrospective"] && !flags["exclude_retrospective"]
+	filter.IncludeLikelyBots = flags["include_likely_bots"]
+	filter.IncludeProxy = flags["include_proxy"]
+
+	filter.ReportedBy = query.Get("reported_by")
+	if len(filter.ReportedBy) > 255 {
+		return filter, fmt.Errorf("reported_by is too long")
+	}
+
+	filter.DeviceID = query.Get("device_id")
+	if len(filter.DeviceID) > 255 {
+		return filter, fmt.Errorf("device_id is too long")
+	}
+
+	for name, bound := range map[string]**time.Time{"from": &filter.From, "to": &filter.To} {
+		if param := query.Get(name); param != "" {
+			day, err := time.Parse("2006-01-02", param)
+			if err != nil {
+				return filter, fmt.Errorf("%s must be a date (YYYY-MM-DD)", name)
+			}
+			*bound = &day
+		}
+	}
+
+	if param := query.Get("days"); param != "" {
+		days, err := strconv.Atoi(param)
+		if err != nil || days < 1 || days > 3650 {
+			return filter, fmt.Errorf("days must be between 1 and 3650")
+		}
+		if filter.From != nil || filter.To != nil {
+			return filter, fmt.Errorf("days can't be combined with from or to")
+		}
+		today := time.Now()
+		from := time.Date(today.Year(), today.Month(), today.Day()-days+1, 0, 0, 0, 0, time.UTC)
+		filter.From = &from
+	}
+
+	if filter.From != nil && filter.To != nil && filter.From.After(*filter.To) {
+		return filter, fmt.Errorf("from must not be after to")
+	}
+	return filter, nil
+}
+
+// questionTypeOf returns a question's type from a question set, or its ID
+// when the set doesn't have it
+func questionTypeOf(questions *utils.QuestionLoader, questionID string) string {
+	question := questions.GetQuestionByID(questionID)
 	if question == nil {
 		return questionID
 	}
@@ -151,12 +353,20 @@ func formatCorrelationDataForChart(data []repository.CorrelationDataPoint, quest
 		Y float64 `json:"y"`
 	}
 
-	chartPoints := make([]ScatterPoint, len(data))
-	for i, point := range data {
-		chartPoints[i] = ScatterPoint{
+	// Imputed points are plotted separately so they can't be mistaken for
+	// reported values
+	chartPoints := make([]ScatterPoint, 0, len(data))
+	imputedPoints := []ScatterPoint{}
+	for _, point := range data {
+		scatterPoint := ScatterPoint{
 			X: point.MetricValue,
 			Y: point.SymptomValue,
 		}
+		if point.Imputed {
+			imputedPoints = append(imputedPoints, scatterPoint)
+		} else {
+			chartPoints = append(chartPoints, scatterPoint)
+		}
 	}
 
 	// Chart.js scatter plot format
@@ -167,6 +377,23 @@ func formatCorrelationDataForChart(data []repository.CorrelationDataPoint, quest
 		BorderColor     string         `json:"borderColor"`
 	}
 
+	datasets := []ScatterDataset{
+		{
+			Label:           "Symptom vs. Metric",
+			Data:            chartPoints,
+			BackgroundColor: "rgba(74, 111, 165, 0.7)",
+			BorderColor:     "rgba(74, 111, 165, 1)",
+		},
+	}
+	if len(imputedPoints) > 0 {
+		datasets = append(datasets, ScatterDataset{
+			Label:           "Imputed symptom values",
+			Data:            imputedPoints,
+			BackgroundColor: "rgba(221, 107, 32, 0.4)",
+			BorderColor:     "rgba(221, 107, 32, 1)",
+		})
+	}
+
 	chartData := ChartData{
 		Title:    fmt.Sprintf("Correlation: %s vs %s", questionLabel, metricLabel),
 		XLabel:   metricLabel,
@@ -174,14 +401,7 @@ func formatCorrelationDataForChart(data []repository.CorrelationDataPoint, quest
 		Question: questionLabel,
 		Metric:   metricLabel,
 		Data: map[string]any{
-			"datasets": []ScatterDataset{
-				{
-					Label:           "Symptom vs. Metric",
-					Data:            chartPoints,
-					BackgroundColor: "rgba(74, 111, 165, 0.7)",
-					BorderColor:     "rgba(74, 111, 165, 1)",
-				},
-			},
+			"datasets": datasets,
 		},
 	}
 
@@ -189,17 +409,46 @@ func formatCorrelationDataForChart(data []repository.CorrelationDataPoint, quest
 }
 
 // Format timeline data for Chart.js line chart
-func formatTimelineDataForChart(data []repository.TimelineDataPoint, questionLabel, questionType, metricLabel string) ChartData {
+func formatTimelineDataForChart(data []repository.TimelineDataPoint, questionLabel, questionType, metricLabel string, blinded bool, bucket string, markers [][]string) ChartData {
 	// Extract and format dates for labels
 	labels := make([]string, len(data))
 	symptomData := make([]float64, len(data))
 	metricData := make([]float64, len(data))
+	retrospective := make([]bool, len(data))
+	pointStyles := make([]string, len(data))
+
+	// Bucketed points are means; their ranges let the chart show the spread
+	bucketed := repository.IsTimelineBucket(bucket)
+	metricMin := make([]float64, len(data))
+	metricMax := make([]float64, len(data))
+	symptomMin := make([]float64, len(data))
+	symptomMax := make([]float64, len(data))
+	counts := make([]int, len(data))
 
 	for i, point := range data {
 		// Format date as "Jan 2, 2006"
-		labels[i] = point.Date.Format("Jan 2, 2006")
+		switch bucket {
+		case repository.TimelineBucketWeek:
+			labels[i] = "Week of " + point.Date.Format("Jan 2, 2006")
+		case repository.TimelineBucketMonth:
+			labels[i] = point.Date.Format("Jan 2006")
+		default:
+			labels[i] = point.Date.Format("Jan 2, 2006")
+		}
 		symptomData[i] = point.SymptomValue
 		metricData[i] = point.MetricValue
+		retrospective[i] = point.IsRetrospective
+		metricMin[i] = point.MetricMin
+		metricMax[i] = point.MetricMax
+		symptomMin[i] = point.SymptomMin
+		symptomMax[i] = point.SymptomMax
+		counts[i] = point.Count
+
+		// Recalled (backfilled) entries are drawn with a distinct marker
+		pointStyles[i] = "circle"
+		if point.IsRetrospective {
+			pointStyles[i] = "rectRot"
+		}
 	}
 
 	// Chart.js line chart format
@@ -209,17 +458,21 @@ func formatTimelineDataForChart(data []repository.TimelineDataPoint, questionLab
 		BorderColor     string    `json:"borderColor"`
 		BackgroundColor string    `json:"backgroundColor"`
 		YAxisID         string    `json:"yAxisID"`
+		PointStyle      []string  `json:"pointStyle"`
 	}
 
 	chartData := ChartData{
 		Title:  fmt.Sprintf("Timeline: %s and %s", questionLabel, metricLabel),
+		Bucket: bucket,
 		XLabel: "Date",
 
 		Metric:   metricLabel,
 		Question: questionLabel,
 	}
 
-	if questionType == "cpt" ||
+	// Blinded viewers only get the metric series
+	if blinded ||
+		questionType == "cpt" ||
 		questionType == "text" ||
 		questionType == "tmt" ||
 		questionType == "digit_span" {
@@ -232,8 +485,13 @@ func formatTimelineDataForChart(data []repository.TimelineDataPoint, questionLab
 					BorderColor:     "rgba(90, 154, 104, 1)",
 					BackgroundColor: "rgba(90, 154, 104, 0.2)",
 					YAxisID:         "y",
+					PointStyle:      pointStyles,
 				},
 			},
+			"retrospective": retrospective,
+		}
+		if bucketed {
+			dataset["ranges"] = map[string]any{"metric_min": metricMin, "metric_max": metricMax, "count": counts}
 		}
 		chartData.Data = dataset
 		chartData.YLabel = metricLabel
@@ -248,6 +506,7 @@ func formatTimelineDataForChart(data []repository.TimelineDataPoint, questionLab
 					BorderColor:     "rgba(74, 111, 165, 1)",
 					BackgroundColor: "rgba(74, 111, 165, 0.2)",
 					YAxisID:         "y",
+					PointStyle:      pointStyles,
 				},
 				{
 					Label:           metricLabel,
@@ -255,8 +514,22 @@ func formatTimelineDataForChart(data []repository.TimelineDataPoint, questionLab
 					BorderColor:     "rgba(90, 154, 104, 1)",
 					BackgroundColor: "rgba(90, 154, 104, 0.2)",
 					YAxisID:         "y1",
+					PointStyle:      pointStyles,
 				},
 			},
+			"retrospective": retrospective,
+		}
+		if bucketed {
+			dataset["ranges"] = map[string]any{
+				"metric_min":  metricMin,
+				"metric_max":  metricMax,
+				"symptom_min": symptomMin,
+				"symptom_max": symptomMax,
+				"count":       counts,
+			}
+		}
+		if markers != nil {
+			dataset["threshold_flags"] = markers
 		}
 		chartData.Data = dataset
 		chartData.YLabel = fmt.Sprintf("%s Severity", questionLabel)
@@ -268,43 +541,30 @@ func formatTimelineDataForChart(data []repository.TimelineDataPoint, questionLab
 
 // Helper to get metric label
 func getMetricLabel(metricKey string) string {
-	metricLabels := map[string]string{
-		// Mouse metrics
-		"click_precision":      "Click Precision",
-		"path_efficiency":      "Path Efficiency",
-		"overshoot_rate":       "Overshoot Rate",
-		"average_velocity":     "Average Velocity",
-		"velocity_variability": "Velocity Variability",
-		// Keyboard metrics
-		"typing_speed":                  "Typing Speed",
-		"average_inter_key_interval":    "Inter-Key Interval",
-		"typing_rhythm_variability":     "Typing Rhythm Variability",
-		"average_key_hold_time":         "Key Hold Time",
-		"key_press_variability":         "Key Press Variability",
-		"correction_rate":               "Correction Rate",
-		"pause_rate":                    "Pause Rate",
-		"immediate_correction_tendency": "Immediate Correction Tendency",
-		"deep_thinking_pause_rate":      "Deep Thinking Pause Rate",
-		"keyboard_fluency":              "Keyboard Fluency Score",
-		// Cognitive performance test metrics
-		"reaction_time":         "Reaction Time",
-		"detection_rate":        "Detection Rate",
-		"omission_error_rate":   "Omission Error Rate",
-		"commission_error_rate": "Commission Error Rate",
-		// Trail making test metrics
-		"part_a_time":   "Part A Time",
-		"part_b_time":   "Part B Time",
-		"b_to_a_ratio":  "B/A Ratio",
-		"part_a_errors": "Part A Errors",
-		"part_b_errors": "Part B Errors",
-		// Digit span test metrics
-		"highest_span":   "Highest Span Achieved",
-		"correct_trials": "Correct Trials",
-		"total_trials":   "Total Trials",
-	}
-
-	if label, ok := metricLabels[metricKey]; ok {
-		return label
-	}
-	return metricKey
+	return metrics.Label(metricKey)
+}
+
+// thresholdMarkers lists, for each timeline point, the thresholds whose
+// flags cover the point's day, or any day of its bucket
+func thresholdMarkers(data []repository.TimelineDataPoint, flags []models.SymptomFlag, bucket string) [][]string {
+	markers := make([][]string, len(data))
+	for i, point := range data {
+		start := point.Date
+		end := start
+		switch bucket {
+		case repository.TimelineBucketWeek:
+			end = start.AddDate(0, 0, 6)
+		case repository.TimelineBucketMonth:
+			end = start.AddDate(0, 1, -1)
+		}
+		from, to := start.Format("2006-01-02"), end.Format("2006-01-02")
+
+		markers[i] = []string{}
+		for _, flag := range flags {
+			if flag.StartDay.Format("2006-01-02") <= to && flag.EndDay.Format("2006-01-02") >= from {
+				markers[i] = append(markers[i], flag.Threshold.Describe())
+			}
+		}
+	}
+	return markers
 }
@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestChartFilterReporter(t *testing.T) {
+	tests := []struct {
+		query            string
+		wantIncludeProxy bool
+		wantReportedBy   string
+		wantErr          bool
+	}{
+		{query: ""},
+		{query: "include_proxy=true", wantIncludeProxy: true},
+		{query: "include_proxy=false"},
+		{query: "reported_by=carer@example.com", wantReportedBy: "carer@example.com"},
+		{query: "include_proxy=true&reported_by=carer@example.com", wantIncludeProxy: true, wantReportedBy: "carer@example.com"},
+		{query: "include_proxy=maybe", wantErr: true},
+		{query: "reported_by=" + strings.Repeat("a", 256), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		query, err := url.ParseQuery(tt.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		filter, err := chartFilter(query)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("chartFilter(%q) error = %v, want error %v", tt.query, err, tt.wantErr)
+			continue
+		}
+		if tt.wantErr {
+			continue
+		}
+		if filter.IncludeProxy != tt.wantIncludeProxy || filter.ReportedBy != tt.wantReportedBy {
+			t.Errorf("chartFilter(%q) = include_proxy %v, reported_by %q; want %v, %q",
+				tt.query, filter.IncludeProxy, filter.ReportedBy, tt.wantIncludeProxy, tt.wantReportedBy)
+		}
+	}
+}
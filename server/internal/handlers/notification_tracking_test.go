@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/andevellicus/crapp/internal/config"
+	"github.com/andevellicus/crapp/internal/repository"
+	"github.com/andevellicus/crapp/internal/services"
+	"github.com/andevellicus/crapp/internal/testutil"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+func TestTrackClick(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	log := zap.NewNop().Sugar()
+	recorder := testutil.NewRecorder()
+	repo := &repository.Repository{NotificationEvents: repository.NewNotificationEventRepository(testutil.OpenGorm(t, recorder.Respond), log)}
+	tracker := services.NewNotificationTracker(repo, log, "tracking-secret", &config.ReminderConfig{TrackClicks: true})
+	handler := NewNotificationTrackingHandler(tracker, log)
+
+	router := gin.New()
+	router.GET(services.TrackClickPath, handler.TrackClick)
+	router.POST(services.TrackClickPath, handler.TrackClick)
+
+	signed := tracker.ClickURL("message-1", "/history")
+	withSig := func(link, sig string) string {
+		u, _ := url.Parse(link)
+		query := u.Query()
+		query.Set("sig", sig)
+		u.RawQuery = query.Encode()
+		return u.String()
+	}
+	withTarget := func(link, to string) string {
+		u, _ := url.Parse(link)
+		query := u.Query()
+		query.Set("to", to)
+		u.RawQuery = query.Encode()
+		return u.String()
+	}
+
+	tests := []struct {
+		name         string
+		method       string
+		target       string
+		wantStatus   int
+		wantLocation string
+		wantRecorded bool
+	}{
+		{name: "signed link redirects", method: http.MethodGet, target: signed, wantStatus: http.StatusFound, wantLocation: "/history", wantRecorded: true},
+		{name: "signed notification callback", method: http.MethodPost, target: tracker.ClickURL("message-1", ""), wantStatus: http.StatusNoContent, wantRecorded: true},
+		{name: "forged signature", method: http.MethodGet, target: withSig(signed, strings.Repeat("0", 64)), wantStatus: http.StatusNotFound},
+		{name: "destination changed after signing", method: http.MethodGet, target: withTarget(signed, "/admin"), wantStatus: http.StatusNotFound},
+		{name: "open redirect attempt", method: http.MethodGet, target: withTarget(signed, "https://evil.example"), wantStatus: http.StatusNotFound},
+		{name: "missing signature", method: http.MethodGet, target: services.TrackClickPath + "?m=message-1&to=%2Fhistory", wantStatus: http.StatusBadRequest},
+		{name: "missing message", method: http.MethodGet, target: services.TrackClickPath + "?to=%2F&sig=abc", wantStatus: http.StatusBadRequest},
+		{name: "forged notification callback", method: http.MethodPost, target: services.TrackClickPath + "?m=message-1&sig=abc", wantStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := len(recorder.Statements())
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(tt.method, tt.target, nil))
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if location := w.Header().Get("Location"); location != tt.wantLocation {
+				t.Errorf("Location = %q, want %q", location, tt.wantLocation)
+			}
+			if recorded := len(recorder.Statements()) > before; recorded != tt.wantRecorded {
+				t.Errorf("click recorded = %v, want %v", recorded, tt.wantRecorded)
+			}
+		})
+	}
+}
@@ -0,0 +1,244 @@
+// internal/handlers/organization.go
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/andevellicus/crapp/internal/config"
+	"github.com/andevellicus/crapp/internal/models"
+	"github.com/andevellicus/crapp/internal/repository"
+	"github.com/andevellicus/crapp/internal/utils"
+	"github.com/andevellicus/crapp/internal/validation"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// slugPattern restricts organization and study IDs to URL-safe slugs
+var slugPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+// OrganizationHandler handles organization and study administration
+type OrganizationHandler struct {
+	repo *repository.Repository
+	log  *zap.SugaredLogger
+}
+
+// NewOrganizationHandler creates a new organization handler
+func NewOrganizationHandler(repo *repository.Repository, log *zap.SugaredLogger) *OrganizationHandler {
+	return &OrganizationHandler{
+		repo: repo,
+		log:  log.Named("organization"),
+	}
+}
+
+// ListOrganizations returns all organizations
+func (h *OrganizationHandler) ListOrganizations(c *gin.Context) {
+	orgs, err := h.repo.Organizations.List()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error listing organizations"})
+		return
+	}
+	c.JSON(http.StatusOK, orgs)
+}
+
+// CreateOrganization registers a new organization
+func (h *OrganizationHandler) CreateOrganization(c *gin.Context) {
+	req := c.MustGet("validatedRequest").(*validation.CreateOrganizationRequest)
+	id := strings.ToLower(req.ID)
+
+	if !slugPattern.MatchString(id) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Organization ID must contain only letters, numbers, and dashes"})
+		return
+	}
+
+	org := &models.Organization{
+		ID:            id,
+		Name:          req.Name,
+		QuestionsFile: req.QuestionsFile,
+	}
+	if len(req.Branding) > 0 {
+		var branding config.BrandingConfig
+		if err := json.Unmarshal(req.Branding, &branding); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid branding"})
+			return
+		}
+		org.Branding = string(req.Branding)
+	}
+
+	if org.QuestionsFile != "" {
+		if _, err := utils.NewQuestionLoader(org.QuestionsFile); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid questions file: " + err.Error()})
+			return
+		}
+	}
+
+	if _, err := h.repo.Organizations.Get(org.ID); err == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Organization already exists"})
+		return
+	}
+
+	if err := h.repo.ProvisionOrganization(org, req.DedicatedSchema); err != nil {
+		h.log.Errorw("Error provisioning organization", "error", err, "id", org.ID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creating organization"})
+		return
+	}
+
+	h.log.Infow("Created organization", "id", org.ID, "schema", org.Schema)
+	c.JSON(http.StatusCreated, org)
+}
+
+// ListStudies returns an organization's studies
+func (h *OrganizationHandler) ListStudies(c *gin.Context) {
+	orgID := strings.ToLower(c.Param("id"))
+	if scope := orgScope(c); scope != "" && scope != orgID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Organization not found"})
+		return
+	}
+
+	studies, err := h.repo.Organizations.ListStudies(orgID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error listing studies"})
+		return
+	}
+	c.JSON(http.StatusOK, studies)
+}
+
+// CreateStudy adds a study to an organization
+func (h *OrganizationHandler) CreateStudy(c *gin.Context) {
+	req := c.MustGet("validatedRequest").(*validation.CreateStudyRequest)
+	orgID := strings.ToLower(c.Param("id"))
+
+	if scope := orgScope(c); scope != "" && scope != orgID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Organization not found"})
+		return
+	}
+	if _, err := h.repo.Organizations.Get(orgID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Organization not found"})
+		return
+	}
+
+	slug := strings.ToLower(req.Slug)
+	if !slugPattern.MatchString(slug) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Study slug must contain only letters, numbers, and dashes"})
+		return
+	}
+
+	study := &models.Study{
+		OrganizationID: orgID,
+		Slug:           slug,
+		Name:           req.Name,
+		WithdrawalData: req.WithdrawalData,
+	}
+	if study.WithdrawalData == "" {
+		study.WithdrawalData = models.WithdrawalDataChoice
+	}
+	if err := h.repo.Organizations.CreateStudy(study); err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Study already exists"})
+		return
+	}
+
+	h.log.Infow("Created study", "org", orgID, "slug", slug)
+	c.JSON(http.StatusCreated, study)
+}
+
+// AssignUser moves a user into an organization and sets their roles within it
+func (h *OrganizationHandler) AssignUser(c *gin.Context) {
+	req := c.MustGet("validatedRequest").(*validation.AssignOrganizationRequest)
+	orgID := strings.ToLower(req.OrganizationID)
+
+	if _, err := h.repo.Organizations.Get(orgID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Organization not found"})
+		return
+	}
+
+	if err := h.repo.Organizations.AssignUser(req.Email, orgID, req.IsOrgAdmin, req.IsBlindedReviewer); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	h.log.Infow("Assigned user to organization", "email", req.Email, "org", orgID,
+		"org_admin", req.IsOrgAdmin, "blinded_reviewer", req.IsBlindedReviewer)
+	c.JSON(http.StatusOK, gin.H{
+		"success":             true,
+		"organization_id":     orgID,
+		"is_org_admin":        req.IsOrgAdmin,
+		"is_blinded_reviewer": req.IsBlindedReviewer,
+	})
+}
+
+// orgScope returns the organization an admin request is limited to, or empty
+// for deployment-wide admins
+func orgScope(c *gin.Context) string {
+	scope, _ := c.Get("orgScope")
+	orgID, _ := scope.(string)
+	return orgID
+}
+
+// userInOrgScope reports whether an admin request may act on a user
+func userInOrgScope(c *gin.Context, repo *repository.Repository, email string) bool {
+	scope := orgScope(c)
+	if scope == "" {
+		return true
+	}
+	inScope, err := repo.Organizations.HasUser(scope, email)
+	return err == nil && inScope
+}
+
+// questionsForUser returns the question loader for a user's organization,
+// falling back to the deployment questionnaire
+func questionsForUser(repo *repository.Repository, registry *utils.QuestionRegistry, log *zap.SugaredLogger, email string) *utils.QuestionLoader {
+	org, err := repo.Organizations.GetForUser(email)
+	if err != nil || org.QuestionsFile == "" {
+		return registry.Default()
+	}
+
+	loader, err := registry.Get(org.QuestionsFile)
+	if err != nil {
+		log.Errorw("Error loading organization questions, using default", "error", err, "org", org.ID)
+		return registry.Default()
+	}
+	return loader
+}
+
+// questionsForOrganization returns the question set an organization uses,
+// or the default set for an empty organization ID
+func questionsForOrganization(repo *repository.Repository, registry *utils.QuestionRegistry, log *zap.SugaredLogger, orgID string) *utils.QuestionLoader {
+	if orgID == "" {
+		return registry.Default()
+	}
+	org, err := repo.Organizations.Get(orgID)
+	if err != nil || org.QuestionsFile == "" {
+		return registry.Default()
+	}
+
+	loader, err := registry.Get(org.QuestionsFile)
+	if err != nil {
+		log.Errorw("Error loading organization questions, using default", "error", err, "org", org.ID)
+		return registry.Default()
+	}
+	return loader
+}
+
+// scopedStudy loads the study named in the path, writing an error response
+// and returning nil if it does not exist in the admin's organization
+func scopedStudy(c *gin.Context, repo *repository.Repository) *models.Study {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid study ID"})
+		return nil
+	}
+
+	study, err := repo.Organizations.GetStudy(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Study not found"})
+		return nil
+	}
+	if scope := orgScope(c); scope != "" && scope != study.OrganizationID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Study not found"})
+		return nil
+	}
+	return study
+}
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"database/sql/driver"
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/andevellicus/crapp/internal/models"
+	"github.com/andevellicus/crapp/internal/repository"
+	"github.com/andevellicus/crapp/internal/testutil"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+func TestUserInOrgScope(t *testing.T) {
+	users := testutil.Users(
+		models.User{Email: "participant@a.example", OrganizationID: "org-a"},
+		models.User{Email: "participant@b.example", OrganizationID: "org-b"},
+		models.User{Email: "unassigned@example.com"},
+	)
+	log := zap.NewNop().Sugar()
+	repo := &repository.Repository{Organizations: repository.NewOrganizationRepository(testutil.OpenGorm(t, users), log)}
+
+	failing := func(string, []driver.Value) ([]string, [][]driver.Value, error) {
+		return nil, nil, errors.New("connection lost")
+	}
+	broken := &repository.Repository{Organizations: repository.NewOrganizationRepository(testutil.OpenGorm(t, failing), log)}
+
+	tests := []struct {
+		name  string
+		repo  *repository.Repository
+		scope any // Value of "orgScope"; nil leaves it unset
+		email string
+		want  bool
+	}{
+		{name: "unscoped admin reaches any user", repo: repo, scope: "", email: "participant@b.example", want: true},
+		{name: "unscoped admin reaches users without an organization", repo: repo, scope: "", email: "unassigned@example.com", want: true},
+		{name: "no scope set is unscoped", repo: repo, scope: nil, email: "participant@a.example", want: true},
+		{name: "user in the scoped organization", repo: repo, scope: "org-a", email: "participant@a.example", want: true},
+		{name: "email case is ignored", repo: repo, scope: "org-a", email: "Participant@A.example", want: true},
+		{name: "user in another organization", repo: repo, scope: "org-a", email: "participant@b.example", want: false},
+		{name: "user without an organization", repo: repo, scope: "org-a", email: "unassigned@example.com", want: false},
+		{name: "unknown user", repo: repo, scope: "org-a", email: "nobody@example.com", want: false},
+		{name: "database error denies", repo: broken, scope: "org-a", email: "participant@a.example", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			if tt.scope != nil {
+				c.Set("orgScope", tt.scope)
+			}
+			if got := userInOrgScope(c, tt.repo, tt.email); got != tt.want {
+				t.Errorf("userInOrgScope(%q, %q) = %v, want %v", tt.scope, tt.email, got, tt.want)
+			}
+		})
+	}
+}
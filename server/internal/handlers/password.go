@@ -2,43 +2,50 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strings"
 
+	"github.com/andevellicus/crapp/internal/models"
 	"github.com/andevellicus/crapp/internal/services"
 	"github.com/andevellicus/crapp/internal/validation"
 	"github.com/gin-gonic/gin"
 )
 
-// ForgotPassword handles password reset requests
+// resetRequestedMessage answers every reset request that isn't throttled, so
+// the response doesn't reveal whether the email has an account
+const resetRequestedMessage = "If your email is registered, you will receive a password reset link"
+
+// ForgotPassword handles password reset requests. Throttling and the response
+// are the same whether or not the email has an account.
 func (h *AuthHandler) ForgotPassword(c *gin.Context) {
 	req := c.MustGet("validatedRequest").(*validation.ForgotPasswordRequest)
 
+	emailService, exists := c.Get("emailService")
+	if !exists || emailService == nil || emailService.(*services.EmailService) == nil {
+		h.log.Errorw("Email service not available for password reset")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Email service not available"})
+		return
+	}
+
 	email := strings.ToLower(req.Email)
 	// Generate reset token
-	token, err := h.authService.GeneratePasswordResetToken(email)
-	if err != nil {
-		// Don't expose whether the email exists or not for security
-		h.log.Warnw("Failed to generate reset token", "error", err, "email", email)
-		c.JSON(http.StatusOK, gin.H{"message": "If your email is registered, you will receive a password reset link"})
+	token, code, err := h.authService.GeneratePasswordResetToken(email)
+	if errors.Is(err, services.ErrResetThrottled) {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Please wait before requesting another reset email"})
 		return
 	}
-
-	// Send email
-	emailService, exists := c.Get("emailService")
-	if !exists || emailService == nil {
-		h.log.Errorw("Email service not available", "email", email)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Email service not available"})
+	if err != nil {
+		h.log.Warnw("Failed to generate reset token", "error", err, "email", email)
+		c.JSON(http.StatusOK, gin.H{"message": resetRequestedMessage})
 		return
 	}
 
-	if err := emailService.(*services.EmailService).SendPasswordResetEmail(email, token); err != nil {
+	if err := emailService.(*services.EmailService).SendPasswordResetEmail(email, token, code, h.authService.ResetTokenMinutes()); err != nil {
 		h.log.Errorw("Failed to send password reset email", "error", err, "email", email)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send reset email"})
-		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Password reset link has been sent to your email"})
+	c.JSON(http.StatusOK, gin.H{"message": resetRequestedMessage})
 }
 
 // ValidateResetToken validates a password reset token
@@ -64,13 +71,40 @@ func (h *AuthHandler) ValidateResetToken(c *gin.Context) {
 func (h *AuthHandler) ResetPassword(c *gin.Context) {
 	req := c.MustGet("validatedRequest").(*validation.ResetPasswordRequest)
 
+	// A numeric code stands in for the link token
+	token := req.Token
+	if req.Code != "" {
+		resolved, err := h.authService.ResolveResetCode(req.Email, req.Code)
+		if err != nil {
+			h.log.Warnw("Invalid reset code", "error", err, "email", req.Email)
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired code"})
+			return
+		}
+		token = resolved
+	}
+
+	// Look up the owner before the token is consumed
+	email, _ := h.authService.ValidatePasswordResetToken(token)
+
 	// Reset password
-	err := h.authService.ResetPassword(req.Token, req.NewPassword)
+	err := h.authService.ResetPassword(token, req.NewPassword)
 	if err != nil {
 		h.log.Errorw("Failed to reset password", "error", err)
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
+	if email != "" {
+		recordAudit(h.repo, h.log, c, email, models.AuditPasswordReset, "", nil)
+
+		if h.authService.ShouldNotifyOnReset() {
+			if emailService, exists := c.Get("emailService"); exists && emailService.(*services.EmailService) != nil {
+				if err := emailService.(*services.EmailService).SendPasswordChangedEmail(email, h.authService.RevokesSessionsOnReset()); err != nil {
+					h.log.Warnw("Failed to send password changed email", "error", err, "email", email)
+				}
+			}
+		}
+	}
+
 	c.JSON(http.StatusOK, gin.H{"message": "Password has been reset successfully"})
 }
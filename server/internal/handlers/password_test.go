@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andevellicus/crapp/internal/config"
+	"github.com/andevellicus/crapp/internal/models"
+	"github.com/andevellicus/crapp/internal/repository"
+	"github.com/andevellicus/crapp/internal/services"
+	"github.com/andevellicus/crapp/internal/testutil"
+	"github.com/andevellicus/crapp/internal/utils"
+	"github.com/andevellicus/crapp/internal/validation"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// TestForgotPasswordHidesAccounts checks a registered email and an unknown
+// one get the same answers, both on the first request and when throttled
+func TestForgotPasswordHidesAccounts(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	log := zap.NewNop().Sugar()
+	recorder := testutil.NewRecorder(models.User{Email: "participant@example.com"})
+	db := testutil.OpenGorm(t, recorder.Respond)
+	days, _ := utils.NewAssessmentDay("UTC", "")
+	users := repository.NewUserRepository(db, log, &config.Config{}, days)
+	repo := &repository.Repository{
+		Users:               users,
+		PasswordResetTokens: repository.NewPasswordTokenRepository(db, log, users),
+	}
+	authService := services.NewAuthService(repo, &config.JWTConfig{},
+		&config.PasswordResetConfig{TokenMinutes: 30, ResendCooldownSeconds: 60, MaxPerHour: 5})
+	handler := &AuthHandler{repo: repo, log: log, authService: authService}
+	// Nothing listens on port 1, so every send fails fast
+	emailService := services.NewEmailService(nil, &config.EmailConfig{SMTPHost: "127.0.0.1", SMTPPort: 1},
+		&config.BrandingConfig{}, &config.OutboundPolicy{MaxAttempts: 1}, log, nil)
+
+	forgot := func(email string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/api/auth/forgot-password", nil)
+		c.Set("validatedRequest", &validation.ForgotPasswordRequest{Email: email})
+		c.Set("emailService", emailService)
+		handler.ForgotPassword(c)
+		return w
+	}
+
+	for _, round := range []struct {
+		name string
+		want int
+	}{
+		{"first request", http.StatusOK},
+		{"within the cooldown", http.StatusTooManyRequests},
+	} {
+		registered := forgot("participant@example.com")
+		unknown := forgot("nobody@example.com")
+		if registered.Code != round.want || unknown.Code != round.want {
+			t.Errorf("%s: status = %d for a registered email and %d for an unknown one, want %d",
+				round.name, registered.Code, unknown.Code, round.want)
+		}
+		if registered.Body.String() != unknown.Body.String() {
+			t.Errorf("%s: registered email got %s, unknown email got %s", round.name, registered.Body, unknown.Body)
+		}
+	}
+
+	// Only the registered email should have been issued a token
+	issued := 0
+	for _, statement := range recorder.Statements() {
+		if strings.HasPrefix(statement.Query, `INSERT INTO "password_reset_tokens"`) {
+			issued++
+		}
+	}
+	if issued != 1 {
+		t.Errorf("issued %d reset tokens, want 1", issued)
+	}
+}
@@ -0,0 +1,130 @@
+// internal/handlers/perf_beacon.go
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/andevellicus/crapp/internal/config"
+	"github.com/andevellicus/crapp/internal/models"
+	"github.com/andevellicus/crapp/internal/repository"
+	"github.com/andevellicus/crapp/internal/validation"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// PerfBeaconHandler takes in Web Vitals and timing beacons from the PWA and
+// judges whether a device was too slow for its cognitive test results to be
+// trusted
+type PerfBeaconHandler struct {
+	repo *repository.Repository
+	log  *zap.SugaredLogger
+	cfg  *config.PerformanceConfig
+
+	mu         sync.Mutex
+	lastPruned time.Time
+}
+
+// NewPerfBeaconHandler creates a new performance beacon handler
+func NewPerfBeaconHandler(repo *repository.Repository, log *zap.SugaredLogger, cfg *config.PerformanceConfig) *PerfBeaconHandler {
+	return &PerfBeaconHandler{
+		repo: repo,
+		log:  log.Named("perf-beacons"),
+		cfg:  cfg,
+	}
+}
+
+// Report stores a batch of beacons from one session. Beacons carry no
+// account; the session ID is stored only as a hash.
+func (h *PerfBeaconHandler) Report(c *gin.Context) {
+	req := c.MustGet("validatedRequest").(*validation.PerfBeaconBatchRequest)
+	if !h.cfg.Enabled {
+		c.Status(http.StatusNoContent)
+		return
+	}
+
+	sessionHash := perfSessionHash(req.SessionID)
+	release := scrubClientText(req.Release)
+	now := time.Now()
+	beacons := make([]models.PerfBeacon, 0, len(req.Beacons))
+	for _, b := range req.Beacons {
+		recordedAt := b.RecordedAt
+		if recordedAt.IsZero() || recordedAt.After(now) || now.Sub(recordedAt) > 24*time.Hour {
+			recordedAt = now
+		}
+		beacons = append(beacons, models.PerfBeacon{
+			SessionHash: sessionHash,
+			Metric:      b.Metric,
+			Value:       b.Value,
+			Context:     b.Context,
+			Route:       scrubClientURL(b.Route),
+			Release:     release,
+			RecordedAt:  recordedAt,
+		})
+	}
+
+	if err := h.repo.PerfBeacons.CreateBatch(beacons); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error storing beacons"})
+		return
+	}
+	h.pruneExpired()
+
+	c.Status(http.StatusAccepted)
+}
+
+// Assess summarizes a session's beacons and reports whether the device was
+// too slow during cognitive tests. Without a session, or without beacons,
+// nothing is flagged.
+func (h *PerfBeaconHandler) Assess(sessionID string) (bool, models.JSON) {
+	if h == nil || !h.cfg.Enabled || sessionID == "" {
+		return false, nil
+	}
+
+	summary, err := h.repo.PerfBeacons.SummarizeSession(perfSessionHash(sessionID))
+	if err != nil {
+		h.log.Warnw("Error summarizing performance beacons", "error", err)
+		return false, nil
+	}
+	if summary.LongTasks == 0 && summary.MaxINPMs == 0 && summary.LCPMs == 0 {
+		return false, nil
+	}
+
+	slow := (h.cfg.LongTaskBudgetMs > 0 && summary.LongTaskMs > h.cfg.LongTaskBudgetMs) ||
+		(h.cfg.MaxINPMs > 0 && summary.MaxINPMs > h.cfg.MaxINPMs)
+	return slow, models.JSON{
+		"long_tasks":   summary.LongTasks,
+		"long_task_ms": summary.LongTaskMs,
+		"max_inp_ms":   summary.MaxINPMs,
+		"lcp_ms":       summary.LCPMs,
+	}
+}
+
+// pruneExpired removes beacons past their retention, at most hourly
+func (h *PerfBeaconHandler) pruneExpired() {
+	if h.cfg.RetentionDays <= 0 {
+		return
+	}
+	h.mu.Lock()
+	if time.Since(h.lastPruned) < time.Hour {
+		h.mu.Unlock()
+		return
+	}
+	h.lastPruned = time.Now()
+	h.mu.Unlock()
+
+	deleted, err := h.repo.PerfBeacons.DeleteBefore(time.Now().AddDate(0, 0, -h.cfg.RetentionDays))
+	if err != nil {
+		h.log.Warnw("Failed to prune performance beacons", "error", err)
+	} else if deleted > 0 {
+		h.log.Infow("Pruned performance beacons", "count", deleted)
+	}
+}
+
+// perfSessionHash is how a beacon session is stored
+func perfSessionHash(sessionID string) string {
+	sum := sha256.Sum256([]byte(sessionID))
+	return hex.EncodeToString(sum[:])
+}
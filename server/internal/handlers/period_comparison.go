@@ -0,0 +1,164 @@
+// internal/handlers/period_comparison.go
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/andevellicus/crapp/internal/repository"
+	"github.com/andevellicus/crapp/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// comparedPeriod is one of the two date ranges being compared
+type comparedPeriod struct {
+	services.PeriodSummary
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+}
+
+// GetPeriodComparison compares a symptom or metric between two date ranges,
+// such as before and after a medication change. Each assessment day counts
+// once, with its values averaged, and the means are compared with Welch's
+// t-test.
+func (h *GinAPIHandler) GetPeriodComparison(c *gin.Context) {
+	// Access is checked by RequireSelfOrRole
+	userID := c.GetString("resourceOwner")
+	blinded := c.GetBool("blinded")
+	symptomKey := c.Query("symptom")
+	metricKey := c.Query("metric")
+	series := c.DefaultQuery("series", "symptom")
+
+	if series != "symptom" && series != "metric" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "series must be symptom or metric"})
+		return
+	}
+	questions := questionsForUser(h.repo, h.questions, h.log, userID)
+	questionType := questionTypeOf(questions, symptomKey)
+	if series == "symptom" {
+		// Blinded viewers only get the metric series
+		if blinded {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Not allowed to view this user's answers"})
+			return
+		}
+		if slices.Contains(nonNumericQuestionTypes, questionType) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "This question has no numeric answers to analyse"})
+			return
+		}
+	} else if metricKey == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "metric is required for the metric series"})
+		return
+	}
+
+	periodA, err := comparisonPeriod(c, "a")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	periodB, err := comparisonPeriod(c, "b")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !periodA.To.Before(*periodB.From) && !periodB.To.Before(*periodA.From) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The two periods must not overlap"})
+		return
+	}
+
+	alpha := 0.05
+	if param := c.Query("alpha"); param != "" {
+		val, err := strconv.ParseFloat(param, 64)
+		if err != nil || val <= 0 || val >= 0.5 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "alpha must be between 0 and 0.5"})
+			return
+		}
+		alpha = val
+	}
+	includeRetrospective := c.Query("include_retrospective") == "true"
+
+	var timelineData []repository.TimelineDataPoint
+	if series == "symptom" && metricKey == "" {
+		timelineData, err = h.repo.ForUser(userID).Assessments.GetSymptomTimeline(userID, symptomKey, includeRetrospective)
+	} else {
+		timelineData, err = h.loadTimeline(userID, symptomKey, metricKey, questionType, repository.ChartFilter{
+			IncludeRetrospective: includeRetrospective,
+			From:                 earliest(periodA.From, periodB.From),
+			To:                   latest(periodA.To, periodB.To),
+		})
+	}
+	if err != nil {
+		h.log.Errorw("Error retrieving series for period comparison", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error retrieving data"})
+		return
+	}
+	timelineData = repository.BucketTimeline(timelineData, repository.TimelineBucketDay)
+
+	var valuesA, valuesB []float64
+	for _, point := range timelineData {
+		value := point.SymptomValue
+		if series == "metric" {
+			value = point.MetricValue
+		}
+		switch {
+		case periodA.Contains(point.Date):
+			valuesA = append(valuesA, value)
+		case periodB.Contains(point.Date):
+			valuesB = append(valuesB, value)
+		}
+	}
+	result := services.ComparePeriods(valuesA, valuesB, alpha)
+
+	label := getMetricLabel(metricKey)
+	if series == "symptom" {
+		label = questionTitle(questions, symptomKey)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"series":             series,
+		"label":              label,
+		"a":                  comparedPeriod{result.A, *periodA.From, *periodA.To},
+		"b":                  comparedPeriod{result.B, *periodB.From, *periodB.To},
+		"difference":         result.Difference,
+		"effect_size":        result.EffectSize,
+		"test":               result.Test,
+		"t":                  result.T,
+		"degrees_of_freedom": result.DegreesOfFreedom,
+		"p_value":            result.PValue,
+		"significant":        result.Significant,
+		"alpha":              result.Alpha,
+	})
+}
+
+// comparisonPeriod reads a period's <prefix>_from and <prefix>_to dates,
+// both required and inclusive
+func comparisonPeriod(c *gin.Context, prefix string) (repository.ChartFilter, error) {
+	from, err := time.Parse("2006-01-02", c.Query(prefix+"_from"))
+	if err != nil {
+		return repository.ChartFilter{}, fmt.Errorf("%s_from must be a date (YYYY-MM-DD)", prefix)
+	}
+	to, err := time.Parse("2006-01-02", c.Query(prefix+"_to"))
+	if err != nil {
+		return repository.ChartFilter{}, fmt.Errorf("%s_to must be a date (YYYY-MM-DD)", prefix)
+	}
+	if from.After(to) {
+		return repository.ChartFilter{}, fmt.Errorf("%s_from must not be after %s_to", prefix, prefix)
+	}
+	return repository.ChartFilter{From: &from, To: &to}, nil
+}
+
+func earliest(a, b *time.Time) *time.Time {
+	if a.Before(*b) {
+		return a
+	}
+	return b
+}
+
+func latest(a, b *time.Time) *time.Time {
+	if a.After(*b) {
+		return a
+	}
+	return b
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"net/http"
 
+	"github.com/andevellicus/crapp/internal/models"
 	"github.com/andevellicus/crapp/internal/repository"
 	"github.com/andevellicus/crapp/internal/scheduler"
 	"github.com/andevellicus/crapp/internal/services"
@@ -76,22 +77,43 @@ func (h *PushHandler) UpdatePreferences(c *gin.Context) {
 	}
 
 	// Get validated preferences
-	prefs := c.MustGet("validatedRequest").(*validation.NotificationPreferencesRequest)
+	req := c.MustGet("validatedRequest").(*validation.NotificationPreferencesRequest)
 
-	// Convert to repository model
-	preferences := repository.UserNotificationPreferences{
-		PushEnabled:   prefs.PushEnabled,
-		EmailEnabled:  prefs.EmailEnabled,
-		ReminderTimes: prefs.ReminderTimes,
+	// Apply the changes on top of the current preferences
+	preferences, err := h.repo.Users.GetNotificationPreferences(userEmail.(string))
+	if err != nil {
+		h.log.Errorw("Failed to get preferences", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save preferences"})
+		return
+	}
+
+	for channel, update := range req.Channels {
+		settings := preferences.Channels[channel]
+		settings.Enabled = update.Enabled
+		for notificationType, enabled := range update.Types {
+			settings.Types[notificationType] = enabled
+		}
+		if update.ReminderTimes != nil {
+			settings.ReminderTimes = update.ReminderTimes
+		}
+		preferences.Channels[channel] = settings
+	}
+	if req.CutoffTime != "" {
+		preferences.CutoffTime = req.CutoffTime
 	}
 
 	// Save preferences
-	if err := h.repo.Users.SaveNotificationPreferences(userEmail.(string), &preferences); err != nil {
+	if err := h.repo.Users.SaveNotificationPreferences(userEmail.(string), preferences); err != nil {
 		h.log.Errorw("Failed to save preferences", "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save preferences"})
 		return
 	}
 
+	recordAudit(h.repo, h.log, c, userEmail.(string), models.AuditPreferencesChange, "", map[string]any{
+		"channels":    preferences.Channels,
+		"cutoff_time": preferences.CutoffTime,
+	})
+
 	// Update schedules if needed
 	if h.scheduler != nil {
 		if err := h.scheduler.UpdateSchedules(); err != nil {
@@ -99,10 +121,10 @@ func (h *PushHandler) UpdatePreferences(c *gin.Context) {
 		}
 	}
 
-	c.JSON(http.StatusOK, gin.H{"success": true})
+	c.JSON(http.StatusOK, preferences)
 }
 
-// GetPreferences gets a user's push notification preferences
+// GetPreferences gets a user's notification preferences for every channel and type
 func (h *PushHandler) GetPreferences(c *gin.Context) {
 	userEmail, exists := c.Get("userEmail")
 	if !exists {
@@ -118,9 +140,5 @@ func (h *PushHandler) GetPreferences(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"push_enabled":   preferences.PushEnabled,
-		"email_enabled":  preferences.EmailEnabled,
-		"reminder_times": preferences.ReminderTimes,
-	})
+	c.JSON(http.StatusOK, preferences)
 }
@@ -0,0 +1,99 @@
+// internal/handlers/prometheus.go
+package handlers
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/andevellicus/crapp/internal/config"
+	"github.com/andevellicus/crapp/internal/repository"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// MetricsHandler serves operational metrics in the Prometheus text format
+type MetricsHandler struct {
+	repo         *repository.Repository
+	log          *zap.SugaredLogger
+	config       *config.MetricsConfig
+	clientErrors *ClientErrorHandler
+}
+
+// NewMetricsHandler creates a new metrics handler
+func NewMetricsHandler(repo *repository.Repository, log *zap.SugaredLogger, cfg *config.MetricsConfig, clientErrors *ClientErrorHandler) *MetricsHandler {
+	return &MetricsHandler{
+		repo:         repo,
+		log:          log.Named("metrics"),
+		config:       cfg,
+		clientErrors: clientErrors,
+	}
+}
+
+// GetMetrics writes database pool statistics for every open pool, and the
+// number of errors reported by clients
+func (h *MetricsHandler) GetMetrics(c *gin.Context) {
+	if h.config.Token != "" {
+		token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(h.config.Token)) != 1 {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+	}
+
+	stats := h.repo.PoolStats()
+	pools := make([]string, 0, len(stats))
+	for pool := range stats {
+		pools = append(pools, pool)
+	}
+	slices.Sort(pools)
+
+	var b strings.Builder
+	write := func(kind, name, help string, value func(pool string) float64) {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+		for _, pool := range pools {
+			fmt.Fprintf(&b, "%s{pool=%q} %g\n", name, pool, value(pool))
+		}
+	}
+	gauge := func(name, help string, value func(pool string) float64) { write("gauge", name, help, value) }
+	counter := func(name, help string, value func(pool string) float64) { write("counter", name, help, value) }
+
+	gauge("crapp_db_max_open_connections", "Maximum number of open connections to the database.",
+		func(p string) float64 { return float64(stats[p].MaxOpenConnections) })
+	gauge("crapp_db_open_connections", "Number of established connections, both in use and idle.",
+		func(p string) float64 { return float64(stats[p].OpenConnections) })
+	gauge("crapp_db_in_use_connections", "Number of connections currently in use.",
+		func(p string) float64 { return float64(stats[p].InUse) })
+	gauge("crapp_db_idle_connections", "Number of idle connections.",
+		func(p string) float64 { return float64(stats[p].Idle) })
+	counter("crapp_db_wait_count_total", "Total number of connections waited for.",
+		func(p string) float64 { return float64(stats[p].WaitCount) })
+	counter("crapp_db_wait_duration_seconds_total", "Total time blocked waiting for a new connection.",
+		func(p string) float64 { return stats[p].WaitDuration.Seconds() })
+	counter("crapp_db_max_idle_closed_total", "Total connections closed due to the idle limit.",
+		func(p string) float64 { return float64(stats[p].MaxIdleClosed) })
+	counter("crapp_db_max_idle_time_closed_total", "Total connections closed due to the idle time limit.",
+		func(p string) float64 { return float64(stats[p].MaxIdleTimeClosed) })
+	counter("crapp_db_max_lifetime_closed_total", "Total connections closed due to the lifetime limit.",
+		func(p string) float64 { return float64(stats[p].MaxLifetimeClosed) })
+
+	if h.clientErrors != nil {
+		counts := h.clientErrors.Counts()
+		keys := make([]clientErrorKey, 0, len(counts))
+		for key := range counts {
+			keys = append(keys, key)
+		}
+		slices.SortFunc(keys, func(a, b clientErrorKey) int {
+			return strings.Compare(a.kind+"/"+a.release, b.kind+"/"+b.release)
+		})
+
+		fmt.Fprint(&b, "# HELP crapp_client_errors_total JavaScript errors reported by clients since startup.\n# TYPE crapp_client_errors_total counter\n")
+		for _, key := range keys {
+			fmt.Fprintf(&b, "crapp_client_errors_total{kind=%q,release=%q} %d\n", key.kind, key.release, counts[key])
+		}
+	}
+
+	c.Data(http.StatusOK, "text/plain; version=0.0.4; charset=utf-8", []byte(b.String()))
+}
@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/andevellicus/crapp/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// scheduledQuestion is when a question that isn't asked every day is next due
+type scheduledQuestion struct {
+	QuestionID    string  `json:"question_id"`
+	Title         string  `json:"title"`
+	Type          string  `json:"type"`
+	EveryDays     int     `json:"every_days"`
+	LastCompleted *string `json:"last_completed"` // YYYY-MM-DD, empty if never completed
+	NextDue       string  `json:"next_due"`       // YYYY-MM-DD
+	Due           bool    `json:"due"`
+}
+
+// questionSchedule works out which of the questions with an every_days rule
+// are due on an assessment day. A question is due once that many days have
+// passed since the user last completed it, or if they never have.
+func (h *FormHandler) questionSchedule(email string, questions []utils.Question, day time.Time) ([]scheduledQuestion, error) {
+	types := make(map[string]string)
+	for _, q := range questions {
+		if q.EveryDays > 0 {
+			types[q.ID] = q.Type
+		}
+	}
+	if len(types) == 0 {
+		return nil, nil
+	}
+
+	last, err := h.repo.ForUser(email).Assessments.LastCompletedDays(email, types)
+	if err != nil {
+		return nil, err
+	}
+
+	schedule := make([]scheduledQuestion, 0, len(types))
+	for _, q := range questions {
+		if q.EveryDays <= 0 {
+			continue
+		}
+		entry := scheduledQuestion{QuestionID: q.ID, Title: q.Title, Type: q.Type, EveryDays: q.EveryDays}
+		nextDue := day
+		if completed, ok := last[q.ID]; ok {
+			date := completed.Format("2006-01-02")
+			entry.LastCompleted = &date
+			// Dates come back at UTC midnight; move them onto the day's calendar
+			next := time.Date(completed.Year(), completed.Month(), completed.Day()+q.EveryDays, 0, 0, 0, 0, day.Location())
+			if next.After(day) {
+				nextDue = next
+			}
+		}
+		entry.NextDue = nextDue.Format("2006-01-02")
+		entry.Due = !nextDue.After(day)
+		schedule = append(schedule, entry)
+	}
+	return schedule, nil
+}
+
+// dueQuestions returns the indexes of the questions a form for the day should
+// include: every question without a schedule, and scheduled ones that are due.
+// If nothing would be left, all questions are included.
+func (h *FormHandler) dueQuestions(email string, questions []utils.Question, day time.Time) ([]int, error) {
+	schedule, err := h.questionSchedule(email, questions, day)
+	if err != nil {
+		return nil, err
+	}
+	notDue := make(map[string]bool)
+	for _, entry := range schedule {
+		if !entry.Due {
+			notDue[entry.QuestionID] = true
+		}
+	}
+
+	// Honeypots are never served
+	indexes := make([]int, 0, len(questions))
+	for i, q := range questions {
+		if !notDue[q.ID] && !q.Honeypot {
+			indexes = append(indexes, i)
+		}
+	}
+	if len(indexes) == 0 {
+		for i, q := range questions {
+			if !q.Honeypot {
+				indexes = append(indexes, i)
+			}
+		}
+	}
+	return indexes, nil
+}
+
+// GetQuestionSchedule lists the user's questions that aren't asked every day,
+// with when each was last completed and is next due
+func (h *FormHandler) GetQuestionSchedule(c *gin.Context) {
+	userEmail := c.GetString("userEmail")
+
+	days := h.repo.AssessmentDay()
+	if user, err := h.repo.Users.GetByEmail(userEmail); err == nil && user != nil {
+		days = h.repo.Users.AssessmentDayFor(user)
+	}
+	today := days.Today()
+
+	questions := questionsForUser(h.repo, h.questions, h.log, userEmail).GetQuestions()
+	schedule, err := h.questionSchedule(userEmail, questions, today)
+	if err != nil {
+		h.log.Errorw("Error building question schedule", "error", err, "user", userEmail)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	if schedule == nil {
+		schedule = []scheduledQuestion{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"today":     today.Format("2006-01-02"),
+		"questions": schedule,
+	})
+}
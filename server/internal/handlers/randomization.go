@@ -0,0 +1,124 @@
+// internal/handlers/randomization.go
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/andevellicus/crapp/internal/models"
+	"github.com/andevellicus/crapp/internal/repository"
+	"github.com/andevellicus/crapp/internal/services"
+	"github.com/andevellicus/crapp/internal/validation"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// RandomizationHandler handles study arm randomization
+type RandomizationHandler struct {
+	repo                 *repository.Repository
+	log                  *zap.SugaredLogger
+	randomizationService *services.RandomizationService
+}
+
+// NewRandomizationHandler creates a new randomization handler
+func NewRandomizationHandler(repo *repository.Repository, log *zap.SugaredLogger, randomizationService *services.RandomizationService) *RandomizationHandler {
+	return &RandomizationHandler{
+		repo:                 repo,
+		log:                  log.Named("randomization"),
+		randomizationService: randomizationService,
+	}
+}
+
+// GetScheme returns a study's randomization scheme
+func (h *RandomizationHandler) GetScheme(c *gin.Context) {
+	study := scopedStudy(c, h.repo)
+	if study == nil {
+		return
+	}
+
+	scheme, err := h.repo.Randomization.GetScheme(study.ID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Study has no randomization scheme"})
+		return
+	}
+	c.JSON(http.StatusOK, scheme)
+}
+
+// ConfigureScheme sets a study's randomization scheme before enrollment starts
+func (h *RandomizationHandler) ConfigureScheme(c *gin.Context) {
+	study := scopedStudy(c, h.repo)
+	if study == nil {
+		return
+	}
+	req := c.MustGet("validatedRequest").(*validation.ConfigureRandomizationRequest)
+
+	scheme := &models.RandomizationScheme{
+		StudyID:   study.ID,
+		Method:    req.Method,
+		BlockSize: req.BlockSize,
+		Seed:      req.Seed,
+		BlindArms: req.BlindArms == nil || *req.BlindArms,
+		CreatedBy: c.GetString("userEmail"),
+	}
+	if err := h.randomizationService.Configure(scheme, req.Arms); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.log.Infow("Configured randomization", "study_id", study.ID, "method", scheme.Method, "admin", scheme.CreatedBy)
+	c.JSON(http.StatusOK, scheme)
+}
+
+// Randomize allocates a participant to an arm
+func (h *RandomizationHandler) Randomize(c *gin.Context) {
+	study := scopedStudy(c, h.repo)
+	if study == nil {
+		return
+	}
+	req := c.MustGet("validatedRequest").(*validation.RandomizeParticipantRequest)
+	email := strings.ToLower(req.Email)
+
+	// Participants can only join studies of their own organization
+	inOrg, err := h.repo.Organizations.HasUser(study.OrganizationID, email)
+	if err != nil || !inOrg {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Participant not found"})
+		return
+	}
+
+	adminEmail := c.GetString("userEmail")
+	allocation, err := h.randomizationService.Randomize(study, email, req.Age, req.Sex, adminEmail)
+	if errors.Is(err, repository.ErrAlreadyAllocated) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Participant has already been randomized"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// The arm itself stays out of the participant-visible audit log
+	recordAudit(h.repo, h.log, c, email, models.AuditArmAllocated, "", map[string]any{
+		"study_id":     study.ID,
+		"allocated_by": adminEmail,
+		"method":       allocation.Method,
+		"sequence":     allocation.Sequence,
+	})
+
+	c.JSON(http.StatusCreated, allocation)
+}
+
+// ListAllocations returns a study's allocation list
+func (h *RandomizationHandler) ListAllocations(c *gin.Context) {
+	study := scopedStudy(c, h.repo)
+	if study == nil {
+		return
+	}
+
+	allocations, err := h.repo.Randomization.ListAllocations(study.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error listing allocations"})
+		return
+	}
+	c.JSON(http.StatusOK, allocations)
+}
@@ -0,0 +1,81 @@
+// internal/handlers/raw_data.go
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/andevellicus/crapp/internal/models"
+	"github.com/andevellicus/crapp/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// rawDataWarnBytes is the payload size above which a raw data download
+// carries a warning, since a browser may struggle to open it
+const rawDataWarnBytes = 5 << 20
+
+// GetOwnRawData returns the raw keystroke and mouse data recorded during one
+// of the caller's assessments, decompressed, with the raw data of any
+// cognitive tests taken in it. The ownership middleware has already loaded
+// the assessment.
+func (h *ReplayHandler) GetOwnRawData(c *gin.Context) {
+	assessment := c.MustGet("assessment").(*models.Assessment)
+	userEmail := c.GetString("userEmail")
+
+	raw, err := h.repo.ForUser(userEmail).Assessments.GetRawData(assessment.ID)
+	if err != nil {
+		h.log.Errorw("Error loading raw assessment data", "error", err, "assessment_id", assessment.ID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error loading assessment data"})
+		return
+	}
+
+	response := gin.H{
+		"assessment_id": assessment.ID,
+		"submitted_at":  assessment.SubmittedAt,
+	}
+	size := 0
+	if interactions := decompressInteractions(raw.InteractionData); interactions != nil {
+		response["interactions"] = interactions
+		size += len(interactions)
+	}
+	if raw.CPT != nil && len(raw.CPT.RawData) > 0 {
+		response["cpt"] = raw.CPT.RawData
+		size += len(raw.CPT.RawData)
+	}
+	if raw.TMT != nil && len(raw.TMT.RawData) > 0 {
+		response["tmt"] = raw.TMT.RawData
+		size += len(raw.TMT.RawData)
+	}
+	if raw.DigitSpan != nil && len(raw.DigitSpan.RawData) > 0 {
+		response["digit_span"] = raw.DigitSpan.RawData
+		size += len(raw.DigitSpan.RawData)
+	}
+	response["size_bytes"] = size
+	if size > rawDataWarnBytes {
+		response["warning"] = fmt.Sprintf("This download is %.1f MB, which some browsers and spreadsheet programs can't open. Save it to a file rather than viewing it.",
+			float64(size)/(1<<20))
+	}
+
+	recordAudit(h.repo, h.log, c, userEmail, models.AuditDataExport, "",
+		map[string]any{"assessment_id": assessment.ID, "raw_data": true})
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="assessment-%d-raw-data.json"`, assessment.ID))
+	c.JSON(http.StatusOK, response)
+}
+
+// decompressInteractions returns interaction data as JSON, or nil if there is
+// none or it can't be read
+func decompressInteractions(data []byte) json.RawMessage {
+	if len(data) == 0 {
+		return nil
+	}
+	decompressed, err := utils.DecompressData(data)
+	if err != nil {
+		decompressed = data
+	}
+	if !json.Valid(decompressed) {
+		return nil
+	}
+	return decompressed
+}
@@ -0,0 +1,62 @@
+// internal/handlers/redaction.go
+package handlers
+
+import (
+	"github.com/andevellicus/crapp/internal/models"
+	"github.com/andevellicus/crapp/internal/repository"
+	"github.com/gin-gonic/gin"
+)
+
+// isBlinded reports whether the request comes from a blinded reviewer
+func isBlinded(c *gin.Context) bool {
+	return c.GetBool("blinded")
+}
+
+// redactTimeline removes symptom values from timeline points
+func redactTimeline(points []repository.TimelineDataPoint) {
+	for i := range points {
+		points[i].SymptomValue = 0
+		points[i].SymptomMin = 0
+		points[i].SymptomMax = 0
+	}
+}
+
+// redactResponses removes answer values, and arms of studies that blind
+// them, from exported responses
+func redactResponses(responses []repository.ReviewResponse) {
+	for i := range responses {
+		responses[i].NumericValue = nil
+		responses[i].TextValue = nil
+		responses[i].ChangedFromPrevious = nil
+		responses[i].Masked = true
+		if responses[i].ArmBlinded {
+			responses[i].Arm = ""
+		}
+	}
+}
+
+// redactAdherenceArms removes arms of studies that blind them
+func redactAdherenceArms(participants []repository.ParticipantAdherence) {
+	for i := range participants {
+		if participants[i].ArmBlinded {
+			participants[i].Arm = ""
+		}
+	}
+}
+
+// redactMetricArms removes arms of studies that blind them from exported metrics
+func redactMetricArms(metrics []repository.ReviewMetric) {
+	for i := range metrics {
+		if metrics[i].ArmBlinded {
+			metrics[i].Arm = ""
+		}
+	}
+}
+
+// redactClinicalEvents removes free text that could reveal treatment allocation
+func redactClinicalEvents(events []models.ClinicalEvent) {
+	for i := range events {
+		events[i].Description = ""
+		events[i].FollowUpNotes = ""
+	}
+}
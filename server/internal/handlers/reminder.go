@@ -0,0 +1,105 @@
+// internal/handlers/reminder.go
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/andevellicus/crapp/internal/models"
+	"github.com/andevellicus/crapp/internal/repository"
+	"github.com/andevellicus/crapp/internal/scheduler"
+	"github.com/andevellicus/crapp/internal/services"
+	"github.com/andevellicus/crapp/internal/validation"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// ReminderHandler lets users snooze or skip the current day's reminders, and
+// shows the in-app reminder
+type ReminderHandler struct {
+	repo      *repository.Repository
+	log       *zap.SugaredLogger
+	scheduler *scheduler.ReminderScheduler
+	renderer  *services.ReminderRenderer
+}
+
+// NewReminderHandler creates a new reminder handler
+func NewReminderHandler(repo *repository.Repository, log *zap.SugaredLogger, scheduler *scheduler.ReminderScheduler, renderer *services.ReminderRenderer) *ReminderHandler {
+	return &ReminderHandler{
+		repo:      repo,
+		log:       log.Named("reminders"),
+		scheduler: scheduler,
+		renderer:  renderer,
+	}
+}
+
+// GetMessage returns the in-app reminder while the user's assessment for the
+// day is still to do
+func (h *ReminderHandler) GetMessage(c *gin.Context) {
+	userEmail := c.GetString("userEmail")
+
+	completed, err := h.repo.Users.HasCompletedAssessment(userEmail)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error checking today's assessment"})
+		return
+	}
+	if completed {
+		c.JSON(http.StatusOK, gin.H{"due": false})
+		return
+	}
+
+	content, err := h.renderer.Render(userEmail, models.ReminderChannelInApp)
+	if err != nil {
+		h.log.Errorw("Failed to render in-app reminder", "error", err, "user", userEmail)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error loading reminder"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"due": true, "title": content.Subject, "body": content.Body})
+}
+
+// Snooze holds back the user's reminders for a while, then sends one
+func (h *ReminderHandler) Snooze(c *gin.Context) {
+	userEmail, exists := c.Get("userEmail")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	if h.scheduler == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Reminders are not available"})
+		return
+	}
+
+	req := c.MustGet("validatedRequest").(*validation.SnoozeReminderRequest)
+
+	remindAt, err := h.scheduler.Snooze(userEmail.(string), req.Minutes)
+	if err != nil {
+		h.log.Errorw("Failed to snooze reminders", "error", err, "user", userEmail)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to snooze reminders"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "remind_at": remindAt})
+}
+
+// SkipToday stops the user's remaining reminders for the current assessment day
+func (h *ReminderHandler) SkipToday(c *gin.Context) {
+	userEmail, exists := c.Get("userEmail")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	if h.scheduler == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Reminders are not available"})
+		return
+	}
+
+	day, err := h.scheduler.SkipToday(userEmail.(string))
+	if err != nil {
+		h.log.Errorw("Failed to skip today's reminders", "error", err, "user", userEmail)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to skip reminders"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "assessment_day": day.Format("2006-01-02")})
+}
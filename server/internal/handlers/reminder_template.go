@@ -0,0 +1,124 @@
+// internal/handlers/reminder_template.go
+package handlers
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/andevellicus/crapp/internal/models"
+	"github.com/andevellicus/crapp/internal/repository"
+	"github.com/andevellicus/crapp/internal/services"
+	"github.com/andevellicus/crapp/internal/validation"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// ReminderTemplateHandler lets admins word a study's reminders
+type ReminderTemplateHandler struct {
+	repo *repository.Repository
+	log  *zap.SugaredLogger
+}
+
+// NewReminderTemplateHandler creates a new reminder template handler
+func NewReminderTemplateHandler(repo *repository.Repository, log *zap.SugaredLogger) *ReminderTemplateHandler {
+	return &ReminderTemplateHandler{
+		repo: repo,
+		log:  log.Named("reminder-templates"),
+	}
+}
+
+// ListTemplates returns a study's reminder templates, with the default copy
+// for each channel and the variables the copy may use
+func (h *ReminderTemplateHandler) ListTemplates(c *gin.Context) {
+	study := scopedStudy(c, h.repo)
+	if study == nil {
+		return
+	}
+
+	templates, err := h.repo.ReminderTemplates.List(study.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error listing reminder templates"})
+		return
+	}
+	defaults := make(map[string]models.ReminderTemplate, len(services.ReminderChannels))
+	for _, channel := range services.ReminderChannels {
+		defaults[channel] = services.DefaultReminderTemplate(channel)
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"templates": templates,
+		"defaults":  defaults,
+		"variables": services.ReminderVariables,
+	})
+}
+
+// SaveTemplate sets a study's reminder copy for a channel
+func (h *ReminderTemplateHandler) SaveTemplate(c *gin.Context) {
+	study := scopedStudy(c, h.repo)
+	if study == nil {
+		return
+	}
+	channel, ok := reminderChannel(c)
+	if !ok {
+		return
+	}
+	req := c.MustGet("validatedRequest").(*validation.ReminderTemplateRequest)
+
+	for _, text := range []string{req.Subject, req.Body} {
+		if err := services.ValidateReminderTemplate(text); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	tmpl := &models.ReminderTemplate{
+		StudyID:   study.ID,
+		Channel:   channel,
+		Subject:   strings.TrimSpace(req.Subject),
+		Body:      strings.TrimSpace(req.Body),
+		UpdatedBy: c.GetString("userEmail"),
+	}
+	if err := h.repo.ReminderTemplates.Save(tmpl); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error saving reminder template"})
+		return
+	}
+
+	h.log.Infow("Saved reminder template", "study_id", study.ID, "channel", channel, "admin", tmpl.UpdatedBy)
+	c.JSON(http.StatusOK, tmpl)
+}
+
+// DeleteTemplate returns a study's reminders on a channel to the default copy
+func (h *ReminderTemplateHandler) DeleteTemplate(c *gin.Context) {
+	study := scopedStudy(c, h.repo)
+	if study == nil {
+		return
+	}
+	channel, ok := reminderChannel(c)
+	if !ok {
+		return
+	}
+
+	deleted, err := h.repo.ReminderTemplates.Delete(study.ID, channel)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error deleting reminder template"})
+		return
+	}
+	if !deleted {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Study has no template for this channel"})
+		return
+	}
+
+	h.log.Infow("Deleted reminder template", "study_id", study.ID, "channel", channel, "admin", c.GetString("userEmail"))
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+// reminderChannel reads the channel named in the path, writing an error
+// response if it isn't one reminders are sent through
+func reminderChannel(c *gin.Context) (string, bool) {
+	channel := c.Param("channel")
+	if !slices.Contains(services.ReminderChannels, channel) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Channel must be email, push, or in_app"})
+		return "", false
+	}
+	return channel, true
+}
@@ -0,0 +1,251 @@
+// internal/handlers/replay.go
+package handlers
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/andevellicus/crapp/internal/metrics"
+	"github.com/andevellicus/crapp/internal/models"
+	"github.com/andevellicus/crapp/internal/repository"
+	"github.com/andevellicus/crapp/internal/utils"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// replayTolerance is how far a replayed value may differ from the stored one,
+// relative to its size, before it is reported as a mismatch
+const replayTolerance = 1e-9
+
+// replaySection is one calculator's replay: the raw data, the values stored at
+// submission, the values calculated again, and the steps in between
+type replaySection struct {
+	Raw        json.RawMessage  `json:"raw,omitempty"`
+	Stored     any              `json:"stored,omitempty"`
+	Replayed   any              `json:"replayed,omitempty"`
+	Trace      *metrics.Trace   `json:"trace,omitempty"`
+	Mismatches []replayMismatch `json:"mismatches"`
+	Error      string           `json:"error,omitempty"` // Why the data could not be replayed
+}
+
+// replayMismatch is a value the replay calculated differently. A nil side
+// means that side has no value.
+type replayMismatch struct {
+	QuestionID string   `json:"question_id,omitempty"`
+	MetricKey  string   `json:"metric_key"`
+	Stored     *float64 `json:"stored"`
+	Replayed   *float64 `json:"replayed"`
+}
+
+// ReplayHandler recalculates an assessment's metrics from its raw data so
+// suspect scores can be checked
+type ReplayHandler struct {
+	repo *repository.Repository
+	log  *zap.SugaredLogger
+}
+
+// NewReplayHandler creates a new replay handler
+func NewReplayHandler(repo *repository.Repository, log *zap.SugaredLogger) *ReplayHandler {
+	return &ReplayHandler{
+		repo: repo,
+		log:  log.Named("replay"),
+	}
+}
+
+// ReplayAssessment returns an assessment's raw interaction and cognitive test
+// data next to its stored metrics, the metrics calculated again, and the
+// intermediate values of that calculation. The participant is named with
+// ?email= so the assessment is found in their organization's data.
+func (h *ReplayHandler) ReplayAssessment(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("assessmentId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid assessment ID"})
+		return
+	}
+	email := strings.ToLower(c.Query("email"))
+	if email == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "email is required"})
+		return
+	}
+
+	tenant := h.repo.ForUser(email)
+	assessment, err := tenant.Assessments.GetByID(uint(id))
+	if err != nil || !strings.EqualFold(assessment.UserEmail, email) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Assessment not found"})
+		return
+	}
+
+	raw, err := tenant.Assessments.GetRawData(assessment.ID)
+	if err != nil {
+		h.log.Errorw("Error loading raw assessment data", "error", err, "assessment_id", assessment.ID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error loading assessment data"})
+		return
+	}
+
+	// Raw keyboard events can reveal what was typed
+	recordAudit(h.repo, h.log, c, c.GetString("userEmail"), models.AuditAssessmentReplayed, "",
+		map[string]any{"participant": email, "assessment_id": assessment.ID})
+
+	response := gin.H{
+		"assessment_id": assessment.ID,
+		"participant":   assessment.UserEmail,
+		"submitted_at":  assessment.SubmittedAt,
+		"interactions":  h.replayInteractions(raw),
+	}
+	if raw.CPT != nil {
+		response["cpt"] = replayCPT(raw.CPT)
+	}
+	if raw.TMT != nil {
+		response["tmt"] = replayTMT(raw.TMT)
+	}
+	if raw.DigitSpan != nil {
+		response["digit_span"] = replayDigitSpan(raw.DigitSpan)
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// replayInteractions recalculates the mouse and keyboard metrics. Stored
+// metrics from other sources, such as question durations and custom
+// metrics, are left out of the comparison.
+func (h *ReplayHandler) replayInteractions(raw *repository.AssessmentRawData) *replaySection {
+	section := &replaySection{Mismatches: []replayMismatch{}}
+
+	stored := map[[2]string]float64{}
+	storedList := []models.AssessmentMetric{}
+	for _, m := range raw.Metrics {
+		if def, ok := metrics.Lookup(m.MetricKey); ok && (def.Group == metrics.GroupMouse || def.Group == metrics.GroupKeyboard) {
+			stored[[2]string{m.QuestionID, m.MetricKey}] = m.MetricValue
+			storedList = append(storedList, m)
+		}
+	}
+	section.Stored = storedList
+
+	if len(raw.InteractionData) == 0 {
+		section.Error = "No interaction data was kept for this assessment"
+		return section
+	}
+	data, err := utils.DecompressData(raw.InteractionData)
+	if err != nil {
+		// Stored uncompressed when compression failed
+		data = raw.InteractionData
+	}
+	section.Raw = data
+
+	var interactions metrics.InteractionData
+	if err := json.Unmarshal(data, &interactions); err != nil {
+		h.log.Warnw("Error parsing interaction data for replay", "error", err)
+		section.Error = "Interaction data could not be parsed"
+		return section
+	}
+	calculated, trace := metrics.ReplayInteractionMetrics(&interactions)
+	section.Trace = trace
+
+	replayed := append(calculated.GlobalMetrics, calculated.QuestionMetrics...)
+	sort.Slice(replayed, func(i, j int) bool {
+		if replayed[i].QuestionID != replayed[j].QuestionID {
+			return replayed[i].QuestionID < replayed[j].QuestionID
+		}
+		return replayed[i].MetricKey < replayed[j].MetricKey
+	})
+	section.Replayed = replayed
+
+	seen := map[[2]string]bool{}
+	for _, m := range replayed {
+		key := [2]string{m.QuestionID, m.MetricKey}
+		seen[key] = true
+		value := m.MetricValue
+		if storedValue, ok := stored[key]; !ok {
+			section.Mismatches = append(section.Mismatches, replayMismatch{m.QuestionID, m.MetricKey, nil, &value})
+		} else if !replayMatches(storedValue, value) {
+			section.Mismatches = append(section.Mismatches, replayMismatch{m.QuestionID, m.MetricKey, &storedValue, &value})
+		}
+	}
+	for _, m := range storedList {
+		if !seen[[2]string{m.QuestionID, m.MetricKey}] {
+			value := m.MetricValue
+			section.Mismatches = append(section.Mismatches, replayMismatch{m.QuestionID, m.MetricKey, &value, nil})
+		}
+	}
+	return section
+}
+
+// replayCPT recalculates CPT scores from the stored raw data
+func replayCPT(stored *models.CPTResult) *replaySection {
+	section := &replaySection{Raw: stored.RawData, Mismatches: []replayMismatch{}}
+	storedScores := metrics.CPTScores(stored)
+	section.Stored = storedScores
+
+	var data metrics.CPTData
+	if err := json.Unmarshal(stored.RawData, &data); err != nil {
+		section.Error = "CPT data could not be parsed"
+		return section
+	}
+	result, trace := metrics.ReplayCPTMetrics(&data)
+	section.Trace = trace
+	section.Replayed, section.Mismatches = compareScores(storedScores, metrics.CPTScores(result))
+	return section
+}
+
+// replayTMT recalculates Trail Making Test scores from the stored raw data
+func replayTMT(stored *models.TMTResult) *replaySection {
+	section := &replaySection{Raw: stored.RawData, Mismatches: []replayMismatch{}}
+	storedScores := metrics.TrailScores(stored)
+	section.Stored = storedScores
+
+	var data metrics.TrailMakingData
+	if err := json.Unmarshal(stored.RawData, &data); err != nil {
+		section.Error = "Trail Making Test data could not be parsed"
+		return section
+	}
+	result, trace := metrics.ReplayTrailMetrics(&data)
+	section.Trace = trace
+	section.Replayed, section.Mismatches = compareScores(storedScores, metrics.TrailScores(result))
+	return section
+}
+
+// replayDigitSpan recalculates digit span scores from the stored raw data
+func replayDigitSpan(stored *models.DigitSpanResult) *replaySection {
+	section := &replaySection{Raw: stored.RawData, Mismatches: []replayMismatch{}}
+	storedScores := metrics.DigitSpanScores(stored)
+	section.Stored = storedScores
+
+	var data metrics.DigitSpanRawData
+	if err := json.Unmarshal(stored.RawData, &data); err != nil {
+		section.Error = "Digit span data could not be parsed"
+		return section
+	}
+	result, trace, err := metrics.ReplayDigitSpanMetrics(&data)
+	section.Trace = trace
+	if err != nil {
+		section.Error = err.Error()
+		return section
+	}
+	section.Replayed, section.Mismatches = compareScores(storedScores, metrics.DigitSpanScores(result))
+	return section
+}
+
+// compareScores lists the replayed scores that differ from the stored ones
+func compareScores(stored, replayed map[string]float64) (map[string]float64, []replayMismatch) {
+	mismatches := []replayMismatch{}
+	keys := make([]string, 0, len(replayed))
+	for key := range replayed {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		storedValue, replayedValue := stored[key], replayed[key]
+		if !replayMatches(storedValue, replayedValue) {
+			mismatches = append(mismatches, replayMismatch{MetricKey: key, Stored: &storedValue, Replayed: &replayedValue})
+		}
+	}
+	return replayed, mismatches
+}
+
+// replayMatches reports whether two values agree within replayTolerance
+func replayMatches(stored, replayed float64) bool {
+	return math.Abs(stored-replayed) <= replayTolerance*math.Max(1, math.Max(math.Abs(stored), math.Abs(replayed)))
+}
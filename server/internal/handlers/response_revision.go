@@ -0,0 +1,199 @@
+// internal/handlers/response_revision.go
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/andevellicus/crapp/internal/models"
+	"github.com/andevellicus/crapp/internal/validation"
+	"github.com/gin-gonic/gin"
+)
+
+// amendableResponse pairs an editable assessment with its answers
+type amendableResponse struct {
+	models.Assessment
+	Responses []models.QuestionResponse `json:"responses"`
+}
+
+// AssessmentOwner is the middleware.OwnerFunc for routes on a submitted
+// assessment. Assessments are looked up in the requesting user's organization.
+func (h *FormHandler) AssessmentOwner(c *gin.Context) (string, error) {
+	id, err := strconv.ParseUint(c.Param("assessmentId"), 10, 64)
+	if err != nil {
+		return "", err
+	}
+	assessment, err := h.repo.ForUser(c.GetString("userEmail")).Assessments.GetByID(uint(id))
+	if err != nil {
+		return "", err
+	}
+	c.Set("assessment", assessment)
+	return assessment.UserEmail, nil
+}
+
+// GetAmendableAnswers returns the user's assessments submitted during the
+// current assessment day, whose answers can still be amended
+func (h *FormHandler) GetAmendableAnswers(c *gin.Context) {
+	userEmail := c.GetString("userEmail")
+	repo := h.repo.ForUser(userEmail)
+	days := h.repo.AssessmentDay()
+	if user, err := h.repo.Users.GetByEmail(userEmail); err == nil && user != nil {
+		days = h.repo.Users.AssessmentDayFor(user)
+	}
+	today := days.Today()
+
+	assessments, err := repo.Assessments.GetSubmittedSince(userEmail, days.Start(today))
+	if err != nil {
+		h.log.Errorw("Error listing amendable assessments", "error", err, "user", userEmail)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	result := make([]amendableResponse, 0, len(assessments))
+	for _, assessment := range assessments {
+		responses, err := repo.QuestionResponses.GetByAssessment(assessment.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+			return
+		}
+		result = append(result, amendableResponse{Assessment: assessment, Responses: responses})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"assessments":    result,
+		"editable_until": days.Start(days.AddDays(today, 1)),
+	})
+}
+
+// AmendAnswer changes an answer of an assessment submitted during the current
+// assessment day. The previous value is kept in the revision history.
+func (h *FormHandler) AmendAnswer(c *gin.Context) {
+	req := c.MustGet("validatedRequest").(*validation.AmendAnswerRequest)
+	assessment := c.MustGet("assessment").(*models.Assessment)
+	userEmail := c.GetString("userEmail")
+	questionID := c.Param("questionId")
+
+	if h.rejectWithdrawn(c, assessment.UserEmail) {
+		return
+	}
+
+	days := h.repo.AssessmentDay()
+	if user, err := h.repo.Users.GetByEmail(assessment.UserEmail); err == nil && user != nil {
+		days = h.repo.Users.AssessmentDayFor(user)
+	}
+	if !days.Of(assessment.SubmittedAt).Equal(days.Today()) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Answers can only be changed on the day they were submitted"})
+		return
+	}
+
+	questions := questionsForUser(h.repo, h.questions, h.log, assessment.UserEmail)
+	question := questions.GetQuestionByID(questionID)
+	if question == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Question not found"})
+		return
+	}
+	switch question.Type {
+	case "cpt", "tmt", "digit_span":
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cognitive test results can't be changed"})
+		return
+	}
+
+	answer := req.Answer
+	if text, ok := answer.(string); ok {
+		answer = h.sanitizer.Answer(text)
+	}
+	if errs := validation.NewFormValidator(questions).ValidateAnswer(questionID, answer); len(errs) > 0 {
+		c.JSON(http.StatusBadRequest, validation.NewValidationResponse(false, "Validation failed", errs))
+		return
+	}
+
+	repo := h.repo.ForUser(assessment.UserEmail)
+	responses, err := repo.QuestionResponses.GetByAssessment(assessment.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	var response *models.QuestionResponse
+	for i := range responses {
+		if responses[i].QuestionID == questionID {
+			response = &responses[i]
+			break
+		}
+	}
+	if response == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No answer to change for this question"})
+		return
+	}
+
+	updated := models.QuestionResponse{}
+	if !h.setResponseValue(&updated, *question, answer) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid answer"})
+		return
+	}
+	if sameResponse(*response, updated) {
+		c.JSON(http.StatusOK, response)
+		return
+	}
+
+	if _, err := repo.QuestionResponses.Amend(response, updated, userEmail); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error saving answer"})
+		return
+	}
+
+	// Charts read the summary table, so rebuild this assessment's rows
+	if err := repo.ChartSummaries.Refresh(assessment.ID); err != nil {
+		h.log.Warnw("Chart summary refresh after amendment failed", "error", err, "assessment_id", assessment.ID)
+	}
+
+	h.log.Infow("Answer amended", "assessment_id", assessment.ID, "question_id", questionID, "revision", response.Revision)
+	c.JSON(http.StatusOK, response)
+}
+
+// SearchResponseRevisions lists a participant's amended answers with their
+// previous values, newest first
+func (h *AdminHandler) SearchResponseRevisions(c *gin.Context) {
+	email := strings.ToLower(c.Query("email"))
+	if email == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "email is required"})
+		return
+	}
+
+	skip := 0
+	limit := 50
+
+	if skipParam := c.Query("skip"); skipParam != "" {
+		if val, err := strconv.Atoi(skipParam); err == nil && val >= 0 {
+			skip = val
+		}
+	}
+
+	if limitParam := c.Query("limit"); limitParam != "" {
+		if val, err := strconv.Atoi(limitParam); err == nil && val > 0 && val <= 200 {
+			limit = val
+		}
+	}
+
+	var assessmentID uint64
+	if param := c.Query("assessment_id"); param != "" {
+		var err error
+		if assessmentID, err = strconv.ParseUint(param, 10, 64); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid assessment ID"})
+			return
+		}
+	}
+
+	revisions, total, err := h.repo.ForUser(email).QuestionResponses.SearchRevisions(email, uint(assessmentID), skip, limit)
+	if err != nil {
+		h.log.Errorw("Error searching response revisions", "error", err, "email", email)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error searching revisions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"revisions": revisions,
+		"total":     total,
+		"skip":      skip,
+		"limit":     limit,
+	})
+}
@@ -0,0 +1,243 @@
+// internal/handlers/review.go
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/andevellicus/crapp/internal/repository"
+	"github.com/andevellicus/crapp/internal/storage"
+	"github.com/andevellicus/crapp/internal/utils"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// ReviewHandler serves read-only trial dashboards to admins and blinded reviewers
+type ReviewHandler struct {
+	repo      *repository.Repository
+	questions *utils.QuestionRegistry
+	store     storage.Storage
+	linkTTL   time.Duration
+	log       *zap.SugaredLogger
+}
+
+// NewReviewHandler creates a new review handler. Analysis exports can be
+// saved to the store and downloaded through a signed link.
+func NewReviewHandler(repo *repository.Repository, log *zap.SugaredLogger, questions *utils.QuestionRegistry, store storage.Storage, linkTTL time.Duration) *ReviewHandler {
+	return &ReviewHandler{
+		repo:      repo,
+		questions: questions,
+		store:     store,
+		linkTTL:   linkTTL,
+		log:       log.Named("review"),
+	}
+}
+
+// GetAdherence reports how regularly each participant completed assessments
+func (h *ReviewHandler) GetAdherence(c *gin.Context) {
+	scope := orgScope(c)
+	days, since := reviewWindow(c, h.repo.AssessmentDay())
+
+	participants, err := h.repo.ForOrganization(scope).Assessments.GetAdherence(scope, since)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error retrieving adherence"})
+		return
+	}
+
+	for i := range participants {
+		participants[i].Rate = float64(participants[i].DaysCompleted) / float64(days)
+	}
+	if isBlinded(c) {
+		redactAdherenceArms(participants)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"days":         days,
+		"participants": participants,
+	})
+}
+
+// GetDataQuality reports the completeness of each participant's data
+func (h *ReviewHandler) GetDataQuality(c *gin.Context) {
+	scope := orgScope(c)
+	days, since := reviewWindow(c, h.repo.AssessmentDay())
+
+	participants, err := h.repo.ForOrganization(scope).Assessments.GetDataQuality(scope, since)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error retrieving data quality"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"days":         days,
+		"participants": participants,
+	})
+}
+
+// ExportResponses exports question responses and clinical events, masked for
+// blinded reviewers. The long and wide formats instead download a zip of
+// analysis-ready CSV files with a data dictionary, and the parquet format a
+// zip of typed Parquet tables with their schema. With deliver=link these are
+// saved to storage and a signed download URL is returned instead.
+func (h *ReviewHandler) ExportResponses(c *gin.Context) {
+	scope := orgScope(c)
+	days, since := reviewWindow(c, h.repo.AssessmentDay())
+
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != exportFormatLong && format != exportFormatWide && format != exportFormatParquet {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be json, long, wide, or parquet"})
+		return
+	}
+
+	responses, err := h.repo.ForOrganization(scope).Assessments.GetResponsesForReview(scope, since, time.Time{})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error exporting responses"})
+		return
+	}
+
+	if format != "json" {
+		h.exportForAnalysis(c, scope, since, format, responses)
+		return
+	}
+
+	events, err := h.repo.ClinicalEvents.ListSince(scope, since)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error exporting clinical events"})
+		return
+	}
+
+	blinded := isBlinded(c)
+	if blinded {
+		redactResponses(responses)
+		redactClinicalEvents(events)
+	}
+
+	h.log.Infow("Exported responses for review", "reviewer", c.GetString("userEmail"), "org", scope, "rows", len(responses), "blinded", blinded)
+	c.JSON(http.StatusOK, gin.H{
+		"days":            days,
+		"blinded":         blinded,
+		"responses":       responses,
+		"clinical_events": events,
+	})
+}
+
+// exportForAnalysis sends responses and metrics as a zip of CSV files in the
+// long or wide layout, or of Parquet tables
+func (h *ReviewHandler) exportForAnalysis(c *gin.Context, scope string, since time.Time, format string, responses []repository.ReviewResponse) {
+	metrics, err := h.repo.ForOrganization(scope).Assessments.GetMetricsForReview(scope, since, time.Time{})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error exporting metrics"})
+		return
+	}
+
+	blinded := isBlinded(c)
+	if blinded {
+		redactResponses(responses)
+		redactMetricArms(metrics)
+	}
+
+	export := &analysisExport{
+		responses: responses,
+		metrics:   metrics,
+		questions: questionsForOrganization(h.repo, h.questions, h.log, scope).GetQuestions(),
+		filters:   exportFilters(scope, since, time.Now(), blinded),
+	}
+	// Attachments are never shown to blinded reviewers
+	if c.Query("include_attachments") == "true" && !blinded {
+		if h.store == nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Attachment storage is not configured"})
+			return
+		}
+		export.attachments, err = h.repo.ForOrganization(scope).Attachments.ListForExport(scope, since, time.Time{})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error exporting attachments"})
+			return
+		}
+		export.files = h.store
+	}
+
+	filename := analysisExportFilename(format)
+	if c.Query("deliver") == "link" {
+		h.storeExport(c, scope, filename, func(w io.Writer) error { return export.writeFormat(w, format) })
+	} else {
+		c.Header("Content-Type", "application/zip")
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+		if err := export.writeFormat(c.Writer, format); err != nil {
+			h.log.Errorw("Error writing analysis export", "error", err, "format", format)
+			return
+		}
+	}
+
+	h.log.Infow("Exported responses for analysis", "reviewer", c.GetString("userEmail"), "org", scope,
+		"format", format, "responses", len(responses), "metrics", len(metrics), "attachments", len(export.attachments), "blinded", blinded)
+}
+
+// storeExport saves an export under exports/<organization>/ and responds with
+// a signed link to it
+func (h *ReviewHandler) storeExport(c *gin.Context, scope, filename string, write func(io.Writer) error) {
+	if h.store == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Export storage is not configured"})
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := write(&buf); err != nil {
+		h.log.Errorw("Error writing analysis export", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creating export"})
+		return
+	}
+
+	key := exportKey(scope, filename)
+	if err := h.store.Put(c.Request.Context(), key, &buf, "application/zip"); err != nil {
+		h.log.Errorw("Error storing analysis export", "error", err, "key", key)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error storing export"})
+		return
+	}
+
+	url, err := h.store.SignedURL(key, h.linkTTL)
+	if err != nil {
+		h.log.Errorw("Error signing export link", "error", err, "key", key)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creating download link"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"url":        url,
+		"filename":   filename,
+		"expires_at": time.Now().Add(h.linkTTL),
+	})
+}
+
+// exportFilters describes an export's scope for its manifest
+func exportFilters(scope string, since, until time.Time, blinded bool) map[string]any {
+	return map[string]any{
+		"organization_id": scope,
+		"submitted_from":  since.UTC(),
+		"submitted_to":    until.UTC(),
+		"blinded":         blinded,
+	}
+}
+
+// exportKey is where an organization's export is stored
+func exportKey(scope, filename string) string {
+	if scope == "" {
+		scope = "all"
+	}
+	return fmt.Sprintf("exports/%s/%d-%s", scope, time.Now().Unix(), filename)
+}
+
+// reviewWindow reads the days query parameter, defaulting to 30, and returns
+// the start of the earliest assessment day in the window
+func reviewWindow(c *gin.Context, day utils.AssessmentDay) (int, time.Time) {
+	days := 30
+	if daysParam := c.Query("days"); daysParam != "" {
+		if val, err := strconv.Atoi(daysParam); err == nil && val > 0 && val <= 365 {
+			days = val
+		}
+	}
+	return days, day.Start(day.AddDays(day.Today(), 1-days))
+}
@@ -0,0 +1,108 @@
+// internal/handlers/service_worker.go
+package handlers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/fs"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/andevellicus/crapp/internal/buildinfo"
+	"github.com/andevellicus/crapp/internal/config"
+	"github.com/andevellicus/crapp/internal/utils"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// ServiceWorkerHandler renders the service worker with the current build's
+// precache list and a version stamp, so clients drop old caches on deploy
+type ServiceWorkerHandler struct {
+	log      *zap.SugaredLogger
+	config   *config.ServiceWorkerConfig
+	assets   *utils.AssetVersions
+	template *template.Template
+	distDir  string
+}
+
+// NewServiceWorkerHandler creates a new service worker handler
+func NewServiceWorkerHandler(log *zap.SugaredLogger, cfg *config.ServiceWorkerConfig, assets *utils.AssetVersions) (*ServiceWorkerHandler, error) {
+	tmpl, err := template.ParseFiles(filepath.Join("client", "public", "templates", "service-worker.js"))
+	if err != nil {
+		return nil, err
+	}
+	return &ServiceWorkerHandler{
+		log:      log.Named("service-worker"),
+		config:   cfg,
+		assets:   assets,
+		template: tmpl,
+		distDir:  filepath.Join("client", "dist"),
+	}, nil
+}
+
+// ServeServiceWorker renders the service worker script
+func (h *ServiceWorkerHandler) ServeServiceWorker(c *gin.Context) {
+	precache := h.precacheList()
+	version := buildinfo.Get().ShortCommit()
+	sum := sha256.Sum256([]byte(strings.Join(precache, "\n")))
+	version += "-" + hex.EncodeToString(sum[:4])
+
+	precacheJSON, _ := json.Marshal(precache)
+	strategyJSON, _ := json.Marshal(h.config.APIStrategy)
+	pathsJSON, _ := json.Marshal(h.config.APICachePaths)
+
+	var body bytes.Buffer
+	err := h.template.Execute(&body, map[string]string{
+		"Version":       version,
+		"Precache":      string(precacheJSON),
+		"APIStrategy":   string(strategyJSON),
+		"APICachePaths": string(pathsJSON),
+	})
+	if err != nil {
+		h.log.Errorw("Error rendering service worker", "error", err)
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+
+	// Browsers check for a new worker on every navigation; the ETag lets
+	// unchanged workers be answered with 304
+	if notModified(c, weakETag("service-worker", body.String())) {
+		return
+	}
+	c.Header("Cache-Control", "no-cache")
+
+	// Allow service worker to control the whole origin
+	c.Header("Service-Worker-Allowed", "/")
+	c.Data(http.StatusOK, "application/javascript; charset=utf-8", body.Bytes())
+}
+
+// precacheList returns the app shell, configured extras, and the scripts and
+// styles of the current build, linked by content hash
+func (h *ServiceWorkerHandler) precacheList() []string {
+	precache := append([]string{"/"}, h.config.Precache...)
+
+	// Built files are served from the site root (scripts) and /css (styles)
+	filepath.WalkDir(h.distDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(h.distDir, path)
+		if err != nil {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+
+		isScript := !strings.Contains(rel, "/") && strings.HasSuffix(rel, ".js")
+		isStyle := strings.HasPrefix(rel, "css/") && strings.HasSuffix(rel, ".css")
+		if isScript || isStyle {
+			precache = append(precache, h.assets.URL("/"+rel))
+		}
+		return nil
+	})
+
+	return precache
+}
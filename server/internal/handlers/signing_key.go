@@ -0,0 +1,36 @@
+// internal/handlers/signing_key.go
+package handlers
+
+import (
+	"encoding/base64"
+	"net/http"
+
+	"github.com/andevellicus/crapp/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// SigningKeyHandler publishes the key that verifies download manifests
+type SigningKeyHandler struct {
+	signer *utils.BundleSigner
+}
+
+// NewSigningKeyHandler creates a new signing key handler
+func NewSigningKeyHandler(signer *utils.BundleSigner) *SigningKeyHandler {
+	return &SigningKeyHandler{signer: signer}
+}
+
+// GetPublicKey returns the manifest signing key as a JSON Web Key Set. The
+// key ID matches the key_id in each signed manifest.
+func (h *SigningKeyHandler) GetPublicKey(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=3600")
+	c.JSON(http.StatusOK, gin.H{
+		"keys": []gin.H{{
+			"kty": "OKP",
+			"crv": "Ed25519",
+			"alg": "EdDSA",
+			"use": "sig",
+			"kid": h.signer.KeyID(),
+			"x":   base64.RawURLEncoding.EncodeToString(h.signer.PublicKey()),
+		}},
+	})
+}
@@ -0,0 +1,112 @@
+// internal/handlers/status.go
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/andevellicus/crapp/internal/config"
+	"github.com/andevellicus/crapp/internal/repository"
+	"github.com/andevellicus/crapp/internal/services"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// Service states on the status page
+const (
+	statusUp       = "up"
+	statusDegraded = "degraded"
+)
+
+// statusCacheTTL limits how often public status requests reach the database
+const statusCacheTTL = 15 * time.Second
+
+// StatusHandler serves coarse service health for a public status page. It
+// reports only up or degraded per component, never errors or hostnames.
+type StatusHandler struct {
+	repo    *repository.Repository
+	log     *zap.SugaredLogger
+	email   *services.EmailService // nil when email is disabled
+	push    *services.PushService  // nil when push is not configured
+	notices []config.StatusNotice
+
+	mu        sync.Mutex
+	services  map[string]string
+	checkedAt time.Time
+}
+
+// NewStatusHandler creates a new status handler
+func NewStatusHandler(repo *repository.Repository, log *zap.SugaredLogger, email *services.EmailService, push *services.PushService, cfg *config.StatusConfig) *StatusHandler {
+	return &StatusHandler{
+		repo:    repo,
+		log:     log.Named("status"),
+		email:   email,
+		push:    push,
+		notices: cfg.Notices,
+	}
+}
+
+// GetStatus returns the state of each service and the current notices
+func (h *StatusHandler) GetStatus(c *gin.Context) {
+	now := time.Now()
+	serviceStates, checkedAt := h.check(c.Request.Context(), now)
+
+	overall := statusUp
+	for _, state := range serviceStates {
+		if state != statusUp {
+			overall = statusDegraded
+		}
+	}
+
+	notices := []config.StatusNotice{}
+	for _, notice := range h.notices {
+		if (notice.Start.IsZero() || !now.Before(notice.Start)) && (notice.End.IsZero() || now.Before(notice.End)) {
+			notices = append(notices, notice)
+		}
+	}
+
+	c.Header("Cache-Control", "public, max-age=15")
+	c.JSON(http.StatusOK, gin.H{
+		"status":     overall,
+		"services":   serviceStates,
+		"notices":    notices,
+		"checked_at": checkedAt,
+	})
+}
+
+// check returns each service's state, reusing a recent check
+func (h *StatusHandler) check(ctx context.Context, now time.Time) (map[string]string, time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.services != nil && now.Sub(h.checkedAt) < statusCacheTTL {
+		return h.services, h.checkedAt
+	}
+
+	// The API answering this request is up
+	states := map[string]string{"api": statusUp, "db": statusUp}
+
+	pingCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	if err := h.repo.Ping(pingCtx); err != nil {
+		h.log.Warnw("Status check could not reach the database", "error", err)
+		states["db"] = statusDegraded
+	}
+	if h.email != nil {
+		states["email"] = statusUp
+		if h.email.Failing() {
+			states["email"] = statusDegraded
+		}
+	}
+	if h.push != nil {
+		states["push"] = statusUp
+		if h.push.Failing() {
+			states["push"] = statusDegraded
+		}
+	}
+
+	h.services = states
+	h.checkedAt = now
+	return states, now
+}
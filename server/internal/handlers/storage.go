@@ -0,0 +1,58 @@
+// internal/handlers/storage.go
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/andevellicus/crapp/internal/storage"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// StorageHandler serves signed download links for the local storage backend.
+// Cloud backends sign their own URLs, so this is only routed for local disk.
+type StorageHandler struct {
+	store *storage.LocalStorage
+	log   *zap.SugaredLogger
+}
+
+// NewStorageHandler creates a new storage handler
+func NewStorageHandler(store *storage.LocalStorage, log *zap.SugaredLogger) *StorageHandler {
+	return &StorageHandler{
+		store: store,
+		log:   log.Named("storage"),
+	}
+}
+
+// Download streams an object after checking the link's signature and expiry
+func (h *StorageHandler) Download(c *gin.Context) {
+	key := strings.TrimPrefix(c.Param("key"), "/")
+	if err := h.store.Verify(key, c.Query("expires"), c.Query("signature")); err != nil {
+		h.log.Warnw("Rejected storage download", "key", key, "error", err, "ip", c.ClientIP())
+		c.JSON(http.StatusForbidden, gin.H{"error": "Download link is invalid or has expired"})
+		return
+	}
+
+	object, err := h.store.Get(c.Request.Context(), key)
+	if errors.Is(err, storage.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+		return
+	}
+	if err != nil {
+		h.log.Errorw("Error opening stored file", "key", key, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error downloading file"})
+		return
+	}
+	defer object.Close()
+
+	c.Header("Content-Type", "application/octet-stream")
+	c.Header("Content-Disposition", "attachment; filename=\""+path.Base(key)+"\"")
+	c.Header("Cache-Control", "private, no-store")
+	if _, err := io.Copy(c.Writer, object); err != nil {
+		h.log.Errorw("Error streaming stored file", "key", key, "error", err)
+	}
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/andevellicus/crapp/internal/models"
+	"github.com/andevellicus/crapp/internal/services"
+	"github.com/andevellicus/crapp/internal/utils"
+)
+
+// submissionFeedback assembles the completion screen for an assessment: the
+// user's streak, the assessment's composite symptom score against the days
+// before it, and a message from their study's templates. Lookups that fail
+// leave their part out rather than failing the submission.
+func (h *FormHandler) submissionFeedback(email string, day time.Time, answers []models.QuestionResponse, symptoms []utils.Question) *services.Feedback {
+	cfg := *h.feedback
+	days := h.repo.AssessmentDay()
+	firstName := ""
+	if user, err := h.repo.Users.GetByEmail(email); err == nil && user != nil {
+		days = h.repo.Users.AssessmentDayFor(user)
+		firstName = user.FirstName
+		cfg = h.feedback.ForStudy(user.StudyID)
+	}
+
+	streak, err := h.repo.ForUser(email).Assessments.StreakDays(email, days.Today())
+	if err != nil {
+		h.log.Warnw("Error counting assessment streak", "error", err, "user", email)
+	}
+
+	// The composite is the mean of the symptom ratings just submitted
+	isSymptom := make(map[string]bool, len(symptoms))
+	questionIDs := make([]string, 0, len(symptoms))
+	for _, q := range symptoms {
+		isSymptom[q.ID] = true
+		questionIDs = append(questionIDs, q.ID)
+	}
+	var ratings []float64
+	for _, answer := range answers {
+		if isSymptom[answer.QuestionID] && answer.ValueType == "number" {
+			ratings = append(ratings, answer.NumericValue)
+		}
+	}
+
+	// Its baseline is the mean of each earlier day's composite, which counts
+	// a caregiver's answers as today's does
+	var baseline []float64
+	if len(ratings) > 0 && cfg.BaselineDays > 0 {
+		since := days.AddDays(day, -cfg.BaselineDays)
+		values, err := h.repo.ForUser(email).Assessments.GetDailySeries(email, since, nil, questionIDs, true)
+		if err != nil {
+			h.log.Warnw("Error loading feedback baseline", "error", err, "user", email)
+		}
+		dayKey := day.Format("2006-01-02")
+		sums := make(map[string]float64)
+		counts := make(map[string]int)
+		for _, v := range values {
+			if key := v.Date.Format("2006-01-02"); key < dayKey {
+				sums[key] += v.Value
+				counts[key]++
+			}
+		}
+		for key, sum := range sums {
+			baseline = append(baseline, sum/float64(counts[key]))
+		}
+	}
+
+	composite := services.CompareComposite(ratings, baseline, &cfg)
+	return services.NewFeedback(streak, composite, firstName, &cfg)
+}
@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"encoding/json"
+
+	"github.com/andevellicus/crapp/internal/utils"
+)
+
+// servedSettings are the settings a cognitive test was served with, recorded
+// with its results
+type servedSettings struct {
+	JSON json.RawMessage
+	Hash string
+}
+
+// servedQuestion returns a question as served to a user. Cognitive tests take
+// the settings of the user's study, are adjusted for their accessibility
+// preferences, and carry the hash of the resulting settings.
+func (h *FormHandler) servedQuestion(email string, question utils.Question) utils.Question {
+	if !utils.IsCognitiveTest(question.Type) {
+		return question
+	}
+	user, err := h.repo.Users.GetByEmail(email)
+	if err != nil || user == nil {
+		h.log.Warnw("Failed to load user for test settings, serving the questionnaire's", "error", err)
+	} else {
+		question = question.ForStudy(user.StudyID)
+		question = cognitiveAdjustment(h.accessibility, user.Accessibility).Apply(question)
+	}
+
+	hash, err := utils.SettingsHash(utils.TestSettings(question))
+	if err != nil {
+		h.log.Warnw("Failed to hash cognitive test settings", "error", err, "question_id", question.ID)
+	}
+	question.SettingsHash = hash
+	return question
+}
+
+// testSettings returns the settings the user's cognitive test of a type was
+// served with
+func (h *FormHandler) testSettings(email, testType string) servedSettings {
+	for _, question := range questionsForUser(h.repo, h.questions, h.log, email).GetQuestions() {
+		if question.Type != testType {
+			continue
+		}
+		question = h.servedQuestion(email, question)
+		encoded, err := json.Marshal(utils.TestSettings(question))
+		if err != nil {
+			h.log.Warnw("Failed to encode cognitive test settings", "error", err, "type", testType)
+			return servedSettings{}
+		}
+		return servedSettings{JSON: encoded, Hash: question.SettingsHash}
+	}
+	return servedSettings{}
+}
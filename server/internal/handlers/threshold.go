@@ -0,0 +1,245 @@
+// internal/handlers/threshold.go
+package handlers
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/andevellicus/crapp/internal/models"
+	"github.com/andevellicus/crapp/internal/repository"
+	"github.com/andevellicus/crapp/internal/utils"
+	"github.com/andevellicus/crapp/internal/validation"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// nonNumericQuestionTypes have answers a threshold can't be compared against
+var nonNumericQuestionTypes = []string{"text", "cpt", "tmt", "digit_span"}
+
+// ThresholdHandler manages symptom thresholds and the flags they raise
+type ThresholdHandler struct {
+	repo      *repository.Repository
+	log       *zap.SugaredLogger
+	questions *utils.QuestionRegistry
+}
+
+// NewThresholdHandler creates a new threshold handler
+func NewThresholdHandler(repo *repository.Repository, log *zap.SugaredLogger, questions *utils.QuestionRegistry) *ThresholdHandler {
+	return &ThresholdHandler{
+		repo:      repo,
+		log:       log.Named("threshold"),
+		questions: questions,
+	}
+}
+
+// ListThresholds returns the thresholds that apply to the admin's organization
+func (h *ThresholdHandler) ListThresholds(c *gin.Context) {
+	thresholds, err := h.repo.Thresholds.List(orgScope(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error retrieving thresholds"})
+		return
+	}
+	c.JSON(http.StatusOK, thresholds)
+}
+
+// CreateThreshold defines a new symptom threshold
+func (h *ThresholdHandler) CreateThreshold(c *gin.Context) {
+	req := c.MustGet("validatedRequest").(*validation.SymptomThresholdRequest)
+
+	threshold := &models.SymptomThreshold{CreatedBy: c.GetString("userEmail")}
+	if !h.apply(c, threshold, req) {
+		return
+	}
+	if err := h.repo.Thresholds.Create(threshold); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creating threshold"})
+		return
+	}
+
+	h.log.Infow("Symptom threshold created", "id", threshold.ID, "question_id", threshold.QuestionID, "admin", threshold.CreatedBy)
+	c.JSON(http.StatusCreated, threshold)
+}
+
+// UpdateThreshold changes a symptom threshold. Existing flags are kept.
+func (h *ThresholdHandler) UpdateThreshold(c *gin.Context) {
+	req := c.MustGet("validatedRequest").(*validation.SymptomThresholdRequest)
+
+	threshold, ok := h.lookup(c)
+	if !ok {
+		return
+	}
+	if !h.apply(c, threshold, req) {
+		return
+	}
+	if err := h.repo.Thresholds.Update(threshold); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error updating threshold"})
+		return
+	}
+
+	h.log.Infow("Symptom threshold updated", "id", threshold.ID, "admin", c.GetString("userEmail"))
+	c.JSON(http.StatusOK, threshold)
+}
+
+// DeleteThreshold removes a symptom threshold and its flags
+func (h *ThresholdHandler) DeleteThreshold(c *gin.Context) {
+	threshold, ok := h.lookup(c)
+	if !ok {
+		return
+	}
+	if err := h.repo.Thresholds.Delete(threshold); err != nil {
+		h.log.Errorw("Error deleting threshold", "error", err, "id", threshold.ID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error deleting threshold"})
+		return
+	}
+
+	h.log.Infow("Symptom threshold deleted", "id", threshold.ID, "admin", c.GetString("userEmail"))
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+// SearchFlags lists flagged participants, optionally filtered by status or participant
+func (h *ThresholdHandler) SearchFlags(c *gin.Context) {
+	skip := 0
+	limit := 50
+
+	if skipParam := c.Query("skip"); skipParam != "" {
+		if val, err := strconv.Atoi(skipParam); err == nil && val >= 0 {
+			skip = val
+		}
+	}
+
+	if limitParam := c.Query("limit"); limitParam != "" {
+		if val, err := strconv.Atoi(limitParam); err == nil && val > 0 && val <= 200 {
+			limit = val
+		}
+	}
+
+	flags, total, err := h.repo.Thresholds.SearchFlags(orgScope(c), c.Query("status"), c.Query("email"), skip, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error searching flags"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"flags": flags,
+		"total": total,
+		"skip":  skip,
+		"limit": limit,
+	})
+}
+
+// AcknowledgeFlag marks a flag as reviewed
+func (h *ThresholdHandler) AcknowledgeFlag(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid flag ID"})
+		return
+	}
+
+	flag, err := h.repo.Thresholds.Acknowledge(orgScope(c), uint(id), c.GetString("userEmail"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Flag not found"})
+		return
+	}
+
+	h.log.Infow("Symptom flag acknowledged", "id", flag.ID, "admin", flag.AcknowledgedBy)
+	c.JSON(http.StatusOK, flag)
+}
+
+// SearchRedFlagAlerts lists alerts raised by red-flag answers, optionally
+// filtered by status or participant
+func (h *ThresholdHandler) SearchRedFlagAlerts(c *gin.Context) {
+	skip := 0
+	limit := 50
+
+	if skipParam := c.Query("skip"); skipParam != "" {
+		if val, err := strconv.Atoi(skipParam); err == nil && val >= 0 {
+			skip = val
+		}
+	}
+
+	if limitParam := c.Query("limit"); limitParam != "" {
+		if val, err := strconv.Atoi(limitParam); err == nil && val > 0 && val <= 200 {
+			limit = val
+		}
+	}
+
+	alerts, total, err := h.repo.Thresholds.SearchRedFlagAlerts(orgScope(c), c.Query("status"), c.Query("email"), skip, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error searching alerts"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"alerts": alerts,
+		"total":  total,
+		"skip":   skip,
+		"limit":  limit,
+	})
+}
+
+// AcknowledgeRedFlagAlert marks a red-flag alert as handled
+func (h *ThresholdHandler) AcknowledgeRedFlagAlert(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid alert ID"})
+		return
+	}
+
+	alert, err := h.repo.Thresholds.AcknowledgeRedFlagAlert(orgScope(c), uint(id), c.GetString("userEmail"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Alert not found"})
+		return
+	}
+
+	h.log.Infow("Red-flag alert acknowledged", "id", alert.ID, "admin", alert.AcknowledgedBy)
+	c.JSON(http.StatusOK, alert)
+}
+
+// lookup loads the threshold named in the path. Organization admins can only
+// change their own organization's thresholds.
+func (h *ThresholdHandler) lookup(c *gin.Context) (*models.SymptomThreshold, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid threshold ID"})
+		return nil, false
+	}
+
+	threshold, err := h.repo.Thresholds.Get(orgScope(c), uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Threshold not found"})
+		return nil, false
+	}
+	return threshold, true
+}
+
+// apply validates a request against the organization's questions and copies
+// it onto the threshold
+func (h *ThresholdHandler) apply(c *gin.Context, threshold *models.SymptomThreshold, req *validation.SymptomThresholdRequest) bool {
+	orgID := orgScope(c)
+	if orgID == "" && req.OrganizationID != "" {
+		if _, err := h.repo.Organizations.Get(req.OrganizationID); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Organization not found"})
+			return false
+		}
+		orgID = req.OrganizationID
+	}
+
+	question := questionsForOrganization(h.repo, h.questions, h.log, orgID).GetQuestionByID(req.QuestionID)
+	if question == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Question not found"})
+		return false
+	}
+	if slices.Contains(nonNumericQuestionTypes, question.Type) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Thresholds need a question with numeric answers"})
+		return false
+	}
+
+	threshold.OrganizationID = orgID
+	threshold.Name = req.Name
+	threshold.QuestionID = req.QuestionID
+	threshold.Operator = req.Operator
+	threshold.Value = *req.Value
+	threshold.ConsecutiveDays = req.ConsecutiveDays
+	threshold.Enabled = req.Enabled == nil || *req.Enabled
+	return true
+}
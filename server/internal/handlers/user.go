@@ -1,8 +1,14 @@
 package handlers
 
 import (
+	"encoding/json"
+	"errors"
 	"net/http"
 
+	"github.com/andevellicus/crapp/internal/models"
+	"github.com/andevellicus/crapp/internal/repository"
+	"github.com/andevellicus/crapp/internal/services"
+	"github.com/andevellicus/crapp/internal/utils"
 	"github.com/andevellicus/crapp/internal/validation"
 	"github.com/gin-gonic/gin"
 	"golang.org/x/crypto/bcrypt"
@@ -85,6 +91,8 @@ func (h *AuthHandler) UpdateUser(c *gin.Context) {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error updating user"})
 			return
 		}
+
+		recordAudit(h.repo, h.log, c, user.Email, models.AuditPasswordChanged, "", nil)
 	}
 
 	// Save updated user name
@@ -106,6 +114,44 @@ func (h *AuthHandler) UpdateUser(c *gin.Context) {
 	c.JSON(http.StatusOK, user)
 }
 
+// DeactivateAccount pauses the current user's account. Reminders stop and
+// every session is logged out; the data is kept, and logging in again sends
+// a reactivation link.
+func (h *AuthHandler) DeactivateAccount(c *gin.Context) {
+	userEmail := c.GetString("userEmail")
+	user, err := h.repo.Users.GetByEmail(userEmail)
+	if err != nil || user == nil {
+		h.log.Errorw("Error retrieving user for deactivation", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error retrieving user"})
+		return
+	}
+	if user.DeactivatedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Account is already paused"})
+		return
+	}
+
+	if err := h.repo.Users.SetPaused(userEmail, true); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to pause account"})
+		return
+	}
+	if err := h.authService.RevokeAllUserTokens(userEmail); err != nil {
+		h.log.Warnw("Failed to revoke sessions of paused account", "error", err, "email", userEmail)
+	}
+	recordAudit(h.repo, h.log, c, userEmail, models.AuditAccountPaused, "", nil)
+
+	if emailService, exists := c.Get("emailService"); exists && emailService != nil {
+		if err := emailService.(*services.EmailService).SendAccountPausedEmail(user.Email, user.FirstName); err != nil {
+			h.log.Warnw("Failed to send account paused email", "error", err, "email", userEmail)
+		}
+	}
+
+	cookieConfig := h.authService.GetCookieConfig()
+	c.SetCookie("auth_token", "", -1, cookieConfig.Path, cookieConfig.Domain, cookieConfig.Secure, cookieConfig.HttpOnly)
+	c.SetCookie("refresh_token", "", -1, cookieConfig.Path, cookieConfig.Domain, cookieConfig.Secure, cookieConfig.HttpOnly)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Account paused"})
+}
+
 // DeleteAccount handles user account deletion
 func (h *AuthHandler) DeleteAccount(c *gin.Context) {
 	// Get validated request
@@ -140,7 +186,11 @@ func (h *AuthHandler) DeleteAccount(c *gin.Context) {
 	}
 
 	// Delete user account
-	err = h.repo.Users.Delete(userEmail.(string))
+	err = h.repo.ForUser(userEmail.(string)).Users.Delete(userEmail.(string))
+	if errors.Is(err, repository.ErrLegalHold) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Your data is under a legal hold and can't be deleted yet"})
+		return
+	}
 	if err != nil {
 		h.log.Errorw("Error deleting user account", "error", err, "userEmail", userEmail)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete account"})
@@ -152,3 +202,90 @@ func (h *AuthHandler) DeleteAccount(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"message": "Account deleted successfully"})
 }
+
+// ExportUserData returns a copy of the personal data held about the current user
+func (h *AuthHandler) ExportUserData(c *gin.Context) {
+	userEmail, exists := c.Get("userEmail")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+	email := userEmail.(string)
+
+	user, err := h.repo.Users.GetByEmail(email)
+	if err != nil || user == nil {
+		h.log.Errorw("Error retrieving user for export", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error retrieving user"})
+		return
+	}
+	user.Password = nil
+	user.PushSubscription = ""
+
+	devices, err := h.repo.Devices.GetUserDevices(email)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error exporting devices"})
+		return
+	}
+
+	assessments, err := h.repo.ForUser(email).Assessments.GetByUser(email)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error exporting assessments"})
+		return
+	}
+
+	attachments, err := h.repo.ForUser(email).Attachments.ListForUser(email)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error exporting attachments"})
+		return
+	}
+
+	caregiverLinks, err := h.repo.CaregiverLinks.GetForUser(email)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error exporting caregiver links"})
+		return
+	}
+
+	acceptances, err := h.repo.PolicyAcceptances.GetForUser(email)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error exporting policy acceptances"})
+		return
+	}
+
+	recordAudit(h.repo, h.log, c, email, models.AuditDataExport, "", nil)
+
+	sections := []struct {
+		name        string
+		description string
+		data        any
+	}{
+		{"user", "Account details; the password and push subscription are left out", user},
+		{"devices", "Devices used to sign in", devices},
+		{"assessments", "Submitted assessments with answers and interaction metrics", assessments},
+		{"attachments", "Files attached to assessments; each can be downloaded from its assessment", attachments},
+		{"caregiver_links", "Caregivers linked to the account, in either direction", caregiverLinks},
+		{"policy_acceptances", "Accepted versions of the terms and privacy policy", acceptances},
+	}
+
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", "attachment; filename=\"crapp-export.zip\"")
+	bundle := utils.NewBundle(c.Writer, "personal_data", map[string]any{"user": email})
+	dictionary := make(map[string]string, len(sections))
+	for _, section := range sections {
+		file := section.name + ".json"
+		w, err := bundle.Create(file)
+		if err == nil {
+			encoder := json.NewEncoder(w)
+			encoder.SetIndent("", "  ")
+			err = encoder.Encode(section.data)
+		}
+		if err != nil {
+			h.log.Errorw("Error writing personal data export", "error", err, "file", file)
+			return
+		}
+		dictionary[file] = section.description
+	}
+	bundle.SetDataDictionary(dictionary)
+	if err := bundle.Close(); err != nil {
+		h.log.Errorw("Error writing personal data export", "error", err)
+	}
+}
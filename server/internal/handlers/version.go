@@ -0,0 +1,70 @@
+// internal/handlers/version.go
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"os"
+
+	"github.com/andevellicus/crapp/internal/buildinfo"
+	"github.com/andevellicus/crapp/internal/config"
+	"github.com/andevellicus/crapp/internal/repository"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// VersionHandler reports what is deployed
+type VersionHandler struct {
+	repo              *repository.Repository
+	log               *zap.SugaredLogger
+	config            *config.Config
+	questionsChecksum string
+}
+
+// NewVersionHandler creates a new version handler. The questions file is
+// hashed once, since questions are loaded at startup.
+func NewVersionHandler(repo *repository.Repository, log *zap.SugaredLogger, cfg *config.Config) *VersionHandler {
+	h := &VersionHandler{
+		repo:   repo,
+		log:    log.Named("version"),
+		config: cfg,
+	}
+
+	if content, err := os.ReadFile(cfg.App.QuestionsFile); err != nil {
+		h.log.Warnw("Failed to read questions file for checksum", "error", err, "file", cfg.App.QuestionsFile)
+	} else {
+		sum := sha256.Sum256(content)
+		h.questionsChecksum = hex.EncodeToString(sum[:])
+	}
+
+	return h
+}
+
+// GetVersion returns the public version and short commit
+func (h *VersionHandler) GetVersion(c *gin.Context) {
+	info := buildinfo.Get()
+	c.JSON(http.StatusOK, gin.H{
+		"version": info.Version,
+		"commit":  info.ShortCommit(),
+	})
+}
+
+// GetBuildDetails returns the full build description with migration status
+// and the checksum of the questions file in use
+func (h *VersionHandler) GetBuildDetails(c *gin.Context) {
+	info := buildinfo.Get()
+	if info.SchemaVersion == "" {
+		info.SchemaVersion = h.config.SchemaVersion
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"build":       info,
+		"environment": h.config.App.Environment,
+		"migrations":  h.repo.MigrationStatus(),
+		"questions": gin.H{
+			"file":   h.config.App.QuestionsFile,
+			"sha256": h.questionsChecksum,
+		},
+	})
+}
@@ -7,18 +7,41 @@ import (
 	"os"
 	"path/filepath"
 
+	"github.com/andevellicus/crapp/internal/config"
+	"github.com/andevellicus/crapp/internal/repository"
+	"github.com/andevellicus/crapp/internal/utils"
 	"github.com/gin-gonic/gin"
 )
 
-// Create a new handler:
-func ServeReactApp(c *gin.Context) {
+// ViewHandler serves the React app shell
+type ViewHandler struct {
+	repo     *repository.Repository
+	branding *config.BrandingConfig
+}
+
+// NewViewHandler creates a new view handler
+func NewViewHandler(repo *repository.Repository, branding *config.BrandingConfig) *ViewHandler {
+	return &ViewHandler{
+		repo:     repo,
+		branding: branding,
+	}
+}
+
+// ServeReactApp renders the app shell with the deployment's branding.
+// An org query parameter applies that organization's branding, and a study
+// query parameter then applies that study's overrides.
+func (h *ViewHandler) ServeReactApp(c *gin.Context) {
+	base := h.branding.Merge(h.repo.Organizations.Branding(c.Query("org")))
+	brand := base.ForStudy(c.Query("study"))
 	c.HTML(http.StatusOK, "app.html", gin.H{
-		"title": "CRAPP - Cognitive Reporting APP",
+		"title": brand.DisplayName,
+		"brand": brand,
 	})
 }
 
-// setupTemplates initializes templates with custom functions
-func SetupTemplates() (*template.Template, error) {
+// setupTemplates initializes templates with custom functions. The asset
+// function links built files by content hash so they can be cached forever.
+func SetupTemplates(assets *utils.AssetVersions) (*template.Template, error) {
 	// Define custom template functions
 	funcMap := template.FuncMap{
 		"add": func(a, b int) int {
@@ -27,6 +50,7 @@ func SetupTemplates() (*template.Template, error) {
 		"sub": func(a, b int) int {
 			return a - b
 		},
+		"asset": assets.URL,
 		// Add more custom functions as needed
 	}
 
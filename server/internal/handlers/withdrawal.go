@@ -0,0 +1,148 @@
+// internal/handlers/withdrawal.go
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/andevellicus/crapp/internal/models"
+	"github.com/andevellicus/crapp/internal/repository"
+	"github.com/andevellicus/crapp/internal/utils"
+	"github.com/andevellicus/crapp/internal/validation"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// WithdrawalHandler handles participants withdrawing from their study
+type WithdrawalHandler struct {
+	repo      *repository.Repository
+	log       *zap.SugaredLogger
+	sanitizer *utils.Sanitizer
+}
+
+// NewWithdrawalHandler creates a new study withdrawal handler
+func NewWithdrawalHandler(repo *repository.Repository, log *zap.SugaredLogger, sanitizer *utils.Sanitizer) *WithdrawalHandler {
+	return &WithdrawalHandler{
+		repo:      repo,
+		log:       log.Named("withdrawal"),
+		sanitizer: sanitizer,
+	}
+}
+
+// Withdraw takes the current user out of their study. Reminders stop and no
+// further assessments are accepted, but the account stays open. Whether the
+// data collected so far is deleted follows the study's consent.
+func (h *WithdrawalHandler) Withdraw(c *gin.Context) {
+	req := c.MustGet("validatedRequest").(*validation.WithdrawStudyRequest)
+	userEmail := c.GetString("userEmail")
+
+	user, err := h.repo.Users.GetByEmail(userEmail)
+	if err != nil || user == nil {
+		h.log.Errorw("Error retrieving user for withdrawal", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error retrieving user"})
+		return
+	}
+	if user.WithdrawnAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Already withdrawn from the study"})
+		return
+	}
+
+	policy := models.WithdrawalDataChoice
+	if user.StudyID != "" {
+		study, err := h.repo.Organizations.FindStudy(user.OrganizationID, user.StudyID)
+		if err != nil {
+			h.log.Errorw("Error retrieving study for withdrawal", "error", err, "study", user.StudyID)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error retrieving study"})
+			return
+		}
+		if study != nil && study.WithdrawalData != "" {
+			policy = study.WithdrawalData
+		}
+	}
+
+	deleteData := req.DeleteData
+	switch policy {
+	case models.WithdrawalDataRetain:
+		if deleteData {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "This study's consent keeps data collected before withdrawal"})
+			return
+		}
+	case models.WithdrawalDataDelete:
+		deleteData = true
+	}
+
+	withdrawal := &models.StudyWithdrawal{
+		UserEmail:      user.Email,
+		OrganizationID: user.OrganizationID,
+		StudyID:        user.StudyID,
+		Reason:         h.sanitizer.Note(req.Reason),
+		DataDeleted:    deleteData,
+		WithdrawnAt:    time.Now(),
+	}
+	if deleteData {
+		err := h.repo.ForUser(user.Email).Users.DeleteResearchData(user.Email)
+		if errors.Is(err, repository.ErrLegalHold) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Your data is under a legal hold and can't be deleted yet"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete study data"})
+			return
+		}
+	}
+	if err := h.repo.StudyWithdrawals.Withdraw(withdrawal); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record withdrawal"})
+		return
+	}
+
+	recordAudit(h.repo, h.log, c, user.Email, models.AuditStudyWithdrawn, "", map[string]any{
+		"study_id":     user.StudyID,
+		"data_deleted": deleteData,
+	})
+	h.log.Infow("Participant withdrew from study", "email", user.Email, "study", user.StudyID, "data_deleted", deleteData)
+
+	c.JSON(http.StatusOK, withdrawal)
+}
+
+// GetWithdrawal returns the current user's withdrawal, if they have withdrawn
+// from their present study
+func (h *WithdrawalHandler) GetWithdrawal(c *gin.Context) {
+	userEmail := c.GetString("userEmail")
+
+	user, err := h.repo.Users.GetByEmail(userEmail)
+	if err != nil || user == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error retrieving user"})
+		return
+	}
+	if user.WithdrawnAt == nil {
+		c.JSON(http.StatusOK, gin.H{"withdrawn": false})
+		return
+	}
+
+	withdrawal, err := h.repo.StudyWithdrawals.GetLatest(userEmail)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error retrieving withdrawal"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"withdrawn":  true,
+		"withdrawal": withdrawal,
+	})
+}
+
+// ListWithdrawals lists withdrawals for review. Reasons are left out for
+// blinded reviewers, since they may mention treatment effects.
+func (h *WithdrawalHandler) ListWithdrawals(c *gin.Context) {
+	withdrawals, err := h.repo.StudyWithdrawals.List(orgScope(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error retrieving withdrawals"})
+		return
+	}
+	if isBlinded(c) {
+		for i := range withdrawals {
+			withdrawals[i].Reason = ""
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"withdrawals": withdrawals})
+}
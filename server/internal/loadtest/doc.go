@@ -0,0 +1,4 @@
+// Package loadtest holds benchmarks that drive a running CRAPP server over
+// HTTP and check the latency of its hot endpoints against budgets. It has no
+// code outside its tests.
+package loadtest
@@ -0,0 +1,256 @@
+package loadtest
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// The load benchmarks send requests to a running server from parallel
+// clients and report p50, p95 and p99 latencies. They are skipped unless a
+// server and an account are given:
+//
+//	CRAPP_LOADTEST_URL=https://staging.example.org \
+//	CRAPP_LOADTEST_EMAIL=load@example.org CRAPP_LOADTEST_PASSWORD=... \
+//	CRAPP_LOADTEST_SYMPTOM=headache \
+//	  go test ./internal/loadtest -run '^$' -bench . -benchtime 30s -cpu 8
+//
+// Auth cookies are only sent over HTTPS. Set CRAPP_LOADTEST_INSECURE=1 for a
+// staging server with a self-signed certificate.
+
+// budgets are the p95 latencies each endpoint should stay under on a staging
+// server. Login is dominated by password hashing.
+var budgets = map[string]time.Duration{
+	"login":  400 * time.Millisecond,
+	"submit": 300 * time.Millisecond,
+	"chart":  200 * time.Millisecond,
+}
+
+// maxFailureRate is the fraction of requests a benchmark may lose
+const maxFailureRate = 0.01
+
+// minBudgetSamples is the fewest requests a p95 is checked against its
+// budget with, so the short calibration runs don't fail on a cold server
+const minBudgetSamples = 20
+
+type options struct {
+	baseURL  string
+	email    string
+	password string
+	symptom  string
+	metric   string
+	insecure bool
+}
+
+func BenchmarkLogin(b *testing.B) {
+	opts := loadOptions(b)
+	// Each login registers a device, like a fresh browser would
+	attack(b, "login", func() error {
+		return login(newSession(opts), opts)
+	})
+}
+
+// BenchmarkSubmit starts a new form and submits it without answers, which
+// runs the same insert and metrics path as a full submission
+func BenchmarkSubmit(b *testing.B) {
+	opts := loadOptions(b)
+	session := loggedIn(b, opts)
+	attack(b, "submit", func() error { return submit(session, opts) })
+}
+
+func BenchmarkChart(b *testing.B) {
+	opts := loadOptions(b)
+	if opts.symptom == "" {
+		b.Skip("CRAPP_LOADTEST_SYMPTOM is not set")
+	}
+	session := loggedIn(b, opts)
+	attack(b, "chart", func() error { return chart(session, opts) })
+}
+
+// loadOptions reads the server and account from the environment, skipping
+// the benchmark when they are missing
+func loadOptions(b *testing.B) options {
+	b.Helper()
+	opts := options{
+		baseURL:  strings.TrimRight(os.Getenv("CRAPP_LOADTEST_URL"), "/"),
+		email:    os.Getenv("CRAPP_LOADTEST_EMAIL"),
+		password: os.Getenv("CRAPP_LOADTEST_PASSWORD"),
+		symptom:  os.Getenv("CRAPP_LOADTEST_SYMPTOM"),
+		metric:   os.Getenv("CRAPP_LOADTEST_METRIC"),
+		insecure: os.Getenv("CRAPP_LOADTEST_INSECURE") != "",
+	}
+	if opts.baseURL == "" || opts.email == "" || opts.password == "" {
+		b.Skip("CRAPP_LOADTEST_URL, CRAPP_LOADTEST_EMAIL and CRAPP_LOADTEST_PASSWORD are not all set")
+	}
+	if opts.metric == "" {
+		opts.metric = "click_precision"
+	}
+	return opts
+}
+
+// loggedIn returns a session shared by every client of a benchmark
+func loggedIn(b *testing.B, opts options) *http.Client {
+	b.Helper()
+	session := newSession(opts)
+	if err := login(session, opts); err != nil {
+		b.Fatalf("logging in: %v", err)
+	}
+	return session
+}
+
+// attack sends requests from parallel clients until the benchmark has run
+// b.N of them, then reports their latency percentiles. It fails when too
+// many requests fail or the p95 is over the endpoint's budget.
+func attack(b *testing.B, target string, request func() error) {
+	var (
+		mu        sync.Mutex
+		latencies = make([]time.Duration, 0, b.N)
+		failures  int
+		firstErr  error
+	)
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			start := time.Now()
+			err := request()
+			elapsed := time.Since(start)
+
+			mu.Lock()
+			latencies = append(latencies, elapsed)
+			if err != nil {
+				failures++
+				if firstErr == nil {
+					firstErr = err
+				}
+			}
+			mu.Unlock()
+		}
+	})
+	b.StopTimer()
+
+	if len(latencies) == 0 {
+		return
+	}
+	slices.Sort(latencies)
+	p95 := percentile(latencies, 0.95)
+	b.ReportMetric(milliseconds(percentile(latencies, 0.50)), "p50-ms")
+	b.ReportMetric(milliseconds(p95), "p95-ms")
+	b.ReportMetric(milliseconds(percentile(latencies, 0.99)), "p99-ms")
+	b.ReportMetric(float64(failures)/float64(len(latencies)), "failures/op")
+
+	if float64(failures)/float64(len(latencies)) > maxFailureRate {
+		b.Errorf("%s: %d of %d requests failed, first: %v", target, failures, len(latencies), firstErr)
+	}
+	if budget := budgets[target]; len(latencies) >= minBudgetSamples && p95 > budget {
+		b.Errorf("%s: p95 of %s is over the %s budget", target, p95, budget)
+	}
+}
+
+// percentile returns the nearest-rank percentile of sorted latencies
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	i := int(float64(len(sorted))*p+0.5) - 1
+	return sorted[min(max(i, 0), len(sorted)-1)]
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// newSession returns a client that keeps the auth and device cookies the
+// server sets at login
+func newSession(opts options) *http.Client {
+	jar, _ := cookiejar.New(nil)
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if opts.insecure {
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+	return &http.Client{Jar: jar, Transport: transport, Timeout: 30 * time.Second}
+}
+
+func login(client *http.Client, opts options) error {
+	body := map[string]any{
+		"email":       opts.email,
+		"password":    opts.password,
+		"device_info": map[string]any{"device_name": "loadtest", "device_type": "loadtest"},
+	}
+	var resp struct {
+		DeviceID string `json:"device_id"`
+	}
+	if err := call(client, http.MethodPost, opts.baseURL+"/api/auth/login", body, &resp); err != nil {
+		return err
+	}
+	if resp.DeviceID == "" {
+		// A suspicious login is held for email confirmation instead
+		return errors.New("login was not completed")
+	}
+	return nil
+}
+
+func submit(client *http.Client, opts options) error {
+	var state struct {
+		ID string `json:"id"`
+	}
+	now := float64(time.Now().UnixMilli())
+	if err := call(client, http.MethodPost, opts.baseURL+"/api/form/init", map[string]any{"force_new": true, "client_time": now}, &state); err != nil {
+		return fmt.Errorf("init: %w", err)
+	}
+	body := map[string]any{"location_permission": "unavailable", "client_time": now}
+	if err := call(client, http.MethodPost, opts.baseURL+"/api/form/state/"+url.PathEscape(state.ID)+"/submit", body, nil); err != nil {
+		return fmt.Errorf("submit: %w", err)
+	}
+	return nil
+}
+
+func chart(client *http.Client, opts options) error {
+	query := url.Values{"user_id": {opts.email}, "symptom": {opts.symptom}, "metric": {opts.metric}}
+	return call(client, http.MethodGet, opts.baseURL+"/api/metrics/chart/timeline?"+query.Encode(), nil, nil)
+}
+
+// call sends a JSON request and decodes the response into out, if given. Any
+// status outside 2xx is an error.
+func call(client *http.Client, method, target string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, target, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	if out == nil {
+		_, err = io.Copy(io.Discard, resp.Body)
+		return err
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
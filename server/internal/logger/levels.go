@@ -0,0 +1,206 @@
+package logger
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// Levels holds the active log levels. It is set by InitLogger.
+var Levels *LevelRegistry
+
+// LevelRegistry holds the default log level, per-logger levels from config,
+// and temporary overrides set at runtime. A logger's level comes from the
+// longest matching name, where "push" matches the logger "push" as well as
+// its children ("push.sender") and namespaced copies ("tenant-a.push").
+type LevelRegistry struct {
+	mu        sync.RWMutex
+	base      zapcore.Level
+	modules   map[string]zapcore.Level
+	overrides map[string]*levelOverride
+}
+
+type levelOverride struct {
+	level     zapcore.Level
+	expiresAt time.Time
+	timer     *time.Timer
+}
+
+// LevelOverride describes a temporary level change
+type LevelOverride struct {
+	Module    string    `json:"module"`
+	Level     string    `json:"level"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// LevelSnapshot is the registry's current state
+type LevelSnapshot struct {
+	Default   string            `json:"default"`
+	Modules   map[string]string `json:"modules"`
+	Overrides []LevelOverride   `json:"overrides"`
+}
+
+// NewLevelRegistry creates a registry from level names. Unknown names are
+// reported in the error and ignored.
+func NewLevelRegistry(base string, modules map[string]string) (*LevelRegistry, error) {
+	r := &LevelRegistry{
+		base:      zapcore.InfoLevel,
+		modules:   make(map[string]zapcore.Level, len(modules)),
+		overrides: make(map[string]*levelOverride),
+	}
+
+	var invalid []string
+	if base != "" {
+		if err := r.base.UnmarshalText([]byte(base)); err != nil {
+			invalid = append(invalid, "default="+base)
+			r.base = zapcore.InfoLevel
+		}
+	}
+	for module, name := range modules {
+		var level zapcore.Level
+		if err := level.UnmarshalText([]byte(name)); err != nil {
+			invalid = append(invalid, module+"="+name)
+			continue
+		}
+		r.modules[strings.ToLower(module)] = level
+	}
+
+	if len(invalid) > 0 {
+		return r, fmt.Errorf("invalid log levels: %s", strings.Join(invalid, ", "))
+	}
+	return r, nil
+}
+
+// Enabled reports whether a logger should write an entry at a level
+func (r *LevelRegistry) Enabled(loggerName string, level zapcore.Level) bool {
+	return level >= r.LevelFor(loggerName)
+}
+
+// LevelFor returns the level in effect for a logger. Overrides take
+// precedence over configured levels, and an override of the empty module
+// replaces the default level.
+func (r *LevelRegistry) LevelFor(loggerName string) zapcore.Level {
+	name := strings.ToLower(loggerName)
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if level, ok := longestMatch(name, r.overrides, func(o *levelOverride) zapcore.Level { return o.level }); ok {
+		return level
+	}
+	if level, ok := longestMatch(name, r.modules, func(l zapcore.Level) zapcore.Level { return l }); ok {
+		return level
+	}
+	if override, ok := r.overrides[""]; ok {
+		return override.level
+	}
+	return r.base
+}
+
+// Override sets a module's level for a limited time. An empty module changes
+// the default level.
+func (r *LevelRegistry) Override(module string, level zapcore.Level, duration time.Duration) time.Time {
+	module = strings.ToLower(module)
+	expiresAt := time.Now().Add(duration)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if existing, ok := r.overrides[module]; ok {
+		existing.timer.Stop()
+	}
+	override := &levelOverride{level: level, expiresAt: expiresAt}
+	override.timer = time.AfterFunc(duration, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if r.overrides[module] == override {
+			delete(r.overrides, module)
+		}
+	})
+	r.overrides[module] = override
+
+	return expiresAt
+}
+
+// ClearOverride removes a module's temporary level, reporting whether one was set
+func (r *LevelRegistry) ClearOverride(module string) bool {
+	module = strings.ToLower(module)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	override, ok := r.overrides[module]
+	if ok {
+		override.timer.Stop()
+		delete(r.overrides, module)
+	}
+	return ok
+}
+
+// Snapshot returns the configured levels and active overrides
+func (r *LevelRegistry) Snapshot() LevelSnapshot {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	snapshot := LevelSnapshot{
+		Default:   r.base.String(),
+		Modules:   make(map[string]string, len(r.modules)),
+		Overrides: make([]LevelOverride, 0, len(r.overrides)),
+	}
+	for module, level := range r.modules {
+		snapshot.Modules[module] = level.String()
+	}
+	for module, override := range r.overrides {
+		snapshot.Overrides = append(snapshot.Overrides, LevelOverride{
+			Module:    module,
+			Level:     override.level.String(),
+			ExpiresAt: override.expiresAt,
+		})
+	}
+	sort.Slice(snapshot.Overrides, func(i, j int) bool {
+		return snapshot.Overrides[i].Module < snapshot.Overrides[j].Module
+	})
+	return snapshot
+}
+
+// longestMatch finds the most specific non-empty module matching a logger name
+func longestMatch[T any](name string, levels map[string]T, level func(T) zapcore.Level) (zapcore.Level, bool) {
+	best := ""
+	for module := range levels {
+		if module == "" || len(module) <= len(best) {
+			continue
+		}
+		if name == module ||
+			strings.HasPrefix(name, module+".") ||
+			strings.HasSuffix(name, "."+module) ||
+			strings.Contains(name, "."+module+".") {
+			best = module
+		}
+	}
+	if best == "" {
+		return 0, false
+	}
+	return level(levels[best]), true
+}
+
+// levelCore filters entries through the level registry before passing them
+// to the wrapped core
+type levelCore struct {
+	zapcore.Core
+	levels *LevelRegistry
+}
+
+func (c *levelCore) With(fields []zapcore.Field) zapcore.Core {
+	return &levelCore{Core: c.Core.With(fields), levels: c.levels}
+}
+
+func (c *levelCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.levels.Enabled(entry.LoggerName, entry.Level) {
+		return checked
+	}
+	return c.Core.Check(entry, checked)
+}
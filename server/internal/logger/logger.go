@@ -1,6 +1,7 @@
 package logger
 
 import (
+	"errors"
 	"log"
 	"os"
 	"path/filepath"
@@ -47,6 +48,21 @@ type LogConfig struct {
 	MaxAge int
 	// Whether to compress old log files
 	Compress bool
+	// Default level, and levels for individual named loggers
+	Level   string
+	Modules map[string]string
+	// Stdout encoding, "json" or "console"
+	Format string
+	// Outputs. Any combination may be enabled at once.
+	Stdout bool
+	File   bool
+	Syslog SyslogSink
+	Loki   LokiSink
+	// Added to every entry so logs from several instances can be told apart.
+	// InstanceID defaults to the host name.
+	InstanceID  string
+	Version     string
+	Environment string
 }
 
 // InitLogger initializes the Zap logger with partitioning and rotation
@@ -98,58 +114,98 @@ func InitLogger(logDir string, development bool, logConfig *LogConfig) error {
 			MaxBackups: 10,
 			MaxAge:     30, // 30 days
 			Compress:   true,
+			Stdout:     true,
+			File:       true,
 		}
 	}
 
-	// Create stdout core for terminal output
-	stdoutCore := zapcore.NewCore(
-		consoleEncoder,
-		zapcore.AddSync(os.Stdout),
-		zapcore.InfoLevel,
-	)
+	instanceID := logConfig.InstanceID
+	if instanceID == "" {
+		instanceID, _ = os.Hostname()
+	}
+
+	// Levels are decided per logger by the registry, so the stdout and info
+	// cores accept everything down to debug
+	levels, levelErr := NewLevelRegistry(logConfig.Level, logConfig.Modules)
+	Levels = levels
+
+	var cores []zapcore.Core
+	var sinkErrs []error
+
+	if logConfig.Stdout {
+		stdoutEncoder := consoleEncoder
+		if logConfig.Format == "json" {
+			stdoutEncoder = jsonEncoder
+		}
+		cores = append(cores, zapcore.NewCore(
+			stdoutEncoder,
+			zapcore.AddSync(os.Stdout),
+			zapcore.DebugLevel,
+		))
+	}
 
 	// Create file cores for different log levels
-	errorCore := zapcore.NewCore(
-		jsonEncoder,
-		zapcore.AddSync(&lumberjack.Logger{
-			Filename:   filepath.Join(logDir, timestamp+".error.log"),
-			MaxSize:    logConfig.MaxSize,
-			MaxBackups: logConfig.MaxBackups,
-			MaxAge:     logConfig.MaxAge,
-			Compress:   logConfig.Compress,
-		}),
-		zapcore.ErrorLevel,
-	)
-
-	warnCore := zapcore.NewCore(
-		jsonEncoder,
-		zapcore.AddSync(&lumberjack.Logger{
-			Filename:   filepath.Join(logDir, timestamp+".warn.log"),
-			MaxSize:    logConfig.MaxSize,
-			MaxBackups: logConfig.MaxBackups,
-			MaxAge:     logConfig.MaxAge,
-			Compress:   logConfig.Compress,
-		}),
-		zapcore.WarnLevel,
-	)
-
-	infoCore := zapcore.NewCore(
-		jsonEncoder,
-		zapcore.AddSync(&lumberjack.Logger{
-			Filename:   filepath.Join(logDir, timestamp+".info.log"),
-			MaxSize:    logConfig.MaxSize,
-			MaxBackups: logConfig.MaxBackups,
-			MaxAge:     logConfig.MaxAge,
-			Compress:   logConfig.Compress,
-		}),
-		zapcore.InfoLevel,
-	)
+	if logConfig.File {
+		for _, file := range []struct {
+			suffix string
+			level  zapcore.Level
+		}{
+			{"error", zapcore.ErrorLevel},
+			{"warn", zapcore.WarnLevel},
+			{"info", zapcore.DebugLevel},
+		} {
+			cores = append(cores, zapcore.NewCore(
+				jsonEncoder,
+				zapcore.AddSync(&lumberjack.Logger{
+					Filename:   filepath.Join(logDir, timestamp+"."+file.suffix+".log"),
+					MaxSize:    logConfig.MaxSize,
+					MaxBackups: logConfig.MaxBackups,
+					MaxAge:     logConfig.MaxAge,
+					Compress:   logConfig.Compress,
+				}),
+				file.level,
+			))
+		}
+	}
+
+	if logConfig.Syslog.Enabled {
+		syslogCore, err := newSyslogCore(logConfig.Syslog, jsonEncoder.Clone(), zapcore.DebugLevel)
+		if err != nil {
+			sinkErrs = append(sinkErrs, err)
+		} else {
+			cores = append(cores, syslogCore)
+		}
+	}
+
+	if logConfig.Loki.Enabled {
+		if logConfig.Loki.URL == "" {
+			sinkErrs = append(sinkErrs, errors.New("loki sink is enabled without a URL"))
+		} else {
+			labels := map[string]string{
+				"app":         "crapp",
+				"instance":    instanceID,
+				"environment": logConfig.Environment,
+			}
+			cores = append(cores, zapcore.NewCore(
+				jsonEncoder.Clone(),
+				newLokiWriter(logConfig.Loki, labels),
+				zapcore.DebugLevel,
+			))
+		}
+	}
 
 	// Create logger with multiple cores
-	core := zapcore.NewTee(stdoutCore, errorCore, warnCore, infoCore)
+	core := &levelCore{
+		Core:   zapcore.NewTee(cores...),
+		levels: levels,
+	}
 
 	// Create logger
-	Log = zap.New(core, zap.AddCaller(), zap.AddStacktrace(zapcore.ErrorLevel))
+	Log = zap.New(core, zap.AddCaller(), zap.AddStacktrace(zapcore.ErrorLevel), zap.Fields(
+		zap.String("instance", instanceID),
+		zap.String("version", logConfig.Version),
+		zap.String("environment", logConfig.Environment),
+	))
 	Sugar = Log.Sugar()
 
 	// Set development mode if needed
@@ -161,9 +217,24 @@ func InitLogger(logDir string, development bool, logConfig *LogConfig) error {
 	// Replace global logger
 	zap.ReplaceGlobals(Log)
 
+	if levelErr != nil {
+		Sugar.Warnw("Ignoring invalid log level settings", "error", levelErr)
+	}
+	for _, err := range sinkErrs {
+		Sugar.Errorw("Log sink unavailable", "error", err)
+	}
+
 	return nil
 }
 
+// Sampled returns a copy of a logger that logs the first entries with a given
+// message each second in full, then only every thereafter-th one
+func Sampled(log *zap.SugaredLogger, initial, thereafter int) *zap.SugaredLogger {
+	return log.Desugar().WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return zapcore.NewSamplerWithOptions(core, time.Second, initial, thereafter)
+	})).Sugar()
+}
+
 // GetLogger returns a named logger for a specific component
 func GetLogger(name string) *zap.Logger {
 	return Log.Named(name)
@@ -179,7 +250,7 @@ func Sync() {
 	_ = Log.Sync()
 }
 
-func SetUpGormConfig(dbLogger *zap.Logger, logLevel string) *gorm.Config {
+func SetUpGormConfig(dbLogger *zap.Logger, logLevel string, slowThreshold time.Duration) *gorm.Config {
 	var level gormlogger.LogLevel
 
 	// Convert string log level to GORM log level
@@ -200,7 +271,7 @@ func SetUpGormConfig(dbLogger *zap.Logger, logLevel string) *gorm.Config {
 		Logger: gormlogger.New(
 			&GormLogAdapter{ZapLogger: dbLogger},
 			gormlogger.Config{
-				SlowThreshold:             time.Second,
+				SlowThreshold:             slowThreshold,
 				LogLevel:                  level,
 				IgnoreRecordNotFoundError: true,
 				Colorful:                  false,
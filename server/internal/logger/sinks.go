@@ -0,0 +1,228 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/syslog"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// SyslogSink sends JSON log lines to a syslog daemon
+type SyslogSink struct {
+	Enabled bool
+	Network string // "udp", "tcp", or empty for the local daemon
+	Address string
+	Tag     string
+}
+
+// LokiSink pushes JSON log lines to a Grafana Loki push endpoint
+type LokiSink struct {
+	Enabled       bool
+	URL           string // e.g. http://loki:3100/loki/api/v1/push
+	TenantID      string // Sent as X-Scope-OrgID for multi-tenant Loki
+	Username      string
+	Password      string
+	Labels        map[string]string
+	BatchSize     int
+	FlushInterval time.Duration
+}
+
+// maxLokiBacklog bounds how many batches of lines are kept while Loki is unreachable
+const maxLokiBacklog = 10
+
+// syslogCore writes entries to syslog at the severity matching their level
+type syslogCore struct {
+	zapcore.LevelEnabler
+	enc    zapcore.Encoder
+	writer *syslog.Writer
+}
+
+func newSyslogCore(sink SyslogSink, enc zapcore.Encoder, enab zapcore.LevelEnabler) (zapcore.Core, error) {
+	tag := sink.Tag
+	if tag == "" {
+		tag = "crapp"
+	}
+	writer, err := syslog.Dial(sink.Network, sink.Address, syslog.LOG_INFO|syslog.LOG_DAEMON, tag)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to syslog: %w", err)
+	}
+	return &syslogCore{LevelEnabler: enab, enc: enc, writer: writer}, nil
+}
+
+func (c *syslogCore) With(fields []zapcore.Field) zapcore.Core {
+	enc := c.enc.Clone()
+	for _, field := range fields {
+		field.AddTo(enc)
+	}
+	return &syslogCore{LevelEnabler: c.LevelEnabler, enc: enc, writer: c.writer}
+}
+
+func (c *syslogCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+	return checked
+}
+
+func (c *syslogCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	buf, err := c.enc.EncodeEntry(entry, fields)
+	if err != nil {
+		return err
+	}
+	line := strings.TrimRight(buf.String(), "\n")
+	buf.Free()
+
+	switch {
+	case entry.Level >= zapcore.ErrorLevel:
+		return c.writer.Err(line)
+	case entry.Level == zapcore.WarnLevel:
+		return c.writer.Warning(line)
+	case entry.Level == zapcore.InfoLevel:
+		return c.writer.Info(line)
+	default:
+		return c.writer.Debug(line)
+	}
+}
+
+func (c *syslogCore) Sync() error {
+	return nil
+}
+
+// lokiWriter batches log lines and pushes them to Loki in the background.
+// Lines are dropped rather than blocking the application when Loki is down.
+type lokiWriter struct {
+	sink   LokiSink
+	labels map[string]string
+	client *http.Client
+
+	mu    sync.Mutex
+	lines [][2]string
+}
+
+func newLokiWriter(sink LokiSink, labels map[string]string) *lokiWriter {
+	if sink.BatchSize <= 0 {
+		sink.BatchSize = 500
+	}
+	if sink.FlushInterval <= 0 {
+		sink.FlushInterval = 5 * time.Second
+	}
+
+	streamLabels := make(map[string]string, len(labels)+len(sink.Labels))
+	for k, v := range labels {
+		if v != "" {
+			streamLabels[k] = v
+		}
+	}
+	for k, v := range sink.Labels {
+		streamLabels[k] = v
+	}
+
+	w := &lokiWriter{
+		sink:   sink,
+		labels: streamLabels,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+	go w.loop()
+	return w
+}
+
+// Write queues one encoded log line
+func (w *lokiWriter) Write(p []byte) (int, error) {
+	line := strings.TrimRight(string(p), "\n")
+	ts := strconv.FormatInt(time.Now().UnixNano(), 10)
+
+	w.mu.Lock()
+	if len(w.lines) >= w.sink.BatchSize*maxLokiBacklog {
+		w.lines = w.lines[1:]
+	}
+	w.lines = append(w.lines, [2]string{ts, line})
+	full := len(w.lines) >= w.sink.BatchSize
+	w.mu.Unlock()
+
+	if full {
+		go w.flush()
+	}
+	return len(p), nil
+}
+
+// Sync pushes queued lines immediately
+func (w *lokiWriter) Sync() error {
+	return w.flush()
+}
+
+func (w *lokiWriter) loop() {
+	ticker := time.NewTicker(w.sink.FlushInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		w.flush()
+	}
+}
+
+func (w *lokiWriter) flush() error {
+	w.mu.Lock()
+	lines := w.lines
+	w.lines = nil
+	w.mu.Unlock()
+
+	if len(lines) == 0 {
+		return nil
+	}
+
+	payload := map[string]any{
+		"streams": []map[string]any{{
+			"stream": w.labels,
+			"values": lines,
+		}},
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	if err := w.push(body); err != nil {
+		// The logger can't log its own failures, so report them on stderr and
+		// requeue the lines for the next attempt
+		fmt.Fprintf(os.Stderr, "loki push failed: %v\n", err)
+		w.mu.Lock()
+		w.lines = append(lines, w.lines...)
+		if excess := len(w.lines) - w.sink.BatchSize*maxLokiBacklog; excess > 0 {
+			w.lines = w.lines[excess:]
+		}
+		w.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+func (w *lokiWriter) push(body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, w.sink.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if w.sink.TenantID != "" {
+		req.Header.Set("X-Scope-OrgID", w.sink.TenantID)
+	}
+	if w.sink.Username != "" {
+		req.SetBasicAuth(w.sink.Username, w.sink.Password)
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("loki returned %s", resp.Status)
+	}
+	return nil
+}
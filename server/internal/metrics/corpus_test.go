@@ -0,0 +1,261 @@
+package metrics
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// update rewrites the expected outputs of corpus cases that differ, for
+// changes to the calculators that have been reviewed:
+//
+//	go test ./internal/metrics -run TestCorpus -update
+var update = flag.Bool("update", false, "rewrite expected corpus outputs")
+
+// Payload kinds in the corpus, one per calculator
+const (
+	kindInteraction = "interaction"
+	kindCPT         = "cpt"
+	kindTMT         = "tmt"
+	kindDigitSpan   = "digit_span"
+)
+
+// corpusTolerance is how far an output may differ from its expected value,
+// relative to its size
+const corpusTolerance = 1e-9
+
+// fuzzMaxMagnitude bounds the numbers in fuzzed payloads to what a device
+// clock or screen could report, epoch milliseconds included, so float
+// overflow is not what is found
+const fuzzMaxMagnitude = 1e13
+
+// corpusCase is one payload in testdata with the outputs the calculators must
+// produce from it
+type corpusCase struct {
+	Kind        string             `json:"kind"`
+	Description string             `json:"description"`
+	Input       json.RawMessage    `json:"input"`
+	Expected    map[string]float64 `json:"expected"`
+}
+
+// TestCorpus runs every payload in testdata through its calculator and
+// compares the outputs with the expected ones, so a refactor cannot silently
+// change clinical metrics. To add a case, drop in a file with an empty
+// expected object and run with -update.
+func TestCorpus(t *testing.T) {
+	files := corpusFiles(t)
+	for _, file := range files {
+		t.Run(strings.TrimSuffix(filepath.Base(file), ".json"), func(t *testing.T) {
+			c := readCase(t, file)
+			outputs, err := calculate(c.Kind, c.Input)
+			if err != nil {
+				t.Fatalf("calculating %s: %v", c.Kind, err)
+			}
+
+			differences := compareOutputs(c.Expected, outputs)
+			if len(differences) == 0 {
+				return
+			}
+			if *update {
+				c.Expected = outputs
+				encoded, err := json.MarshalIndent(c, "", "  ")
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(file, append(encoded, '\n'), 0644); err != nil {
+					t.Fatal(err)
+				}
+				t.Logf("updated %d outputs", len(differences))
+				return
+			}
+			t.Errorf("%s (%s):\n  %s", c.Description, c.Kind, strings.Join(differences, "\n  "))
+		})
+	}
+}
+
+func FuzzInteractionMetrics(f *testing.F) { fuzzCalculator(f, kindInteraction) }
+func FuzzCPTMetrics(f *testing.F)         { fuzzCalculator(f, kindCPT) }
+func FuzzTrailMetrics(f *testing.F)       { fuzzCalculator(f, kindTMT) }
+func FuzzDigitSpanMetrics(f *testing.F)   { fuzzCalculator(f, kindDigitSpan) }
+
+// fuzzCalculator seeds a fuzz target with the corpus payloads of a kind and
+// fails on any input that makes the calculator panic or produce NaN or an
+// infinity, which can't be stored or encoded as JSON
+func fuzzCalculator(f *testing.F, kind string) {
+	for _, file := range corpusFiles(f) {
+		if c := readCase(f, file); c.Kind == kind {
+			f.Add([]byte(c.Input))
+		}
+	}
+
+	f.Fuzz(func(t *testing.T, input []byte) {
+		if !withinMagnitude(input) {
+			t.Skip("numbers out of range")
+		}
+		outputs, err := calculate(kind, input)
+		if err != nil {
+			// Payloads the parser rejects never reach a calculator
+			return
+		}
+		for key, v := range outputs {
+			if math.IsNaN(v) || math.IsInf(v, 0) {
+				t.Errorf("%s = %v", key, v)
+			}
+		}
+	})
+}
+
+// calculate parses a payload and runs its calculator, flattening the results.
+// Interaction metrics are keyed "<question>/<metric>", with their sample
+// sizes under "<question>/<metric>/sample_size"; test start and end times
+// are in epoch milliseconds.
+func calculate(kind string, input []byte) (map[string]float64, error) {
+	switch kind {
+	case kindInteraction:
+		var data InteractionData
+		if err := json.Unmarshal(input, &data); err != nil {
+			return nil, err
+		}
+		calculated := CalculateInteractionMetrics(&data)
+		out := map[string]float64{}
+		for _, m := range append(calculated.GlobalMetrics, calculated.QuestionMetrics...) {
+			key := m.QuestionID + "/" + m.MetricKey
+			out[key] = m.MetricValue
+			out[key+"/sample_size"] = float64(m.SampleSize)
+		}
+		return out, nil
+	case kindCPT:
+		var data CPTData
+		if err := json.Unmarshal(input, &data); err != nil {
+			return nil, err
+		}
+		result := CalculateCPTMetrics(&data)
+		out := CPTScores(result)
+		out["test_start_time"] = float64(result.TestStartTime.UnixMilli())
+		out["test_end_time"] = float64(result.TestEndTime.UnixMilli())
+		return out, nil
+	case kindTMT:
+		var data TrailMakingData
+		if err := json.Unmarshal(input, &data); err != nil {
+			return nil, err
+		}
+		result := CalculateTrailMetrics(&data)
+		out := TrailScores(result)
+		out["test_start_time"] = float64(result.TestStartTime.UnixMilli())
+		out["test_end_time"] = float64(result.TestEndTime.UnixMilli())
+		return out, nil
+	case kindDigitSpan:
+		var data DigitSpanRawData
+		if err := json.Unmarshal(input, &data); err != nil {
+			return nil, err
+		}
+		result, err := CalculateDigitSpanMetrics(&data)
+		if err != nil {
+			return nil, err
+		}
+		out := DigitSpanScores(result)
+		out["test_start_time"] = float64(result.TestStartTime.UnixMilli())
+		out["test_end_time"] = float64(result.TestEndTime.UnixMilli())
+		return out, nil
+	}
+	return nil, fmt.Errorf("unknown payload kind %q", kind)
+}
+
+// compareOutputs describes every output that is missing, unexpected, or
+// different from its expected value
+func compareOutputs(expected, actual map[string]float64) []string {
+	keys := make(map[string]bool, len(expected)+len(actual))
+	for key := range expected {
+		keys[key] = true
+	}
+	for key := range actual {
+		keys[key] = true
+	}
+	sorted := make([]string, 0, len(keys))
+	for key := range keys {
+		sorted = append(sorted, key)
+	}
+	sort.Strings(sorted)
+
+	differences := []string{}
+	for _, key := range sorted {
+		want, hasWant := expected[key]
+		got, hasGot := actual[key]
+		switch {
+		case !hasGot:
+			differences = append(differences, fmt.Sprintf("%s: expected %v, not calculated", key, want))
+		case !hasWant:
+			differences = append(differences, fmt.Sprintf("%s: calculated %v, not expected", key, got))
+		case math.Abs(want-got) > corpusTolerance*math.Max(1, math.Max(math.Abs(want), math.Abs(got))):
+			differences = append(differences, fmt.Sprintf("%s: expected %v, calculated %v", key, want, got))
+		}
+	}
+	return differences
+}
+
+// withinMagnitude reports whether every number in a JSON payload is within
+// fuzzMaxMagnitude. Input that isn't JSON passes, for the parser to reject.
+func withinMagnitude(input []byte) bool {
+	decoder := json.NewDecoder(bytes.NewReader(input))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return true
+	}
+
+	var check func(any) bool
+	check = func(v any) bool {
+		switch v := v.(type) {
+		case json.Number:
+			f, err := v.Float64()
+			return err == nil && math.Abs(f) <= fuzzMaxMagnitude
+		case map[string]any:
+			for _, child := range v {
+				if !check(child) {
+					return false
+				}
+			}
+		case []any:
+			for _, child := range v {
+				if !check(child) {
+					return false
+				}
+			}
+		}
+		return true
+	}
+	return check(value)
+}
+
+func corpusFiles(tb testing.TB) []string {
+	tb.Helper()
+	files, err := filepath.Glob(filepath.Join("testdata", "*.json"))
+	if err != nil {
+		tb.Fatal(err)
+	}
+	if len(files) == 0 {
+		tb.Fatal("no corpus files in testdata")
+	}
+	sort.Strings(files)
+	return files
+}
+
+func readCase(tb testing.TB, file string) corpusCase {
+	tb.Helper()
+	raw, err := os.ReadFile(file)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	var c corpusCase
+	if err := json.Unmarshal(raw, &c); err != nil {
+		tb.Fatalf("%s: %v", file, err)
+	}
+	return c
+}
@@ -20,11 +20,14 @@ type CPTResponse struct {
 
 // CPTData represents the structure of raw CPT test data
 type CPTData struct {
+	TimeOrigin       float64                   `json:"timeOrigin"` // performance.timeOrigin, in epoch milliseconds
 	TestStartTime    float64                   `json:"testStartTime"`
 	TestEndTime      float64                   `json:"testEndTime"`
 	StimuliPresented []CPTStimulusPresentation `json:"stimuliPresented"`
 	Responses        []CPTResponse             `json:"responses"`
 	Settings         map[string]any            `json:"settings"`
+
+	trace *Trace // Set while replaying
 }
 
 // Helper methods for CPT calculations
@@ -151,6 +154,32 @@ func calculateCommissionErrorRate(data *CPTData) float64 {
 	return float64(countCommissionErrors(data)) / float64(nonTargetCount)
 }
 
+// traceCPT records the counts and reaction times the CPT scores come from
+func traceCPT(data *CPTData) {
+	targets, nonTargets := 0, 0
+	for _, stim := range data.StimuliPresented {
+		if stim.IsTarget {
+			targets++
+		} else {
+			nonTargets++
+		}
+	}
+	reactionTimes := make([]float64, 0, len(data.Responses))
+	for _, response := range data.Responses {
+		if response.IsTarget {
+			reactionTimes = append(reactionTimes, response.ResponseTime)
+		}
+	}
+	data.trace.record("cpt", nil, "counts", map[string]any{
+		"stimuli": len(data.StimuliPresented), "targets": targets, "non_targets": nonTargets,
+		"responses": len(data.Responses), "correct_detections": countCorrectDetections(data),
+		"commission_errors": countCommissionErrors(data), "omission_errors": countOmissionErrors(data),
+	})
+	data.trace.record("cpt", nil, "reaction_times", map[string]any{
+		"target_reaction_times": reactionTimes, "mean": calculateAverageReactionTime(data), "sd": calculateReactionTimeSD(data),
+	})
+}
+
 func serializeCPTData(data *CPTData) json.RawMessage {
 	result, err := json.Marshal(data)
 	if err != nil {
@@ -0,0 +1,116 @@
+package metrics
+
+// Which way a metric moves as performance improves
+const (
+	DirectionHigherIsBetter = "higher_is_better"
+	DirectionLowerIsBetter  = "lower_is_better"
+	DirectionNeutral        = "neutral" // No better or worse, such as speed-accuracy trade-offs
+	DirectionUnknown        = "unknown" // Custom metrics, whose meaning the formula doesn't say
+)
+
+// Properties describe a metric's values for analysis tools
+type Properties struct {
+	Unit      string   `json:"unit,omitempty"`
+	Min       *float64 `json:"min,omitempty"`
+	Max       *float64 `json:"max,omitempty"` // Empty when unbounded
+	Direction string   `json:"direction"`
+}
+
+// property builds Properties with an optional minimum and maximum
+func property(unit string, direction string, limits ...float64) Properties {
+	p := Properties{Unit: unit, Direction: direction}
+	switch len(limits) {
+	case 1:
+		p.Min = &limits[0]
+	case 2:
+		p.Min, p.Max = &limits[0], &limits[1]
+	}
+	return p
+}
+
+// properties of the built-in metrics, by key
+var properties = map[string]Properties{
+	"click_precision":      property("ratio", DirectionHigherIsBetter, 0, 1),
+	"path_efficiency":      property("ratio", DirectionHigherIsBetter, 0, 1),
+	"overshoot_rate":       property("ratio", DirectionLowerIsBetter, 0, 1),
+	"average_velocity":     property("px/s", DirectionNeutral, 0),
+	"velocity_variability": property("coefficient of variation", DirectionLowerIsBetter, 0),
+
+	"typing_speed":                  property("characters/s", DirectionHigherIsBetter, 0),
+	"average_inter_key_interval":    property("ms", DirectionLowerIsBetter, 0),
+	"typing_rhythm_variability":     property("coefficient of variation", DirectionLowerIsBetter, 0),
+	"average_key_hold_time":         property("ms", DirectionNeutral, 0),
+	"key_press_variability":         property("coefficient of variation", DirectionLowerIsBetter, 0),
+	"correction_rate":               property("corrections/character", DirectionLowerIsBetter, 0),
+	"pause_rate":                    property("ratio", DirectionLowerIsBetter, 0, 1),
+	"immediate_correction_tendency": property("ratio", DirectionNeutral, 0, 1),
+	"deep_thinking_pause_rate":      property("ratio", DirectionNeutral, 0, 1),
+	"keyboard_fluency":              property("score", DirectionHigherIsBetter, 0, 100),
+
+	"question_duration": property("s", DirectionNeutral, 0),
+
+	"reaction_time":         property("ms", DirectionLowerIsBetter, 0),
+	"detection_rate":        property("ratio", DirectionHigherIsBetter, 0, 1),
+	"omission_error_rate":   property("ratio", DirectionLowerIsBetter, 0, 1),
+	"commission_error_rate": property("ratio", DirectionLowerIsBetter, 0, 1),
+
+	"part_a_time":   property("ms", DirectionLowerIsBetter, 0),
+	"part_b_time":   property("ms", DirectionLowerIsBetter, 0),
+	"b_to_a_ratio":  property("ratio", DirectionLowerIsBetter, 0),
+	"part_a_errors": property("count", DirectionLowerIsBetter, 0),
+	"part_b_errors": property("count", DirectionLowerIsBetter, 0),
+
+	"highest_span":   property("digits", DirectionHigherIsBetter, 0),
+	"correct_trials": property("count", DirectionHigherIsBetter, 0),
+	"total_trials":   property("count", DirectionNeutral, 0),
+}
+
+// Describe returns a metric's properties. Custom and unknown metrics have
+// no unit or range and an unknown direction.
+func Describe(key string) Properties {
+	if p, ok := properties[key]; ok {
+		return p
+	}
+	return Properties{Direction: DirectionUnknown}
+}
+
+// TestField is one value a cognitive test stores for each run
+type TestField struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Unit        string `json:"unit,omitempty"`
+	Description string `json:"description"`
+}
+
+// TestFields lists the stored outputs of each cognitive test, by question
+// type. Names match the JSON fields of the test's results.
+var TestFields = map[string][]TestField{
+	GroupCPT: {
+		{"correct_detections", "integer", "count", "Targets responded to"},
+		{"commission_errors", "integer", "count", "Responses to non-targets"},
+		{"omission_errors", "integer", "count", "Targets missed"},
+		{"average_reaction_time", "number", "ms", "Mean reaction time to targets"},
+		{"reaction_time_sd", "number", "ms", "Standard deviation of reaction times"},
+		{"detection_rate", "number", "ratio", "Share of targets detected"},
+		{"omission_error_rate", "number", "ratio", "Share of targets missed"},
+		{"commission_error_rate", "number", "ratio", "Share of non-targets responded to"},
+		{"test_start_time", "datetime", "", "When the test started"},
+		{"test_end_time", "datetime", "", "When the test ended"},
+	},
+	GroupTMT: {
+		{"part_a_completion_time", "number", "ms", "Time to finish part A"},
+		{"part_a_errors", "integer", "count", "Wrong items selected in part A"},
+		{"part_b_completion_time", "number", "ms", "Time to finish part B"},
+		{"part_b_errors", "integer", "count", "Wrong items selected in part B"},
+		{"b_to_a_ratio", "number", "ratio", "Part B time divided by part A time"},
+		{"test_start_time", "datetime", "", "When the test started"},
+		{"test_end_time", "datetime", "", "When the test ended"},
+	},
+	GroupDigitSpan: {
+		{"highest_span_achieved", "integer", "digits", "Longest sequence recalled correctly"},
+		{"total_trials", "integer", "count", "Trials attempted"},
+		{"correct_trials", "integer", "count", "Trials recalled correctly"},
+		{"test_start_time", "datetime", "", "When the test started"},
+		{"test_end_time", "datetime", "", "When the test ended"},
+	},
+}
@@ -14,10 +14,13 @@ type DigitSpanAttempt struct {
 }
 
 type DigitSpanRawData struct {
+	TimeOrigin    float64            `json:"timeOrigin"`    // performance.timeOrigin, in epoch milliseconds
 	TestStartTime float64            `json:"testStartTime"` // JS performance.now() timestamp
 	TestEndTime   float64            `json:"testEndTime"`   // JS performance.now() timestamp
 	Results       []DigitSpanAttempt `json:"results"`       // Array of attempt data
 	Settings      map[string]any     `json:"settings"`      // Test settings used
+
+	trace *Trace // Set while replaying
 }
 
 func CalculateDigitSpanMetrics(results *DigitSpanRawData) (*models.DigitSpanResult, error) {
@@ -49,6 +52,9 @@ func CalculateDigitSpanMetrics(results *DigitSpanRawData) (*models.DigitSpanResu
 				highestSpan = attempt.Span
 			}
 		}
+		results.trace.record("digit_span", nil, "attempt", map[string]any{
+			"span": attempt.Span, "trial": attempt.Trial, "correct": attempt.Correct, "highest_span": highestSpan,
+		})
 	}
 
 	// If no correct attempts at all, highest span is one less than the minimum attempted span
@@ -59,13 +65,19 @@ func CalculateDigitSpanMetrics(results *DigitSpanRawData) (*models.DigitSpanResu
 	if highestSpan < 0 {
 		highestSpan = 0
 	}
+	results.trace.record("digit_span", nil, "result", map[string]any{
+		"initial_span": initialSpan, "min_attempted_span": minAttemptedSpan, "total_trials": totalTrials,
+		"correct_trials": correctTrials, "highest_span": highestSpan,
+	})
 
 	// --- Create the Result Object (partially populated) ---
 	result := &models.DigitSpanResult{
 		HighestSpanAchieved: highestSpan,
 		TotalTrials:         totalTrials,
 		CorrectTrials:       correctTrials,
-		// NOTE: UserEmail, DeviceID, AssessmentID, CreatedAt, TestStartTime, TestEndTime, RawData
+		TestStartTime:       clientTime(results.TimeOrigin, results.TestStartTime),
+		TestEndTime:         clientTime(results.TimeOrigin, results.TestEndTime),
+		// NOTE: UserEmail, DeviceID, AssessmentID, CreatedAt, RawData
 		// need to be populated by the calling handler.
 	}
 	return result, nil
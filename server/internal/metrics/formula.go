@@ -0,0 +1,303 @@
+package metrics
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Formula is a parsed arithmetic expression over metric keys, such as
+// "0.6 * typing_speed + 0.4 * (1 - correction_rate)". Only numbers, metric
+// keys, + - * /, parentheses and the functions min, max, abs, sqrt, log and
+// exp are allowed, so evaluating a formula can't reach anything but the
+// metric values it is given.
+type Formula struct {
+	source string
+	root   node
+	keys   []string
+}
+
+// formulaFunctions are the functions a formula may call, with their arity
+// (-1 for one or more arguments)
+var formulaFunctions = map[string]struct {
+	arity int
+	fn    func(args []float64) float64
+}{
+	"min":  {-1, func(a []float64) float64 { return slices.Min(a) }},
+	"max":  {-1, func(a []float64) float64 { return slices.Max(a) }},
+	"abs":  {1, func(a []float64) float64 { return math.Abs(a[0]) }},
+	"sqrt": {1, func(a []float64) float64 { return math.Sqrt(a[0]) }},
+	"log":  {1, func(a []float64) float64 { return math.Log(a[0]) }},
+	"exp":  {1, func(a []float64) float64 { return math.Exp(a[0]) }},
+}
+
+// maxFormulaLength keeps formulas small enough to evaluate on every submit
+const maxFormulaLength = 500
+
+// ParseFormula parses a formula, checking its syntax and function calls
+func ParseFormula(source string) (*Formula, error) {
+	if len(source) > maxFormulaLength {
+		return nil, fmt.Errorf("formula is longer than %d characters", maxFormulaLength)
+	}
+	tokens, err := tokenize(source)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	root, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q at position %d", p.tokens[p.pos].text, p.tokens[p.pos].pos+1)
+	}
+
+	keys := []string{}
+	root.collect(&keys)
+	slices.Sort(keys)
+	return &Formula{source: source, root: root, keys: slices.Compact(keys)}, nil
+}
+
+// Keys returns the metric keys a formula reads, sorted
+func (f *Formula) Keys() []string {
+	return f.keys
+}
+
+// String returns the formula's source
+func (f *Formula) String() string {
+	return f.source
+}
+
+// Evaluate computes the formula from metric values. It fails when a key has
+// no value or the result isn't a finite number.
+func (f *Formula) Evaluate(values map[string]float64) (float64, error) {
+	for _, key := range f.keys {
+		if _, ok := values[key]; !ok {
+			return 0, fmt.Errorf("no value for %s", key)
+		}
+	}
+	result := f.root.eval(values)
+	if math.IsNaN(result) || math.IsInf(result, 0) {
+		return 0, fmt.Errorf("result is not a finite number")
+	}
+	return result, nil
+}
+
+type node interface {
+	eval(values map[string]float64) float64
+	collect(keys *[]string)
+}
+
+type numberNode float64
+
+func (n numberNode) eval(map[string]float64) float64 { return float64(n) }
+func (n numberNode) collect(*[]string)               {}
+
+type keyNode string
+
+func (n keyNode) eval(values map[string]float64) float64 { return values[string(n)] }
+func (n keyNode) collect(keys *[]string)                 { *keys = append(*keys, string(n)) }
+
+type unaryNode struct{ operand node }
+
+func (n unaryNode) eval(values map[string]float64) float64 { return -n.operand.eval(values) }
+func (n unaryNode) collect(keys *[]string)                 { n.operand.collect(keys) }
+
+type binaryNode struct {
+	op          byte
+	left, right node
+}
+
+func (n binaryNode) eval(values map[string]float64) float64 {
+	l, r := n.left.eval(values), n.right.eval(values)
+	switch n.op {
+	case '+':
+		return l + r
+	case '-':
+		return l - r
+	case '*':
+		return l * r
+	default:
+		return l / r
+	}
+}
+
+func (n binaryNode) collect(keys *[]string) {
+	n.left.collect(keys)
+	n.right.collect(keys)
+}
+
+type callNode struct {
+	fn   func([]float64) float64
+	args []node
+}
+
+func (n callNode) eval(values map[string]float64) float64 {
+	args := make([]float64, len(n.args))
+	for i, arg := range n.args {
+		args[i] = arg.eval(values)
+	}
+	return n.fn(args)
+}
+
+func (n callNode) collect(keys *[]string) {
+	for _, arg := range n.args {
+		arg.collect(keys)
+	}
+}
+
+type token struct {
+	text string
+	pos  int
+}
+
+func tokenize(source string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(source); {
+		c := rune(source[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case strings.ContainsRune("+-*/(),", c):
+			tokens = append(tokens, token{string(c), i})
+			i++
+		case c == '.' || unicode.IsDigit(c):
+			start := i
+			for i < len(source) && (source[i] == '.' || unicode.IsDigit(rune(source[i]))) {
+				i++
+			}
+			tokens = append(tokens, token{source[start:i], start})
+		case c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z'):
+			start := i
+			for i < len(source) && (source[i] == '_' || unicode.IsLetter(rune(source[i])) || unicode.IsDigit(rune(source[i]))) {
+				i++
+			}
+			tokens = append(tokens, token{source[start:i], start})
+		default:
+			return nil, fmt.Errorf("unexpected character %q at position %d", c, i+1)
+		}
+	}
+	return tokens, nil
+}
+
+// parser is a recursive descent parser over the usual precedence levels:
+// expression = term {(+|-) term}, term = factor {(*|/) factor},
+// factor = -factor | number | key | call | (expression)
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos].text
+	}
+	return ""
+}
+
+func (p *parser) expression() (node, error) {
+	left, err := p.term()
+	if err != nil {
+		return nil, err
+	}
+	for op := p.peek(); op == "+" || op == "-"; op = p.peek() {
+		p.pos++
+		right, err := p.term()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryNode{op[0], left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) term() (node, error) {
+	left, err := p.factor()
+	if err != nil {
+		return nil, err
+	}
+	for op := p.peek(); op == "*" || op == "/"; op = p.peek() {
+		p.pos++
+		right, err := p.factor()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryNode{op[0], left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) factor() (node, error) {
+	if p.pos >= len(p.tokens) {
+		return nil, fmt.Errorf("formula ends unexpectedly")
+	}
+	tok := p.tokens[p.pos]
+	p.pos++
+
+	switch c := tok.text[0]; {
+	case tok.text == "-":
+		operand, err := p.factor()
+		if err != nil {
+			return nil, err
+		}
+		return unaryNode{operand}, nil
+
+	case tok.text == "(":
+		inner, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ")" {
+			return nil, fmt.Errorf("missing ) for ( at position %d", tok.pos+1)
+		}
+		p.pos++
+		return inner, nil
+
+	case c == '.' || (c >= '0' && c <= '9'):
+		value, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q at position %d", tok.text, tok.pos+1)
+		}
+		return numberNode(value), nil
+
+	case c == '_' || unicode.IsLetter(rune(c)):
+		if p.peek() != "(" {
+			return keyNode(tok.text), nil
+		}
+		return p.call(tok)
+	}
+	return nil, fmt.Errorf("unexpected %q at position %d", tok.text, tok.pos+1)
+}
+
+func (p *parser) call(name token) (node, error) {
+	fn, ok := formulaFunctions[strings.ToLower(name.text)]
+	if !ok {
+		return nil, fmt.Errorf("unknown function %q at position %d", name.text, name.pos+1)
+	}
+	p.pos++ // (
+
+	var args []node
+	for p.peek() != ")" {
+		if len(args) > 0 {
+			if p.peek() != "," {
+				return nil, fmt.Errorf("expected , or ) in call to %s", name.text)
+			}
+			p.pos++
+		}
+		arg, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+	}
+	p.pos++ // )
+
+	if len(args) == 0 || (fn.arity > 0 && len(args) != fn.arity) {
+		return nil, fmt.Errorf("wrong number of arguments to %s", name.text)
+	}
+	return callNode{fn.fn, args}, nil
+}
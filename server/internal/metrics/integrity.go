@@ -0,0 +1,173 @@
+package metrics
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Integrity verdicts stored with each assessment
+const (
+	IntegrityVerified   = "verified"   // The hash chain matched and the events were plausible
+	IntegritySuspect    = "suspect"    // At least one check failed
+	IntegrityUnverified = "unverified" // No interaction data or hash to check, e.g. from older clients
+)
+
+// Problems found by the integrity checks
+const (
+	IntegrityNonceMismatch   = "nonce_mismatch"   // The nonce echoed at submission isn't the form's
+	IntegrityHashMismatch    = "hash_mismatch"    // The hash chain doesn't match the events
+	IntegrityTimestampsOrder = "timestamps_order" // Event timestamps go backwards or are negative
+	IntegrityTooFewEvents    = "too_few_events"   // Fewer clicks and key presses than answered questions
+	IntegrityEventsOutlast   = "events_outlast"   // Events span longer than the form was open
+)
+
+// integrityTimingSlack allows for the device and server measuring the form's
+// length from slightly different moments
+const integrityTimingSlack = 5 * time.Second
+
+// IntegrityInput is what the server knows about a form, to check the
+// interaction data submitted with it against
+type IntegrityInput struct {
+	Nonce       string        // Issued when the form was created
+	EchoedNonce string        // Sent back by the client at submission
+	Hash        string        // Head of the client's hash chain over the events
+	Answers     int           // Questions answered, other than cognitive tests
+	Elapsed     time.Duration // How long the form was open, by the server's clock
+}
+
+// IntegrityResult is the verdict on a form's interaction data and the
+// problems behind it
+type IntegrityResult struct {
+	Verdict string   `json:"verdict"`
+	Issues  []string `json:"issues,omitempty"`
+}
+
+// CheckIntegrity checks a form's interaction data for tampering. The hash
+// chain must match the events and the nonce issued for the form, timestamps
+// must not go backwards, there must be an input event for every answer, and
+// the events must fit within the time the form was open.
+func CheckIntegrity(data *InteractionData, in IntegrityInput) IntegrityResult {
+	var issues []string
+	if in.EchoedNonce != "" && in.EchoedNonce != in.Nonce {
+		issues = append(issues, IntegrityNonceMismatch)
+	}
+	if data == nil || in.Hash == "" {
+		if len(issues) > 0 {
+			return IntegrityResult{Verdict: IntegritySuspect, Issues: issues}
+		}
+		return IntegrityResult{Verdict: IntegrityUnverified}
+	}
+
+	if IntegrityHash(in.Nonce, data) != in.Hash {
+		issues = append(issues, IntegrityHashMismatch)
+	}
+
+	last := 0.0
+	ordered := true
+	check := func(timestamps []float64) {
+		previous := 0.0
+		for _, ts := range timestamps {
+			if ts < previous {
+				ordered = false
+			}
+			previous = ts
+			last = max(last, ts)
+		}
+	}
+	check(movementTimestamps(data.MouseMovements))
+	check(interactionTimestamps(data.MouseInteractions))
+	check(keyboardTimestamps(data.KeyboardEvents))
+	if !ordered {
+		issues = append(issues, IntegrityTimestampsOrder)
+	}
+
+	inputs := len(data.MouseInteractions)
+	for _, event := range data.KeyboardEvents {
+		if event.Type == "keydown" {
+			inputs++
+		}
+	}
+	if inputs < in.Answers {
+		issues = append(issues, IntegrityTooFewEvents)
+	}
+
+	if in.Elapsed > 0 && time.Duration(last*float64(time.Millisecond)) > in.Elapsed+integrityTimingSlack {
+		issues = append(issues, IntegrityEventsOutlast)
+	}
+
+	if len(issues) > 0 {
+		return IntegrityResult{Verdict: IntegritySuspect, Issues: issues}
+	}
+	return IntegrityResult{Verdict: IntegrityVerified}
+}
+
+// IntegrityHash returns the head of the hash chain over a form's events, as
+// the client computes it. The chain starts from the SHA-256 of the nonce and
+// takes each mouse movement, then each click, then each key event in the
+// order recorded. Each link is the hex SHA-256 of the previous link and the
+// event's kind, timestamp and question, joined by "|". Timestamps are
+// written as JavaScript writes numbers.
+func IntegrityHash(nonce string, data *InteractionData) string {
+	link := sha256Hex(nonce)
+	next := func(kind string, timestamp float64, questionID string) {
+		link = sha256Hex(strings.Join([]string{link, kind, jsNumber(timestamp), questionID}, "|"))
+	}
+	for _, m := range data.MouseMovements {
+		next("move", m.Timestamp, m.QuestionID)
+	}
+	for _, i := range data.MouseInteractions {
+		next("click", i.Timestamp, i.QuestionID)
+	}
+	for _, k := range data.KeyboardEvents {
+		next(k.Type, k.Timestamp, k.QuestionID)
+	}
+	return link
+}
+
+func sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+// jsNumber formats a number the way JavaScript's String() does
+func jsNumber(v float64) string {
+	if v == 0 {
+		return "0"
+	}
+	if abs := math.Abs(v); abs >= 1e-6 && abs < 1e21 {
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	// JavaScript drops the exponent's leading zeros: 1e-7, not 1e-07
+	s := strconv.FormatFloat(v, 'e', -1, 64)
+	mantissa, exponent, _ := strings.Cut(s, "e")
+	sign, digits := exponent[:1], strings.TrimLeft(exponent[1:], "0")
+	return mantissa + "e" + sign + digits
+}
+
+func movementTimestamps(events []MouseMovement) []float64 {
+	timestamps := make([]float64, len(events))
+	for i, e := range events {
+		timestamps[i] = e.Timestamp
+	}
+	return timestamps
+}
+
+func interactionTimestamps(events []MouseInteraction) []float64 {
+	timestamps := make([]float64, len(events))
+	for i, e := range events {
+		timestamps[i] = e.Timestamp
+	}
+	return timestamps
+}
+
+func keyboardTimestamps(events []KeyboardEvent) []float64 {
+	timestamps := make([]float64, len(events))
+	for i, e := range events {
+		timestamps[i] = e.Timestamp
+	}
+	return timestamps
+}
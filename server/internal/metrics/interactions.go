@@ -6,6 +6,8 @@ type InteractionData struct {
 	MouseInteractions []MouseInteraction `json:"interactions"`
 	KeyboardEvents    []KeyboardEvent    `json:"keyboardEvents"`
 	StartTime         float64            `json:"startTime"`
+
+	trace *Trace // Set while replaying
 }
 
 // Calculate per-question metrics
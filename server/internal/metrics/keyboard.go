@@ -112,6 +112,9 @@ func calculateKeyboardMetrics(questionID *string, interactions *InteractionData)
 		if totalTime > 0 && contentKeys > 0 {
 			// Characters per second for actual content
 			typingSpeed := float64(contentKeys) / totalTime
+			interactions.trace.record("typing_speed", questionID, "result", map[string]any{
+				"keydowns": len(keydownEvents), "content_keys": contentKeys, "seconds": totalTime, "keys_per_second": typingSpeed,
+			})
 			metrics["typing_speed"] = MetricResult{
 				Value:      typingSpeed,
 				Calculated: true,
@@ -151,6 +154,10 @@ func calculateKeyboardMetrics(questionID *string, interactions *InteractionData)
 			}
 		}
 
+		interactions.trace.record("average_inter_key_interval", questionID, "outliers", map[string]any{
+			"intervals": len(intervals), "p95": sortedIntervals[p95idx], "cutoff": maxInterval, "kept": len(filteredIntervals),
+		})
+
 		// Only proceed if we have enough filtered intervals
 		if len(filteredIntervals) >= 3 {
 			var intervalSum float64
@@ -173,6 +180,9 @@ func calculateKeyboardMetrics(questionID *string, interactions *InteractionData)
 			// Use Bessel's correction for sample variance
 			intervalVariance /= float64(len(filteredIntervals) - 1)
 			typingVariability := math.Sqrt(intervalVariance) / avgInterval
+			interactions.trace.record("typing_rhythm_variability", questionID, "result", map[string]any{
+				"mean_interval": avgInterval, "variance": intervalVariance, "coefficient_of_variation": typingVariability,
+			})
 
 			metrics["typing_rhythm_variability"] = MetricResult{
 				Value:      typingVariability,
@@ -209,6 +219,11 @@ func calculateKeyboardMetrics(questionID *string, interactions *InteractionData)
 			}
 		}
 
+		interactions.trace.record("pause_rate", questionID, "result", map[string]any{
+			"intervals": len(intervals), "mean_interval": avgInterval, "pause_threshold": pauseThreshold,
+			"pauses": pauseCount, "long_pauses": longPauseCount,
+		})
+
 		metrics["pause_rate"] = MetricResult{
 			Value:      float64(pauseCount) / float64(len(intervals)),
 			Calculated: true,
@@ -276,6 +291,10 @@ func calculateKeyboardMetrics(questionID *string, interactions *InteractionData)
 			}
 		}
 
+		interactions.trace.record("average_key_hold_time", questionID, "outliers", map[string]any{
+			"hold_times": len(keyHoldTimes), "q1": q1, "q3": q3, "kept": len(filteredHoldTimes),
+		})
+
 		if len(filteredHoldTimes) >= 5 {
 			var holdTimeSum float64
 			for _, holdTime := range filteredHoldTimes {
@@ -296,6 +315,9 @@ func calculateKeyboardMetrics(questionID *string, interactions *InteractionData)
 			}
 			holdTimeVariance /= float64(len(filteredHoldTimes) - 1)
 			keyPressVar := math.Sqrt(holdTimeVariance) / avgHoldTime
+			interactions.trace.record("key_press_variability", questionID, "result", map[string]any{
+				"mean_hold_time": avgHoldTime, "variance": holdTimeVariance, "coefficient_of_variation": keyPressVar,
+			})
 
 			metrics["key_press_variability"] = MetricResult{
 				Value:      keyPressVar,
@@ -328,6 +350,10 @@ func calculateKeyboardMetrics(questionID *string, interactions *InteractionData)
 			}
 		}
 
+		interactions.trace.record("correction_rate", questionID, "counts", map[string]any{
+			"corrections": correctionCount, "immediate_corrections": immediateCorrections, "characters": charCount,
+		})
+
 		// Need at least some character input
 		if charCount >= 3 {
 			metrics["correction_rate"] = MetricResult{
@@ -368,6 +394,10 @@ func calculateKeyboardMetrics(questionID *string, interactions *InteractionData)
 			rhythmConsistency*0.4 + // Weight: 40%
 			correctionQuality*0.2) // Weight: 20%
 
+		interactions.trace.record("keyboard_fluency", questionID, "components", map[string]any{
+			"typing_speed": typingSpeed, "rhythm_consistency": rhythmConsistency, "correction_quality": correctionQuality, "score": fluencyScore,
+		})
+
 		// Cap at 100
 		if fluencyScore > 100.0 {
 			fluencyScore = 100.0
@@ -77,15 +77,25 @@ func CalculateInteractionMetrics(interactions *InteractionData) *CalculatedMetri
 	return result
 }
 
+// clientTime converts a performance.now() reading to the device's wall clock.
+// Data from clients that send no time origin is read as epoch milliseconds.
+func clientTime(origin, ms float64) time.Time {
+	return time.UnixMilli(int64(origin + ms))
+}
+
 func CalculateCPTMetrics(results *CPTData) *models.CPTResult {
+	if results.trace != nil {
+		traceCPT(results)
+	}
+
 	// Create CPT result model with all fields properly populated
 	return &models.CPTResult{
 		// These fields will be set by the handler
 		// UserEmail, DeviceID, AssessmentID
 
 		// Time fields
-		TestStartTime: time.UnixMilli(int64(results.TestStartTime)),
-		TestEndTime:   time.UnixMilli(int64(results.TestEndTime)),
+		TestStartTime: clientTime(results.TimeOrigin, results.TestStartTime),
+		TestEndTime:   clientTime(results.TimeOrigin, results.TestEndTime),
 
 		// Performance metrics
 		CorrectDetections:   countCorrectDetections(results),
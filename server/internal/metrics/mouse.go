@@ -60,11 +60,17 @@ func calculateClickPrecision(questionID *string, interactions *InteractionData)
 		}
 
 		sum += normalizedDistance
+		interactions.trace.record("click_precision", questionID, "click", map[string]any{
+			"target_id": interaction.TargetID, "distance": distance, "max_distance": maxDistance, "normalized_distance": normalizedDistance,
+		})
 	}
 
 	// Calculate precision (higher is better)
 	avgNormalizedDistance := sum / float64(len(inter))
 	precision := 1 - avgNormalizedDistance
+	interactions.trace.record("click_precision", questionID, "result", map[string]any{
+		"clicks": len(inter), "average_normalized_distance": avgNormalizedDistance, "precision": precision,
+	})
 
 	return MetricResult{
 		Value:      precision,
@@ -120,6 +126,9 @@ func calculatePathEfficiency(questionID *string, interactions *InteractionData)
 
 		// If direct distance is very small, efficiency is meaningless
 		if directDistance < 10.0 { // 10 pixels minimum threshold
+			interactions.trace.record("path_efficiency", questionID, "skipped_target", map[string]any{
+				"target_id": targetID, "direct_distance": directDistance, "reason": "direct distance under 10px",
+			})
 			continue
 		}
 
@@ -156,6 +165,10 @@ func calculatePathEfficiency(questionID *string, interactions *InteractionData)
 			}
 			totalEfficiency += efficiency
 			count++
+			interactions.trace.record("path_efficiency", questionID, "target", map[string]any{
+				"target_id": targetID, "movements": len(relevantMovements), "direct_distance": directDistance,
+				"actual_distance": actualDistance, "efficiency": efficiency,
+			})
 		}
 	}
 
@@ -168,6 +181,9 @@ func calculatePathEfficiency(questionID *string, interactions *InteractionData)
 	}
 
 	result := totalEfficiency / float64(count)
+	interactions.trace.record("path_efficiency", questionID, "result", map[string]any{
+		"targets": count, "total_efficiency": totalEfficiency, "efficiency": result,
+	})
 	return MetricResult{
 		Value:      result,
 		Calculated: true,
@@ -257,6 +273,10 @@ func calculateOvershootRate(questionID *string, interactions *InteractionData) M
 
 		overshootSum += overshootScore
 		totalTargets++
+		interactions.trace.record("overshoot_rate", questionID, "target", map[string]any{
+			"target_id": targetID, "movements": len(relevantMovements), "min_distance": minDistance,
+			"min_distance_index": minDistanceIdx, "score": overshootScore,
+		})
 	}
 
 	if totalTargets == 0 {
@@ -269,6 +289,9 @@ func calculateOvershootRate(questionID *string, interactions *InteractionData) M
 
 	// Average overshoot score across all targets
 	result := overshootSum / float64(totalTargets)
+	interactions.trace.record("overshoot_rate", questionID, "result", map[string]any{
+		"targets": totalTargets, "overshoot_sum": overshootSum, "rate": result,
+	})
 	return MetricResult{
 		Value:      result,
 		Calculated: true,
@@ -326,6 +349,7 @@ func calculateAverageVelocity(questionID *string, interactions *InteractionData)
 	}
 
 	// Calculate trimmed mean (remove top and bottom 5%) if we have enough samples
+	kept := len(velocities)
 	if len(velocities) > 10 {
 		sort.Float64s(velocities)
 		trimIndex := int(math.Floor(float64(len(velocities)) * 0.05))
@@ -340,6 +364,9 @@ func calculateAverageVelocity(questionID *string, interactions *InteractionData)
 		sum += v
 	}
 	result := sum / float64(len(velocities))
+	interactions.trace.record("average_velocity", questionID, "result", map[string]any{
+		"movements": len(movements), "velocities": kept, "after_trimming": len(velocities), "mean": result,
+	})
 
 	return MetricResult{
 		Value:      result,
@@ -416,6 +443,10 @@ func calculateVelocityVariability(questionID *string, interactions *InteractionD
 		}
 
 		// Only use filtered velocities if we didn't filter too many
+		interactions.trace.record("velocity_variability", questionID, "outliers", map[string]any{
+			"q1": q1, "q3": q3, "lower_bound": lowerBound, "upper_bound": upperBound,
+			"before": len(velocities), "after": len(filteredVelocities), "applied": len(filteredVelocities) > len(velocities)/2,
+		})
 		if len(filteredVelocities) > len(velocities)/2 {
 			velocities = filteredVelocities
 		}
@@ -437,6 +468,9 @@ func calculateVelocityVariability(questionID *string, interactions *InteractionD
 
 	// Coefficient of variation
 	result := math.Sqrt(variance) / avg
+	interactions.trace.record("velocity_variability", questionID, "result", map[string]any{
+		"movements": len(movements), "velocities": len(velocities), "mean": avg, "variance": variance, "coefficient_of_variation": result,
+	})
 
 	return MetricResult{
 		Value:      result,
@@ -0,0 +1,126 @@
+package metrics
+
+import "sync"
+
+// Metric groups, by the kind of input or test that produces them
+const (
+	GroupMouse     = "mouse"
+	GroupKeyboard  = "keyboard"
+	GroupTiming    = "timing"
+	GroupCPT       = "cpt"
+	GroupTMT       = "tmt"
+	GroupDigitSpan = "digit_span"
+)
+
+// Definition describes one metric the app records
+type Definition struct {
+	Key   string `json:"key"`
+	Label string `json:"label"`
+	Group string `json:"group"`
+}
+
+// Registry lists every metric the app records. Keys are stable: exports
+// derive column names from them.
+var Registry = []Definition{
+	{"click_precision", "Click Precision", GroupMouse},
+	{"path_efficiency", "Path Efficiency", GroupMouse},
+	{"overshoot_rate", "Overshoot Rate", GroupMouse},
+	{"average_velocity", "Average Velocity", GroupMouse},
+	{"velocity_variability", "Velocity Variability", GroupMouse},
+
+	{"typing_speed", "Typing Speed", GroupKeyboard},
+	{"average_inter_key_interval", "Inter-Key Interval", GroupKeyboard},
+	{"typing_rhythm_variability", "Typing Rhythm Variability", GroupKeyboard},
+	{"average_key_hold_time", "Key Hold Time", GroupKeyboard},
+	{"key_press_variability", "Key Press Variability", GroupKeyboard},
+	{"correction_rate", "Correction Rate", GroupKeyboard},
+	{"pause_rate", "Pause Rate", GroupKeyboard},
+	{"immediate_correction_tendency", "Immediate Correction Tendency", GroupKeyboard},
+	{"deep_thinking_pause_rate", "Deep Thinking Pause Rate", GroupKeyboard},
+	{"keyboard_fluency", "Keyboard Fluency Score", GroupKeyboard},
+
+	{"question_duration", "Time on Question (s)", GroupTiming},
+
+	{"reaction_time", "Reaction Time", GroupCPT},
+	{"detection_rate", "Detection Rate", GroupCPT},
+	{"omission_error_rate", "Omission Error Rate", GroupCPT},
+	{"commission_error_rate", "Commission Error Rate", GroupCPT},
+
+	{"part_a_time", "Part A Time", GroupTMT},
+	{"part_b_time", "Part B Time", GroupTMT},
+	{"b_to_a_ratio", "B/A Ratio", GroupTMT},
+	{"part_a_errors", "Part A Errors", GroupTMT},
+	{"part_b_errors", "Part B Errors", GroupTMT},
+
+	{"highest_span", "Highest Span Achieved", GroupDigitSpan},
+	{"correct_trials", "Correct Trials", GroupDigitSpan},
+	{"total_trials", "Total Trials", GroupDigitSpan},
+}
+
+var registryByKey = func() map[string]Definition {
+	byKey := make(map[string]Definition, len(Registry))
+	for _, def := range Registry {
+		byKey[def.Key] = def
+	}
+	return byKey
+}()
+
+// Custom metrics are defined by admins at runtime and follow the built-in ones
+var (
+	customMu    sync.RWMutex
+	custom      []Definition
+	customByKey = map[string]Definition{}
+)
+
+// SetCustom replaces the admin-defined metrics
+func SetCustom(defs []Definition) {
+	byKey := make(map[string]Definition, len(defs))
+	for _, def := range defs {
+		byKey[def.Key] = def
+	}
+
+	customMu.Lock()
+	defer customMu.Unlock()
+	custom = defs
+	customByKey = byKey
+}
+
+// All returns the built-in metrics followed by the admin-defined ones
+func All() []Definition {
+	customMu.RLock()
+	defer customMu.RUnlock()
+	return append(append([]Definition{}, Registry...), custom...)
+}
+
+// IsBuiltIn reports whether a key belongs to a metric the app records itself
+func IsBuiltIn(key string) bool {
+	_, ok := registryByKey[key]
+	return ok
+}
+
+// Lookup returns the definition of a metric key
+func Lookup(key string) (Definition, bool) {
+	if def, ok := registryByKey[key]; ok {
+		return def, true
+	}
+	customMu.RLock()
+	defer customMu.RUnlock()
+	def, ok := customByKey[key]
+	return def, ok
+}
+
+// Label returns a metric's display label, or the key itself if unknown
+func Label(key string) string {
+	if def, ok := Lookup(key); ok {
+		return def.Label
+	}
+	return key
+}
+
+// ColumnName is the export column for a metric recorded against a question
+// (or "global", or a cognitive test type): the question ID and metric key
+// joined by a double underscore. Statistics packages and pandas accept these
+// names as they are.
+func ColumnName(questionID, key string) string {
+	return questionID + "__" + key
+}
@@ -0,0 +1,37 @@
+package metrics
+
+import "github.com/andevellicus/crapp/internal/models"
+
+// CPTScores lists a CPT result's scores by column name
+func CPTScores(r *models.CPTResult) map[string]float64 {
+	return map[string]float64{
+		"correct_detections":    float64(r.CorrectDetections),
+		"commission_errors":     float64(r.CommissionErrors),
+		"omission_errors":       float64(r.OmissionErrors),
+		"average_reaction_time": r.AverageReactionTime,
+		"reaction_time_sd":      r.ReactionTimeSD,
+		"detection_rate":        r.DetectionRate,
+		"omission_error_rate":   r.OmissionErrorRate,
+		"commission_error_rate": r.CommissionErrorRate,
+	}
+}
+
+// TrailScores lists a Trail Making Test result's scores by column name
+func TrailScores(r *models.TMTResult) map[string]float64 {
+	return map[string]float64{
+		"part_a_completion_time": r.PartACompletionTime,
+		"part_a_errors":          float64(r.PartAErrors),
+		"part_b_completion_time": r.PartBCompletionTime,
+		"part_b_errors":          float64(r.PartBErrors),
+		"b_to_a_ratio":           r.BToARatio,
+	}
+}
+
+// DigitSpanScores lists a digit span result's scores by column name
+func DigitSpanScores(r *models.DigitSpanResult) map[string]float64 {
+	return map[string]float64{
+		"highest_span_achieved": float64(r.HighestSpanAchieved),
+		"total_trials":          float64(r.TotalTrials),
+		"correct_trials":        float64(r.CorrectTrials),
+	}
+}
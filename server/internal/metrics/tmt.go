@@ -11,6 +11,7 @@ import (
 
 // TrailMakingData represents the raw data from a Trail Making Test
 type TrailMakingData struct {
+	TimeOrigin          float64        `json:"timeOrigin"` // performance.timeOrigin, in epoch milliseconds
 	TestStartTime       float64        `json:"testStartTime"`
 	TestEndTime         float64        `json:"testEndTime"`
 	PartAStartTime      float64        `json:"partAStartTime"`
@@ -23,6 +24,8 @@ type TrailMakingData struct {
 	PartBCompletionTime float64        `json:"partBCompletionTime"`
 	Clicks              []Click        `json:"clicks"`
 	Settings            map[string]any `json:"settings"`
+
+	trace *Trace // Set while replaying
 }
 
 // Click represents a single interaction during the Trail Making Test
@@ -39,8 +42,8 @@ func CalculateTrailMetrics(data *TrailMakingData) *models.TMTResult {
 	// Create Trail Making Test result model
 	return &models.TMTResult{
 		// Time fields
-		TestStartTime: time.UnixMilli(int64(data.TestStartTime)),
-		TestEndTime:   time.UnixMilli(int64(data.TestEndTime)),
+		TestStartTime: clientTime(data.TimeOrigin, data.TestStartTime),
+		TestEndTime:   clientTime(data.TimeOrigin, data.TestEndTime),
 
 		// Part A metrics
 		PartACompletionTime: data.PartACompletionTime,
@@ -61,6 +64,10 @@ func CalculateTrailMetrics(data *TrailMakingData) *models.TMTResult {
 
 // Calculate B/A ratio (important clinical measure)
 func calculateBToARatio(data *TrailMakingData) float64 {
+	data.trace.record("tmt", nil, "b_to_a_ratio", map[string]any{
+		"part_a_completion_time": data.PartACompletionTime, "part_b_completion_time": data.PartBCompletionTime,
+		"part_a_errors": data.PartAErrors, "part_b_errors": data.PartBErrors, "clicks": len(data.Clicks),
+	})
 	if data.PartACompletionTime <= 0 {
 		return 0
 	}
@@ -0,0 +1,73 @@
+package metrics
+
+import "github.com/andevellicus/crapp/internal/models"
+
+// maxTraceSteps bounds a trace, since per-event steps grow with the data
+const maxTraceSteps = 5000
+
+// Trace records the intermediate values of metric calculations. It is only
+// set when an assessment is replayed; calculators skip it when it is nil.
+type Trace struct {
+	Steps     []TraceStep `json:"steps"`
+	Truncated bool        `json:"truncated,omitempty"` // Steps past maxTraceSteps were dropped
+}
+
+// TraceStep is one set of intermediate values of a metric
+type TraceStep struct {
+	Metric     string         `json:"metric"`
+	QuestionID string         `json:"question_id,omitempty"` // Empty for global metrics
+	Step       string         `json:"step"`
+	Values     map[string]any `json:"values"`
+}
+
+// record adds a step, unless tracing is off
+func (t *Trace) record(metric string, questionID *string, step string, values map[string]any) {
+	if t == nil {
+		return
+	}
+	if len(t.Steps) >= maxTraceSteps {
+		t.Truncated = true
+		return
+	}
+	s := TraceStep{Metric: metric, Step: step, Values: values}
+	if questionID != nil {
+		s.QuestionID = *questionID
+	}
+	t.Steps = append(t.Steps, s)
+}
+
+// ReplayInteractionMetrics recalculates interaction metrics, recording the
+// steps taken
+func ReplayInteractionMetrics(interactions *InteractionData) (*CalculatedMetrics, *Trace) {
+	trace := &Trace{}
+	interactions.trace = trace
+	defer func() { interactions.trace = nil }()
+	return CalculateInteractionMetrics(interactions), trace
+}
+
+// ReplayCPTMetrics recalculates CPT scores, recording the steps taken
+func ReplayCPTMetrics(data *CPTData) (*models.CPTResult, *Trace) {
+	trace := &Trace{}
+	data.trace = trace
+	defer func() { data.trace = nil }()
+	return CalculateCPTMetrics(data), trace
+}
+
+// ReplayTrailMetrics recalculates Trail Making Test scores, recording the
+// steps taken
+func ReplayTrailMetrics(data *TrailMakingData) (*models.TMTResult, *Trace) {
+	trace := &Trace{}
+	data.trace = trace
+	defer func() { data.trace = nil }()
+	return CalculateTrailMetrics(data), trace
+}
+
+// ReplayDigitSpanMetrics recalculates digit span scores, recording the steps
+// taken
+func ReplayDigitSpanMetrics(data *DigitSpanRawData) (*models.DigitSpanResult, *Trace, error) {
+	trace := &Trace{}
+	data.trace = trace
+	defer func() { data.trace = nil }()
+	result, err := CalculateDigitSpanMetrics(data)
+	return result, trace, err
+}
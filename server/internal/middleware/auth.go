@@ -3,7 +3,9 @@ package middleware
 import (
 	"net/http"
 	"strings"
+	"time"
 
+	"github.com/andevellicus/crapp/internal/repository"
 	"github.com/andevellicus/crapp/internal/services"
 	"github.com/gin-gonic/gin"
 )
@@ -44,6 +46,75 @@ func AuthMiddleware(authService *services.AuthService) gin.HandlerFunc {
 		c.Set("userEmail", claims.Email)
 		c.Set("isAdmin", claims.IsAdmin)
 		c.Set("tokenID", claims.TokenID)
+		c.Set("reauthenticated", claims.ReauthUntil > time.Now().Unix())
+		if claims.KioskSessionID != "" {
+			c.Set("kioskSessionID", claims.KioskSessionID)
+		}
+
+		if claims.ImpersonationID != "" {
+			c.Set("impersonator", claims.ImpersonatorEmail)
+			c.Set("impersonationID", claims.ImpersonationID)
+			c.Header("X-Impersonated-By", claims.ImpersonatorEmail)
+
+			if claims.ReadOnly && !readOnlyAllowed(c) {
+				c.JSON(http.StatusForbidden, gin.H{"error": "This impersonation session is read-only"})
+				c.Abort()
+			} else {
+				c.Next()
+			}
+
+			// Everything done while impersonating is audited, including refusals
+			if err := authService.RecordImpersonatedRequest(claims, c.Request.Method, c.FullPath(), c.Writer.Status(), c.ClientIP(), c.Request.UserAgent()); err != nil {
+				c.Error(err)
+			}
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// readOnlyAllowed reports whether a read-only impersonation token may make a
+// request: reads, plus ending the session or logging out
+func readOnlyAllowed(c *gin.Context) bool {
+	switch c.Request.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	switch c.FullPath() {
+	case "/api/impersonation/end", "/api/auth/logout":
+		return true
+	}
+	return false
+}
+
+// NoImpersonationMiddleware blocks impersonation tokens from routes that must
+// only ever be used by the account holder
+func NoImpersonationMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, impersonating := c.Get("impersonationID"); impersonating {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Not available while impersonating"})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// ReauthMiddleware requires a recent password re-entry, obtained from
+// /api/auth/reauth, before destructive or sensitive actions. It answers 403
+// rather than 401 so clients don't mistake it for an expired session.
+func ReauthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !c.GetBool("reauthenticated") {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":           "Please confirm your password to continue",
+				"reauth_required": true,
+			})
+			c.Abort()
+			return
+		}
 
 		c.Next()
 	}
@@ -63,3 +134,113 @@ func AdminMiddleware() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// OrgAdminMiddleware admits deployment admins and organization admins, setting
+// "orgScope" to the organization the request is limited to. Deployment admins
+// are unscoped unless they pass an org query parameter.
+func OrgAdminMiddleware(repo *repository.Repository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if isAdmin, exists := c.Get("isAdmin"); exists && isAdmin.(bool) {
+			c.Set("orgScope", strings.ToLower(c.Query("org")))
+			c.Next()
+			return
+		}
+
+		userEmail, exists := c.Get("userEmail")
+		if exists {
+			user, err := repo.Users.GetByEmail(userEmail.(string))
+			if err == nil && user != nil && user.IsOrgAdmin && user.OrganizationID != "" {
+				c.Set("orgScope", user.OrganizationID)
+				c.Next()
+				return
+			}
+		}
+
+		c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+		c.Abort()
+	}
+}
+
+// ReviewerMiddleware admits admins, organization admins, and blinded
+// reviewers to read-only dashboards. It sets "orgScope" like
+// OrgAdminMiddleware and "blinded" for reviewers whose view must be masked.
+func ReviewerMiddleware(repo *repository.Repository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if isAdmin, exists := c.Get("isAdmin"); exists && isAdmin.(bool) {
+			c.Set("orgScope", strings.ToLower(c.Query("org")))
+			c.Set("blinded", false)
+			c.Next()
+			return
+		}
+
+		userEmail, exists := c.Get("userEmail")
+		if exists {
+			user, err := repo.Users.GetByEmail(userEmail.(string))
+			if err == nil && user != nil && user.OrganizationID != "" && (user.IsOrgAdmin || user.IsBlindedReviewer) {
+				c.Set("orgScope", user.OrganizationID)
+				c.Set("blinded", !user.IsOrgAdmin)
+				c.Next()
+				return
+			}
+		}
+
+		c.JSON(http.StatusForbidden, gin.H{"error": "Reviewer access required"})
+		c.Abort()
+	}
+}
+
+// KioskRestrictionMiddleware blocks kiosk session tokens from routes outside the assessment flow,
+// so a patient on a shared device cannot browse their history or settings
+func KioskRestrictionMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, isKiosk := c.Get("kioskSessionID"); isKiosk {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Not available during a kiosk session"})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// PolicyAcceptanceMiddleware blocks users who have not accepted the current terms and privacy policy.
+// Exporting or deleting one's own data is always allowed.
+func PolicyAcceptanceMiddleware(legalService *services.LegalService) gin.HandlerFunc {
+	exempt := map[string]bool{
+		"GET /api/user":        true,
+		"GET /api/user/export": true,
+		"PUT /api/user/delete": true,
+
+		"POST /api/impersonation/end": true,
+	}
+
+	return func(c *gin.Context) {
+		if !legalService.IsEnforced() || exempt[c.Request.Method+" "+c.FullPath()] {
+			c.Next()
+			return
+		}
+
+		userEmail, exists := c.Get("userEmail")
+		if !exists {
+			c.Next()
+			return
+		}
+
+		pending, err := legalService.PendingDocuments(userEmail.(string))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error checking policy acceptance"})
+			c.Abort()
+			return
+		}
+		if len(pending) > 0 {
+			c.JSON(http.StatusPreconditionRequired, gin.H{
+				"error":   "Acceptance of updated terms required",
+				"pending": pending,
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,187 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/andevellicus/crapp/internal/config"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// redactedValue replaces secrets in logged bodies
+const redactedValue = "[REDACTED]"
+
+// Keys whose values are never logged. Keys containing "password", "token", or
+// "secret" are redacted as well.
+var sensitiveBodyKeys = map[string]bool{
+	"authorization": true,
+	"auth":          true,
+	"p256dh":        true,
+	"code":          true,
+	"answer":        true,
+}
+
+// Raw interaction and cognitive test payloads are large and identifying, so
+// they are truncated to their size
+var interactionBodyKeys = map[string]bool{
+	"interaction_data": true,
+	"cpt_data":         true,
+	"tmt_data":         true,
+	"digit_span_data":  true,
+}
+
+var emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+
+// bodyLogging holds the admin toggle. Bodies are only logged while it is on
+// and body logging is enabled in config.
+var bodyLogging struct {
+	sync.RWMutex
+	until time.Time
+}
+
+// EnableBodyLogging turns body logging on until the duration runs out
+func EnableBodyLogging(duration time.Duration) time.Time {
+	bodyLogging.Lock()
+	defer bodyLogging.Unlock()
+	bodyLogging.until = time.Now().Add(duration)
+	return bodyLogging.until
+}
+
+// DisableBodyLogging turns body logging off
+func DisableBodyLogging() {
+	bodyLogging.Lock()
+	defer bodyLogging.Unlock()
+	bodyLogging.until = time.Time{}
+}
+
+// BodyLoggingUntil returns when body logging turns off, or the zero time when it is off
+func BodyLoggingUntil() time.Time {
+	bodyLogging.RLock()
+	defer bodyLogging.RUnlock()
+	if time.Now().After(bodyLogging.until) {
+		return time.Time{}
+	}
+	return bodyLogging.until
+}
+
+// bodyCaptureWriter keeps the start of the response body as it is written
+type bodyCaptureWriter struct {
+	gin.ResponseWriter
+	body  bytes.Buffer
+	limit int
+}
+
+func (w *bodyCaptureWriter) Write(b []byte) (int, error) {
+	if remaining := w.limit - w.body.Len(); remaining > 0 {
+		w.body.Write(b[:min(len(b), remaining)])
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// BodyLoggerMiddleware logs redacted request and response bodies for the
+// configured routes while an admin has body logging switched on. It is a
+// debugging aid and does nothing unless enabled in config.
+func BodyLoggerMiddleware(log *zap.SugaredLogger, cfg *config.BodyLoggingConfig) gin.HandlerFunc {
+	if !cfg.Enabled {
+		return func(c *gin.Context) { c.Next() }
+	}
+	log = log.Named("body")
+
+	return func(c *gin.Context) {
+		path := c.Request.URL.Path
+		if BodyLoggingUntil().IsZero() || !bodyLogRouteMatches(cfg.Routes, path) {
+			c.Next()
+			return
+		}
+
+		var requestBody []byte
+		if c.Request.Body != nil && strings.HasPrefix(c.ContentType(), "application/json") {
+			requestBody, _ = io.ReadAll(io.LimitReader(c.Request.Body, int64(cfg.MaxBodyBytes)+1))
+			c.Request.Body = io.NopCloser(io.MultiReader(bytes.NewReader(requestBody), c.Request.Body))
+		}
+
+		writer := &bodyCaptureWriter{ResponseWriter: c.Writer, limit: cfg.MaxBodyBytes}
+		c.Writer = writer
+
+		c.Next()
+
+		log.Infow("Request bodies",
+			"method", c.Request.Method,
+			"path", path,
+			"status", c.Writer.Status(),
+			"request", redactBody(requestBody, cfg),
+			"response", redactBody(writer.body.Bytes(), cfg),
+		)
+	}
+}
+
+// bodyLogRouteMatches reports whether a path falls under one of the route
+// prefixes; an empty list matches every API route
+func bodyLogRouteMatches(routes []string, path string) bool {
+	if len(routes) == 0 {
+		return strings.HasPrefix(path, "/api/") || strings.Contains(path, "/api/")
+	}
+	for _, route := range routes {
+		if strings.HasPrefix(path, route) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactBody renders a JSON body with secrets, emails, and interaction
+// payloads removed. Bodies that can't be parsed are summarized by size.
+func redactBody(body []byte, cfg *config.BodyLoggingConfig) any {
+	if len(body) == 0 {
+		return nil
+	}
+	if len(body) > cfg.MaxBodyBytes {
+		return fmt.Sprintf("[body larger than %d bytes]", cfg.MaxBodyBytes)
+	}
+
+	var value any
+	if err := json.Unmarshal(body, &value); err != nil {
+		return fmt.Sprintf("[non-JSON body, %d bytes]", len(body))
+	}
+	return redactValue("", value, cfg.MaxFieldBytes)
+}
+
+func redactValue(key string, value any, maxFieldBytes int) any {
+	lowerKey := strings.ToLower(key)
+	if sensitiveBodyKeys[lowerKey] ||
+		strings.Contains(lowerKey, "password") ||
+		strings.Contains(lowerKey, "token") ||
+		strings.Contains(lowerKey, "secret") {
+		return redactedValue
+	}
+	if interactionBodyKeys[lowerKey] {
+		raw, _ := json.Marshal(value)
+		if len(raw) > maxFieldBytes {
+			return fmt.Sprintf("[truncated, %d bytes]", len(raw))
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		for k, child := range v {
+			v[k] = redactValue(k, child, maxFieldBytes)
+		}
+		return v
+	case []any:
+		for i, child := range v {
+			v[i] = redactValue("", child, maxFieldBytes)
+		}
+		return v
+	case string:
+		return emailPattern.ReplaceAllString(v, "[EMAIL]")
+	default:
+		return v
+	}
+}
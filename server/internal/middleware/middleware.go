@@ -1,16 +1,22 @@
 package middleware
 
 import (
-	"net/http"
-	"sync"
 	"time"
 
+	"github.com/andevellicus/crapp/internal/config"
+	"github.com/andevellicus/crapp/internal/logger"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 )
 
-// GinLogger returns a Gin middleware for Zap logging
-func GinLogger(log *zap.SugaredLogger) gin.HandlerFunc {
+// GinLogger returns a Gin middleware for Zap logging. Successful requests are
+// sampled when configured; errors are always logged.
+func GinLogger(log *zap.SugaredLogger, sampling *config.LogSamplingConfig) gin.HandlerFunc {
+	requestLog := log
+	if sampling.Enabled && sampling.Initial > 0 && sampling.Thereafter > 0 {
+		requestLog = logger.Sampled(log, sampling.Initial, sampling.Thereafter)
+	}
+
 	return func(c *gin.Context) {
 		// Start timer
 		start := time.Now()
@@ -53,7 +59,7 @@ func GinLogger(log *zap.SugaredLogger) gin.HandlerFunc {
 				"path", path,
 			)
 		default:
-			log.Infow("Request completed",
+			requestLog.Infow("Request completed",
 				"status", statusCode,
 				"latency", latency,
 				"client", clientIP,
@@ -87,38 +93,3 @@ func SecurityHeadersMiddleware() gin.HandlerFunc {
 		c.Next()
 	}
 }
-
-func RateLimiterMiddleware() gin.HandlerFunc {
-	// Create a store for IP-based rate limiting
-	store := make(map[string][]time.Time)
-	mu := &sync.Mutex{}
-
-	return func(c *gin.Context) {
-		ip := c.ClientIP()
-		now := time.Now()
-
-		mu.Lock()
-		defer mu.Unlock()
-
-		// Clean old requests (older than 1 minute)
-		var recent []time.Time
-		for _, t := range store[ip] {
-			if now.Sub(t) < time.Minute {
-				recent = append(recent, t)
-			}
-		}
-
-		// Allow max 60 requests per minute
-		if len(recent) >= 60 {
-			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
-				"error": "Rate limit exceeded. Try again later.",
-			})
-			return
-		}
-
-		// Add current request time
-		store[ip] = append(recent, now)
-
-		c.Next()
-	}
-}
@@ -0,0 +1,94 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/andevellicus/crapp/internal/repository"
+	"github.com/gin-gonic/gin"
+)
+
+// Role grants access to data that belongs to another user
+type Role int
+
+const (
+	RoleAdmin           Role = iota // Deployment admins see everyone's data
+	RoleOrgAdmin                    // Organization admins see their participants' data
+	RoleBlindedReviewer             // Blinded reviewers see their participants' data with symptom values masked
+)
+
+// OwnerFunc returns the email of the user whose data a request is about. An
+// empty email means the current user; an error means the resource doesn't
+// exist.
+type OwnerFunc func(c *gin.Context) (string, error)
+
+// OwnerFromQuery reads the owner from a query parameter
+func OwnerFromQuery(name string) OwnerFunc {
+	return func(c *gin.Context) (string, error) {
+		return c.Query(name), nil
+	}
+}
+
+// CheckSelfOrRole reports whether the current user may access the owner's
+// data, either as the owner or through one of the roles, and whether symptom
+// values must be masked from them
+func CheckSelfOrRole(c *gin.Context, repo *repository.Repository, owner string, roles ...Role) (allowed, blinded bool) {
+	viewer := c.GetString("userEmail")
+	if viewer == "" {
+		return false, false
+	}
+	if strings.EqualFold(owner, viewer) {
+		return true, false
+	}
+	if slices.Contains(roles, RoleAdmin) && c.GetBool("isAdmin") {
+		return true, false
+	}
+	if !slices.Contains(roles, RoleOrgAdmin) && !slices.Contains(roles, RoleBlindedReviewer) {
+		return false, false
+	}
+
+	user, err := repo.Users.GetByEmail(viewer)
+	if err != nil || user == nil || user.OrganizationID == "" {
+		return false, false
+	}
+	orgAdmin := user.IsOrgAdmin && slices.Contains(roles, RoleOrgAdmin)
+	reviewer := user.IsBlindedReviewer && slices.Contains(roles, RoleBlindedReviewer)
+	if !orgAdmin && !reviewer {
+		return false, false
+	}
+
+	inOrg, err := repo.Organizations.HasUser(user.OrganizationID, owner)
+	if err != nil || !inOrg {
+		return false, false
+	}
+	return true, !orgAdmin
+}
+
+// RequireSelfOrRole admits the owner of the requested resource and users
+// holding one of the roles. It sets "resourceOwner" to the owner's email and
+// "blinded" when symptom values must be masked.
+func RequireSelfOrRole(repo *repository.Repository, owner OwnerFunc, roles ...Role) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		email, err := owner(c)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Not found"})
+			c.Abort()
+			return
+		}
+		if email == "" {
+			email = c.GetString("userEmail")
+		}
+
+		allowed, blinded := CheckSelfOrRole(c, repo, email, roles...)
+		if !allowed {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+			c.Abort()
+			return
+		}
+
+		c.Set("resourceOwner", strings.ToLower(email))
+		c.Set("blinded", blinded)
+		c.Next()
+	}
+}
@@ -0,0 +1,196 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/andevellicus/crapp/internal/config"
+	"github.com/andevellicus/crapp/internal/models"
+	"github.com/andevellicus/crapp/internal/repository"
+	"github.com/andevellicus/crapp/internal/testutil"
+	"github.com/andevellicus/crapp/internal/utils"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// policyUsers are the accounts the policy tests act as and on
+var policyUsers = []models.User{
+	{Email: "admin@example.com", IsAdmin: true},
+	{Email: "orgadmin@a.example", OrganizationID: "org-a", IsOrgAdmin: true},
+	{Email: "reviewer@a.example", OrganizationID: "org-a", IsBlindedReviewer: true},
+	{Email: "participant@a.example", OrganizationID: "org-a"},
+	{Email: "participant@b.example", OrganizationID: "org-b"},
+	{Email: "orphan-admin@example.com", IsOrgAdmin: true}, // Org admin flag without an organization
+}
+
+func newPolicyRepo(t *testing.T) *repository.Repository {
+	t.Helper()
+	db := testutil.OpenGorm(t, testutil.Users(policyUsers...))
+	log := zap.NewNop().Sugar()
+	days, _ := utils.NewAssessmentDay("UTC", "")
+	return &repository.Repository{
+		Users:         repository.NewUserRepository(db, log, &config.Config{}, days),
+		Organizations: repository.NewOrganizationRepository(db, log),
+	}
+}
+
+// serve runs a request through middleware as the given viewer and returns
+// the response with the context values the next handler saw
+func serve(t *testing.T, viewer string, isAdmin bool, target string, handler gin.HandlerFunc) (*httptest.ResponseRecorder, map[string]any) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/resource", func(c *gin.Context) {
+		if viewer != "" {
+			c.Set("userEmail", viewer)
+			c.Set("isAdmin", isAdmin)
+		}
+	}, handler, func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{
+			"resourceOwner": c.GetString("resourceOwner"),
+			"blinded":       c.GetBool("blinded"),
+			"orgScope":      c.GetString("orgScope"),
+		})
+	})
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, target, nil))
+
+	seen := map[string]any{}
+	if recorder.Code == http.StatusOK {
+		if err := json.Unmarshal(recorder.Body.Bytes(), &seen); err != nil {
+			t.Fatalf("decoding response: %v", err)
+		}
+	}
+	return recorder, seen
+}
+
+func TestRequireSelfOrRole(t *testing.T) {
+	repo := newPolicyRepo(t)
+
+	tests := []struct {
+		name        string
+		viewer      string
+		isAdmin     bool
+		owner       string
+		roles       []Role
+		wantStatus  int
+		wantOwner   string
+		wantBlinded bool
+	}{
+		{name: "owner", viewer: "participant@a.example", owner: "participant@a.example",
+			wantStatus: http.StatusOK, wantOwner: "participant@a.example"},
+		{name: "owner in other case", viewer: "participant@a.example", owner: "Participant@A.example",
+			wantStatus: http.StatusOK, wantOwner: "participant@a.example"},
+		{name: "no owner means the viewer", viewer: "participant@a.example",
+			wantStatus: http.StatusOK, wantOwner: "participant@a.example"},
+		{name: "another participant", viewer: "participant@a.example", owner: "participant@b.example",
+			roles: []Role{RoleAdmin, RoleOrgAdmin, RoleBlindedReviewer}, wantStatus: http.StatusForbidden},
+		{name: "not signed in", owner: "participant@a.example",
+			roles: []Role{RoleAdmin, RoleOrgAdmin}, wantStatus: http.StatusForbidden},
+
+		{name: "admin with admin role", viewer: "admin@example.com", isAdmin: true, owner: "participant@b.example",
+			roles: []Role{RoleAdmin}, wantStatus: http.StatusOK, wantOwner: "participant@b.example"},
+		{name: "admin on an owner-only route", viewer: "admin@example.com", isAdmin: true, owner: "participant@b.example",
+			wantStatus: http.StatusForbidden},
+		{name: "admin flag only counts from the token", viewer: "admin@example.com", owner: "participant@b.example",
+			roles: []Role{RoleAdmin}, wantStatus: http.StatusForbidden},
+
+		{name: "org admin in their organization", viewer: "orgadmin@a.example", owner: "participant@a.example",
+			roles: []Role{RoleOrgAdmin}, wantStatus: http.StatusOK, wantOwner: "participant@a.example"},
+		{name: "org admin outside their organization", viewer: "orgadmin@a.example", owner: "participant@b.example",
+			roles: []Role{RoleOrgAdmin}, wantStatus: http.StatusForbidden},
+		{name: "org admin without the org admin role", viewer: "orgadmin@a.example", owner: "participant@a.example",
+			roles: []Role{RoleAdmin, RoleBlindedReviewer}, wantStatus: http.StatusForbidden},
+		{name: "org admin with no organization", viewer: "orphan-admin@example.com", owner: "participant@a.example",
+			roles: []Role{RoleOrgAdmin}, wantStatus: http.StatusForbidden},
+
+		{name: "reviewer in their organization is blinded", viewer: "reviewer@a.example", owner: "participant@a.example",
+			roles: []Role{RoleOrgAdmin, RoleBlindedReviewer}, wantStatus: http.StatusOK,
+			wantOwner: "participant@a.example", wantBlinded: true},
+		{name: "reviewer outside their organization", viewer: "reviewer@a.example", owner: "participant@b.example",
+			roles: []Role{RoleBlindedReviewer}, wantStatus: http.StatusForbidden},
+		{name: "reviewer without the reviewer role", viewer: "reviewer@a.example", owner: "participant@a.example",
+			roles: []Role{RoleOrgAdmin}, wantStatus: http.StatusForbidden},
+
+		{name: "unknown viewer", viewer: "nobody@example.com", owner: "participant@a.example",
+			roles: []Role{RoleOrgAdmin, RoleBlindedReviewer}, wantStatus: http.StatusForbidden},
+		{name: "unknown owner", viewer: "orgadmin@a.example", owner: "nobody@example.com",
+			roles: []Role{RoleOrgAdmin}, wantStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := "/resource"
+			if tt.owner != "" {
+				target += "?user_email=" + tt.owner
+			}
+			recorder, seen := serve(t, tt.viewer, tt.isAdmin, target,
+				RequireSelfOrRole(repo, OwnerFromQuery("user_email"), tt.roles...))
+
+			if recorder.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", recorder.Code, tt.wantStatus)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			if seen["resourceOwner"] != tt.wantOwner {
+				t.Errorf("resourceOwner = %v, want %q", seen["resourceOwner"], tt.wantOwner)
+			}
+			if seen["blinded"] != tt.wantBlinded {
+				t.Errorf("blinded = %v, want %v", seen["blinded"], tt.wantBlinded)
+			}
+		})
+	}
+}
+
+func TestRequireSelfOrRoleMissingResource(t *testing.T) {
+	repo := newPolicyRepo(t)
+	missing := func(c *gin.Context) (string, error) { return "", errors.New("not found") }
+
+	recorder, _ := serve(t, "admin@example.com", true, "/resource", RequireSelfOrRole(repo, missing, RoleAdmin))
+	if recorder.Code != http.StatusNotFound {
+		t.Fatalf("status = %d, want %d", recorder.Code, http.StatusNotFound)
+	}
+}
+
+func TestOrgAdminMiddleware(t *testing.T) {
+	repo := newPolicyRepo(t)
+
+	tests := []struct {
+		name       string
+		viewer     string
+		isAdmin    bool
+		query      string
+		wantStatus int
+		wantScope  string
+	}{
+		{name: "admin sees every organization", viewer: "admin@example.com", isAdmin: true,
+			wantStatus: http.StatusOK, wantScope: ""},
+		{name: "admin narrows to an organization", viewer: "admin@example.com", isAdmin: true, query: "?org=ORG-B",
+			wantStatus: http.StatusOK, wantScope: "org-b"},
+		{name: "org admin is held to their organization", viewer: "orgadmin@a.example", query: "?org=org-b",
+			wantStatus: http.StatusOK, wantScope: "org-a"},
+		{name: "org admin with no organization", viewer: "orphan-admin@example.com", wantStatus: http.StatusForbidden},
+		{name: "blinded reviewer", viewer: "reviewer@a.example", wantStatus: http.StatusForbidden},
+		{name: "participant", viewer: "participant@a.example", wantStatus: http.StatusForbidden},
+		{name: "unknown user", viewer: "nobody@example.com", wantStatus: http.StatusForbidden},
+		{name: "not signed in", wantStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder, seen := serve(t, tt.viewer, tt.isAdmin, "/resource"+tt.query, OrgAdminMiddleware(repo))
+
+			if recorder.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", recorder.Code, tt.wantStatus)
+			}
+			if tt.wantStatus == http.StatusOK && seen["orgScope"] != tt.wantScope {
+				t.Errorf("orgScope = %v, want %q", seen["orgScope"], tt.wantScope)
+			}
+		})
+	}
+}
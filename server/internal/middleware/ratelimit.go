@@ -0,0 +1,107 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/andevellicus/crapp/internal/config"
+	"github.com/gin-gonic/gin"
+)
+
+// bucket is a token bucket for one client
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// RateLimiterMiddleware limits requests using the named policy from config.
+// Authenticated requests are keyed by user, others by client IP. Allowlisted
+// networks bypass the limit.
+func RateLimiterMiddleware(cfg *config.RateLimitConfig, policyName string) gin.HandlerFunc {
+	policy, ok := cfg.Policies[policyName]
+	if !cfg.Enabled || !ok || policy.Requests <= 0 || policy.WindowSeconds <= 0 {
+		return func(c *gin.Context) { c.Next() }
+	}
+
+	capacity := float64(policy.Burst)
+	if capacity <= 0 {
+		capacity = float64(policy.Requests)
+	}
+	refillPerSecond := float64(policy.Requests) / float64(policy.WindowSeconds)
+
+	var allowlist []netip.Prefix
+	for _, cidr := range cfg.Allowlist {
+		if prefix, err := netip.ParsePrefix(cidr); err == nil {
+			allowlist = append(allowlist, prefix)
+		}
+	}
+
+	buckets := make(map[string]*bucket)
+	mu := &sync.Mutex{}
+	lastSweep := time.Now()
+
+	return func(c *gin.Context) {
+		ip := c.ClientIP()
+		if addr, err := netip.ParseAddr(ip); err == nil {
+			for _, prefix := range allowlist {
+				if prefix.Contains(addr.Unmap()) {
+					c.Next()
+					return
+				}
+			}
+		}
+
+		key := ip
+		if email, exists := c.Get("userEmail"); exists {
+			key = email.(string)
+		}
+
+		now := time.Now()
+
+		mu.Lock()
+		// Drop buckets that have refilled completely
+		if now.Sub(lastSweep) > time.Duration(policy.WindowSeconds)*time.Second {
+			for k, b := range buckets {
+				if b.tokens+now.Sub(b.last).Seconds()*refillPerSecond >= capacity {
+					delete(buckets, k)
+				}
+			}
+			lastSweep = now
+		}
+
+		b, exists := buckets[key]
+		if !exists {
+			b = &bucket{tokens: capacity, last: now}
+			buckets[key] = b
+		}
+		b.tokens = math.Min(capacity, b.tokens+now.Sub(b.last).Seconds()*refillPerSecond)
+		b.last = now
+
+		allowed := b.tokens >= 1
+		if allowed {
+			b.tokens--
+		}
+		remaining := int(b.tokens)
+		resetSeconds := int(math.Ceil((capacity - b.tokens) / refillPerSecond))
+		retryAfter := int(math.Ceil((1 - b.tokens) / refillPerSecond))
+		mu.Unlock()
+
+		c.Header("RateLimit-Limit", strconv.Itoa(int(capacity)))
+		c.Header("RateLimit-Remaining", strconv.Itoa(remaining))
+		c.Header("RateLimit-Reset", strconv.Itoa(resetSeconds))
+
+		if !allowed {
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+				"error": "Rate limit exceeded. Try again later.",
+			})
+			return
+		}
+
+		c.Next()
+	}
+}
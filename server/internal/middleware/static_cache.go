@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/andevellicus/crapp/internal/config"
+	"github.com/andevellicus/crapp/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// fingerprintedName matches build output named with a content hash, such as
+// main.3f2a9c1b.js
+var fingerprintedName = regexp.MustCompile(`[.-][0-9a-f]{8,}\.[a-z0-9]+$`)
+
+// StaticCacheMiddleware sets cache headers for static files. A request whose
+// URL carries the current content hash of the file, or whose file name
+// includes one, never changes and is cached as immutable. Everything else is
+// cached for the configured TTL and revalidated with Last-Modified.
+func StaticCacheMiddleware(cfg *config.StaticConfig, assets *utils.AssetVersions) gin.HandlerFunc {
+	immutable := fmt.Sprintf("public, max-age=%d, immutable", cfg.ImmutableMaxAgeSeconds)
+	revalidate := "no-cache"
+	if cfg.MaxAgeSeconds > 0 {
+		revalidate = fmt.Sprintf("public, max-age=%d", cfg.MaxAgeSeconds)
+	}
+
+	return func(c *gin.Context) {
+		path := c.Request.URL.Path
+		version := c.Query("v")
+
+		switch {
+		case version != "" && version == assets.Version(path):
+			c.Header("Cache-Control", immutable)
+		case fingerprintedName.MatchString(path):
+			c.Header("Cache-Control", immutable)
+		default:
+			c.Header("Cache-Control", revalidate)
+		}
+		c.Next()
+	}
+}
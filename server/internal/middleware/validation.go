@@ -19,13 +19,11 @@ func ValidateRequest(model any) gin.HandlerFunc {
 		// Create a new instance of the model
 		modelValue := reflect.New(modelType).Interface()
 
-		// Validate the request
+		// Validate the request. Failures use the same envelope as form
+		// validation so the client handles both alike.
 		errors := validator.Bind(c, modelValue)
 		if len(errors) > 0 {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error":   "Validation failed",
-				"details": errors,
-			})
+			c.JSON(http.StatusBadRequest, validation.NewValidationResponse(false, "Validation failed", errors))
 			c.Abort()
 			return
 		}
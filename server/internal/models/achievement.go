@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// Achievement is a badge or milestone a user earned, awarded once per key
+type Achievement struct {
+	ID           uint      `json:"id" gorm:"primaryKey"`
+	UserEmail    string    `json:"user_email" gorm:"not null;uniqueIndex:idx_user_achievement"`
+	Key          string    `json:"key" gorm:"type:varchar(64);not null;uniqueIndex:idx_user_achievement"`
+	AssessmentID uint      `json:"assessment_id"` // The submission that earned it
+	AwardedAt    time.Time `json:"awarded_at"`
+}
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// AssessmentAttachment is the optional photo or document a participant adds
+// to an assessment, such as a picture of a rash. The file and its thumbnail
+// are kept in the storage backend.
+type AssessmentAttachment struct {
+	ID           uint   `json:"id" gorm:"primaryKey"`
+	AssessmentID uint   `json:"assessment_id" gorm:"not null;uniqueIndex"`
+	UserEmail    string `json:"user_email" gorm:"not null;index"`
+	FileName     string `json:"file_name"`
+	ContentType  string `json:"content_type" gorm:"type:varchar(100)"`
+	SizeBytes    int64  `json:"size_bytes"`
+	SHA256       string `json:"sha256" gorm:"type:varchar(64)"`
+	StorageKey   string `json:"-" gorm:"not null"`
+	ThumbnailKey string `json:"-"` // Empty for documents
+	// Left out of analysis exports even when attachments are requested
+	ExcludeFromExports bool      `json:"exclude_from_exports" gorm:"default:false"`
+	CreatedAt          time.Time `json:"created_at"`
+}
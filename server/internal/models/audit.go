@@ -0,0 +1,52 @@
+package models
+
+import "time"
+
+// Audit event types
+const (
+	AuditLogin             = "login"
+	AuditLoginChallenged   = "login_challenged"
+	AuditLogout            = "logout"
+	AuditPasswordChanged   = "password_changed"
+	AuditReauthenticated   = "reauthenticated"
+	AuditAccountPaused     = "account_paused"
+	AuditAccountResumed    = "account_reactivated"
+	AuditStudyWithdrawn    = "study_withdrawn"
+	AuditPasswordReset     = "password_reset"
+	AuditPreferencesChange = "preferences_changed"
+	AuditDataExport        = "data_export"
+	AuditArmAllocated      = "arm_allocated"
+
+	AuditImpersonationRequested = "impersonation_requested"
+	AuditImpersonationConsent   = "impersonation_consent"
+	AuditImpersonationStarted   = "impersonation_started"
+	AuditImpersonationEnded     = "impersonation_ended"
+	AuditImpersonatedRequest    = "impersonated_request"
+
+	AuditIntegrityRepair    = "integrity_repair"
+	AuditAssessmentReplayed = "assessment_replayed"
+
+	AuditLegalHoldPlaced     = "legal_hold_placed"
+	AuditLegalHoldReleased   = "legal_hold_released"
+	AuditRetentionOverridden = "retention_overridden"
+)
+
+// AuditEvent records a security-relevant action taken on a user's account
+type AuditEvent struct {
+	ID        uint   `json:"id" gorm:"primaryKey"`
+	UserEmail string `json:"user_email" gorm:"index"`
+	EventType string `json:"event_type" gorm:"type:varchar(40);not null;index"`
+	DeviceID  string `json:"device_id,omitempty"`
+	IPAddress string `json:"ip_address,omitempty"`
+	UserAgent string `json:"user_agent,omitempty"`
+	Details   string `json:"details,omitempty" gorm:"type:jsonb"`
+
+	// Coarse IP geolocation, when available
+	Country    string   `json:"country,omitempty" gorm:"type:varchar(2)"`
+	City       string   `json:"city,omitempty"`
+	Latitude   *float64 `json:"latitude,omitempty"`
+	Longitude  *float64 `json:"longitude,omitempty"`
+	Suspicious bool     `json:"suspicious" gorm:"default:false;index"`
+
+	CreatedAt time.Time `json:"created_at" gorm:"index"`
+}
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// Caregiver link statuses
+const (
+	CaregiverLinkPending = "pending"
+	CaregiverLinkActive  = "active"
+	CaregiverLinkRevoked = "revoked"
+)
+
+// CaregiverLink connects a participant to a caregiver allowed to report on their behalf
+type CaregiverLink struct {
+	ID             uint       `json:"id" gorm:"primaryKey"`
+	PatientEmail   string     `json:"patient_email" gorm:"index"`
+	CaregiverEmail string     `json:"caregiver_email" gorm:"index"`
+	Status         string     `json:"status" gorm:"type:varchar(20);not null"`
+	InviteToken    string     `json:"-" gorm:"uniqueIndex"`
+	InvitedAt      time.Time  `json:"invited_at"`
+	ConsentedAt    *time.Time `json:"consented_at"`
+	RevokedAt      *time.Time `json:"revoked_at"`
+}
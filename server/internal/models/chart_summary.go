@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// ChartSummary is a precomputed chart point: a symptom answer paired with one
+// metric for the same question in an assessment. Rows are refreshed after each
+// submission and nightly, so chart requests skip the three-table join.
+type ChartSummary struct {
+	ID              uint      `json:"id" gorm:"primaryKey"`
+	AssessmentID    uint      `json:"assessment_id" gorm:"not null;uniqueIndex:idx_chart_summary_point"`
+	UserEmail       string    `json:"user_email" gorm:"not null;index:idx_chart_summary_lookup"` // Lowercased
+	QuestionID      string    `json:"question_id" gorm:"not null;uniqueIndex:idx_chart_summary_point;index:idx_chart_summary_lookup"`
+	MetricKey       string    `json:"metric_key" gorm:"not null;uniqueIndex:idx_chart_summary_point;index:idx_chart_summary_lookup"`
+	Date            time.Time `json:"date" gorm:"type:date;not null"` // Assessment day the point is plotted on
+	SubmittedAt     time.Time `json:"submitted_at"`
+	IsRetrospective bool      `json:"is_retrospective"`
+	LikelyBot       bool      `json:"likely_bot"`
+	SymptomValue    float64   `json:"symptom_value"`
+	MetricValue     float64   `json:"metric_value"`
+}
@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// ChartView is a saved chart configuration. Views belong to whoever looks at
+// the charts, so an admin's views apply to every participant they review.
+type ChartView struct {
+	ID        uint   `json:"id" gorm:"primaryKey"`
+	UserEmail string `json:"user_email" gorm:"type:varchar(255);not null;index"`
+	Name      string `json:"name" gorm:"type:varchar(100);not null"`
+	Symptom   string `json:"symptom" gorm:"type:varchar(100)"`
+	Metric    string `json:"metric" gorm:"type:varchar(100)"`
+
+	// Timeline date range: the last RangeDays days, or the fixed dates
+	// between StartDate and EndDate (either may be open). Empty shows all.
+	RangeDays int    `json:"range_days,omitempty"`
+	StartDate string `json:"start_date,omitempty" gorm:"type:varchar(10)"` // YYYY-MM-DD
+	EndDate   string `json:"end_date,omitempty" gorm:"type:varchar(10)"`   // YYYY-MM-DD
+
+	Bucket               string    `json:"bucket,omitempty" gorm:"type:varchar(10)"` // Timeline smoothing: day, week or month
+	IncludeRetrospective bool      `json:"include_retrospective"`
+	IsDefault            bool      `json:"is_default"` // Applied when a chart request names no symptom or metric
+	CreatedAt            time.Time `json:"created_at"`
+	UpdatedAt            time.Time `json:"updated_at"`
+}
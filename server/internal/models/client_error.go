@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// Kinds of client error reports
+const (
+	ClientErrorException = "error"               // Uncaught exception
+	ClientErrorRejection = "unhandled_rejection" // Promise rejection nobody handled
+)
+
+// ClientError is a JavaScript error reported by the PWA. Personal data is
+// scrubbed before it is stored, and reports aren't linked to an account.
+// Reports with the same fingerprint are the same error.
+type ClientError struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	Fingerprint string    `json:"fingerprint" gorm:"type:varchar(64);not null;index"`
+	Kind        string    `json:"kind" gorm:"type:varchar(20);not null"`
+	Message     string    `json:"message" gorm:"type:text"`
+	Stack       string    `json:"stack,omitempty" gorm:"type:text"`
+	Source      string    `json:"source,omitempty"` // Script URL, without its query string
+	Line        int       `json:"line,omitempty"`
+	Column      int       `json:"column,omitempty"`
+	Route       string    `json:"route,omitempty"`
+	Release     string    `json:"release,omitempty" gorm:"type:varchar(64);index"`
+	UserAgent   string    `json:"user_agent,omitempty"`
+	OccurredAt  time.Time `json:"occurred_at"`
+	CreatedAt   time.Time `json:"created_at" gorm:"index"`
+}
@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// Clinical event types
+const (
+	ClinicalEventDeviation    = "protocol_deviation"
+	ClinicalEventAdverseEvent = "adverse_event"
+)
+
+// Follow-up statuses for clinical events
+const (
+	FollowUpOpen       = "open"
+	FollowUpInProgress = "in_progress"
+	FollowUpResolved   = "resolved"
+)
+
+// ClinicalEvent is a protocol deviation or adverse event reported by a clinician
+type ClinicalEvent struct {
+	ID             uint      `json:"id" gorm:"primaryKey"`
+	EventType      string    `json:"event_type" gorm:"type:varchar(30);not null;index"`
+	UserEmail      string    `json:"user_email" gorm:"not null;index"`
+	OrganizationID string    `json:"organization_id" gorm:"type:varchar(64);index"`
+	EventDate      time.Time `json:"event_date" gorm:"type:date;not null"`
+	// Adverse events use mild, moderate, severe, life_threatening or fatal;
+	// deviations use minor or major
+	Severity       string    `json:"severity" gorm:"type:varchar(20);not null"`
+	Description    string    `json:"description" gorm:"type:text;not null"`
+	FollowUpStatus string    `json:"follow_up_status" gorm:"type:varchar(20);not null;index"`
+	FollowUpNotes  string    `json:"follow_up_notes,omitempty" gorm:"type:text"`
+	ReportedBy     string    `json:"reported_by"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
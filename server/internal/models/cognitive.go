@@ -22,6 +22,8 @@ type CPTResult struct {
 	OmissionErrorRate   float64         `json:"omission_error_rate"`
 	CommissionErrorRate float64         `json:"commission_error_rate"`
 	RawData             json.RawMessage `json:"raw_data" gorm:"type:jsonb"`
+	Settings            json.RawMessage `json:"settings,omitempty" gorm:"type:jsonb"` // Test settings served, after study and accessibility adjustments
+	SettingsHash        string          `json:"settings_hash,omitempty" gorm:"index"` // Results with equal hashes were taken under the same settings
 	CreatedAt           time.Time       `json:"created_at"`
 
 	// Relationships
@@ -44,6 +46,8 @@ type TMTResult struct {
 	PartBErrors         int             `json:"part_b_errors"`
 	BToARatio           float64         `json:"b_to_a_ratio"`
 	RawData             json.RawMessage `json:"raw_data" gorm:"type:jsonb"`
+	Settings            json.RawMessage `json:"settings,omitempty" gorm:"type:jsonb"` // Test settings served, after study and accessibility adjustments
+	SettingsHash        string          `json:"settings_hash,omitempty" gorm:"index"` // Results with equal hashes were taken under the same settings
 	CreatedAt           time.Time       `json:"created_at"`
 
 	// Relationships
@@ -68,6 +72,10 @@ type DigitSpanResult struct {
 	// Store the full raw data from the frontend test component
 	// This allows for flexible analysis later without needing schema changes
 	RawData json.RawMessage `json:"raw_data" gorm:"type:jsonb"`
+	// Test settings served, after study and accessibility adjustments, and
+	// their hash. Results with equal hashes were taken under the same settings.
+	Settings     json.RawMessage `json:"settings,omitempty" gorm:"type:jsonb"`
+	SettingsHash string          `json:"settings_hash,omitempty" gorm:"index"`
 
 	// Optional: Store start/end time if needed directly on the record
 	TestStartTime time.Time `json:"test_start_time"` // Converted from RawData
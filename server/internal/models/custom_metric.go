@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// CustomMetric is an admin-defined metric computed by a formula over the
+// metrics recorded for the same question, such as a weighted composite
+type CustomMetric struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Key       string    `json:"key" gorm:"uniqueIndex;not null"`
+	Label     string    `json:"label" gorm:"not null"`
+	Group     string    `json:"group" gorm:"not null"` // Metric group whose charts offer it
+	Formula   string    `json:"formula" gorm:"type:text;not null"`
+	CreatedBy string    `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// When stored values were last rebuilt for every assessment; behind
+	// UpdatedAt while a recompute is pending
+	RecomputedAt *time.Time `json:"recomputed_at,omitempty"`
+}
@@ -10,6 +10,7 @@ type Device struct {
 	DeviceType string    `json:"device_type"` // mobile, tablet, desktop
 	Browser    string    `json:"browser,omitempty"`
 	OS         string    `json:"os,omitempty"`
+	Timezone   string    `json:"timezone,omitempty" gorm:"type:varchar(64)"` // IANA zone detected by the browser
 	LastActive time.Time `json:"last_active"`
 	CreatedAt  time.Time `json:"created_at"`
 
@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// Export job statuses
+const (
+	ExportQueued  = "queued"
+	ExportRunning = "running"
+	ExportDone    = "done"
+	ExportFailed  = "failed"
+)
+
+// ExportJob tracks an analysis export built in the background. The window is
+// fixed when the job is created so that resumed chunks line up.
+type ExportJob struct {
+	ID             string     `json:"id" gorm:"primaryKey"`
+	CreatedBy      string     `json:"created_by" gorm:"index"`
+	OrganizationID string     `json:"organization_id,omitempty" gorm:"type:varchar(64)"` // Empty for every organization
+	Format         string     `json:"format" gorm:"type:varchar(10);not null"`
+	Blinded        bool       `json:"blinded" gorm:"default:false"`
+	Since          time.Time  `json:"since"`
+	Until          time.Time  `json:"until"`
+	Status         string     `json:"status" gorm:"type:varchar(10);not null;index"`
+	Progress       int        `json:"progress"` // Percent complete
+	ChunksTotal    int        `json:"chunks_total"`
+	ChunksDone     int        `json:"chunks_done"`
+	Filename       string     `json:"filename"`
+	StorageKey     string     `json:"-"` // Finished file; cleared once it expires
+	Error          string     `json:"error,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	StartedAt      *time.Time `json:"started_at,omitempty"`
+	CompletedAt    *time.Time `json:"completed_at,omitempty"`
+	ExpiresAt      *time.Time `json:"expires_at,omitempty"` // When the finished file is removed
+
+	IncludeAttachments bool `json:"include_attachments" gorm:"default:false"`
+}
@@ -7,17 +7,42 @@ import (
 
 // FormState represents user's progress in filling out an assessment
 type FormState struct {
-	ID              string    `json:"id" gorm:"primaryKey"`
-	UserEmail       string    `json:"user_email" gorm:"index"`
-	CurrentStep     int       `json:"current_step"`
-	Answers         JSON      `json:"answers" gorm:"type:jsonb"`
-	QuestionOrder   string    `json:"question_order" gorm:"type:text"`
-	StartedAt       time.Time `json:"started_at"`
-	LastUpdatedAt   time.Time `json:"last_updated_at"`
-	InteractionData []byte    `json:"interaction_data" gorm:"type:bytea"`
-	CPTData         []byte    `json:"cpt_data" gorm:"type:bytea"`
-	TMTData         []byte    `json:"tmt_data" gorm:"type:bytea"`
-	DigitSpanData   []byte    `json:"digit_span_data" gorm:"type:bytea"`
+	ID              string     `json:"id" gorm:"primaryKey"`
+	UserEmail       string     `json:"user_email" gorm:"index"`
+	ReportedBy      *string    `json:"reported_by,omitempty" gorm:"index"`         // Caregiver filling in the form, if any
+	AssessmentDate  *time.Time `json:"assessment_date,omitempty" gorm:"type:date"` // Set for retrospective entries
+	CurrentStep     int        `json:"current_step"`
+	Answers         JSON       `json:"answers" gorm:"type:jsonb"`
+	QuestionOrder   string     `json:"question_order" gorm:"type:text"`
+	StartedAt       time.Time  `json:"started_at"`
+	LastUpdatedAt   time.Time  `json:"last_updated_at"`
+	StepDurations   JSON       `json:"step_durations" gorm:"type:jsonb"` // Seconds spent on each question ID so far
+	InteractionData []byte     `json:"interaction_data" gorm:"type:bytea"`
+	CPTData         []byte     `json:"cpt_data" gorm:"type:bytea"`
+	TMTData         []byte     `json:"tmt_data" gorm:"type:bytea"`
+	DigitSpanData   []byte     `json:"digit_span_data" gorm:"type:bytea"`
+
+	// Device clock minus server clock, in milliseconds, sampled when the form
+	// starts; later samples update the largest drift from it
+	ClockSkewMs  *float64 `json:"clock_skew_ms,omitempty"`
+	ClockDriftMs float64  `json:"clock_drift_ms"`
+
+	// How the question order was chosen: the scheme from the questions file,
+	// and the shuffle seed or Latin square row it used. Empty for forms
+	// started before they were kept.
+	OrderScheme string `json:"order_scheme,omitempty"`
+	OrderSeed   *int64 `json:"order_seed,omitempty"`
+	OrderRow    *int   `json:"order_row,omitempty"`
+
+	// "short" when adaptive mode served only the core questions, "full" when
+	// it served all of them; empty when adaptive mode is off
+	FormVariant string `json:"form_variant,omitempty"`
+
+	// Issued when the form is created. The client seeds its hash chain over
+	// the interaction events with it and echoes it at submission.
+	Nonce string `json:"nonce"`
+	// Head of the client's hash chain over the interaction data last saved
+	IntegrityHash string `json:"-"`
 
 	// Will be 0 until assessment is "completed"
 	AssessmentID *uint `json:"assessment_id" gorm:"index"`
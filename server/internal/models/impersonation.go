@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// Impersonation session statuses
+const (
+	ImpersonationPending  = "pending"  // Waiting for the participant's consent
+	ImpersonationApproved = "approved" // Consent given, not started yet
+	ImpersonationDenied   = "denied"
+	ImpersonationActive   = "active"
+	ImpersonationEnded    = "ended"
+)
+
+// ImpersonationSession lets a support admin see the app as a participant
+type ImpersonationSession struct {
+	ID              string     `json:"id" gorm:"primaryKey"`
+	AdminEmail      string     `json:"admin_email" gorm:"index;not null"`
+	TargetEmail     string     `json:"target_email" gorm:"index;not null"`
+	Reason          string     `json:"reason" gorm:"type:text;not null"`
+	ReadOnly        bool       `json:"read_only"`
+	ConsentRequired bool       `json:"consent_required"`
+	Status          string     `json:"status" gorm:"type:varchar(20);not null;index"`
+	TokenID         string     `json:"-" gorm:"index"` // JWT ID of the impersonation token
+	RespondedAt     *time.Time `json:"responded_at,omitempty"`
+	StartedAt       *time.Time `json:"started_at,omitempty"`
+	ExpiresAt       *time.Time `json:"expires_at,omitempty"`
+	EndedAt         *time.Time `json:"ended_at,omitempty"`
+	EndedBy         string     `json:"ended_by,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
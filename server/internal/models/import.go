@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+// User import job statuses
+const (
+	ImportPending   = "pending"
+	ImportRunning   = "running"
+	ImportCompleted = "completed"
+	ImportFailed    = "failed"
+)
+
+// User import modes: email an account setup link, or email a temporary password
+const (
+	ImportModeInvite            = "invite"
+	ImportModeTemporaryPassword = "temporary_password"
+)
+
+// UserImportJob tracks an asynchronous bulk participant import
+type UserImportJob struct {
+	ID            string     `json:"id" gorm:"primaryKey"`
+	CreatedBy     string     `json:"created_by" gorm:"index"`
+	Mode          string     `json:"mode" gorm:"type:varchar(20);not null"`
+	Status        string     `json:"status" gorm:"type:varchar(20);not null"`
+	TotalRows     int        `json:"total_rows"`
+	ProcessedRows int        `json:"processed_rows"`
+	SucceededRows int        `json:"succeeded_rows"`
+	FailedRows    int        `json:"failed_rows"`
+	Results       string     `json:"-" gorm:"type:jsonb"` // Per-row outcomes
+	Error         string     `json:"error,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	CompletedAt   *time.Time `json:"completed_at"`
+}
+
+// UserImportRowResult is the outcome of importing one CSV row
+type UserImportRowResult struct {
+	Row    int    `json:"row"`
+	Email  string `json:"email"`
+	Status string `json:"status"` // "created" or "failed"
+	Error  string `json:"error,omitempty"`
+}
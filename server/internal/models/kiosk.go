@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// KioskSession represents a clinician-launched assessment session on a shared device
+type KioskSession struct {
+	ID             string     `json:"id" gorm:"primaryKey"`
+	PatientEmail   string     `json:"patient_email" gorm:"index"`
+	ClinicianEmail string     `json:"clinician_email" gorm:"index"`
+	DeviceID       string     `json:"device_id" gorm:"index"`
+	TokenID        string     `json:"-" gorm:"index"` // JWT ID of the session token
+	ExpiresAt      time.Time  `json:"expires_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+	EndedAt        *time.Time `json:"ended_at"`
+	AssessmentID   *uint      `json:"assessment_id" gorm:"index"`
+}
@@ -2,6 +2,10 @@ package models
 
 import "time"
 
+// MetricQuestionDuration is the seconds a participant spent on a question,
+// recorded alongside the interaction metrics so it can be charted
+const MetricQuestionDuration = "question_duration"
+
 // AssessmentMetric represents an indexed metric for efficient querying
 type AssessmentMetric struct {
 	ID           uint      `json:"id" gorm:"primaryKey"`
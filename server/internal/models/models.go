@@ -10,7 +10,7 @@ import (
 // Assessment represents a submitted symptom assessment
 type Assessment struct {
 	ID          uint      `json:"id" gorm:"primaryKey"`
-	UserEmail   string    `json:"user_email" gorm:"index"`
+	UserEmail   string    `json:"user_email" gorm:"index;uniqueIndex:idx_assessments_user_day"`
 	DeviceID    string    `json:"device_id" gorm:"index"`
 	SubmittedAt time.Time `json:"submitted_at" gorm:"default:CURRENT_TIMESTAMP"`
 
@@ -22,6 +22,62 @@ type Assessment struct {
 	Longitude *float64 `json:"longitude" gorm:"type:double precision"`
 	// Use pointer for nullable string field
 	LocationError *string `json:"location_error" gorm:"type:text"`
+
+	// Email of the clinician who proctored the assessment (kiosk mode), if any
+	SupervisedBy *string `json:"supervised_by,omitempty" gorm:"index"`
+	// Email of the caregiver who submitted on the user's behalf, if any
+	ReportedBy *string `json:"reported_by,omitempty" gorm:"index"`
+
+	// Retrospective (backfilled) assessments describe an earlier day from recall
+	IsRetrospective bool       `json:"is_retrospective" gorm:"default:false"`
+	AssessmentDate  *time.Time `json:"assessment_date,omitempty" gorm:"type:date"`
+	// The assessment day this entry counts towards, live or retrospective. A
+	// user has at most one assessment per day; older rows leave it empty.
+	AssessmentDay *time.Time `json:"assessment_day,omitempty" gorm:"type:date;uniqueIndex:idx_assessments_user_day"`
+
+	// Timing, from when the form was opened until it was submitted
+	StartedAt       *time.Time `json:"started_at,omitempty"`
+	DurationSeconds *float64   `json:"duration_seconds,omitempty"`
+	StepDurations   JSON       `json:"step_durations,omitempty" gorm:"type:jsonb"` // Seconds spent on each question ID
+	// Completed faster than the configured minimum time per question
+	FastCompletion bool `json:"fast_completion" gorm:"default:false"`
+	// The device was too slow during the cognitive tests for their reaction
+	// times to be trusted, judged from the session's performance beacons
+	SlowDevice  bool `json:"slow_device" gorm:"default:false"`
+	Performance JSON `json:"performance,omitempty" gorm:"type:jsonb"` // A PerfSummary
+	// Device clock minus server clock when the form was started, and how far
+	// it drifted from that while the form was filled in, in milliseconds.
+	// Client timestamps are normalized by the skew.
+	ClockSkewMs  *float64 `json:"clock_skew_ms,omitempty"`
+	ClockDriftMs *float64 `json:"clock_drift_ms,omitempty"`
+	ClockDrift   bool     `json:"clock_drift" gorm:"default:false"` // Drift exceeded the configured threshold
+	// Provenance of the question order: its scheme, the seed it was shuffled
+	// from or the Latin square row it used, and the question IDs in the order
+	// they were shown, as a JSON array
+	OrderScheme   string `json:"order_scheme,omitempty"`
+	OrderSeed     *int64 `json:"order_seed,omitempty"`
+	OrderRow      *int   `json:"order_row,omitempty"`
+	QuestionOrder string `json:"question_order,omitempty" gorm:"type:text"`
+	// Adaptive form served: "short" (core questions) or "full"; empty when
+	// adaptive mode was off
+	FormVariant string `json:"form_variant,omitempty" gorm:"index"`
+
+	// Whether the interaction data submitted with the form passed the
+	// integrity checks: "verified", "suspect" or "unverified", and the
+	// problems found, separated by commas
+	IntegrityVerdict string `json:"integrity_verdict,omitempty" gorm:"index"`
+	IntegrityIssues  string `json:"integrity_issues,omitempty"`
+
+	// Looks like a non-human submission, and the signals behind it separated
+	// by commas. Left out of analytics unless asked for.
+	LikelyBot  bool   `json:"likely_bot" gorm:"default:false;index"`
+	BotSignals string `json:"bot_signals,omitempty"`
+
+	// When an answer was last amended; empty if none has been
+	RevisedAt *time.Time `json:"revised_at,omitempty"`
+
+	// When chart summary rows were last built; empty until then
+	SummarizedAt *time.Time `json:"-" gorm:"index"`
 }
 
 // QuestionResponse represents a response to a specific question
@@ -34,6 +90,15 @@ type QuestionResponse struct {
 	TextValue    string    `json:"text_value"`               // For text inputs
 	CreatedAt    time.Time `json:"created_at"`
 
+	// Set only for questions pre-filled from the previous assessment: true
+	// when the user changed the carried-over answer, false when they kept it
+	ChangedFromPrevious *bool `json:"changed_from_previous,omitempty"`
+
+	// Times the user amended the answer, and when they last did. Earlier
+	// values are kept as QuestionResponseRevision rows.
+	Revision  int        `json:"revision" gorm:"default:0"`
+	RevisedAt *time.Time `json:"revised_at,omitempty"`
+
 	// Relationships
 	Assessment Assessment `json:"-" gorm:"foreignKey:AssessmentID"`
 }
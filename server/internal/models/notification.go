@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+// Notification delivery channels
+const (
+	NotificationChannelPush  = "push"
+	NotificationChannelEmail = "email"
+)
+
+// Notification types users can turn on or off per channel
+const (
+	NotificationReminders      = "reminders"
+	NotificationWeeklySummary  = "weekly_summary"
+	NotificationSecurityAlerts = "security_alerts"
+	NotificationAnnouncements  = "announcements"
+)
+
+// NotificationChannels lists every delivery channel
+var NotificationChannels = []string{NotificationChannelPush, NotificationChannelEmail}
+
+// NotificationTypes lists every configurable notification type
+var NotificationTypes = []string{
+	NotificationReminders,
+	NotificationWeeklySummary,
+	NotificationSecurityAlerts,
+	NotificationAnnouncements,
+}
+
+// Reminder engagement event kinds
+const (
+	NotificationEventSent    = "sent"
+	NotificationEventOpened  = "opened"
+	NotificationEventClicked = "clicked"
+)
+
+// NotificationEvent records a reminder message being sent, opened or
+// clicked. Each kind is counted once per message.
+type NotificationEvent struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	MessageID string    `json:"message_id" gorm:"type:varchar(36);not null;uniqueIndex:idx_notification_event_kind"`
+	Kind      string    `json:"kind" gorm:"type:varchar(10);not null;uniqueIndex:idx_notification_event_kind"`
+	Channel   string    `json:"channel" gorm:"type:varchar(10);not null"`
+	UserEmail string    `json:"user_email" gorm:"not null;index"`
+	CreatedAt time.Time `json:"created_at" gorm:"index"`
+}
@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// Organization is a clinic or institution hosted on this instance
+type Organization struct {
+	ID            string    `json:"id" gorm:"primaryKey;type:varchar(64)"` // URL-safe slug
+	Name          string    `json:"name" gorm:"not null"`
+	QuestionsFile string    `json:"questions_file,omitempty"` // Overrides the deployment questionnaire
+	Schema        string    `json:"schema,omitempty"`         // Postgres schema holding the organization's research data; empty for the shared schema
+	Branding      string    `json:"branding,omitempty" gorm:"type:jsonb"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// Study groups an organization's participants. Slugs are unique within an organization.
+type Study struct {
+	ID             uint      `json:"id" gorm:"primaryKey"`
+	OrganizationID string    `json:"organization_id" gorm:"type:varchar(64);not null;uniqueIndex:idx_org_study_slug"`
+	Slug           string    `json:"slug" gorm:"type:varchar(64);not null;uniqueIndex:idx_org_study_slug"`
+	Name           string    `json:"name" gorm:"not null"`
+	CreatedAt      time.Time `json:"created_at"`
+
+	// What happens to a participant's collected data when they withdraw,
+	// as set out in the study's consent
+	WithdrawalData string `json:"withdrawal_data" gorm:"type:varchar(16);default:'choice'"`
+}
@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+// Performance beacon metrics. Durations are in milliseconds; CLS is unitless.
+const (
+	PerfLCP      = "lcp"       // Largest contentful paint
+	PerfINP      = "inp"       // Interaction to next paint
+	PerfCLS      = "cls"       // Cumulative layout shift
+	PerfFCP      = "fcp"       // First contentful paint
+	PerfTTFB     = "ttfb"      // Time to first byte
+	PerfLongTask = "long_task" // A task that blocked the main thread for over 50ms
+)
+
+// CognitiveTestContexts are the beacon contexts of timed cognitive tests,
+// whose reaction times a slow device distorts
+var CognitiveTestContexts = []string{"cpt", "tmt", "digit_span"}
+
+// PerfBeacon is one Web Vitals or timing measurement sent by the PWA. Beacons
+// are tied to a random per-page-load session, stored only as a hash, and not
+// to an account.
+type PerfBeacon struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	SessionHash string    `json:"session_hash" gorm:"type:varchar(64);not null;index"`
+	Metric      string    `json:"metric" gorm:"type:varchar(20);not null"`
+	Value       float64   `json:"value"`
+	Context     string    `json:"context" gorm:"type:varchar(40)"` // Where it was measured, such as "cpt" or "form"
+	Route       string    `json:"route,omitempty"`
+	Release     string    `json:"release,omitempty" gorm:"type:varchar(64)"`
+	RecordedAt  time.Time `json:"recorded_at"`
+	CreatedAt   time.Time `json:"created_at" gorm:"index"`
+}
+
+// PerfSummary describes how a device performed during an assessment's
+// cognitive tests
+type PerfSummary struct {
+	LongTasks  int     `json:"long_tasks"`
+	LongTaskMs float64 `json:"long_task_ms"`
+	MaxINPMs   float64 `json:"max_inp_ms"`
+	LCPMs      float64 `json:"lcp_ms,omitempty"` // Page load, for context
+}
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// Legal document types users must accept
+const (
+	PolicyTerms   = "terms"
+	PolicyPrivacy = "privacy"
+)
+
+// PolicyAcceptance records a user accepting a specific version of a legal document
+type PolicyAcceptance struct {
+	ID           uint      `json:"id" gorm:"primaryKey"`
+	UserEmail    string    `json:"user_email" gorm:"index"`
+	DocumentType string    `json:"document_type" gorm:"type:varchar(20);not null"`
+	Version      string    `json:"version" gorm:"not null"`
+	AcceptedAt   time.Time `json:"accepted_at"`
+	IPAddress    string    `json:"ip_address,omitempty"`
+	UserAgent    string    `json:"user_agent,omitempty"`
+}
@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+// Question navigation events
+const (
+	QuestionEventStart  = "start"  // Form opened on its first question
+	QuestionEventNext   = "next"   // Question answered, moving forward
+	QuestionEventPrev   = "prev"   // Moved back from a question
+	QuestionEventSubmit = "submit" // Form submitted
+)
+
+// QuestionEvent records one step through a form. It carries no user identity,
+// only the form it belongs to, so it can be aggregated across organizations.
+type QuestionEvent struct {
+	ID              uint      `json:"id" gorm:"primaryKey"`
+	FormStateID     string    `json:"form_state_id" gorm:"type:varchar(36);not null;index"`
+	Event           string    `json:"event" gorm:"type:varchar(10);not null"`
+	QuestionID      string    `json:"question_id"`       // Question being left, empty for start and submit
+	ShownQuestionID string    `json:"shown_question_id"` // Question shown afterwards, empty once all are answered
+	DurationMs      int64     `json:"duration_ms"`       // Time spent on the question being left
+	CreatedAt       time.Time `json:"created_at" gorm:"index"`
+}
+
+// QuestionAnalytics is one question's aggregated navigation for an assessment day
+type QuestionAnalytics struct {
+	ID              uint      `json:"id" gorm:"primaryKey"`
+	Day             time.Time `json:"day" gorm:"type:date;not null;uniqueIndex:idx_question_analytics_day"`
+	QuestionID      string    `json:"question_id" gorm:"not null;uniqueIndex:idx_question_analytics_day"`
+	Views           int64     `json:"views"`            // Times the question was shown
+	Answers         int64     `json:"answers"`          // Times it was answered moving forward
+	BackNavigations int64     `json:"back_navigations"` // Times users went back from it
+	Abandonments    int64     `json:"abandonments"`     // Forms left unfinished on it
+	AvgSeconds      float64   `json:"avg_seconds"`      // Average time on the question
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// TableName keeps the analytics table name singular, as it is a report
+func (QuestionAnalytics) TableName() string {
+	return "question_analytics"
+}
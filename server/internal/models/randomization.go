@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+// Randomization methods
+const (
+	RandomizationSimple     = "simple"
+	RandomizationBlock      = "block"
+	RandomizationStratified = "stratified"
+)
+
+// RandomizationScheme configures how a study's participants are allocated to arms
+type RandomizationScheme struct {
+	StudyID   uint   `json:"study_id" gorm:"primaryKey"`
+	Method    string `json:"method" gorm:"type:varchar(20);not null"`
+	Arms      string `json:"arms" gorm:"type:jsonb;not null"` // JSON array of arm codes
+	BlockSize int    `json:"block_size"`
+	// Seed makes allocation sequences reproducible for audit
+	Seed int64 `json:"seed"`
+	// BlindArms hides allocations from blinded reviewers
+	BlindArms bool      `json:"blind_arms" gorm:"default:true"`
+	CreatedBy string    `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ArmAllocation records one participant's assignment to a study arm
+type ArmAllocation struct {
+	ID        uint   `json:"id" gorm:"primaryKey"`
+	StudyID   uint   `json:"study_id" gorm:"not null;uniqueIndex:idx_study_participant;index:idx_study_stratum"`
+	UserEmail string `json:"user_email" gorm:"not null;uniqueIndex:idx_study_participant"`
+	Arm       string `json:"arm" gorm:"type:varchar(64);not null"`
+	Method    string `json:"method" gorm:"type:varchar(20);not null"`
+	// Stratum is the age band and sex for stratified allocation, empty otherwise
+	Stratum string `json:"stratum,omitempty" gorm:"type:varchar(40);index:idx_study_stratum"`
+	Age     *int   `json:"age,omitempty"`
+	Sex     string `json:"sex,omitempty" gorm:"type:varchar(10)"`
+	// Sequence is the allocation's position within its stratum
+	Sequence    int       `json:"sequence"`
+	Seed        int64     `json:"seed"`
+	AllocatedBy string    `json:"allocated_by"`
+	CreatedAt   time.Time `json:"created_at"`
+}
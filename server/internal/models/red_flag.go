@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// RedFlagAlert is raised the moment a user submits an answer at a question's
+// red-flag level. It uses the same open and acknowledged statuses as
+// symptom flags.
+type RedFlagAlert struct {
+	ID             uint       `json:"id" gorm:"primaryKey"`
+	UserEmail      string     `json:"user_email" gorm:"not null;index"`
+	OrganizationID string     `json:"organization_id" gorm:"type:varchar(64);index"`
+	AssessmentID   uint       `json:"assessment_id" gorm:"not null;index"`
+	QuestionID     string     `json:"question_id" gorm:"type:varchar(100);not null"`
+	QuestionTitle  string     `json:"question_title"`
+	Value          float64    `json:"value"`
+	Status         string     `json:"status" gorm:"type:varchar(20);not null;index"`
+	NotifiedTo     string     `json:"notified_to,omitempty"` // Comma-separated recipients the dispatcher reached
+	NotifiedAt     *time.Time `json:"notified_at,omitempty"`
+	AcknowledgedBy string     `json:"acknowledged_by,omitempty"`
+	AcknowledgedAt *time.Time `json:"acknowledged_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
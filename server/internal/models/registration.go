@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// Sign-up attempt outcomes
+const (
+	SignupAccepted = "accepted"
+	SignupRejected = "rejected"
+)
+
+// SignupAttempt records a registration attempt for abuse detection
+type SignupAttempt struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Email     string    `json:"email"`
+	IPAddress string    `json:"ip_address" gorm:"index"`
+	Outcome   string    `json:"outcome" gorm:"type:varchar(20);not null"`
+	Reason    string    `json:"reason,omitempty" gorm:"type:varchar(40)"`
+	CreatedAt time.Time `json:"created_at" gorm:"index"`
+}
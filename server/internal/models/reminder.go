@@ -0,0 +1,116 @@
+package models
+
+import "time"
+
+// ReminderSent records a reminder dispatched to a user, so the same reminder
+// is never sent twice for one assessment day and time slot
+type ReminderSent struct {
+	ID            uint      `json:"id" gorm:"primaryKey"`
+	UserEmail     string    `json:"user_email" gorm:"not null;uniqueIndex:idx_reminders_sent_slot"`
+	Channel       string    `json:"channel" gorm:"type:varchar(10);not null;uniqueIndex:idx_reminders_sent_slot"`
+	AssessmentDay time.Time `json:"assessment_day" gorm:"type:date;not null;uniqueIndex:idx_reminders_sent_slot"`
+	TimeSlot      string    `json:"time_slot" gorm:"type:varchar(5);not null;uniqueIndex:idx_reminders_sent_slot"` // HH:MM
+	SentAt        time.Time `json:"sent_at"`
+}
+
+// TableName keeps the ledger's table name readable
+func (ReminderSent) TableName() string {
+	return "reminders_sent"
+}
+
+// ReminderRun records the outcome of one reminder dispatch for a channel and
+// time slot
+type ReminderRun struct {
+	ID            uint      `json:"id" gorm:"primaryKey"`
+	Channel       string    `json:"channel" gorm:"type:varchar(10);not null;index"`
+	AssessmentDay time.Time `json:"assessment_day" gorm:"type:date;not null;index"`
+	TimeSlot      string    `json:"time_slot" gorm:"type:varchar(5);not null"`  // HH:MM
+	Timezone      string    `json:"timezone,omitempty" gorm:"type:varchar(64)"` // Participants' zone, empty for the assessment zone
+	Eligible      int       `json:"eligible"`
+	Sent          int       `json:"sent"`
+	Failed        int       `json:"failed"`
+	Pruned        int       `json:"pruned"`  // Expired subscriptions removed
+	Skipped       int       `json:"skipped"` // Already completed or already sent
+	StartedAt     time.Time `json:"started_at"`
+	DurationMs    int64     `json:"duration_ms"`
+}
+
+// ReminderOverride holds a user's reminder changes for a single assessment
+// day: skipping the rest of the day's reminders, or holding them back until
+// a snooze ends
+type ReminderOverride struct {
+	ID            uint       `json:"id" gorm:"primaryKey"`
+	UserEmail     string     `json:"user_email" gorm:"not null;uniqueIndex:idx_reminder_overrides_day"`
+	AssessmentDay time.Time  `json:"assessment_day" gorm:"type:date;not null;uniqueIndex:idx_reminder_overrides_day"`
+	SkipDay       bool       `json:"skip_day"`
+	SnoozedUntil  *time.Time `json:"snoozed_until,omitempty" gorm:"index"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// Suppresses reports whether the override holds back reminders at a given time
+func (o *ReminderOverride) Suppresses(at time.Time) bool {
+	if o == nil {
+		return false
+	}
+	return o.SkipDay || (o.SnoozedUntil != nil && at.Before(*o.SnoozedUntil))
+}
+
+// Admin reminder job statuses
+const (
+	ReminderJobScheduled = "scheduled"
+	ReminderJobRunning   = "running"
+	ReminderJobDone      = "done"
+)
+
+// Admin reminder delivery statuses
+const (
+	ReminderDeliveryPending = "pending"
+	ReminderDeliverySent    = "sent"
+	ReminderDeliveryFailed  = "failed"
+	ReminderDeliverySkipped = "skipped" // Push turned off, or the day's assessment already done
+)
+
+// ReminderJob is an admin-initiated reminder to one or more users, sent
+// through the chosen channels now or at a scheduled time
+type ReminderJob struct {
+	ID             string     `json:"id" gorm:"primaryKey"`
+	CreatedBy      string     `json:"created_by" gorm:"index"`
+	OrganizationID string     `json:"organization_id,omitempty" gorm:"type:varchar(64)"` // Scope of the admin who created it
+	Channels       string     `json:"channels" gorm:"not null"`                          // Separated by commas
+	SkipCompleted  bool       `json:"skip_completed" gorm:"default:false"`               // Skip users who have done the day's assessment by send time
+	SendAt         time.Time  `json:"send_at" gorm:"index"`
+	Status         string     `json:"status" gorm:"type:varchar(10);not null;index"`
+	Recipients     int        `json:"recipients"`
+	Sent           int        `json:"sent"`
+	Failed         int        `json:"failed"`
+	Skipped        int        `json:"skipped"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	CompletedAt    *time.Time `json:"completed_at,omitempty"`
+}
+
+// ReminderDelivery is one reminder job's delivery to one user on one channel
+type ReminderDelivery struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	JobID     string     `json:"-" gorm:"not null;index"`
+	UserEmail string     `json:"user_email" gorm:"not null"`
+	Channel   string     `json:"channel" gorm:"type:varchar(10);not null"`
+	Status    string     `json:"status" gorm:"type:varchar(10);not null"`
+	Error     string     `json:"error,omitempty"`
+	SentAt    *time.Time `json:"sent_at,omitempty"`
+}
+
+// ReminderChannelInApp is the reminder shown inside the app while the day's
+// assessment is still to do
+const ReminderChannelInApp = "in_app"
+
+// ReminderTemplate overrides a study's reminder copy on one channel. The
+// subject and body may use the reminder template variables.
+type ReminderTemplate struct {
+	StudyID   uint      `json:"study_id" gorm:"primaryKey"`
+	Channel   string    `json:"channel" gorm:"primaryKey;type:varchar(10)"`
+	Subject   string    `json:"subject"` // Email subject or notification title
+	Body      string    `json:"body" gorm:"type:text;not null"`
+	UpdatedBy string    `json:"updated_by"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// QuestionResponseRevision keeps an answer's previous value each time the
+// user amends it, so the original answer is never lost
+type QuestionResponseRevision struct {
+	ID           uint      `json:"id" gorm:"primaryKey"`
+	ResponseID   uint      `json:"response_id" gorm:"index"`
+	AssessmentID uint      `json:"assessment_id" gorm:"index"`
+	QuestionID   string    `json:"question_id"`
+	Revision     int       `json:"revision"` // The response's revision number this value was replaced at
+	ValueType    string    `json:"value_type"`
+	NumericValue float64   `json:"numeric_value"`
+	TextValue    string    `json:"text_value"`
+	AnsweredAt   time.Time `json:"answered_at"` // When the previous value was given
+	RevisedAt    time.Time `json:"revised_at" gorm:"index"`
+	RevisedBy    string    `json:"revised_by"`
+}
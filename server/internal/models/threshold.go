@@ -0,0 +1,100 @@
+package models
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Threshold comparison operators
+const (
+	ThresholdAtLeast = "gte"
+	ThresholdAbove   = "gt"
+	ThresholdAtMost  = "lte"
+	ThresholdBelow   = "lt"
+)
+
+// Symptom flag statuses
+const (
+	FlagOpen         = "open"
+	FlagAcknowledged = "acknowledged"
+)
+
+// SymptomThreshold flags users whose answer to a question meets the
+// condition on enough consecutive assessment days, such as headache >= 3 for
+// 3 days in a row
+type SymptomThreshold struct {
+	ID              uint      `json:"id" gorm:"primaryKey"`
+	OrganizationID  string    `json:"organization_id" gorm:"type:varchar(64);index"` // Empty applies to every organization
+	Name            string    `json:"name" gorm:"type:varchar(200)"`
+	QuestionID      string    `json:"question_id" gorm:"type:varchar(100);not null;index"`
+	Operator        string    `json:"operator" gorm:"type:varchar(3);not null"`
+	Value           float64   `json:"value"`
+	ConsecutiveDays int       `json:"consecutive_days" gorm:"not null;default:1"`
+	Enabled         bool      `json:"enabled"`
+	CreatedBy       string    `json:"created_by"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// Matches reports whether an answer meets the threshold condition
+func (t *SymptomThreshold) Matches(value float64) bool {
+	switch t.Operator {
+	case ThresholdAtLeast:
+		return value >= t.Value
+	case ThresholdAbove:
+		return value > t.Value
+	case ThresholdAtMost:
+		return value <= t.Value
+	case ThresholdBelow:
+		return value < t.Value
+	}
+	return false
+}
+
+// thresholdSymbols are the operators as shown to admins
+var thresholdSymbols = map[string]string{
+	ThresholdAtLeast: "≥",
+	ThresholdAbove:   ">",
+	ThresholdAtMost:  "≤",
+	ThresholdBelow:   "<",
+}
+
+// Describe returns the threshold's name, or its condition when unnamed
+func (t *SymptomThreshold) Describe() string {
+	if t.Name != "" {
+		return t.Name
+	}
+	return fmt.Sprintf("%s %s %s for %d day(s)", t.QuestionID, thresholdSymbols[t.Operator],
+		strconv.FormatFloat(t.Value, 'f', -1, 64), t.ConsecutiveDays)
+}
+
+// Further returns whichever value is further past the threshold
+func (t *SymptomThreshold) Further(a, b float64) float64 {
+	if t.Operator == ThresholdAtMost || t.Operator == ThresholdBelow {
+		return min(a, b)
+	}
+	return max(a, b)
+}
+
+// SymptomFlag is an alert raised when a user's answers crossed a threshold.
+// One flag covers a run of consecutive matching days and grows while the
+// run continues.
+type SymptomFlag struct {
+	ID             uint       `json:"id" gorm:"primaryKey"`
+	ThresholdID    uint       `json:"threshold_id" gorm:"not null;index"`
+	UserEmail      string     `json:"user_email" gorm:"not null;index"`
+	OrganizationID string     `json:"organization_id" gorm:"type:varchar(64);index"`
+	QuestionID     string     `json:"question_id" gorm:"type:varchar(100);not null"`
+	StartDay       time.Time  `json:"start_day" gorm:"type:date;not null"`
+	EndDay         time.Time  `json:"end_day" gorm:"type:date;not null"`
+	Days           int        `json:"days"`
+	PeakValue      float64    `json:"peak_value"` // Furthest value past the threshold during the run
+	Status         string     `json:"status" gorm:"type:varchar(20);not null;index"`
+	AcknowledgedBy string     `json:"acknowledged_by,omitempty"`
+	AcknowledgedAt *time.Time `json:"acknowledged_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+
+	Threshold SymptomThreshold `json:"threshold" gorm:"foreignKey:ThresholdID"`
+}
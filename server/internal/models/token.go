@@ -12,8 +12,14 @@ type RefreshToken struct {
 	DeviceID  string     `json:"device_id" gorm:"index"`
 	TokenID   string     `json:"token_id" gorm:"index"` // JWT ID reference
 	ExpiresAt time.Time  `json:"expires_at"`
-	CreatedAt time.Time  `json:"created_at"`
+	CreatedAt time.Time  `json:"created_at"` // Also when the session was last refreshed
 	RevokedAt *time.Time `json:"revoked_at"`
+
+	// When the user logged in, carried over each time the token is refreshed
+	SessionStartedAt time.Time `json:"session_started_at" gorm:"index"`
+	// Ephemeral sessions, logged in without "remember me", use browser
+	// session cookies and a short refresh expiration
+	Ephemeral bool `json:"ephemeral" gorm:"default:false"`
 }
 
 // RevokedToken represents a revoked JWT token
@@ -26,9 +32,33 @@ type RevokedToken struct {
 
 // PasswordResetToken represents a password reset token
 type PasswordResetToken struct {
-	Token     string     `json:"token" gorm:"primaryKey"`
+	Token        string     `json:"token" gorm:"primaryKey"`
+	UserEmail    string     `json:"user_email" gorm:"index"`
+	CodeHash     string     `json:"-"` // SHA-256 of the optional numeric code
+	CodeAttempts int        `json:"-" gorm:"default:0"`
+	ExpiresAt    time.Time  `json:"expires_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UsedAt       *time.Time `json:"used_at"`
+}
+
+// ReactivationToken is emailed to a paused user to resume their account
+type ReactivationToken struct {
+	Token     string     `json:"-" gorm:"primaryKey"`
 	UserEmail string     `json:"user_email" gorm:"index"`
 	ExpiresAt time.Time  `json:"expires_at"`
 	CreatedAt time.Time  `json:"created_at"`
 	UsedAt    *time.Time `json:"used_at"`
 }
+
+// LoginChallenge holds a suspicious login until the user confirms it by email
+type LoginChallenge struct {
+	Token       string     `json:"-" gorm:"primaryKey"`
+	UserEmail   string     `json:"user_email" gorm:"index"`
+	DeviceInfo  string     `json:"-" gorm:"type:jsonb"` // Device info submitted with the login
+	IPAddress   string     `json:"ip_address"`
+	Reasons     string     `json:"reasons"`                // Comma-separated detection reasons
+	RememberMe  bool       `json:"-" gorm:"default:false"` // Session persistence asked for at login
+	ExpiresAt   time.Time  `json:"expires_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	ConfirmedAt *time.Time `json:"confirmed_at"`
+}
@@ -17,8 +17,56 @@ type User struct {
 	PushSubscription        string    `json:"push_subscription,omitempty" gorm:"type:text"`
 	NotificationPreferences string    `json:"notification_preferences,omitempty" gorm:"type:jsonb"`
 	LastAssessmentDate      time.Time `json:"last_assessment_date,omitempty"`
+	// IANA time zone last detected on the user's devices. Their assessment
+	// days and reminder times follow it; empty uses the deployment's zone.
+	Timezone string `json:"timezone,omitempty" gorm:"type:varchar(64)"`
+
+	// Display and timing needs, also applied to the cognitive tests served
+	Accessibility AccessibilityPreferences `json:"accessibility" gorm:"embedded"`
+
+	// Tenancy
+	OrganizationID string `json:"organization_id" gorm:"type:varchar(64);index"`
+	IsOrgAdmin     bool   `json:"is_org_admin" gorm:"default:false"` // Administers their own organization only
+	// Read-only trial reviewer who sees adherence and data quality with symptom values masked
+	IsBlindedReviewer bool `json:"is_blinded_reviewer" gorm:"default:false"`
+	// Clinician notified first about the user's red-flag answers
+	ClinicianEmail string `json:"clinician_email,omitempty"`
+
+	// Public demo sign-up with a synthetic history, deleted every night
+	Sandbox bool `json:"sandbox,omitempty" gorm:"default:false;index"`
+
+	// Inactivity lifecycle
+	StudyID            string     `json:"study_id,omitempty" gorm:"index"`
+	LifecycleExempt    bool       `json:"lifecycle_exempt" gorm:"default:false"` // Admin override: never flag or purge
+	InactiveSince      *time.Time `json:"inactive_since,omitempty"`
+	ReengagementSentAt *time.Time `json:"reengagement_sent_at,omitempty"`
+	AnonymizedAt       *time.Time `json:"anonymized_at,omitempty"`
+
+	// Retention overrides set by admins. A legal hold keeps the account and
+	// its data from every purge and anonymization. A retention period shorter
+	// than the study's honors a user's request to have their data removed
+	// sooner, even if they are exempt, paused or withdrawn.
+	LegalHold       bool   `json:"legal_hold" gorm:"default:false;index"`
+	RetentionDays   int    `json:"retention_days,omitempty"`   // Days of inactivity before RetentionAction
+	RetentionAction string `json:"retention_action,omitempty"` // "anonymize" or "purge"
+
+	// Self-service pause: reminders stop and logins are held until the user
+	// reactivates from an emailed link. Their data is kept.
+	DeactivatedAt *time.Time `json:"deactivated_at,omitempty" gorm:"index"`
+	ReactivatedAt *time.Time `json:"reactivated_at,omitempty"`
+
+	// Withdrawal from the enrolled study: reminders stop and no further
+	// assessments are accepted. Cleared when the user joins another study.
+	WithdrawnAt *time.Time `json:"withdrawn_at,omitempty" gorm:"index"`
 
 	// Relationships
 	Devices     []Device     `json:"devices,omitempty" gorm:"foreignKey:UserEmail"`
 	Assessments []Assessment `json:"assessments,omitempty" gorm:"foreignKey:UserEmail"`
 }
+
+// AccessibilityPreferences are a user's display and timing needs
+type AccessibilityPreferences struct {
+	LargeText        bool `json:"large_text" gorm:"default:false"`
+	ReducedMotion    bool `json:"reduced_motion" gorm:"default:false"`
+	ExtendedTimeouts bool `json:"extended_timeouts" gorm:"default:false"` // Longer stimuli and time limits in cognitive tests
+}
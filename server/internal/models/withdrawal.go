@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// What a study does with a participant's collected data when they withdraw
+const (
+	WithdrawalDataRetain = "retain" // Consent covers keeping it
+	WithdrawalDataChoice = "choice" // The participant decides when withdrawing
+	WithdrawalDataDelete = "delete" // Always removed
+)
+
+// StudyWithdrawal records a participant leaving their study. The account
+// stays open, unlike account deletion.
+type StudyWithdrawal struct {
+	ID             uint      `json:"id" gorm:"primaryKey"`
+	UserEmail      string    `json:"user_email" gorm:"not null;index"`
+	OrganizationID string    `json:"organization_id" gorm:"type:varchar(64);index"`
+	StudyID        string    `json:"study_id"` // Study slug, as on the user
+	Reason         string    `json:"reason,omitempty" gorm:"type:text"`
+	DataDeleted    bool      `json:"data_deleted" gorm:"default:false"`
+	WithdrawnAt    time.Time `json:"withdrawn_at"`
+}
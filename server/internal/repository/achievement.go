@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/andevellicus/crapp/internal/models"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// AchievementRepository handles the badges and milestones users earn
+type AchievementRepository struct {
+	db  *gorm.DB
+	log *zap.SugaredLogger
+}
+
+// NewAchievementRepository creates a new achievement repository
+func NewAchievementRepository(db *gorm.DB, log *zap.SugaredLogger) *AchievementRepository {
+	return &AchievementRepository{
+		db:  db,
+		log: log.Named("achievement-repo"),
+	}
+}
+
+// Award records an achievement for a user. Returns whether it is new; an
+// achievement already earned keeps its original award.
+func (r *AchievementRepository) Award(email, key string, assessmentID uint) (bool, error) {
+	achievement := &models.Achievement{
+		UserEmail:    strings.ToLower(email),
+		Key:          key,
+		AssessmentID: assessmentID,
+		AwardedAt:    time.Now(),
+	}
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(achievement)
+	if result.Error != nil {
+		r.log.Errorw("Database error awarding achievement", "error", result.Error, "key", key)
+		return false, fmt.Errorf("failed to award achievement: %w", result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// ListForUser returns a user's achievements, oldest first
+func (r *AchievementRepository) ListForUser(email string) ([]models.Achievement, error) {
+	achievements := []models.Achievement{}
+	err := r.db.Where("user_email = ?", strings.ToLower(email)).
+		Order("awarded_at, id").
+		Find(&achievements).Error
+	return achievements, err
+}
@@ -6,21 +6,31 @@ import (
 	"time"
 
 	"github.com/andevellicus/crapp/internal/models"
+	"github.com/andevellicus/crapp/internal/utils"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
 )
 
 // TimelineDataPoint represents a single point in a metrics timeline
 type TimelineDataPoint struct {
-	Date         time.Time `json:"date"`
-	SymptomValue float64   `json:"symptom_value"`
-	MetricValue  float64   `json:"metric_value"`
+	Date            time.Time `json:"date"`
+	SymptomValue    float64   `json:"symptom_value"`
+	MetricValue     float64   `json:"metric_value"`
+	IsRetrospective bool      `json:"is_retrospective"`
+
+	// Set on bucketed points: how many points they summarize and their range
+	Count      int     `json:"count,omitempty"`
+	SymptomMin float64 `json:"symptom_min,omitempty"`
+	SymptomMax float64 `json:"symptom_max,omitempty"`
+	MetricMin  float64 `json:"metric_min,omitempty"`
+	MetricMax  float64 `json:"metric_max,omitempty"`
 }
 
 // CorrelationDataPoint represents a single point for correlation analysis
 type CorrelationDataPoint struct {
 	SymptomValue float64 `json:"symptom_value"`
 	MetricValue  float64 `json:"metric_value"`
+	Imputed      bool    `json:"imputed,omitempty" gorm:"-"` // Symptom value estimated, not reported
 }
 
 // UserRepository extends the generic repository with user-specific methods
@@ -28,14 +38,16 @@ type AssessmentRepository struct {
 	db       *gorm.DB
 	log      *zap.SugaredLogger
 	userRepo *UserRepository
+	days     utils.AssessmentDay
 }
 
 // NewAssessmentRepository creates a new assessment repository
-func NewAssessmentRepository(db *gorm.DB, log *zap.SugaredLogger, userRepo *UserRepository) *AssessmentRepository {
+func NewAssessmentRepository(db *gorm.DB, log *zap.SugaredLogger, userRepo *UserRepository, days utils.AssessmentDay) *AssessmentRepository {
 	return &AssessmentRepository{
 		db:       db,
 		log:      log.Named("assessment-repo"),
 		userRepo: userRepo,
+		days:     days,
 	}
 }
 
@@ -83,10 +95,289 @@ func (r *AssessmentRepository) Create(email string, deviceID string) (uint, erro
 	return assessment.ID, nil
 }
 
+// HasAssessmentForDate checks whether the user already has an assessment covering the given day
+func (r *AssessmentRepository) HasAssessmentForDate(email string, day time.Time) (bool, error) {
+	normalizedEmail := strings.ToLower(email)
+	dayStr := day.Format("2006-01-02")
+
+	var count int64
+	err := r.db.Model(&models.Assessment{}).
+		Where("LOWER(user_email) = ?", normalizedEmail).
+		Where("assessment_date = ? OR (assessment_date IS NULL AND "+r.days.SQL("submitted_at")+" = ?)", dayStr, dayStr).
+		Count(&count).Error
+	if err != nil {
+		r.log.Errorw("Error checking assessment for date", "error", err, "date", dayStr)
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// CountByUser counts a user's submitted assessments
+func (r *AssessmentRepository) CountByUser(email string) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.Assessment{}).
+		Where("LOWER(user_email) = ?", strings.ToLower(email)).
+		Count(&count).Error
+	if err != nil {
+		r.log.Errorw("Error counting user assessments", "error", err)
+		return 0, err
+	}
+	return count, nil
+}
+
+// CognitiveTestsCompleted returns the question types of the cognitive tests
+// with results stored for an assessment
+func (r *AssessmentRepository) CognitiveTestsCompleted(assessmentID uint) (map[string]bool, error) {
+	completed := make(map[string]bool)
+	for questionType, table := range cognitiveResultTables {
+		var count int64
+		if err := r.db.Table(table).Where("assessment_id = ?", assessmentID).Count(&count).Error; err != nil {
+			r.log.Errorw("Error checking cognitive test results", "error", err, "table", table)
+			return nil, err
+		}
+		if count > 0 {
+			completed[questionType] = true
+		}
+	}
+	return completed, nil
+}
+
+// maxStreakDays bounds how far back a streak is counted
+const maxStreakDays = 366
+
+// StreakDays counts the consecutive assessment days a user completed up to a
+// day. If the day itself has no assessment yet, the streak ending the day
+// before is counted. Retrospective entries don't count.
+func (r *AssessmentRepository) StreakDays(email string, day time.Time) (int, error) {
+	assessmentDay := r.assessmentDaySQL()
+	var days []time.Time
+	err := r.db.Table("assessments a").
+		Distinct(assessmentDay+" AS day").
+		Where("LOWER(a.user_email) = ? AND NOT a.is_retrospective", strings.ToLower(email)).
+		Where(assessmentDay+" <= ?", day.Format("2006-01-02")).
+		Order("day DESC").
+		Limit(maxStreakDays).
+		Pluck("day", &days).Error
+	if err != nil {
+		r.log.Errorw("Error loading assessment days for streak", "error", err)
+		return 0, fmt.Errorf("database error: %w", err)
+	}
+
+	// Dates come back at UTC midnight; compare them as calendar dates
+	expected := day
+	if len(days) > 0 && days[0].Format("2006-01-02") != day.Format("2006-01-02") {
+		expected = day.AddDate(0, 0, -1)
+	}
+	streak := 0
+	for _, d := range days {
+		if d.Format("2006-01-02") != expected.Format("2006-01-02") {
+			break
+		}
+		streak++
+		expected = expected.AddDate(0, 0, -1)
+	}
+	return streak, nil
+}
+
+// cognitiveResultTables holds each cognitive test's results, by question type
+var cognitiveResultTables = map[string]string{
+	"cpt":        "cpt_results",
+	"tmt":        "tmt_results",
+	"digit_span": "digit_span_results",
+}
+
+// LastCompletedDays returns the latest assessment day on which the user
+// completed each question, keyed by question ID. The map gives each
+// question's type: cognitive tests count once their results were saved, and
+// other questions once they were answered. Questions never completed are left
+// out.
+func (r *AssessmentRepository) LastCompletedDays(email string, questionTypes map[string]string) (map[string]time.Time, error) {
+	normalizedEmail := strings.ToLower(email)
+	day := r.assessmentDaySQL()
+	result := make(map[string]time.Time)
+
+	var answered []string
+	for id, questionType := range questionTypes {
+		table, isTest := cognitiveResultTables[questionType]
+		if !isTest {
+			answered = append(answered, id)
+			continue
+		}
+		var last *time.Time
+		err := r.db.Table(table+" t").
+			Select("MAX("+day+")").
+			Joins("JOIN assessments a ON a.id = t.assessment_id").
+			Where("LOWER(a.user_email) = ?", normalizedEmail).
+			Scan(&last).Error
+		if err != nil {
+			r.log.Errorw("Error finding last cognitive test", "error", err, "table", table)
+			return nil, err
+		}
+		if last != nil {
+			result[id] = *last
+		}
+	}
+
+	if len(answered) > 0 {
+		var rows []struct {
+			QuestionID string
+			Day        time.Time
+		}
+		err := r.db.Table("question_responses qr").
+			Select("qr.question_id, MAX("+day+") AS day").
+			Joins("JOIN assessments a ON a.id = qr.assessment_id").
+			Where("LOWER(a.user_email) = ? AND qr.question_id IN ?", normalizedEmail, answered).
+			Group("qr.question_id").
+			Scan(&rows).Error
+		if err != nil {
+			r.log.Errorw("Error finding last answered questions", "error", err)
+			return nil, err
+		}
+		for _, row := range rows {
+			result[row.QuestionID] = row.Day
+		}
+	}
+	return result, nil
+}
+
+// assessmentDaySQL is the assessment day of the assessment aliased a. Rows
+// from before assessment days were stored use their submission time.
+func (r *AssessmentRepository) assessmentDaySQL() string {
+	return "COALESCE(a.assessment_day, " + r.days.SQL("a.submitted_at") + ")"
+}
+
+// AnswerStability summarizes a user's recent answers for adaptive forms
+type AnswerStability struct {
+	Days     int                // Distinct assessment days in the window
+	Spread   map[string]float64 // Largest minus smallest numeric answer, by question ID
+	LastFull *time.Time         // Latest day a full form was submitted, at any time
+}
+
+// GetAnswerStability summarizes the user's assessments from the day since
+// onwards: how many days they cover and how much each question's numeric
+// answers varied. Assessments from before adaptive mode count as full forms.
+func (r *AssessmentRepository) GetAnswerStability(email string, since time.Time, questionIDs []string) (*AnswerStability, error) {
+	normalizedEmail := strings.ToLower(email)
+	day := r.assessmentDaySQL()
+	sinceDate := since.Format("2006-01-02")
+	result := &AnswerStability{Spread: make(map[string]float64)}
+
+	var days int64
+	err := r.db.Table("assessments a").
+		Select("COUNT(DISTINCT "+day+")").
+		Where("LOWER(a.user_email) = ? AND "+day+" >= ?", normalizedEmail, sinceDate).
+		Scan(&days).Error
+	if err != nil {
+		r.log.Errorw("Error counting recent assessment days", "error", err)
+		return nil, err
+	}
+	result.Days = int(days)
+
+	err = r.db.Table("assessments a").
+		Select("MAX("+day+")").
+		Where("LOWER(a.user_email) = ? AND COALESCE(a.form_variant, '') <> 'short'", normalizedEmail).
+		Scan(&result.LastFull).Error
+	if err != nil {
+		r.log.Errorw("Error finding last full assessment", "error", err)
+		return nil, err
+	}
+
+	if len(questionIDs) > 0 {
+		var rows []struct {
+			QuestionID string
+			Spread     float64
+		}
+		err = r.db.Table("question_responses qr").
+			Select("qr.question_id, MAX(qr.numeric_value) - MIN(qr.numeric_value) AS spread").
+			Joins("JOIN assessments a ON a.id = qr.assessment_id").
+			Where("LOWER(a.user_email) = ? AND "+day+" >= ?", normalizedEmail, sinceDate).
+			Where("qr.question_id IN ? AND qr.value_type = ?", questionIDs, "number").
+			Group("qr.question_id").
+			Scan(&rows).Error
+		if err != nil {
+			r.log.Errorw("Error measuring answer stability", "error", err)
+			return nil, err
+		}
+		for _, row := range rows {
+			result.Spread[row.QuestionID] = row.Spread
+		}
+	}
+	return result, nil
+}
+
+// GetByUser lists all of a user's assessments, oldest first
+func (r *AssessmentRepository) GetByUser(email string) ([]models.Assessment, error) {
+	normalizedEmail := strings.ToLower(email)
+	assessments := []models.Assessment{}
+	err := r.db.Where("LOWER(user_email) = ?", normalizedEmail).
+		Order("submitted_at ASC").
+		Find(&assessments).Error
+	if err != nil {
+		r.log.Errorw("Error listing user assessments", "error", err, "email", normalizedEmail)
+		return nil, err
+	}
+	return assessments, nil
+}
+
+// ChartVersion returns a value that changes whenever the user's assessments
+// or their answers do, so cached chart responses can be validated without
+// rebuilding them
+func (r *AssessmentRepository) ChartVersion(email string) (string, error) {
+	var row struct {
+		Count  int64
+		Latest *time.Time
+	}
+	err := r.db.Model(&models.Assessment{}).
+		Select("COUNT(*) AS count, MAX(COALESCE(revised_at, submitted_at)) AS latest").
+		Where("LOWER(user_email) = ?", strings.ToLower(email)).
+		Scan(&row).Error
+	if err != nil {
+		r.log.Errorw("Error reading chart version", "error", err)
+		return "", err
+	}
+
+	version := fmt.Sprintf("%d", row.Count)
+	if row.Latest != nil {
+		version += "-" + row.Latest.UTC().Format(time.RFC3339Nano)
+	}
+	return version, nil
+}
+
+// GetPreviousResponses returns the user's most recent answer to each of the
+// given questions, ignoring one assessment (the one being submitted, or 0)
+func (r *AssessmentRepository) GetPreviousResponses(email string, questionIDs []string, excludeAssessmentID uint) (map[string]models.QuestionResponse, error) {
+	previous := make(map[string]models.QuestionResponse)
+	if len(questionIDs) == 0 {
+		return previous, nil
+	}
+
+	var responses []models.QuestionResponse
+	err := r.db.Raw(`
+		SELECT DISTINCT ON (qr.question_id) qr.*
+		FROM question_responses qr
+		JOIN assessments a ON a.id = qr.assessment_id
+		WHERE LOWER(a.user_email) = ?
+			AND qr.question_id IN ?
+			AND a.id <> ?
+		ORDER BY qr.question_id, a.submitted_at DESC`,
+		strings.ToLower(email), questionIDs, excludeAssessmentID).
+		Scan(&responses).Error
+	if err != nil {
+		r.log.Errorw("Error getting previous responses", "error", err)
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+
+	for _, response := range responses {
+		previous[response.QuestionID] = response
+	}
+	return previous, nil
+}
+
 // GetMetricsCorrelation gets correlation data from structured tables
-func (r *AssessmentRepository) GetMetricsCorrelation(userID, symptomKey, metricKey string) (*[]CorrelationDataPoint, error) {
+func (r *AssessmentRepository) GetMetricsCorrelation(userID, symptomKey, metricKey string, filter ChartFilter) (*[]CorrelationDataPoint, error) {
 	var result []CorrelationDataPoint
 
+	conditions, filterArgs := filter.assessmentSQL("a", r.days)
 	query := `
 		SELECT 
 			qr.numeric_value as symptom_value,
@@ -96,12 +387,12 @@ func (r *AssessmentRepository) GetMetricsCorrelation(userID, symptomKey, metricK
 			JOIN question_responses qr ON a.id = qr.assessment_id
 			JOIN assessment_metrics am ON a.id = am.assessment_id AND am.question_id = qr.question_id
 		WHERE 
-			LOWER(a.user_email) = $1
-			AND qr.question_id = $2
-			AND am.metric_key = $3
-    `
+			LOWER(a.user_email) = ?
+			AND qr.question_id = ?
+			AND am.metric_key = ?` + conditions
 
-	err := r.db.Raw(query, userID, symptomKey, metricKey).Scan(&result).Error
+	args := append([]any{userID, symptomKey, metricKey}, filterArgs...)
+	err := r.db.Raw(query, args...).Scan(&result).Error
 	if err != nil {
 		r.log.Errorw("Error in correlation query", "error", err)
 		return nil, fmt.Errorf("database error: %w", err)
@@ -110,29 +401,62 @@ func (r *AssessmentRepository) GetMetricsCorrelation(userID, symptomKey, metricK
 }
 
 // GetMetricsTimeline gets timeline data from structured tables
-func (r *AssessmentRepository) GetMetricsTimeline(userID, symptomKey, metricKey string) ([]TimelineDataPoint, error) {
+func (r *AssessmentRepository) GetMetricsTimeline(userID, symptomKey, metricKey string, filter ChartFilter) ([]TimelineDataPoint, error) {
 	var result []TimelineDataPoint
 
-	// Use a different JOIN approach and debugging
+	conditions, filterArgs := filter.assessmentSQL("a", r.days)
+	// Live entries are plotted on the assessment day they count towards
 	query := `
         SELECT 
-            a.submitted_at as date,
+            COALESCE(a.assessment_date, ` + r.days.SQL("a.submitted_at") + `) as date,
             qr.numeric_value as symptom_value,
-            am.metric_value
+            am.metric_value,
+            a.is_retrospective
         FROM 
             assessments a
             JOIN question_responses qr ON a.id = qr.assessment_id
             JOIN assessment_metrics am ON a.id = am.assessment_id AND am.question_id = qr.question_id
         WHERE 
+            LOWER(a.user_email) = ?
+            AND qr.question_id = ?
+            AND am.metric_key = ?` + conditions + `
+        ORDER BY date ASC, a.submitted_at ASC
+    `
+
+	args := append([]any{userID, symptomKey, metricKey}, filterArgs...)
+	err := r.db.Raw(query, args...).Scan(&result).Error
+	if err != nil {
+		r.log.Errorw("Error in timeline query", "error", err)
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	return result, nil
+}
+
+// GetSymptomTimeline returns a user's answers to a question by assessment
+// day, for analysing the symptom on its own
+func (r *AssessmentRepository) GetSymptomTimeline(userID, symptomKey string, includeRetrospective bool) ([]TimelineDataPoint, error) {
+	var result []TimelineDataPoint
+
+	query := `
+        SELECT
+            COALESCE(a.assessment_date, ` + r.days.SQL("a.submitted_at") + `) as date,
+            qr.numeric_value as symptom_value,
+            a.is_retrospective
+        FROM
+            assessments a
+            JOIN question_responses qr ON a.id = qr.assessment_id
+        WHERE
             LOWER(a.user_email) = $1
             AND qr.question_id = $2
-            AND am.metric_key = $3
-        ORDER BY am.created_at ASC
+            AND qr.numeric_value IS NOT NULL
+            AND (a.is_retrospective = false OR $3)
+            AND NOT a.likely_bot
+        ORDER BY date ASC, a.submitted_at ASC
     `
 
-	err := r.db.Raw(query, userID, symptomKey, metricKey).Scan(&result).Error
+	err := r.db.Raw(query, strings.ToLower(userID), symptomKey, includeRetrospective).Scan(&result).Error
 	if err != nil {
-		r.log.Errorw("Error in timeline query", "error", err)
+		r.log.Errorw("Error in symptom timeline query", "error", err)
 		return nil, fmt.Errorf("database error: %w", err)
 	}
 	return result, nil
@@ -142,12 +466,22 @@ func (r *AssessmentRepository) DeleteAssessment(assessmentID uint) error {
 	// Start a transaction
 	tx := r.db.Begin()
 
-	// Delete question responses
+	// Delete question responses and their revisions
+	if err := tx.Delete(&models.QuestionResponseRevision{}, "assessment_id = ?", assessmentID).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("error deleting response revisions: %w", err)
+	}
 	if err := tx.Delete(&models.QuestionResponse{}, "assessment_id = ?", assessmentID).Error; err != nil {
 		tx.Rollback()
 		return fmt.Errorf("error deleting question responses: %w", err)
 	}
 
+	// Delete chart summaries
+	if err := tx.Delete(&models.ChartSummary{}, "assessment_id = ?", assessmentID).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("error deleting chart summaries: %w", err)
+	}
+
 	// Delete assessment metrics
 	if err := tx.Delete(&models.AssessmentMetric{}, "assessment_id = ?", assessmentID).Error; err != nil {
 		tx.Rollback()
@@ -174,3 +508,24 @@ func (r *AssessmentRepository) DeleteAssessment(assessmentID uint) error {
 
 	return tx.Commit().Error
 }
+
+// retrospectiveAssessments maps a user's retrospective assessment IDs to the day each one describes
+func retrospectiveAssessments(db *gorm.DB, email string) (map[uint]time.Time, error) {
+	var rows []struct {
+		ID             uint
+		AssessmentDate time.Time
+	}
+	err := db.Model(&models.Assessment{}).
+		Select("id, assessment_date").
+		Where("LOWER(user_email) = ? AND is_retrospective = true", strings.ToLower(email)).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	days := make(map[uint]time.Time, len(rows))
+	for _, row := range rows {
+		days[row.ID] = row.AssessmentDate
+	}
+	return days, nil
+}
@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/andevellicus/crapp/internal/models"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// ExportAttachment is an attachment listed in an analysis export
+type ExportAttachment struct {
+	UserEmail    string    `json:"user_email"`
+	AssessmentID uint      `json:"assessment_id"`
+	SubmittedAt  time.Time `json:"submitted_at"`
+	FileName     string    `json:"file_name"`
+	ContentType  string    `json:"content_type"`
+	SizeBytes    int64     `json:"size_bytes"`
+	SHA256       string    `json:"sha256"`
+	StorageKey   string    `json:"-"`
+}
+
+// FileStore removes stored files. It is met by the storage backends.
+type FileStore interface {
+	Delete(ctx context.Context, key string) error
+}
+
+// AttachmentRepository handles files attached to assessments
+type AttachmentRepository struct {
+	db  *gorm.DB
+	log *zap.SugaredLogger
+}
+
+// NewAttachmentRepository creates a new attachment repository
+func NewAttachmentRepository(db *gorm.DB, log *zap.SugaredLogger) *AttachmentRepository {
+	return &AttachmentRepository{
+		db:  db,
+		log: log.Named("attachment-repo"),
+	}
+}
+
+// GetForAssessment returns an assessment's attachment, or nil if it has none
+func (r *AttachmentRepository) GetForAssessment(assessmentID uint) (*models.AssessmentAttachment, error) {
+	var attachment models.AssessmentAttachment
+	err := r.db.Where("assessment_id = ?", assessmentID).First(&attachment).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &attachment, nil
+}
+
+// ListForUser lists a user's attachments, oldest first
+func (r *AttachmentRepository) ListForUser(email string) ([]models.AssessmentAttachment, error) {
+	attachments := []models.AssessmentAttachment{}
+	err := r.db.Where("user_email = ?", strings.ToLower(email)).Order("created_at").Find(&attachments).Error
+	return attachments, err
+}
+
+// Replace saves an assessment's attachment and returns the one it replaced,
+// if any, so its files can be removed
+func (r *AttachmentRepository) Replace(attachment *models.AssessmentAttachment) (*models.AssessmentAttachment, error) {
+	attachment.UserEmail = strings.ToLower(attachment.UserEmail)
+
+	var previous *models.AssessmentAttachment
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var existing models.AssessmentAttachment
+		err := tx.Where("assessment_id = ?", attachment.AssessmentID).First(&existing).Error
+		switch {
+		case err == nil:
+			previous = &existing
+			if err := tx.Delete(&existing).Error; err != nil {
+				return err
+			}
+		case !errors.Is(err, gorm.ErrRecordNotFound):
+			return err
+		}
+		return tx.Create(attachment).Error
+	})
+	if err != nil {
+		r.log.Errorw("Database error saving attachment", "error", err, "assessment", attachment.AssessmentID)
+		return nil, fmt.Errorf("failed to save attachment: %w", err)
+	}
+	return previous, nil
+}
+
+// Delete removes an attachment's record
+func (r *AttachmentRepository) Delete(id uint) error {
+	return r.db.Delete(&models.AssessmentAttachment{}, id).Error
+}
+
+// SetExcludeFromExports changes whether an attachment is left out of exports
+func (r *AttachmentRepository) SetExcludeFromExports(id uint, exclude bool) error {
+	return r.db.Model(&models.AssessmentAttachment{}).Where("id = ?", id).Update("exclude_from_exports", exclude).Error
+}
+
+// ListForExport lists the attachments of assessments submitted since a date,
+// and before until when it is set, by an organization's participants.
+// Attachments excluded from exports are left out.
+func (r *AttachmentRepository) ListForExport(orgID string, since, until time.Time) ([]ExportAttachment, error) {
+	result := []ExportAttachment{}
+
+	err := r.db.Table("assessment_attachments att").
+		Select(`a.user_email, a.id AS assessment_id, a.submitted_at,
+			att.file_name, att.content_type, att.size_bytes, att.sha256, att.storage_key`).
+		Joins("JOIN assessments a ON a.id = att.assessment_id").
+		Joins("JOIN users u ON LOWER(u.email) = LOWER(a.user_email)").
+		Where("a.submitted_at >= ? AND att.exclude_from_exports = false", since).
+		Scopes(submittedBefore(until), orgScopeOn("u", orgID)).
+		Order("a.submitted_at, a.id").
+		Scan(&result).Error
+	if err != nil {
+		r.log.Errorw("Error in attachment export query", "error", err, "org", orgID)
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	return result, nil
+}
+
+// ListOrphaned returns attachments whose assessment has been deleted, such
+// as with the participant's account, so their files can be removed
+func (r *AttachmentRepository) ListOrphaned(limit int) ([]models.AssessmentAttachment, error) {
+	attachments := []models.AssessmentAttachment{}
+	err := r.db.Where("NOT EXISTS (SELECT 1 FROM assessments a WHERE a.id = assessment_attachments.assessment_id)").
+		Limit(limit).
+		Find(&attachments).Error
+	return attachments, err
+}
@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/andevellicus/crapp/internal/models"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// AuditRepository handles persistence of account audit events
+type AuditRepository struct {
+	db  *gorm.DB
+	log *zap.SugaredLogger
+}
+
+// NewAuditRepository creates a new audit repository
+func NewAuditRepository(db *gorm.DB, log *zap.SugaredLogger) *AuditRepository {
+	return &AuditRepository{
+		db:  db,
+		log: log.Named("audit-repo"),
+	}
+}
+
+// Record stores an audit event. Details, if given, are stored as JSON; without
+// them the event stores an empty object, since jsonb refuses an empty string.
+func (r *AuditRepository) Record(event *models.AuditEvent, details map[string]any) error {
+	event.UserEmail = strings.ToLower(event.UserEmail)
+	event.CreatedAt = time.Now()
+
+	event.Details = "{}"
+	if len(details) > 0 {
+		detailsJSON, err := json.Marshal(details)
+		if err != nil {
+			return fmt.Errorf("failed to encode audit details: %w", err)
+		}
+		event.Details = string(detailsJSON)
+	}
+
+	if err := r.db.Create(event).Error; err != nil {
+		r.log.Errorw("Database error recording audit event", "error", err, "email", event.UserEmail, "type", event.EventType)
+		return fmt.Errorf("failed to record audit event: %w", err)
+	}
+	return nil
+}
+
+// GetForUser returns a page of the user's audit events, newest first, and the total count
+func (r *AuditRepository) GetForUser(email string, skip, limit int) ([]models.AuditEvent, int64, error) {
+	events := []models.AuditEvent{}
+	var total int64
+
+	query := r.db.Model(&models.AuditEvent{}).Where("user_email = ?", strings.ToLower(email))
+	if err := query.Count(&total).Error; err != nil {
+		r.log.Errorw("Database error counting audit events", "error", err, "email", email)
+		return nil, 0, err
+	}
+
+	if err := query.Order("created_at DESC").Offset(skip).Limit(limit).Find(&events).Error; err != nil {
+		r.log.Errorw("Database error listing audit events", "error", err, "email", email)
+		return nil, 0, err
+	}
+	return events, total, nil
+}
+
+// GetLastLocatedLogin returns the user's most recent login that has a geolocation
+func (r *AuditRepository) GetLastLocatedLogin(email string) (*models.AuditEvent, error) {
+	var events []models.AuditEvent
+	err := r.db.Where("user_email = ? AND event_type = ? AND country <> ''", strings.ToLower(email), models.AuditLogin).
+		Order("created_at DESC").
+		Limit(1).
+		Find(&events).Error
+	if err != nil {
+		r.log.Errorw("Database error getting last login", "error", err, "email", email)
+		return nil, err
+	}
+	if len(events) == 0 {
+		return nil, nil
+	}
+	return &events[0], nil
+}
+
+// HasLoginFromCountry reports whether the user has logged in from a country before
+func (r *AuditRepository) HasLoginFromCountry(email, country string) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.AuditEvent{}).
+		Where("user_email = ? AND event_type = ? AND country = ?", strings.ToLower(email), models.AuditLogin, country).
+		Count(&count).Error
+	if err != nil {
+		r.log.Errorw("Database error checking login country", "error", err, "email", email)
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// Search returns a page of audit events across users for admin review,
+// limited to an organization's users unless orgID is empty
+func (r *AuditRepository) Search(orgID, email string, suspiciousOnly bool, skip, limit int) ([]models.AuditEvent, int64, error) {
+	events := []models.AuditEvent{}
+	var total int64
+
+	query := r.db.Model(&models.AuditEvent{}).Scopes(OrgUserScope(orgID))
+	if email != "" {
+		query = query.Where("user_email = ?", strings.ToLower(email))
+	}
+	if suspiciousOnly {
+		query = query.Where("suspicious = ?", true)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		r.log.Errorw("Database error counting audit events", "error", err)
+		return nil, 0, err
+	}
+
+	if err := query.Order("created_at DESC").Offset(skip).Limit(limit).Find(&events).Error; err != nil {
+		r.log.Errorw("Database error searching audit events", "error", err)
+		return nil, 0, err
+	}
+	return events, total, nil
+}
@@ -0,0 +1,231 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/andevellicus/crapp/internal/config"
+	"github.com/andevellicus/crapp/internal/models"
+	"go.uber.org/zap"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// The query benchmarks time the chart and submission hot paths with and
+// without prepared statements against a real Postgres database, and fail
+// when a query's average is over its budget. They are skipped unless a
+// database is given:
+//
+//	CRAPP_BENCH_DATABASE_URL=postgres://... go test ./internal/repository -run '^$' -bench .
+//
+// The seeded dataset is written in a transaction that is rolled back, so it
+// never becomes visible to other connections.
+const benchDatabaseEnv = "CRAPP_BENCH_DATABASE_URL"
+
+const (
+	benchAssessments = 365
+	benchSymptom     = "bench_symptom"
+	benchMetric      = "bench_metric"
+	benchQuestions   = 10
+)
+
+// Budgets are the target average latencies of the benchmarked queries
+// against the seeded dataset, in either mode. They are deliberately loose so
+// that only a real regression, such as a lost index, breaks them.
+const (
+	chartBudget   = 20 * time.Millisecond
+	historyBudget = 50 * time.Millisecond
+	submitBudget  = 15 * time.Millisecond
+)
+
+// errBenchRollback discards a write made while benchmarking
+var errBenchRollback = errors.New("benchmark rollback")
+
+func BenchmarkChartCorrelation(b *testing.B) {
+	benchQuery(b, chartBudget, func(repo *Repository, _ *gorm.DB, email string) error {
+		_, err := repo.Assessments.GetMetricsCorrelation(email, benchSymptom, benchMetric, ChartFilter{IncludeRetrospective: true})
+		return err
+	})
+}
+
+func BenchmarkChartTimeline(b *testing.B) {
+	benchQuery(b, chartBudget, func(repo *Repository, _ *gorm.DB, email string) error {
+		_, err := repo.Assessments.GetMetricsTimeline(email, benchSymptom, benchMetric, ChartFilter{IncludeRetrospective: true})
+		return err
+	})
+}
+
+func BenchmarkAssessmentHistory(b *testing.B) {
+	benchQuery(b, historyBudget, func(repo *Repository, _ *gorm.DB, email string) error {
+		_, err := repo.Assessments.GetByUser(email)
+		return err
+	})
+}
+
+func BenchmarkSubmit(b *testing.B) {
+	benchQuery(b, submitBudget, func(_ *Repository, db *gorm.DB, email string) error {
+		return benchSubmit(db, email)
+	})
+}
+
+// benchQuery seeds a synthetic user and times a query against it, once with
+// the default session and once with prepared statements
+func benchQuery(b *testing.B, budget time.Duration, query func(repo *Repository, db *gorm.DB, email string) error) {
+	tx := openBenchDB(b)
+	email, err := seedBenchData(tx, benchAssessments)
+	if err != nil {
+		b.Fatalf("seeding benchmark data: %v", err)
+	}
+
+	modes := []struct {
+		name string
+		db   *gorm.DB
+	}{
+		{"default", tx},
+		{"prepared", tx.Session(&gorm.Session{PrepareStmt: true})},
+	}
+	for _, mode := range modes {
+		repo := newRepositorySet(mode.db, &config.Config{}, zap.NewNop().Sugar())
+		b.Run(mode.name, func(b *testing.B) {
+			// Warm up so both modes start with a connection and, for prepared
+			// mode, a cached statement
+			if err := query(repo, mode.db, email); err != nil {
+				b.Fatal(err)
+			}
+			for b.Loop() {
+				if err := query(repo, mode.db, email); err != nil {
+					b.Fatal(err)
+				}
+			}
+			if perQuery := b.Elapsed() / time.Duration(b.N); perQuery > budget {
+				b.Errorf("%s per query is over the %s budget", perQuery, budget)
+			}
+		})
+	}
+}
+
+// openBenchDB connects to the benchmark database, migrates it, and returns a
+// transaction that is rolled back when the benchmark ends
+func openBenchDB(b *testing.B) *gorm.DB {
+	b.Helper()
+	dsn := os.Getenv(benchDatabaseEnv)
+	if dsn == "" {
+		b.Skipf("%s is not set", benchDatabaseEnv)
+	}
+
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		b.Fatalf("connecting to the benchmark database: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() { sqlDB.Close() })
+
+	if err := db.AutoMigrate(sharedModels...); err != nil {
+		b.Fatalf("migrating the benchmark database: %v", err)
+	}
+	if err := migrateTenantTables(db); err != nil {
+		b.Fatalf("migrating the benchmark database: %v", err)
+	}
+
+	tx := db.Begin()
+	if tx.Error != nil {
+		b.Fatal(tx.Error)
+	}
+	b.Cleanup(func() { tx.Rollback() })
+	return tx
+}
+
+// seedBenchData creates a user's worth of assessments, one per day, each with
+// answers and interaction metrics for several questions
+func seedBenchData(tx *gorm.DB, count int) (string, error) {
+	email := fmt.Sprintf("bench-%d@example.invalid", time.Now().UnixNano())
+	today := time.Now().Truncate(24 * time.Hour)
+
+	for i := range count {
+		day := today.AddDate(0, 0, -i)
+		assessment := models.Assessment{
+			UserEmail:          email,
+			DeviceID:           "bench",
+			SubmittedAt:        day.Add(20 * time.Hour),
+			LocationPermission: "unavailable",
+			AssessmentDay:      &day,
+		}
+		if err := tx.Create(&assessment).Error; err != nil {
+			return "", err
+		}
+
+		responses := make([]models.QuestionResponse, 0, benchQuestions)
+		metrics := make([]models.AssessmentMetric, 0, benchQuestions)
+		for q := range benchQuestions {
+			questionID := fmt.Sprintf("bench_q%d", q)
+			if q == 0 {
+				questionID = benchSymptom
+			}
+			value := float64((i + q) % 4)
+			responses = append(responses, models.QuestionResponse{
+				AssessmentID: assessment.ID,
+				QuestionID:   questionID,
+				ValueType:    "number",
+				NumericValue: value,
+				CreatedAt:    assessment.SubmittedAt,
+			})
+			metrics = append(metrics, models.AssessmentMetric{
+				AssessmentID: assessment.ID,
+				QuestionID:   questionID,
+				MetricKey:    benchMetric,
+				MetricValue:  float64(i%50) / 10,
+				SampleSize:   1,
+				CreatedAt:    assessment.SubmittedAt,
+			})
+		}
+		if err := tx.Create(&responses).Error; err != nil {
+			return "", err
+		}
+		if err := tx.Create(&metrics).Error; err != nil {
+			return "", err
+		}
+	}
+	return email, nil
+}
+
+// benchSubmit writes an assessment with its answers the way a form submission
+// does, inside a savepoint that is rolled back so runs don't accumulate
+func benchSubmit(db *gorm.DB, email string) error {
+	err := db.Transaction(func(tx *gorm.DB) error {
+		assessment := models.Assessment{
+			UserEmail:          email,
+			DeviceID:           "bench",
+			LocationPermission: "unavailable",
+		}
+		if err := tx.Create(&assessment).Error; err != nil {
+			return err
+		}
+
+		responses := make([]models.QuestionResponse, 0, benchQuestions)
+		for q := range benchQuestions {
+			value := float64(q % 4)
+			responses = append(responses, models.QuestionResponse{
+				AssessmentID: assessment.ID,
+				QuestionID:   fmt.Sprintf("bench_q%d", q),
+				ValueType:    "number",
+				NumericValue: value,
+				CreatedAt:    time.Now(),
+			})
+		}
+		if err := tx.Create(&responses).Error; err != nil {
+			return err
+		}
+		return errBenchRollback
+	})
+	if errors.Is(err, errBenchRollback) {
+		return nil
+	}
+	return err
+}
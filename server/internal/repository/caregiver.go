@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/andevellicus/crapp/internal/models"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// CaregiverLinkRepository handles persistence of caregiver relationships
+type CaregiverLinkRepository struct {
+	db  *gorm.DB
+	log *zap.SugaredLogger
+}
+
+// NewCaregiverLinkRepository creates a new caregiver link repository
+func NewCaregiverLinkRepository(db *gorm.DB, log *zap.SugaredLogger) *CaregiverLinkRepository {
+	return &CaregiverLinkRepository{
+		db:  db,
+		log: log.Named("caregiver-repo"),
+	}
+}
+
+// CreateInvite creates a pending caregiver link, replacing any earlier pending invite for the pair
+func (r *CaregiverLinkRepository) CreateInvite(patientEmail, caregiverEmail string) (*models.CaregiverLink, error) {
+	patient := strings.ToLower(patientEmail)
+	caregiver := strings.ToLower(caregiverEmail)
+
+	var count int64
+	if err := r.db.Model(&models.CaregiverLink{}).
+		Where("patient_email = ? AND caregiver_email = ? AND status = ?", patient, caregiver, models.CaregiverLinkActive).
+		Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, fmt.Errorf("caregiver is already linked")
+	}
+
+	now := time.Now()
+	if err := r.db.Model(&models.CaregiverLink{}).
+		Where("patient_email = ? AND caregiver_email = ? AND status = ?", patient, caregiver, models.CaregiverLinkPending).
+		Updates(map[string]any{"status": models.CaregiverLinkRevoked, "revoked_at": &now}).Error; err != nil {
+		r.log.Warnw("Failed to expire previous caregiver invites", "error", err)
+	}
+
+	link := &models.CaregiverLink{
+		PatientEmail:   patient,
+		CaregiverEmail: caregiver,
+		Status:         models.CaregiverLinkPending,
+		InviteToken:    generateUniqueToken(),
+		InvitedAt:      now,
+	}
+	if err := r.db.Create(link).Error; err != nil {
+		r.log.Errorw("Database error creating caregiver invite", "error", err)
+		return nil, fmt.Errorf("failed to create caregiver invite: %w", err)
+	}
+	return link, nil
+}
+
+// Accept records the caregiver's consent for a pending invite addressed to them
+func (r *CaregiverLinkRepository) Accept(token, caregiverEmail string) (*models.CaregiverLink, error) {
+	var link models.CaregiverLink
+	err := r.db.Where("invite_token = ? AND status = ?", token, models.CaregiverLinkPending).First(&link).Error
+	if err != nil {
+		return nil, fmt.Errorf("invitation not found or no longer valid")
+	}
+	if link.CaregiverEmail != strings.ToLower(caregiverEmail) {
+		return nil, fmt.Errorf("invitation was issued to a different account")
+	}
+
+	now := time.Now()
+	link.Status = models.CaregiverLinkActive
+	link.ConsentedAt = &now
+	if err := r.db.Model(&link).Updates(map[string]any{
+		"status":       link.Status,
+		"consented_at": link.ConsentedAt,
+	}).Error; err != nil {
+		r.log.Errorw("Database error accepting caregiver invite", "error", err, "id", link.ID)
+		return nil, err
+	}
+	return &link, nil
+}
+
+// Revoke ends a caregiver link. Either party may revoke it.
+func (r *CaregiverLinkRepository) Revoke(id uint, email string) error {
+	normalizedEmail := strings.ToLower(email)
+	now := time.Now()
+	result := r.db.Model(&models.CaregiverLink{}).
+		Where("id = ? AND (patient_email = ? OR caregiver_email = ?) AND status != ?",
+			id, normalizedEmail, normalizedEmail, models.CaregiverLinkRevoked).
+		Updates(map[string]any{"status": models.CaregiverLinkRevoked, "revoked_at": &now})
+	if result.Error != nil {
+		r.log.Errorw("Database error revoking caregiver link", "error", result.Error, "id", id)
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("caregiver link not found")
+	}
+	return nil
+}
+
+// IsActiveCaregiver reports whether caregiverEmail may currently report for patientEmail
+func (r *CaregiverLinkRepository) IsActiveCaregiver(patientEmail, caregiverEmail string) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.CaregiverLink{}).
+		Where("patient_email = ? AND caregiver_email = ? AND status = ?",
+			strings.ToLower(patientEmail), strings.ToLower(caregiverEmail), models.CaregiverLinkActive).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// GetForUser lists all links in which the user is either the patient or the caregiver
+func (r *CaregiverLinkRepository) GetForUser(email string) ([]models.CaregiverLink, error) {
+	normalizedEmail := strings.ToLower(email)
+	links := []models.CaregiverLink{}
+	err := r.db.Where("patient_email = ? OR caregiver_email = ?", normalizedEmail, normalizedEmail).
+		Order("invited_at DESC").
+		Find(&links).Error
+	if err != nil {
+		r.log.Errorw("Database error listing caregiver links", "error", err, "email", normalizedEmail)
+		return nil, err
+	}
+	return links, nil
+}
@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/andevellicus/crapp/internal/utils"
+	"gorm.io/gorm"
+)
+
+// ChartFilter narrows the assessments a chart is drawn from
+type ChartFilter struct {
+	IncludeRetrospective bool
+	IncludeLikelyBots    bool       // Submissions that looked automated
+	IncludeProxy         bool       // Assessments a caregiver reported for the user
+	ReportedBy           string     // Only this caregiver's reports; empty for any
+	DeviceID             string     // Empty for every device
+	From                 *time.Time // First assessment day, inclusive
+	To                   *time.Time // Last assessment day, inclusive
+}
+
+// chartDaySlack covers the gap between when an assessment was submitted and
+// the assessment day it counts towards, which the day cutoff and time zone
+// can push either way
+const chartDaySlack = 2 * 24 * time.Hour
+
+// Contains reports whether an assessment day is inside the filter's dates
+func (f ChartFilter) Contains(day time.Time) bool {
+	date := day.Format("2006-01-02")
+	return (f.From == nil || date >= f.From.Format("2006-01-02")) &&
+		(f.To == nil || date <= f.To.Format("2006-01-02"))
+}
+
+// assessmentSQL returns the filter as conditions on the assessments table
+// alias, each starting with AND, with their arguments. Date bounds apply to
+// the assessment day; live entries are also bounded by submission time so
+// the (user_email, submitted_at) index can narrow the scan.
+func (f ChartFilter) assessmentSQL(alias string, days utils.AssessmentDay) (string, []any) {
+	day := fmt.Sprintf("COALESCE(%[1]s.assessment_date, %[2]s)", alias, days.SQL(alias+".submitted_at"))
+
+	sql := fmt.Sprintf(" AND (%[1]s.is_retrospective = false OR ?) AND (%[1]s.likely_bot = false OR ?)", alias)
+	args := []any{f.IncludeRetrospective, f.IncludeLikelyBots}
+	if proxy, proxyArgs := f.proxySQL(alias); proxy != "" {
+		sql += " AND " + proxy
+		args = append(args, proxyArgs...)
+	}
+	if f.DeviceID != "" {
+		sql += fmt.Sprintf(" AND %s.device_id = ?", alias)
+		args = append(args, f.DeviceID)
+	}
+	if f.From != nil {
+		sql += fmt.Sprintf(" AND %[1]s >= CAST(? AS date) AND (%[2]s.assessment_date IS NOT NULL OR %[2]s.submitted_at >= ?)", day, alias)
+		args = append(args, f.From.Format("2006-01-02"), f.From.Add(-chartDaySlack))
+	}
+	if f.To != nil {
+		sql += fmt.Sprintf(" AND %[1]s <= CAST(? AS date) AND (%[2]s.assessment_date IS NOT NULL OR %[2]s.submitted_at < ?)", day, alias)
+		args = append(args, f.To.Format("2006-01-02"), f.To.Add(24*time.Hour+chartDaySlack))
+	}
+	return sql, args
+}
+
+// proxySQL returns the filter's condition on who reported an assessment in
+// the assessments table alias, or "" when every report is kept. Reports a
+// caregiver made for the user are left out unless asked for, as their
+// interaction data is the caregiver's.
+func (f ChartFilter) proxySQL(alias string) (string, []any) {
+	switch {
+	case f.ReportedBy != "":
+		return fmt.Sprintf("LOWER(%s.reported_by) = ?", alias), []any{strings.ToLower(f.ReportedBy)}
+	case !f.IncludeProxy:
+		return fmt.Sprintf("%s.reported_by IS NULL", alias), nil
+	}
+	return "", nil
+}
+
+// proxyFilter applies the filter's reporter condition to rows keyed by
+// assessment_id, such as results and chart summaries
+func proxyFilter(f ChartFilter) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if proxy, args := f.proxySQL("pa"); proxy != "" {
+			db = db.Where("assessment_id IN (SELECT pa.id FROM assessments pa WHERE "+proxy+")", args...)
+		}
+		return db
+	}
+}
+
+// resultFilter limits cognitive test results to the filter's device and
+// reporter and, unless asked for, leaves out those from likely bots. Their
+// dates are checked with Contains, as retrospective results are plotted on
+// the day they describe rather than when they were recorded.
+func resultFilter(f ChartFilter) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if f.DeviceID != "" {
+			db = db.Where("device_id = ?", f.DeviceID)
+		}
+		if !f.IncludeLikelyBots {
+			db = db.Where("assessment_id NOT IN (SELECT id FROM assessments WHERE likely_bot)")
+		}
+		return proxyFilter(f)(db)
+	}
+}
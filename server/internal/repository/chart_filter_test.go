@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/andevellicus/crapp/internal/utils"
+)
+
+func TestChartFilterProxySQL(t *testing.T) {
+	tests := []struct {
+		name     string
+		filter   ChartFilter
+		wantSQL  string
+		wantArgs []any
+	}{
+		{name: "caregiver reports left out by default", wantSQL: "a.reported_by IS NULL"},
+		{name: "caregiver reports included", filter: ChartFilter{IncludeProxy: true}},
+		{name: "one caregiver", filter: ChartFilter{ReportedBy: "Carer@Example.com"},
+			wantSQL: "LOWER(a.reported_by) = ?", wantArgs: []any{"carer@example.com"}},
+		{name: "one caregiver with proxies included", filter: ChartFilter{IncludeProxy: true, ReportedBy: "carer@example.com"},
+			wantSQL: "LOWER(a.reported_by) = ?", wantArgs: []any{"carer@example.com"}},
+	}
+
+	days, _ := utils.NewAssessmentDay("UTC", "")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sql, args := tt.filter.proxySQL("a")
+			if sql != tt.wantSQL || !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("proxySQL = %q %v, want %q %v", sql, args, tt.wantSQL, tt.wantArgs)
+			}
+
+			// The live chart queries carry the same condition
+			full, fullArgs := tt.filter.assessmentSQL("a", days)
+			if tt.wantSQL != "" && !strings.Contains(full, " AND "+tt.wantSQL) {
+				t.Errorf("assessmentSQL = %q, missing %q", full, tt.wantSQL)
+			}
+			if tt.wantSQL == "" && strings.Contains(full, "reported_by") {
+				t.Errorf("assessmentSQL = %q, want no reporter condition", full)
+			}
+			if got := strings.Count(full, "?"); got != len(fullArgs) {
+				t.Errorf("assessmentSQL has %d placeholders for %d arguments", got, len(fullArgs))
+			}
+		})
+	}
+}
@@ -0,0 +1,177 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/andevellicus/crapp/internal/models"
+	"github.com/andevellicus/crapp/internal/utils"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// ErrSummaryNotReady means some of a user's assessments have not been
+// summarized yet, so charts must use the live queries
+var ErrSummaryNotReady = errors.New("chart summary not ready")
+
+// ChartSummaryRepository maintains and reads precomputed chart points
+type ChartSummaryRepository struct {
+	db   *gorm.DB
+	log  *zap.SugaredLogger
+	days utils.AssessmentDay
+}
+
+// NewChartSummaryRepository creates a new chart summary repository
+func NewChartSummaryRepository(db *gorm.DB, log *zap.SugaredLogger, days utils.AssessmentDay) *ChartSummaryRepository {
+	return &ChartSummaryRepository{
+		db:   db,
+		log:  log.Named("chart-summary-repo"),
+		days: days,
+	}
+}
+
+// Refresh rebuilds the summary rows of the given assessments and marks them
+// as summarized
+func (r *ChartSummaryRepository) Refresh(assessmentIDs ...uint) error {
+	if len(assessmentIDs) == 0 {
+		return nil
+	}
+
+	// Live entries are plotted on the assessment day they count towards
+	query := `
+		INSERT INTO chart_summaries
+			(assessment_id, user_email, question_id, metric_key, date, submitted_at, is_retrospective, likely_bot, symptom_value, metric_value)
+		SELECT
+			a.id,
+			LOWER(a.user_email),
+			qr.question_id,
+			am.metric_key,
+			COALESCE(a.assessment_date, ` + r.days.SQL("a.submitted_at") + `),
+			a.submitted_at,
+			a.is_retrospective,
+			a.likely_bot,
+			qr.numeric_value,
+			am.metric_value
+		FROM
+			assessments a
+			JOIN question_responses qr ON a.id = qr.assessment_id
+			JOIN assessment_metrics am ON a.id = am.assessment_id AND am.question_id = qr.question_id
+		WHERE
+			a.id IN ?
+		ON CONFLICT (assessment_id, question_id, metric_key) DO NOTHING
+	`
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("assessment_id IN ?", assessmentIDs).Delete(&models.ChartSummary{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec(query, assessmentIDs).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.Assessment{}).
+			Where("id IN ?", assessmentIDs).
+			Update("summarized_at", time.Now()).Error
+	})
+	if err != nil {
+		r.log.Errorw("Error refreshing chart summaries", "error", err, "assessments", len(assessmentIDs))
+		return fmt.Errorf("failed to refresh chart summaries: %w", err)
+	}
+	return nil
+}
+
+// RefreshPending summarizes up to limit assessments that have not been
+// summarized yet, returning how many were processed
+func (r *ChartSummaryRepository) RefreshPending(limit int) (int, error) {
+	var ids []uint
+	err := r.db.Model(&models.Assessment{}).
+		Where("summarized_at IS NULL").
+		Order("id ASC").
+		Limit(limit).
+		Pluck("id", &ids).Error
+	if err != nil {
+		return 0, err
+	}
+	if err := r.Refresh(ids...); err != nil {
+		return 0, err
+	}
+	return len(ids), nil
+}
+
+// ready reports whether every assessment of the user has been summarized.
+// Summaries don't record the device, so device filters always use the live
+// queries.
+func (r *ChartSummaryRepository) ready(email string, filter ChartFilter) (bool, error) {
+	if filter.DeviceID != "" {
+		return false, nil
+	}
+	var pending int64
+	err := r.db.Model(&models.Assessment{}).
+		Where("LOWER(user_email) = ? AND summarized_at IS NULL", email).
+		Count(&pending).Error
+	if err != nil {
+		r.log.Warnw("Error checking chart summary state", "error", err)
+		return false, err
+	}
+	return pending == 0, nil
+}
+
+// GetCorrelation reads correlation points from the summary table. It returns
+// ErrSummaryNotReady while any of the user's assessments is unsummarized.
+func (r *ChartSummaryRepository) GetCorrelation(userID, symptomKey, metricKey string, filter ChartFilter) (*[]CorrelationDataPoint, error) {
+	email := strings.ToLower(userID)
+	if ok, _ := r.ready(email, filter); !ok {
+		return nil, ErrSummaryNotReady
+	}
+
+	var result []CorrelationDataPoint
+	err := r.db.Model(&models.ChartSummary{}).
+		Select("symptom_value, metric_value").
+		Where("user_email = ? AND question_id = ? AND metric_key = ?", email, symptomKey, metricKey).
+		Scopes(summaryFilter(filter)).
+		Scan(&result).Error
+	if err != nil {
+		r.log.Errorw("Error in summary correlation query", "error", err)
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	return &result, nil
+}
+
+// GetTimeline reads timeline points from the summary table. It returns
+// ErrSummaryNotReady while any of the user's assessments is unsummarized.
+func (r *ChartSummaryRepository) GetTimeline(userID, symptomKey, metricKey string, filter ChartFilter) ([]TimelineDataPoint, error) {
+	email := strings.ToLower(userID)
+	if ok, _ := r.ready(email, filter); !ok {
+		return nil, ErrSummaryNotReady
+	}
+
+	var result []TimelineDataPoint
+	err := r.db.Model(&models.ChartSummary{}).
+		Select("date, symptom_value, metric_value, is_retrospective").
+		Where("user_email = ? AND question_id = ? AND metric_key = ?", email, symptomKey, metricKey).
+		Scopes(summaryFilter(filter)).
+		Order("date ASC, submitted_at ASC").
+		Scan(&result).Error
+	if err != nil {
+		r.log.Errorw("Error in summary timeline query", "error", err)
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	return result, nil
+}
+
+// summaryFilter applies a chart filter's retrospective, likely bot, reporter
+// and date conditions to summary rows
+func summaryFilter(filter ChartFilter) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		db = db.Where("is_retrospective = false OR ?", filter.IncludeRetrospective)
+		db = db.Where("likely_bot = false OR ?", filter.IncludeLikelyBots)
+		if filter.From != nil {
+			db = db.Where("date >= CAST(? AS date)", filter.From.Format("2006-01-02"))
+		}
+		if filter.To != nil {
+			db = db.Where("date <= CAST(? AS date)", filter.To.Format("2006-01-02"))
+		}
+		return proxyFilter(filter)(db)
+	}
+}
@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/andevellicus/crapp/internal/models"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// ChartViewRepository handles saved chart configurations
+type ChartViewRepository struct {
+	db  *gorm.DB
+	log *zap.SugaredLogger
+}
+
+// NewChartViewRepository creates a new chart view repository
+func NewChartViewRepository(db *gorm.DB, log *zap.SugaredLogger) *ChartViewRepository {
+	return &ChartViewRepository{
+		db:  db,
+		log: log.Named("chart-view-repo"),
+	}
+}
+
+// List returns a user's saved views, by name
+func (r *ChartViewRepository) List(email string) ([]models.ChartView, error) {
+	views := []models.ChartView{}
+	err := r.db.Where("LOWER(user_email) = ?", strings.ToLower(email)).
+		Order("name ASC, id ASC").
+		Find(&views).Error
+	return views, err
+}
+
+// Get retrieves one of a user's saved views
+func (r *ChartViewRepository) Get(email string, id uint) (*models.ChartView, error) {
+	var view models.ChartView
+	err := r.db.Where("id = ? AND LOWER(user_email) = ?", id, strings.ToLower(email)).First(&view).Error
+	if err != nil {
+		return nil, err
+	}
+	return &view, nil
+}
+
+// GetDefault retrieves a user's default view, or nil when they have none
+func (r *ChartViewRepository) GetDefault(email string) (*models.ChartView, error) {
+	var view models.ChartView
+	err := r.db.Where("LOWER(user_email) = ? AND is_default", strings.ToLower(email)).First(&view).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &view, nil
+}
+
+// Save creates or updates a view. A default view replaces the user's
+// previous default.
+func (r *ChartViewRepository) Save(view *models.ChartView) error {
+	view.UserEmail = strings.ToLower(view.UserEmail)
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if view.IsDefault {
+			if err := tx.Model(&models.ChartView{}).
+				Where("user_email = ? AND id <> ? AND is_default", view.UserEmail, view.ID).
+				Update("is_default", false).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Save(view).Error
+	})
+	if err != nil {
+		r.log.Errorw("Database error saving chart view", "error", err, "id", view.ID)
+		return fmt.Errorf("failed to save chart view: %w", err)
+	}
+	return nil
+}
+
+// Delete removes a saved view
+func (r *ChartViewRepository) Delete(view *models.ChartView) error {
+	return r.db.Delete(view).Error
+}
@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/andevellicus/crapp/internal/models"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// ClientErrorGroup summarizes the reports of one error
+type ClientErrorGroup struct {
+	Fingerprint string    `json:"fingerprint"`
+	Kind        string    `json:"kind"`
+	Message     string    `json:"message"`
+	Source      string    `json:"source"`
+	Line        int       `json:"line"`
+	Count       int64     `json:"count"`
+	Releases    string    `json:"releases"` // Comma-separated
+	FirstSeen   time.Time `json:"first_seen"`
+	LastSeen    time.Time `json:"last_seen"`
+}
+
+// ClientErrorRepository stores errors reported by the PWA
+type ClientErrorRepository struct {
+	db  *gorm.DB
+	log *zap.SugaredLogger
+}
+
+// NewClientErrorRepository creates a new client error repository
+func NewClientErrorRepository(db *gorm.DB, log *zap.SugaredLogger) *ClientErrorRepository {
+	return &ClientErrorRepository{
+		db:  db,
+		log: log.Named("client-error-repo"),
+	}
+}
+
+// CreateBatch stores a batch of reports
+func (r *ClientErrorRepository) CreateBatch(reports []models.ClientError) error {
+	if len(reports) == 0 {
+		return nil
+	}
+	if err := r.db.Create(&reports).Error; err != nil {
+		r.log.Errorw("Database error storing client errors", "error", err, "count", len(reports))
+		return fmt.Errorf("failed to store client errors: %w", err)
+	}
+	return nil
+}
+
+// ListGroups summarizes errors reported since a time, most recent first,
+// optionally for one release
+func (r *ClientErrorRepository) ListGroups(since time.Time, release string, limit int) ([]ClientErrorGroup, error) {
+	groups := []ClientErrorGroup{}
+
+	query := r.db.Model(&models.ClientError{}).
+		Select(`fingerprint, MIN(kind) AS kind, MIN(message) AS message, MIN(source) AS source, MIN(line) AS line,
+			COUNT(*) AS count, STRING_AGG(DISTINCT release, ',') AS releases,
+			MIN(created_at) AS first_seen, MAX(created_at) AS last_seen`).
+		Where("created_at >= ?", since)
+	if release != "" {
+		query = query.Where("release = ?", release)
+	}
+	err := query.Group("fingerprint").
+		Order("last_seen DESC").
+		Limit(limit).
+		Scan(&groups).Error
+	return groups, err
+}
+
+// ListByFingerprint returns the latest reports of one error
+func (r *ClientErrorRepository) ListByFingerprint(fingerprint string, limit int) ([]models.ClientError, error) {
+	reports := []models.ClientError{}
+	err := r.db.Where("fingerprint = ?", fingerprint).
+		Order("created_at DESC").
+		Limit(limit).
+		Find(&reports).Error
+	return reports, err
+}
+
+// DeleteBefore removes reports received before the cutoff
+func (r *ClientErrorRepository) DeleteBefore(cutoff time.Time) (int64, error) {
+	result := r.db.Where("created_at < ?", cutoff).Delete(&models.ClientError{})
+	return result.RowsAffected, result.Error
+}
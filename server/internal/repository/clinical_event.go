@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/andevellicus/crapp/internal/models"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// ClinicalEventCount is one row of the clinical event dashboard summary
+type ClinicalEventCount struct {
+	EventType      string `json:"event_type"`
+	Severity       string `json:"severity"`
+	FollowUpStatus string `json:"follow_up_status"`
+	Count          int64  `json:"count"`
+}
+
+// ClinicalEventRepository handles persistence of protocol deviations and adverse events
+type ClinicalEventRepository struct {
+	db  *gorm.DB
+	log *zap.SugaredLogger
+}
+
+// NewClinicalEventRepository creates a new clinical event repository
+func NewClinicalEventRepository(db *gorm.DB, log *zap.SugaredLogger) *ClinicalEventRepository {
+	return &ClinicalEventRepository{
+		db:  db,
+		log: log.Named("clinical-event-repo"),
+	}
+}
+
+// Create stores a new clinical event
+func (r *ClinicalEventRepository) Create(event *models.ClinicalEvent) error {
+	event.UserEmail = strings.ToLower(event.UserEmail)
+	if err := r.db.Create(event).Error; err != nil {
+		r.log.Errorw("Database error creating clinical event", "error", err, "type", event.EventType)
+		return fmt.Errorf("failed to create clinical event: %w", err)
+	}
+	return nil
+}
+
+// Get retrieves a clinical event within an organization. An empty orgID matches any organization.
+func (r *ClinicalEventRepository) Get(orgID string, id uint) (*models.ClinicalEvent, error) {
+	var event models.ClinicalEvent
+	if err := r.db.Scopes(OrgScope(orgID)).Where("id = ?", id).First(&event).Error; err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+// Update saves changes to a clinical event
+func (r *ClinicalEventRepository) Update(event *models.ClinicalEvent) error {
+	if err := r.db.Save(event).Error; err != nil {
+		r.log.Errorw("Database error updating clinical event", "error", err, "id", event.ID)
+		return fmt.Errorf("failed to update clinical event: %w", err)
+	}
+	return nil
+}
+
+// Search returns a page of clinical events, newest first, with optional filters
+func (r *ClinicalEventRepository) Search(orgID, eventType, status, email string, skip, limit int) ([]models.ClinicalEvent, int64, error) {
+	events := []models.ClinicalEvent{}
+	var total int64
+
+	query := r.db.Model(&models.ClinicalEvent{}).Scopes(OrgScope(orgID))
+	if eventType != "" {
+		query = query.Where("event_type = ?", eventType)
+	}
+	if status != "" {
+		query = query.Where("follow_up_status = ?", status)
+	}
+	if email != "" {
+		query = query.Where("user_email = ?", strings.ToLower(email))
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		r.log.Errorw("Database error counting clinical events", "error", err)
+		return nil, 0, err
+	}
+
+	if err := query.Order("event_date DESC, id DESC").Offset(skip).Limit(limit).Find(&events).Error; err != nil {
+		r.log.Errorw("Database error searching clinical events", "error", err)
+		return nil, 0, err
+	}
+	return events, total, nil
+}
+
+// ListSince returns an organization's clinical events dated on or after a day
+func (r *ClinicalEventRepository) ListSince(orgID string, since time.Time) ([]models.ClinicalEvent, error) {
+	events := []models.ClinicalEvent{}
+	err := r.db.Scopes(OrgScope(orgID)).
+		Where("event_date >= ?", since).
+		Order("event_date, id").
+		Find(&events).Error
+	if err != nil {
+		r.log.Errorw("Database error listing clinical events", "error", err, "org", orgID)
+		return nil, err
+	}
+	return events, nil
+}
+
+// Summary counts clinical events by type, severity, and follow-up status
+func (r *ClinicalEventRepository) Summary(orgID string) ([]ClinicalEventCount, error) {
+	counts := []ClinicalEventCount{}
+	err := r.db.Model(&models.ClinicalEvent{}).
+		Scopes(OrgScope(orgID)).
+		Select("event_type, severity, follow_up_status, COUNT(*) AS count").
+		Group("event_type, severity, follow_up_status").
+		Order("event_type, severity, follow_up_status").
+		Scan(&counts).Error
+	if err != nil {
+		r.log.Errorw("Database error summarizing clinical events", "error", err, "org", orgID)
+		return nil, err
+	}
+	return counts, nil
+}
@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"math"
+	"time"
+)
+
+// MaxCorrelationLag is the furthest the metric series can be shifted, in days
+const MaxCorrelationLag = 14
+
+// LagCorrelation is the correlation between a symptom and a metric measured
+// some days earlier. A positive lag pairs each symptom value with the metric
+// from that many days before it, so a strong correlation at lag 2 means the
+// metric changes two days ahead of the symptom.
+type LagCorrelation struct {
+	Lag         int      `json:"lag"`
+	Correlation *float64 `json:"correlation"` // Pearson's r; null with fewer than 3 pairs or no variation
+	Points      int      `json:"points"`
+}
+
+// CorrelateLags correlates daily symptom and metric values with the metric
+// shifted by every lag from -maxLag to maxLag days. Days with more than one
+// assessment use their mean values.
+func CorrelateLags(days []CoverageDay, maxLag int) []LagCorrelation {
+	symptoms := dailyMeans(days, func(day CoverageDay) *float64 { return day.SymptomValue })
+	metrics := dailyMeans(days, func(day CoverageDay) *float64 { return day.MetricValue })
+
+	lags := make([]LagCorrelation, 0, 2*maxLag+1)
+	for lag := -maxLag; lag <= maxLag; lag++ {
+		var xs, ys []float64
+		for date, symptom := range symptoms {
+			metric, ok := metrics[date.AddDate(0, 0, -lag)]
+			if !ok {
+				continue
+			}
+			xs = append(xs, metric)
+			ys = append(ys, symptom)
+		}
+		lags = append(lags, LagCorrelation{Lag: lag, Correlation: pearson(xs, ys), Points: len(xs)})
+	}
+	return lags
+}
+
+// dailyMeans averages a series' values per calendar day, keyed by the day
+// at midnight UTC so keys can be shifted and compared
+func dailyMeans(days []CoverageDay, value func(CoverageDay) *float64) map[time.Time]float64 {
+	sums := make(map[time.Time]float64)
+	counts := make(map[time.Time]int)
+	for _, day := range days {
+		v := value(day)
+		if v == nil {
+			continue
+		}
+		key := time.Date(day.Date.Year(), day.Date.Month(), day.Date.Day(), 0, 0, 0, 0, time.UTC)
+		sums[key] += *v
+		counts[key]++
+	}
+	for key, count := range counts {
+		sums[key] /= float64(count)
+	}
+	return sums
+}
+
+// pearson returns the correlation coefficient of paired values
+func pearson(xs, ys []float64) *float64 {
+	n := float64(len(xs))
+	if len(xs) < 3 {
+		return nil
+	}
+	var sumX, sumY float64
+	for i := range xs {
+		sumX += xs[i]
+		sumY += ys[i]
+	}
+	meanX, meanY := sumX/n, sumY/n
+
+	var cov, varX, varY float64
+	for i := range xs {
+		dx, dy := xs[i]-meanX, ys[i]-meanY
+		cov += dx * dy
+		varX += dx * dx
+		varY += dy * dy
+	}
+	if varX == 0 || varY == 0 {
+		return nil
+	}
+	r := math.Round(cov/math.Sqrt(varX*varY)*1000) / 1000
+	return &r
+}
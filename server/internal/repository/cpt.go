@@ -3,10 +3,10 @@ package repository
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/andevellicus/crapp/internal/models"
-	"github.com/andevellicus/crapp/internal/utils"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
 )
@@ -39,12 +39,14 @@ func (r *CognitiveTestRepository) Create(results *models.CPTResult) error {
 }
 
 // GetCPTTimelineData retrieves CPT metrics in timeline format
-func (r *CognitiveTestRepository) GetCPTTimelineData(email, metricKey string) ([]TimelineDataPoint, error) {
+func (r *CognitiveTestRepository) GetCPTTimelineData(email, metricKey string, filter ChartFilter) ([]TimelineDataPoint, error) {
 	var results []models.CPTResult
 
 	normalizedEmail := strings.ToLower(email)
 	// Query the database for CPT results for the user, ordered by date
 	err := r.db.Where("LOWER(user_email) = ?", normalizedEmail).
+		Scopes(resultFilter(filter)).
+		Omit("raw_data"). // Charts only need the summary columns
 		Order("created_at ASC").
 		Find(&results).Error
 
@@ -53,46 +55,55 @@ func (r *CognitiveTestRepository) GetCPTTimelineData(email, metricKey string) ([
 		return nil, err
 	}
 
-	// For each result, check if the raw data is compressed and decompress if needed
-	for i := range results {
-		if len(results[i].RawData) > 0 {
-			// Check if data is compressed (assuming you're using the GZIP header approach)
-			if len(results[i].RawData) >= 4 && string(results[i].RawData[0:4]) == "GZIP" {
-				decompressed, err := utils.DecompressData(results[i].RawData)
-				if err != nil {
-					r.log.Warnw("Failed to decompress CPT raw data", "error", err)
-				} else {
-					results[i].RawData = decompressed
-				}
-			}
-		}
+	retrospective, err := retrospectiveAssessments(r.db, normalizedEmail)
+	if err != nil {
+		r.log.Errorw("Error retrieving retrospective assessments", "error", err)
+		return nil, err
 	}
 
 	// Convert to timeline data points
-	timelinePoints := make([]TimelineDataPoint, len(results))
-	for i, result := range results {
+	timelinePoints := make([]TimelineDataPoint, 0, len(results))
+	for _, result := range results {
 		// Initialize with common date
-		timelinePoints[i] = TimelineDataPoint{
+		point := TimelineDataPoint{
 			Date: result.CreatedAt,
 		}
 
+		// Retrospective entries are plotted on the day they describe
+		if day, ok := retrospective[result.AssessmentID]; ok {
+			if !filter.IncludeRetrospective {
+				continue
+			}
+			point.Date = day
+			point.IsRetrospective = true
+		}
+		if !filter.Contains(point.Date) {
+			continue
+		}
+
 		// Set the appropriate metric value based on the metric key
 		switch metricKey {
 		case "reaction_time":
-			timelinePoints[i].MetricValue = result.AverageReactionTime
+			point.MetricValue = result.AverageReactionTime
 			// Use a constant value or 0 for the symptom since there's no direct symptom correlation
-			timelinePoints[i].SymptomValue = 0
+			point.SymptomValue = 0
 		case "detection_rate":
-			timelinePoints[i].MetricValue = result.DetectionRate
-			timelinePoints[i].SymptomValue = 0
+			point.MetricValue = result.DetectionRate
+			point.SymptomValue = 0
 		case "omission_error_rate":
-			timelinePoints[i].MetricValue = result.OmissionErrorRate
-			timelinePoints[i].SymptomValue = 0
+			point.MetricValue = result.OmissionErrorRate
+			point.SymptomValue = 0
 		case "commission_error_rate":
-			timelinePoints[i].MetricValue = result.CommissionErrorRate
-			timelinePoints[i].SymptomValue = 0
+			point.MetricValue = result.CommissionErrorRate
+			point.SymptomValue = 0
 		}
+
+		timelinePoints = append(timelinePoints, point)
 	}
 
+	sort.SliceStable(timelinePoints, func(i, j int) bool {
+		return timelinePoints[i].Date.Before(timelinePoints[j].Date)
+	})
+
 	return timelinePoints, nil
 }
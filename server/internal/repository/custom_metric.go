@@ -0,0 +1,248 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/andevellicus/crapp/internal/metrics"
+	"github.com/andevellicus/crapp/internal/models"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// CustomMetricRepository handles admin-defined metric formulas
+type CustomMetricRepository struct {
+	db  *gorm.DB
+	log *zap.SugaredLogger
+}
+
+// NewCustomMetricRepository creates a new custom metric repository
+func NewCustomMetricRepository(db *gorm.DB, log *zap.SugaredLogger) *CustomMetricRepository {
+	return &CustomMetricRepository{
+		db:  db,
+		log: log.Named("custom-metric-repo"),
+	}
+}
+
+// List returns every custom metric, by key
+func (r *CustomMetricRepository) List() ([]models.CustomMetric, error) {
+	customMetrics := []models.CustomMetric{}
+	err := r.db.Order("key").Find(&customMetrics).Error
+	return customMetrics, err
+}
+
+// Get retrieves a custom metric
+func (r *CustomMetricRepository) Get(id uint) (*models.CustomMetric, error) {
+	var metric models.CustomMetric
+	if err := r.db.Where("id = ?", id).First(&metric).Error; err != nil {
+		return nil, err
+	}
+	return &metric, nil
+}
+
+// Create stores a new custom metric
+func (r *CustomMetricRepository) Create(metric *models.CustomMetric) error {
+	if err := r.db.Create(metric).Error; err != nil {
+		r.log.Errorw("Database error creating custom metric", "error", err, "key", metric.Key)
+		return fmt.Errorf("failed to create custom metric: %w", err)
+	}
+	return nil
+}
+
+// Update saves changes to a custom metric
+func (r *CustomMetricRepository) Update(metric *models.CustomMetric) error {
+	if err := r.db.Save(metric).Error; err != nil {
+		r.log.Errorw("Database error updating custom metric", "error", err, "id", metric.ID)
+		return fmt.Errorf("failed to update custom metric: %w", err)
+	}
+	return nil
+}
+
+// Delete removes a custom metric definition. Its stored values are removed
+// separately with Repository.DeleteMetricValues.
+func (r *CustomMetricRepository) Delete(metric *models.CustomMetric) error {
+	return r.db.Delete(metric).Error
+}
+
+// MarkRecomputed records that stored values are current as of a moment
+func (r *CustomMetricRepository) MarkRecomputed(at time.Time) error {
+	return r.db.Model(&models.CustomMetric{}).
+		Where("updated_at <= ?", at).
+		Update("recomputed_at", at).Error
+}
+
+// Pending reports whether any custom metric changed since it was last recomputed
+func (r *CustomMetricRepository) Pending() (bool, error) {
+	var count int64
+	err := r.db.Model(&models.CustomMetric{}).
+		Where("recomputed_at IS NULL OR recomputed_at < updated_at").
+		Count(&count).Error
+	return count > 0, err
+}
+
+// Formulas parses every custom metric's formula, keyed by metric key.
+// Formulas that no longer parse are logged and skipped.
+func (r *CustomMetricRepository) Formulas() (map[string]*metrics.Formula, error) {
+	customMetrics, err := r.List()
+	if err != nil {
+		return nil, err
+	}
+	formulas := make(map[string]*metrics.Formula, len(customMetrics))
+	for _, metric := range customMetrics {
+		formula, err := metrics.ParseFormula(metric.Formula)
+		if err != nil {
+			r.log.Warnw("Skipping custom metric with invalid formula", "key", metric.Key, "error", err)
+			continue
+		}
+		formulas[metric.Key] = formula
+	}
+	return formulas, nil
+}
+
+// Version changes whenever a custom metric or its stored values do, for
+// chart cache validation
+func (r *CustomMetricRepository) Version() (string, error) {
+	var row struct {
+		Count  int64
+		Latest *time.Time
+	}
+	err := r.db.Model(&models.CustomMetric{}).
+		Select("COUNT(*) AS count, MAX(GREATEST(updated_at, COALESCE(recomputed_at, updated_at))) AS latest").
+		Scan(&row).Error
+	if err != nil {
+		return "", err
+	}
+
+	version := fmt.Sprintf("%d", row.Count)
+	if row.Latest != nil {
+		version += "-" + row.Latest.UTC().Format(time.RFC3339Nano)
+	}
+	return version, nil
+}
+
+// ApplyCustomMetrics computes custom metrics for assessments from the
+// metrics recorded for each of their questions, replacing stored values of
+// the same keys. A formula is skipped for a question that lacks one of its
+// inputs.
+func (r *AssessmentRepository) ApplyCustomMetrics(tx *gorm.DB, assessmentIDs []uint, formulas map[string]*metrics.Formula) error {
+	if len(assessmentIDs) == 0 {
+		return nil
+	}
+	if tx == nil {
+		tx = r.db
+	}
+
+	if len(formulas) == 0 {
+		return nil
+	}
+	customKeys := make([]string, 0, len(formulas))
+	for key := range formulas {
+		customKeys = append(customKeys, key)
+	}
+	if err := tx.Where("assessment_id IN ? AND metric_key IN ?", assessmentIDs, customKeys).
+		Delete(&models.AssessmentMetric{}).Error; err != nil {
+		return err
+	}
+
+	var recorded []models.AssessmentMetric
+	if err := tx.Where("assessment_id IN ? AND metric_key NOT IN ?", assessmentIDs, customKeys).Find(&recorded).Error; err != nil {
+		return err
+	}
+
+	type questionKey struct {
+		assessmentID uint
+		questionID   string
+	}
+	values := make(map[questionKey]map[string]float64)
+	samples := make(map[questionKey]int)
+	for _, m := range recorded {
+		k := questionKey{m.AssessmentID, m.QuestionID}
+		if values[k] == nil {
+			values[k] = make(map[string]float64)
+		}
+		values[k][m.MetricKey] = m.MetricValue
+		samples[k] = max(samples[k], m.SampleSize)
+	}
+
+	now := time.Now()
+	var computed []models.AssessmentMetric
+	for k, inputs := range values {
+		for key, formula := range formulas {
+			value, err := formula.Evaluate(inputs)
+			if err != nil {
+				continue
+			}
+			computed = append(computed, models.AssessmentMetric{
+				AssessmentID: k.assessmentID,
+				QuestionID:   k.questionID,
+				MetricKey:    key,
+				MetricValue:  value,
+				SampleSize:   samples[k],
+				CreatedAt:    now,
+			})
+		}
+	}
+	if len(computed) == 0 {
+		return nil
+	}
+	return tx.Omit("Assessment").CreateInBatches(computed, 500).Error
+}
+
+// RecomputeCustomMetrics rebuilds stored custom metric values for every
+// assessment, a batch at a time, and refreshes their chart summaries.
+// Returns how many assessments were processed.
+func (r *Repository) RecomputeCustomMetrics(formulas map[string]*metrics.Formula, batchSize int) (int, error) {
+	var lastID uint
+	processed := 0
+	for {
+		var ids []uint
+		err := r.db.Model(&models.Assessment{}).
+			Where("id > ?", lastID).
+			Order("id ASC").
+			Limit(batchSize).
+			Pluck("id", &ids).Error
+		if err != nil {
+			return processed, err
+		}
+		if len(ids) == 0 {
+			return processed, nil
+		}
+
+		err = r.db.Transaction(func(tx *gorm.DB) error {
+			return r.Assessments.ApplyCustomMetrics(tx, ids, formulas)
+		})
+		if err != nil {
+			return processed, fmt.Errorf("failed to recompute custom metrics: %w", err)
+		}
+		if err := r.ChartSummaries.Refresh(ids...); err != nil {
+			r.log.Warnw("Chart summary refresh after custom metric recompute failed", "error", err)
+		}
+
+		processed += len(ids)
+		lastID = ids[len(ids)-1]
+	}
+}
+
+// DeleteMetricValues removes every stored value of a metric key, including
+// its chart summary rows
+func (r *Repository) DeleteMetricValues(key string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("metric_key = ?", key).Delete(&models.ChartSummary{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("metric_key = ?", key).Delete(&models.AssessmentMetric{}).Error
+	})
+}
+
+// Definitions returns the custom metrics as registry definitions
+func (r *CustomMetricRepository) Definitions() ([]metrics.Definition, error) {
+	customMetrics, err := r.List()
+	if err != nil {
+		return nil, err
+	}
+	defs := make([]metrics.Definition, 0, len(customMetrics))
+	for _, metric := range customMetrics {
+		defs = append(defs, metrics.Definition{Key: metric.Key, Label: metric.Label, Group: metric.Group})
+	}
+	return defs, nil
+}
@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/andevellicus/crapp/internal/models"
+	"gorm.io/gorm"
+)
+
+// SyntheticAssessment is a made-up assessment with its answers and metrics,
+// given to demo accounts
+type SyntheticAssessment struct {
+	SubmittedAt time.Time
+	Day         time.Time // The assessment day it counts towards
+	Responses   []models.QuestionResponse
+	Metrics     []models.AssessmentMetric
+}
+
+// CreateSynthetic saves made-up assessments for a user and returns their IDs
+func (r *AssessmentRepository) CreateSynthetic(email string, assessments []SyntheticAssessment) ([]uint, error) {
+	normalizedEmail := strings.ToLower(email)
+	ids := make([]uint, 0, len(assessments))
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		for _, synthetic := range assessments {
+			day := synthetic.Day
+			assessment := models.Assessment{
+				UserEmail:          normalizedEmail,
+				DeviceID:           "demo",
+				SubmittedAt:        synthetic.SubmittedAt,
+				LocationPermission: "unavailable",
+				AssessmentDay:      &day,
+			}
+			if err := tx.Create(&assessment).Error; err != nil {
+				return fmt.Errorf("error creating synthetic assessment: %w", err)
+			}
+
+			for i := range synthetic.Responses {
+				synthetic.Responses[i].AssessmentID = assessment.ID
+			}
+			for i := range synthetic.Metrics {
+				synthetic.Metrics[i].AssessmentID = assessment.ID
+			}
+			if len(synthetic.Responses) > 0 {
+				if err := tx.Create(&synthetic.Responses).Error; err != nil {
+					return fmt.Errorf("error creating synthetic responses: %w", err)
+				}
+			}
+			if len(synthetic.Metrics) > 0 {
+				if err := tx.Create(&synthetic.Metrics).Error; err != nil {
+					return fmt.Errorf("error creating synthetic metrics: %w", err)
+				}
+			}
+			ids = append(ids, assessment.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		r.log.Errorw("Failed to create synthetic assessments", "email", normalizedEmail, "error", err)
+		return nil, err
+	}
+	return ids, nil
+}
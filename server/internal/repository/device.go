@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/andevellicus/crapp/internal/models"
+	"github.com/andevellicus/crapp/internal/utils"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
 )
@@ -184,6 +185,10 @@ func (r *DeviceRepository) RegisterDevice(email string, deviceInfo map[string]an
 	deviceType, _ := deviceInfo["device_type"].(string)
 	browser, _ := deviceInfo["user_agent"].(string)
 	os, _ := deviceInfo["os"].(string)
+	timezone, _ := deviceInfo["timezone"].(string)
+	if !utils.ValidTimezone(timezone) {
+		timezone = ""
+	}
 
 	if os == "" && deviceInfo["user_agent"] != nil {
 		if userAgent, ok := deviceInfo["user_agent"].(string); ok && userAgent != "" {
@@ -211,6 +216,7 @@ func (r *DeviceRepository) RegisterDevice(email string, deviceInfo map[string]an
 		DeviceType: deviceType,
 		Browser:    browser,
 		OS:         os,
+		Timezone:   timezone,
 		LastActive: time.Now(),
 	}
 
@@ -223,8 +229,12 @@ func (r *DeviceRepository) RegisterDevice(email string, deviceInfo map[string]an
 
 	// If device exists, update it
 	if existingDevice != nil {
-		// Keep created_at from existing device
+		// Keep created_at from existing device, and its time zone if none
+		// was detected this time
 		device.CreatedAt = existingDevice.CreatedAt
+		if device.Timezone == "" {
+			device.Timezone = existingDevice.Timezone
+		}
 		if err := r.Update(device); err != nil {
 			return nil, err
 		}
@@ -3,10 +3,10 @@ package repository
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/andevellicus/crapp/internal/models"
-	"github.com/andevellicus/crapp/internal/utils"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
 )
@@ -53,12 +53,14 @@ func (r *DigitSpanResultRepository) Create(result *models.DigitSpanResult) error
 }
 
 // GetDigitSpanTimelineData retrieves Digit Span metrics for timeline view
-func (r *DigitSpanResultRepository) GetDigitSpanTimelineData(email, metricKey string) ([]TimelineDataPoint, error) {
+func (r *DigitSpanResultRepository) GetDigitSpanTimelineData(email, metricKey string, filter ChartFilter) ([]TimelineDataPoint, error) {
 	var results []models.DigitSpanResult
 
 	normalizedEmail := strings.ToLower(email)
 	// Query the database for Trail Making Test results for the user, ordered by date
 	err := r.db.Where("user_email = ?", normalizedEmail).
+		Scopes(resultFilter(filter)).
+		Omit("raw_data"). // Charts only need the summary columns
 		Order("created_at ASC").
 		Find(&results).Error
 
@@ -67,40 +69,51 @@ func (r *DigitSpanResultRepository) GetDigitSpanTimelineData(email, metricKey st
 		return nil, err
 	}
 
-	// For each result, check if the raw data is compressed and decompress if needed
-	for i := range results {
-		if len(results[i].RawData) > 0 {
-			// Check if data is compressed (assuming you're using the GZIP header approach)
-			if len(results[i].RawData) >= 4 && string(results[i].RawData[0:4]) == "GZIP" {
-				decompressed, err := utils.DecompressData(results[i].RawData)
-				if err != nil {
-					r.log.Warnw("Failed to decompress TMT raw data", "error", err)
-				} else {
-					results[i].RawData = decompressed
-				}
-			}
-		}
+	retrospective, err := retrospectiveAssessments(r.db, normalizedEmail)
+	if err != nil {
+		r.log.Errorw("Error retrieving retrospective assessments", "error", err)
+		return nil, err
 	}
+
 	// Convert to timeline data points
-	timelinePoints := make([]TimelineDataPoint, len(results))
-	for i, result := range results {
+	timelinePoints := make([]TimelineDataPoint, 0, len(results))
+	for _, result := range results {
 		// Initialize with common date
-		timelinePoints[i] = TimelineDataPoint{
+		point := TimelineDataPoint{
 			Date: result.CreatedAt,
 		}
 
+		// Retrospective entries are plotted on the day they describe
+		if day, ok := retrospective[result.AssessmentID]; ok {
+			if !filter.IncludeRetrospective {
+				continue
+			}
+			point.Date = day
+			point.IsRetrospective = true
+		}
+		if !filter.Contains(point.Date) {
+			continue
+		}
+
 		// Determine which field to select based on metricKey
 		switch metricKey {
 		case "highest_span":
-			timelinePoints[i].MetricValue = float64(result.HighestSpanAchieved)
-			timelinePoints[i].SymptomValue = 0
+			point.MetricValue = float64(result.HighestSpanAchieved)
+			point.SymptomValue = 0
 		case "correct_trials":
-			timelinePoints[i].MetricValue = float64(result.CorrectTrials)
-			timelinePoints[i].SymptomValue = 0
+			point.MetricValue = float64(result.CorrectTrials)
+			point.SymptomValue = 0
 		case "total_trials":
-			timelinePoints[i].MetricValue = float64(result.TotalTrials)
-			timelinePoints[i].SymptomValue = 0
+			point.MetricValue = float64(result.TotalTrials)
+			point.SymptomValue = 0
 		}
+
+		timelinePoints = append(timelinePoints, point)
 	}
+
+	sort.SliceStable(timelinePoints, func(i, j int) bool {
+		return timelinePoints[i].Date.Before(timelinePoints[j].Date)
+	})
+
 	return timelinePoints, nil
 }
@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// DistributionBucket counts how often one answer value was given
+type DistributionBucket struct {
+	Value   float64 `json:"value"`
+	Label   string  `json:"label,omitempty"`
+	Count   int64   `json:"count"`
+	Percent float64 `json:"percent"`
+
+	Users      int64 `json:"-"`                    // Distinct users who gave the answer
+	Suppressed bool  `json:"suppressed,omitempty"` // Count withheld, too few users
+}
+
+// ResponseDistribution is the share of assessments giving each answer to a
+// question
+type ResponseDistribution struct {
+	Total      int64                `json:"total"`
+	Users      int64                `json:"users"` // Distinct users who answered
+	Buckets    []DistributionBucket `json:"buckets"`
+	Suppressed bool                 `json:"suppressed,omitempty"` // Withheld entirely, too few users
+}
+
+// GetResponseDistribution counts a question's numeric answers by value. An
+// empty email counts every user's answers. Of the filter, only its
+// retrospective and reporter conditions apply.
+func (r *AssessmentRepository) GetResponseDistribution(email, questionID string, filter ChartFilter) (*ResponseDistribution, error) {
+	var buckets []DistributionBucket
+
+	query := func() *gorm.DB {
+		query := r.db.Table("question_responses qr").
+			Joins("JOIN assessments a ON a.id = qr.assessment_id").
+			Where("qr.question_id = ? AND qr.value_type IN ?", questionID, []string{"number", "boolean"}).
+			Where("a.is_retrospective = false OR ?", filter.IncludeRetrospective).
+			Where("NOT a.likely_bot")
+		if proxy, args := filter.proxySQL("a"); proxy != "" {
+			query = query.Where(proxy, args...)
+		}
+		if email != "" {
+			query = query.Where("LOWER(a.user_email) = ?", strings.ToLower(email))
+		}
+		return query
+	}
+
+	err := query().
+		Select("qr.numeric_value AS value, COUNT(*) AS count, COUNT(DISTINCT LOWER(a.user_email)) AS users").
+		Group("qr.numeric_value").Order("qr.numeric_value").Scan(&buckets).Error
+	if err != nil {
+		r.log.Errorw("Error in distribution query", "error", err, "question_id", questionID)
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+
+	dist := NewResponseDistribution(buckets)
+	if err := query().Select("COUNT(DISTINCT LOWER(a.user_email))").Scan(&dist.Users).Error; err != nil {
+		r.log.Errorw("Error counting distribution users", "error", err, "question_id", questionID)
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	return dist, nil
+}
+
+// NewResponseDistribution totals bucket counts, merging buckets with the same
+// value, and works out each value's percentage
+func NewResponseDistribution(buckets []DistributionBucket) *ResponseDistribution {
+	counts := make(map[float64]int64)
+	users := make(map[float64]int64)
+	var total int64
+	for _, bucket := range buckets {
+		counts[bucket.Value] += bucket.Count
+		users[bucket.Value] += bucket.Users
+		total += bucket.Count
+	}
+
+	dist := &ResponseDistribution{Total: total, Buckets: make([]DistributionBucket, 0, len(counts))}
+	for value, count := range counts {
+		dist.Buckets = append(dist.Buckets, DistributionBucket{
+			Value:   value,
+			Count:   count,
+			Percent: float64(count) * 100 / float64(total),
+			Users:   users[value],
+		})
+	}
+	sort.Slice(dist.Buckets, func(i, j int) bool {
+		return dist.Buckets[i].Value < dist.Buckets[j].Value
+	})
+	return dist
+}
+
+// SuppressSmallCells withholds the counts of answers given by fewer than
+// minUsers users. When only one answer is withheld, the next rarest is too,
+// so it can't be worked out from the total. A distribution from fewer than
+// minUsers users is withheld entirely.
+func (d *ResponseDistribution) SuppressSmallCells(minUsers int) {
+	if minUsers <= 1 {
+		return
+	}
+	if d.Users < int64(minUsers) {
+		d.Total = 0
+		d.Buckets = []DistributionBucket{}
+		d.Suppressed = true
+		return
+	}
+
+	suppressed := 0
+	for i := range d.Buckets {
+		if d.Buckets[i].Users < int64(minUsers) {
+			d.Buckets[i].Suppressed = true
+			suppressed++
+		}
+	}
+	if suppressed == 1 {
+		rarest := -1
+		for i, bucket := range d.Buckets {
+			if !bucket.Suppressed && (rarest < 0 || bucket.Count < d.Buckets[rarest].Count) {
+				rarest = i
+			}
+		}
+		if rarest >= 0 {
+			d.Buckets[rarest].Suppressed = true
+		}
+	}
+
+	for i := range d.Buckets {
+		if d.Buckets[i].Suppressed {
+			d.Buckets[i].Count = 0
+			d.Buckets[i].Percent = 0
+		}
+	}
+}
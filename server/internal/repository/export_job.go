@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/andevellicus/crapp/internal/models"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ExportJobRepository handles persistence of background export jobs
+type ExportJobRepository struct {
+	db  *gorm.DB
+	log *zap.SugaredLogger
+}
+
+// NewExportJobRepository creates a new export job repository
+func NewExportJobRepository(db *gorm.DB, log *zap.SugaredLogger) *ExportJobRepository {
+	return &ExportJobRepository{
+		db:  db,
+		log: log.Named("export-repo"),
+	}
+}
+
+// Create stores a new export job
+func (r *ExportJobRepository) Create(job *models.ExportJob) error {
+	job.CreatedAt = time.Now()
+	if err := r.db.Create(job).Error; err != nil {
+		r.log.Errorw("Database error creating export job", "error", err)
+		return fmt.Errorf("failed to create export job: %w", err)
+	}
+	return nil
+}
+
+// Get retrieves an export job by ID
+func (r *ExportJobRepository) Get(id string) (*models.ExportJob, error) {
+	var job models.ExportJob
+	if err := r.db.Where("id = ?", id).First(&job).Error; err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// ListForUser returns a user's most recent export jobs, newest first
+func (r *ExportJobRepository) ListForUser(email string, limit int) ([]models.ExportJob, error) {
+	jobs := []models.ExportJob{}
+	if err := r.db.Where("created_by = ?", email).
+		Order("created_at DESC").
+		Limit(limit).
+		Find(&jobs).Error; err != nil {
+		r.log.Errorw("Database error listing export jobs", "error", err, "email", email)
+		return nil, err
+	}
+	return jobs, nil
+}
+
+// CountActive counts a user's queued and running export jobs
+func (r *ExportJobRepository) CountActive(email string) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.ExportJob{}).
+		Where("created_by = ? AND status IN ?", email, []string{models.ExportQueued, models.ExportRunning}).
+		Count(&count).Error
+	return count, err
+}
+
+// ClaimNext marks the oldest queued job as running and returns it, or nil
+// when the queue is empty. Jobs locked by another worker are skipped.
+func (r *ExportJobRepository) ClaimNext() (*models.ExportJob, error) {
+	var job models.ExportJob
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ?", models.ExportQueued).
+			Order("created_at").
+			First(&job).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		job.Status = models.ExportRunning
+		if job.StartedAt == nil {
+			job.StartedAt = &now
+		}
+		return tx.Model(&job).Updates(map[string]any{
+			"status":     job.Status,
+			"started_at": job.StartedAt,
+		}).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		r.log.Errorw("Database error claiming export job", "error", err)
+		return nil, err
+	}
+	return &job, nil
+}
+
+// UpdateProgress saves a job's status, progress, and result
+func (r *ExportJobRepository) UpdateProgress(job *models.ExportJob) error {
+	err := r.db.Model(job).Updates(map[string]any{
+		"status":       job.Status,
+		"progress":     job.Progress,
+		"chunks_done":  job.ChunksDone,
+		"storage_key":  job.StorageKey,
+		"error":        job.Error,
+		"completed_at": job.CompletedAt,
+		"expires_at":   job.ExpiresAt,
+	}).Error
+	if err != nil {
+		r.log.Errorw("Database error updating export job", "error", err, "id", job.ID)
+		return fmt.Errorf("failed to update export job: %w", err)
+	}
+	return nil
+}
+
+// RequeueStale puts running jobs that have not made progress since before
+// back in the queue, such as those cut off by a restart. They resume from
+// their last finished chunk.
+func (r *ExportJobRepository) RequeueStale(before time.Time) (int64, error) {
+	result := r.db.Model(&models.ExportJob{}).
+		Where("status = ? AND updated_at < ?", models.ExportRunning, before).
+		Update("status", models.ExportQueued)
+	if result.Error != nil {
+		r.log.Errorw("Database error requeueing export jobs", "error", result.Error)
+		return 0, result.Error
+	}
+	return result.RowsAffected, nil
+}
+
+// ListExpired returns finished jobs whose files are due for removal
+func (r *ExportJobRepository) ListExpired(now time.Time) ([]models.ExportJob, error) {
+	jobs := []models.ExportJob{}
+	err := r.db.Where("status = ? AND storage_key <> '' AND expires_at < ?", models.ExportDone, now).
+		Find(&jobs).Error
+	return jobs, err
+}
+
+// ClearFile records that a job's file has been removed
+func (r *ExportJobRepository) ClearFile(id string) error {
+	return r.db.Model(&models.ExportJob{}).Where("id = ?", id).Update("storage_key", "").Error
+}
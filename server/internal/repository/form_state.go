@@ -1,6 +1,8 @@
 package repository
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"strings"
@@ -17,6 +19,21 @@ type FormStateRepository struct {
 	log *zap.SugaredLogger
 }
 
+// FormStateScope distinguishes form states a user may have open at the same time
+type FormStateScope struct {
+	ReportedBy     *string    // Caregiver filling in the form, if any
+	AssessmentDate *time.Time // Past day being backfilled, if retrospective
+}
+
+// FormOrder is the question order of a new form state and how it was chosen
+type FormOrder struct {
+	Indexes []int  // Positions in the question list, in the order shown
+	Scheme  string // utils.OrderingRandom, OrderingFixed or OrderingLatinSquare
+	Seed    *int64 // Shuffle seed, for random orders
+	Row     *int   // Latin square row, for counterbalanced orders
+	Variant string // Adaptive form variant, if adaptive mode is on
+}
+
 // NewFormStateRepository creates a new user repository
 func NewFormStateRepository(db *gorm.DB, log *zap.SugaredLogger) *FormStateRepository {
 	return &FormStateRepository{
@@ -25,28 +42,48 @@ func NewFormStateRepository(db *gorm.DB, log *zap.SugaredLogger) *FormStateRepos
 	}
 }
 
-// CreateFormState creates a new form session for a user
-func (r *FormStateRepository) Create(email string, questionOrder []int) (*models.FormState, error) {
+// CreateFormState creates a new form session for a user, keeping how its
+// question order was chosen
+func (r *FormStateRepository) Create(email string, scope FormStateScope, order FormOrder) (*models.FormState, error) {
 	normalizedEmail := strings.ToLower(email)
-	questionOrderBytes, _ := json.Marshal(questionOrder)
+	questionOrderBytes, _ := json.Marshal(order.Indexes)
+	nonce, err := formNonce()
+	if err != nil {
+		return nil, err
+	}
 	formState := &models.FormState{
-		ID:            uuid.New().String(),
-		UserEmail:     normalizedEmail,
-		CurrentStep:   0,
-		Answers:       models.JSON{},
-		QuestionOrder: string(questionOrderBytes),
-		StartedAt:     time.Now(),
-		LastUpdatedAt: time.Now(),
+		ID:             uuid.New().String(),
+		UserEmail:      normalizedEmail,
+		ReportedBy:     scope.ReportedBy,
+		AssessmentDate: scope.AssessmentDate,
+		CurrentStep:    0,
+		Answers:        models.JSON{},
+		QuestionOrder:  string(questionOrderBytes),
+		OrderScheme:    order.Scheme,
+		OrderSeed:      order.Seed,
+		OrderRow:       order.Row,
+		FormVariant:    order.Variant,
+		Nonce:          nonce,
+		StartedAt:      time.Now(),
+		LastUpdatedAt:  time.Now(),
 	}
 
-	err := r.db.Create(formState).Error
-	if err != nil {
+	if err := r.db.Create(formState).Error; err != nil {
 		return nil, err
 	}
 
 	return formState, nil
 }
 
+// formNonce returns a random nonce for a new form
+func formNonce() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate form nonce: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
 func (r *FormStateRepository) GetByID(stateID string) (*models.FormState, error) {
 	if stateID == "" {
 		return nil, fmt.Errorf("stateID cannot be empty")
@@ -90,11 +127,13 @@ func (r *FormStateRepository) Update(formState *models.FormState) error {
         UPDATE form_states 
         SET current_step = ?,
 			answers = ?,
+			step_durations = ?,
             last_updated_at = ?,
 			assessment_id = ?
         WHERE id = ? AND LOWER(user_email) = ?`,
 		formState.CurrentStep,
 		formState.Answers,
+		formState.StepDurations,
 		formState.LastUpdatedAt,
 		formState.AssessmentID,
 		formState.ID,
@@ -113,11 +152,13 @@ func (r *FormStateRepository) Update(formState *models.FormState) error {
 		result = r.db.Exec(`
             UPDATE form_states 
             SET interaction_data = ?,
+                integrity_hash = ?,
                 cpt_data = ?,
                 tmt_data = ?,
 				digit_span_data = ?
             WHERE id = ? AND LOWER(user_email) = ?`,
 			formState.InteractionData,
+			formState.IntegrityHash,
 			formState.CPTData,
 			formState.TMTData,
 			formState.DigitSpanData,
@@ -146,12 +187,35 @@ func (r *FormStateRepository) Delete(id string) error {
 	return nil
 }
 
-// GetUserActiveFormState gets a user's most recent active form state
-func (r *FormStateRepository) GetUserActiveFormState(email string) (*models.FormState, error) {
+// UpdateClock stores a form state's clock skew and drift
+func (r *FormStateRepository) UpdateClock(id string, skewMs *float64, driftMs float64) error {
+	err := r.db.Model(&models.FormState{}).
+		Where("id = ?", id).
+		Updates(map[string]any{"clock_skew_ms": skewMs, "clock_drift_ms": driftMs}).Error
+	if err != nil {
+		r.log.Errorw("Failed to update form state clock", "error", err, "id", id)
+		return fmt.Errorf("failed to update form state clock: %w", err)
+	}
+	return nil
+}
+
+// GetUserActiveFormState gets a user's most recent active form state within the given scope
+func (r *FormStateRepository) GetUserActiveFormState(email string, scope FormStateScope) (*models.FormState, error) {
 	var formState models.FormState
 
 	normalizedEmail := strings.ToLower(email)
-	err := r.db.Where("LOWER(user_email) = ? AND assessment_id IS NULL", normalizedEmail).
+	query := r.db.Where("LOWER(user_email) = ? AND assessment_id IS NULL", normalizedEmail)
+	if scope.ReportedBy != nil {
+		query = query.Where("reported_by = ?", *scope.ReportedBy)
+	} else {
+		query = query.Where("reported_by IS NULL")
+	}
+	if scope.AssessmentDate != nil {
+		query = query.Where("assessment_date = ?", scope.AssessmentDate.Format("2006-01-02"))
+	} else {
+		query = query.Where("assessment_date IS NULL")
+	}
+	err := query.
 		Order("last_updated_at DESC").
 		First(&formState).Error
 
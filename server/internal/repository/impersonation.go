@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/andevellicus/crapp/internal/models"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// ImpersonationRepository handles persistence of admin impersonation sessions
+type ImpersonationRepository struct {
+	db  *gorm.DB
+	log *zap.SugaredLogger
+}
+
+// NewImpersonationRepository creates a new impersonation repository
+func NewImpersonationRepository(db *gorm.DB, log *zap.SugaredLogger) *ImpersonationRepository {
+	return &ImpersonationRepository{
+		db:  db,
+		log: log.Named("impersonation-repo"),
+	}
+}
+
+// Create stores a new impersonation session
+func (r *ImpersonationRepository) Create(session *models.ImpersonationSession) error {
+	session.AdminEmail = strings.ToLower(session.AdminEmail)
+	session.TargetEmail = strings.ToLower(session.TargetEmail)
+	session.CreatedAt = time.Now()
+
+	if err := r.db.Create(session).Error; err != nil {
+		r.log.Errorw("Database error creating impersonation session", "error", err)
+		return fmt.Errorf("failed to create impersonation session: %w", err)
+	}
+	return nil
+}
+
+// Get retrieves an impersonation session by ID
+func (r *ImpersonationRepository) Get(id string) (*models.ImpersonationSession, error) {
+	var session models.ImpersonationSession
+	if err := r.db.Where("id = ?", id).First(&session).Error; err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// Update saves changes to an impersonation session
+func (r *ImpersonationRepository) Update(session *models.ImpersonationSession) error {
+	if err := r.db.Save(session).Error; err != nil {
+		r.log.Errorw("Database error updating impersonation session", "id", session.ID, "error", err)
+		return fmt.Errorf("failed to update impersonation session: %w", err)
+	}
+	return nil
+}
+
+// IsActive reports whether a session is still running. Sessions that ran past
+// their expiry are marked ended.
+func (r *ImpersonationRepository) IsActive(id string) (bool, error) {
+	session, err := r.Get(id)
+	if err != nil {
+		return false, err
+	}
+	if session.Status != models.ImpersonationActive {
+		return false, nil
+	}
+	if session.ExpiresAt != nil && session.ExpiresAt.Before(time.Now()) {
+		session.Status = models.ImpersonationEnded
+		session.EndedAt = session.ExpiresAt
+		session.EndedBy = "expired"
+		return false, r.Update(session)
+	}
+	return true, nil
+}
+
+// ListPendingForTarget lists the requests awaiting a participant's consent
+func (r *ImpersonationRepository) ListPendingForTarget(email string) ([]models.ImpersonationSession, error) {
+	sessions := []models.ImpersonationSession{}
+	err := r.db.Where("target_email = ? AND status = ?", strings.ToLower(email), models.ImpersonationPending).
+		Order("created_at DESC").
+		Find(&sessions).Error
+	return sessions, err
+}
+
+// List returns recent impersonation sessions, newest first, optionally for one admin
+func (r *ImpersonationRepository) List(adminEmail string, limit int) ([]models.ImpersonationSession, error) {
+	sessions := []models.ImpersonationSession{}
+	query := r.db.Order("created_at DESC").Limit(limit)
+	if adminEmail != "" {
+		query = query.Where("admin_email = ?", strings.ToLower(adminEmail))
+	}
+	if err := query.Find(&sessions).Error; err != nil {
+		r.log.Errorw("Database error listing impersonation sessions", "error", err)
+		return nil, err
+	}
+	return sessions, nil
+}
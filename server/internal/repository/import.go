@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/andevellicus/crapp/internal/models"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// UserImportRepository handles persistence of bulk user import jobs
+type UserImportRepository struct {
+	db  *gorm.DB
+	log *zap.SugaredLogger
+}
+
+// NewUserImportRepository creates a new user import repository
+func NewUserImportRepository(db *gorm.DB, log *zap.SugaredLogger) *UserImportRepository {
+	return &UserImportRepository{
+		db:  db,
+		log: log.Named("import-repo"),
+	}
+}
+
+// Create stores a new import job
+func (r *UserImportRepository) Create(job *models.UserImportJob) error {
+	job.CreatedAt = time.Now()
+	if job.Results == "" {
+		job.Results = "[]"
+	}
+
+	if err := r.db.Create(job).Error; err != nil {
+		r.log.Errorw("Database error creating import job", "error", err)
+		return fmt.Errorf("failed to create import job: %w", err)
+	}
+	return nil
+}
+
+// Get retrieves an import job by ID
+func (r *UserImportRepository) Get(id string) (*models.UserImportJob, error) {
+	var job models.UserImportJob
+	if err := r.db.Where("id = ?", id).First(&job).Error; err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// UpdateProgress saves a job's counters, status, and row results
+func (r *UserImportRepository) UpdateProgress(job *models.UserImportJob, results []models.UserImportRowResult) error {
+	resultsJSON, err := json.Marshal(results)
+	if err != nil {
+		return fmt.Errorf("failed to encode import results: %w", err)
+	}
+
+	err = r.db.Model(job).Updates(map[string]any{
+		"status":         job.Status,
+		"processed_rows": job.ProcessedRows,
+		"succeeded_rows": job.SucceededRows,
+		"failed_rows":    job.FailedRows,
+		"results":        string(resultsJSON),
+		"error":          job.Error,
+		"completed_at":   job.CompletedAt,
+	}).Error
+	if err != nil {
+		r.log.Errorw("Database error updating import job", "error", err, "id", job.ID)
+		return fmt.Errorf("failed to update import job: %w", err)
+	}
+	return nil
+}
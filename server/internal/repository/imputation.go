@@ -0,0 +1,150 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+)
+
+// Ways of filling in missing symptom values on correlation charts
+const (
+	ImputeCarryForward = "carry_forward" // Repeat the last reported value
+	ImputeInterpolate  = "interpolate"   // Straight line between the reported values either side
+)
+
+// IsImputeMethod reports whether an imputation method is supported
+func IsImputeMethod(method string) bool {
+	return method == ImputeCarryForward || method == ImputeInterpolate
+}
+
+// CoverageDay is one assessment's symptom and metric values, either of
+// which may be missing
+type CoverageDay struct {
+	Date         time.Time `json:"date"`
+	SymptomValue *float64  `json:"symptom_value"`
+	MetricValue  *float64  `json:"metric_value"`
+}
+
+// MissingDataReport describes the assessments left out of a correlation
+// because a value was missing, and any symptom values filled in
+type MissingDataReport struct {
+	TotalDays      int      `json:"total_days"`
+	UsedPoints     int      `json:"used_points"`
+	DroppedPoints  int      `json:"dropped_points"`
+	MissingSymptom []string `json:"missing_symptom_dates"`
+	MissingMetric  []string `json:"missing_metric_dates"`
+	Method         string   `json:"impute_method,omitempty"`
+	ImputedPoints  int      `json:"imputed_points"`
+	ImputedDates   []string `json:"imputed_dates,omitempty"`
+}
+
+// GetCorrelationCoverage lists every assessment with its symptom and metric
+// values, including those missing one or both, in date order
+func (r *AssessmentRepository) GetCorrelationCoverage(userID, symptomKey, metricKey string, filter ChartFilter) ([]CoverageDay, error) {
+	var result []CoverageDay
+
+	conditions, filterArgs := filter.assessmentSQL("a", r.days)
+	query := `
+		SELECT
+			COALESCE(a.assessment_date, ` + r.days.SQL("a.submitted_at") + `) as date,
+			qr.numeric_value as symptom_value,
+			am.metric_value
+		FROM
+			assessments a
+			LEFT JOIN question_responses qr ON qr.assessment_id = a.id AND qr.question_id = ?
+			LEFT JOIN assessment_metrics am ON am.assessment_id = a.id AND am.question_id = ? AND am.metric_key = ?
+		WHERE
+			LOWER(a.user_email) = LOWER(?)` + conditions + `
+		ORDER BY date ASC, a.submitted_at ASC
+	`
+
+	args := append([]any{symptomKey, symptomKey, metricKey, userID}, filterArgs...)
+	err := r.db.Raw(query, args...).Scan(&result).Error
+	if err != nil {
+		r.log.Errorw("Error in correlation coverage query", "error", err)
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	return result, nil
+}
+
+// ImputeCorrelation builds correlation points from date-ordered coverage
+// days and reports what was dropped. With an imputation method, days that
+// have a metric but no symptom value get an estimated, flagged symptom value;
+// days without a metric are always dropped.
+func ImputeCorrelation(days []CoverageDay, method string) ([]CorrelationDataPoint, *MissingDataReport) {
+	report := &MissingDataReport{
+		TotalDays:      len(days),
+		MissingSymptom: []string{},
+		MissingMetric:  []string{},
+		Method:         method,
+	}
+	points := []CorrelationDataPoint{}
+
+	for i, day := range days {
+		date := day.Date.Format("2006-01-02")
+		if day.SymptomValue == nil {
+			report.MissingSymptom = append(report.MissingSymptom, date)
+		}
+		if day.MetricValue == nil {
+			report.MissingMetric = append(report.MissingMetric, date)
+			report.DroppedPoints++
+			continue
+		}
+
+		if day.SymptomValue != nil {
+			points = append(points, CorrelationDataPoint{SymptomValue: *day.SymptomValue, MetricValue: *day.MetricValue})
+			continue
+		}
+
+		value, ok := imputeSymptom(days, i, method)
+		if !ok {
+			report.DroppedPoints++
+			continue
+		}
+		points = append(points, CorrelationDataPoint{SymptomValue: value, MetricValue: *day.MetricValue, Imputed: true})
+		report.ImputedPoints++
+		report.ImputedDates = append(report.ImputedDates, date)
+	}
+
+	report.UsedPoints = len(points)
+	return points, report
+}
+
+// imputeSymptom estimates the symptom value of days[i] from the reported
+// values around it
+func imputeSymptom(days []CoverageDay, i int, method string) (float64, bool) {
+	prev := -1
+	for j := i - 1; j >= 0; j-- {
+		if days[j].SymptomValue != nil {
+			prev = j
+			break
+		}
+	}
+
+	switch method {
+	case ImputeCarryForward:
+		if prev < 0 {
+			return 0, false
+		}
+		return *days[prev].SymptomValue, true
+
+	case ImputeInterpolate:
+		next := -1
+		for j := i + 1; j < len(days); j++ {
+			if days[j].SymptomValue != nil {
+				next = j
+				break
+			}
+		}
+		if prev < 0 || next < 0 {
+			return 0, false
+		}
+		span := days[next].Date.Sub(days[prev].Date)
+		before, after := *days[prev].SymptomValue, *days[next].SymptomValue
+		if span <= 0 {
+			return before, true
+		}
+		frac := float64(days[i].Date.Sub(days[prev].Date)) / float64(span)
+		return before + (after-before)*frac, true
+	}
+	return 0, false
+}
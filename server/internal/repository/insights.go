@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DailyValue is the mean of one series on one assessment day
+type DailyValue struct {
+	Series string // Metric key or question ID
+	Date   time.Time
+	Value  float64
+}
+
+// GetDailySeries returns a user's daily means since a day. Each metric key is
+// averaged over the questions it was recorded on, cognitive test scores
+// included. Each question ID gives the day's numeric answer. Retrospective
+// entries are left out, as are metrics from assessments flagged for a slow
+// device or a drifting clock, whose timings can't be trusted. Metrics from
+// reports a caregiver made for the user measure the caregiver, so they are
+// always left out; those reports' answers count only with includeProxy.
+func (r *AssessmentRepository) GetDailySeries(email string, since time.Time, metricKeys, questionIDs []string, includeProxy bool) ([]DailyValue, error) {
+	normalizedEmail := strings.ToLower(email)
+	day := r.assessmentDaySQL()
+	sinceDate := since.Format("2006-01-02")
+	var result []DailyValue
+
+	if len(metricKeys) > 0 {
+		var rows []DailyValue
+		err := r.db.Table(reviewMetricRows).
+			Select("m.metric_key AS series, "+day+" AS date, AVG(m.metric_value) AS value").
+			Joins("JOIN assessments a ON a.id = m.assessment_id").
+			Where("LOWER(a.user_email) = ? AND "+day+" >= ?", normalizedEmail, sinceDate).
+			Where("NOT a.is_retrospective AND NOT a.slow_device AND NOT a.clock_drift AND NOT a.likely_bot").
+			Where("a.reported_by IS NULL").
+			Where("m.metric_key IN ?", metricKeys).
+			Group("m.metric_key, date").
+			Order("date").
+			Scan(&rows).Error
+		if err != nil {
+			r.log.Errorw("Error loading daily metric series", "error", err)
+			return nil, fmt.Errorf("database error: %w", err)
+		}
+		result = append(result, rows...)
+	}
+
+	if len(questionIDs) > 0 {
+		var rows []DailyValue
+		err := r.db.Table("question_responses qr").
+			Select("qr.question_id AS series, "+day+" AS date, AVG(qr.numeric_value) AS value").
+			Joins("JOIN assessments a ON a.id = qr.assessment_id").
+			Where("LOWER(a.user_email) = ? AND "+day+" >= ?", normalizedEmail, sinceDate).
+			Where("NOT a.is_retrospective AND NOT a.likely_bot").
+			Where("a.reported_by IS NULL OR ?", includeProxy).
+			Where("qr.question_id IN ? AND qr.value_type = ?", questionIDs, "number").
+			Group("qr.question_id, date").
+			Order("date").
+			Scan(&rows).Error
+		if err != nil {
+			r.log.Errorw("Error loading daily answer series", "error", err)
+			return nil, fmt.Errorf("database error: %w", err)
+		}
+		result = append(result, rows...)
+	}
+	return result, nil
+}
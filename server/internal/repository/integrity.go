@@ -0,0 +1,195 @@
+package repository
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// integritySampleSize is how many offending row IDs a finding lists
+const integritySampleSize = 5
+
+// integrityCheck finds rows that are inconsistent in ways foreign keys don't
+// catch, mostly because tenant tables reference shared ones across schemas
+type integrityCheck struct {
+	name        string
+	description string
+	shared      bool   // Runs once against the shared schema
+	from        string // Table and condition selecting the offending rows
+	key         string // Column identifying an offending row
+	repairable  bool   // Offending rows can be deleted safely
+}
+
+var integrityChecks = []integrityCheck{
+	{
+		name:        "orphaned_metrics",
+		description: "Assessment metrics whose assessment no longer exists",
+		from:        "assessment_metrics m WHERE NOT EXISTS (SELECT 1 FROM assessments a WHERE a.id = m.assessment_id)",
+		key:         "m.id",
+		repairable:  true,
+	},
+	{
+		name:        "orphaned_responses",
+		description: "Question responses whose assessment no longer exists",
+		from:        "question_responses q WHERE NOT EXISTS (SELECT 1 FROM assessments a WHERE a.id = q.assessment_id)",
+		key:         "q.id",
+		repairable:  true,
+	},
+	{
+		name:        "orphaned_chart_summaries",
+		description: "Chart summary points whose assessment no longer exists; they are rebuilt on demand",
+		from:        "chart_summaries s WHERE NOT EXISTS (SELECT 1 FROM assessments a WHERE a.id = s.assessment_id)",
+		key:         "s.id",
+		repairable:  true,
+	},
+	{
+		name:        "orphaned_cpt_results",
+		description: "CPT results not linked to an existing assessment",
+		from:        "cpt_results r WHERE NOT EXISTS (SELECT 1 FROM assessments a WHERE a.id = r.assessment_id)",
+		key:         "r.id",
+	},
+	{
+		name:        "orphaned_tmt_results",
+		description: "Trail Making Test results not linked to an existing assessment",
+		from:        "tmt_results r WHERE NOT EXISTS (SELECT 1 FROM assessments a WHERE a.id = r.assessment_id)",
+		key:         "r.id",
+	},
+	{
+		name:        "orphaned_digit_span_results",
+		description: "Digit span results not linked to an existing assessment",
+		from:        "digit_span_results r WHERE NOT EXISTS (SELECT 1 FROM assessments a WHERE a.id = r.assessment_id)",
+		key:         "r.id",
+	},
+	{
+		name:        "assessments_without_responses",
+		description: "Submitted assessments with no question responses",
+		from:        "assessments a WHERE NOT EXISTS (SELECT 1 FROM question_responses q WHERE q.assessment_id = a.id)",
+		key:         "a.id",
+	},
+	{
+		name:        "assessments_without_user",
+		description: "Assessments belonging to a user that no longer exists",
+		from:        "assessments a WHERE NOT EXISTS (SELECT 1 FROM users u WHERE LOWER(u.email) = LOWER(a.user_email))",
+		key:         "a.id",
+	},
+	{
+		name:        "form_states_missing_assessment",
+		description: "Submitted form states pointing at an assessment that no longer exists",
+		from:        "form_states f WHERE f.assessment_id IS NOT NULL AND NOT EXISTS (SELECT 1 FROM assessments a WHERE a.id = f.assessment_id)",
+		key:         "f.id",
+		repairable:  true,
+	},
+	{
+		name:        "tokens_without_device",
+		description: "Active refresh tokens for a device that no longer exists, including users with no devices",
+		shared:      true,
+		from:        "refresh_tokens t WHERE t.revoked_at IS NULL AND t.expires_at > NOW() AND NOT EXISTS (SELECT 1 FROM devices d WHERE d.id = t.device_id)",
+		key:         "t.token_id",
+		repairable:  true,
+	},
+	{
+		name:        "tokens_without_user",
+		description: "Refresh tokens for a user that no longer exists",
+		shared:      true,
+		from:        "refresh_tokens t WHERE NOT EXISTS (SELECT 1 FROM users u WHERE LOWER(u.email) = LOWER(t.user_email))",
+		key:         "t.token_id",
+		repairable:  true,
+	},
+}
+
+// IntegrityIssue is the result of one integrity check
+type IntegrityIssue struct {
+	Check       string   `json:"check"`
+	Description string   `json:"description"`
+	Count       int64    `json:"count"`
+	Sample      []string `json:"sample,omitempty"` // IDs of some offending rows
+	Repairable  bool     `json:"repairable"`
+	Repaired    int64    `json:"repaired,omitempty"`
+}
+
+// IntegrityReport lists the problems found in one schema
+type IntegrityReport struct {
+	Organization string           `json:"organization,omitempty"` // Empty for the shared schema
+	CheckedAt    time.Time        `json:"checked_at"`
+	Issues       []IntegrityIssue `json:"issues"`
+}
+
+// CheckIntegrity runs the integrity checks against the shared schema and
+// every organization schema. With repair set, repairable rows are deleted,
+// one transaction per schema.
+func (r *Repository) CheckIntegrity(repair bool) ([]IntegrityReport, error) {
+	report, err := r.checkIntegrity(repair, true)
+	if err != nil {
+		return nil, err
+	}
+	reports := []IntegrityReport{*report}
+
+	if r.tenants == nil || !r.cfg.Tenancy.SchemaPerOrganization {
+		return reports, nil
+	}
+
+	orgs, err := r.Organizations.List()
+	if err != nil {
+		return nil, err
+	}
+	for _, org := range orgs {
+		if org.Schema == "" {
+			continue
+		}
+		tenant := r.ForOrganization(org.ID)
+		if tenant == r {
+			continue
+		}
+		report, err := tenant.checkIntegrity(repair, false)
+		if err != nil {
+			return nil, err
+		}
+		report.Organization = org.ID
+		reports = append(reports, *report)
+	}
+	return reports, nil
+}
+
+// checkIntegrity runs the checks on this repository's schema
+func (r *Repository) checkIntegrity(repair, shared bool) (*IntegrityReport, error) {
+	report := &IntegrityReport{CheckedAt: time.Now(), Issues: []IntegrityIssue{}}
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		for _, check := range integrityChecks {
+			if check.shared && !shared {
+				continue
+			}
+
+			issue := IntegrityIssue{
+				Check:       check.name,
+				Description: check.description,
+				Repairable:  check.repairable,
+			}
+			if err := tx.Raw("SELECT COUNT(*) FROM " + check.from).Scan(&issue.Count).Error; err != nil {
+				return err
+			}
+			if issue.Count == 0 {
+				continue
+			}
+			if err := tx.Raw("SELECT CAST("+check.key+" AS text) FROM "+check.from+" ORDER BY 1 LIMIT ?", integritySampleSize).
+				Scan(&issue.Sample).Error; err != nil {
+				return err
+			}
+
+			if repair && check.repairable {
+				result := tx.Exec("DELETE FROM " + check.from)
+				if result.Error != nil {
+					return result.Error
+				}
+				issue.Repaired = result.RowsAffected
+				r.log.Warnw("Repaired integrity issue", "check", check.name, "rows", result.RowsAffected)
+			}
+			report.Issues = append(report.Issues, issue)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/andevellicus/crapp/internal/models"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// KioskSessionRepository handles persistence of proctored kiosk sessions
+type KioskSessionRepository struct {
+	db  *gorm.DB
+	log *zap.SugaredLogger
+}
+
+// NewKioskSessionRepository creates a new kiosk session repository
+func NewKioskSessionRepository(db *gorm.DB, log *zap.SugaredLogger) *KioskSessionRepository {
+	return &KioskSessionRepository{
+		db:  db,
+		log: log.Named("kiosk-repo"),
+	}
+}
+
+// Create stores a new kiosk session
+func (r *KioskSessionRepository) Create(session *models.KioskSession) error {
+	session.PatientEmail = strings.ToLower(session.PatientEmail)
+	session.ClinicianEmail = strings.ToLower(session.ClinicianEmail)
+	session.CreatedAt = time.Now()
+
+	if err := r.db.Create(session).Error; err != nil {
+		r.log.Errorw("Database error creating kiosk session", "error", err)
+		return fmt.Errorf("failed to create kiosk session: %w", err)
+	}
+	return nil
+}
+
+// GetActive retrieves a kiosk session that has neither ended nor expired
+func (r *KioskSessionRepository) GetActive(id string) (*models.KioskSession, error) {
+	if id == "" {
+		return nil, fmt.Errorf("kiosk session ID cannot be empty")
+	}
+
+	var session models.KioskSession
+	err := r.db.Where("id = ? AND ended_at IS NULL AND expires_at > ?", id, time.Now()).First(&session).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("kiosk session not found or no longer active")
+		}
+		r.log.Errorw("Database error getting kiosk session", "id", id, "error", err)
+		return nil, err
+	}
+	return &session, nil
+}
+
+// End marks a kiosk session as finished, optionally linking the submitted assessment
+func (r *KioskSessionRepository) End(id string, assessmentID *uint) error {
+	now := time.Now()
+	updates := map[string]any{"ended_at": &now}
+	if assessmentID != nil {
+		updates["assessment_id"] = assessmentID
+	}
+
+	result := r.db.Model(&models.KioskSession{}).
+		Where("id = ? AND ended_at IS NULL", id).
+		Updates(updates)
+	if result.Error != nil {
+		r.log.Errorw("Database error ending kiosk session", "id", id, "error", result.Error)
+		return fmt.Errorf("failed to end kiosk session: %w", result.Error)
+	}
+	return nil
+}
+
+// GetByClinician lists the kiosk sessions launched by a clinician, newest first
+func (r *KioskSessionRepository) GetByClinician(email string, limit int) ([]models.KioskSession, error) {
+	normalizedEmail := strings.ToLower(email)
+	sessions := []models.KioskSession{}
+	err := r.db.Where("clinician_email = ?", normalizedEmail).
+		Order("created_at DESC").
+		Limit(limit).
+		Find(&sessions).Error
+	if err != nil {
+		r.log.Errorw("Database error listing kiosk sessions", "clinician", normalizedEmail, "error", err)
+		return nil, err
+	}
+	return sessions, nil
+}
@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/andevellicus/crapp/internal/models"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// LoginChallengeRepository handles persistence of pending suspicious login confirmations
+type LoginChallengeRepository struct {
+	db  *gorm.DB
+	log *zap.SugaredLogger
+}
+
+// NewLoginChallengeRepository creates a new login challenge repository
+func NewLoginChallengeRepository(db *gorm.DB, log *zap.SugaredLogger) *LoginChallengeRepository {
+	return &LoginChallengeRepository{
+		db:  db,
+		log: log.Named("login-challenge-repo"),
+	}
+}
+
+// Create stores a new login challenge
+func (r *LoginChallengeRepository) Create(challenge *models.LoginChallenge) error {
+	challenge.UserEmail = strings.ToLower(challenge.UserEmail)
+	challenge.CreatedAt = time.Now()
+
+	if err := r.db.Create(challenge).Error; err != nil {
+		r.log.Errorw("Database error creating login challenge", "error", err, "email", challenge.UserEmail)
+		return fmt.Errorf("failed to create login challenge: %w", err)
+	}
+	return nil
+}
+
+// Consume returns an unexpired, unconfirmed challenge and marks it confirmed
+func (r *LoginChallengeRepository) Consume(token string) (*models.LoginChallenge, error) {
+	var challenge models.LoginChallenge
+	now := time.Now()
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("token = ? AND confirmed_at IS NULL AND expires_at > ?", token, now).
+			First(&challenge).Error; err != nil {
+			return err
+		}
+		return tx.Model(&challenge).Update("confirmed_at", now).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &challenge, nil
+}
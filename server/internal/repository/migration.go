@@ -0,0 +1,33 @@
+package repository
+
+import (
+	"time"
+)
+
+// MigrationStatus describes the schema migrations applied at startup
+type MigrationStatus struct {
+	SharedMigratedAt time.Time `json:"shared_migrated_at"`
+	TenantSchemas    int       `json:"tenant_schemas"`
+	DatabaseOK       bool      `json:"database_ok"`
+	Error            string    `json:"error,omitempty"`
+}
+
+// MigrationStatus reports when the schemas were migrated and whether the
+// database is reachable now
+func (r *Repository) MigrationStatus() MigrationStatus {
+	status := MigrationStatus{
+		SharedMigratedAt: r.migratedAt,
+		TenantSchemas:    r.migratedTenants,
+	}
+
+	sqlDB, err := r.db.DB()
+	if err == nil {
+		err = sqlDB.Ping()
+	}
+	if err != nil {
+		status.Error = err.Error()
+	} else {
+		status.DatabaseOK = true
+	}
+	return status
+}
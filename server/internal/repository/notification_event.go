@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/andevellicus/crapp/internal/models"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ChannelEngagement counts the reminders sent on a channel and how many of
+// them were opened and clicked
+type ChannelEngagement struct {
+	Channel string `json:"channel"`
+	Sent    int64  `json:"sent"`
+	Opened  int64  `json:"opened"`
+	Clicked int64  `json:"clicked"`
+}
+
+// NotificationEventRepository handles persistence of reminder engagement events
+type NotificationEventRepository struct {
+	db  *gorm.DB
+	log *zap.SugaredLogger
+}
+
+// NewNotificationEventRepository creates a new notification event repository
+func NewNotificationEventRepository(db *gorm.DB, log *zap.SugaredLogger) *NotificationEventRepository {
+	return &NotificationEventRepository{
+		db:  db,
+		log: log.Named("notification-event-repo"),
+	}
+}
+
+// RecordSent records a reminder message being sent to a user
+func (r *NotificationEventRepository) RecordSent(messageID, channel, email string) error {
+	return r.record(&models.NotificationEvent{
+		MessageID: messageID,
+		Kind:      models.NotificationEventSent,
+		Channel:   channel,
+		UserEmail: strings.ToLower(email),
+	})
+}
+
+// Record records a sent message being opened or clicked, taking its channel
+// and user from when it was sent. Messages never recorded as sent are
+// ignored, as are repeats of a kind already recorded.
+func (r *NotificationEventRepository) Record(messageID, kind string) error {
+	var sent models.NotificationEvent
+	err := r.db.Where("message_id = ? AND kind = ?", messageID, models.NotificationEventSent).First(&sent).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		r.log.Errorw("Database error finding sent notification", "error", err, "message_id", messageID)
+		return err
+	}
+
+	return r.record(&models.NotificationEvent{
+		MessageID: messageID,
+		Kind:      kind,
+		Channel:   sent.Channel,
+		UserEmail: sent.UserEmail,
+	})
+}
+
+func (r *NotificationEventRepository) record(event *models.NotificationEvent) error {
+	if err := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(event).Error; err != nil {
+		r.log.Errorw("Database error recording notification event", "error", err, "kind", event.Kind)
+		return fmt.Errorf("failed to record notification event: %w", err)
+	}
+	return nil
+}
+
+// Engagement returns each channel's sent, opened and clicked counts for
+// messages sent since a time, limited to an organization's users unless
+// orgID is empty
+func (r *NotificationEventRepository) Engagement(orgID string, since time.Time) ([]ChannelEngagement, error) {
+	stats := []ChannelEngagement{}
+	err := r.db.Model(&models.NotificationEvent{}).
+		Scopes(OrgUserScope(orgID)).
+		Select("channel, "+
+			"COUNT(*) FILTER (WHERE kind = ?) AS sent, "+
+			"COUNT(*) FILTER (WHERE kind = ?) AS opened, "+
+			"COUNT(*) FILTER (WHERE kind = ?) AS clicked",
+			models.NotificationEventSent, models.NotificationEventOpened, models.NotificationEventClicked).
+		Where("message_id IN (?)", r.db.Model(&models.NotificationEvent{}).Select("message_id").
+			Where("kind = ? AND created_at >= ?", models.NotificationEventSent, since)).
+		Group("channel").
+		Order("channel").
+		Scan(&stats).Error
+	if err != nil {
+		r.log.Errorw("Database error summarizing notification engagement", "error", err)
+		return nil, err
+	}
+	return stats, nil
+}
+
+// PurgeBefore deletes events for messages sent before a time, returning how
+// many were deleted
+func (r *NotificationEventRepository) PurgeBefore(before time.Time) (int64, error) {
+	result := r.db.Where("message_id IN (?)", r.db.Model(&models.NotificationEvent{}).Select("message_id").
+		Where("kind = ? AND created_at < ?", models.NotificationEventSent, before)).
+		Delete(&models.NotificationEvent{})
+	if result.Error != nil {
+		r.log.Errorw("Database error purging notification events", "error", result.Error)
+		return 0, result.Error
+	}
+	return result.RowsAffected, nil
+}
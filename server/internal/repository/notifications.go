@@ -1,109 +1,274 @@
 package repository
 
 import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
 	"time"
 
 	"github.com/andevellicus/crapp/internal/models"
 )
 
-// GetUsersForReminder gets all users who should receive a reminder at the given index
-func (r *Repository) GetUsersForReminder(reminderTime string) ([]models.User, error) {
+// notificationPreferencesVersion is the layout of stored notification preferences
+const notificationPreferencesVersion = 2
+
+// ChannelPreferences holds a user's settings for one delivery channel
+type ChannelPreferences struct {
+	Enabled       bool            `json:"enabled"`                  // Master switch for the channel
+	Types         map[string]bool `json:"types"`                    // Notification types delivered on the channel
+	ReminderTimes []string        `json:"reminder_times,omitempty"` // HH:MM times for assessment reminders
+}
+
+// UserNotificationPreferences represents a user's complete notification preferences
+type UserNotificationPreferences struct {
+	Version  int                           `json:"version"`
+	Channels map[string]ChannelPreferences `json:"channels"`
+	// Time when user can still complete yesterday's assessment
+	CutoffTime string `json:"cutoff_time,omitempty"`
+}
+
+// legacyNotificationPreferences is the original flat layout with reminder
+// times shared by every channel
+type legacyNotificationPreferences struct {
+	PushEnabled   bool     `json:"push_enabled"`
+	EmailEnabled  bool     `json:"email_enabled"`
+	ReminderTimes []string `json:"reminder_times"`
+	CutoffTime    string   `json:"cutoff_time,omitempty"`
+}
+
+// defaultNotificationTypes are the types a newly enabled channel delivers
+var defaultNotificationTypes = map[string]bool{
+	models.NotificationReminders:      true,
+	models.NotificationWeeklySummary:  false,
+	models.NotificationSecurityAlerts: true,
+	models.NotificationAnnouncements:  true,
+}
+
+// Allows reports whether a notification type should be sent on a channel
+func (p *UserNotificationPreferences) Allows(channel, notificationType string) bool {
+	settings, ok := p.Channels[channel]
+	return ok && settings.Enabled && settings.Types[notificationType]
+}
+
+// RemindsAt reports whether a channel sends assessment reminders at a time
+func (p *UserNotificationPreferences) RemindsAt(channel, reminderTime string) bool {
+	if !p.Allows(channel, models.NotificationReminders) {
+		return false
+	}
+	for _, prefTime := range p.Channels[channel].ReminderTimes {
+		if formatTime(prefTime) == formatTime(reminderTime) {
+			return true
+		}
+	}
+	return false
+}
+
+// SetChannelEnabled turns a whole channel on or off, keeping its other settings
+func (p *UserNotificationPreferences) SetChannelEnabled(channel string, enabled bool) {
+	settings := p.Channels[channel]
+	settings.Enabled = enabled
+	p.Channels[channel] = settings
+}
+
+// defaultNotificationPreferences returns preferences with every channel off
+func (r *UserRepository) defaultNotificationPreferences() *UserNotificationPreferences {
+	prefs := &UserNotificationPreferences{
+		Version:    notificationPreferencesVersion,
+		Channels:   make(map[string]ChannelPreferences, len(models.NotificationChannels)),
+		CutoffTime: r.cfg.Reminders.CutoffTime,
+	}
+	for _, channel := range models.NotificationChannels {
+		prefs.Channels[channel] = ChannelPreferences{
+			Types:         maps.Clone(defaultNotificationTypes),
+			ReminderTimes: slices.Clone(r.cfg.Reminders.Times),
+		}
+	}
+	return prefs
+}
+
+// preferencesOf decodes a user's stored preferences, upgrading the legacy
+// layout and filling in channels and types added since they were saved
+func (r *UserRepository) preferencesOf(user *models.User) (*UserNotificationPreferences, error) {
+	prefs := r.defaultNotificationPreferences()
+	if user.NotificationPreferences == "" {
+		return prefs, nil
+	}
+
+	var stored UserNotificationPreferences
+	if err := json.Unmarshal([]byte(user.NotificationPreferences), &stored); err != nil {
+		return nil, err
+	}
+
+	if stored.Version < notificationPreferencesVersion {
+		var legacy legacyNotificationPreferences
+		if err := json.Unmarshal([]byte(user.NotificationPreferences), &legacy); err != nil {
+			return nil, err
+		}
+		for _, channel := range models.NotificationChannels {
+			settings := prefs.Channels[channel]
+			if len(legacy.ReminderTimes) > 0 {
+				settings.ReminderTimes = slices.Clone(legacy.ReminderTimes)
+			}
+			prefs.Channels[channel] = settings
+		}
+		prefs.SetChannelEnabled(models.NotificationChannelPush, legacy.PushEnabled)
+		prefs.SetChannelEnabled(models.NotificationChannelEmail, legacy.EmailEnabled)
+		if legacy.CutoffTime != "" {
+			prefs.CutoffTime = legacy.CutoffTime
+		}
+		return prefs, nil
+	}
+
+	for channel, settings := range stored.Channels {
+		if !slices.Contains(models.NotificationChannels, channel) {
+			continue
+		}
+		types := maps.Clone(defaultNotificationTypes)
+		for notificationType, enabled := range settings.Types {
+			if _, known := types[notificationType]; known {
+				types[notificationType] = enabled
+			}
+		}
+		settings.Types = types
+		prefs.Channels[channel] = settings
+	}
+	if stored.CutoffTime != "" {
+		prefs.CutoffTime = stored.CutoffTime
+	}
+	return prefs, nil
+}
+
+// MigrateNotificationPreferences rewrites preferences still stored in the
+// legacy flat layout and returns how many users were updated
+func (r *UserRepository) MigrateNotificationPreferences() (int, error) {
+	var users []models.User
+	err := r.db.Select("id", "email", "notification_preferences").
+		Where("notification_preferences IS NOT NULL AND notification_preferences->>'version' IS NULL").
+		Find(&users).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to load notification preferences: %w", err)
+	}
+
+	migrated := 0
+	for i := range users {
+		prefs, err := r.preferencesOf(&users[i])
+		if err != nil {
+			r.log.Warnw("Skipping unreadable notification preferences", "email", users[i].Email, "error", err)
+			continue
+		}
+		if err := r.SaveNotificationPreferences(users[i].Email, prefs); err != nil {
+			return migrated, err
+		}
+		migrated++
+	}
+	return migrated, nil
+}
+
+// ReminderSlot is a daily reminder time, as HH:MM on the clock in a time zone.
+// Users are reminded in the slot for their own time zone; an empty zone is
+// the deployment's assessment time zone.
+type ReminderSlot struct {
+	Timezone string
+	Time     string
+}
+
+// includes reports whether a slot is for a user's time zone
+func (slot ReminderSlot) includes(user *models.User) bool {
+	return user.Timezone == slot.Timezone
+}
+
+// GetUsersForReminder gets all users who should receive a push reminder in the given slot
+func (r *Repository) GetUsersForReminder(slot ReminderSlot) ([]models.User, error) {
 	var users []models.User
 
 	// Find users with push subscriptions
-	if err := r.db.Where("push_subscription IS NOT NULL AND push_subscription != ''").Find(&users).Error; err != nil {
+	if err := r.db.Where("push_subscription IS NOT NULL AND push_subscription != '' AND deactivated_at IS NULL AND withdrawn_at IS NULL").Find(&users).Error; err != nil {
 		return nil, err
 	}
 
-	// Filter users by their preferences
+	// Filter users by their time zone and preferences
 	var eligibleUsers []models.User
-	for _, user := range users {
-		preferences, err := r.Users.GetNotificationPreferences(user.Email)
-		if err != nil {
-			r.log.Warnw("Failed to get push preferences", "user", user.Email, "error", err)
+	for i := range users {
+		if !slot.includes(&users[i]) {
 			continue
 		}
-
-		// Only include users who have enabled notifications
-		if !preferences.PushEnabled && !preferences.EmailEnabled {
+		preferences, err := r.Users.preferencesOf(&users[i])
+		if err != nil {
+			r.log.Warnw("Failed to get push preferences", "user", users[i].Email, "error", err)
 			continue
 		}
 
-		// Check if this time matches any of their preferred times
-		for _, prefTime := range preferences.ReminderTimes {
-			// Format both times to HH:MM for comparison
-			if formatTime(prefTime) == formatTime(reminderTime) {
-				eligibleUsers = append(eligibleUsers, user)
-				break
-			}
+		if preferences.RemindsAt(models.NotificationChannelPush, slot.Time) {
+			eligibleUsers = append(eligibleUsers, users[i])
 		}
 	}
 
 	return eligibleUsers, nil
 }
 
-func (r *Repository) GetAllUniqueReminderTimes() ([]string, error) {
+// GetReminderSlots returns every time zone and time at which some user wants
+// a reminder
+func (r *Repository) GetReminderSlots() ([]ReminderSlot, error) {
 	var users []models.User
 
-	// Find users with push subscriptions
+	// Find users with notification preferences
 	if err := r.db.Where("notification_preferences IS NOT NULL").Find(&users).Error; err != nil {
 		return nil, err
 	}
 
-	// Collect all unique times
-	timeMap := make(map[string]bool)
+	// Collect all unique slots
+	slotMap := make(map[ReminderSlot]bool)
 
-	for _, user := range users {
-		preferences, err := r.Users.GetNotificationPreferences(user.Email)
+	for i := range users {
+		preferences, err := r.Users.preferencesOf(&users[i])
 		if err != nil {
 			continue
 		}
 
-		if preferences.PushEnabled || preferences.EmailEnabled {
-			for _, timeStr := range preferences.ReminderTimes {
+		for channel, settings := range preferences.Channels {
+			if !preferences.Allows(channel, models.NotificationReminders) {
+				continue
+			}
+			for _, timeStr := range settings.ReminderTimes {
 				// Normalize time format
-				formattedTime := formatTime(timeStr)
-				timeMap[formattedTime] = true
+				slotMap[ReminderSlot{Timezone: users[i].Timezone, Time: formatTime(timeStr)}] = true
 			}
 		}
 	}
 
 	// Convert map to slice
-	var times []string
-	for timeStr := range timeMap {
-		times = append(times, timeStr)
+	var slots []ReminderSlot
+	for slot := range slotMap {
+		slots = append(slots, slot)
 	}
 
-	return times, nil
+	return slots, nil
 }
 
-// Add a new function to get users for email reminders
-func (r *Repository) GetUsersForEmailReminder(reminderTime string) ([]*models.User, error) {
+// GetUsersForEmailReminder gets all users who should receive an email reminder in the given slot
+func (r *Repository) GetUsersForEmailReminder(slot ReminderSlot) ([]*models.User, error) {
 	var users []*models.User
 
-	// Get all users
-	if err := r.db.Find(&users).Error; err != nil {
+	// Get all users, leaving out paused accounts and withdrawn participants
+	if err := r.db.Where("deactivated_at IS NULL AND withdrawn_at IS NULL").Find(&users).Error; err != nil {
 		return nil, err
 	}
 
-	// Filter users based on their email preferences
+	// Filter users based on their time zone and email preferences
 	var eligibleUsers []*models.User
 	for _, user := range users {
-		preferences, err := r.Users.GetNotificationPreferences(user.Email)
+		if !slot.includes(user) {
+			continue
+		}
+		preferences, err := r.Users.preferencesOf(user)
 		if err != nil {
 			r.log.Warnw("Failed to get preferences", "user", user.Email, "error", err)
 			continue
 		}
 
-		// Check if email reminders are enabled
-		if preferences.EmailEnabled {
-			// Check if this time matches any of their preferred times
-			for _, prefTime := range preferences.ReminderTimes {
-				if formatTime(prefTime) == formatTime(reminderTime) {
-					eligibleUsers = append(eligibleUsers, user)
-					break
-				}
-			}
+		if preferences.RemindsAt(models.NotificationChannelEmail, slot.Time) {
+			eligibleUsers = append(eligibleUsers, user)
 		}
 	}
 
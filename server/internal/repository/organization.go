@@ -0,0 +1,229 @@
+package repository
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/andevellicus/crapp/internal/config"
+	"github.com/andevellicus/crapp/internal/models"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// OrganizationRepository handles persistence of organizations and their studies
+type OrganizationRepository struct {
+	db  *gorm.DB
+	log *zap.SugaredLogger
+}
+
+// NewOrganizationRepository creates a new organization repository
+func NewOrganizationRepository(db *gorm.DB, log *zap.SugaredLogger) *OrganizationRepository {
+	return &OrganizationRepository{
+		db:  db,
+		log: log.Named("org-repo"),
+	}
+}
+
+// OrgScope restricts a query on a table with an organization_id column to one
+// organization. An empty orgID means unrestricted and is only valid for
+// deployment-wide admins.
+func OrgScope(orgID string) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if orgID == "" {
+			return db
+		}
+		return db.Where("organization_id = ?", orgID)
+	}
+}
+
+// OrgUserScope restricts a query on a table with a user_email column to users of one organization
+func OrgUserScope(orgID string) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if orgID == "" {
+			return db
+		}
+		return db.Where("user_email IN (?)", db.Session(&gorm.Session{NewDB: true}).
+			Model(&models.User{}).Select("email").Where("organization_id = ?", orgID))
+	}
+}
+
+// EnsureDefault creates the default organization if needed and assigns any
+// users without an organization to it
+func (r *OrganizationRepository) EnsureDefault(id, name string) error {
+	org := models.Organization{ID: id, Name: name, Branding: "{}", CreatedAt: time.Now()}
+	if err := r.db.Where("id = ?", id).FirstOrCreate(&org).Error; err != nil {
+		return fmt.Errorf("failed to create default organization: %w", err)
+	}
+
+	if err := r.db.Model(&models.User{}).
+		Where("organization_id IS NULL OR organization_id = ''").
+		Update("organization_id", id).Error; err != nil {
+		return fmt.Errorf("failed to assign users to default organization: %w", err)
+	}
+	return nil
+}
+
+// Create stores a new organization
+func (r *OrganizationRepository) Create(org *models.Organization) error {
+	org.ID = strings.ToLower(org.ID)
+	org.CreatedAt = time.Now()
+	if org.Branding == "" {
+		org.Branding = "{}"
+	}
+
+	if err := r.db.Create(org).Error; err != nil {
+		r.log.Errorw("Database error creating organization", "error", err, "id", org.ID)
+		return fmt.Errorf("failed to create organization: %w", err)
+	}
+	return nil
+}
+
+// Get retrieves an organization by ID
+func (r *OrganizationRepository) Get(id string) (*models.Organization, error) {
+	var org models.Organization
+	if err := r.db.Where("id = ?", strings.ToLower(id)).First(&org).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("organization %s not found", id)
+		}
+		return nil, err
+	}
+	return &org, nil
+}
+
+// Branding returns the branding an organization overrides the deployment's
+// with. An unknown organization or unreadable branding overrides nothing.
+func (r *OrganizationRepository) Branding(id string) config.BrandingConfig {
+	var override config.BrandingConfig
+	if id == "" {
+		return override
+	}
+	org, err := r.Get(id)
+	if err != nil || org.Branding == "" {
+		return override
+	}
+	if err := json.Unmarshal([]byte(org.Branding), &override); err != nil {
+		r.log.Warnw("Ignoring unreadable organization branding", "error", err, "id", id)
+		return config.BrandingConfig{}
+	}
+	return override
+}
+
+// GetForUser retrieves the organization a user belongs to
+func (r *OrganizationRepository) GetForUser(email string) (*models.Organization, error) {
+	var org models.Organization
+	err := r.db.Joins("JOIN users ON users.organization_id = organizations.id").
+		Where("LOWER(users.email) = ?", strings.ToLower(email)).
+		First(&org).Error
+	if err != nil {
+		return nil, err
+	}
+	return &org, nil
+}
+
+// List returns all organizations
+func (r *OrganizationRepository) List() ([]models.Organization, error) {
+	orgs := []models.Organization{}
+	if err := r.db.Order("name").Find(&orgs).Error; err != nil {
+		r.log.Errorw("Database error listing organizations", "error", err)
+		return nil, err
+	}
+	return orgs, nil
+}
+
+// CreateStudy stores a new study within an organization
+func (r *OrganizationRepository) CreateStudy(study *models.Study) error {
+	study.Slug = strings.ToLower(study.Slug)
+	study.CreatedAt = time.Now()
+
+	if err := r.db.Create(study).Error; err != nil {
+		r.log.Errorw("Database error creating study", "error", err, "org", study.OrganizationID, "slug", study.Slug)
+		return fmt.Errorf("failed to create study: %w", err)
+	}
+	return nil
+}
+
+// ListStudies returns an organization's studies
+func (r *OrganizationRepository) ListStudies(orgID string) ([]models.Study, error) {
+	studies := []models.Study{}
+	if err := r.db.Scopes(OrgScope(orgID)).Order("name").Find(&studies).Error; err != nil {
+		r.log.Errorw("Database error listing studies", "error", err, "org", orgID)
+		return nil, err
+	}
+	return studies, nil
+}
+
+// StudyExists reports whether a study slug exists within an organization
+func (r *OrganizationRepository) StudyExists(orgID, slug string) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.Study{}).
+		Where("organization_id = ? AND slug = ?", orgID, strings.ToLower(slug)).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// FindStudy retrieves a study by its slug within an organization, or nil if there is none
+func (r *OrganizationRepository) FindStudy(orgID, slug string) (*models.Study, error) {
+	var study models.Study
+	err := r.db.Where("organization_id = ? AND slug = ?", orgID, strings.ToLower(slug)).First(&study).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &study, nil
+}
+
+// AssignUser moves a user into an organization and sets their roles within it
+func (r *OrganizationRepository) AssignUser(email, orgID string, isOrgAdmin, isBlindedReviewer bool) error {
+	result := r.db.Model(&models.User{}).
+		Where("LOWER(email) = ?", strings.ToLower(email)).
+		Updates(map[string]any{
+			"organization_id":     orgID,
+			"is_org_admin":        isOrgAdmin,
+			"is_blinded_reviewer": isBlindedReviewer,
+		})
+	if result.Error != nil {
+		r.log.Errorw("Database error assigning user to organization", "error", result.Error, "email", email)
+		return fmt.Errorf("failed to update user: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("user %s not found", email)
+	}
+	return nil
+}
+
+// HasUser reports whether a user belongs to an organization. An empty orgID
+// matches any existing user.
+func (r *OrganizationRepository) HasUser(orgID, email string) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.User{}).Scopes(OrgScope(orgID)).
+		Where("LOWER(email) = ?", strings.ToLower(email)).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// orgScopeOn is OrgScope for a query where the users table is aliased
+func orgScopeOn(alias, orgID string) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if orgID == "" {
+			return db
+		}
+		return db.Where(alias+".organization_id = ?", orgID)
+	}
+}
+
+// GetStudy retrieves a study by ID
+func (r *OrganizationRepository) GetStudy(id uint) (*models.Study, error) {
+	var study models.Study
+	if err := r.db.Where("id = ?", id).First(&study).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("study %d not found", id)
+		}
+		return nil, err
+	}
+	return &study, nil
+}
@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"database/sql/driver"
+	"reflect"
+	"testing"
+
+	"github.com/andevellicus/crapp/internal/config"
+	"github.com/andevellicus/crapp/internal/testutil"
+	"go.uber.org/zap"
+)
+
+func newBrandedOrganizations(t *testing.T, branding string) *OrganizationRepository {
+	t.Helper()
+	recorder := testutil.NewRecorder()
+	recorder.Rows("organizations", []string{"id", "name", "branding"},
+		[]driver.Value{"acme", "Acme Hospital", branding})
+	return NewOrganizationRepository(testutil.OpenGorm(t, recorder.Respond), zap.NewNop().Sugar())
+}
+
+func TestOrganizationBrandingOverridesDeployment(t *testing.T) {
+	orgs := newBrandedOrganizations(t, `{"short_name":"Acme","logo_path":"/acme.png"}`)
+	deployment := config.BrandingConfig{
+		DisplayName: "CRAPP",
+		ShortName:   "CRAPP",
+		LogoPath:    "/logo.png",
+		Studies:     map[string]config.BrandingConfig{"trial": {ShortName: "Trial"}},
+	}
+
+	brand := deployment.Merge(orgs.Branding("acme"))
+	if brand.ShortName != "Acme" || brand.LogoPath != "/acme.png" || brand.DisplayName != "CRAPP" {
+		t.Errorf("organization branding = %+v", brand)
+	}
+	if study := brand.ForStudy("trial"); study.ShortName != "Trial" || study.LogoPath != "/acme.png" {
+		t.Errorf("study branding = %+v, want the study name over the organization's", study)
+	}
+}
+
+func TestOrganizationBrandingIgnoresBadOverrides(t *testing.T) {
+	orgs := newBrandedOrganizations(t, `not json`)
+	if got := orgs.Branding("acme"); !reflect.DeepEqual(got, config.BrandingConfig{}) {
+		t.Errorf("Branding with unreadable JSON = %+v, want no overrides", got)
+	}
+	if got := orgs.Branding(""); !reflect.DeepEqual(got, config.BrandingConfig{}) {
+		t.Errorf("Branding without an organization = %+v, want no overrides", got)
+	}
+}
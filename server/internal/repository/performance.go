@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/andevellicus/crapp/internal/models"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// PerfBeaconRepository stores performance beacons sent by the PWA
+type PerfBeaconRepository struct {
+	db  *gorm.DB
+	log *zap.SugaredLogger
+}
+
+// NewPerfBeaconRepository creates a new performance beacon repository
+func NewPerfBeaconRepository(db *gorm.DB, log *zap.SugaredLogger) *PerfBeaconRepository {
+	return &PerfBeaconRepository{
+		db:  db,
+		log: log.Named("perf-repo"),
+	}
+}
+
+// CreateBatch stores a batch of beacons
+func (r *PerfBeaconRepository) CreateBatch(beacons []models.PerfBeacon) error {
+	if len(beacons) == 0 {
+		return nil
+	}
+	if err := r.db.Create(&beacons).Error; err != nil {
+		r.log.Errorw("Database error storing performance beacons", "error", err, "count", len(beacons))
+		return fmt.Errorf("failed to store performance beacons: %w", err)
+	}
+	return nil
+}
+
+// SummarizeSession totals a session's long tasks and slowest interaction
+// during cognitive tests, with its page load time
+func (r *PerfBeaconRepository) SummarizeSession(sessionHash string) (*models.PerfSummary, error) {
+	var summary models.PerfSummary
+	err := r.db.Model(&models.PerfBeacon{}).
+		Select(`COUNT(*) FILTER (WHERE metric = ? AND context IN ?) AS long_tasks,
+			COALESCE(SUM(value) FILTER (WHERE metric = ? AND context IN ?), 0) AS long_task_ms,
+			COALESCE(MAX(value) FILTER (WHERE metric = ? AND context IN ?), 0) AS max_inp_ms,
+			COALESCE(MAX(value) FILTER (WHERE metric = ?), 0) AS lcp_ms`,
+			models.PerfLongTask, models.CognitiveTestContexts,
+			models.PerfLongTask, models.CognitiveTestContexts,
+			models.PerfINP, models.CognitiveTestContexts,
+			models.PerfLCP).
+		Where("session_hash = ?", sessionHash).
+		Scan(&summary).Error
+	if err != nil {
+		return nil, err
+	}
+	return &summary, nil
+}
+
+// DeleteBefore removes beacons received before the cutoff
+func (r *PerfBeaconRepository) DeleteBefore(cutoff time.Time) (int64, error) {
+	result := r.db.Where("created_at < ?", cutoff).Delete(&models.PerfBeacon{})
+	return result.RowsAffected, result.Error
+}
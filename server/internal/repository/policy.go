@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/andevellicus/crapp/internal/models"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// PolicyAcceptanceRepository handles persistence of terms and privacy policy acceptances
+type PolicyAcceptanceRepository struct {
+	db  *gorm.DB
+	log *zap.SugaredLogger
+}
+
+// NewPolicyAcceptanceRepository creates a new policy acceptance repository
+func NewPolicyAcceptanceRepository(db *gorm.DB, log *zap.SugaredLogger) *PolicyAcceptanceRepository {
+	return &PolicyAcceptanceRepository{
+		db:  db,
+		log: log.Named("policy-repo"),
+	}
+}
+
+// Record stores a user's acceptance of a document version. Accepting the same version twice is a no-op.
+func (r *PolicyAcceptanceRepository) Record(acceptance *models.PolicyAcceptance) error {
+	acceptance.UserEmail = strings.ToLower(acceptance.UserEmail)
+
+	accepted, err := r.HasAccepted(acceptance.UserEmail, acceptance.DocumentType, acceptance.Version)
+	if err != nil {
+		return err
+	}
+	if accepted {
+		return nil
+	}
+
+	acceptance.AcceptedAt = time.Now()
+	if err := r.db.Create(acceptance).Error; err != nil {
+		r.log.Errorw("Database error recording policy acceptance", "error", err, "email", acceptance.UserEmail)
+		return fmt.Errorf("failed to record policy acceptance: %w", err)
+	}
+	return nil
+}
+
+// HasAccepted reports whether the user has accepted the given version of a document
+func (r *PolicyAcceptanceRepository) HasAccepted(email, documentType, version string) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.PolicyAcceptance{}).
+		Where("user_email = ? AND document_type = ? AND version = ?", strings.ToLower(email), documentType, version).
+		Count(&count).Error
+	if err != nil {
+		r.log.Errorw("Database error checking policy acceptance", "error", err, "email", email)
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// GetForUser returns the user's full acceptance history, newest first
+func (r *PolicyAcceptanceRepository) GetForUser(email string) ([]models.PolicyAcceptance, error) {
+	acceptances := []models.PolicyAcceptance{}
+	err := r.db.Where("user_email = ?", strings.ToLower(email)).
+		Order("accepted_at DESC").
+		Find(&acceptances).Error
+	if err != nil {
+		r.log.Errorw("Database error listing policy acceptances", "error", err, "email", email)
+		return nil, err
+	}
+	return acceptances, nil
+}
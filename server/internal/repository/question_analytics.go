@@ -0,0 +1,195 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/andevellicus/crapp/internal/models"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// QuestionAnalyticsRepository records form navigation and aggregates it per question
+type QuestionAnalyticsRepository struct {
+	db  *gorm.DB
+	log *zap.SugaredLogger
+}
+
+// QuestionAnalyticsSummary is one question's navigation over a date range
+type QuestionAnalyticsSummary struct {
+	QuestionID      string  `json:"question_id"`
+	Views           int64   `json:"views"`
+	Answers         int64   `json:"answers"`
+	BackNavigations int64   `json:"back_navigations"`
+	Abandonments    int64   `json:"abandonments"`
+	AvgSeconds      float64 `json:"avg_seconds"`
+	AbandonmentRate float64 `json:"abandonment_rate"` // Abandonments per view
+	BackRate        float64 `json:"back_rate"`        // Back navigations per view
+
+	Suppressed bool `json:"suppressed,omitempty"` // Figures withheld, too few views
+}
+
+// NewQuestionAnalyticsRepository creates a new question analytics repository
+func NewQuestionAnalyticsRepository(db *gorm.DB, log *zap.SugaredLogger) *QuestionAnalyticsRepository {
+	return &QuestionAnalyticsRepository{
+		db:  db,
+		log: log.Named("question-analytics-repo"),
+	}
+}
+
+// RecordEvent stores a navigation event
+func (r *QuestionAnalyticsRepository) RecordEvent(event *models.QuestionEvent) error {
+	if err := r.db.Create(event).Error; err != nil {
+		r.log.Errorw("Database error recording question event", "error", err, "event", event.Event)
+		return err
+	}
+	return nil
+}
+
+// Aggregate rebuilds the analytics for the assessment day running from start
+// to end. Forms count as abandoned on the question they last showed, once
+// they have been idle longer than abandonAfter.
+func (r *QuestionAnalyticsRepository) Aggregate(day, start, end time.Time, abandonAfter time.Duration) (int, error) {
+	rows := make(map[string]*models.QuestionAnalytics)
+	row := func(questionID string) *models.QuestionAnalytics {
+		if _, ok := rows[questionID]; !ok {
+			rows[questionID] = &models.QuestionAnalytics{Day: day, QuestionID: questionID}
+		}
+		return rows[questionID]
+	}
+
+	var steps []struct {
+		QuestionID      string
+		Answers         int64
+		BackNavigations int64
+		AvgMs           float64
+	}
+	err := r.db.Model(&models.QuestionEvent{}).
+		Select(`question_id,
+			COUNT(*) FILTER (WHERE event = ?) AS answers,
+			COUNT(*) FILTER (WHERE event = ?) AS back_navigations,
+			COALESCE(AVG(duration_ms), 0) AS avg_ms`, models.QuestionEventNext, models.QuestionEventPrev).
+		Where("created_at >= ? AND created_at < ? AND question_id <> ''", start, end).
+		Group("question_id").
+		Scan(&steps).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to aggregate question steps: %w", err)
+	}
+	for _, s := range steps {
+		analytics := row(s.QuestionID)
+		analytics.Answers = s.Answers
+		analytics.BackNavigations = s.BackNavigations
+		analytics.AvgSeconds = s.AvgMs / 1000
+	}
+
+	var views []struct {
+		ShownQuestionID string
+		Views           int64
+	}
+	err = r.db.Model(&models.QuestionEvent{}).
+		Select("shown_question_id, COUNT(*) AS views").
+		Where("created_at >= ? AND created_at < ? AND shown_question_id <> ''", start, end).
+		Group("shown_question_id").
+		Scan(&views).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to aggregate question views: %w", err)
+	}
+	for _, v := range views {
+		row(v.ShownQuestionID).Views = v.Views
+	}
+
+	// A form's last event decides where it was abandoned; submitted forms end
+	// with an empty shown question
+	var abandoned []struct {
+		ShownQuestionID string
+		Abandonments    int64
+	}
+	err = r.db.Raw(`
+		SELECT shown_question_id, COUNT(*) AS abandonments
+		FROM (
+			SELECT DISTINCT ON (form_state_id) shown_question_id, created_at
+			FROM question_events
+			ORDER BY form_state_id, created_at DESC, id DESC
+		) last_events
+		WHERE created_at >= ? AND created_at < ? AND created_at < ? AND shown_question_id <> ''
+		GROUP BY shown_question_id`, start, end, time.Now().Add(-abandonAfter)).
+		Scan(&abandoned).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to aggregate abandonments: %w", err)
+	}
+	for _, a := range abandoned {
+		row(a.ShownQuestionID).Abandonments = a.Abandonments
+	}
+
+	if len(rows) == 0 {
+		return 0, nil
+	}
+
+	analytics := make([]*models.QuestionAnalytics, 0, len(rows))
+	for _, a := range rows {
+		a.UpdatedAt = time.Now()
+		analytics = append(analytics, a)
+	}
+
+	err = r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "day"}, {Name: "question_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"views", "answers", "back_navigations", "abandonments", "avg_seconds", "updated_at"}),
+	}).Create(&analytics).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to save question analytics: %w", err)
+	}
+	return len(analytics), nil
+}
+
+// Summarize combines the daily analytics for each question between two days, inclusive
+func (r *QuestionAnalyticsRepository) Summarize(from, to time.Time) ([]QuestionAnalyticsSummary, error) {
+	var summaries []QuestionAnalyticsSummary
+	err := r.db.Model(&models.QuestionAnalytics{}).
+		Select(`question_id,
+			SUM(views) AS views,
+			SUM(answers) AS answers,
+			SUM(back_navigations) AS back_navigations,
+			SUM(abandonments) AS abandonments,
+			COALESCE(SUM(avg_seconds * (answers + back_navigations)) / NULLIF(SUM(answers + back_navigations), 0), 0) AS avg_seconds`).
+		Where("day >= ? AND day <= ?", from.Format("2006-01-02"), to.Format("2006-01-02")).
+		Group("question_id").
+		Order("question_id").
+		Scan(&summaries).Error
+	if err != nil {
+		r.log.Errorw("Database error summarizing question analytics", "error", err)
+		return nil, err
+	}
+
+	for i := range summaries {
+		if views := float64(summaries[i].Views); views > 0 {
+			summaries[i].AbandonmentRate = float64(summaries[i].Abandonments) / views
+			summaries[i].BackRate = float64(summaries[i].BackNavigations) / views
+		}
+	}
+	return summaries, nil
+}
+
+// SuppressSmallQuestionAnalytics withholds the figures of questions shown
+// fewer than minViews times. Navigation events carry no user identity, so
+// views are the closest measure of how many people a figure describes.
+func SuppressSmallQuestionAnalytics(summaries []QuestionAnalyticsSummary, minViews int) {
+	if minViews <= 1 {
+		return
+	}
+	for i := range summaries {
+		if summaries[i].Views < int64(minViews) {
+			summaries[i] = QuestionAnalyticsSummary{QuestionID: summaries[i].QuestionID, Suppressed: true}
+		}
+	}
+}
+
+// PurgeEventsBefore deletes raw navigation events that have been aggregated
+func (r *QuestionAnalyticsRepository) PurgeEventsBefore(cutoff time.Time) (int64, error) {
+	result := r.db.Where("created_at < ?", cutoff).Delete(&models.QuestionEvent{})
+	if result.Error != nil {
+		r.log.Errorw("Database error purging question events", "error", result.Error)
+		return 0, result.Error
+	}
+	return result.RowsAffected, nil
+}
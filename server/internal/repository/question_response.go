@@ -39,22 +39,23 @@ func (r *QuestionResponseRepository) SaveBatch(responses []models.QuestionRespon
 
 	// Prepare values for bulk insert
 	valueStrings := make([]string, 0, len(responses))
-	valueArgs := make([]any, 0, len(responses)*7)
+	valueArgs := make([]any, 0, len(responses)*8)
 
 	for i, response := range responses {
-		valueStrings = append(valueStrings, fmt.Sprintf("($%d, $%d, $%d, $%d, $%d, $%d, $%d)",
-			i*7+1, i*7+2, i*7+3, i*7+4, i*7+5, i*7+6, i*7+7))
+		valueStrings = append(valueStrings, fmt.Sprintf("($%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d)",
+			i*8+1, i*8+2, i*8+3, i*8+4, i*8+5, i*8+6, i*8+7, i*8+8))
 
 		valueArgs = append(valueArgs, response.AssessmentID)
 		valueArgs = append(valueArgs, response.QuestionID)
 		valueArgs = append(valueArgs, response.ValueType)
 		valueArgs = append(valueArgs, response.NumericValue)
 		valueArgs = append(valueArgs, response.TextValue)
+		valueArgs = append(valueArgs, response.ChangedFromPrevious)
 		valueArgs = append(valueArgs, response.CreatedAt)
 		valueArgs = append(valueArgs, 0) // For ID which will be generated
 	}
 
-	stmt := fmt.Sprintf("INSERT INTO temp_question_responses (assessment_id, question_id, value_type, numeric_value, text_value, created_at, id) VALUES %s",
+	stmt := fmt.Sprintf("INSERT INTO temp_question_responses (assessment_id, question_id, value_type, numeric_value, text_value, changed_from_previous, created_at, id) VALUES %s",
 		strings.Join(valueStrings, ","))
 
 	if err := tx.Exec(stmt, valueArgs...).Error; err != nil {
@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/andevellicus/crapp/internal/models"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrAlreadyAllocated is returned when a participant already has an arm in the study
+var ErrAlreadyAllocated = errors.New("participant already allocated")
+
+// RandomizationRepository handles persistence of randomization schemes and allocations
+type RandomizationRepository struct {
+	db  *gorm.DB
+	log *zap.SugaredLogger
+}
+
+// NewRandomizationRepository creates a new randomization repository
+func NewRandomizationRepository(db *gorm.DB, log *zap.SugaredLogger) *RandomizationRepository {
+	return &RandomizationRepository{
+		db:  db,
+		log: log.Named("randomization-repo"),
+	}
+}
+
+// GetScheme retrieves a study's randomization scheme
+func (r *RandomizationRepository) GetScheme(studyID uint) (*models.RandomizationScheme, error) {
+	var scheme models.RandomizationScheme
+	if err := r.db.Where("study_id = ?", studyID).First(&scheme).Error; err != nil {
+		return nil, err
+	}
+	return &scheme, nil
+}
+
+// SaveScheme creates or replaces a study's scheme. Schemes are frozen once
+// the first participant has been allocated.
+func (r *RandomizationRepository) SaveScheme(scheme *models.RandomizationScheme) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&models.ArmAllocation{}).Where("study_id = ?", scheme.StudyID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return fmt.Errorf("randomization has already started for this study")
+		}
+
+		scheme.CreatedAt = time.Now()
+		if err := tx.Save(scheme).Error; err != nil {
+			r.log.Errorw("Database error saving randomization scheme", "error", err, "study_id", scheme.StudyID)
+			return fmt.Errorf("failed to save randomization scheme: %w", err)
+		}
+		return nil
+	})
+}
+
+// Allocate assigns a participant to an arm. The scheme row is locked so the
+// sequence within each stratum has no gaps or duplicates; pick chooses the arm
+// for the next sequence number.
+func (r *RandomizationRepository) Allocate(allocation *models.ArmAllocation, pick func(scheme *models.RandomizationScheme, sequence int) (string, error)) error {
+	allocation.UserEmail = strings.ToLower(allocation.UserEmail)
+
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var scheme models.RandomizationScheme
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("study_id = ?", allocation.StudyID).
+			First(&scheme).Error; err != nil {
+			return fmt.Errorf("study has no randomization scheme: %w", err)
+		}
+
+		var existing int64
+		if err := tx.Model(&models.ArmAllocation{}).
+			Where("study_id = ? AND user_email = ?", allocation.StudyID, allocation.UserEmail).
+			Count(&existing).Error; err != nil {
+			return err
+		}
+		if existing > 0 {
+			return ErrAlreadyAllocated
+		}
+
+		var sequence int64
+		if err := tx.Model(&models.ArmAllocation{}).
+			Where("study_id = ? AND stratum = ?", allocation.StudyID, allocation.Stratum).
+			Count(&sequence).Error; err != nil {
+			return err
+		}
+
+		arm, err := pick(&scheme, int(sequence))
+		if err != nil {
+			return err
+		}
+
+		allocation.Arm = arm
+		allocation.Method = scheme.Method
+		allocation.Sequence = int(sequence)
+		allocation.Seed = scheme.Seed
+		allocation.CreatedAt = time.Now()
+
+		if err := tx.Create(allocation).Error; err != nil {
+			r.log.Errorw("Database error creating allocation", "error", err, "study_id", allocation.StudyID)
+			return fmt.Errorf("failed to record allocation: %w", err)
+		}
+		return nil
+	})
+}
+
+// ListAllocations returns a study's allocations in order
+func (r *RandomizationRepository) ListAllocations(studyID uint) ([]models.ArmAllocation, error) {
+	allocations := []models.ArmAllocation{}
+	if err := r.db.Where("study_id = ?", studyID).Order("created_at, id").Find(&allocations).Error; err != nil {
+		r.log.Errorw("Database error listing allocations", "error", err, "study_id", studyID)
+		return nil, err
+	}
+	return allocations, nil
+}
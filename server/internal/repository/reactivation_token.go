@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/andevellicus/crapp/internal/models"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// ReactivationTokenRepository handles links that resume paused accounts
+type ReactivationTokenRepository struct {
+	db  *gorm.DB
+	log *zap.SugaredLogger
+}
+
+// NewReactivationTokenRepository creates a new reactivation token repository
+func NewReactivationTokenRepository(db *gorm.DB, log *zap.SugaredLogger) *ReactivationTokenRepository {
+	return &ReactivationTokenRepository{
+		db:  db,
+		log: log.Named("reactivation-repo"),
+	}
+}
+
+// Create issues a reactivation link for a user, replacing any earlier ones
+func (r *ReactivationTokenRepository) Create(email string, ttl time.Duration) (*models.ReactivationToken, error) {
+	normalizedEmail := strings.ToLower(email)
+	now := time.Now()
+	token := &models.ReactivationToken{
+		Token:     uuid.NewString(),
+		UserEmail: normalizedEmail,
+		ExpiresAt: now.Add(ttl),
+		CreatedAt: now,
+	}
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.ReactivationToken{}).
+			Where("user_email = ? AND used_at IS NULL", normalizedEmail).
+			Update("used_at", now).Error; err != nil {
+			return err
+		}
+		return tx.Create(token).Error
+	})
+	if err != nil {
+		r.log.Errorw("Database error creating reactivation token", "error", err, "email", normalizedEmail)
+		return nil, fmt.Errorf("failed to create reactivation token: %w", err)
+	}
+	return token, nil
+}
+
+// Consume returns an unexpired, unused token and marks it used
+func (r *ReactivationTokenRepository) Consume(tokenStr string) (*models.ReactivationToken, error) {
+	var token models.ReactivationToken
+	now := time.Now()
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("token = ? AND used_at IS NULL AND expires_at > ?", tokenStr, now).
+			First(&token).Error; err != nil {
+			return err
+		}
+		return tx.Model(&token).Update("used_at", now).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/andevellicus/crapp/internal/models"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// SignupAttemptRepository handles persistence of registration attempts
+type SignupAttemptRepository struct {
+	db  *gorm.DB
+	log *zap.SugaredLogger
+}
+
+// SignupRejectionCount is the number of rejected sign-ups for one reason
+type SignupRejectionCount struct {
+	Reason string `json:"reason"`
+	Count  int64  `json:"count"`
+}
+
+// NewSignupAttemptRepository creates a new sign-up attempt repository
+func NewSignupAttemptRepository(db *gorm.DB, log *zap.SugaredLogger) *SignupAttemptRepository {
+	return &SignupAttemptRepository{
+		db:  db,
+		log: log.Named("signup-repo"),
+	}
+}
+
+// Record stores a sign-up attempt
+func (r *SignupAttemptRepository) Record(attempt *models.SignupAttempt) error {
+	attempt.Email = strings.ToLower(attempt.Email)
+	attempt.CreatedAt = time.Now()
+
+	if err := r.db.Create(attempt).Error; err != nil {
+		r.log.Errorw("Database error recording sign-up attempt", "error", err)
+		return fmt.Errorf("failed to record sign-up attempt: %w", err)
+	}
+	return nil
+}
+
+// CountAcceptedFromIP returns how many accounts were created from an IP since a time
+func (r *SignupAttemptRepository) CountAcceptedFromIP(ip string, since time.Time) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.SignupAttempt{}).
+		Where("ip_address = ? AND outcome = ? AND created_at > ?", ip, models.SignupAccepted, since).
+		Count(&count).Error
+	return count, err
+}
+
+// GetMetrics returns accepted and rejected totals plus rejections by reason since a time
+func (r *SignupAttemptRepository) GetMetrics(since time.Time) (int64, int64, []SignupRejectionCount, error) {
+	var accepted, rejected int64
+	if err := r.db.Model(&models.SignupAttempt{}).
+		Where("outcome = ? AND created_at > ?", models.SignupAccepted, since).
+		Count(&accepted).Error; err != nil {
+		return 0, 0, nil, err
+	}
+	if err := r.db.Model(&models.SignupAttempt{}).
+		Where("outcome = ? AND created_at > ?", models.SignupRejected, since).
+		Count(&rejected).Error; err != nil {
+		return 0, 0, nil, err
+	}
+
+	byReason := []SignupRejectionCount{}
+	err := r.db.Model(&models.SignupAttempt{}).
+		Select("reason, COUNT(*) AS count").
+		Where("outcome = ? AND created_at > ?", models.SignupRejected, since).
+		Group("reason").
+		Order("count DESC").
+		Scan(&byReason).Error
+	if err != nil {
+		r.log.Errorw("Database error aggregating sign-up rejections", "error", err)
+		return 0, 0, nil, err
+	}
+
+	return accepted, rejected, byReason, nil
+}
@@ -0,0 +1,147 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/andevellicus/crapp/internal/models"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ReminderRepository keeps the ledger of reminders already sent
+type ReminderRepository struct {
+	db  *gorm.DB
+	log *zap.SugaredLogger
+}
+
+// NewReminderRepository creates a new reminder repository
+func NewReminderRepository(db *gorm.DB, log *zap.SugaredLogger) *ReminderRepository {
+	return &ReminderRepository{
+		db:  db,
+		log: log.Named("reminder-repo"),
+	}
+}
+
+// Claim records that a reminder is about to be sent. It returns false when the
+// reminder for this user, channel, assessment day, and time slot has already
+// been claimed, so concurrent or restarted schedulers send it at most once.
+func (r *ReminderRepository) Claim(email, channel string, day time.Time, timeSlot string) (bool, error) {
+	entry := &models.ReminderSent{
+		UserEmail:     strings.ToLower(email),
+		Channel:       channel,
+		AssessmentDay: day,
+		TimeSlot:      formatTime(timeSlot),
+		SentAt:        time.Now(),
+	}
+
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(entry)
+	if result.Error != nil {
+		r.log.Errorw("Database error claiming reminder", "error", result.Error, "channel", channel)
+		return false, fmt.Errorf("failed to claim reminder: %w", result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// Release removes a claim whose reminder could not be delivered, so a later
+// run may try again
+func (r *ReminderRepository) Release(email, channel string, day time.Time, timeSlot string) error {
+	err := r.db.
+		Where("user_email = ? AND channel = ? AND assessment_day = ? AND time_slot = ?",
+			strings.ToLower(email), channel, day.Format("2006-01-02"), formatTime(timeSlot)).
+		Delete(&models.ReminderSent{}).Error
+	if err != nil {
+		r.log.Errorw("Database error releasing reminder", "error", err, "channel", channel)
+	}
+	return err
+}
+
+// RecordRun saves the outcome of a reminder dispatch
+func (r *ReminderRepository) RecordRun(run *models.ReminderRun) error {
+	run.TimeSlot = formatTime(run.TimeSlot)
+	if err := r.db.Create(run).Error; err != nil {
+		r.log.Errorw("Database error recording reminder run", "error", err, "channel", run.Channel)
+		return err
+	}
+	return nil
+}
+
+// PurgeBefore deletes ledger entries and overrides for assessment days
+// before a date
+func (r *ReminderRepository) PurgeBefore(day time.Time) (int64, error) {
+	cutoff := day.Format("2006-01-02")
+	result := r.db.Where("assessment_day < ?", cutoff).Delete(&models.ReminderSent{})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	overrides := r.db.Where("assessment_day < ?", cutoff).Delete(&models.ReminderOverride{})
+	return result.RowsAffected + overrides.RowsAffected, overrides.Error
+}
+
+// GetOverride returns a user's reminder override for an assessment day, or
+// nil if they haven't changed that day's reminders
+func (r *ReminderRepository) GetOverride(email string, day time.Time) (*models.ReminderOverride, error) {
+	var override models.ReminderOverride
+	err := r.db.
+		Where("user_email = ? AND assessment_day = ?", strings.ToLower(email), day.Format("2006-01-02")).
+		First(&override).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		r.log.Errorw("Database error getting reminder override", "error", err)
+		return nil, fmt.Errorf("failed to get reminder override: %w", err)
+	}
+	return &override, nil
+}
+
+// SkipDay stops the user's remaining reminders for an assessment day
+func (r *ReminderRepository) SkipDay(email string, day time.Time) error {
+	return r.saveOverride(&models.ReminderOverride{
+		UserEmail:     strings.ToLower(email),
+		AssessmentDay: day,
+		SkipDay:       true,
+	}, "skip_day")
+}
+
+// SnoozeUntil holds back the user's reminders for an assessment day until
+// the given time
+func (r *ReminderRepository) SnoozeUntil(email string, day, until time.Time) error {
+	return r.saveOverride(&models.ReminderOverride{
+		UserEmail:     strings.ToLower(email),
+		AssessmentDay: day,
+		SnoozedUntil:  &until,
+	}, "snoozed_until")
+}
+
+// PendingSnoozes returns the snoozes that haven't ended yet and weren't
+// followed by skipping the day
+func (r *ReminderRepository) PendingSnoozes(now time.Time) ([]models.ReminderOverride, error) {
+	var overrides []models.ReminderOverride
+	err := r.db.
+		Where("snoozed_until > ? AND skip_day = ?", now, false).
+		Find(&overrides).Error
+	if err != nil {
+		r.log.Errorw("Database error listing pending snoozes", "error", err)
+		return nil, err
+	}
+	return overrides, nil
+}
+
+// saveOverride inserts an override or updates one column of the existing
+// override for that user and day
+func (r *ReminderRepository) saveOverride(override *models.ReminderOverride, column string) error {
+	override.UpdatedAt = time.Now()
+	err := r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_email"}, {Name: "assessment_day"}},
+		DoUpdates: clause.AssignmentColumns([]string{column, "updated_at"}),
+	}).Create(override).Error
+	if err != nil {
+		r.log.Errorw("Database error saving reminder override", "error", err, "column", column)
+		return fmt.Errorf("failed to save reminder override: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,152 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/andevellicus/crapp/internal/models"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ReminderJobRepository handles persistence of admin reminder jobs and their
+// deliveries
+type ReminderJobRepository struct {
+	db  *gorm.DB
+	log *zap.SugaredLogger
+}
+
+// NewReminderJobRepository creates a new reminder job repository
+func NewReminderJobRepository(db *gorm.DB, log *zap.SugaredLogger) *ReminderJobRepository {
+	return &ReminderJobRepository{
+		db:  db,
+		log: log.Named("reminder-job-repo"),
+	}
+}
+
+// Create stores a new job with a pending delivery for each recipient on each
+// of its channels
+func (r *ReminderJobRepository) Create(job *models.ReminderJob, recipients []string, channels []string) error {
+	job.Status = models.ReminderJobScheduled
+	job.Recipients = len(recipients)
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(job).Error; err != nil {
+			return err
+		}
+
+		deliveries := make([]models.ReminderDelivery, 0, len(recipients)*len(channels))
+		for _, email := range recipients {
+			for _, channel := range channels {
+				deliveries = append(deliveries, models.ReminderDelivery{
+					JobID:     job.ID,
+					UserEmail: email,
+					Channel:   channel,
+					Status:    models.ReminderDeliveryPending,
+				})
+			}
+		}
+		if len(deliveries) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(deliveries, 500).Error
+	})
+	if err != nil {
+		r.log.Errorw("Database error creating reminder job", "error", err)
+		return fmt.Errorf("failed to create reminder job: %w", err)
+	}
+	return nil
+}
+
+// Get retrieves a reminder job by ID
+func (r *ReminderJobRepository) Get(id string) (*models.ReminderJob, error) {
+	var job models.ReminderJob
+	if err := r.db.Where("id = ?", id).First(&job).Error; err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// ListDeliveries returns a job's deliveries, optionally only those with a
+// status
+func (r *ReminderJobRepository) ListDeliveries(jobID, status string) ([]models.ReminderDelivery, error) {
+	deliveries := []models.ReminderDelivery{}
+	query := r.db.Where("job_id = ?", jobID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	err := query.Order("id").Find(&deliveries).Error
+	return deliveries, err
+}
+
+// ClaimDue marks the oldest scheduled job whose send time has come as running
+// and returns it, or nil when none is due. Jobs locked by another worker are
+// skipped.
+func (r *ReminderJobRepository) ClaimDue(now time.Time) (*models.ReminderJob, error) {
+	var job models.ReminderJob
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND send_at <= ?", models.ReminderJobScheduled, now).
+			Order("send_at").
+			First(&job).Error; err != nil {
+			return err
+		}
+
+		job.Status = models.ReminderJobRunning
+		return tx.Model(&job).Update("status", job.Status).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		r.log.Errorw("Database error claiming reminder job", "error", err)
+		return nil, err
+	}
+	return &job, nil
+}
+
+// UpdateDelivery saves a delivery's outcome
+func (r *ReminderJobRepository) UpdateDelivery(delivery *models.ReminderDelivery) error {
+	err := r.db.Model(delivery).Updates(map[string]any{
+		"status":  delivery.Status,
+		"error":   delivery.Error,
+		"sent_at": delivery.SentAt,
+	}).Error
+	if err != nil {
+		r.log.Errorw("Database error updating reminder delivery", "error", err, "id", delivery.ID)
+		return fmt.Errorf("failed to update reminder delivery: %w", err)
+	}
+	return nil
+}
+
+// UpdateProgress saves a job's status and delivery counts
+func (r *ReminderJobRepository) UpdateProgress(job *models.ReminderJob) error {
+	err := r.db.Model(job).Updates(map[string]any{
+		"status":       job.Status,
+		"sent":         job.Sent,
+		"failed":       job.Failed,
+		"skipped":      job.Skipped,
+		"completed_at": job.CompletedAt,
+	}).Error
+	if err != nil {
+		r.log.Errorw("Database error updating reminder job", "error", err, "id", job.ID)
+		return fmt.Errorf("failed to update reminder job: %w", err)
+	}
+	return nil
+}
+
+// RequeueStale puts running jobs that have not made progress since before
+// back on the schedule, such as those cut off by a restart. Only their
+// pending deliveries are sent when they run again.
+func (r *ReminderJobRepository) RequeueStale(before time.Time) (int64, error) {
+	result := r.db.Model(&models.ReminderJob{}).
+		Where("status = ? AND updated_at < ?", models.ReminderJobRunning, before).
+		Update("status", models.ReminderJobScheduled)
+	if result.Error != nil {
+		r.log.Errorw("Database error requeueing reminder jobs", "error", result.Error)
+		return 0, result.Error
+	}
+	return result.RowsAffected, nil
+}
@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/andevellicus/crapp/internal/models"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ReminderTemplateRepository handles persistence of per-study reminder copy
+type ReminderTemplateRepository struct {
+	db  *gorm.DB
+	log *zap.SugaredLogger
+}
+
+// NewReminderTemplateRepository creates a new reminder template repository
+func NewReminderTemplateRepository(db *gorm.DB, log *zap.SugaredLogger) *ReminderTemplateRepository {
+	return &ReminderTemplateRepository{
+		db:  db,
+		log: log.Named("reminder-template-repo"),
+	}
+}
+
+// Find retrieves a study's template for a channel, or nil if it has none
+func (r *ReminderTemplateRepository) Find(studyID uint, channel string) (*models.ReminderTemplate, error) {
+	var tmpl models.ReminderTemplate
+	err := r.db.Where("study_id = ? AND channel = ?", studyID, channel).First(&tmpl).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		r.log.Errorw("Database error retrieving reminder template", "error", err, "study_id", studyID)
+		return nil, err
+	}
+	return &tmpl, nil
+}
+
+// List returns a study's templates
+func (r *ReminderTemplateRepository) List(studyID uint) ([]models.ReminderTemplate, error) {
+	templates := []models.ReminderTemplate{}
+	err := r.db.Where("study_id = ?", studyID).Order("channel").Find(&templates).Error
+	return templates, err
+}
+
+// Save creates or replaces a study's template for a channel
+func (r *ReminderTemplateRepository) Save(tmpl *models.ReminderTemplate) error {
+	err := r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "study_id"}, {Name: "channel"}},
+		DoUpdates: clause.AssignmentColumns([]string{"subject", "body", "updated_by", "updated_at"}),
+	}).Create(tmpl).Error
+	if err != nil {
+		r.log.Errorw("Database error saving reminder template", "error", err, "study_id", tmpl.StudyID)
+		return fmt.Errorf("failed to save reminder template: %w", err)
+	}
+	return nil
+}
+
+// Delete removes a study's template for a channel, reporting whether it had one
+func (r *ReminderTemplateRepository) Delete(studyID uint, channel string) (bool, error) {
+	result := r.db.Where("study_id = ? AND channel = ?", studyID, channel).Delete(&models.ReminderTemplate{})
+	if result.Error != nil {
+		r.log.Errorw("Database error deleting reminder template", "error", result.Error, "study_id", studyID)
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/andevellicus/crapp/internal/models"
+	"gorm.io/gorm"
+)
+
+// AssessmentRawData is what an assessment's metrics were calculated from,
+// with the values that were stored
+type AssessmentRawData struct {
+	InteractionData []byte // As kept on the form state, possibly compressed
+	CPT             *models.CPTResult
+	TMT             *models.TMTResult
+	DigitSpan       *models.DigitSpanResult
+	Metrics         []models.AssessmentMetric
+}
+
+// GetRawData loads an assessment's raw interaction and cognitive test data
+// and its stored metrics. Missing parts are left empty.
+func (r *AssessmentRepository) GetRawData(assessmentID uint) (*AssessmentRawData, error) {
+	data := &AssessmentRawData{}
+
+	var formState models.FormState
+	err := r.db.Select("interaction_data").Where("assessment_id = ?", assessmentID).First(&formState).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("error loading interaction data: %w", err)
+	}
+	data.InteractionData = formState.InteractionData
+
+	data.CPT, err = firstOrNil[models.CPTResult](r.db, assessmentID)
+	if err != nil {
+		return nil, fmt.Errorf("error loading CPT result: %w", err)
+	}
+	data.TMT, err = firstOrNil[models.TMTResult](r.db, assessmentID)
+	if err != nil {
+		return nil, fmt.Errorf("error loading TMT result: %w", err)
+	}
+	data.DigitSpan, err = firstOrNil[models.DigitSpanResult](r.db, assessmentID)
+	if err != nil {
+		return nil, fmt.Errorf("error loading digit span result: %w", err)
+	}
+
+	if err := r.db.Where("assessment_id = ?", assessmentID).
+		Order("question_id, metric_key").
+		Find(&data.Metrics).Error; err != nil {
+		return nil, fmt.Errorf("error loading metrics: %w", err)
+	}
+	return data, nil
+}
+
+// firstOrNil loads the latest row of a cognitive test result table for an
+// assessment, or nil when there is none
+func firstOrNil[T any](db *gorm.DB, assessmentID uint) (*T, error) {
+	var row T
+	err := db.Where("assessment_id = ?", assessmentID).Order("id DESC").First(&row).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &row, nil
+}
@@ -1,12 +1,16 @@
 package repository
 
 import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
 	"time"
 
 	"github.com/andevellicus/crapp/internal/config"
 	"github.com/andevellicus/crapp/internal/logger"
-	"github.com/andevellicus/crapp/internal/models"
 	"github.com/andevellicus/crapp/internal/utils"
+	"github.com/jackc/pgx/v5/pgconn"
 	_ "github.com/lib/pq"
 	"go.uber.org/zap"
 	"gorm.io/driver/postgres"
@@ -17,6 +21,21 @@ import (
 type Repository struct {
 	db  *gorm.DB
 	log *zap.SugaredLogger
+	cfg *config.Config
+
+	// Cutoff-aware day boundaries shared by completion checks and reports
+	days utils.AssessmentDay
+
+	// Tenant repositories keyed by organization, only set on the shared repository
+	tenantsMu sync.Mutex
+	tenants   map[string]*Repository
+
+	// Where attachment files are kept, passed on to tenant repositories
+	files FileStore
+
+	// Startup migration results, only set on the shared repository
+	migratedAt      time.Time
+	migratedTenants int
 
 	// Add specialized repositories
 	Users               *UserRepository
@@ -30,6 +49,33 @@ type Repository struct {
 	RefreshTokens       *RefreshTokenRepository
 	PasswordResetTokens *PasswordTokenRepository
 	RevokedTokens       *RevokedTokenRepository
+	KioskSessions       *KioskSessionRepository
+	CaregiverLinks      *CaregiverLinkRepository
+	PolicyAcceptances   *PolicyAcceptanceRepository
+	AuditEvents         *AuditRepository
+	LoginChallenges     *LoginChallengeRepository
+	ReactivationTokens  *ReactivationTokenRepository
+	StudyWithdrawals    *StudyWithdrawalRepository
+	SignupAttempts      *SignupAttemptRepository
+	UserImports         *UserImportRepository
+	ExportJobs          *ExportJobRepository
+	ClientErrors        *ClientErrorRepository
+	PerfBeacons         *PerfBeaconRepository
+	Organizations       *OrganizationRepository
+	Randomization       *RandomizationRepository
+	ClinicalEvents      *ClinicalEventRepository
+	Reminders           *ReminderRepository
+	ReminderJobs        *ReminderJobRepository
+	ReminderTemplates   *ReminderTemplateRepository
+	NotificationEvents  *NotificationEventRepository
+	Impersonations      *ImpersonationRepository
+	QuestionAnalytics   *QuestionAnalyticsRepository
+	ChartSummaries      *ChartSummaryRepository
+	Thresholds          *ThresholdRepository
+	CustomMetrics       *CustomMetricRepository
+	ChartViews          *ChartViewRepository
+	Attachments         *AttachmentRepository
+	Achievements        *AchievementRepository
 }
 
 // NewRepository creates a new repository with the given database connection
@@ -40,15 +86,49 @@ func NewRepository(cfg *config.Config, log *zap.SugaredLogger, questionLoader *u
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
 
+	repo := newRepositorySet(db, cfg, log)
+	repo.tenants = make(map[string]*Repository)
+	repo.migratedAt = time.Now()
+
+	if err := repo.Organizations.EnsureDefault(cfg.Tenancy.DefaultOrganization, cfg.Tenancy.DefaultOrganizationName); err != nil {
+		log.Fatalf("Failed to set up default organization: %v", err)
+	}
+
+	if migrated, err := repo.Users.MigrateNotificationPreferences(); err != nil {
+		log.Fatalf("Failed to migrate notification preferences: %v", err)
+	} else if migrated > 0 {
+		log.Infow("Migrated notification preferences", "users", migrated)
+	}
+
+	if cfg.Tenancy.SchemaPerOrganization {
+		migrated, err := repo.MigrateTenantSchemas()
+		if err != nil {
+			log.Fatalf("Failed to migrate tenant schemas: %v", err)
+		}
+		repo.migratedTenants = migrated
+	}
+
+	return repo
+}
+
+// newRepositorySet builds the specialized repositories on a database connection
+func newRepositorySet(db *gorm.DB, cfg *config.Config, log *zap.SugaredLogger) *Repository {
+	days, err := utils.NewAssessmentDay(cfg.Assessment.Timezone, cfg.Reminders.CutoffTime)
+	if err != nil {
+		log.Warnw("Invalid assessment day settings, using defaults", "error", err)
+	}
+
 	repo := &Repository{
-		db:  db,
-		log: log.Named("repository"),
+		db:   db,
+		log:  log.Named("repository"),
+		cfg:  cfg,
+		days: days,
 	}
 
 	// Initialize specialized repositories
-	repo.Users = NewUserRepository(db, log, cfg)
+	repo.Users = NewUserRepository(db, log, cfg, days)
 	repo.Devices = NewDeviceRepository(db, log)
-	repo.Assessments = NewAssessmentRepository(db, log, repo.Users)
+	repo.Assessments = NewAssessmentRepository(db, log, repo.Users, days)
 	repo.QuestionResponses = NewQuestionResponseRepository(db, log)
 	repo.CPTResults = NewCognitiveTestRepository(db, log)
 	repo.TMTResults = NewTrailRepository(db, log)
@@ -57,11 +137,56 @@ func NewRepository(cfg *config.Config, log *zap.SugaredLogger, questionLoader *u
 	repo.RefreshTokens = NewRefreshTokenRepository(db, log)
 	repo.PasswordResetTokens = NewPasswordTokenRepository(db, log, repo.Users)
 	repo.RevokedTokens = NewRevokedTokenRepository(db, log)
-	repo.RevokedTokens = NewRevokedTokenRepository(db, log)
+	repo.KioskSessions = NewKioskSessionRepository(db, log)
+	repo.CaregiverLinks = NewCaregiverLinkRepository(db, log)
+	repo.PolicyAcceptances = NewPolicyAcceptanceRepository(db, log)
+	repo.AuditEvents = NewAuditRepository(db, log)
+	repo.LoginChallenges = NewLoginChallengeRepository(db, log)
+	repo.ReactivationTokens = NewReactivationTokenRepository(db, log)
+	repo.StudyWithdrawals = NewStudyWithdrawalRepository(db, log)
+	repo.SignupAttempts = NewSignupAttemptRepository(db, log)
+	repo.UserImports = NewUserImportRepository(db, log)
+	repo.ExportJobs = NewExportJobRepository(db, log)
+	repo.ClientErrors = NewClientErrorRepository(db, log)
+	repo.PerfBeacons = NewPerfBeaconRepository(db, log)
+	repo.Organizations = NewOrganizationRepository(db, log)
+	repo.Randomization = NewRandomizationRepository(db, log)
+	repo.ClinicalEvents = NewClinicalEventRepository(db, log)
+	repo.Reminders = NewReminderRepository(db, log)
+	repo.ReminderJobs = NewReminderJobRepository(db, log)
+	repo.ReminderTemplates = NewReminderTemplateRepository(db, log)
+	repo.NotificationEvents = NewNotificationEventRepository(db, log)
+	repo.Impersonations = NewImpersonationRepository(db, log)
+	repo.QuestionAnalytics = NewQuestionAnalyticsRepository(db, log)
+	repo.ChartSummaries = NewChartSummaryRepository(db, log, days)
+	repo.Thresholds = NewThresholdRepository(db, log)
+	repo.CustomMetrics = NewCustomMetricRepository(db, log)
+	repo.ChartViews = NewChartViewRepository(db, log)
+	repo.Attachments = NewAttachmentRepository(db, log)
+	repo.Achievements = NewAchievementRepository(db, log)
 
 	return repo
 }
 
+// SetFileStore gives the repository the storage that holds attachment files,
+// so erasing a user also removes theirs
+func (r *Repository) SetFileStore(files FileStore) {
+	r.tenantsMu.Lock()
+	defer r.tenantsMu.Unlock()
+
+	r.files = files
+	r.Users.files = files
+	for _, tenant := range r.tenants {
+		tenant.files = files
+		tenant.Users.files = files
+	}
+}
+
+// AssessmentDay returns the deployment's assessment day boundaries
+func (r *Repository) AssessmentDay() utils.AssessmentDay {
+	return r.days
+}
+
 func (r *Repository) CreateInBatches(value any, batchSize int) error {
 	// Create in batches
 	if err := r.db.CreateInBatches(value, batchSize).Error; err != nil {
@@ -79,87 +204,92 @@ func setupDatabase(cfg *config.Config) (*gorm.DB, error) {
 	dbLogger := logger.GetLogger("gorm")
 
 	// Configure GORM logger
-	gormConfig := logger.SetUpGormConfig(dbLogger, cfg.Logging.Level)
+	gormConfig := logger.SetUpGormConfig(dbLogger, cfg.Logging.Level, slowQueryThreshold(cfg))
+	withPerformanceOptions(gormConfig, cfg)
 
 	db, err := gorm.Open(postgres.Open(dbURL), gormConfig)
 	if err != nil {
 		return nil, err
 	}
 
-	// Migrate database schema
-	err = db.AutoMigrate(
-		&models.User{},
-		&models.Assessment{},
-		&models.Device{},
-		&models.FormState{},
-		&models.AssessmentMetric{},
-		&models.QuestionResponse{},
-		&models.RefreshToken{},
-		&models.RevokedToken{},
-		&models.PasswordResetToken{},
-		&models.CPTResult{},
-		&models.TMTResult{},
-		&models.DigitSpanResult{},
-	)
-	if err != nil {
+	// Migrate database schema. Research data tables are also created here so
+	// organizations without a dedicated schema share them.
+	if err := db.AutoMigrate(sharedModels...); err != nil {
+		return nil, err
+	}
+	if err := migrateTenantTables(db); err != nil {
 		return nil, err
 	}
-
-	// Add GIN index for JSONB fields
-	db.Exec("CREATE INDEX IF NOT EXISTS idx_form_states_answers ON form_states USING GIN (answers)")
 
 	// For text stored as JSON, we need to cast to jsonb first
 	db.Exec("CREATE INDEX IF NOT EXISTS idx_user_notification_email ON users((notification_preferences->>'email_enabled')) WHERE notification_preferences IS NOT NULL")
 	db.Exec("CREATE INDEX IF NOT EXISTS idx_user_notification_push ON users((notification_preferences->>'push_enabled')) WHERE notification_preferences IS NOT NULL")
 	db.Exec("CREATE INDEX IF NOT EXISTS idx_user_notification_gin ON users USING GIN (notification_preferences) WHERE notification_preferences IS NOT NULL")
-
-	// Add composite indexes for common query patterns
-	db.Exec("CREATE INDEX IF NOT EXISTS idx_metrics_query ON assessment_metrics(assessment_id, question_id, metric_key)")
-	db.Exec("CREATE INDEX IF NOT EXISTS idx_question_response_query ON question_responses(assessment_id, question_id, value_type)")
-	db.Exec("CREATE INDEX IF NOT EXISTS idx_timeline_query ON assessments(user_email, submitted_at)")
-	db.Exec("CREATE INDEX IF NOT EXISTS idx_active_form_states ON form_states(user_email) WHERE assessment_id IS NULL")
-	db.Exec("CREATE INDEX IF NOT EXISTS idx_active_assessments ON assessments(user_email, submitted_at DESC)")
 	db.Exec("CREATE INDEX IF NOT EXISTS idx_users_lower_email ON users (LOWER(email));")
 
-	// Standard indexes
-	db.Exec("CREATE INDEX IF NOT EXISTS idx_assessments_user_email ON assessments(user_email)")
-	db.Exec("CREATE INDEX IF NOT EXISTS idx_assessments_device_id ON assessments(device_id)")
-	db.Exec("CREATE INDEX IF NOT EXISTS idx_assessments_submitted_at ON assessments(submitted_at)")
-	db.Exec("CREATE INDEX IF NOT EXISTS idx_question_responses_assessment_id ON question_responses(assessment_id)")
-	db.Exec("CREATE INDEX IF NOT EXISTS idx_question_responses_question_id ON question_responses(question_id)")
-	db.Exec("CREATE INDEX IF NOT EXISTS idx_assessment_metrics_assessment_id ON assessment_metrics(assessment_id)")
-	db.Exec("CREATE INDEX IF NOT EXISTS idx_assessment_metrics_metric_key ON assessment_metrics(metric_key)")
-	db.Exec("CREATE INDEX IF NOT EXISTS idx_cpt_results_user_email ON cpt_results(user_email)")
-	db.Exec("CREATE INDEX IF NOT EXISTS idx_cpt_results_created_at ON cpt_results(created_at)")
-
 	// Set connection pool parameters
 	sqlDB, err := db.DB()
 	if err != nil {
 		return nil, err
 	}
 
-	// Set max open connections based on your expected load
-	sqlDB.SetMaxOpenConns(50)
-	// Set max idle connections to reduce connection churn
-	sqlDB.SetMaxIdleConns(25)
-	// Set connection max lifetime to clean up inactive connections
-	sqlDB.SetConnMaxLifetime(30 * time.Minute)
-
-	// Add pool stat logging
-	go func() {
-		ticker := time.NewTicker(10 * time.Minute)
-		for range ticker.C {
-			stats := sqlDB.Stats()
-			dbLogger.Sugar().Infof("DB Pool stats: Open=%d, InUse=%d, Idle=%d, WaitCount=%d, WaitDuration=%v",
-				stats.OpenConnections, stats.InUse, stats.Idle, stats.WaitCount, stats.WaitDuration)
-		}
-	}()
+	// Pool stats are exposed on the metrics endpoint
+	sqlDB.SetMaxOpenConns(cfg.Database.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(cfg.Database.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(time.Duration(cfg.Database.ConnMaxLifetimeMinutes) * time.Minute)
+	sqlDB.SetConnMaxIdleTime(time.Duration(cfg.Database.ConnMaxIdleTimeMinutes) * time.Minute)
 
 	dbLogger.Info("Database initialized")
 
 	return db, nil
 }
 
+// slowQueryThreshold returns the duration above which GORM logs a query as
+// slow. GORM treats zero as disabled.
+func slowQueryThreshold(cfg *config.Config) time.Duration {
+	return time.Duration(cfg.Database.SlowQueryMs) * time.Millisecond
+}
+
+// withPerformanceOptions applies the configured GORM performance options
+func withPerformanceOptions(gormConfig *gorm.Config, cfg *config.Config) {
+	gormConfig.PrepareStmt = cfg.Database.PrepareStmt
+	gormConfig.SkipDefaultTransaction = cfg.Database.SkipDefaultTransaction
+}
+
+// PoolStats returns connection pool statistics keyed by pool: "shared" for
+// the main pool and the organization ID for each open tenant pool
+func (r *Repository) PoolStats() map[string]sql.DBStats {
+	stats := make(map[string]sql.DBStats)
+	if sqlDB, err := r.db.DB(); err == nil {
+		stats["shared"] = sqlDB.Stats()
+	}
+
+	r.tenantsMu.Lock()
+	defer r.tenantsMu.Unlock()
+	for orgID, tenant := range r.tenants {
+		if sqlDB, err := tenant.db.DB(); err == nil {
+			stats[orgID] = sqlDB.Stats()
+		}
+	}
+	return stats
+}
+
+// Ping checks that the shared database answers
+func (r *Repository) Ping(ctx context.Context) error {
+	sqlDB, err := r.db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
+
+// IsUniqueViolation reports whether an error comes from a unique constraint,
+// for example a concurrent insert of the same row
+func IsUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
 func (r *Repository) WithTransaction(fn func(tx *gorm.DB) error) error {
 	tx := r.db.Begin()
 	if tx.Error != nil {
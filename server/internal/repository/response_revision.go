@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/andevellicus/crapp/internal/models"
+	"gorm.io/gorm"
+)
+
+// ResponseRevisionEntry is a revision with the participant and the answer's
+// current value, for the admin history view
+type ResponseRevisionEntry struct {
+	models.QuestionResponseRevision
+	UserEmail           string  `json:"user_email"`
+	CurrentValueType    string  `json:"current_value_type"`
+	CurrentNumericValue float64 `json:"current_numeric_value"`
+	CurrentTextValue    string  `json:"current_text_value"`
+}
+
+// GetByID retrieves an assessment
+func (r *AssessmentRepository) GetByID(id uint) (*models.Assessment, error) {
+	var assessment models.Assessment
+	if err := r.db.Where("id = ?", id).First(&assessment).Error; err != nil {
+		return nil, err
+	}
+	return &assessment, nil
+}
+
+// GetSubmittedSince lists the user's assessments submitted at or after a
+// moment, oldest first
+func (r *AssessmentRepository) GetSubmittedSince(email string, since time.Time) ([]models.Assessment, error) {
+	assessments := []models.Assessment{}
+	err := r.db.Where("LOWER(user_email) = ? AND submitted_at >= ?", strings.ToLower(email), since).
+		Order("submitted_at ASC").
+		Find(&assessments).Error
+	return assessments, err
+}
+
+// Amend replaces a response's value, keeping the previous value as a
+// revision. The assessment is marked as revised so cached charts refresh.
+func (r *QuestionResponseRepository) Amend(response *models.QuestionResponse, updated models.QuestionResponse, by string) (*models.QuestionResponseRevision, error) {
+	now := time.Now()
+	answeredAt := response.CreatedAt
+	if response.RevisedAt != nil {
+		answeredAt = *response.RevisedAt
+	}
+
+	revision := &models.QuestionResponseRevision{
+		ResponseID:   response.ID,
+		AssessmentID: response.AssessmentID,
+		QuestionID:   response.QuestionID,
+		Revision:     response.Revision + 1,
+		ValueType:    response.ValueType,
+		NumericValue: response.NumericValue,
+		TextValue:    response.TextValue,
+		AnsweredAt:   answeredAt,
+		RevisedAt:    now,
+		RevisedBy:    by,
+	}
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(revision).Error; err != nil {
+			return err
+		}
+
+		response.ValueType = updated.ValueType
+		response.NumericValue = updated.NumericValue
+		response.TextValue = updated.TextValue
+		response.Revision = revision.Revision
+		response.RevisedAt = &now
+		if err := tx.Model(&models.QuestionResponse{}).Where("id = ?", response.ID).Updates(map[string]any{
+			"value_type":    response.ValueType,
+			"numeric_value": response.NumericValue,
+			"text_value":    response.TextValue,
+			"revision":      response.Revision,
+			"revised_at":    now,
+		}).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(&models.Assessment{}).
+			Where("id = ?", response.AssessmentID).
+			Update("revised_at", now).Error
+	})
+	if err != nil {
+		r.log.Errorw("Database error amending response", "error", err, "response_id", response.ID)
+		return nil, fmt.Errorf("failed to amend response: %w", err)
+	}
+	return revision, nil
+}
+
+// SearchRevisions returns a page of a user's answer revisions, newest first,
+// optionally limited to one assessment
+func (r *QuestionResponseRepository) SearchRevisions(email string, assessmentID uint, skip, limit int) ([]ResponseRevisionEntry, int64, error) {
+	entries := []ResponseRevisionEntry{}
+	var total int64
+
+	query := r.db.Table("question_response_revisions rv").
+		Joins("JOIN assessments a ON a.id = rv.assessment_id").
+		Joins("JOIN question_responses qr ON qr.id = rv.response_id").
+		Where("LOWER(a.user_email) = ?", strings.ToLower(email))
+	if assessmentID != 0 {
+		query = query.Where("rv.assessment_id = ?", assessmentID)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err := query.
+		Select("rv.*, LOWER(a.user_email) AS user_email, qr.value_type AS current_value_type, " +
+			"qr.numeric_value AS current_numeric_value, qr.text_value AS current_text_value").
+		Order("rv.revised_at DESC, rv.id DESC").
+		Offset(skip).Limit(limit).
+		Scan(&entries).Error
+	return entries, total, err
+}
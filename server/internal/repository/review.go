@@ -0,0 +1,243 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/andevellicus/crapp/internal/metrics"
+	"github.com/andevellicus/crapp/internal/models"
+	"gorm.io/gorm"
+)
+
+// ParticipantAdherence summarizes how regularly a participant completed assessments
+type ParticipantAdherence struct {
+	Email           string     `json:"email"`
+	StudyID         string     `json:"study_id,omitempty"`
+	Arm             string     `json:"arm,omitempty"`
+	ArmBlinded      bool       `json:"-"` // The study hides arms from blinded reviewers
+	DaysCompleted   int        `json:"days_completed"`
+	Assessments     int        `json:"assessments"`
+	LastSubmittedAt *time.Time `json:"last_submitted_at,omitempty"`
+	Rate            float64    `json:"rate"` // Share of days in the window with an assessment
+	// Set while the participant has paused their account, as opposed to
+	// having stopped taking part
+	PausedAt *time.Time `json:"paused_at,omitempty"`
+	// Set once the participant has withdrawn from their study
+	WithdrawnAt *time.Time `json:"withdrawn_at,omitempty"`
+}
+
+// ParticipantDataQuality summarizes the completeness of a participant's data
+type ParticipantDataQuality struct {
+	Email               string `json:"email"`
+	StudyID             string `json:"study_id,omitempty"`
+	Assessments         int    `json:"assessments"`
+	Retrospective       int    `json:"retrospective"`
+	WithMetrics         int    `json:"with_metrics"`
+	WithLocation        int    `json:"with_location"`
+	AbandonedFormStates int    `json:"abandoned_form_states"`
+	FastCompletions     int    `json:"fast_completions"`  // Finished faster than the minimum time per question
+	SlowDevices         int    `json:"slow_devices"`      // Taken on a device too slow for reliable reaction times
+	ClockDrifts         int    `json:"clock_drifts"`      // Device clock drifted past the threshold while filling in
+	SuspectIntegrity    int    `json:"suspect_integrity"` // Interaction data failed the integrity checks
+	LikelyBots          int    `json:"likely_bots"`       // Looked like non-human submissions
+	// Share of assessments that were not rushed, kept a steady clock, passed
+	// or skipped the integrity checks, came from a person, and carry
+	// interaction metrics (retrospective entries never do)
+	QualityScore float64 `json:"quality_score"`
+	Usable       int     `json:"-"`
+}
+
+// ReviewAssessment identifies the assessment a reviewer export row belongs to
+type ReviewAssessment struct {
+	UserEmail       string    `json:"user_email"`
+	StudyID         string    `json:"study_id,omitempty"`
+	Arm             string    `json:"arm,omitempty"`
+	ArmBlinded      bool      `json:"-"`
+	AssessmentID    uint      `json:"assessment_id"`
+	SubmittedAt     time.Time `json:"submitted_at"`
+	IsRetrospective bool      `json:"is_retrospective"`
+	SlowDevice      bool      `json:"slow_device"`
+	ClockDrift      bool      `json:"clock_drift"`
+
+	// How the question order was chosen; see models.Assessment
+	OrderScheme string `json:"order_scheme,omitempty"`
+	OrderSeed   *int64 `json:"order_seed,omitempty"`
+	OrderRow    *int   `json:"order_row,omitempty"`
+	FormVariant string `json:"form_variant,omitempty"` // Adaptive form served; see models.Assessment
+}
+
+// ReviewResponse is one answered question in a reviewer export
+type ReviewResponse struct {
+	ReviewAssessment
+	QuestionID   string   `json:"question_id"`
+	Position     *int     `json:"position,omitempty"` // 1-based step the question was shown at
+	ValueType    string   `json:"value_type"`
+	NumericValue *float64 `json:"numeric_value,omitempty"`
+	TextValue    *string  `json:"text_value,omitempty"`
+	Masked       bool     `json:"masked,omitempty"` // Values withheld from blinded reviewers
+
+	// Whether a pre-filled answer was changed; empty for other questions
+	ChangedFromPrevious *bool `json:"changed_from_previous,omitempty"`
+}
+
+// ReviewMetric is one interaction or cognitive test metric in a reviewer
+// export. Cognitive test results use the test type as their question ID.
+type ReviewMetric struct {
+	ReviewAssessment
+	QuestionID  string  `json:"question_id"`
+	MetricKey   string  `json:"metric_key"`
+	MetricValue float64 `json:"metric_value"`
+}
+
+// reviewMetricRows lists interaction metrics alongside the summary scores
+// of each cognitive test, as (assessment_id, question_id, metric_key, metric_value)
+const reviewMetricRows = `(
+	SELECT assessment_id, question_id, metric_key, metric_value FROM assessment_metrics
+	UNION ALL SELECT assessment_id, 'cpt', 'reaction_time', average_reaction_time FROM cpt_results
+	UNION ALL SELECT assessment_id, 'cpt', 'detection_rate', detection_rate FROM cpt_results
+	UNION ALL SELECT assessment_id, 'cpt', 'omission_error_rate', omission_error_rate FROM cpt_results
+	UNION ALL SELECT assessment_id, 'cpt', 'commission_error_rate', commission_error_rate FROM cpt_results
+	UNION ALL SELECT assessment_id, 'tmt', 'part_a_time', part_a_completion_time FROM tmt_results
+	UNION ALL SELECT assessment_id, 'tmt', 'part_b_time', part_b_completion_time FROM tmt_results
+	UNION ALL SELECT assessment_id, 'tmt', 'b_to_a_ratio', b_to_a_ratio FROM tmt_results
+	UNION ALL SELECT assessment_id, 'tmt', 'part_a_errors', part_a_errors FROM tmt_results
+	UNION ALL SELECT assessment_id, 'tmt', 'part_b_errors', part_b_errors FROM tmt_results
+	UNION ALL SELECT assessment_id, 'digit_span', 'highest_span', highest_span_achieved FROM digit_span_results
+	UNION ALL SELECT assessment_id, 'digit_span', 'correct_trials', correct_trials FROM digit_span_results
+	UNION ALL SELECT assessment_id, 'digit_span', 'total_trials', total_trials FROM digit_span_results
+) m`
+
+// armJoins attaches a participant's allocation in their enrolled study, with
+// users aliased as u
+const armJoins = `LEFT JOIN studies s ON s.organization_id = u.organization_id AND s.slug = u.study_id
+	LEFT JOIN arm_allocations aa ON aa.study_id = s.id AND aa.user_email = LOWER(u.email)
+	LEFT JOIN randomization_schemes rs ON rs.study_id = s.id`
+
+// GetAdherence summarizes assessment completion since a date for an
+// organization's active participants
+func (r *AssessmentRepository) GetAdherence(orgID string, since time.Time) ([]ParticipantAdherence, error) {
+	result := []ParticipantAdherence{}
+
+	err := r.db.Table("users u").
+		Select(`u.email, u.study_id, aa.arm, COALESCE(rs.blind_arms, false) AS arm_blinded, u.deactivated_at AS paused_at, u.withdrawn_at,
+			COUNT(DISTINCT COALESCE(a.assessment_date, `+r.days.SQL("a.submitted_at")+`)) AS days_completed,
+			COUNT(a.id) AS assessments,
+			MAX(a.submitted_at) AS last_submitted_at`).
+		Joins("LEFT JOIN assessments a ON LOWER(a.user_email) = LOWER(u.email) AND a.submitted_at >= ?", since).
+		Joins(armJoins).
+		Where("u.anonymized_at IS NULL AND u.is_admin = false").
+		Scopes(orgScopeOn("u", orgID)).
+		Group("u.email, u.study_id, aa.arm, rs.blind_arms, u.deactivated_at, u.withdrawn_at").
+		Order("u.email").
+		Scan(&result).Error
+	if err != nil {
+		r.log.Errorw("Error in adherence query", "error", err, "org", orgID)
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	return result, nil
+}
+
+// GetDataQuality summarizes data completeness since a date for an
+// organization's active participants
+func (r *AssessmentRepository) GetDataQuality(orgID string, since time.Time) ([]ParticipantDataQuality, error) {
+	result := []ParticipantDataQuality{}
+
+	err := r.db.Table("users u").
+		Select(`u.email, u.study_id,
+			COUNT(a.id) AS assessments,
+			COUNT(a.id) FILTER (WHERE a.is_retrospective) AS retrospective,
+			COUNT(a.id) FILTER (WHERE EXISTS (SELECT 1 FROM assessment_metrics am WHERE am.assessment_id = a.id AND am.metric_key <> ?)) AS with_metrics,
+			COUNT(a.id) FILTER (WHERE a.latitude IS NOT NULL) AS with_location,
+			COUNT(a.id) FILTER (WHERE a.fast_completion) AS fast_completions,
+			COUNT(a.id) FILTER (WHERE a.slow_device) AS slow_devices,
+			COUNT(a.id) FILTER (WHERE a.clock_drift) AS clock_drifts,
+			COUNT(a.id) FILTER (WHERE a.integrity_verdict = ?) AS suspect_integrity,
+			COUNT(a.id) FILTER (WHERE a.likely_bot) AS likely_bots,
+			COUNT(a.id) FILTER (WHERE NOT a.fast_completion AND NOT a.clock_drift AND NOT a.likely_bot AND a.integrity_verdict IS DISTINCT FROM ? AND (a.is_retrospective
+				OR EXISTS (SELECT 1 FROM assessment_metrics am WHERE am.assessment_id = a.id AND am.metric_key <> ?))) AS usable,
+			(SELECT COUNT(*) FROM form_states fs
+				WHERE LOWER(fs.user_email) = LOWER(u.email)
+				AND fs.assessment_id IS NULL
+				AND fs.started_at >= ?
+				AND fs.last_updated_at < ?) AS abandoned_form_states`,
+			models.MetricQuestionDuration, metrics.IntegritySuspect, metrics.IntegritySuspect,
+			models.MetricQuestionDuration, since, time.Now().Add(-24*time.Hour)).
+		Joins("LEFT JOIN assessments a ON LOWER(a.user_email) = LOWER(u.email) AND a.submitted_at >= ?", since).
+		Where("u.anonymized_at IS NULL AND u.is_admin = false").
+		Scopes(orgScopeOn("u", orgID)).
+		Group("u.email, u.study_id").
+		Order("u.email").
+		Scan(&result).Error
+	if err != nil {
+		r.log.Errorw("Error in data quality query", "error", err, "org", orgID)
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+
+	for i := range result {
+		if result[i].Assessments > 0 {
+			result[i].QualityScore = float64(result[i].Usable) / float64(result[i].Assessments)
+		}
+	}
+	return result, nil
+}
+
+// GetResponsesForReview lists question responses submitted since a date, and
+// before until when it is set, by an organization's participants. Likely
+// bots are left out.
+func (r *AssessmentRepository) GetResponsesForReview(orgID string, since, until time.Time) ([]ReviewResponse, error) {
+	result := []ReviewResponse{}
+
+	err := r.db.Table("question_responses qr").
+		Select(`a.user_email, u.study_id, aa.arm, COALESCE(rs.blind_arms, false) AS arm_blinded, a.id AS assessment_id, a.submitted_at, a.is_retrospective, a.slow_device, a.clock_drift,
+			a.order_scheme, a.order_seed, a.order_row, a.form_variant,
+			qr.question_id, qr.value_type, qr.numeric_value, qr.text_value, qr.changed_from_previous,
+			(SELECT o.n FROM jsonb_array_elements_text(NULLIF(a.question_order, '')::jsonb) WITH ORDINALITY AS o(id, n)
+				WHERE o.id = qr.question_id LIMIT 1) AS position`).
+		Joins("JOIN assessments a ON a.id = qr.assessment_id").
+		Joins("JOIN users u ON LOWER(u.email) = LOWER(a.user_email)").
+		Joins(armJoins).
+		Where("a.submitted_at >= ? AND NOT a.likely_bot", since).
+		Scopes(submittedBefore(until), orgScopeOn("u", orgID)).
+		Order("a.submitted_at, a.id, qr.question_id").
+		Scan(&result).Error
+	if err != nil {
+		r.log.Errorw("Error in review export query", "error", err, "org", orgID)
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	return result, nil
+}
+
+// GetMetricsForReview lists interaction and cognitive test metrics from
+// assessments submitted since a date, and before until when it is set, by an
+// organization's participants. Likely bots are left out.
+func (r *AssessmentRepository) GetMetricsForReview(orgID string, since, until time.Time) ([]ReviewMetric, error) {
+	result := []ReviewMetric{}
+
+	err := r.db.Table(reviewMetricRows).
+		Select(`a.user_email, u.study_id, aa.arm, COALESCE(rs.blind_arms, false) AS arm_blinded, a.id AS assessment_id, a.submitted_at, a.is_retrospective, a.slow_device, a.clock_drift,
+			a.order_scheme, a.order_seed, a.order_row, a.form_variant,
+			m.question_id, m.metric_key, m.metric_value`).
+		Joins("JOIN assessments a ON a.id = m.assessment_id").
+		Joins("JOIN users u ON LOWER(u.email) = LOWER(a.user_email)").
+		Joins(armJoins).
+		Where("a.submitted_at >= ? AND NOT a.likely_bot", since).
+		Scopes(submittedBefore(until), orgScopeOn("u", orgID)).
+		Order("a.submitted_at, a.id, m.question_id, m.metric_key").
+		Scan(&result).Error
+	if err != nil {
+		r.log.Errorw("Error in review metrics export query", "error", err, "org", orgID)
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	return result, nil
+}
+
+// submittedBefore limits a query on assessments aliased as a to those
+// submitted before until, unless it is zero
+func submittedBefore(until time.Time) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if until.IsZero() {
+			return db
+		}
+		return db.Where("a.submitted_at < ?", until)
+	}
+}
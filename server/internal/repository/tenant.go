@@ -0,0 +1,299 @@
+package repository
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/andevellicus/crapp/internal/logger"
+	"github.com/andevellicus/crapp/internal/models"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// sharedModels live in the public schema and are shared by all organizations
+var sharedModels = []any{
+	&models.User{},
+	&models.Device{},
+	&models.RefreshToken{},
+	&models.RevokedToken{},
+	&models.PasswordResetToken{},
+	&models.KioskSession{},
+	&models.CaregiverLink{},
+	&models.PolicyAcceptance{},
+	&models.AuditEvent{},
+	&models.LoginChallenge{},
+	&models.ReactivationToken{},
+	&models.StudyWithdrawal{},
+	&models.SignupAttempt{},
+	&models.UserImportJob{},
+	&models.ExportJob{},
+	&models.ClientError{},
+	&models.PerfBeacon{},
+	&models.Organization{},
+	&models.Study{},
+	&models.RandomizationScheme{},
+	&models.ArmAllocation{},
+	&models.ClinicalEvent{},
+	&models.ReminderSent{},
+	&models.ReminderRun{},
+	&models.ReminderOverride{},
+	&models.ReminderJob{},
+	&models.ReminderDelivery{},
+	&models.ReminderTemplate{},
+	&models.NotificationEvent{},
+	&models.ImpersonationSession{},
+	&models.QuestionEvent{},
+	&models.QuestionAnalytics{},
+	&models.SymptomThreshold{},
+	&models.SymptomFlag{},
+	&models.RedFlagAlert{},
+	&models.CustomMetric{},
+	&models.ChartView{},
+	&models.Achievement{},
+}
+
+// tenantModels hold research data and move into an organization's own schema
+// when it has one
+var tenantModels = []any{
+	&models.Assessment{},
+	&models.FormState{},
+	&models.AssessmentMetric{},
+	&models.QuestionResponse{},
+	&models.QuestionResponseRevision{},
+	&models.CPTResult{},
+	&models.TMTResult{},
+	&models.DigitSpanResult{},
+	&models.ChartSummary{},
+	&models.AssessmentAttachment{},
+}
+
+// tenantIndexes are created alongside the tenant tables in every schema
+var tenantIndexes = []string{
+	// Add GIN index for JSONB fields
+	"CREATE INDEX IF NOT EXISTS idx_form_states_answers ON form_states USING GIN (answers)",
+
+	// Add composite indexes for common query patterns
+	"CREATE INDEX IF NOT EXISTS idx_metrics_query ON assessment_metrics(assessment_id, question_id, metric_key)",
+	"CREATE INDEX IF NOT EXISTS idx_question_response_query ON question_responses(assessment_id, question_id, value_type)",
+	"CREATE INDEX IF NOT EXISTS idx_timeline_query ON assessments(user_email, submitted_at)",
+	"CREATE INDEX IF NOT EXISTS idx_active_form_states ON form_states(user_email) WHERE assessment_id IS NULL",
+	"CREATE INDEX IF NOT EXISTS idx_active_assessments ON assessments(user_email, submitted_at DESC)",
+
+	// Standard indexes
+	"CREATE INDEX IF NOT EXISTS idx_assessments_user_email ON assessments(user_email)",
+	"CREATE INDEX IF NOT EXISTS idx_assessments_device_id ON assessments(device_id)",
+	"CREATE INDEX IF NOT EXISTS idx_assessments_submitted_at ON assessments(submitted_at)",
+	"CREATE INDEX IF NOT EXISTS idx_question_responses_assessment_id ON question_responses(assessment_id)",
+	"CREATE INDEX IF NOT EXISTS idx_question_responses_question_id ON question_responses(question_id)",
+	"CREATE INDEX IF NOT EXISTS idx_assessment_metrics_assessment_id ON assessment_metrics(assessment_id)",
+	"CREATE INDEX IF NOT EXISTS idx_assessment_metrics_metric_key ON assessment_metrics(metric_key)",
+	"CREATE INDEX IF NOT EXISTS idx_cpt_results_user_email ON cpt_results(user_email)",
+	"CREATE INDEX IF NOT EXISTS idx_cpt_results_created_at ON cpt_results(created_at)",
+
+	// Chart and history queries match emails case-insensitively
+	"CREATE INDEX IF NOT EXISTS idx_assessments_lower_email ON assessments(LOWER(user_email), submitted_at)",
+	"CREATE INDEX IF NOT EXISTS idx_cpt_results_lower_email ON cpt_results(LOWER(user_email), created_at)",
+	"CREATE INDEX IF NOT EXISTS idx_tmt_results_lower_email ON tmt_results(LOWER(user_email), created_at)",
+
+	// Chart date ranges read summaries by day
+	"CREATE INDEX IF NOT EXISTS idx_chart_summary_range ON chart_summaries(user_email, question_id, metric_key, date)",
+}
+
+// migrateTenantTables creates or updates the research data tables in the
+// connection's current schema
+func migrateTenantTables(db *gorm.DB) error {
+	if err := db.AutoMigrate(tenantModels...); err != nil {
+		return err
+	}
+	for _, stmt := range tenantIndexes {
+		if err := db.Exec(stmt).Error; err != nil {
+			return fmt.Errorf("failed to create index: %w", err)
+		}
+	}
+	return nil
+}
+
+// SchemaName returns the Postgres schema name for an organization
+func (r *Repository) SchemaName(orgID string) string {
+	return r.cfg.Tenancy.SchemaPrefix + strings.ReplaceAll(strings.ToLower(orgID), "-", "_")
+}
+
+// ForOrganization returns the repository for an organization's data. Without
+// schema-per-organization, or for organizations in the shared schema, this is
+// the shared repository itself.
+func (r *Repository) ForOrganization(orgID string) *Repository {
+	if r.tenants == nil || !r.cfg.Tenancy.SchemaPerOrganization || orgID == "" {
+		return r
+	}
+
+	r.tenantsMu.Lock()
+	defer r.tenantsMu.Unlock()
+
+	if tenant, ok := r.tenants[orgID]; ok {
+		return tenant
+	}
+
+	org, err := r.Organizations.Get(orgID)
+	if err != nil || org.Schema == "" {
+		return r
+	}
+
+	db, err := r.openTenantDB(org.Schema)
+	if err != nil {
+		r.log.Errorw("Failed to connect to tenant schema, using shared schema", "error", err, "org", orgID, "schema", org.Schema)
+		return r
+	}
+
+	tenant := newRepositorySet(db, r.cfg, logger.Sugar.Named("tenant-"+orgID))
+	tenant.files = r.files
+	tenant.Users.files = r.files
+	r.tenants[orgID] = tenant
+	return tenant
+}
+
+// ForUser returns the repository holding a user's research data
+func (r *Repository) ForUser(email string) *Repository {
+	if r.tenants == nil || !r.cfg.Tenancy.SchemaPerOrganization {
+		return r
+	}
+
+	org, err := r.Organizations.GetForUser(email)
+	if err != nil {
+		return r
+	}
+	return r.ForOrganization(org.ID)
+}
+
+// DataRepositories returns the shared repository followed by the repository of
+// every organization with its own schema, for jobs that maintain research data
+func (r *Repository) DataRepositories() []*Repository {
+	repos := []*Repository{r}
+	if r.tenants == nil || !r.cfg.Tenancy.SchemaPerOrganization {
+		return repos
+	}
+
+	orgs, err := r.Organizations.List()
+	if err != nil {
+		r.log.Errorw("Failed to list organizations", "error", err)
+		return repos
+	}
+	for _, org := range orgs {
+		if org.Schema == "" {
+			continue
+		}
+		if tenant := r.ForOrganization(org.ID); tenant != r {
+			repos = append(repos, tenant)
+		}
+	}
+	return repos
+}
+
+// ProvisionOrganization stores a new organization. When isolated, the
+// organization gets its own schema with freshly migrated research tables.
+func (r *Repository) ProvisionOrganization(org *models.Organization, isolated bool) error {
+	if isolated {
+		if !r.cfg.Tenancy.SchemaPerOrganization {
+			return fmt.Errorf("schema-per-organization is not enabled")
+		}
+		org.Schema = r.SchemaName(org.ID)
+
+		if err := r.migrateTenantSchema(org.Schema); err != nil {
+			return err
+		}
+	}
+
+	return r.Organizations.Create(org)
+}
+
+// MigrateTenantSchemas applies the current research data schema to every
+// organization that has its own schema, returning how many were migrated
+func (r *Repository) MigrateTenantSchemas() (int, error) {
+	orgs, err := r.Organizations.List()
+	if err != nil {
+		return 0, err
+	}
+
+	migrated := 0
+	for _, org := range orgs {
+		if org.Schema == "" {
+			continue
+		}
+		if err := r.migrateTenantSchema(org.Schema); err != nil {
+			return migrated, fmt.Errorf("organization %s: %w", org.ID, err)
+		}
+		migrated++
+		r.log.Infow("Migrated tenant schema", "org", org.ID, "schema", org.Schema)
+	}
+	return migrated, nil
+}
+
+// migrateTenantSchema creates a tenant schema if needed and migrates its tables
+func (r *Repository) migrateTenantSchema(schema string) error {
+	quoted := `"` + strings.ReplaceAll(schema, `"`, `""`) + `"`
+	if err := r.db.Exec("CREATE SCHEMA IF NOT EXISTS " + quoted).Error; err != nil {
+		return fmt.Errorf("failed to create schema %s: %w", schema, err)
+	}
+
+	db, err := r.openTenantDB(schema)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	}()
+
+	if err := migrateTenantTables(db); err != nil {
+		return fmt.Errorf("failed to migrate schema %s: %w", schema, err)
+	}
+	return nil
+}
+
+// openTenantDB opens a connection pool whose search path resolves research
+// tables in the tenant schema and everything else in the shared schema
+func (r *Repository) openTenantDB(schema string) (*gorm.DB, error) {
+	dsn, err := withSearchPath(r.cfg.Database.URL, schema+",public")
+	if err != nil {
+		return nil, err
+	}
+
+	gormConfig := logger.SetUpGormConfig(logger.GetLogger("gorm"), r.cfg.Logging.Level, slowQueryThreshold(r.cfg))
+	withPerformanceOptions(gormConfig, r.cfg)
+	// Shared tables are reached through the search path, so relationships must
+	// not pull them into the tenant schema
+	gormConfig.IgnoreRelationshipsWhenMigrating = true
+
+	db, err := gorm.Open(postgres.Open(dsn), gormConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to schema %s: %w", schema, err)
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	sqlDB.SetMaxOpenConns(r.cfg.Tenancy.TenantMaxConns)
+	sqlDB.SetMaxIdleConns(r.cfg.Tenancy.TenantMaxConns / 2)
+	sqlDB.SetConnMaxLifetime(time.Duration(r.cfg.Database.ConnMaxLifetimeMinutes) * time.Minute)
+	sqlDB.SetConnMaxIdleTime(time.Duration(r.cfg.Database.ConnMaxIdleTimeMinutes) * time.Minute)
+
+	return db, nil
+}
+
+// withSearchPath adds a search_path runtime parameter to a URL or key/value DSN
+func withSearchPath(dsn, searchPath string) (string, error) {
+	if strings.Contains(dsn, "://") {
+		u, err := url.Parse(dsn)
+		if err != nil {
+			return "", fmt.Errorf("invalid database URL: %w", err)
+		}
+		query := u.Query()
+		query.Set("search_path", searchPath)
+		u.RawQuery = query.Encode()
+		return u.String(), nil
+	}
+	return dsn + " search_path=" + searchPath, nil
+}
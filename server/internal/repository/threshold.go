@@ -0,0 +1,279 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/andevellicus/crapp/internal/models"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// DailySymptomValue is a user's answer to a question on one assessment day
+type DailySymptomValue struct {
+	UserEmail      string    `json:"user_email"`
+	OrganizationID string    `json:"organization_id"`
+	Date           time.Time `json:"date"`
+	Value          float64   `json:"value"`
+}
+
+// ThresholdRepository handles symptom thresholds and the flags they raise
+type ThresholdRepository struct {
+	db  *gorm.DB
+	log *zap.SugaredLogger
+}
+
+// NewThresholdRepository creates a new threshold repository
+func NewThresholdRepository(db *gorm.DB, log *zap.SugaredLogger) *ThresholdRepository {
+	return &ThresholdRepository{
+		db:  db,
+		log: log.Named("threshold-repo"),
+	}
+}
+
+// Create stores a new threshold
+func (r *ThresholdRepository) Create(threshold *models.SymptomThreshold) error {
+	if err := r.db.Create(threshold).Error; err != nil {
+		r.log.Errorw("Database error creating threshold", "error", err, "question_id", threshold.QuestionID)
+		return fmt.Errorf("failed to create threshold: %w", err)
+	}
+	return nil
+}
+
+// Get retrieves a threshold within an organization. An empty orgID matches any organization.
+func (r *ThresholdRepository) Get(orgID string, id uint) (*models.SymptomThreshold, error) {
+	var threshold models.SymptomThreshold
+	if err := r.db.Scopes(OrgScope(orgID)).Where("id = ?", id).First(&threshold).Error; err != nil {
+		return nil, err
+	}
+	return &threshold, nil
+}
+
+// Update saves changes to a threshold
+func (r *ThresholdRepository) Update(threshold *models.SymptomThreshold) error {
+	if err := r.db.Save(threshold).Error; err != nil {
+		r.log.Errorw("Database error updating threshold", "error", err, "id", threshold.ID)
+		return fmt.Errorf("failed to update threshold: %w", err)
+	}
+	return nil
+}
+
+// Delete removes a threshold and the flags it raised
+func (r *ThresholdRepository) Delete(threshold *models.SymptomThreshold) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("threshold_id = ?", threshold.ID).Delete(&models.SymptomFlag{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(threshold).Error
+	})
+}
+
+// List returns an organization's thresholds, including those that apply to
+// every organization. An empty orgID lists all thresholds.
+func (r *ThresholdRepository) List(orgID string) ([]models.SymptomThreshold, error) {
+	thresholds := []models.SymptomThreshold{}
+	query := r.db.Order("question_id, id")
+	if orgID != "" {
+		query = query.Where("organization_id = ? OR organization_id = ''", orgID)
+	}
+	if err := query.Find(&thresholds).Error; err != nil {
+		return nil, err
+	}
+	return thresholds, nil
+}
+
+// ListEnabled returns every enabled threshold
+func (r *ThresholdRepository) ListEnabled() ([]models.SymptomThreshold, error) {
+	var thresholds []models.SymptomThreshold
+	err := r.db.Where("enabled = ?", true).Order("id").Find(&thresholds).Error
+	return thresholds, err
+}
+
+// RecordRun stores a run of matching days as a flag. A run that overlaps or
+// directly follows one already flagged extends that flag instead. Returns
+// whether a new flag was raised.
+func (r *ThresholdRepository) RecordRun(flag *models.SymptomFlag) (bool, error) {
+	var existing models.SymptomFlag
+	err := r.db.Where("threshold_id = ? AND user_email = ?", flag.ThresholdID, flag.UserEmail).
+		Where("start_day <= ? AND end_day >= ?", flag.EndDay, flag.StartDay.AddDate(0, 0, -1)).
+		Order("end_day DESC").
+		First(&existing).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		flag.Status = models.FlagOpen
+		if err := r.db.Create(flag).Error; err != nil {
+			return false, fmt.Errorf("failed to create symptom flag: %w", err)
+		}
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if flag.StartDay.Before(existing.StartDay) {
+		existing.StartDay = flag.StartDay
+	}
+	if flag.EndDay.After(existing.EndDay) {
+		existing.EndDay = flag.EndDay
+	}
+	existing.Days = int(existing.EndDay.Sub(existing.StartDay).Hours()/24) + 1
+	existing.PeakValue = flag.PeakValue
+	if err := r.db.Omit("Threshold").Save(&existing).Error; err != nil {
+		return false, fmt.Errorf("failed to extend symptom flag: %w", err)
+	}
+	*flag = existing
+	return false, nil
+}
+
+// SearchFlags returns a page of flags, newest first, with optional filters
+func (r *ThresholdRepository) SearchFlags(orgID, status, email string, skip, limit int) ([]models.SymptomFlag, int64, error) {
+	flags := []models.SymptomFlag{}
+	var total int64
+
+	query := r.db.Model(&models.SymptomFlag{}).Scopes(OrgScope(orgID))
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if email != "" {
+		query = query.Where("user_email = ?", strings.ToLower(email))
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err := query.Preload("Threshold").Order("end_day DESC, id DESC").Offset(skip).Limit(limit).Find(&flags).Error
+	return flags, total, err
+}
+
+// Acknowledge marks a flag as reviewed
+func (r *ThresholdRepository) Acknowledge(orgID string, id uint, by string) (*models.SymptomFlag, error) {
+	var flag models.SymptomFlag
+	if err := r.db.Scopes(OrgScope(orgID)).Preload("Threshold").Where("id = ?", id).First(&flag).Error; err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	flag.Status = models.FlagAcknowledged
+	flag.AcknowledgedBy = by
+	flag.AcknowledgedAt = &now
+	if err := r.db.Omit("Threshold").Save(&flag).Error; err != nil {
+		return nil, fmt.Errorf("failed to acknowledge symptom flag: %w", err)
+	}
+	return &flag, nil
+}
+
+// FlagsForQuestion returns a user's flags on a question, oldest first, for
+// marking threshold crossings on their charts
+func (r *ThresholdRepository) FlagsForQuestion(email, questionID string) ([]models.SymptomFlag, error) {
+	var flags []models.SymptomFlag
+	err := r.db.Preload("Threshold").
+		Where("user_email = ? AND question_id = ?", strings.ToLower(email), questionID).
+		Order("start_day").
+		Find(&flags).Error
+	return flags, err
+}
+
+// FlagVersion changes whenever a user's flags do, for chart cache validation
+func (r *ThresholdRepository) FlagVersion(email string) (string, error) {
+	var row struct {
+		Count  int64
+		Latest *time.Time
+	}
+	err := r.db.Model(&models.SymptomFlag{}).
+		Select("COUNT(*) AS count, MAX(updated_at) AS latest").
+		Where("user_email = ?", strings.ToLower(email)).
+		Scan(&row).Error
+	if err != nil {
+		return "", err
+	}
+
+	version := fmt.Sprintf("%d", row.Count)
+	if row.Latest != nil {
+		version += "-" + row.Latest.UTC().Format(time.RFC3339Nano)
+	}
+	return version, nil
+}
+
+// CreateRedFlagAlert stores a newly raised red-flag alert
+func (r *ThresholdRepository) CreateRedFlagAlert(alert *models.RedFlagAlert) error {
+	alert.UserEmail = strings.ToLower(alert.UserEmail)
+	alert.Status = models.FlagOpen
+	if err := r.db.Create(alert).Error; err != nil {
+		r.log.Errorw("Database error creating red-flag alert", "error", err, "question_id", alert.QuestionID)
+		return fmt.Errorf("failed to create red-flag alert: %w", err)
+	}
+	return nil
+}
+
+// RedFlagNotified records who a red-flag alert reached
+func (r *ThresholdRepository) RedFlagNotified(id uint, recipients []string) error {
+	return r.db.Model(&models.RedFlagAlert{}).
+		Where("id = ?", id).
+		Updates(map[string]any{
+			"notified_to": strings.Join(recipients, ","),
+			"notified_at": time.Now(),
+		}).Error
+}
+
+// SearchRedFlagAlerts returns a page of red-flag alerts, newest first, with
+// optional filters
+func (r *ThresholdRepository) SearchRedFlagAlerts(orgID, status, email string, skip, limit int) ([]models.RedFlagAlert, int64, error) {
+	alerts := []models.RedFlagAlert{}
+	var total int64
+
+	query := r.db.Model(&models.RedFlagAlert{}).Scopes(OrgScope(orgID))
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if email != "" {
+		query = query.Where("user_email = ?", strings.ToLower(email))
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err := query.Order("created_at DESC, id DESC").Offset(skip).Limit(limit).Find(&alerts).Error
+	return alerts, total, err
+}
+
+// AcknowledgeRedFlagAlert marks a red-flag alert as reviewed
+func (r *ThresholdRepository) AcknowledgeRedFlagAlert(orgID string, id uint, by string) (*models.RedFlagAlert, error) {
+	var alert models.RedFlagAlert
+	if err := r.db.Scopes(OrgScope(orgID)).Where("id = ?", id).First(&alert).Error; err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	alert.Status = models.FlagAcknowledged
+	alert.AcknowledgedBy = by
+	alert.AcknowledgedAt = &now
+	if err := r.db.Save(&alert).Error; err != nil {
+		return nil, fmt.Errorf("failed to acknowledge red-flag alert: %w", err)
+	}
+	return &alert, nil
+}
+
+// GetDailySymptomValues returns each user's numeric answers to a question
+// since a day, ordered by user and day. A non-empty orgID limits this to the
+// organization's users.
+func (r *AssessmentRepository) GetDailySymptomValues(questionID, orgID string, since time.Time) ([]DailySymptomValue, error) {
+	var values []DailySymptomValue
+
+	day := "COALESCE(a.assessment_date, " + r.days.SQL("a.submitted_at") + ")"
+	query := r.db.Table("question_responses qr").
+		Select("LOWER(a.user_email) AS user_email, u.organization_id, "+day+" AS date, qr.numeric_value AS value").
+		Joins("JOIN assessments a ON a.id = qr.assessment_id").
+		Joins("JOIN users u ON LOWER(u.email) = LOWER(a.user_email)").
+		Where("qr.question_id = ? AND qr.value_type IN ? AND qr.numeric_value IS NOT NULL", questionID, []string{"number", "boolean"}).
+		Where(day+" >= ?", since)
+	if orgID != "" {
+		query = query.Where("u.organization_id = ?", orgID)
+	}
+
+	if err := query.Order("user_email, date").Scan(&values).Error; err != nil {
+		r.log.Errorw("Error in daily symptom query", "error", err, "question_id", questionID)
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	return values, nil
+}
@@ -0,0 +1,82 @@
+package repository
+
+import "time"
+
+// Timeline bucket sizes for downsampling long timelines
+const (
+	TimelineBucketDay   = "day"
+	TimelineBucketWeek  = "week"
+	TimelineBucketMonth = "month"
+)
+
+// IsTimelineBucket reports whether a bucket size is supported
+func IsTimelineBucket(bucket string) bool {
+	switch bucket {
+	case TimelineBucketDay, TimelineBucketWeek, TimelineBucketMonth:
+		return true
+	}
+	return false
+}
+
+// bucketStart returns the first day of the bucket a date falls in. Weeks
+// start on Monday.
+func bucketStart(date time.Time, bucket string) time.Time {
+	day := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
+	switch bucket {
+	case TimelineBucketWeek:
+		offset := (int(day.Weekday()) + 6) % 7
+		return day.AddDate(0, 0, -offset)
+	case TimelineBucketMonth:
+		return time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, day.Location())
+	}
+	return day
+}
+
+// BucketTimeline collapses date-ordered points into one point per bucket.
+// Each point carries the bucket's mean values, with the minimum, maximum, and
+// number of points it summarizes. A bucket is retrospective only if all of
+// its points are.
+func BucketTimeline(points []TimelineDataPoint, bucket string) []TimelineDataPoint {
+	if !IsTimelineBucket(bucket) || len(points) == 0 {
+		return points
+	}
+
+	buckets := make([]TimelineDataPoint, 0)
+	var symptomSum, metricSum float64
+	flush := func() {
+		last := &buckets[len(buckets)-1]
+		last.SymptomValue = symptomSum / float64(last.Count)
+		last.MetricValue = metricSum / float64(last.Count)
+	}
+
+	for _, point := range points {
+		start := bucketStart(point.Date, bucket)
+		if len(buckets) == 0 || !buckets[len(buckets)-1].Date.Equal(start) {
+			if len(buckets) > 0 {
+				flush()
+			}
+			buckets = append(buckets, TimelineDataPoint{
+				Date:            start,
+				IsRetrospective: true,
+				SymptomMin:      point.SymptomValue,
+				SymptomMax:      point.SymptomValue,
+				MetricMin:       point.MetricValue,
+				MetricMax:       point.MetricValue,
+			})
+			symptomSum, metricSum = 0, 0
+		}
+
+		last := &buckets[len(buckets)-1]
+		last.Count++
+		symptomSum += point.SymptomValue
+		metricSum += point.MetricValue
+		last.SymptomMin = min(last.SymptomMin, point.SymptomValue)
+		last.SymptomMax = max(last.SymptomMax, point.SymptomValue)
+		last.MetricMin = min(last.MetricMin, point.MetricValue)
+		last.MetricMax = max(last.MetricMax, point.MetricValue)
+		last.IsRetrospective = last.IsRetrospective && point.IsRetrospective
+	}
+	flush()
+
+	return buckets
+}
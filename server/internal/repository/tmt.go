@@ -2,10 +2,10 @@ package repository
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/andevellicus/crapp/internal/models"
-	"github.com/andevellicus/crapp/internal/utils"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
 )
@@ -36,12 +36,14 @@ func (r *TMTRepository) Create(results *models.TMTResult) error {
 }
 
 // GetTrailTimelineData retrieves Trail Making Test metrics in timeline format
-func (r *TMTRepository) GetTMTTimelineData(email, metricKey string) ([]TimelineDataPoint, error) {
+func (r *TMTRepository) GetTMTTimelineData(email, metricKey string, filter ChartFilter) ([]TimelineDataPoint, error) {
 	var results []models.TMTResult
 
 	normalizedEmail := strings.ToLower(email)
 	// Query the database for CPT results for the user, ordered by date
 	err := r.db.Where("LOWER(user_email) = ?", normalizedEmail).
+		Scopes(resultFilter(filter)).
+		Omit("raw_data"). // Charts only need the summary columns
 		Order("created_at ASC").
 		Find(&results).Error
 
@@ -50,49 +52,58 @@ func (r *TMTRepository) GetTMTTimelineData(email, metricKey string) ([]TimelineD
 		return nil, err
 	}
 
-	// For each result, check if the raw data is compressed and decompress if needed
-	for i := range results {
-		if len(results[i].RawData) > 0 {
-			// Check if data is compressed (assuming you're using the GZIP header approach)
-			if len(results[i].RawData) >= 4 && string(results[i].RawData[0:4]) == "GZIP" {
-				decompressed, err := utils.DecompressData(results[i].RawData)
-				if err != nil {
-					r.log.Warnw("Failed to decompress TMT raw data", "error", err)
-				} else {
-					results[i].RawData = decompressed
-				}
-			}
-		}
+	retrospective, err := retrospectiveAssessments(r.db, normalizedEmail)
+	if err != nil {
+		r.log.Errorw("Error retrieving retrospective assessments", "error", err)
+		return nil, err
 	}
 
 	// Convert to timeline data points
-	timelinePoints := make([]TimelineDataPoint, len(results))
-	for i, result := range results {
+	timelinePoints := make([]TimelineDataPoint, 0, len(results))
+	for _, result := range results {
 		// Initialize with common date
-		timelinePoints[i] = TimelineDataPoint{
+		point := TimelineDataPoint{
 			Date: result.CreatedAt,
 		}
 
+		// Retrospective entries are plotted on the day they describe
+		if day, ok := retrospective[result.AssessmentID]; ok {
+			if !filter.IncludeRetrospective {
+				continue
+			}
+			point.Date = day
+			point.IsRetrospective = true
+		}
+		if !filter.Contains(point.Date) {
+			continue
+		}
+
 		// Set the appropriate metric value based on the metric key
 		switch metricKey {
 		case "part_a_time":
-			timelinePoints[i].MetricValue = result.PartACompletionTime
-			timelinePoints[i].SymptomValue = 0
+			point.MetricValue = result.PartACompletionTime
+			point.SymptomValue = 0
 		// Continuing from the partial GetTrailTimelineData method:
 		case "part_b_time":
-			timelinePoints[i].MetricValue = result.PartBCompletionTime
-			timelinePoints[i].SymptomValue = 0
+			point.MetricValue = result.PartBCompletionTime
+			point.SymptomValue = 0
 		case "b_to_a_ratio":
-			timelinePoints[i].MetricValue = result.BToARatio
-			timelinePoints[i].SymptomValue = 0
+			point.MetricValue = result.BToARatio
+			point.SymptomValue = 0
 		case "part_a_errors":
-			timelinePoints[i].MetricValue = float64(result.PartAErrors)
-			timelinePoints[i].SymptomValue = 0
+			point.MetricValue = float64(result.PartAErrors)
+			point.SymptomValue = 0
 		case "part_b_errors":
-			timelinePoints[i].MetricValue = float64(result.PartBErrors)
-			timelinePoints[i].SymptomValue = 0
+			point.MetricValue = float64(result.PartBErrors)
+			point.SymptomValue = 0
 		}
+
+		timelinePoints = append(timelinePoints, point)
 	}
 
+	sort.SliceStable(timelinePoints, func(i, j int) bool {
+		return timelinePoints[i].Date.Before(timelinePoints[j].Date)
+	})
+
 	return timelinePoints, nil
 }
@@ -90,6 +90,32 @@ func (r *RefreshTokenRepository) Delete(tokenString string) error {
 		Error
 }
 
+// RevokeStaleSessions revokes refresh tokens last used before idleBefore or
+// belonging to sessions started before startedBefore. A zero time skips
+// that check. It returns how many tokens were revoked.
+func (r *RefreshTokenRepository) RevokeStaleSessions(idleBefore, startedBefore time.Time) (int64, error) {
+	if idleBefore.IsZero() && startedBefore.IsZero() {
+		return 0, nil
+	}
+
+	query := r.db.Model(&models.RefreshToken{}).Where("revoked_at IS NULL")
+	switch {
+	case idleBefore.IsZero():
+		query = query.Where("COALESCE(session_started_at, created_at) < ?", startedBefore)
+	case startedBefore.IsZero():
+		query = query.Where("created_at < ?", idleBefore)
+	default:
+		query = query.Where("created_at < ? OR COALESCE(session_started_at, created_at) < ?", idleBefore, startedBefore)
+	}
+
+	result := query.Update("revoked_at", time.Now())
+	if result.Error != nil {
+		r.log.Errorw("Database error revoking stale sessions", "error", result.Error)
+		return 0, result.Error
+	}
+	return result.RowsAffected, nil
+}
+
 // ----- RevokedToken Repository -----
 
 type RevokedTokenRepository struct {
@@ -146,6 +172,12 @@ func (r *RevokedTokenRepository) RevokeToken(tokenID string, email string) error
 // RevokeAllUserTokens revokes all tokens for a user
 func (r *RevokedTokenRepository) RevokeAllUserTokens(email string) error {
 	normalizedEmail := strings.ToLower(email)
+	// Get all active refresh tokens to find token IDs before they are revoked
+	var tokens []models.RefreshToken
+	if err := r.db.Where("LOWER(user_email) = ? AND revoked_at IS NULL", normalizedEmail).Find(&tokens).Error; err != nil {
+		return err
+	}
+
 	// Revoke all refresh tokens
 	now := time.Now()
 	if err := r.db.Model(&models.RefreshToken{}).
@@ -155,12 +187,6 @@ func (r *RevokedTokenRepository) RevokeAllUserTokens(email string) error {
 		return err
 	}
 
-	// Get all active refresh tokens to find token IDs
-	var tokens []models.RefreshToken
-	if err := r.db.Where("LOWER(user_email) = ? AND revoked_at IS NULL", normalizedEmail).Find(&tokens).Error; err != nil {
-		return err
-	}
-
 	// Add all token IDs to revoked tokens
 	for _, token := range tokens {
 		r.RevokeToken(token.TokenID, normalizedEmail)
@@ -186,7 +212,9 @@ func NewPasswordTokenRepository(db *gorm.DB, log *zap.SugaredLogger, userRepo *U
 }
 
 // Specialized methods
-func (r *PasswordTokenRepository) Create(email string, expiresInMinutes int) (*models.PasswordResetToken, error) {
+// Create issues a new reset token, expiring any earlier ones. codeHash is the
+// hash of an optional short numeric code that can be used instead of the link.
+func (r *PasswordTokenRepository) Create(email string, expiresInMinutes int, codeHash string) (*models.PasswordResetToken, error) {
 	normalizedEmail := strings.ToLower(email)
 	// Check if user exists using the User repository
 	exists, err := r.userRepo.UserExists(normalizedEmail)
@@ -212,6 +240,7 @@ func (r *PasswordTokenRepository) Create(email string, expiresInMinutes int) (*m
 	token := &models.PasswordResetToken{
 		Token:     tokenStr,
 		UserEmail: normalizedEmail,
+		CodeHash:  codeHash,
 		ExpiresAt: time.Now().Add(time.Duration(expiresInMinutes) * time.Minute),
 		CreatedAt: time.Now(),
 	}
@@ -232,6 +261,32 @@ func (r *PasswordTokenRepository) ValidatePasswordResetToken(tokenStr string) (*
 	return &token, nil
 }
 
+// ValidateCode returns the active reset token matching a user's numeric code.
+// Every wrong guess counts against the token, which is burned after maxAttempts.
+func (r *PasswordTokenRepository) ValidateCode(email string, codeHash string, maxAttempts int) (*models.PasswordResetToken, error) {
+	var token models.PasswordResetToken
+	err := r.db.Where("LOWER(user_email) = ? AND code_hash <> '' AND used_at IS NULL AND expires_at > ?", strings.ToLower(email), time.Now()).
+		Order("created_at DESC").
+		First(&token).Error
+	if err != nil {
+		return nil, err
+	}
+
+	if token.CodeHash != codeHash {
+		token.CodeAttempts++
+		updates := map[string]any{"code_attempts": token.CodeAttempts}
+		if token.CodeAttempts >= maxAttempts {
+			updates["used_at"] = time.Now()
+		}
+		if err := r.db.Model(&token).Updates(updates).Error; err != nil {
+			r.log.Warnw("Failed to record reset code attempt", "error", err)
+		}
+		return nil, fmt.Errorf("incorrect reset code")
+	}
+
+	return &token, nil
+}
+
 func (r *PasswordTokenRepository) MarkTokenAsUsed(tokenStr string) error {
 	now := time.Now()
 	return r.db.Model(&models.PasswordResetToken{}).
@@ -253,6 +308,16 @@ func (r *Repository) CleanupExpiredTokens() error {
 		return err
 	}
 
+	// Delete expired login challenges
+	if err := r.db.Where("expires_at < ?", now).Delete(&models.LoginChallenge{}).Error; err != nil {
+		return err
+	}
+
+	// Delete expired reactivation links
+	if err := r.db.Where("expires_at < ?", now).Delete(&models.ReactivationToken{}).Error; err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -9,34 +10,33 @@ import (
 
 	"github.com/andevellicus/crapp/internal/config"
 	"github.com/andevellicus/crapp/internal/models"
+	"github.com/andevellicus/crapp/internal/utils"
+	"github.com/google/uuid"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// ErrLegalHold is returned when deleting or anonymizing a user under a legal hold
+var ErrLegalHold = errors.New("user is under a legal hold")
+
 type UserRepository struct {
-	db  *gorm.DB
-	log *zap.SugaredLogger
-	cfg *config.Config
-}
+	db   *gorm.DB
+	log  *zap.SugaredLogger
+	cfg  *config.Config
+	days utils.AssessmentDay
 
-// UserNotificationPreferences represents a user's complete notification preferences
-type UserNotificationPreferences struct {
-	// Push notification preferences
-	PushEnabled bool `json:"push_enabled"`
-	// Email notification preferences
-	EmailEnabled bool `json:"email_enabled"`
-	// Shared reminder time settings
-	ReminderTimes []string `json:"reminder_times"`
-	// Time when user can still complete yesterday's assessment
-	CutoffTime string `json:"cutoff_time,omitempty"`
+	// Where attachment files are kept, nil when storage is not configured
+	files FileStore
 }
 
 // NewUserRepository creates a new user repository
-func NewUserRepository(db *gorm.DB, log *zap.SugaredLogger, cfg *config.Config) *UserRepository {
+func NewUserRepository(db *gorm.DB, log *zap.SugaredLogger, cfg *config.Config, days utils.AssessmentDay) *UserRepository {
 	return &UserRepository{
-		db:  db,
-		log: log.Named("user-repo"),
-		cfg: cfg,
+		db:   db,
+		log:  log.Named("user-repo"),
+		cfg:  cfg,
+		days: days,
 	}
 }
 
@@ -45,14 +45,14 @@ func (r *UserRepository) Create(user *models.User) error {
 		return fmt.Errorf("invalid user data: %w", err)
 	}
 
+	// Users not placed in an organization join the deployment default
+	if user.OrganizationID == "" {
+		user.OrganizationID = r.cfg.Tenancy.DefaultOrganization
+	}
+
 	// Initialize default notification preferences if not set
 	if user.NotificationPreferences == "" {
-		defaultPrefs := UserNotificationPreferences{
-			PushEnabled:   false,
-			EmailEnabled:  false,
-			ReminderTimes: r.cfg.Reminders.Times,
-			CutoffTime:    r.cfg.Reminders.CutoffTime,
-		}
+		defaultPrefs := r.defaultNotificationPreferences()
 
 		prefsJSON, err := json.Marshal(defaultPrefs)
 		if err != nil {
@@ -122,6 +122,23 @@ func (r *UserRepository) LastLoginNow(email string) error {
 	return nil
 }
 
+// UpdateTimezone saves the time zone detected on a user's device. It reports
+// whether the zone changed, since their reminders then move with it.
+func (r *UserRepository) UpdateTimezone(email, timezone string) (bool, error) {
+	if !utils.ValidTimezone(timezone) {
+		return false, nil
+	}
+	normalizedEmail := strings.ToLower(email)
+	result := r.db.Model(&models.User{}).
+		Where("LOWER(email) = ? AND timezone IS DISTINCT FROM ?", normalizedEmail, timezone).
+		Update("timezone", timezone)
+	if result.Error != nil {
+		r.log.Errorw("Database error updating time zone", "email", normalizedEmail, "error", result.Error)
+		return false, fmt.Errorf("failed to update user: %w", result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
+
 func (r *UserRepository) Delete(email string) error {
 	// Start a transaction
 	tx := r.db.Begin()
@@ -129,97 +146,673 @@ func (r *UserRepository) Delete(email string) error {
 		return fmt.Errorf("failed to begin transaction: %w", tx.Error)
 	}
 
+	fileKeys, err := deleteResearchData(tx, email)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	// Delete refresh tokens
+	if err := tx.Delete(&models.RefreshToken{}, "LOWER(user_email)  = ?", email).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("error deleting refresh tokens: %w", err)
+	}
+
+	// Delete revoked tokens
+	if err := tx.Delete(&models.RevokedToken{}, "LOWER(user_email)  = ?", email).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("error deleting revoked tokens: %w", err)
+	}
+
+	// Delete password reset tokens
+	if err := tx.Delete(&models.PasswordResetToken{}, "LOWER(user_email)  = ?", email).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("error deleting password reset tokens: %w", err)
+	}
+
+	// Delete held logins, which record where they came from
+	if err := tx.Delete(&models.LoginChallenge{}, "LOWER(user_email) = ?", email).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("error deleting login challenges: %w", err)
+	}
+
+	// Delete reactivation links for a paused account
+	if err := tx.Delete(&models.ReactivationToken{}, "LOWER(user_email) = ?", email).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("error deleting reactivation tokens: %w", err)
+	}
+
+	// Delete caregiver links in either direction
+	if err := tx.Delete(&models.CaregiverLink{}, "patient_email = ? OR caregiver_email = ?", email, email).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("error deleting caregiver links: %w", err)
+	}
+
+	// Delete policy acceptance history
+	if err := tx.Delete(&models.PolicyAcceptance{}, "user_email = ?", email).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("error deleting policy acceptances: %w", err)
+	}
+
+	// Delete audit history
+	if err := tx.Delete(&models.AuditEvent{}, "user_email = ?", email).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("error deleting audit events: %w", err)
+	}
+
+	// Delete saved chart views
+	if err := tx.Delete(&models.ChartView{}, "LOWER(user_email) = ?", email).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("error deleting chart views: %w", err)
+	}
+
+	// Delete earned achievements
+	if err := tx.Delete(&models.Achievement{}, "user_email = ?", email).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("error deleting achievements: %w", err)
+	}
+
+	// Delete reminder engagement events
+	if err := tx.Delete(&models.NotificationEvent{}, "user_email = ?", email).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("error deleting notification events: %w", err)
+	}
+
+	// Delete reminder history and settings
+	if err := deleteReminders(tx, email); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	// Clear the email from sign-up attempts
+	if err := clearSignupAttempts(tx, email); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	// Delete study withdrawals
+	if err := tx.Delete(&models.StudyWithdrawal{}, "LOWER(user_email) = ?", email).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("error deleting study withdrawals: %w", err)
+	}
+
+	// Delete impersonation sessions in either direction
+	if err := tx.Delete(&models.ImpersonationSession{}, "LOWER(target_email) = ? OR LOWER(admin_email) = ?", email, email).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("error deleting impersonation sessions: %w", err)
+	}
+
+	// Delete adverse events and protocol deviations logged against them
+	if err := tx.Delete(&models.ClinicalEvent{}, "LOWER(user_email) = ?", email).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("error deleting clinical events: %w", err)
+	}
+
+	// Delete red-flag alerts raised by their answers
+	if err := tx.Delete(&models.RedFlagAlert{}, "LOWER(user_email) = ?", email).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("error deleting red-flag alerts: %w", err)
+	}
+
+	// Delete symptom flags raised when their answers crossed a threshold
+	if err := tx.Delete(&models.SymptomFlag{}, "LOWER(user_email) = ?", email).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("error deleting symptom flags: %w", err)
+	}
+
+	// Rows kept for the study or the clinic lose the email to a placeholder
+	detached := fmt.Sprintf("deleted-%s@deleted.invalid", uuid.NewString())
+
+	// Allocations are kept because the next sequence number in each stratum
+	// is counted from them, and removing one would repeat it and unbalance
+	// the block; they lose the email, age and sex
+	if err := tx.Model(&models.ArmAllocation{}).Where("LOWER(user_email) = ?", email).
+		Updates(map[string]any{
+			"user_email": detached,
+			"age":        nil,
+			"sex":        "",
+		}).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("error detaching arm allocations: %w", err)
+	}
+
+	// Kiosk sessions are kept so the clinician who ran them stays on record
+	if err := tx.Model(&models.KioskSession{}).Where("LOWER(patient_email) = ?", email).
+		Update("patient_email", detached).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("error detaching kiosk sessions: %w", err)
+	}
+
+	// Delete devices
+	if err := tx.Delete(&models.Device{}, "LOWER(user_email)  = ?", email).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("error deleting devices: %w", err)
+	}
+
+	// Finally, delete the user
+	if err := tx.Delete(&models.User{}, "LOWER(email) = ?", email).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("error deleting user: %w", err)
+	}
+
+	// Commit transaction
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+	r.removeFiles(fileKeys)
+	return nil
+}
+
+// DeleteResearchData removes a user's assessments, form states and test
+// results while keeping their account
+func (r *UserRepository) DeleteResearchData(email string) error {
+	normalizedEmail := strings.ToLower(email)
+	var fileKeys []string
+	if err := r.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		fileKeys, err = deleteResearchData(tx, normalizedEmail)
+		return err
+	}); err != nil {
+		r.log.Errorw("Failed to delete research data", "email", normalizedEmail, "error", err)
+		return err
+	}
+	r.removeFiles(fileKeys)
+	return nil
+}
+
+// deleteResearchData removes everything recorded by a user's assessments and
+// returns the storage keys of their attachment files, to be removed once the
+// transaction commits. Nothing is removed while the user is under a legal hold.
+func deleteResearchData(tx *gorm.DB, email string) ([]string, error) {
+	if err := checkLegalHold(tx, email); err != nil {
+		return nil, err
+	}
+
+	fileKeys, err := deleteAttachments(tx, email)
+	if err != nil {
+		return nil, err
+	}
+
 	// Find assessment IDs for the user first
 	var assessmentIDs []uint
 	if err := tx.Model(&models.Assessment{}).Where("LOWER(user_email) = ?", email).Pluck("id", &assessmentIDs).Error; err != nil {
-		tx.Rollback()
-		return fmt.Errorf("error finding assessments for user %s: %w", email, err)
+		return nil, fmt.Errorf("error finding assessments for user %s: %w", email, err)
 	}
 
 	// Only proceed if there are assessments to deal with
 	if len(assessmentIDs) > 0 {
+		// Delete chart summaries built from them
+		if err := tx.Where("assessment_id IN (?)", assessmentIDs).Delete(&models.ChartSummary{}).Error; err != nil {
+			return nil, fmt.Errorf("error deleting chart summaries: %w", err)
+		}
+
 		// Delete assessment_metrics first
 		if err := tx.Where("assessment_id IN (?)", assessmentIDs).Delete(&models.AssessmentMetric{}).Error; err != nil {
-			tx.Rollback()
-			return fmt.Errorf("error deleting assessment metrics: %w", err)
+			return nil, fmt.Errorf("error deleting assessment metrics: %w", err)
 		}
 
-		// Delete question responses next
+		// Delete question responses and their revisions next
+		if err := tx.Where("assessment_id IN (?)", assessmentIDs).Delete(&models.QuestionResponseRevision{}).Error; err != nil {
+			return nil, fmt.Errorf("error deleting response revisions: %w", err)
+		}
 		if err := tx.Where("assessment_id IN (?)", assessmentIDs).Delete(&models.QuestionResponse{}).Error; err != nil {
-			tx.Rollback()
-			return fmt.Errorf("error deleting question responses: %w", err)
+			return nil, fmt.Errorf("error deleting question responses: %w", err)
 		}
 
 		// Delete CPT results linked to these assessments
 		if err := tx.Where("assessment_id IN (?)", assessmentIDs).Delete(&models.CPTResult{}).Error; err != nil {
-			tx.Rollback()
-			return fmt.Errorf("error deleting assessment CPT results: %w", err)
+			return nil, fmt.Errorf("error deleting assessment CPT results: %w", err)
 		}
 
 		// Delete TMT results linked to these assessments
 		if err := tx.Where("assessment_id IN (?)", assessmentIDs).Delete(&models.TMTResult{}).Error; err != nil {
-			tx.Rollback()
-			return fmt.Errorf("error deleting assessment TMT results: %w", err)
+			return nil, fmt.Errorf("error deleting assessment TMT results: %w", err)
 		}
 
 		// Delete digit span results linked to these assessments
 		if err := tx.Where("assessment_id IN (?)", assessmentIDs).Delete(&models.DigitSpanResult{}).Error; err != nil {
-			tx.Rollback()
-			return fmt.Errorf("error deleting assessment digit span results: %w", err)
+			return nil, fmt.Errorf("error deleting assessment digit span results: %w", err)
 		}
 
 		// Delete form states
 		if err := tx.Delete(&models.FormState{}, "LOWER(user_email)  = ?", email).Error; err != nil {
-			tx.Rollback()
-			return fmt.Errorf("error deleting form states: %w", err)
+			return nil, fmt.Errorf("error deleting form states: %w", err)
 		}
 
 		// --- Now delete the assessments themselves ---
 		if err := tx.Where("id IN (?)", assessmentIDs).Delete(&models.Assessment{}).Error; err != nil {
-			tx.Rollback()
-			return fmt.Errorf("error deleting assessments for user %s: %w", email, err)
+			return nil, fmt.Errorf("error deleting assessments for user %s: %w", email, err)
 		}
 	} else {
 		// If there were no assessments, still need to delete any dangling form states
 		// (e.g., states that were started but never submitted/linked)
 		if err := tx.Where("LOWER(user_email)  = ? AND assessment_id IS NULL", email).Delete(&models.FormState{}).Error; err != nil {
-			tx.Rollback()
-			return fmt.Errorf("error deleting dangling form states: %w", err)
+			return nil, fmt.Errorf("error deleting dangling form states: %w", err)
 		}
 	}
 
-	// Delete refresh tokens
-	if err := tx.Delete(&models.RefreshToken{}, "LOWER(user_email)  = ?", email).Error; err != nil {
-		tx.Rollback()
-		return fmt.Errorf("error deleting refresh tokens: %w", err)
+	return fileKeys, nil
+}
+
+// Anonymize replaces a user's identity with a random pseudonym, keeping their
+// assessment data for analysis while dropping credentials and contact details.
+// Returns the pseudonymous email the data now belongs to.
+func (r *UserRepository) Anonymize(email string) (string, error) {
+	normalizedEmail := strings.ToLower(email)
+	pseudonym := fmt.Sprintf("anon-%s@anonymized.invalid", uuid.NewString())
+	now := time.Now()
+
+	var fileKeys []string
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		if err := tx.Where("LOWER(email) = ?", normalizedEmail).First(&user).Error; err != nil {
+			return fmt.Errorf("error finding user %s: %w", normalizedEmail, err)
+		}
+		if user.LegalHold {
+			return ErrLegalHold
+		}
+
+		// Create the pseudonymous user first so data can be moved onto it
+		anonymous := models.User{
+			Email:              pseudonym,
+			CreatedAt:          user.CreatedAt,
+			LastLogin:          user.LastLogin,
+			LastAssessmentDate: user.LastAssessmentDate,
+			StudyID:            user.StudyID,
+			OrganizationID:     user.OrganizationID,
+			AnonymizedAt:       &now,
+		}
+		if err := tx.Create(&anonymous).Error; err != nil {
+			return fmt.Errorf("error creating anonymized user: %w", err)
+		}
+
+		// Move research data over to the pseudonym
+		for _, model := range []any{
+			&models.Assessment{},
+			&models.FormState{},
+			&models.CPTResult{},
+			&models.TMTResult{},
+			&models.DigitSpanResult{},
+			&models.ChartSummary{},
+			&models.ClinicalEvent{},
+			&models.ArmAllocation{},
+		} {
+			if err := tx.Model(model).Where("LOWER(user_email) = ?", normalizedEmail).
+				Update("user_email", pseudonym).Error; err != nil {
+				return fmt.Errorf("error reassigning %T: %w", model, err)
+			}
+		}
+
+		// Assessments still point at their devices, so devices are kept with
+		// only their type; the name, browser, OS and time zone describe the
+		// person's own hardware
+		if err := tx.Model(&models.Device{}).Where("LOWER(user_email) = ?", normalizedEmail).
+			Updates(map[string]any{
+				"user_email":  pseudonym,
+				"device_name": "",
+				"browser":     "",
+				"os":          "",
+				"timezone":    "",
+			}).Error; err != nil {
+			return fmt.Errorf("error scrubbing devices: %w", err)
+		}
+
+		// Attached photos and documents can show the person themselves
+		var err error
+		if fileKeys, err = deleteAttachments(tx, normalizedEmail); err != nil {
+			return err
+		}
+
+		// Drop everything that identifies the person or grants access
+		if err := tx.Delete(&models.RefreshToken{}, "LOWER(user_email) = ?", normalizedEmail).Error; err != nil {
+			return fmt.Errorf("error deleting refresh tokens: %w", err)
+		}
+		if err := tx.Delete(&models.RevokedToken{}, "LOWER(user_email) = ?", normalizedEmail).Error; err != nil {
+			return fmt.Errorf("error deleting revoked tokens: %w", err)
+		}
+		if err := tx.Delete(&models.PasswordResetToken{}, "LOWER(user_email) = ?", normalizedEmail).Error; err != nil {
+			return fmt.Errorf("error deleting password reset tokens: %w", err)
+		}
+		if err := tx.Delete(&models.LoginChallenge{}, "LOWER(user_email) = ?", normalizedEmail).Error; err != nil {
+			return fmt.Errorf("error deleting login challenges: %w", err)
+		}
+		if err := tx.Delete(&models.ReactivationToken{}, "LOWER(user_email) = ?", normalizedEmail).Error; err != nil {
+			return fmt.Errorf("error deleting reactivation tokens: %w", err)
+		}
+		if err := tx.Delete(&models.CaregiverLink{}, "patient_email = ? OR caregiver_email = ?", normalizedEmail, normalizedEmail).Error; err != nil {
+			return fmt.Errorf("error deleting caregiver links: %w", err)
+		}
+		if err := tx.Delete(&models.PolicyAcceptance{}, "user_email = ?", normalizedEmail).Error; err != nil {
+			return fmt.Errorf("error deleting policy acceptances: %w", err)
+		}
+		if err := tx.Delete(&models.AuditEvent{}, "user_email = ?", normalizedEmail).Error; err != nil {
+			return fmt.Errorf("error deleting audit events: %w", err)
+		}
+		// Saved views, badges and reminder engagement only serve the person
+		if err := tx.Delete(&models.ChartView{}, "LOWER(user_email) = ?", normalizedEmail).Error; err != nil {
+			return fmt.Errorf("error deleting chart views: %w", err)
+		}
+		if err := tx.Delete(&models.Achievement{}, "user_email = ?", normalizedEmail).Error; err != nil {
+			return fmt.Errorf("error deleting achievements: %w", err)
+		}
+		if err := tx.Delete(&models.NotificationEvent{}, "user_email = ?", normalizedEmail).Error; err != nil {
+			return fmt.Errorf("error deleting notification events: %w", err)
+		}
+		if err := deleteReminders(tx, normalizedEmail); err != nil {
+			return err
+		}
+		if err := clearSignupAttempts(tx, normalizedEmail); err != nil {
+			return err
+		}
+		if err := tx.Delete(&models.ImpersonationSession{}, "LOWER(target_email) = ? OR LOWER(admin_email) = ?", normalizedEmail, normalizedEmail).Error; err != nil {
+			return fmt.Errorf("error deleting impersonation sessions: %w", err)
+		}
+		if err := tx.Model(&models.StudyWithdrawal{}).Where("LOWER(user_email) = ?", normalizedEmail).
+			Updates(map[string]any{"user_email": pseudonym, "reason": ""}).Error; err != nil {
+			return fmt.Errorf("error reassigning study withdrawals: %w", err)
+		}
+		if err := tx.Model(&models.KioskSession{}).Where("patient_email = ?", normalizedEmail).
+			Update("patient_email", pseudonym).Error; err != nil {
+			return fmt.Errorf("error reassigning kiosk sessions: %w", err)
+		}
+		if err := tx.Model(&models.RedFlagAlert{}).Where("LOWER(user_email) = ?", normalizedEmail).
+			Update("user_email", pseudonym).Error; err != nil {
+			return fmt.Errorf("error reassigning red-flag alerts: %w", err)
+		}
+		if err := tx.Model(&models.SymptomFlag{}).Where("LOWER(user_email) = ?", normalizedEmail).
+			Update("user_email", pseudonym).Error; err != nil {
+			return fmt.Errorf("error reassigning symptom flags: %w", err)
+		}
+
+		if err := tx.Delete(&models.User{}, "LOWER(email) = ?", normalizedEmail).Error; err != nil {
+			return fmt.Errorf("error deleting user: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		r.log.Errorw("Failed to anonymize user", "email", normalizedEmail, "error", err)
+		return "", err
 	}
+	r.removeFiles(fileKeys)
 
-	// Delete revoked tokens
-	if err := tx.Delete(&models.RevokedToken{}, "LOWER(user_email)  = ?", email).Error; err != nil {
-		tx.Rollback()
-		return fmt.Errorf("error deleting revoked tokens: %w", err)
+	return pseudonym, nil
+}
+
+// deleteAttachments removes the records of a user's attachments and returns
+// the storage keys of their files and thumbnails
+func deleteAttachments(tx *gorm.DB, email string) ([]string, error) {
+	var attachments []models.AssessmentAttachment
+	if err := tx.Where("LOWER(user_email) = ?", email).Find(&attachments).Error; err != nil {
+		return nil, fmt.Errorf("error finding attachments: %w", err)
+	}
+	if len(attachments) == 0 {
+		return nil, nil
+	}
+	if err := tx.Delete(&models.AssessmentAttachment{}, "LOWER(user_email) = ?", email).Error; err != nil {
+		return nil, fmt.Errorf("error deleting attachments: %w", err)
 	}
 
-	// Delete password reset tokens
-	if err := tx.Delete(&models.PasswordResetToken{}, "LOWER(user_email)  = ?", email).Error; err != nil {
-		tx.Rollback()
-		return fmt.Errorf("error deleting password reset tokens: %w", err)
+	keys := make([]string, 0, 2*len(attachments))
+	for _, attachment := range attachments {
+		keys = append(keys, attachment.StorageKey)
+		if attachment.ThumbnailKey != "" {
+			keys = append(keys, attachment.ThumbnailKey)
+		}
 	}
+	return keys, nil
+}
 
-	// Delete devices
-	if err := tx.Delete(&models.Device{}, "LOWER(user_email)  = ?", email).Error; err != nil {
-		tx.Rollback()
-		return fmt.Errorf("error deleting devices: %w", err)
+// deleteReminders removes the reminders sent to a user, their deliveries
+// from scheduled jobs, and their snoozes and skipped days
+func deleteReminders(tx *gorm.DB, email string) error {
+	if err := tx.Delete(&models.ReminderSent{}, "LOWER(user_email) = ?", email).Error; err != nil {
+		return fmt.Errorf("error deleting sent reminders: %w", err)
+	}
+	if err := tx.Delete(&models.ReminderDelivery{}, "LOWER(user_email) = ?", email).Error; err != nil {
+		return fmt.Errorf("error deleting reminder deliveries: %w", err)
+	}
+	if err := tx.Delete(&models.ReminderOverride{}, "LOWER(user_email) = ?", email).Error; err != nil {
+		return fmt.Errorf("error deleting reminder overrides: %w", err)
+	}
+	return nil
+}
+
+// clearSignupAttempts removes an email from the sign-up attempts made with it.
+// The attempts stay, since sign-up limits and metrics count them by IP.
+func clearSignupAttempts(tx *gorm.DB, email string) error {
+	if err := tx.Model(&models.SignupAttempt{}).Where("LOWER(email) = ?", email).
+		Update("email", "").Error; err != nil {
+		return fmt.Errorf("error clearing sign-up attempts: %w", err)
 	}
+	return nil
+}
 
-	// Finally, delete the user
-	if err := tx.Delete(&models.User{}, "LOWER(user_email)  = ?", email).Error; err != nil {
-		tx.Rollback()
-		return fmt.Errorf("error deleting user: %w", err)
+// removeFiles deletes attachment files from storage. A file that can't be
+// removed is logged; its record is already gone.
+func (r *UserRepository) removeFiles(keys []string) {
+	if r.files == nil {
+		return
+	}
+	ctx := context.Background()
+	for _, key := range keys {
+		if err := r.files.Delete(ctx, key); err != nil {
+			r.log.Warnw("Failed to delete attachment file", "error", err, "key", key)
+		}
 	}
+}
 
-	// Commit transaction
-	return tx.Commit().Error
+// checkLegalHold returns ErrLegalHold if the user is under a legal hold
+func checkLegalHold(tx *gorm.DB, email string) error {
+	var held int64
+	if err := tx.Model(&models.User{}).
+		Where("LOWER(email) = ? AND legal_hold = ?", strings.ToLower(email), true).
+		Count(&held).Error; err != nil {
+		return fmt.Errorf("error checking legal hold: %w", err)
+	}
+	if held > 0 {
+		return ErrLegalHold
+	}
+	return nil
+}
+
+// GetLifecycleCandidates returns the users the inactivity policy applies to.
+// Paused users are left alone until they come back, and withdrawn
+// participants are no longer expected to take part.
+func (r *UserRepository) GetLifecycleCandidates() ([]models.User, error) {
+	var users []models.User
+	err := r.db.Where("is_admin = ? AND lifecycle_exempt = ? AND anonymized_at IS NULL AND deactivated_at IS NULL AND withdrawn_at IS NULL", false, false).
+		Find(&users).Error
+	if err != nil {
+		r.log.Errorw("Database error getting lifecycle candidates", "error", err)
+		return nil, err
+	}
+	return users, nil
+}
+
+// GetRetentionOverrides returns the users with their own retention period.
+// Unlike the inactivity policy, these apply to exempt, paused and withdrawn
+// users too, since the users asked for them.
+func (r *UserRepository) GetRetentionOverrides() ([]models.User, error) {
+	var users []models.User
+	err := r.db.Where("is_admin = ? AND legal_hold = ? AND anonymized_at IS NULL AND retention_days > 0", false, false).
+		Find(&users).Error
+	if err != nil {
+		r.log.Errorw("Database error getting retention overrides", "error", err)
+		return nil, err
+	}
+	return users, nil
+}
+
+// SetRetentionOverride places or releases a user's legal hold and sets their
+// own retention period. A period of 0 leaves the user to their study's policy.
+func (r *UserRepository) SetRetentionOverride(email string, legalHold bool, retentionDays int, retentionAction string) error {
+	if retentionDays <= 0 {
+		retentionDays, retentionAction = 0, ""
+	}
+
+	result := r.db.Model(&models.User{}).
+		Where("LOWER(email) = ?", strings.ToLower(email)).
+		Updates(map[string]any{
+			"legal_hold":       legalHold,
+			"retention_days":   retentionDays,
+			"retention_action": retentionAction,
+		})
+	if result.Error != nil {
+		r.log.Errorw("Database error updating retention override", "email", email, "error", result.Error)
+		return fmt.Errorf("failed to update user: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("user %s not found", email)
+	}
+	return nil
+}
+
+// GetSandboxUsers returns the emails of demo sign-ups
+func (r *UserRepository) GetSandboxUsers() ([]string, error) {
+	var emails []string
+	err := r.db.Model(&models.User{}).Where("sandbox = ?", true).Pluck("email", &emails).Error
+	if err != nil {
+		r.log.Errorw("Database error getting sandbox users", "error", err)
+		return nil, err
+	}
+	return emails, nil
+}
+
+// SetInactive flags or clears a user's inactivity. Clearing also resets the
+// re-engagement email so it is sent again on the next lapse.
+func (r *UserRepository) SetInactive(email string, since *time.Time) error {
+	updates := map[string]any{"inactive_since": since}
+	if since == nil {
+		updates["reengagement_sent_at"] = nil
+	}
+
+	result := r.db.Model(&models.User{}).
+		Where("LOWER(email) = ?", strings.ToLower(email)).
+		Updates(updates)
+	if result.Error != nil {
+		r.log.Errorw("Database error updating inactivity flag", "email", email, "error", result.Error)
+		return fmt.Errorf("failed to update user: %w", result.Error)
+	}
+	return nil
+}
+
+// SetPaused pauses or reactivates a user's account
+func (r *UserRepository) SetPaused(email string, paused bool) error {
+	now := time.Now()
+	updates := map[string]any{"deactivated_at": nil, "reactivated_at": &now}
+	if paused {
+		updates = map[string]any{"deactivated_at": &now}
+	}
+
+	result := r.db.Model(&models.User{}).
+		Where("LOWER(email) = ?", strings.ToLower(email)).
+		Updates(updates)
+	if result.Error != nil {
+		r.log.Errorw("Database error updating account pause", "email", email, "error", result.Error)
+		return fmt.Errorf("failed to update user: %w", result.Error)
+	}
+	return nil
+}
+
+// ReengagementSentNow records that a re-engagement email went out
+func (r *UserRepository) ReengagementSentNow(email string) error {
+	result := r.db.Model(&models.User{}).
+		Where("LOWER(email) = ?", strings.ToLower(email)).
+		Update("reengagement_sent_at", time.Now())
+	if result.Error != nil {
+		r.log.Errorw("Database error recording re-engagement email", "email", email, "error", result.Error)
+		return fmt.Errorf("failed to update user: %w", result.Error)
+	}
+	return nil
+}
+
+// SetClinician assigns the clinician notified about a user's red-flag
+// answers. An empty clinician removes the assignment.
+func (r *UserRepository) SetClinician(email, clinician string) error {
+	result := r.db.Model(&models.User{}).
+		Where("LOWER(email) = ?", strings.ToLower(email)).
+		Update("clinician_email", strings.ToLower(clinician))
+	if result.Error != nil {
+		r.log.Errorw("Database error assigning clinician", "email", email, "error", result.Error)
+		return fmt.Errorf("failed to update user: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("user %s not found", email)
+	}
+	return nil
+}
+
+// SetAccessibility saves a user's accessibility preferences
+func (r *UserRepository) SetAccessibility(email string, preferences models.AccessibilityPreferences) error {
+	result := r.db.Model(&models.User{}).
+		Where("LOWER(email) = ?", strings.ToLower(email)).
+		Updates(map[string]any{
+			"large_text":        preferences.LargeText,
+			"reduced_motion":    preferences.ReducedMotion,
+			"extended_timeouts": preferences.ExtendedTimeouts,
+		})
+	if result.Error != nil {
+		r.log.Errorw("Database error updating accessibility preferences", "email", email, "error", result.Error)
+		return fmt.Errorf("failed to update user: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("user %s not found", email)
+	}
+	return nil
+}
+
+// UpdateLifecycleOverride sets a user's study and inactivity policy exemption
+func (r *UserRepository) UpdateLifecycleOverride(email string, studyID string, exempt bool) error {
+	result := r.db.Model(&models.User{}).
+		Where("LOWER(email) = ?", strings.ToLower(email)).
+		Updates(map[string]any{
+			"study_id":         studyID,
+			"lifecycle_exempt": exempt,
+			"withdrawn_at":     keepWithdrawalIn(studyID),
+		})
+	if result.Error != nil {
+		r.log.Errorw("Database error updating lifecycle override", "email", email, "error", result.Error)
+		return fmt.Errorf("failed to update user: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("user %s not found", email)
+	}
+	return nil
+}
+
+// SetStudy enrolls a user in a study
+func (r *UserRepository) SetStudy(email, studyID string) error {
+	result := r.db.Model(&models.User{}).
+		Where("LOWER(email) = ?", strings.ToLower(email)).
+		Updates(map[string]any{
+			"study_id":     studyID,
+			"withdrawn_at": keepWithdrawalIn(studyID),
+		})
+	if result.Error != nil {
+		r.log.Errorw("Database error setting study", "email", email, "error", result.Error)
+		return fmt.Errorf("failed to update user: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("user %s not found", email)
+	}
+	return nil
+}
+
+// keepWithdrawalIn keeps a withdrawal only while the user stays in the study
+// they withdrew from; enrolling in another study starts afresh
+func keepWithdrawalIn(studyID string) clause.Expr {
+	return gorm.Expr("CASE WHEN study_id = ? THEN withdrawn_at END", studyID)
+}
+
+// GetAlertRecipients returns the admins to notify about an organization's
+// participants: its organization admins and every global admin
+func (r *UserRepository) GetAlertRecipients(orgID string) ([]string, error) {
+	var emails []string
+	err := r.db.Model(&models.User{}).
+		Where("anonymized_at IS NULL").
+		Where("is_admin = ? OR (is_org_admin = ? AND organization_id = ?)", true, true, orgID).
+		Pluck("email", &emails).Error
+	return emails, err
 }
 
 // GetByEmail retrieves a user by email
@@ -284,17 +877,45 @@ func (r *UserRepository) UpdatePassword(email string, hashedPassword []byte) err
 	return nil
 }
 
-// Check if user has already completed assessment for today
+// HasCompletedAssessment checks whether the user has submitted an assessment
+// for the current assessment day, using their own cutoff time when set
 func (r *UserRepository) HasCompletedAssessment(email string) (bool, error) {
 	normalizedEmail := strings.ToLower(email)
-	var count int64
-	today := time.Now().Truncate(24 * time.Hour).Format("2006-01-02") // Start of today
+	var user models.User
+	err := r.db.Select("last_assessment_date", "notification_preferences", "timezone").
+		Where("LOWER(email) = ?", normalizedEmail).
+		First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if user.LastAssessmentDate.IsZero() {
+		return false, nil
+	}
 
-	err := r.db.Model(&models.User{}).
-		Where("LOWER(email) = ? AND last_assessment_date >= ?", normalizedEmail, today).
-		Count(&count).Error
+	day := r.AssessmentDayFor(&user)
+	return !day.Of(user.LastAssessmentDate).Before(day.Today()), nil
+}
 
-	return count > 0, err
+// AssessmentDayFor returns a user's assessment day boundaries, in their own
+// time zone and with the cutoff time from their notification preferences when
+// valid
+func (r *UserRepository) AssessmentDayFor(user *models.User) utils.AssessmentDay {
+	days, err := r.days.InZone(user.Timezone)
+	if err != nil {
+		days = r.days
+	}
+	prefs, err := r.preferencesOf(user)
+	if err != nil {
+		return days
+	}
+	day, err := days.WithCutoff(prefs.CutoffTime)
+	if err != nil {
+		return days
+	}
+	return day
 }
 
 // SavePushSubscription saves a push subscription for a user
@@ -317,6 +938,7 @@ func (r *UserRepository) SavePushSubscription(email string, subscription string)
 // SaveNotificationPreferences saves a user's complete notification preferences
 func (r *UserRepository) SaveNotificationPreferences(email string, preferences *UserNotificationPreferences) error {
 	normalizedEmail := strings.ToLower(email)
+	preferences.Version = notificationPreferencesVersion
 	// Convert preferences to JSON
 	preferencesJSON, err := json.Marshal(preferences)
 	if err != nil {
@@ -337,6 +959,14 @@ func (r *UserRepository) SaveNotificationPreferences(email string, preferences *
 	return nil
 }
 
+// ClearPushSubscription removes a subscription the push service reported as
+// expired
+func (r *UserRepository) ClearPushSubscription(email string) error {
+	return r.db.Model(&models.User{}).
+		Where("LOWER(email) = ?", strings.ToLower(email)).
+		Update("push_subscription", "").Error
+}
+
 // GetPushSubscription gets a user's push subscription
 func (r *UserRepository) GetPushSubscription(email string) (string, error) {
 	normalizedEmail := strings.ToLower(email)
@@ -348,7 +978,7 @@ func (r *UserRepository) GetPushSubscription(email string) (string, error) {
 	return user.PushSubscription, nil
 }
 
-// GetPushPreferences gets a user's push notification preferences
+// GetNotificationPreferences gets a user's notification preferences
 func (r *UserRepository) GetNotificationPreferences(email string) (*UserNotificationPreferences, error) {
 	normalizedEmail := strings.ToLower(email)
 	var user models.User
@@ -356,31 +986,17 @@ func (r *UserRepository) GetNotificationPreferences(email string) (*UserNotifica
 		return nil, err
 	}
 
-	// Default preferences if none are set
-	if user.NotificationPreferences == "" {
-		return &UserNotificationPreferences{
-			PushEnabled:   false,
-			EmailEnabled:  false,
-			ReminderTimes: []string{"20:00"},
-			CutoffTime:    "10:00",
-		}, nil
-	}
-
-	var preferences UserNotificationPreferences
-	if err := json.Unmarshal([]byte(user.NotificationPreferences), &preferences); err != nil {
-		return nil, err
-	}
-
-	return &preferences, nil
+	return r.preferencesOf(&user)
 }
 
-// SearchUsers searches for users by email or name
-func (r *UserRepository) SearchUsers(query string, skip, limit int) (*[]models.User, int64, error) {
+// SearchUsers searches for users by email or name, limited to an organization
+// unless orgID is empty
+func (r *UserRepository) SearchUsers(orgID, query string, skip, limit int) (*[]models.User, int64, error) {
 	var users []models.User
 	var total int64
 
 	// Start with the base model query
-	queryBuilder := r.db.Model(&models.User{}) // Use a separate variable for the query builder
+	queryBuilder := r.db.Model(&models.User{}).Scopes(OrgScope(orgID)) // Use a separate variable for the query builder
 
 	// Apply the search filter if a query is provided
 	if query != "" {
@@ -410,6 +1026,42 @@ func (r *UserRepository) SearchUsers(query string, skip, limit int) (*[]models.U
 	return &users, total, nil
 }
 
+// ReminderTargets selects the users an admin reminder goes to. Empty fields
+// don't narrow the selection.
+type ReminderTargets struct {
+	Emails       []string // Only these users
+	StudyID      string
+	InactiveOnly bool // Only users flagged by the inactivity policy
+}
+
+// GetReminderTargets returns the emails of an organization's users that a
+// reminder selects, or every organization's when orgID is empty. Admins and
+// paused, withdrawn and anonymized users are never reminded.
+func (r *UserRepository) GetReminderTargets(orgID string, targets ReminderTargets) ([]string, error) {
+	query := r.db.Model(&models.User{}).Scopes(OrgScope(orgID)).
+		Where("is_admin = ? AND anonymized_at IS NULL AND deactivated_at IS NULL AND withdrawn_at IS NULL", false)
+	if len(targets.Emails) > 0 {
+		emails := make([]string, len(targets.Emails))
+		for i, email := range targets.Emails {
+			emails[i] = strings.ToLower(email)
+		}
+		query = query.Where("LOWER(email) IN ?", emails)
+	}
+	if targets.StudyID != "" {
+		query = query.Where("study_id = ?", targets.StudyID)
+	}
+	if targets.InactiveOnly {
+		query = query.Where("inactive_since IS NOT NULL")
+	}
+
+	var emails []string
+	if err := query.Order("email").Pluck("email", &emails).Error; err != nil {
+		r.log.Errorw("Database error selecting reminder targets", "error", err)
+		return nil, err
+	}
+	return emails, nil
+}
+
 // Helper method for validation
 func (r *UserRepository) validateUser(user *models.User) error {
 	if user.Email == "" {
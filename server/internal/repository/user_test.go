@@ -0,0 +1,308 @@
+package repository
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/andevellicus/crapp/internal/config"
+	"github.com/andevellicus/crapp/internal/models"
+	"github.com/andevellicus/crapp/internal/testutil"
+	"github.com/andevellicus/crapp/internal/utils"
+	"go.uber.org/zap"
+	"gorm.io/gorm/schema"
+)
+
+const erasedEmail = "participant@example.com"
+
+// fakeFiles records the keys deleted from storage
+type fakeFiles struct {
+	deleted []string
+}
+
+func (f *fakeFiles) Delete(_ context.Context, key string) error {
+	f.deleted = append(f.deleted, key)
+	return nil
+}
+
+// newRecordedUsers returns a user repository on a recorded fake database in
+// which the erased user has one photo attached to an assessment
+func newRecordedUsers(t *testing.T, users ...models.User) (*UserRepository, *testutil.Recorder) {
+	t.Helper()
+	recorder := testutil.NewRecorder(users...)
+	recorder.Rows("assessment_attachments",
+		[]string{"id", "assessment_id", "user_email", "storage_key", "thumbnail_key"},
+		[]driver.Value{int64(1), int64(7), erasedEmail, "attachments/photo.jpg", "attachments/photo-thumb.jpg"})
+	days, _ := utils.NewAssessmentDay("UTC", "")
+	return NewUserRepository(testutil.OpenGorm(t, recorder.Respond), zap.NewNop().Sugar(), &config.Config{}, days), recorder
+}
+
+// attachmentFiles are the storage keys of the photo in newRecordedUsers
+var attachmentFiles = []string{"attachments/photo.jpg", "attachments/photo-thumb.jpg"}
+
+// statementsOn returns the recorded statements of a kind, such as DELETE or
+// UPDATE, on a table
+func statementsOn(recorder *testutil.Recorder, verb, table string) []testutil.Statement {
+	var found []testutil.Statement
+	for _, s := range recorder.Statements() {
+		if !strings.HasPrefix(s.Query, verb) {
+			continue
+		}
+		if strings.Contains(s.Query, fmt.Sprintf(`"%s"`, table)) {
+			found = append(found, s)
+		}
+	}
+	return found
+}
+
+// hasArg reports whether a statement was sent a value
+func hasArg(s testutil.Statement, value any) bool {
+	for _, arg := range s.Args {
+		if arg == value {
+			return true
+		}
+	}
+	return false
+}
+
+func TestDeleteRemovesPersonalRows(t *testing.T) {
+	users, recorder := newRecordedUsers(t, models.User{Email: erasedEmail})
+	files := &fakeFiles{}
+	users.files = files
+	if err := users.Delete(erasedEmail); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if !slices.Equal(files.deleted, attachmentFiles) {
+		t.Errorf("Delete removed files %v, want %v", files.deleted, attachmentFiles)
+	}
+
+	for _, table := range []string{
+		"refresh_tokens", "revoked_tokens", "password_reset_tokens", "login_challenges",
+		"reactivation_tokens", "caregiver_links", "policy_acceptances", "audit_events", "chart_views", "achievements",
+		"notification_events", "reminders_sent", "reminder_deliveries", "reminder_overrides",
+		"study_withdrawals", "impersonation_sessions", "clinical_events", "red_flag_alerts",
+		"symptom_flags", "assessment_attachments", "devices", "users",
+	} {
+		deletes := statementsOn(recorder, "DELETE", table)
+		if len(deletes) == 0 {
+			t.Errorf("Delete left %s in place", table)
+			continue
+		}
+		if !hasArg(deletes[0], erasedEmail) {
+			t.Errorf("Delete of %s was not keyed on the user: %s %v", table, deletes[0].Query, deletes[0].Args)
+		}
+	}
+}
+
+// TestDeleteDetachesArmAllocations checks allocations stay, since the
+// sequence within a stratum is counted from them, but lose the participant
+func TestDeleteDetachesArmAllocations(t *testing.T) {
+	users, recorder := newRecordedUsers(t, models.User{Email: erasedEmail})
+	if err := users.Delete(erasedEmail); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+
+	if deletes := statementsOn(recorder, "DELETE", "arm_allocations"); len(deletes) > 0 {
+		t.Errorf("Delete removed arm allocations: %s", deletes[0].Query)
+	}
+	updates := statementsOn(recorder, "UPDATE", "arm_allocations")
+	if len(updates) != 1 {
+		t.Fatalf("Delete sent %d allocation updates, want 1", len(updates))
+	}
+	update := updates[0]
+	for _, column := range []string{"user_email", "age", "sex"} {
+		if !strings.Contains(update.Query, fmt.Sprintf(`"%s"=`, column)) {
+			t.Errorf("allocation update leaves %s: %s", column, update.Query)
+		}
+	}
+	for _, arg := range update.Args {
+		if s, ok := arg.(string); ok && strings.HasPrefix(s, "deleted-") {
+			return
+		}
+	}
+	t.Errorf("allocation update does not replace the email: %v", update.Args)
+}
+
+// TestDeleteDetachesKioskSessions checks kiosk sessions keep the clinician
+// who ran them but not the patient
+func TestDeleteDetachesKioskSessions(t *testing.T) {
+	users, recorder := newRecordedUsers(t, models.User{Email: erasedEmail})
+	if err := users.Delete(erasedEmail); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+
+	if deletes := statementsOn(recorder, "DELETE", "kiosk_sessions"); len(deletes) > 0 {
+		t.Errorf("Delete removed kiosk sessions: %s", deletes[0].Query)
+	}
+	updates := statementsOn(recorder, "UPDATE", "kiosk_sessions")
+	if len(updates) != 1 {
+		t.Fatalf("Delete sent %d kiosk session updates, want 1", len(updates))
+	}
+	update := updates[0]
+	if !strings.Contains(update.Query, `"patient_email"=`) || strings.Contains(update.Query, `"clinician_email"=`) {
+		t.Errorf("kiosk session update should only replace the patient: %s", update.Query)
+	}
+	if !hasArg(update, erasedEmail) {
+		t.Errorf("kiosk session update was not keyed on the user: %v", update.Args)
+	}
+	for _, arg := range update.Args {
+		if s, ok := arg.(string); ok && strings.HasPrefix(s, "deleted-") {
+			return
+		}
+	}
+	t.Errorf("kiosk session update does not replace the patient: %v", update.Args)
+}
+
+func TestDeleteRefusesLegalHold(t *testing.T) {
+	users, recorder := newRecordedUsers(t, models.User{Email: erasedEmail, LegalHold: true})
+	if err := users.Delete(erasedEmail); !errors.Is(err, ErrLegalHold) {
+		t.Fatalf("Delete = %v, want ErrLegalHold", err)
+	}
+	for _, s := range recorder.Statements() {
+		if strings.HasPrefix(s.Query, "DELETE") {
+			t.Errorf("Delete under legal hold sent %s", s.Query)
+		}
+	}
+}
+
+func TestDeleteResearchDataRemovesAttachments(t *testing.T) {
+	users, recorder := newRecordedUsers(t, models.User{Email: erasedEmail})
+	files := &fakeFiles{}
+	users.files = files
+	if err := users.DeleteResearchData(erasedEmail); err != nil {
+		t.Fatalf("DeleteResearchData: %v", err)
+	}
+	if len(statementsOn(recorder, "DELETE", "assessment_attachments")) == 0 {
+		t.Error("DeleteResearchData left the attachment records")
+	}
+	if !slices.Equal(files.deleted, attachmentFiles) {
+		t.Errorf("DeleteResearchData removed files %v, want %v", files.deleted, attachmentFiles)
+	}
+}
+
+func TestAnonymizeRemovesPersonalRows(t *testing.T) {
+	users, recorder := newRecordedUsers(t, models.User{Email: erasedEmail})
+	files := &fakeFiles{}
+	users.files = files
+	if _, err := users.Anonymize(erasedEmail); err != nil {
+		t.Fatalf("Anonymize: %v", err)
+	}
+	if !slices.Equal(files.deleted, attachmentFiles) {
+		t.Errorf("Anonymize removed files %v, want %v", files.deleted, attachmentFiles)
+	}
+
+	for _, table := range []string{
+		"refresh_tokens", "revoked_tokens", "password_reset_tokens", "caregiver_links",
+		"policy_acceptances", "audit_events", "reminders_sent", "reminder_deliveries",
+		"reminder_overrides", "impersonation_sessions", "assessment_attachments", "users",
+	} {
+		deletes := statementsOn(recorder, "DELETE", table)
+		if len(deletes) == 0 {
+			t.Errorf("Anonymize left %s in place", table)
+			continue
+		}
+		if !hasArg(deletes[0], erasedEmail) {
+			t.Errorf("Anonymize's delete of %s was not keyed on the user: %s %v", table, deletes[0].Query, deletes[0].Args)
+		}
+	}
+}
+
+func TestAnonymizeScrubsDevices(t *testing.T) {
+	users, recorder := newRecordedUsers(t, models.User{Email: erasedEmail})
+	pseudonym, err := users.Anonymize(erasedEmail)
+	if err != nil {
+		t.Fatalf("Anonymize: %v", err)
+	}
+
+	updates := statementsOn(recorder, "UPDATE", "devices")
+	if len(updates) != 1 {
+		t.Fatalf("Anonymize sent %d device updates, want 1", len(updates))
+	}
+	update := updates[0]
+	for _, column := range []string{"device_name", "browser", "os", "timezone"} {
+		if !strings.Contains(update.Query, fmt.Sprintf(`"%s"=`, column)) {
+			t.Errorf("device update leaves %s: %s", column, update.Query)
+		}
+	}
+	if !hasArg(update, pseudonym) || !hasArg(update, erasedEmail) {
+		t.Errorf("device update does not move devices to the pseudonym: %s %v", update.Query, update.Args)
+	}
+}
+
+func TestAnonymizeMovesResearchData(t *testing.T) {
+	users, recorder := newRecordedUsers(t, models.User{Email: erasedEmail})
+	pseudonym, err := users.Anonymize(erasedEmail)
+	if err != nil {
+		t.Fatalf("Anonymize: %v", err)
+	}
+
+	for _, table := range []string{
+		"assessments", "form_states", "cpt_results", "tmt_results", "digit_span_results",
+		"chart_summaries", "clinical_events", "arm_allocations", "study_withdrawals",
+		"red_flag_alerts", "symptom_flags",
+	} {
+		updates := statementsOn(recorder, "UPDATE", table)
+		if len(updates) == 0 {
+			t.Errorf("Anonymize left %s on the real email", table)
+			continue
+		}
+		if !hasArg(updates[0], pseudonym) || !hasArg(updates[0], erasedEmail) {
+			t.Errorf("update of %s does not move it to the pseudonym: %s %v", table, updates[0].Query, updates[0].Args)
+		}
+	}
+	if deletes := statementsOn(recorder, "DELETE", "clinical_events"); len(deletes) > 0 {
+		t.Errorf("Anonymize deleted clinical events that stay with the study")
+	}
+}
+
+// personalColumns hold the email of the person a row is about. Columns naming
+// staff, such as created_by or clinician_email, are not erased with a
+// participant.
+var personalColumns = []string{"email", "user_email", "patient_email", "caregiver_email", "target_email", "admin_email"}
+
+// TestErasureCoversSharedTables checks both erasure paths delete or rewrite
+// every shared table with a column holding the person's email, so a table
+// added later can't keep it by being forgotten
+func TestErasureCoversSharedTables(t *testing.T) {
+	erasures := []struct {
+		name  string
+		erase func(*UserRepository) error
+	}{
+		{"Delete", func(users *UserRepository) error { return users.Delete(erasedEmail) }},
+		{"Anonymize", func(users *UserRepository) error {
+			_, err := users.Anonymize(erasedEmail)
+			return err
+		}},
+	}
+
+	for _, erasure := range erasures {
+		t.Run(erasure.name, func(t *testing.T) {
+			users, recorder := newRecordedUsers(t, models.User{Email: erasedEmail})
+			if err := erasure.erase(users); err != nil {
+				t.Fatalf("%s: %v", erasure.name, err)
+			}
+
+			for _, model := range sharedModels {
+				parsed, err := schema.Parse(model, &sync.Map{}, schema.NamingStrategy{})
+				if err != nil {
+					t.Fatalf("parsing %T: %v", model, err)
+				}
+				if !slices.ContainsFunc(personalColumns, func(column string) bool {
+					return parsed.LookUpField(column) != nil
+				}) {
+					continue
+				}
+
+				statements := append(statementsOn(recorder, "DELETE", parsed.Table), statementsOn(recorder, "UPDATE", parsed.Table)...)
+				if !slices.ContainsFunc(statements, func(s testutil.Statement) bool { return hasArg(s, erasedEmail) }) {
+					t.Errorf("%s leaves the email on %s", erasure.name, parsed.Table)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/andevellicus/crapp/internal/models"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// StudyWithdrawalRepository handles participants withdrawing from studies
+type StudyWithdrawalRepository struct {
+	db  *gorm.DB
+	log *zap.SugaredLogger
+}
+
+// NewStudyWithdrawalRepository creates a new study withdrawal repository
+func NewStudyWithdrawalRepository(db *gorm.DB, log *zap.SugaredLogger) *StudyWithdrawalRepository {
+	return &StudyWithdrawalRepository{
+		db:  db,
+		log: log.Named("withdrawal-repo"),
+	}
+}
+
+// Withdraw records a withdrawal and marks the user as withdrawn
+func (r *StudyWithdrawalRepository) Withdraw(withdrawal *models.StudyWithdrawal) error {
+	withdrawal.UserEmail = strings.ToLower(withdrawal.UserEmail)
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.User{}).
+			Where("LOWER(email) = ?", withdrawal.UserEmail).
+			Update("withdrawn_at", withdrawal.WithdrawnAt).Error; err != nil {
+			return err
+		}
+		return tx.Create(withdrawal).Error
+	})
+	if err != nil {
+		r.log.Errorw("Database error recording withdrawal", "error", err, "email", withdrawal.UserEmail)
+		return fmt.Errorf("failed to record withdrawal: %w", err)
+	}
+	return nil
+}
+
+// GetLatest returns a user's most recent withdrawal, or nil if they have never withdrawn
+func (r *StudyWithdrawalRepository) GetLatest(email string) (*models.StudyWithdrawal, error) {
+	var withdrawal models.StudyWithdrawal
+	err := r.db.Where("user_email = ?", strings.ToLower(email)).
+		Order("withdrawn_at DESC").
+		First(&withdrawal).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &withdrawal, nil
+}
+
+// List returns an organization's withdrawals, newest first. An empty orgID
+// lists every organization's.
+func (r *StudyWithdrawalRepository) List(orgID string) ([]models.StudyWithdrawal, error) {
+	withdrawals := []models.StudyWithdrawal{}
+	if err := r.db.Scopes(OrgScope(orgID)).Order("withdrawn_at DESC").Find(&withdrawals).Error; err != nil {
+		r.log.Errorw("Database error listing withdrawals", "error", err, "org", orgID)
+		return nil, err
+	}
+	return withdrawals, nil
+}
@@ -0,0 +1,201 @@
+// internal/scheduler/admin_reminder.go
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/andevellicus/crapp/internal/models"
+	"github.com/andevellicus/crapp/internal/repository"
+	"github.com/andevellicus/crapp/internal/services"
+	"go.uber.org/zap"
+)
+
+// adminReminderStaleAfter is how long a running reminder job may go without
+// progress before it is scheduled again, such as after a restart
+const adminReminderStaleAfter = 15 * time.Minute
+
+// adminReminderProgressEvery is how many deliveries are sent between saves
+// of a job's counts
+const adminReminderProgressEvery = 25
+
+// AdminReminderScheduler sends admin-initiated reminder jobs once their send
+// time comes. Each delivery's outcome is saved as it is sent, so a job cut
+// off by a restart only sends what is still pending.
+type AdminReminderScheduler struct {
+	repo         *repository.Repository
+	log          *zap.SugaredLogger
+	emailService *services.EmailService
+	pushService  *services.PushService
+	reminders    *services.ReminderRenderer
+	wakeChan     chan struct{}
+	stopChan     chan struct{}
+}
+
+// NewAdminReminderScheduler creates a new admin reminder scheduler
+func NewAdminReminderScheduler(repo *repository.Repository, log *zap.SugaredLogger, emailService *services.EmailService, pushService *services.PushService, reminders *services.ReminderRenderer) *AdminReminderScheduler {
+	return &AdminReminderScheduler{
+		repo:         repo,
+		log:          log.Named("admin-reminders"),
+		emailService: emailService,
+		pushService:  pushService,
+		reminders:    reminders,
+		wakeChan:     make(chan struct{}, 1),
+		stopChan:     make(chan struct{}),
+	}
+}
+
+// Start begins sending due reminder jobs. Jobs are checked every minute and
+// whenever one is created to send now.
+func (s *AdminReminderScheduler) Start() {
+	go func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+
+		for {
+			if requeued, err := s.repo.ReminderJobs.RequeueStale(time.Now().Add(-adminReminderStaleAfter)); err == nil && requeued > 0 {
+				s.log.Infow("Requeued stalled reminder jobs", "count", requeued)
+			}
+			for !s.stopping() {
+				job, err := s.repo.ReminderJobs.ClaimDue(time.Now())
+				if err != nil || job == nil {
+					break
+				}
+				s.run(job)
+			}
+
+			select {
+			case <-s.wakeChan:
+			case <-ticker.C:
+			case <-s.stopChan:
+				return
+			}
+		}
+	}()
+
+	s.log.Info("Admin reminder scheduler started")
+}
+
+// Stop stops the admin reminder scheduler. A job in progress stops after its
+// current delivery and sends the rest once it is picked up again.
+func (s *AdminReminderScheduler) Stop() {
+	close(s.stopChan)
+	s.log.Info("Admin reminder scheduler stopped")
+}
+
+// Wake tells the scheduler that a job may be due
+func (s *AdminReminderScheduler) Wake() {
+	select {
+	case s.wakeChan <- struct{}{}:
+	default:
+	}
+}
+
+func (s *AdminReminderScheduler) stopping() bool {
+	select {
+	case <-s.stopChan:
+		return true
+	default:
+		return false
+	}
+}
+
+// run sends a job's pending deliveries and then marks it done
+func (s *AdminReminderScheduler) run(job *models.ReminderJob) {
+	deliveries, err := s.repo.ReminderJobs.ListDeliveries(job.ID, models.ReminderDeliveryPending)
+	if err != nil {
+		s.log.Errorw("Failed to load reminder deliveries", "id", job.ID, "error", err)
+		return
+	}
+	s.log.Infow("Sending reminder job", "id", job.ID, "pending", len(deliveries))
+
+	for i := range deliveries {
+		if s.stopping() {
+			return
+		}
+
+		delivery := &deliveries[i]
+		status, sendErr := s.deliver(job, delivery)
+		delivery.Status = status
+		switch status {
+		case models.ReminderDeliverySent:
+			now := time.Now()
+			delivery.SentAt = &now
+			job.Sent++
+		case models.ReminderDeliveryFailed:
+			delivery.Error = sendErr.Error()
+			job.Failed++
+			s.log.Warnw("Failed to send admin reminder", "id", job.ID, "email", delivery.UserEmail,
+				"channel", delivery.Channel, "error", sendErr)
+		default:
+			job.Skipped++
+		}
+		if err := s.repo.ReminderJobs.UpdateDelivery(delivery); err != nil {
+			return
+		}
+
+		if (i+1)%adminReminderProgressEvery == 0 {
+			s.repo.ReminderJobs.UpdateProgress(job)
+		}
+	}
+
+	now := time.Now()
+	job.Status = models.ReminderJobDone
+	job.CompletedAt = &now
+	if err := s.repo.ReminderJobs.UpdateProgress(job); err != nil {
+		return
+	}
+	s.log.Infow("Reminder job done", "id", job.ID, "sent", job.Sent, "failed", job.Failed, "skipped", job.Skipped)
+}
+
+// deliver sends one delivery and returns its status, with the error when it
+// failed
+func (s *AdminReminderScheduler) deliver(job *models.ReminderJob, delivery *models.ReminderDelivery) (string, error) {
+	user, err := s.repo.Users.GetByEmail(delivery.UserEmail)
+	if err != nil || user == nil {
+		return models.ReminderDeliveryFailed, errors.New("user not found")
+	}
+	// Users may have paused or withdrawn since the job was created
+	if user.AnonymizedAt != nil || user.DeactivatedAt != nil || user.WithdrawnAt != nil {
+		return models.ReminderDeliverySkipped, nil
+	}
+
+	if job.SkipCompleted {
+		completed, err := s.repo.Users.HasCompletedAssessment(user.Email)
+		if err != nil {
+			return models.ReminderDeliveryFailed, fmt.Errorf("checking today's assessment: %w", err)
+		}
+		if completed {
+			return models.ReminderDeliverySkipped, nil
+		}
+	}
+
+	switch delivery.Channel {
+	case "email":
+		if s.emailService == nil {
+			return models.ReminderDeliveryFailed, errors.New("email service not available")
+		}
+		content, err := s.reminders.Render(user.Email, models.NotificationChannelEmail)
+		if err != nil {
+			return models.ReminderDeliveryFailed, err
+		}
+		if err := s.emailService.SendReminderEmail(user.Email, content); err != nil {
+			return models.ReminderDeliveryFailed, err
+		}
+	case "push":
+		if s.pushService == nil {
+			return models.ReminderDeliveryFailed, errors.New("push notification service not available")
+		}
+		prefs, err := s.repo.Users.GetNotificationPreferences(user.Email)
+		if err != nil || prefs == nil || !prefs.Channels[models.NotificationChannelPush].Enabled {
+			return models.ReminderDeliverySkipped, nil
+		}
+		if err := s.pushService.SendReminderNotification(user.Email); err != nil {
+			return models.ReminderDeliveryFailed, err
+		}
+	default:
+		return models.ReminderDeliveryFailed, fmt.Errorf("unknown channel %q", delivery.Channel)
+	}
+	return models.ReminderDeliverySent, nil
+}
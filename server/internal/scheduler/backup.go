@@ -0,0 +1,77 @@
+// internal/scheduler/backup.go
+package scheduler
+
+import (
+	"context"
+	"time"
+
+	"github.com/andevellicus/crapp/internal/services"
+	"go.uber.org/zap"
+)
+
+// backupCheckInterval is how often the scheduler checks whether a backup is due
+const backupCheckInterval = 15 * time.Minute
+
+// BackupScheduler runs a backup whenever the newest one is older than the
+// configured interval, so restarts don't delay or duplicate backups
+type BackupScheduler struct {
+	backups  *services.BackupService
+	log      *zap.SugaredLogger
+	interval time.Duration
+	stopChan chan struct{}
+}
+
+// NewBackupScheduler creates a new backup scheduler
+func NewBackupScheduler(backups *services.BackupService, log *zap.SugaredLogger, intervalHours int) *BackupScheduler {
+	return &BackupScheduler{
+		backups:  backups,
+		log:      log.Named("backup-scheduler"),
+		interval: time.Duration(intervalHours) * time.Hour,
+		stopChan: make(chan struct{}),
+	}
+}
+
+// Start begins the backup scheduler
+func (s *BackupScheduler) Start() {
+	go func() {
+		ticker := time.NewTicker(backupCheckInterval)
+		defer ticker.Stop()
+
+		s.run()
+
+		for {
+			select {
+			case <-ticker.C:
+				s.run()
+			case <-s.stopChan:
+				return
+			}
+		}
+	}()
+
+	s.log.Infow("Backup scheduler started", "interval", s.interval)
+}
+
+// Stop stops the backup scheduler
+func (s *BackupScheduler) Stop() {
+	close(s.stopChan)
+	s.log.Info("Backup scheduler stopped")
+}
+
+// run creates a backup if the newest one is due for replacement
+func (s *BackupScheduler) run() {
+	ctx := context.Background()
+
+	manifests, err := s.backups.List(ctx)
+	if err != nil {
+		s.log.Errorw("Failed to list backups", "error", err)
+		return
+	}
+	if len(manifests) > 0 && time.Since(manifests[0].CreatedAt) < s.interval {
+		return
+	}
+
+	if _, err := s.backups.Run(ctx, services.BackupScheduled); err != nil {
+		s.log.Errorw("Scheduled backup failed", "error", err)
+	}
+}
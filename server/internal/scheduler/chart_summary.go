@@ -0,0 +1,83 @@
+// internal/scheduler/chart_summary.go
+package scheduler
+
+import (
+	"time"
+
+	"github.com/andevellicus/crapp/internal/repository"
+	"go.uber.org/zap"
+)
+
+// chartSummaryBatch is how many assessments are summarized per query
+const chartSummaryBatch = 500
+
+// ChartSummaryScheduler summarizes assessments the post-submit refresh missed,
+// including those submitted before summaries existed
+type ChartSummaryScheduler struct {
+	repo     *repository.Repository
+	log      *zap.SugaredLogger
+	interval time.Duration
+	stopChan chan struct{}
+}
+
+// NewChartSummaryScheduler creates a new chart summary scheduler
+func NewChartSummaryScheduler(repo *repository.Repository, log *zap.SugaredLogger) *ChartSummaryScheduler {
+	return &ChartSummaryScheduler{
+		repo:     repo,
+		log:      log.Named("chart-summary"),
+		interval: 24 * time.Hour,
+		stopChan: make(chan struct{}),
+	}
+}
+
+// Start begins the chart summary scheduler
+func (s *ChartSummaryScheduler) Start() {
+	go func() {
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		// Run immediately on start
+		s.run()
+
+		for {
+			select {
+			case <-ticker.C:
+				s.run()
+			case <-s.stopChan:
+				return
+			}
+		}
+	}()
+
+	s.log.Info("Chart summary scheduler started")
+}
+
+// Stop stops the chart summary scheduler
+func (s *ChartSummaryScheduler) Stop() {
+	close(s.stopChan)
+	s.log.Info("Chart summary scheduler stopped")
+}
+
+// run summarizes pending assessments in the shared and every tenant schema
+func (s *ChartSummaryScheduler) run() {
+	s.log.Debug("Running chart summary task")
+
+	total := 0
+	for _, repo := range s.repo.DataRepositories() {
+		for {
+			count, err := repo.ChartSummaries.RefreshPending(chartSummaryBatch)
+			if err != nil {
+				s.log.Errorw("Failed to refresh chart summaries", "error", err)
+				break
+			}
+			total += count
+			if count < chartSummaryBatch {
+				break
+			}
+		}
+	}
+
+	if total > 0 {
+		s.log.Infow("Refreshed chart summaries", "assessments", total)
+	}
+}
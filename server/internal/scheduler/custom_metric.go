@@ -0,0 +1,120 @@
+// internal/scheduler/custom_metric.go
+package scheduler
+
+import (
+	"time"
+
+	"github.com/andevellicus/crapp/internal/metrics"
+	"github.com/andevellicus/crapp/internal/repository"
+	"go.uber.org/zap"
+)
+
+// customMetricBatch is how many assessments are recomputed per transaction
+const customMetricBatch = 500
+
+// CustomMetricScheduler rebuilds stored custom metric values after an admin
+// adds or changes a formula, and keeps the metric registry in step with the
+// database
+type CustomMetricScheduler struct {
+	repo        *repository.Repository
+	log         *zap.SugaredLogger
+	interval    time.Duration
+	triggerChan chan struct{}
+	stopChan    chan struct{}
+}
+
+// NewCustomMetricScheduler creates a new custom metric scheduler
+func NewCustomMetricScheduler(repo *repository.Repository, log *zap.SugaredLogger) *CustomMetricScheduler {
+	return &CustomMetricScheduler{
+		repo:        repo,
+		log:         log.Named("custom-metrics"),
+		interval:    time.Hour,
+		triggerChan: make(chan struct{}, 1),
+		stopChan:    make(chan struct{}),
+	}
+}
+
+// Start begins the custom metric scheduler
+func (s *CustomMetricScheduler) Start() {
+	go func() {
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		// Run immediately on start
+		s.run()
+
+		for {
+			select {
+			case <-ticker.C:
+				s.run()
+			case <-s.triggerChan:
+				s.run()
+			case <-s.stopChan:
+				return
+			}
+		}
+	}()
+
+	s.log.Info("Custom metric scheduler started")
+}
+
+// Stop stops the custom metric scheduler
+func (s *CustomMetricScheduler) Stop() {
+	close(s.stopChan)
+	s.log.Info("Custom metric scheduler stopped")
+}
+
+// Trigger asks for a recompute without waiting for the next tick. Requests
+// made while one is already queued are merged.
+func (s *CustomMetricScheduler) Trigger() {
+	select {
+	case s.triggerChan <- struct{}{}:
+	default:
+	}
+}
+
+// run reloads the registry and, when a formula changed since the last run,
+// recomputes every assessment in the shared and every tenant schema
+func (s *CustomMetricScheduler) run() {
+	s.log.Debug("Running custom metric task")
+
+	defs, err := s.repo.CustomMetrics.Definitions()
+	if err != nil {
+		s.log.Errorw("Failed to load custom metrics", "error", err)
+		return
+	}
+	metrics.SetCustom(defs)
+
+	pending, err := s.repo.CustomMetrics.Pending()
+	if err != nil {
+		s.log.Errorw("Failed to check for pending custom metrics", "error", err)
+		return
+	}
+	if !pending {
+		return
+	}
+
+	// Formulas changed after this point are picked up by the next run
+	started := time.Now()
+	formulas, err := s.repo.CustomMetrics.Formulas()
+	if err != nil {
+		s.log.Errorw("Failed to load custom metric formulas", "error", err)
+		return
+	}
+
+	total := 0
+	for _, repo := range s.repo.DataRepositories() {
+		count, err := repo.RecomputeCustomMetrics(formulas, customMetricBatch)
+		total += count
+		if err != nil {
+			s.log.Errorw("Failed to recompute custom metrics", "error", err)
+			return
+		}
+	}
+
+	if err := s.repo.CustomMetrics.MarkRecomputed(started); err != nil {
+		s.log.Errorw("Failed to mark custom metrics recomputed", "error", err)
+		return
+	}
+	s.log.Infow("Recomputed custom metrics", "metrics", len(formulas), "assessments", total)
+}
@@ -0,0 +1,66 @@
+// internal/scheduler/demo.go
+package scheduler
+
+import (
+	"time"
+
+	"github.com/andevellicus/crapp/internal/repository"
+	"github.com/andevellicus/crapp/internal/services"
+	"go.uber.org/zap"
+)
+
+// DemoScheduler purges the public demo's sandbox accounts when each
+// assessment day starts
+type DemoScheduler struct {
+	repo     *repository.Repository
+	log      *zap.SugaredLogger
+	demo     *services.DemoService
+	stopChan chan struct{}
+}
+
+// NewDemoScheduler creates a new demo purge scheduler
+func NewDemoScheduler(repo *repository.Repository, log *zap.SugaredLogger, demo *services.DemoService) *DemoScheduler {
+	return &DemoScheduler{
+		repo:     repo,
+		log:      log.Named("demo"),
+		demo:     demo,
+		stopChan: make(chan struct{}),
+	}
+}
+
+// Start begins the demo purge scheduler. Nothing is purged on start, so a
+// restart doesn't cut short a visitor's trial.
+func (s *DemoScheduler) Start() {
+	go func() {
+		days := s.repo.AssessmentDay()
+		for {
+			next := days.Start(days.AddDays(days.Today(), 1))
+			timer := time.NewTimer(time.Until(next))
+			select {
+			case <-timer.C:
+				s.run()
+			case <-s.stopChan:
+				timer.Stop()
+				return
+			}
+		}
+	}()
+
+	s.log.Info("Demo purge scheduler started")
+}
+
+// Stop stops the demo purge scheduler
+func (s *DemoScheduler) Stop() {
+	close(s.stopChan)
+	s.log.Info("Demo purge scheduler stopped")
+}
+
+// run deletes every sandbox account
+func (s *DemoScheduler) run() {
+	purged, err := s.demo.Purge()
+	if err != nil {
+		s.log.Errorw("Failed to purge sandbox accounts", "error", err)
+		return
+	}
+	s.log.Infow("Purged sandbox accounts", "users", purged)
+}
@@ -0,0 +1,207 @@
+// internal/scheduler/lifecycle.go
+package scheduler
+
+import (
+	"time"
+
+	"github.com/andevellicus/crapp/internal/config"
+	"github.com/andevellicus/crapp/internal/models"
+	"github.com/andevellicus/crapp/internal/repository"
+	"github.com/andevellicus/crapp/internal/services"
+	"go.uber.org/zap"
+)
+
+// LifecycleScheduler periodically applies the account inactivity policy
+type LifecycleScheduler struct {
+	repo         *repository.Repository
+	log          *zap.SugaredLogger
+	config       *config.LifecycleConfig
+	emailService *services.EmailService
+	interval     time.Duration
+	stopChan     chan struct{}
+}
+
+// NewLifecycleScheduler creates a new inactivity lifecycle scheduler
+func NewLifecycleScheduler(repo *repository.Repository,
+	log *zap.SugaredLogger,
+	cfg *config.LifecycleConfig,
+	emailService *services.EmailService) *LifecycleScheduler {
+
+	interval := time.Duration(cfg.IntervalHours) * time.Hour
+	if interval <= 0 {
+		interval = 24 * time.Hour
+	}
+
+	return &LifecycleScheduler{
+		repo:         repo,
+		log:          log.Named("lifecycle"),
+		config:       cfg,
+		emailService: emailService,
+		interval:     interval,
+		stopChan:     make(chan struct{}),
+	}
+}
+
+// Start begins the lifecycle scheduler
+func (s *LifecycleScheduler) Start() {
+	go func() {
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		// Run immediately on start
+		s.run()
+
+		for {
+			select {
+			case <-ticker.C:
+				s.run()
+			case <-s.stopChan:
+				return
+			}
+		}
+	}()
+
+	s.log.Info("Lifecycle scheduler started")
+}
+
+// Stop stops the lifecycle scheduler
+func (s *LifecycleScheduler) Stop() {
+	close(s.stopChan)
+	s.log.Info("Lifecycle scheduler stopped")
+}
+
+// run applies users' own retention periods, then evaluates every eligible
+// user against their study's policy
+func (s *LifecycleScheduler) run() {
+	s.log.Debug("Running inactivity lifecycle task")
+	now := time.Now()
+
+	overrides, err := s.repo.Users.GetRetentionOverrides()
+	if err != nil {
+		s.log.Errorw("Failed to load retention overrides", "error", err)
+	}
+	for i := range overrides {
+		user := &overrides[i]
+		daysInactive := int(now.Sub(lastActivity(user)).Hours() / 24)
+		if daysInactive >= user.RetentionDays {
+			s.retain(user, user.RetentionAction, daysInactive)
+		}
+	}
+
+	users, err := s.repo.Users.GetLifecycleCandidates()
+	if err != nil {
+		s.log.Errorw("Failed to load users for lifecycle task", "error", err)
+		return
+	}
+
+	for i := range users {
+		s.apply(&users[i], now)
+	}
+
+	s.log.Debugw("Inactivity lifecycle task completed", "users", len(users))
+}
+
+// apply moves a single user through the inactivity stages. Each stage has
+// its own threshold, so disabling one leaves the others running.
+func (s *LifecycleScheduler) apply(user *models.User, now time.Time) {
+	policy := s.config.ForStudy(user.StudyID)
+	lastActive := lastActivity(user)
+	daysInactive := int(now.Sub(lastActive).Hours() / 24)
+
+	// Retention period reached: anonymize or purge and stop there. Users
+	// under a legal hold carry on through the other stages.
+	if policy.RetentionDays > 0 && daysInactive >= policy.RetentionDays && !user.LegalHold {
+		if s.retain(user, policy.RetentionAction, daysInactive) {
+			return
+		}
+	}
+
+	if policy.PushDisableDays > 0 && daysInactive >= policy.PushDisableDays && user.PushSubscription != "" {
+		s.disablePush(user.Email)
+	}
+
+	// Users who came back are no longer inactive
+	if policy.InactiveDays <= 0 || daysInactive < policy.InactiveDays {
+		if user.InactiveSince != nil {
+			if err := s.repo.Users.SetInactive(user.Email, nil); err != nil {
+				s.log.Warnw("Failed to clear inactivity flag", "email", user.Email, "error", err)
+			}
+		}
+		return
+	}
+
+	if user.InactiveSince == nil {
+		since := lastActive.AddDate(0, 0, policy.InactiveDays)
+		if err := s.repo.Users.SetInactive(user.Email, &since); err != nil {
+			s.log.Warnw("Failed to flag inactive user", "email", user.Email, "error", err)
+		}
+	}
+
+	// Re-engagement email goes out once per lapse
+	if user.ReengagementSentAt == nil && s.emailService != nil {
+		if err := s.emailService.SendReengagementEmail(user.Email, user.FirstName, daysInactive); err != nil {
+			s.log.Warnw("Failed to send re-engagement email", "email", user.Email, "error", err)
+		} else if err := s.repo.Users.ReengagementSentNow(user.Email); err != nil {
+			s.log.Warnw("Failed to record re-engagement email", "email", user.Email, "error", err)
+		}
+	}
+}
+
+// retain applies a retention action to a user, reporting whether there was
+// one to apply
+func (s *LifecycleScheduler) retain(user *models.User, action string, daysInactive int) bool {
+	switch action {
+	case config.RetentionAnonymize:
+		if _, err := s.repo.ForUser(user.Email).Users.Anonymize(user.Email); err != nil {
+			s.log.Errorw("Failed to anonymize inactive user", "email", user.Email, "error", err)
+		} else {
+			s.log.Infow("Anonymized inactive user", "days_inactive", daysInactive)
+		}
+		return true
+	case config.RetentionPurge:
+		if err := s.repo.ForUser(user.Email).Users.Delete(user.Email); err != nil {
+			s.log.Errorw("Failed to purge inactive user", "email", user.Email, "error", err)
+		} else {
+			s.log.Infow("Purged inactive user", "days_inactive", daysInactive)
+		}
+		return true
+	}
+	return false
+}
+
+// disablePush removes a user's push subscription and turns push reminders off
+func (s *LifecycleScheduler) disablePush(email string) {
+	if err := s.repo.Users.SavePushSubscription(email, ""); err != nil {
+		s.log.Warnw("Failed to remove push subscription", "email", email, "error", err)
+		return
+	}
+
+	prefs, err := s.repo.Users.GetNotificationPreferences(email)
+	if err != nil {
+		s.log.Warnw("Failed to load notification preferences", "email", email, "error", err)
+		return
+	}
+	prefs.SetChannelEnabled(models.NotificationChannelPush, false)
+	if err := s.repo.Users.SaveNotificationPreferences(email, prefs); err != nil {
+		s.log.Warnw("Failed to disable push preference", "email", email, "error", err)
+		return
+	}
+
+	s.log.Infow("Disabled push notifications for inactive user", "email", email)
+}
+
+// lastActivity returns the most recent login, assessment, or signup time
+func lastActivity(user *models.User) time.Time {
+	last := user.CreatedAt
+	if user.LastLogin.After(last) {
+		last = user.LastLogin
+	}
+	if user.LastAssessmentDate.After(last) {
+		last = user.LastAssessmentDate
+	}
+	// A pause doesn't count as inactivity
+	if user.ReactivatedAt != nil && user.ReactivatedAt.After(last) {
+		last = *user.ReactivatedAt
+	}
+	return last
+}
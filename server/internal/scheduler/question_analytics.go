@@ -0,0 +1,92 @@
+// internal/scheduler/question_analytics.go
+package scheduler
+
+import (
+	"time"
+
+	"github.com/andevellicus/crapp/internal/repository"
+	"go.uber.org/zap"
+)
+
+const (
+	// Recent days are re-aggregated because forms left open may still be
+	// finished or abandoned after the day ends
+	questionAnalyticsDays = 7
+	// Forms idle this long count as abandoned
+	questionAbandonAfter = 24 * time.Hour
+	// Raw navigation events are kept this long after aggregation
+	questionEventRetention = 90 * 24 * time.Hour
+)
+
+// QuestionAnalyticsScheduler aggregates form navigation into per-question
+// analytics every night
+type QuestionAnalyticsScheduler struct {
+	repo     *repository.Repository
+	log      *zap.SugaredLogger
+	interval time.Duration
+	stopChan chan struct{}
+}
+
+// NewQuestionAnalyticsScheduler creates a new question analytics scheduler
+func NewQuestionAnalyticsScheduler(repo *repository.Repository, log *zap.SugaredLogger) *QuestionAnalyticsScheduler {
+	return &QuestionAnalyticsScheduler{
+		repo:     repo,
+		log:      log.Named("question-analytics"),
+		interval: 24 * time.Hour,
+		stopChan: make(chan struct{}),
+	}
+}
+
+// Start begins the question analytics scheduler
+func (s *QuestionAnalyticsScheduler) Start() {
+	go func() {
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		// Run immediately on start
+		s.run()
+
+		for {
+			select {
+			case <-ticker.C:
+				s.run()
+			case <-s.stopChan:
+				return
+			}
+		}
+	}()
+
+	s.log.Info("Question analytics scheduler started")
+}
+
+// Stop stops the question analytics scheduler
+func (s *QuestionAnalyticsScheduler) Stop() {
+	close(s.stopChan)
+	s.log.Info("Question analytics scheduler stopped")
+}
+
+// run aggregates the recent assessment days, including today so far
+func (s *QuestionAnalyticsScheduler) run() {
+	s.log.Debug("Running question analytics task")
+
+	days := s.repo.AssessmentDay()
+	today := days.Today()
+	for i := questionAnalyticsDays; i >= 0; i-- {
+		day := days.AddDays(today, -i)
+		start := days.Start(day)
+		end := days.Start(days.AddDays(day, 1))
+
+		count, err := s.repo.QuestionAnalytics.Aggregate(day, start, end, questionAbandonAfter)
+		if err != nil {
+			s.log.Errorw("Failed to aggregate question analytics", "error", err, "day", day.Format("2006-01-02"))
+			continue
+		}
+		s.log.Debugw("Aggregated question analytics", "day", day.Format("2006-01-02"), "questions", count)
+	}
+
+	if purged, err := s.repo.QuestionAnalytics.PurgeEventsBefore(time.Now().Add(-questionEventRetention)); err != nil {
+		s.log.Errorw("Failed to purge question events", "error", err)
+	} else if purged > 0 {
+		s.log.Infow("Purged old question events", "count", purged)
+	}
+}
@@ -3,6 +3,7 @@ package scheduler
 
 import (
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
@@ -10,17 +11,26 @@ import (
 	"github.com/andevellicus/crapp/internal/models"
 	"github.com/andevellicus/crapp/internal/repository"
 	"github.com/andevellicus/crapp/internal/services"
+	"github.com/andevellicus/crapp/internal/utils"
 	"go.uber.org/zap"
 )
 
+// reminderLedgerDays is how long sent reminders are kept in the ledger
+const reminderLedgerDays = 7
+
+// notificationEventDays is how long reminder open and click counts are kept
+const notificationEventDays = 365
+
 // ReminderScheduler handles scheduling of reminders
 type ReminderScheduler struct {
 	pushService  *services.PushService
 	emailService *services.EmailService
+	reminders    *services.ReminderRenderer
 	config       *config.Config
 	repo         *repository.Repository
 	log          *zap.SugaredLogger
 	jobs         map[string]*time.Timer
+	snoozes      map[string]*time.Timer // One-off reminders keyed by user email
 	mutex        sync.Mutex
 }
 
@@ -29,52 +39,61 @@ func NewReminderScheduler(repo *repository.Repository,
 	log *zap.SugaredLogger,
 	config *config.Config,
 	pushService *services.PushService,
-	emailService *services.EmailService) *ReminderScheduler {
+	emailService *services.EmailService,
+	reminders *services.ReminderRenderer) *ReminderScheduler {
 
 	return &ReminderScheduler{
 		pushService:  pushService,
 		emailService: emailService,
+		reminders:    reminders,
 		repo:         repo,
 		log:          log.Named("sched"),
 		config:       config,
 		jobs:         make(map[string]*time.Timer),
+		snoozes:      make(map[string]*time.Timer),
 		mutex:        sync.Mutex{},
 	}
 }
 
 // Start initializes and starts the scheduler
 func (s *ReminderScheduler) Start() error {
-	// Get all unique user-defined reminder times
-	userTimes, err := s.repo.GetAllUniqueReminderTimes()
-	if err != nil {
-		s.log.Errorw("Error getting user reminder times", "error", err)
-		// Fall back to config times if there's an error
-		userTimes = s.config.Reminders.Times
+	// Only recent ledger entries can still prevent a duplicate
+	days := s.repo.AssessmentDay()
+	cutoff := days.AddDays(days.Today(), -reminderLedgerDays)
+	if purged, err := s.repo.Reminders.PurgeBefore(cutoff); err != nil {
+		s.log.Warnw("Failed to purge reminder ledger", "error", err)
+	} else if purged > 0 {
+		s.log.Debugw("Purged reminder ledger", "entries", purged)
+	}
+	if purged, err := s.repo.NotificationEvents.PurgeBefore(time.Now().AddDate(0, 0, -notificationEventDays)); err != nil {
+		s.log.Warnw("Failed to purge notification events", "error", err)
+	} else if purged > 0 {
+		s.log.Debugw("Purged notification events", "events", purged)
 	}
 
-	// Combine with default times from config
-	allTimes := make(map[string]bool)
-
-	// Add user times
-	for _, timeStr := range userTimes {
-		allTimes[timeStr] = true
+	// Get every time zone and time users want reminders at
+	slots, err := s.repo.GetReminderSlots()
+	if err != nil {
+		// Fall back to config times if there's an error
+		s.log.Errorw("Error getting user reminder times", "error", err)
 	}
 
-	// Add config times if user times are empty:
-	if len(userTimes) < 1 {
+	// Use the config times in the assessment time zone if no user has any
+	if len(slots) < 1 {
 		for _, timeStr := range s.config.Reminders.Times {
-			allTimes[timeStr] = true
+			slots = append(slots, repository.ReminderSlot{Time: timeStr})
 		}
 	}
 
-	// Schedule all unique times
-	timeIndex := 0
-	for timeStr := range allTimes {
-		if err := s.scheduleReminderDaily(timeStr, timeIndex); err != nil {
-			return fmt.Errorf("failed to schedule reminder for %s: %w", timeStr, err)
+	// Schedule all unique slots
+	for _, slot := range slots {
+		if err := s.scheduleReminderDaily(slot); err != nil {
+			return fmt.Errorf("failed to schedule reminder for %s: %w", slot.Time, err)
 		}
-		timeIndex++
 	}
+
+	// Snoozes outlive restarts and schedule changes
+	s.restoreSnoozes()
 	return nil
 }
 
@@ -89,7 +108,8 @@ func (s *ReminderScheduler) Stop() {
 	}
 }
 
-// UpdateSchedules refreshes all scheduled reminders
+// UpdateSchedules refreshes all scheduled reminders. Snoozed reminders
+// are left in place.
 func (s *ReminderScheduler) UpdateSchedules() error {
 	// Stop all current jobs
 	s.Stop()
@@ -98,34 +118,160 @@ func (s *ReminderScheduler) UpdateSchedules() error {
 	return s.Start()
 }
 
-// scheduleReminderDaily schedules a daily reminder at the specified time
-func (s *ReminderScheduler) scheduleReminderDaily(timeStr string, reminderIndex int) error {
-	// Parse time
-	t, err := time.Parse("15:04", timeStr)
+// Snooze holds back the user's reminders for the current assessment day for
+// the given number of minutes (the configured snooze period if zero), then
+// sends one reminder. A new snooze replaces any pending one. It returns when
+// the reminder is due.
+func (s *ReminderScheduler) Snooze(email string, minutes int) (time.Time, error) {
+	if minutes <= 0 {
+		minutes = s.config.Reminders.SnoozeMinutes
+	}
+
+	user, err := s.repo.Users.GetByEmail(email)
 	if err != nil {
-		return fmt.Errorf("invalid time format: %w", err)
+		return time.Time{}, fmt.Errorf("failed to look up user: %w", err)
+	}
+	day := s.repo.Users.AssessmentDayFor(user).Today()
+	until := time.Now().Add(time.Duration(minutes) * time.Minute)
+
+	if err := s.repo.Reminders.SnoozeUntil(user.Email, day, until); err != nil {
+		return time.Time{}, err
+	}
+	s.armSnooze(user.Email, day, until)
+	return until, nil
+}
+
+// SkipToday stops the user's remaining reminders for the current assessment
+// day, including a pending snooze
+func (s *ReminderScheduler) SkipToday(email string) (time.Time, error) {
+	user, err := s.repo.Users.GetByEmail(email)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to look up user: %w", err)
+	}
+	day := s.repo.Users.AssessmentDayFor(user).Today()
+
+	if err := s.repo.Reminders.SkipDay(user.Email, day); err != nil {
+		return time.Time{}, err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	key := strings.ToLower(user.Email)
+	if timer, exists := s.snoozes[key]; exists {
+		timer.Stop()
+		delete(s.snoozes, key)
+	}
+	return day, nil
+}
+
+// restoreSnoozes re-arms snoozes saved before a restart
+func (s *ReminderScheduler) restoreSnoozes() {
+	pending, err := s.repo.Reminders.PendingSnoozes(time.Now())
+	if err != nil {
+		s.log.Warnw("Failed to restore snoozed reminders", "error", err)
+		return
+	}
+	for _, override := range pending {
+		s.mutex.Lock()
+		_, armed := s.snoozes[override.UserEmail]
+		s.mutex.Unlock()
+		if !armed {
+			s.armSnooze(override.UserEmail, override.AssessmentDay, *override.SnoozedUntil)
+		}
+	}
+}
+
+// armSnooze starts the timer that sends a snoozed reminder, replacing any
+// timer already pending for the user
+func (s *ReminderScheduler) armSnooze(email string, day, until time.Time) {
+	key := strings.ToLower(email)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if timer, exists := s.snoozes[key]; exists {
+		timer.Stop()
+	}
+
+	var timer *time.Timer
+	timer = time.AfterFunc(time.Until(until), func() {
+		s.mutex.Lock()
+		if s.snoozes[key] == timer {
+			delete(s.snoozes, key)
+		}
+		s.mutex.Unlock()
+
+		s.sendSnoozedReminder(key, day)
+	})
+	s.snoozes[key] = timer
+}
+
+// sendSnoozedReminder sends the reminder a user snoozed, by push if possible
+// and otherwise by email
+func (s *ReminderScheduler) sendSnoozedReminder(email string, day time.Time) {
+	// The user may have skipped the day or finished the assessment since
+	override, err := s.repo.Reminders.GetOverride(email, day)
+	if err != nil {
+		return
+	}
+	if override != nil && override.SkipDay {
+		s.log.Debugw("Skipping snoozed reminder - day skipped", "user", email)
+		return
+	}
+	completed, err := s.repo.Users.HasCompletedAssessment(email)
+	if err != nil {
+		s.log.Warnw("Failed to check assessment completion status", "error", err, "user", email)
+		return
+	}
+	if completed {
+		s.log.Debugw("Skipping snoozed reminder - assessment already completed", "user", email)
+		return
 	}
 
-	// Get current time
-	now := time.Now()
+	if s.pushService != nil {
+		err := s.pushService.SendReminderNotification(email)
+		if err == nil {
+			s.log.Infow("Sent snoozed reminder", "user", email, "channel", models.NotificationChannelPush)
+			return
+		}
+		s.log.Warnw("Failed to send snoozed push reminder", "error", err, "user", email)
+	}
 
-	// Set reminder time for today
-	reminderTime := time.Date(
-		now.Year(), now.Month(), now.Day(),
-		t.Hour(), t.Minute(), 0, 0,
-		now.Location(),
-	)
+	if s.emailService != nil && s.config.Email.Enabled {
+		content, err := s.reminders.Render(email, models.NotificationChannelEmail)
+		if err != nil {
+			return
+		}
+		if err := s.emailService.SendReminderEmail(email, content); err != nil {
+			s.log.Warnw("Failed to send snoozed reminder email", "error", err, "user", email)
+			return
+		}
+		s.log.Infow("Sent snoozed reminder", "user", email, "channel", models.NotificationChannelEmail)
+	}
+}
 
-	// If the time has already passed today, schedule for tomorrow
-	if reminderTime.Before(now) {
-		reminderTime = reminderTime.Add(24 * time.Hour)
+// scheduleReminderDaily schedules a daily reminder for a slot. Slot times
+// are wall-clock times in the slot's time zone, so reminders keep their
+// place on the clock when it changes.
+func (s *ReminderScheduler) scheduleReminderDaily(slot repository.ReminderSlot) error {
+	// Parse time
+	t, err := time.Parse("15:04", slot.Time)
+	if err != nil {
+		return fmt.Errorf("invalid time format: %w", err)
+	}
+
+	days, err := s.repo.AssessmentDay().InZone(slot.Timezone)
+	if err != nil {
+		s.log.Warnw("Scheduling reminders in the assessment time zone", "error", err, "time", slot.Time)
 	}
 
-	// Calculate duration until reminder
-	duration := reminderTime.Sub(now)
+	// Calculate duration until the next reminder, which is tomorrow if the
+	// time has already passed today
+	reminderTime := utils.NextWallClock(time.Now(), days.Location(), t.Hour(), t.Minute())
+	duration := time.Until(reminderTime)
 
 	// Create a unique key for this reminder
-	key := fmt.Sprintf("reminder_%s", timeStr)
+	key := fmt.Sprintf("reminder_%s_%s", slot.Timezone, slot.Time)
 
 	// Lock mutex to prevent race conditions
 	s.mutex.Lock()
@@ -139,12 +285,12 @@ func (s *ReminderScheduler) scheduleReminderDaily(timeStr string, reminderIndex
 	// Create new timer
 	timer := time.AfterFunc(duration, func() {
 		// Call sendReminders instead of directly using pushService
-		if err := s.sendReminders(timeStr); err != nil {
+		if err := s.sendReminders(slot, days); err != nil {
 			s.log.Errorw("Error sending reminders", "error", err)
 		}
 
 		// Reschedule for tomorrow
-		if err := s.scheduleReminderDaily(timeStr, reminderIndex); err != nil {
+		if err := s.scheduleReminderDaily(slot); err != nil {
 			s.log.Errorw("Error rescheduling reminder", "error", err)
 		}
 	})
@@ -154,20 +300,47 @@ func (s *ReminderScheduler) scheduleReminderDaily(timeStr string, reminderIndex
 	return nil
 }
 
-// sendReminders sends push and email reminders to eligible users
-func (s *ReminderScheduler) sendReminders(timeStr string) error {
+// sendReminders sends push and email reminders to eligible users in a slot
+func (s *ReminderScheduler) sendReminders(slot repository.ReminderSlot, days utils.AssessmentDay) error {
+	timeStr := slot.Time
+
 	// Send push notifications if service is available
 	if s.pushService != nil {
-		if err := s.pushService.SendReminderToAllEligibleUsers(timeStr); err != nil {
-			s.log.Errorw("Error sending push reminders", "error", err, "time", timeStr)
+		started := time.Now()
+		stats, err := s.pushService.SendReminderToAllEligibleUsers(slot)
+		if err != nil {
+			s.log.Errorw("Error sending push reminders", "error", err, "time", timeStr, "timezone", slot.Timezone)
 			// Continue to email reminders even if push fails
+		} else {
+			s.log.Infow("Push reminders dispatched",
+				"time", timeStr,
+				"timezone", slot.Timezone,
+				"eligible", stats.Eligible,
+				"sent", stats.Sent,
+				"failed", stats.Failed,
+				"pruned", stats.Pruned,
+				"skipped", stats.Skipped,
+				"duration", time.Since(started))
+			s.repo.Reminders.RecordRun(&models.ReminderRun{
+				Channel:       models.NotificationChannelPush,
+				AssessmentDay: days.Today(),
+				TimeSlot:      timeStr,
+				Timezone:      slot.Timezone,
+				Eligible:      stats.Eligible,
+				Sent:          stats.Sent,
+				Failed:        stats.Failed,
+				Pruned:        stats.Pruned,
+				Skipped:       stats.Skipped,
+				StartedAt:     started,
+				DurationMs:    time.Since(started).Milliseconds(),
+			})
 		}
 	}
 
 	// Send email reminders if service is available
 	if s.emailService != nil && s.config.Email.Enabled {
 		// Get users who have enabled email reminders for this time
-		users, err := s.repo.GetUsersForEmailReminder(timeStr)
+		users, err := s.repo.GetUsersForEmailReminder(slot)
 		if err != nil {
 			s.log.Errorw("Error getting users for email reminders", "error", err, "time", timeStr)
 		} else if len(users) > 0 {
@@ -190,19 +363,41 @@ func (s *ReminderScheduler) sendReminders(timeStr string) error {
 					continue
 				}
 
+				// Respect a skip or snooze the user set for today
+				day := s.repo.Users.AssessmentDayFor(user).Today()
+				override, err := s.repo.Reminders.GetOverride(user.Email, day)
+				if err != nil {
+					continue
+				}
+				if override.Suppresses(time.Now()) {
+					s.log.Infow("Skipping reminder - skipped or snoozed by user",
+						"user", user.Email)
+					continue
+				}
+
+				// The ledger stops duplicate sends after a reschedule or restart
+				claimed, err := s.repo.Reminders.Claim(user.Email, models.NotificationChannelEmail, day, timeStr)
+				if err != nil {
+					continue
+				}
+				if !claimed {
+					s.log.Infow("Skipping reminder - already sent",
+						"user", user.Email, "time", timeStr)
+					continue
+				}
+
 				// Use goroutine to send emails asynchronously
 				go func(u *models.User) {
-					// Default to email as first name if first name is empty
-					firstName := u.FirstName
-					if firstName == "" {
-						firstName = u.Email
+					content, err := s.reminders.Render(u.Email, models.NotificationChannelEmail)
+					if err == nil {
+						err = s.emailService.SendReminderEmail(u.Email, content)
 					}
-
-					if err := s.emailService.SendReminderEmail(u.Email, firstName); err != nil {
+					if err != nil {
 						s.log.Warnw("Failed to send reminder email",
 							"error", err,
 							"user", u.Email,
 							"time", timeStr)
+						s.repo.Reminders.Release(u.Email, models.NotificationChannelEmail, day, timeStr)
 					} else {
 						s.log.Infow("Sent reminder email",
 							"user", u.Email,
@@ -0,0 +1,173 @@
+// internal/scheduler/threshold.go
+package scheduler
+
+import (
+	"time"
+
+	"github.com/andevellicus/crapp/internal/models"
+	"github.com/andevellicus/crapp/internal/repository"
+	"github.com/andevellicus/crapp/internal/services"
+	"go.uber.org/zap"
+)
+
+// thresholdLookbackDays is how far past the required run length each
+// evaluation looks, so runs are still found after a missed night
+const thresholdLookbackDays = 7
+
+// ThresholdScheduler evaluates symptom thresholds once each assessment day
+// closes, flagging users whose answers crossed one and alerting their admins
+type ThresholdScheduler struct {
+	repo         *repository.Repository
+	log          *zap.SugaredLogger
+	emailService *services.EmailService
+	stopChan     chan struct{}
+}
+
+// NewThresholdScheduler creates a new symptom threshold scheduler
+func NewThresholdScheduler(repo *repository.Repository, log *zap.SugaredLogger, emailService *services.EmailService) *ThresholdScheduler {
+	return &ThresholdScheduler{
+		repo:         repo,
+		log:          log.Named("thresholds"),
+		emailService: emailService,
+		stopChan:     make(chan struct{}),
+	}
+}
+
+// Start begins the threshold scheduler
+func (s *ThresholdScheduler) Start() {
+	go func() {
+		// Catch up on anything missed while the server was down
+		s.run()
+
+		days := s.repo.AssessmentDay()
+		for {
+			next := days.Start(days.AddDays(days.Today(), 1))
+			timer := time.NewTimer(time.Until(next))
+			select {
+			case <-timer.C:
+				s.run()
+			case <-s.stopChan:
+				timer.Stop()
+				return
+			}
+		}
+	}()
+
+	s.log.Info("Threshold scheduler started")
+}
+
+// Stop stops the threshold scheduler
+func (s *ThresholdScheduler) Stop() {
+	close(s.stopChan)
+	s.log.Info("Threshold scheduler stopped")
+}
+
+// run evaluates every enabled threshold against every organization's data
+func (s *ThresholdScheduler) run() {
+	thresholds, err := s.repo.Thresholds.ListEnabled()
+	if err != nil {
+		s.log.Errorw("Failed to load symptom thresholds", "error", err)
+		return
+	}
+
+	raised := 0
+	days := s.repo.AssessmentDay()
+	today := days.Today()
+	for i := range thresholds {
+		threshold := &thresholds[i]
+		since := days.AddDays(today, -(threshold.ConsecutiveDays + thresholdLookbackDays))
+
+		for _, repo := range s.repo.DataRepositories() {
+			values, err := repo.Assessments.GetDailySymptomValues(threshold.QuestionID, threshold.OrganizationID, since)
+			if err != nil {
+				s.log.Errorw("Failed to load answers for threshold", "threshold", threshold.ID, "error", err)
+				continue
+			}
+			raised += s.evaluate(threshold, values)
+		}
+	}
+
+	s.log.Infow("Evaluated symptom thresholds", "thresholds", len(thresholds), "new_flags", raised)
+}
+
+// evaluate finds runs of consecutive matching days in values ordered by
+// user and day, records those long enough, and returns how many new flags
+// were raised
+func (s *ThresholdScheduler) evaluate(threshold *models.SymptomThreshold, values []repository.DailySymptomValue) int {
+	raised := 0
+	var run *models.SymptomFlag
+
+	flush := func() {
+		if run != nil && run.Days >= threshold.ConsecutiveDays {
+			raised += s.record(threshold, run)
+		}
+		run = nil
+	}
+
+	for _, v := range values {
+		if run != nil && run.UserEmail != v.UserEmail {
+			flush()
+		}
+		if !threshold.Matches(v.Value) {
+			flush()
+			continue
+		}
+
+		switch {
+		case run != nil && sameDay(v.Date, run.EndDay):
+			run.PeakValue = threshold.Further(run.PeakValue, v.Value)
+		case run != nil && sameDay(v.Date, run.EndDay.AddDate(0, 0, 1)):
+			run.EndDay = v.Date
+			run.Days++
+			run.PeakValue = threshold.Further(run.PeakValue, v.Value)
+		default:
+			flush()
+			run = &models.SymptomFlag{
+				ThresholdID:    threshold.ID,
+				UserEmail:      v.UserEmail,
+				OrganizationID: v.OrganizationID,
+				QuestionID:     threshold.QuestionID,
+				StartDay:       v.Date,
+				EndDay:         v.Date,
+				Days:           1,
+				PeakValue:      v.Value,
+			}
+		}
+	}
+	flush()
+	return raised
+}
+
+// record stores a run and alerts admins when it raises a new flag
+func (s *ThresholdScheduler) record(threshold *models.SymptomThreshold, run *models.SymptomFlag) int {
+	created, err := s.repo.Thresholds.RecordRun(run)
+	if err != nil {
+		s.log.Errorw("Failed to record symptom flag", "threshold", threshold.ID, "error", err)
+		return 0
+	}
+	if !created {
+		return 0
+	}
+
+	s.log.Infow("Symptom threshold crossed", "threshold", threshold.ID, "flag", run.ID, "days", run.Days)
+	if s.emailService == nil {
+		return 1
+	}
+
+	recipients, err := s.repo.Users.GetAlertRecipients(run.OrganizationID)
+	if err != nil {
+		s.log.Warnw("Failed to load alert recipients", "flag", run.ID, "error", err)
+		return 1
+	}
+	for _, to := range recipients {
+		if err := s.emailService.SendSymptomAlertEmail(to, run.ID, threshold.Describe(), run.Days, run.StartDay, run.EndDay); err != nil {
+			s.log.Warnw("Failed to send symptom alert", "flag", run.ID, "to", to, "error", err)
+		}
+	}
+	return 1
+}
+
+// sameDay compares calendar dates, ignoring time and zone
+func sameDay(a, b time.Time) bool {
+	return a.Format("2006-01-02") == b.Format("2006-01-02")
+}
@@ -4,23 +4,27 @@ package scheduler
 import (
 	"time"
 
+	"github.com/andevellicus/crapp/internal/config"
 	"github.com/andevellicus/crapp/internal/repository"
 	"go.uber.org/zap"
 )
 
-// TokenCleanupScheduler periodically cleans up expired tokens
+// TokenCleanupScheduler periodically cleans up expired tokens and ends
+// sessions past the idle timeout or session lifetime
 type TokenCleanupScheduler struct {
 	repo     *repository.Repository
 	log      *zap.SugaredLogger
+	jwt      *config.JWTConfig
 	interval time.Duration
 	stopChan chan struct{}
 }
 
 // NewTokenCleanupScheduler creates a new token cleanup scheduler
-func NewTokenCleanupScheduler(repo *repository.Repository, log *zap.SugaredLogger) *TokenCleanupScheduler {
+func NewTokenCleanupScheduler(repo *repository.Repository, log *zap.SugaredLogger, jwt *config.JWTConfig) *TokenCleanupScheduler {
 	return &TokenCleanupScheduler{
 		repo:     repo,
 		log:      log.Named("token-cleanup"),
+		jwt:      jwt,
 		interval: 12 * time.Hour, // Run cleanup every 12 hours
 		stopChan: make(chan struct{}),
 	}
@@ -58,6 +62,19 @@ func (s *TokenCleanupScheduler) Stop() {
 func (s *TokenCleanupScheduler) cleanup() {
 	s.log.Debug("Running token cleanup task")
 
+	var idleBefore, startedBefore time.Time
+	if s.jwt.IdleTimeoutDays > 0 {
+		idleBefore = time.Now().AddDate(0, 0, -s.jwt.IdleTimeoutDays)
+	}
+	if s.jwt.SessionLifetimeDays > 0 {
+		startedBefore = time.Now().AddDate(0, 0, -s.jwt.SessionLifetimeDays)
+	}
+	if revoked, err := s.repo.RefreshTokens.RevokeStaleSessions(idleBefore, startedBefore); err != nil {
+		s.log.Errorw("Failed to revoke stale sessions", "error", err)
+	} else if revoked > 0 {
+		s.log.Infow("Revoked stale sessions", "count", revoked)
+	}
+
 	err := s.repo.CleanupExpiredTokens()
 	if err != nil {
 		s.log.Errorw("Failed to clean up expired tokens", "error", err)
@@ -0,0 +1,182 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/andevellicus/crapp/internal/repository"
+	"github.com/andevellicus/crapp/internal/utils"
+	"go.uber.org/zap"
+)
+
+// What an achievement counts towards
+const (
+	achievementStreak      = "streak"      // Consecutive assessment days
+	achievementAssessments = "assessments" // Assessments submitted
+	achievementBattery     = "battery"     // Every cognitive test in one assessment
+)
+
+// AchievementDefinition describes a badge or milestone and the goal that earns it
+type AchievementDefinition struct {
+	Key         string `json:"key"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	kind        string
+	goal        int
+}
+
+// Achievements lists every achievement, in the order they are shown
+var Achievements = []AchievementDefinition{
+	{Key: "first_assessment", Title: "First check-in", Description: "Submit your first assessment", kind: achievementAssessments, goal: 1},
+	{Key: "streak_7", Title: "7-day streak", Description: "Check in 7 days in a row", kind: achievementStreak, goal: 7},
+	{Key: "streak_30", Title: "30-day streak", Description: "Check in 30 days in a row", kind: achievementStreak, goal: 30},
+	{Key: "assessments_30", Title: "30 assessments", Description: "Submit 30 assessments", kind: achievementAssessments, goal: 30},
+	{Key: "assessments_100", Title: "100 assessments", Description: "Submit 100 assessments", kind: achievementAssessments, goal: 100},
+	{Key: "cognitive_battery", Title: "Full cognitive battery", Description: "Complete every cognitive test in one assessment", kind: achievementBattery, goal: 1},
+}
+
+// AchievementProgress is an achievement with how close a user is to it
+type AchievementProgress struct {
+	AchievementDefinition
+	Earned    bool       `json:"earned"`
+	AwardedAt *time.Time `json:"awarded_at,omitempty"`
+	Progress  int        `json:"progress"`
+	Goal      int        `json:"goal"`
+}
+
+// AchievementService awards achievements on submission and reports progress
+type AchievementService struct {
+	repo *repository.Repository
+	log  *zap.SugaredLogger
+}
+
+// NewAchievementService creates a new achievement service
+func NewAchievementService(repo *repository.Repository, log *zap.SugaredLogger) *AchievementService {
+	return &AchievementService{
+		repo: repo,
+		log:  log.Named("achievements"),
+	}
+}
+
+// Award checks a submitted assessment against every achievement and records
+// those the user newly earned, which it returns. The streak includes the
+// submission. Questions decide which cognitive tests make a full battery.
+func (s *AchievementService) Award(email string, assessmentID uint, streak int, questions []utils.Question) ([]AchievementDefinition, error) {
+	count, err := s.repo.ForUser(email).Assessments.CountByUser(email)
+	if err != nil {
+		return nil, err
+	}
+	battery, err := s.completedBattery(email, assessmentID, questions)
+	if err != nil {
+		return nil, err
+	}
+	progress := map[string]int{
+		achievementStreak:      streak,
+		achievementAssessments: int(count),
+		achievementBattery:     battery,
+	}
+
+	var awarded []AchievementDefinition
+	for _, a := range Achievements {
+		if progress[a.kind] < a.goal {
+			continue
+		}
+		created, err := s.repo.Achievements.Award(email, a.Key, assessmentID)
+		if err != nil {
+			return awarded, err
+		}
+		if created {
+			s.log.Infow("Achievement earned", "user", email, "achievement", a.Key)
+			awarded = append(awarded, a)
+		}
+	}
+	return awarded, nil
+}
+
+// completedBattery returns 1 if the assessment has results for every
+// cognitive test in the questions, and 0 otherwise or if there are none
+func (s *AchievementService) completedBattery(email string, assessmentID uint, questions []utils.Question) (int, error) {
+	var tests []string
+	for _, q := range questions {
+		switch q.Type {
+		case "cpt", "tmt", "digit_span":
+			tests = append(tests, q.Type)
+		}
+	}
+	if len(tests) == 0 {
+		return 0, nil
+	}
+
+	completed, err := s.repo.ForUser(email).Assessments.CognitiveTestsCompleted(assessmentID)
+	if err != nil {
+		return 0, err
+	}
+	for _, test := range tests {
+		if !completed[test] {
+			return 0, nil
+		}
+	}
+	return 1, nil
+}
+
+// Progress lists every achievement with whether the user earned it and how
+// far along they are. The streak counts up to the user's current day.
+func (s *AchievementService) Progress(email string) ([]AchievementProgress, int, error) {
+	earned, err := s.repo.Achievements.ListForUser(email)
+	if err != nil {
+		return nil, 0, err
+	}
+	awardedAt := make(map[string]time.Time, len(earned))
+	for _, a := range earned {
+		awardedAt[a.Key] = a.AwardedAt
+	}
+
+	streak, err := s.currentStreak(email)
+	if err != nil {
+		return nil, 0, err
+	}
+	count, err := s.repo.ForUser(email).Assessments.CountByUser(email)
+	if err != nil {
+		return nil, 0, err
+	}
+	current := map[string]int{
+		achievementStreak:      streak,
+		achievementAssessments: int(count),
+	}
+
+	progress := make([]AchievementProgress, 0, len(Achievements))
+	for _, a := range Achievements {
+		entry := AchievementProgress{AchievementDefinition: a, Goal: a.goal, Progress: min(current[a.kind], a.goal)}
+		if at, ok := awardedAt[a.Key]; ok {
+			entry.Earned = true
+			entry.AwardedAt = &at
+			entry.Progress = a.goal
+		}
+		progress = append(progress, entry)
+	}
+	return progress, streak, nil
+}
+
+// ReminderNudge words the user's streak for a reminder, naming the streak
+// badge it would earn today if one is next. It is empty without a streak.
+func (s *AchievementService) ReminderNudge(email string) string {
+	streak, err := s.currentStreak(email)
+	if err != nil || streak == 0 {
+		return ""
+	}
+	for _, a := range Achievements {
+		if a.kind == achievementStreak && streak+1 == a.goal {
+			return fmt.Sprintf("Check in today to earn your %s badge!", a.Title)
+		}
+	}
+	return fmt.Sprintf("You're on a %d-day streak. Check in today to keep it going!", streak)
+}
+
+// currentStreak counts the user's streak up to their current assessment day
+func (s *AchievementService) currentStreak(email string) (int, error) {
+	days := s.repo.AssessmentDay()
+	if user, err := s.repo.Users.GetByEmail(email); err == nil && user != nil {
+		days = s.repo.Users.AssessmentDayFor(user)
+	}
+	return s.repo.ForUser(email).Assessments.StreakDays(email, days.Today())
+}
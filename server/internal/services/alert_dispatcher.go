@@ -0,0 +1,89 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/andevellicus/crapp/internal/models"
+	"github.com/andevellicus/crapp/internal/repository"
+	"go.uber.org/zap"
+)
+
+// AlertDispatcher delivers urgent alerts to the staff responsible for a user
+type AlertDispatcher struct {
+	repo  *repository.Repository
+	log   *zap.SugaredLogger
+	push  *PushService
+	email *EmailService // Nil when email is disabled
+}
+
+// NewAlertDispatcher creates a new alert dispatcher
+func NewAlertDispatcher(repo *repository.Repository, log *zap.SugaredLogger, push *PushService, email *EmailService) *AlertDispatcher {
+	return &AlertDispatcher{
+		repo:  repo,
+		log:   log.Named("alerts"),
+		push:  push,
+		email: email,
+	}
+}
+
+// DispatchRedFlag notifies the user's assigned clinician about a red-flag
+// alert, or their organization's admins if no clinician is assigned. Each
+// recipient gets a high-priority email and a high-urgency push
+// notification; the recipients reached are recorded on the alert.
+func (d *AlertDispatcher) DispatchRedFlag(alert *models.RedFlagAlert) {
+	recipients, err := d.redFlagRecipients(alert)
+	if err != nil {
+		d.log.Errorw("Failed to find red-flag alert recipients", "alert", alert.ID, "error", err)
+		return
+	}
+	if len(recipients) == 0 {
+		d.log.Warnw("No one to notify about red-flag alert", "alert", alert.ID, "organization", alert.OrganizationID)
+		return
+	}
+
+	title := "Urgent: red-flag answer"
+	body := fmt.Sprintf("A participant answered %q at its red-flag level. Review alert #%d.", alert.QuestionTitle, alert.ID)
+
+	var reached []string
+	for _, to := range recipients {
+		delivered := false
+		if d.email != nil {
+			if err := d.email.SendRedFlagAlertEmail(to, alert.ID, alert.QuestionTitle, alert.Value, alert.CreatedAt); err != nil {
+				d.log.Warnw("Failed to email red-flag alert", "alert", alert.ID, "to", to, "error", err)
+			} else {
+				delivered = true
+			}
+		}
+		if err := d.push.SendUrgentNotification(to, title, body, "/admin/charts"); err != nil {
+			d.log.Debugw("Failed to push red-flag alert", "alert", alert.ID, "to", to, "error", err)
+		} else {
+			delivered = true
+		}
+		if delivered {
+			reached = append(reached, to)
+		}
+	}
+
+	if len(reached) == 0 {
+		d.log.Errorw("Red-flag alert reached no one", "alert", alert.ID, "recipients", recipients)
+		return
+	}
+	if err := d.repo.Thresholds.RedFlagNotified(alert.ID, reached); err != nil {
+		d.log.Warnw("Failed to record red-flag alert delivery", "alert", alert.ID, "error", err)
+	}
+	d.log.Infow("Dispatched red-flag alert", "alert", alert.ID, "recipients", reached)
+}
+
+// redFlagRecipients returns the user's assigned clinician, falling back to
+// the admins who receive symptom alerts
+func (d *AlertDispatcher) redFlagRecipients(alert *models.RedFlagAlert) ([]string, error) {
+	user, err := d.repo.Users.GetByEmail(alert.UserEmail)
+	if err != nil {
+		return nil, err
+	}
+	if clinician := strings.TrimSpace(user.ClinicianEmail); clinician != "" {
+		return []string{clinician}, nil
+	}
+	return d.repo.Users.GetAlertRecipients(alert.OrganizationID)
+}
@@ -2,9 +2,15 @@
 package services
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"math/big"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/andevellicus/crapp/internal/config"
@@ -19,23 +25,50 @@ type AuthService struct {
 	repo            *repository.Repository
 	tokenTTL        time.Duration
 	refreshTokenTTL time.Duration
+	ephemeralTTL    time.Duration
+	idleTimeout     time.Duration
+	sessionLifetime time.Duration
 	secretKey       string
 	JWTConfig       *config.JWTConfig
+	resetConfig     *config.PasswordResetConfig
+	resetRequests   *resetLog
 }
 
+// ErrReauthUnavailable is returned when a token can't be re-authenticated,
+// such as a kiosk token, which has no session behind it
+var ErrReauthUnavailable = errors.New("re-authentication not available for this session")
+
+// ErrSessionExpired is returned when a refresh token outlives the idle timeout
+// or session lifetime, so the user has to log in again
+var ErrSessionExpired = errors.New("session expired")
+
+// ErrResetThrottled is returned when a user requests password resets too quickly
+var ErrResetThrottled = errors.New("password reset requested too frequently")
+
 // CustomClaims defines the claims in the JWT token
 type CustomClaims struct {
 	Email   string `json:"email"`
 	IsAdmin bool   `json:"is_admin"`
 	TokenID string `json:"token_id"`
+	// Set only on proctored kiosk session tokens
+	KioskSessionID string `json:"kiosk_session_id,omitempty"`
+	// Set only on admin impersonation tokens
+	ImpersonatorEmail string `json:"impersonator,omitempty"`
+	ImpersonationID   string `json:"impersonation_id,omitempty"`
+	ReadOnly          bool   `json:"read_only,omitempty"`
+	// Set when the user recently re-entered their password; sensitive
+	// actions are allowed until this Unix time
+	ReauthUntil int64 `json:"reauth_until,omitempty"`
 	jwt.RegisteredClaims
 }
 
 // TokenPair contains both access and refresh tokens
 type TokenPair struct {
-	AccessToken  string `json:"access_token"`
-	RefreshToken string `json:"refresh_token"`
-	ExpiresIn    int    `json:"expires_in"` // Expiration in seconds
+	AccessToken      string `json:"access_token"`
+	RefreshToken     string `json:"refresh_token"`
+	ExpiresIn        int    `json:"expires_in"`         // Expiration in seconds
+	RefreshExpiresIn int    `json:"refresh_expires_in"` // Refresh token expiration in seconds
+	Remembered       bool   `json:"remembered"`         // Whether cookies should outlive the browser session
 }
 
 // Add this to the AuthService struct
@@ -47,13 +80,18 @@ type CookieConfig struct {
 	SameSite http.SameSite
 }
 
-func NewAuthService(repo *repository.Repository, cfg *config.JWTConfig) *AuthService {
+func NewAuthService(repo *repository.Repository, cfg *config.JWTConfig, resetCfg *config.PasswordResetConfig) *AuthService {
 	return &AuthService{
 		repo:            repo,
 		tokenTTL:        time.Duration(cfg.Expires) * time.Minute,           // Short-lived access token
 		refreshTokenTTL: time.Duration(cfg.RefreshExpires) * time.Hour * 24, // Longer-lived refresh token (days)
+		ephemeralTTL:    time.Duration(cfg.EphemeralRefreshHours) * time.Hour,
+		idleTimeout:     time.Duration(cfg.IdleTimeoutDays) * time.Hour * 24,
+		sessionLifetime: time.Duration(cfg.SessionLifetimeDays) * time.Hour * 24,
 		secretKey:       cfg.Secret,
 		JWTConfig:       cfg,
+		resetConfig:     resetCfg,
+		resetRequests:   &resetLog{sent: make(map[string][]time.Time)},
 	}
 }
 
@@ -67,59 +105,89 @@ func (s *AuthService) GetCookieConfig() CookieConfig {
 	}
 }
 
-// Authenticate validates credentials and returns user with session
-func (s *AuthService) Authenticate(email, password string, deviceInfo map[string]any) (*models.User, *models.Device, *TokenPair, error) {
+// VerifyCredentials checks a user's email and password without logging them in
+func (s *AuthService) VerifyCredentials(email, password string) (*models.User, error) {
 	normalizedEmail := strings.ToLower(email)
 
 	// Get user
 	user, err := s.repo.Users.GetByEmail(normalizedEmail)
 	if err != nil {
-		return nil, nil, nil, err
+		return nil, err
 	}
 	if user == nil {
 		// User does not exist
-		return nil, nil, nil, fmt.Errorf("authenticate: GetByEmail for user %s failed - user does not exist", normalizedEmail)
+		return nil, fmt.Errorf("authenticate: GetByEmail for user %s failed - user does not exist", normalizedEmail)
 	}
 
 	if user.Password == nil {
 		// Return a generic error to avoid exposing account state
-		return nil, nil, nil, fmt.Errorf("attempted login for user with nil password hash")
+		return nil, fmt.Errorf("attempted login for user with nil password hash")
 	}
 
 	// Verify password
 	err = bcrypt.CompareHashAndPassword(user.Password, []byte(password))
 	if err != nil {
-		return nil, nil, nil, fmt.Errorf("invalid password")
+		return nil, fmt.Errorf("invalid password")
 	}
 
+	return user, nil
+}
+
+// CompleteLogin registers the device and issues tokens for a verified user.
+// Sessions that aren't remembered get a short refresh expiration.
+func (s *AuthService) CompleteLogin(user *models.User, deviceInfo map[string]any, remember bool) (*models.Device, *TokenPair, error) {
+	normalizedEmail := strings.ToLower(user.Email)
+
 	// Register device
 	device, err := s.repo.Devices.RegisterDevice(normalizedEmail, deviceInfo)
 	if err != nil {
-		return nil, nil, nil, err
+		return nil, nil, err
 	}
 
 	// Generate token pair
-	tokenPair, err := s.GenerateTokenPair(normalizedEmail, user.IsAdmin, device.ID)
+	tokenPair, err := s.GenerateTokenPair(normalizedEmail, user.IsAdmin, device.ID, remember)
 	if err != nil {
-		return nil, nil, nil, err
+		return nil, nil, err
 	}
 
 	// Update last login time
 	if err := s.repo.Users.LastLoginNow(normalizedEmail); err != nil {
+		return nil, nil, err
+	}
+
+	return device, tokenPair, nil
+}
+
+// Authenticate verifies credentials and completes the login in one step
+func (s *AuthService) Authenticate(email, password string, deviceInfo map[string]any, remember bool) (*models.User, *models.Device, *TokenPair, error) {
+	user, err := s.VerifyCredentials(email, password)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	device, tokenPair, err := s.CompleteLogin(user, deviceInfo, remember)
+	if err != nil {
 		return nil, nil, nil, err
 	}
 
 	return user, device, tokenPair, nil
 }
 
-// GenerateTokenPair creates a new JWT access token and refresh token
-func (s *AuthService) GenerateTokenPair(email string, isAdmin bool, deviceID string) (*TokenPair, error) {
+// GenerateTokenPair creates a new JWT access token and refresh token,
+// starting a new session
+func (s *AuthService) GenerateTokenPair(email string, isAdmin bool, deviceID string, remember bool) (*TokenPair, error) {
+	return s.issueTokenPair(email, isAdmin, deviceID, time.Now(), remember)
+}
+
+// issueTokenPair creates a token pair for a session that started at
+// sessionStart. The refresh token never outlives the session lifetime.
+func (s *AuthService) issueTokenPair(email string, isAdmin bool, deviceID string, sessionStart time.Time, remember bool) (*TokenPair, error) {
 	normalizedEmail := strings.ToLower(email)
 	// Create a token ID (jti)
 	tokenID := uuid.New().String()
 
 	// Generate access token
-	accessToken, err := s.generateAccessToken(normalizedEmail, isAdmin, tokenID)
+	accessToken, err := s.generateAccessToken(normalizedEmail, isAdmin, tokenID, time.Time{})
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate access token: %w", err)
 	}
@@ -127,14 +195,25 @@ func (s *AuthService) GenerateTokenPair(email string, isAdmin bool, deviceID str
 	// Generate refresh token
 	refreshToken := uuid.New().String()
 
+	ttl := s.refreshTokenTTL
+	if !remember {
+		ttl = min(s.ephemeralTTL, ttl)
+	}
+	expiresAt := time.Now().Add(ttl)
+	if s.sessionLifetime > 0 && sessionStart.Add(s.sessionLifetime).Before(expiresAt) {
+		expiresAt = sessionStart.Add(s.sessionLifetime)
+	}
+
 	// Store refresh token in database
 	refreshTokenModel := &models.RefreshToken{
-		Token:     refreshToken,
-		UserEmail: normalizedEmail,
-		DeviceID:  deviceID,
-		TokenID:   tokenID,
-		ExpiresAt: time.Now().Add(s.refreshTokenTTL),
-		CreatedAt: time.Now(),
+		Token:            refreshToken,
+		UserEmail:        normalizedEmail,
+		DeviceID:         deviceID,
+		TokenID:          tokenID,
+		ExpiresAt:        expiresAt,
+		CreatedAt:        time.Now(),
+		SessionStartedAt: sessionStart,
+		Ephemeral:        !remember,
 	}
 
 	if err = s.repo.RefreshTokens.Create(refreshTokenModel); err != nil {
@@ -142,14 +221,17 @@ func (s *AuthService) GenerateTokenPair(email string, isAdmin bool, deviceID str
 	}
 
 	return &TokenPair{
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
-		ExpiresIn:    int(s.tokenTTL.Seconds()),
+		AccessToken:      accessToken,
+		RefreshToken:     refreshToken,
+		ExpiresIn:        int(s.tokenTTL.Seconds()),
+		RefreshExpiresIn: int(time.Until(expiresAt).Seconds()),
+		Remembered:       remember,
 	}, nil
 }
 
-// generateAccessToken creates a JWT access token
-func (s *AuthService) generateAccessToken(email string, isAdmin bool, tokenID string) (string, error) {
+// generateAccessToken creates a JWT access token. A non-zero reauthUntil
+// marks the token as recently re-authenticated until then.
+func (s *AuthService) generateAccessToken(email string, isAdmin bool, tokenID string, reauthUntil time.Time) (string, error) {
 	// Add more claims for security
 	expirationTime := time.Now().Add(s.tokenTTL)
 	notBeforeTime := time.Now().Add(s.JWTConfig.NotBefore)
@@ -172,6 +254,10 @@ func (s *AuthService) generateAccessToken(email string, isAdmin bool, tokenID st
 		},
 	}
 
+	if !reauthUntil.IsZero() {
+		claims.ReauthUntil = reauthUntil.Unix()
+	}
+
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	tokenString, err := token.SignedString([]byte(s.JWTConfig.Secret))
 	if err != nil {
@@ -181,6 +267,114 @@ func (s *AuthService) generateAccessToken(email string, isAdmin bool, tokenID st
 	return tokenString, err
 }
 
+// Reauthenticate checks the user's password again and issues an access
+// token for the same session that allows sensitive actions for the next few
+// minutes. It returns the token, its lifetime in seconds, and whether the
+// session is remembered.
+func (s *AuthService) Reauthenticate(email, tokenID, password string) (string, int, bool, error) {
+	// Only sessions with a refresh token can be elevated
+	session, err := s.repo.RefreshTokens.GetByTokenID(tokenID)
+	if err != nil || session.ExpiresAt.Before(time.Now()) {
+		return "", 0, false, ErrReauthUnavailable
+	}
+
+	user, err := s.VerifyCredentials(email, password)
+	if err != nil {
+		return "", 0, false, err
+	}
+
+	reauthUntil := time.Now().Add(time.Duration(s.JWTConfig.ReauthMinutes) * time.Minute)
+	accessToken, err := s.generateAccessToken(user.Email, user.IsAdmin, tokenID, reauthUntil)
+	if err != nil {
+		return "", 0, false, err
+	}
+	return accessToken, int(s.tokenTTL.Seconds()), !session.Ephemeral, nil
+}
+
+// GenerateKioskToken creates a short-lived access token scoped to a kiosk session.
+// No refresh token is issued, so the session cannot outlive its expiry.
+func (s *AuthService) GenerateKioskToken(patientEmail, sessionID string, ttl time.Duration) (string, string, error) {
+	normalizedEmail := strings.ToLower(patientEmail)
+	tokenID := uuid.New().String()
+	now := time.Now()
+
+	claims := &CustomClaims{
+		Email:          normalizedEmail,
+		IsAdmin:        false, // Kiosk sessions never carry admin rights
+		TokenID:        tokenID,
+		KioskSessionID: sessionID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			Issuer:    s.JWTConfig.Issuer,
+			Audience:  []string{s.JWTConfig.Audience},
+			Subject:   normalizedEmail,
+			ID:        tokenID,
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	tokenString, err := token.SignedString([]byte(s.JWTConfig.Secret))
+	if err != nil {
+		return "", "", fmt.Errorf("failed to sign kiosk token: %w", err)
+	}
+
+	return tokenString, tokenID, nil
+}
+
+// GenerateImpersonationToken creates a short-lived access token that lets an
+// admin act as a participant. The token names the admin and session, and no
+// refresh token is issued, so it cannot outlive the session.
+func (s *AuthService) GenerateImpersonationToken(session *models.ImpersonationSession, ttl time.Duration) (string, string, error) {
+	tokenID := uuid.New().String()
+	now := time.Now()
+
+	claims := &CustomClaims{
+		Email:             session.TargetEmail,
+		IsAdmin:           false, // Admin rights never carry over into the participant's view
+		TokenID:           tokenID,
+		ImpersonatorEmail: session.AdminEmail,
+		ImpersonationID:   session.ID,
+		ReadOnly:          session.ReadOnly,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			Issuer:    s.JWTConfig.Issuer,
+			Audience:  []string{s.JWTConfig.Audience},
+			Subject:   session.TargetEmail,
+			ID:        tokenID,
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	tokenString, err := token.SignedString([]byte(s.JWTConfig.Secret))
+	if err != nil {
+		return "", "", fmt.Errorf("failed to sign impersonation token: %w", err)
+	}
+
+	return tokenString, tokenID, nil
+}
+
+// RecordImpersonatedRequest adds a request made with an impersonation token
+// to the participant's audit log
+func (s *AuthService) RecordImpersonatedRequest(claims *CustomClaims, method, path string, status int, ip, userAgent string) error {
+	event := &models.AuditEvent{
+		UserEmail: claims.Email,
+		EventType: models.AuditImpersonatedRequest,
+		IPAddress: ip,
+		UserAgent: userAgent,
+	}
+	return s.repo.AuditEvents.Record(event, map[string]any{
+		"impersonator":     claims.ImpersonatorEmail,
+		"impersonation_id": claims.ImpersonationID,
+		"method":           method,
+		"path":             path,
+		"status":           status,
+	})
+}
+
 // RefreshToken generates a new access token using a refresh token
 func (s *AuthService) RefreshToken(refreshToken string, deviceID string) (*TokenPair, error) {
 	// 1. Validate the existing refresh token BY STRING
@@ -195,21 +389,36 @@ func (s *AuthService) RefreshToken(refreshToken string, deviceID string) (*Token
 		return nil, fmt.Errorf("invalid device for refresh token")
 	}
 
-	// 3. Get user associated with the token
+	// 3. Check the idle timeout and session lifetime
+	sessionStart := storedToken.SessionStartedAt
+	if sessionStart.IsZero() {
+		// Tokens issued before sessions were tracked
+		sessionStart = storedToken.CreatedAt
+	}
+	now := time.Now()
+	if (s.idleTimeout > 0 && now.Sub(storedToken.CreatedAt) > s.idleTimeout) ||
+		(s.sessionLifetime > 0 && now.Sub(sessionStart) > s.sessionLifetime) {
+		if err := s.repo.RefreshTokens.Delete(refreshToken); err != nil {
+			fmt.Printf("Warning: Failed to revoke expired session's refresh token: %v\n", err)
+		}
+		return nil, ErrSessionExpired
+	}
+
+	// 4. Get user associated with the token
 	user, err := s.repo.Users.GetByEmail(storedToken.UserEmail)
 	if err != nil || user == nil {
 		// User associated with token not found
 		return nil, fmt.Errorf("user not found for refresh token: %w", err)
 	}
 
-	// 4. Generate NEW token pair FIRST
-	newTokenPair, err := s.GenerateTokenPair(user.Email, user.IsAdmin, deviceID)
+	// 5. Generate NEW token pair FIRST, continuing the session
+	newTokenPair, err := s.issueTokenPair(user.Email, user.IsAdmin, deviceID, sessionStart, !storedToken.Ephemeral)
 	if err != nil {
 		// Failed to generate/store new tokens, return error WITHOUT revoking old one
 		return nil, fmt.Errorf("failed to generate new token pair: %w", err)
 	}
 
-	// 5. Successfully generated new pair, NOW revoke the OLD refresh token
+	// 6. Successfully generated new pair, NOW revoke the OLD refresh token
 	// It's okay if revocation fails, the old token will expire eventually.
 	// The user has the new valid token pair.
 	err = s.repo.RefreshTokens.Delete(refreshToken) // Marks the old token as revoked
@@ -265,6 +474,14 @@ func (s *AuthService) ValidateToken(tokenString string) (*CustomClaims, error) {
 		return nil, fmt.Errorf("token has been revoked")
 	}
 
+	// Impersonation tokens stop working as soon as their session ends
+	if claims.ImpersonationID != "" {
+		active, err := s.repo.Impersonations.IsActive(claims.ImpersonationID)
+		if err != nil || !active {
+			return nil, fmt.Errorf("impersonation session is no longer active")
+		}
+	}
+
 	return claims, nil
 }
 
@@ -299,21 +516,95 @@ func (s *AuthService) RevokeAllUserTokens(email string) error {
 	return s.repo.RevokedTokens.RevokeAllUserTokens(email)
 }
 
-// GeneratePasswordResetToken creates a token for password reset
-func (s *AuthService) GeneratePasswordResetToken(email string) (string, error) {
+// GeneratePasswordResetToken issues a reset token, plus a numeric code when
+// enabled. Returns ErrResetThrottled if the email is requesting too often,
+// which is checked before the account is looked up so that throttling is the
+// same for emails with and without an account.
+func (s *AuthService) GeneratePasswordResetToken(email string) (string, string, error) {
 	normalizedEmail := strings.ToLower(email)
+	cooldown := time.Duration(s.resetConfig.ResendCooldownSeconds) * time.Second
+	if !s.resetRequests.allow(normalizedEmail, cooldown, s.resetConfig.MaxPerHour, time.Now()) {
+		return "", "", ErrResetThrottled
+	}
+
 	// Check if user exists
 	user, err := s.repo.Users.GetByEmail(normalizedEmail)
 	if err != nil || user == nil {
-		return "", fmt.Errorf("user not found: %w", err)
+		return "", "", fmt.Errorf("user not found: %w", err)
+	}
+
+	var code, codeHash string
+	if s.resetConfig.NumericCode {
+		code, err = generateNumericCode(s.resetConfig.CodeLength)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to generate reset code: %w", err)
+		}
+		codeHash = hashResetCode(code)
 	}
 
-	// Create a reset token (valid for 30 minutes)
-	token, err := s.repo.PasswordResetTokens.Create(normalizedEmail, 30)
+	token, err := s.repo.PasswordResetTokens.Create(normalizedEmail, s.resetConfig.TokenMinutes, codeHash)
 	if err != nil {
-		return "", fmt.Errorf("failed to create reset token: %w", err)
+		return "", "", fmt.Errorf("failed to create reset token: %w", err)
 	}
 
+	return token.Token, code, nil
+}
+
+// resetLog remembers when reset emails were requested for each address in
+// the last hour, whether or not the address has an account
+type resetLog struct {
+	mu        sync.Mutex
+	sent      map[string][]time.Time
+	lastSweep time.Time
+}
+
+// allow reports whether an email may request another reset, given the
+// cooldown between requests and the hourly limit, and records the request if
+// so. A zero cooldown or limit doesn't apply.
+func (l *resetLog) allow(email string, cooldown time.Duration, maxPerHour int, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	hourAgo := now.Add(-time.Hour)
+	// Forget addresses with no requests left in the window
+	if now.Sub(l.lastSweep) > time.Hour {
+		for key, times := range l.sent {
+			if len(times) == 0 || !times[len(times)-1].After(hourAgo) {
+				delete(l.sent, key)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	recent := l.sent[email][:0]
+	for _, t := range l.sent[email] {
+		if t.After(hourAgo) {
+			recent = append(recent, t)
+		}
+	}
+	l.sent[email] = recent
+
+	if cooldown > 0 && len(recent) > 0 && now.Sub(recent[len(recent)-1]) < cooldown {
+		return false
+	}
+	if maxPerHour > 0 && len(recent) >= maxPerHour {
+		return false
+	}
+	l.sent[email] = append(recent, now)
+	return true
+}
+
+// ResetTokenMinutes returns how long reset links and codes stay valid
+func (s *AuthService) ResetTokenMinutes() int {
+	return s.resetConfig.TokenMinutes
+}
+
+// ResolveResetCode exchanges a user's numeric reset code for the matching reset token
+func (s *AuthService) ResolveResetCode(email, code string) (string, error) {
+	token, err := s.repo.PasswordResetTokens.ValidateCode(email, hashResetCode(code), s.resetConfig.MaxCodeAttempts)
+	if err != nil {
+		return "", fmt.Errorf("invalid or expired code: %w", err)
+	}
 	return token.Token, nil
 }
 
@@ -351,5 +642,45 @@ func (s *AuthService) ResetPassword(tokenStr string, newPassword string) error {
 		return fmt.Errorf("failed to mark token as used: %w", err)
 	}
 
+	// Sign out everywhere, in case the old password was compromised
+	if s.resetConfig.RevokeSessions {
+		if err := s.RevokeAllUserTokens(userEmail); err != nil {
+			return fmt.Errorf("failed to revoke sessions: %w", err)
+		}
+	}
+
 	return nil
 }
+
+// RevokesSessionsOnReset reports whether a reset signs the user out everywhere
+func (s *AuthService) RevokesSessionsOnReset() bool {
+	return s.resetConfig.RevokeSessions
+}
+
+// ShouldNotifyOnReset reports whether users are emailed after a password reset
+func (s *AuthService) ShouldNotifyOnReset() bool {
+	return s.resetConfig.NotifyOnReset
+}
+
+// generateNumericCode returns a random code of the given number of digits
+func generateNumericCode(length int) (string, error) {
+	if length <= 0 {
+		length = 6
+	}
+
+	digits := make([]byte, length)
+	for i := range digits {
+		n, err := rand.Int(rand.Reader, big.NewInt(10))
+		if err != nil {
+			return "", err
+		}
+		digits[i] = byte('0' + n.Int64())
+	}
+	return string(digits), nil
+}
+
+// hashResetCode hashes a numeric reset code for storage
+func hashResetCode(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
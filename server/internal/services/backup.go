@@ -0,0 +1,359 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/andevellicus/crapp/internal/buildinfo"
+	"github.com/andevellicus/crapp/internal/config"
+	"github.com/andevellicus/crapp/internal/storage"
+	"go.uber.org/zap"
+)
+
+// backupPrefix is where backups and their manifests are stored
+const backupPrefix = "backups/"
+
+// Backup triggers
+const (
+	BackupScheduled = "scheduled"
+	BackupManual    = "manual"
+)
+
+var (
+	// ErrBackupRunning is returned when a backup is requested while one is in progress
+	ErrBackupRunning = errors.New("a backup is already running")
+	// ErrBackupNotFound is returned for an unknown backup name
+	ErrBackupNotFound = errors.New("backup not found")
+)
+
+// BackupManifest describes a backup. It is stored next to the dump so
+// backups can be listed and checked without the database.
+type BackupManifest struct {
+	Name          string     `json:"name"`
+	Key           string     `json:"key"`
+	Size          int64      `json:"size"`
+	SHA256        string     `json:"sha256"`
+	CreatedAt     time.Time  `json:"created_at"`
+	Trigger       string     `json:"trigger"`
+	AppVersion    string     `json:"app_version"`
+	SchemaVersion string     `json:"schema_version,omitempty"`
+	Verified      bool       `json:"verified"`
+	VerifiedAt    *time.Time `json:"verified_at,omitempty"`
+	VerifyError   string     `json:"verify_error,omitempty"`
+}
+
+// BackupService dumps the database to storage with pg_dump, verifies the
+// stored copy, rotates old backups, and restores them with pg_restore
+type BackupService struct {
+	store       storage.Storage
+	cfg         *config.BackupConfig
+	databaseURL string
+	log         *zap.SugaredLogger
+	running     sync.Mutex
+}
+
+// NewBackupService creates a new backup service
+func NewBackupService(store storage.Storage, cfg *config.BackupConfig, databaseURL string, log *zap.SugaredLogger) *BackupService {
+	return &BackupService{
+		store:       store,
+		cfg:         cfg,
+		databaseURL: databaseURL,
+		log:         log.Named("backup"),
+	}
+}
+
+// Run creates and verifies a backup, then applies the retention policy
+func (s *BackupService) Run(ctx context.Context, trigger string) (*BackupManifest, error) {
+	if !s.running.TryLock() {
+		return nil, ErrBackupRunning
+	}
+	defer s.running.Unlock()
+	return s.run(ctx, trigger)
+}
+
+// Start runs a backup in the background and calls done when it finishes.
+// It fails straight away if a backup is already running.
+func (s *BackupService) Start(trigger string, done func(*BackupManifest, error)) error {
+	if !s.running.TryLock() {
+		return ErrBackupRunning
+	}
+	go func() {
+		defer s.running.Unlock()
+		done(s.run(context.Background(), trigger))
+	}()
+	return nil
+}
+
+func (s *BackupService) run(ctx context.Context, trigger string) (*BackupManifest, error) {
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(s.cfg.TimeoutMinutes)*time.Minute)
+	defer cancel()
+
+	manifest, err := s.create(ctx, trigger)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.verify(ctx, manifest); err != nil {
+		s.log.Errorw("Backup failed verification", "backup", manifest.Name, "error", err)
+	}
+	if err := s.saveManifest(ctx, manifest); err != nil {
+		return nil, err
+	}
+
+	if err := s.Prune(ctx); err != nil {
+		s.log.Errorw("Failed to prune old backups", "error", err)
+	}
+	return manifest, nil
+}
+
+// create streams pg_dump's custom-format archive into storage
+func (s *BackupService) create(ctx context.Context, trigger string) (*BackupManifest, error) {
+	start := time.Now().UTC()
+	name := "crapp-" + start.Format("20060102T150405Z")
+
+	dump, err := os.CreateTemp("", "crapp-backup-*.dump")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(dump.Name())
+	defer dump.Close()
+
+	hash := sha256.New()
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, s.cfg.PgDumpPath, "--format=custom", "--no-owner", "--no-privileges", "--dbname", s.databaseURL)
+	cmd.Stdout = io.MultiWriter(dump, hash)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("pg_dump failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	size, err := dump.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := dump.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	manifest := &BackupManifest{
+		Name:          name,
+		Key:           backupPrefix + name + ".dump",
+		Size:          size,
+		SHA256:        hex.EncodeToString(hash.Sum(nil)),
+		CreatedAt:     start,
+		Trigger:       trigger,
+		AppVersion:    buildinfo.Get().Version,
+		SchemaVersion: buildinfo.Get().SchemaVersion,
+	}
+	if err := s.store.Put(ctx, manifest.Key, dump, "application/octet-stream"); err != nil {
+		return nil, fmt.Errorf("uploading backup: %w", err)
+	}
+
+	s.log.Infow("Database backup created", "backup", name, "bytes", size, "duration", time.Since(start), "trigger", trigger)
+	return manifest, nil
+}
+
+// Verify checks a stored backup again and records the result in its manifest
+func (s *BackupService) Verify(ctx context.Context, name string) (*BackupManifest, error) {
+	manifest, err := s.Get(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.verify(ctx, manifest); err != nil {
+		s.log.Errorw("Backup failed verification", "backup", name, "error", err)
+	}
+	return manifest, s.saveManifest(ctx, manifest)
+}
+
+// verify downloads the backup, compares its checksum with the manifest, and
+// asks pg_restore to read the archive's table of contents
+func (s *BackupService) verify(ctx context.Context, manifest *BackupManifest) error {
+	now := time.Now().UTC()
+	manifest.VerifiedAt = &now
+
+	err := s.download(ctx, manifest, func(path string) error {
+		var stderr bytes.Buffer
+		cmd := exec.CommandContext(ctx, s.cfg.PgRestorePath, "--list", path)
+		cmd.Stdout = io.Discard
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("pg_restore could not read the archive: %w: %s", err, strings.TrimSpace(stderr.String()))
+		}
+		return nil
+	})
+
+	manifest.Verified = err == nil
+	manifest.VerifyError = ""
+	if err != nil {
+		manifest.VerifyError = err.Error()
+	}
+	return err
+}
+
+// download copies the backup to a temporary file, checks its size and
+// checksum, and passes the file to use
+func (s *BackupService) download(ctx context.Context, manifest *BackupManifest, use func(path string) error) error {
+	object, err := s.store.Get(ctx, manifest.Key)
+	if err != nil {
+		return fmt.Errorf("downloading backup: %w", err)
+	}
+	defer object.Close()
+
+	file, err := os.CreateTemp("", "crapp-restore-*.dump")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(file, hash), object)
+	if err != nil {
+		return fmt.Errorf("downloading backup: %w", err)
+	}
+	if size != manifest.Size {
+		return fmt.Errorf("backup is %d bytes, expected %d", size, manifest.Size)
+	}
+	if sum := hex.EncodeToString(hash.Sum(nil)); sum != manifest.SHA256 {
+		return fmt.Errorf("backup checksum %s does not match %s", sum, manifest.SHA256)
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return use(file.Name())
+}
+
+// List returns every backup manifest, newest first
+func (s *BackupService) List(ctx context.Context) ([]BackupManifest, error) {
+	objects, err := s.store.List(ctx, backupPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	var manifests []BackupManifest
+	for _, object := range objects {
+		if !strings.HasSuffix(object.Key, ".json") {
+			continue
+		}
+		name := strings.TrimSuffix(strings.TrimPrefix(object.Key, backupPrefix), ".json")
+		manifest, err := s.Get(ctx, name)
+		if err != nil {
+			s.log.Warnw("Skipping unreadable backup manifest", "key", object.Key, "error", err)
+			continue
+		}
+		manifests = append(manifests, *manifest)
+	}
+
+	sort.Slice(manifests, func(i, j int) bool {
+		return manifests[i].CreatedAt.After(manifests[j].CreatedAt)
+	})
+	return manifests, nil
+}
+
+// Get reads a backup's manifest. The name "latest" resolves to the newest
+// verified backup.
+func (s *BackupService) Get(ctx context.Context, name string) (*BackupManifest, error) {
+	if name == "latest" {
+		manifests, err := s.List(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for i := range manifests {
+			if manifests[i].Verified {
+				return &manifests[i], nil
+			}
+		}
+		return nil, ErrBackupNotFound
+	}
+
+	object, err := s.store.Get(ctx, backupPrefix+name+".json")
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, ErrBackupNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer object.Close()
+
+	var manifest BackupManifest
+	if err := json.NewDecoder(object).Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("reading backup manifest: %w", err)
+	}
+	return &manifest, nil
+}
+
+func (s *BackupService) saveManifest(ctx context.Context, manifest *BackupManifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	return s.store.Put(ctx, backupPrefix+manifest.Name+".json", bytes.NewReader(data), "application/json")
+}
+
+// Prune removes backups past the retention policy. The newest verified
+// backup is never removed.
+func (s *BackupService) Prune(ctx context.Context) error {
+	manifests, err := s.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	keptVerified := false
+	cutoff := time.Now().AddDate(0, 0, -s.cfg.MaxAgeDays)
+	for i, manifest := range manifests {
+		keep := i < s.cfg.KeepLast || (s.cfg.MaxAgeDays > 0 && manifest.CreatedAt.After(cutoff))
+		if manifest.Verified && !keptVerified {
+			keep, keptVerified = true, true
+		}
+		if keep {
+			continue
+		}
+
+		if err := s.store.Delete(ctx, manifest.Key); err != nil {
+			return err
+		}
+		if err := s.store.Delete(ctx, backupPrefix+manifest.Name+".json"); err != nil {
+			return err
+		}
+		s.log.Infow("Removed old backup", "backup", manifest.Name, "created_at", manifest.CreatedAt)
+	}
+	return nil
+}
+
+// Restore replaces the database contents with a verified backup. Existing
+// objects in the backup are dropped and recreated in a single transaction.
+func (s *BackupService) Restore(ctx context.Context, name string) (*BackupManifest, error) {
+	manifest, err := s.Get(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.download(ctx, manifest, func(path string) error {
+		var stderr bytes.Buffer
+		cmd := exec.CommandContext(ctx, s.cfg.PgRestorePath, "--clean", "--if-exists", "--no-owner", "--no-privileges",
+			"--single-transaction", "--exit-on-error", "--dbname", s.databaseURL, path)
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("pg_restore failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.log.Infow("Database restored from backup", "backup", manifest.Name, "created_at", manifest.CreatedAt)
+	return manifest, nil
+}
@@ -0,0 +1,163 @@
+package services
+
+import (
+	"math"
+	"sort"
+)
+
+// DefaultMinSegment is the fewest points a segment between change points may have
+const DefaultMinSegment = 3
+
+// ChangePoint is where a series' mean shifts to a new level
+type ChangePoint struct {
+	Index      int     `json:"index"` // First point of the new segment
+	BeforeMean float64 `json:"before_mean"`
+	AfterMean  float64 `json:"after_mean"`
+	Change     float64 `json:"change"`
+	Confidence float64 `json:"confidence"` // 0-1, from a two-sample z-test of the adjacent segments
+}
+
+// Segment is a run of points between change points
+type Segment struct {
+	Start int     `json:"start"`
+	End   int     `json:"end"` // Exclusive
+	Mean  float64 `json:"mean"`
+}
+
+// ChangePointResult is the outcome of change-point detection on a series
+type ChangePointResult struct {
+	Method       string        `json:"method"`
+	Penalty      float64       `json:"penalty"`
+	MinSegment   int           `json:"min_segment"`
+	Sigma        float64       `json:"sigma"` // Estimated noise level
+	ChangePoints []ChangePoint `json:"change_points"`
+	Segments     []Segment     `json:"segments"`
+}
+
+// DetectChangePoints finds shifts in the mean of a series with PELT (pruned
+// exact linear time) under a Gaussian cost. The noise level is estimated from
+// differences between neighbouring points so level shifts don't inflate it.
+// A penalty of zero uses 2·ln(n), the BIC for a mean change; larger penalties
+// find fewer change points.
+func DetectChangePoints(series []float64, penalty float64, minSegment int) *ChangePointResult {
+	n := len(series)
+	if minSegment < 1 {
+		minSegment = DefaultMinSegment
+	}
+	if penalty <= 0 {
+		penalty = 2 * math.Log(math.Max(float64(n), 2))
+	}
+
+	result := &ChangePointResult{
+		Method:       "pelt",
+		Penalty:      penalty,
+		MinSegment:   minSegment,
+		ChangePoints: []ChangePoint{},
+		Segments:     []Segment{},
+	}
+	if n == 0 {
+		return result
+	}
+
+	sigma := estimateNoise(series)
+	result.Sigma = sigma
+
+	// Prefix sums give each segment's cost in constant time
+	sum := make([]float64, n+1)
+	sumSq := make([]float64, n+1)
+	for i, x := range series {
+		sum[i+1] = sum[i] + x
+		sumSq[i+1] = sumSq[i] + x*x
+	}
+	mean := func(s, t int) float64 { return (sum[t] - sum[s]) / float64(t-s) }
+
+	breaks := []int{}
+	if sigma > 0 && n >= 2*minSegment {
+		variance := sigma * sigma
+		cost := func(s, t int) float64 {
+			total := sum[t] - sum[s]
+			return (sumSq[t] - sumSq[s] - total*total/float64(t-s)) / variance
+		}
+
+		best := make([]float64, n+1)
+		last := make([]int, n+1)
+		best[0] = -penalty
+		candidates := []int{0}
+		for t := 1; t <= n; t++ {
+			best[t] = math.Inf(1)
+			for _, s := range candidates {
+				if t-s < minSegment {
+					continue
+				}
+				if c := best[s] + cost(s, t) + penalty; c < best[t] {
+					best[t], last[t] = c, s
+				}
+			}
+
+			// Drop candidates that can never start the optimal last segment
+			kept := candidates[:0]
+			for _, s := range candidates {
+				if t-s < minSegment || best[s]+cost(s, t) <= best[t] {
+					kept = append(kept, s)
+				}
+			}
+			candidates = kept
+			if !math.IsInf(best[t], 1) {
+				candidates = append(candidates, t)
+			}
+		}
+
+		for t := last[n]; t > 0; t = last[t] {
+			breaks = append(breaks, t)
+		}
+		sort.Ints(breaks)
+	}
+
+	bounds := append(append([]int{0}, breaks...), n)
+	for i := 0; i+1 < len(bounds); i++ {
+		result.Segments = append(result.Segments, Segment{
+			Start: bounds[i],
+			End:   bounds[i+1],
+			Mean:  mean(bounds[i], bounds[i+1]),
+		})
+	}
+	for i := 1; i < len(result.Segments); i++ {
+		before, after := result.Segments[i-1], result.Segments[i]
+		n1, n2 := float64(before.End-before.Start), float64(after.End-after.Start)
+		z := math.Abs(after.Mean-before.Mean) / (sigma * math.Sqrt(1/n1+1/n2))
+		result.ChangePoints = append(result.ChangePoints, ChangePoint{
+			Index:      after.Start,
+			BeforeMean: before.Mean,
+			AfterMean:  after.Mean,
+			Change:     after.Mean - before.Mean,
+			Confidence: math.Round(math.Erf(z/math.Sqrt2)*1000) / 1000,
+		})
+	}
+	return result
+}
+
+// estimateNoise estimates the series' standard deviation from the median
+// absolute difference between neighbours. Ordinal answers often repeat, so
+// when that is zero the root mean square difference is used instead.
+func estimateNoise(series []float64) float64 {
+	if len(series) < 2 {
+		return 0
+	}
+	diffs := make([]float64, len(series)-1)
+	var squares float64
+	for i := 1; i < len(series); i++ {
+		diffs[i-1] = math.Abs(series[i] - series[i-1])
+		squares += diffs[i-1] * diffs[i-1]
+	}
+	sort.Float64s(diffs)
+
+	median := diffs[len(diffs)/2]
+	if len(diffs)%2 == 0 {
+		median = (diffs[len(diffs)/2-1] + diffs[len(diffs)/2]) / 2
+	}
+	if median > 0 {
+		// Differences of independent noise have √2 times its spread
+		return median / (0.6745 * math.Sqrt2)
+	}
+	return math.Sqrt(squares / float64(len(diffs)) / 2)
+}
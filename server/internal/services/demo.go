@@ -0,0 +1,156 @@
+package services
+
+import (
+	"math"
+	mathrand "math/rand/v2"
+	"slices"
+	"time"
+
+	"github.com/andevellicus/crapp/internal/config"
+	"github.com/andevellicus/crapp/internal/models"
+	"github.com/andevellicus/crapp/internal/repository"
+	"github.com/andevellicus/crapp/internal/utils"
+	"go.uber.org/zap"
+)
+
+// demoSkipRate is the share of days a synthetic history leaves out, so
+// adherence looks like a real participant's
+const demoSkipRate = 0.15
+
+// DemoService runs the public demo. Sign-ups get a synthetic history to
+// explore, and every sandbox account is purged each night.
+type DemoService struct {
+	repo      *repository.Repository
+	log       *zap.SugaredLogger
+	questions *utils.QuestionLoader
+	cfg       *config.DemoConfig
+}
+
+// NewDemoService creates a new demo service
+func NewDemoService(repo *repository.Repository, log *zap.SugaredLogger, questions *utils.QuestionLoader, cfg *config.DemoConfig) *DemoService {
+	return &DemoService{
+		repo:      repo,
+		log:       log.Named("demo"),
+		questions: questions,
+		cfg:       cfg,
+	}
+}
+
+// Seed gives a new sandbox user a synthetic history: an assessment on most
+// of the last SyntheticDays, with every radio question answered. Symptoms
+// drift from day to day, and the mouse metrics worsen as they do, so the
+// charts and insights have something to show.
+func (s *DemoService) Seed(email string) error {
+	if s.cfg.SyntheticDays <= 0 {
+		return nil
+	}
+
+	var questions []utils.Question
+	for _, q := range s.questions.GetRadioQuestions() {
+		if !q.Honeypot && len(demoOptionValues(q)) > 1 {
+			questions = append(questions, q)
+		}
+	}
+	if len(questions) == 0 {
+		return nil
+	}
+
+	// Each question's severity, from 0 (mildest option) to 1, walks randomly
+	severity := make([]float64, len(questions))
+	for i := range severity {
+		severity[i] = mathrand.Float64() * 0.6
+	}
+
+	days := s.repo.AssessmentDay()
+	today := days.Today()
+	assessments := make([]repository.SyntheticAssessment, 0, s.cfg.SyntheticDays)
+	for offset := s.cfg.SyntheticDays; offset >= 1; offset-- {
+		for i := range severity {
+			severity[i] = math.Min(1, math.Max(0, severity[i]+mathrand.NormFloat64()*0.12))
+		}
+		if mathrand.Float64() < demoSkipRate {
+			continue
+		}
+
+		day := days.AddDays(today, -offset)
+		submittedAt := days.Start(day).Add(18*time.Hour + time.Duration(mathrand.IntN(240))*time.Minute)
+		synthetic := repository.SyntheticAssessment{SubmittedAt: submittedAt, Day: day}
+		for i, q := range questions {
+			values := demoOptionValues(q)
+			value := values[int(math.Round(severity[i]*float64(len(values)-1)))]
+			synthetic.Responses = append(synthetic.Responses, models.QuestionResponse{
+				QuestionID:   q.ID,
+				ValueType:    "number",
+				NumericValue: value,
+				CreatedAt:    submittedAt,
+			})
+			for key, metric := range demoMetrics(severity[i]) {
+				synthetic.Metrics = append(synthetic.Metrics, models.AssessmentMetric{
+					QuestionID:  q.ID,
+					MetricKey:   key,
+					MetricValue: metric,
+					SampleSize:  1,
+					CreatedAt:   submittedAt,
+				})
+			}
+		}
+		assessments = append(assessments, synthetic)
+	}
+
+	repo := s.repo.ForUser(email)
+	ids, err := repo.Assessments.CreateSynthetic(email, assessments)
+	if err != nil {
+		return err
+	}
+	if err := repo.ChartSummaries.Refresh(ids...); err != nil {
+		// The chart summary job picks these up later
+		s.log.Warnw("Failed to summarize synthetic assessments", "error", err, "email", email)
+	}
+
+	s.log.Infow("Seeded demo account", "email", email, "assessments", len(ids))
+	return nil
+}
+
+// Purge deletes every sandbox account and its data, returning how many
+// were deleted
+func (s *DemoService) Purge() (int, error) {
+	emails, err := s.repo.Users.GetSandboxUsers()
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for _, email := range emails {
+		if err := s.repo.ForUser(email).Users.Delete(email); err != nil {
+			s.log.Errorw("Failed to purge sandbox user", "email", email, "error", err)
+			continue
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+// demoOptionValues returns a radio question's numeric option values in
+// ascending order
+func demoOptionValues(q utils.Question) []float64 {
+	var values []float64
+	for _, option := range q.Options {
+		if v := utils.OptionNumber(option); !math.IsNaN(v) {
+			values = append(values, v)
+		}
+	}
+	slices.Sort(values)
+	return values
+}
+
+// demoMetrics returns plausible mouse metrics for a question answered at a
+// severity from 0 to 1
+func demoMetrics(severity float64) map[string]float64 {
+	noise := func(scale float64) float64 { return mathrand.NormFloat64() * scale }
+	return map[string]float64{
+		"click_precision":             math.Min(1, math.Max(0, 0.9-0.2*severity+noise(0.04))),
+		"path_efficiency":             math.Min(1, math.Max(0, 0.85-0.15*severity+noise(0.05))),
+		"average_velocity":            math.Max(0, 420-120*severity+noise(40)),
+		models.MetricQuestionDuration: math.Max(0.5, 3+4*severity+noise(1)),
+	}
+}
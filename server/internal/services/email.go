@@ -3,13 +3,19 @@ package services
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"html/template"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/andevellicus/crapp/internal/config"
+	"github.com/andevellicus/crapp/internal/models"
+	"github.com/andevellicus/crapp/internal/repository"
+	"github.com/andevellicus/crapp/internal/utils"
 	"github.com/go-mail/mail"
 	"github.com/vanng822/go-premailer/premailer"
 	"go.uber.org/zap"
@@ -18,17 +24,27 @@ import (
 // EmailService handles sending emails
 type EmailService struct {
 	config    *config.EmailConfig
+	branding  *config.BrandingConfig
+	repo      *repository.Repository // Looks up each recipient's organization and study branding
 	log       *zap.SugaredLogger
 	templates map[string]*template.Template
+	smtp      *utils.Resilient
+	timeout   time.Duration
+	tracker   *NotificationTracker // Counts reminder opens and clicks; nil tracks nothing
 }
 
 // NewEmailService creates a new email service
-func NewEmailService(cfg *config.EmailConfig, log *zap.SugaredLogger) *EmailService {
+func NewEmailService(repo *repository.Repository, cfg *config.EmailConfig, branding *config.BrandingConfig, policy *config.OutboundPolicy, log *zap.SugaredLogger, tracker *NotificationTracker) *EmailService {
 	service := &EmailService{
 		config:    cfg,
+		branding:  branding,
+		repo:      repo,
 		log:       log.Named("email"),
 		templates: make(map[string]*template.Template),
+		tracker:   tracker,
 	}
+	service.smtp = newResilient("smtp", policy, service.log)
+	service.timeout = time.Duration(policy.TimeoutSeconds) * time.Second
 
 	// Load all email templates with CSS already inlined
 	service.loadEmailTemplates()
@@ -36,19 +52,45 @@ func NewEmailService(cfg *config.EmailConfig, log *zap.SugaredLogger) *EmailServ
 	return service
 }
 
+// Failing reports whether recent sends have been failing
+func (s *EmailService) Failing() bool {
+	return s.smtp.Failing()
+}
+
 // SendEmail sends an email with the given parameters
 func (s *EmailService) SendEmail(to string, subject string, htmlBody string, textBody string) error {
+	return s.sendEmail(to, subject, htmlBody, textBody, false)
+}
+
+// sendEmail sends an email, marked high priority for mail clients when urgent
+func (s *EmailService) sendEmail(to, subject, htmlBody, textBody string, urgent bool) error {
 	m := mail.NewMessage()
 	m.SetHeader("From", fmt.Sprintf("%s <%s>", s.config.FromName, s.config.FromEmail))
 	m.SetHeader("To", to)
 	m.SetHeader("Subject", subject)
+	if urgent {
+		m.SetHeader("X-Priority", "1")
+		m.SetHeader("Importance", "high")
+	}
 	m.SetBody("text/plain", textBody)
 	m.AddAlternative("text/html", htmlBody)
 
 	d := mail.NewDialer(s.config.SMTPHost, s.config.SMTPPort, s.config.SMTPUsername, s.config.SMTPPassword)
 	d.StartTLSPolicy = mail.MandatoryStartTLS
+	if s.timeout > 0 {
+		// Bound socket I/O so attempts abandoned by the resilience timeout exit
+		d.Timeout = s.timeout
+	}
 
-	if err := d.DialAndSend(m); err != nil {
+	err := s.smtp.Do(func() error {
+		return d.DialAndSend(m)
+	})
+	if errors.Is(err, utils.ErrCircuitOpen) {
+		// The breaker already logged the outage; don't log every skipped email
+		s.log.Debugw("Skipped email while SMTP is unavailable", "to", to)
+		return err
+	}
+	if err != nil {
 		s.log.Errorw("Failed to send email", "error", err, "to", to)
 		return err
 	}
@@ -57,30 +99,69 @@ func (s *EmailService) SendEmail(to string, subject string, htmlBody string, tex
 	return nil
 }
 
-// SendPasswordResetEmail sends a password reset email
-func (s *EmailService) SendPasswordResetEmail(to string, resetToken string) error {
-	subject := "Reset Your CRAPP Password"
+// SendPasswordResetEmail sends a password reset email. The code is optional
+// and offered as an alternative to the link when set.
+func (s *EmailService) SendPasswordResetEmail(to string, resetToken string, code string, expiresMinutes int) error {
+	brand := s.brandFor(to)
+	subject := fmt.Sprintf("Reset Your %s Password", brand.ShortName)
 	resetLink := fmt.Sprintf("%s/reset-password?token=%s", s.config.AppURL, resetToken)
 
 	// Prepare data for template
 	data := map[string]string{
-		"ResetLink": resetLink,
-		"AppURL":    s.config.AppURL,
+		"ResetLink":      resetLink,
+		"ResetCode":      code,
+		"ExpiresMinutes": strconv.Itoa(expiresMinutes),
+		"AppURL":         s.config.AppURL,
 	}
 
-	textBody := fmt.Sprintf("Reset your CRAPP password by clicking this link: %s\n\nIf you did not request a password reset, please ignore this email.", resetLink)
+	textBody := fmt.Sprintf("Reset your %s password by clicking this link: %s", brand.ShortName, resetLink)
+	if code != "" {
+		textBody += fmt.Sprintf("\n\nOr enter this code: %s", code)
+	}
+	textBody += fmt.Sprintf("\n\nThis expires in %d minutes. If you did not request a password reset, please ignore this email.", expiresMinutes)
 	// Render HTML template using the stored template with CSS inlined
-	htmlBody, err := s.renderTemplate("password_reset", data)
+	htmlBody, err := s.renderTemplate("password_reset", brand, data)
 	if err != nil {
 		s.log.Errorw("Failed to render password reset template", "error", err)
-		htmlBody = fmt.Sprintf("<html><body><h1>Welcome to CRAPP</h1><p>%s</p></body></html>", textBody)
+		htmlBody = fmt.Sprintf("<html><body><h1>Reset Your %s Password</h1><p>%s</p></body></html>", brand.ShortName, textBody)
+	}
+	return s.SendEmail(to, subject, htmlBody, textBody)
+}
+
+// SendPasswordChangedEmail tells a user their password was reset
+func (s *EmailService) SendPasswordChangedEmail(to string, sessionsRevoked bool) error {
+	brand := s.brandFor(to)
+	subject := fmt.Sprintf("Your %s password was changed", brand.ShortName)
+
+	signedOut := ""
+	if sessionsRevoked {
+		signedOut = "true"
+	}
+
+	// Prepare data for template
+	data := map[string]string{
+		"SignedOut": signedOut,
+		"AppURL":    s.config.AppURL,
+	}
+
+	textBody := fmt.Sprintf("The password for your %s account was just reset.", brand.ShortName)
+	if sessionsRevoked {
+		textBody += " For your security, you have been signed out on all devices."
+	}
+	textBody += " If you did not do this, reset your password immediately and contact support."
+	// Render HTML template with CSS inlined
+	htmlBody, err := s.renderTemplate("password_changed", brand, data)
+	if err != nil {
+		s.log.Errorw("Failed to render password changed email", "error", err)
+		htmlBody = fmt.Sprintf("<html><body><h1>Password Changed</h1><p>%s</p></body></html>", textBody)
 	}
 	return s.SendEmail(to, subject, htmlBody, textBody)
 }
 
 // SendWelcomeEmail sends a welcome email after registration
 func (s *EmailService) SendWelcomeEmail(to string, firstName string) error {
-	subject := "Welcome to CRAPP - Cognitive Reporting Application"
+	brand := s.brandFor(to)
+	subject := fmt.Sprintf("Welcome to %s", brand.DisplayName)
 
 	// Prepare data for template
 	data := map[string]string{
@@ -88,20 +169,83 @@ func (s *EmailService) SendWelcomeEmail(to string, firstName string) error {
 		"AppURL":    s.config.AppURL,
 	}
 
-	textBody := fmt.Sprintf("Welcome to CRAPP, %s! Thank you for registering. Visit %s to log in and complete your first assessment.",
-		firstName, s.config.AppURL)
+	textBody := fmt.Sprintf("Welcome to %s, %s! Thank you for registering. Visit %s to log in and complete your first assessment.",
+		brand.ShortName, firstName, s.config.AppURL)
 	// Render HTML template with CSS inlined
-	htmlBody, err := s.renderTemplate("welcome", data)
+	htmlBody, err := s.renderTemplate("welcome", brand, data)
 	if err != nil {
 		s.log.Errorw("Failed to render welcome email", "error", err)
-		htmlBody = fmt.Sprintf("<html><body><h1>Welcome to CRAPP</h1><p>%s</p></body></html>", textBody)
+		htmlBody = fmt.Sprintf("<html><body><h1>Welcome to %s</h1><p>%s</p></body></html>", brand.ShortName, textBody)
 	}
 	return s.SendEmail(to, subject, htmlBody, textBody)
 }
 
-// SendReminderEmail sends a reminder to complete the daily assessment
-func (s *EmailService) SendReminderEmail(to string, firstName string) error {
-	subject := "Daily Assessment Reminder - CRAPP"
+// SendReminderEmail sends a reminder to complete the daily assessment, with
+// copy already rendered for the recipient. When tracking is on, its link
+// counts clicks and a pixel counts opens.
+func (s *EmailService) SendReminderEmail(to string, content ReminderContent) error {
+	brand := s.brandFor(to)
+	appURL := strings.TrimSuffix(s.config.AppURL, "/")
+	messageID := s.tracker.NewMessage()
+	reminderURL := s.config.AppURL
+	if click := s.tracker.ClickURL(messageID, "/"); click != "" {
+		reminderURL = appURL + click
+	}
+	trackingPixel := ""
+	if open := s.tracker.OpenURL(messageID); open != "" {
+		trackingPixel = appURL + open
+	}
+
+	// Prepare data for template
+	data := map[string]string{
+		"Message":       content.Body,
+		"AppURL":        s.config.AppURL,
+		"ReminderURL":   reminderURL,
+		"TrackingPixel": trackingPixel,
+	}
+
+	textBody := fmt.Sprintf("%s Visit %s to log in.", content.Body, reminderURL)
+	// Render HTML template with CSS inlined
+	htmlBody, err := s.renderTemplate("reminder", brand, data)
+	if err != nil {
+		s.log.Errorw("Failed to render reminder email", "error", err)
+		htmlBody = fmt.Sprintf("<html><body><h1>%s Daily Reminder</h1><p>%s</p></body></html>", brand.ShortName, textBody)
+	}
+	if err := s.SendEmail(to, content.Subject, htmlBody, textBody); err != nil {
+		return err
+	}
+	s.tracker.Sent(messageID, models.NotificationChannelEmail, to)
+	return nil
+}
+
+// SendCaregiverInviteEmail invites a caregiver to report on a participant's behalf
+func (s *EmailService) SendCaregiverInviteEmail(to string, patientName string, inviteToken string) error {
+	brand := s.brandFor(to)
+	subject := fmt.Sprintf("You've been invited as a caregiver - %s", brand.ShortName)
+	acceptLink := fmt.Sprintf("%s/caregiver/accept?token=%s", s.config.AppURL, inviteToken)
+
+	// Prepare data for template
+	data := map[string]string{
+		"PatientName": patientName,
+		"AcceptLink":  acceptLink,
+		"AppURL":      s.config.AppURL,
+	}
+
+	textBody := fmt.Sprintf("%s has invited you to complete %s reports on their behalf. Review the invitation here: %s\n\nIf you were not expecting this invitation, please ignore this email.",
+		patientName, brand.ShortName, acceptLink)
+	// Render HTML template with CSS inlined
+	htmlBody, err := s.renderTemplate("caregiver_invite", brand, data)
+	if err != nil {
+		s.log.Errorw("Failed to render caregiver invite email", "error", err)
+		htmlBody = fmt.Sprintf("<html><body><h1>%s Caregiver Invitation</h1><p>%s</p></body></html>", brand.ShortName, textBody)
+	}
+	return s.SendEmail(to, subject, htmlBody, textBody)
+}
+
+// SendAccountPausedEmail confirms that a user paused their account
+func (s *EmailService) SendAccountPausedEmail(to string, firstName string) error {
+	brand := s.brandFor(to)
+	subject := fmt.Sprintf("Your account is paused - %s", brand.ShortName)
 
 	// Prepare data for template
 	data := map[string]string{
@@ -109,17 +253,185 @@ func (s *EmailService) SendReminderEmail(to string, firstName string) error {
 		"AppURL":    s.config.AppURL,
 	}
 
-	textBody := fmt.Sprintf("Hi %s, this is a reminder to complete your daily assessment on CRAPP. Visit %s to log in.",
-		firstName, s.config.AppURL)
+	textBody := fmt.Sprintf("Hi %s, your %s account is paused. You won't receive reminders, and your data is kept safe. To come back, log in at %s and we'll email you a link to reactivate your account.",
+		firstName, brand.ShortName, s.config.AppURL)
 	// Render HTML template with CSS inlined
-	htmlBody, err := s.renderTemplate("reminder", data)
+	htmlBody, err := s.renderTemplate("account_paused", brand, data)
 	if err != nil {
-		s.log.Errorw("Failed to render reminder email", "error", err)
-		htmlBody = fmt.Sprintf("<html><body><h1>CRAPP Daily Reminder</h1><p>%s</p></body></html>", textBody)
+		s.log.Errorw("Failed to render account paused email", "error", err)
+		htmlBody = fmt.Sprintf("<html><body><h1>Account Paused</h1><p>%s</p></body></html>", textBody)
+	}
+	return s.SendEmail(to, subject, htmlBody, textBody)
+}
+
+// SendReactivationEmail sends a paused user a link to resume their account
+func (s *EmailService) SendReactivationEmail(to string, firstName string, reactivationToken string) error {
+	brand := s.brandFor(to)
+	subject := fmt.Sprintf("Reactivate your account - %s", brand.ShortName)
+	reactivateLink := fmt.Sprintf("%s/reactivate?token=%s", s.config.AppURL, reactivationToken)
+
+	// Prepare data for template
+	data := map[string]string{
+		"FirstName":      firstName,
+		"ReactivateLink": reactivateLink,
+		"AppURL":         s.config.AppURL,
+	}
+
+	textBody := fmt.Sprintf("Hi %s, someone tried to log in to your paused %s account. To reactivate it, open this link and then log in again: %s\n\nIf this wasn't you, ignore this email and your account stays paused.",
+		firstName, brand.ShortName, reactivateLink)
+	// Render HTML template with CSS inlined
+	htmlBody, err := s.renderTemplate("reactivate", brand, data)
+	if err != nil {
+		s.log.Errorw("Failed to render reactivation email", "error", err)
+		htmlBody = fmt.Sprintf("<html><body><h1>Reactivate Your Account</h1><p>%s</p></body></html>", textBody)
+	}
+	return s.SendEmail(to, subject, htmlBody, textBody)
+}
+
+// SendLoginConfirmationEmail asks the user to confirm a sign-in from an unusual location
+func (s *EmailService) SendLoginConfirmationEmail(to string, firstName string, location string, confirmToken string) error {
+	brand := s.brandFor(to)
+	subject := fmt.Sprintf("Confirm your sign-in - %s", brand.ShortName)
+	confirmLink := fmt.Sprintf("%s/login/confirm?token=%s", s.config.AppURL, confirmToken)
+
+	// Prepare data for template
+	data := map[string]string{
+		"FirstName":   firstName,
+		"Location":    location,
+		"ConfirmLink": confirmLink,
+		"AppURL":      s.config.AppURL,
+	}
+
+	textBody := fmt.Sprintf("Hi %s, we noticed a sign-in to your %s account from %s. If this was you, confirm it here: %s\n\nIf this wasn't you, do not click the link and change your password right away.",
+		firstName, brand.ShortName, location, confirmLink)
+	// Render HTML template with CSS inlined
+	htmlBody, err := s.renderTemplate("login_confirm", brand, data)
+	if err != nil {
+		s.log.Errorw("Failed to render login confirmation email", "error", err)
+		htmlBody = fmt.Sprintf("<html><body><h1>Confirm Your Sign-In</h1><p>%s</p></body></html>", textBody)
+	}
+	return s.SendEmail(to, subject, htmlBody, textBody)
+}
+
+// SendInvitationEmail invites an imported participant to set their password
+func (s *EmailService) SendInvitationEmail(to string, firstName string, setupToken string) error {
+	brand := s.brandFor(to)
+	subject := fmt.Sprintf("You're invited to %s", brand.ShortName)
+	setupLink := fmt.Sprintf("%s/reset-password?token=%s", s.config.AppURL, setupToken)
+
+	// Prepare data for template
+	data := map[string]string{
+		"FirstName": firstName,
+		"SetupLink": setupLink,
+		"AppURL":    s.config.AppURL,
+	}
+
+	textBody := fmt.Sprintf("Hi %s, an account has been created for you on %s. Choose your password here to get started: %s\n\nThis link expires in 7 days.",
+		firstName, brand.ShortName, setupLink)
+	// Render HTML template with CSS inlined
+	htmlBody, err := s.renderTemplate("invitation", brand, data)
+	if err != nil {
+		s.log.Errorw("Failed to render invitation email", "error", err)
+		htmlBody = fmt.Sprintf("<html><body><h1>Welcome to %s</h1><p>%s</p></body></html>", brand.ShortName, textBody)
 	}
 	return s.SendEmail(to, subject, htmlBody, textBody)
 }
 
+// SendTemporaryPasswordEmail sends an imported participant their initial password
+func (s *EmailService) SendTemporaryPasswordEmail(to string, firstName string, password string) error {
+	brand := s.brandFor(to)
+	subject := fmt.Sprintf("Your %s account", brand.ShortName)
+
+	// Prepare data for template
+	data := map[string]string{
+		"FirstName":         firstName,
+		"Email":             to,
+		"TemporaryPassword": password,
+		"AppURL":            s.config.AppURL,
+	}
+
+	textBody := fmt.Sprintf("Hi %s, an account has been created for you on %s.\n\nEmail: %s\nTemporary password: %s\n\nSign in at %s and change your password from your profile.",
+		firstName, brand.ShortName, to, password, s.config.AppURL)
+	// Render HTML template with CSS inlined
+	htmlBody, err := s.renderTemplate("temporary_password", brand, data)
+	if err != nil {
+		s.log.Errorw("Failed to render temporary password email", "error", err)
+		htmlBody = fmt.Sprintf("<html><body><h1>Welcome to %s</h1><p>%s</p></body></html>", brand.ShortName, textBody)
+	}
+	return s.SendEmail(to, subject, htmlBody, textBody)
+}
+
+// SendReengagementEmail invites an inactive user back to the app
+func (s *EmailService) SendReengagementEmail(to string, firstName string, daysInactive int) error {
+	brand := s.brandFor(to)
+	subject := fmt.Sprintf("We miss you - %s", brand.ShortName)
+
+	// Prepare data for template
+	data := map[string]string{
+		"FirstName":    firstName,
+		"DaysInactive": strconv.Itoa(daysInactive),
+		"AppURL":       s.config.AppURL,
+	}
+
+	textBody := fmt.Sprintf("Hi %s, we noticed you haven't used %s in %d days. Your regular reports help build an accurate picture of your symptoms. Visit %s to pick up where you left off.",
+		firstName, brand.ShortName, daysInactive, s.config.AppURL)
+	// Render HTML template with CSS inlined
+	htmlBody, err := s.renderTemplate("reengagement", brand, data)
+	if err != nil {
+		s.log.Errorw("Failed to render re-engagement email", "error", err)
+		htmlBody = fmt.Sprintf("<html><body><h1>We Miss You</h1><p>%s</p></body></html>", textBody)
+	}
+	return s.SendEmail(to, subject, htmlBody, textBody)
+}
+
+// SendSymptomAlertEmail tells an admin that a participant crossed a symptom
+// threshold. Participant details stay in the dashboard, out of the email.
+func (s *EmailService) SendSymptomAlertEmail(to string, flagID uint, thresholdName string, days int, startDay, endDay time.Time) error {
+	brand := s.brandFor(to)
+	subject := fmt.Sprintf("Symptom threshold crossed - %s", brand.ShortName)
+
+	data := map[string]string{
+		"FlagID":        strconv.FormatUint(uint64(flagID), 10),
+		"ThresholdName": thresholdName,
+		"Days":          strconv.Itoa(days),
+		"StartDay":      startDay.Format("Jan 2, 2006"),
+		"EndDay":        endDay.Format("Jan 2, 2006"),
+		"AppURL":        s.config.AppURL + "/admin/charts",
+	}
+
+	textBody := fmt.Sprintf("A participant's answers met the threshold %q on %d consecutive days, from %s to %s. Review flag #%d at %s.",
+		thresholdName, days, data["StartDay"], data["EndDay"], flagID, data["AppURL"])
+	htmlBody, err := s.renderTemplate("symptom_alert", brand, data)
+	if err != nil {
+		s.log.Errorw("Failed to render symptom alert email", "error", err)
+		htmlBody = fmt.Sprintf("<html><body><h1>Symptom Threshold Crossed</h1><p>%s</p></body></html>", textBody)
+	}
+	return s.SendEmail(to, subject, htmlBody, textBody)
+}
+
+// SendRedFlagAlertEmail sends a high-priority email about a red-flag answer
+func (s *EmailService) SendRedFlagAlertEmail(to string, alertID uint, questionTitle string, value float64, submittedAt time.Time) error {
+	brand := s.brandFor(to)
+	subject := fmt.Sprintf("Urgent: red-flag answer - %s", brand.ShortName)
+
+	data := map[string]string{
+		"AlertID":       strconv.FormatUint(uint64(alertID), 10),
+		"QuestionTitle": questionTitle,
+		"Value":         strconv.FormatFloat(value, 'f', -1, 64),
+		"SubmittedAt":   submittedAt.Format("Jan 2, 2006 15:04 MST"),
+		"AppURL":        s.config.AppURL + "/admin/charts",
+	}
+
+	textBody := fmt.Sprintf("A participant just answered %q with %s, at or above its red-flag level, at %s. Review alert #%d at %s.",
+		questionTitle, data["Value"], data["SubmittedAt"], alertID, data["AppURL"])
+	htmlBody, err := s.renderTemplate("red_flag_alert", brand, data)
+	if err != nil {
+		s.log.Errorw("Failed to render red-flag alert email", "error", err)
+		htmlBody = fmt.Sprintf("<html><body><h1>Urgent: Red-Flag Answer</h1><p>%s</p></body></html>", textBody)
+	}
+	return s.sendEmail(to, subject, htmlBody, textBody, true)
+}
+
 // inlineCSS applies CSS rules directly to HTML elements using Premailer
 func (s *EmailService) inlineCSS(htmlContent, cssContent string) string {
 	// First, inject the CSS if it's not already there
@@ -198,15 +510,58 @@ func (s *EmailService) loadEmailTemplates() {
 	}
 }
 
-// renderTemplate renders an email template with the provided data
-func (s *EmailService) renderTemplate(templateName string, data any) (string, error) {
+// brandFor returns the branding of the organization and study an email's
+// recipient belongs to. Addresses without an account get the deployment's.
+func (s *EmailService) brandFor(to string) config.BrandingConfig {
+	if s.repo == nil {
+		return s.branding.ForStudy("")
+	}
+	user, err := s.repo.Users.GetByEmail(to)
+	if err != nil {
+		return s.branding.ForStudy("")
+	}
+	return userBranding(s.repo, s.branding, user)
+}
+
+// userBranding applies a user's organization branding and then their
+// study's overrides to the deployment branding
+func userBranding(repo *repository.Repository, base *config.BrandingConfig, user *models.User) config.BrandingConfig {
+	return base.Merge(repo.Organizations.Branding(user.OrganizationID)).ForStudy(user.StudyID)
+}
+
+// brandingData returns the branding values shared by every email template
+func (s *EmailService) brandingData(brand config.BrandingConfig) map[string]string {
+	logoURL := brand.LogoPath
+	if strings.HasPrefix(logoURL, "/") {
+		// Email clients need an absolute URL
+		logoURL = strings.TrimRight(s.config.AppURL, "/") + logoURL
+	}
+
+	return map[string]string{
+		"AppName":      brand.DisplayName,
+		"AppShortName": brand.ShortName,
+		"LogoURL":      logoURL,
+		"PrimaryColor": brand.PrimaryColor,
+		"AccentColor":  brand.AccentColor,
+		"SupportEmail": brand.SupportEmail,
+		"SupportURL":   brand.SupportURL,
+	}
+}
+
+// renderTemplate renders an email template with the provided data and branding
+func (s *EmailService) renderTemplate(templateName string, brand config.BrandingConfig, data map[string]string) (string, error) {
 	tmpl, exists := s.templates[templateName]
 	if !exists {
 		return "", fmt.Errorf("template %s not found", templateName)
 	}
 
+	templateData := s.brandingData(brand)
+	for key, value := range data {
+		templateData[key] = value
+	}
+
 	var buf bytes.Buffer
-	if err := tmpl.Execute(&buf, data); err != nil {
+	if err := tmpl.Execute(&buf, templateData); err != nil {
 		return "", err
 	}
 
@@ -0,0 +1,98 @@
+package services
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/andevellicus/crapp/internal/config"
+)
+
+// Feedback outcomes, which pick the completion message
+const (
+	FeedbackCompleted = "completed"
+	FeedbackStreak    = "streak"
+	FeedbackBetter    = "better"
+	FeedbackWorse     = "worse"
+)
+
+// Feedback is shown on the completion screen after a form is submitted
+type Feedback struct {
+	Streak    int             `json:"streak"`    // Consecutive days completed, including this one
+	Composite *CompositeScore `json:"composite"` // Nil when no symptom questions were answered
+	Outcome   string          `json:"outcome"`
+	Message   string          `json:"message"`
+}
+
+// CompositeScore is the mean of an assessment's symptom ratings, compared
+// with the mean of the user's daily composites over the days before it.
+// Lower scores mean milder symptoms.
+type CompositeScore struct {
+	Score        float64  `json:"score"`
+	Baseline     *float64 `json:"baseline"` // Nil until there are enough baseline days
+	BaselineDays int      `json:"baseline_days"`
+	Change       *float64 `json:"change"`
+	Direction    string   `json:"direction,omitempty"` // better, worse or steady; empty without a baseline
+}
+
+// CompareComposite scores an assessment's symptom ratings against the daily
+// composites before it. It returns nil when there are no ratings.
+func CompareComposite(ratings, baselineDays []float64, cfg *config.FeedbackConfig) *CompositeScore {
+	if len(ratings) == 0 {
+		return nil
+	}
+	composite := &CompositeScore{Score: roundedMean(ratings), BaselineDays: len(baselineDays)}
+	if len(baselineDays) < max(cfg.MinBaselineDays, 1) {
+		return composite
+	}
+
+	baseline := roundedMean(baselineDays)
+	change := math.Round((composite.Score-baseline)*100) / 100
+	composite.Baseline = &baseline
+	composite.Change = &change
+	switch {
+	case change <= -cfg.MinChange:
+		composite.Direction = FeedbackBetter
+	case change >= cfg.MinChange:
+		composite.Direction = FeedbackWorse
+	default:
+		composite.Direction = InsightSteady
+	}
+	return composite
+}
+
+// NewFeedback picks the outcome for a submission and words its message from
+// the configured templates. A clear change in symptoms takes precedence over
+// the streak. Templates fill in {streak} and {first_name}.
+func NewFeedback(streak int, composite *CompositeScore, firstName string, cfg *config.FeedbackConfig) *Feedback {
+	feedback := &Feedback{Streak: streak, Composite: composite, Outcome: FeedbackCompleted}
+	switch {
+	case composite != nil && (composite.Direction == FeedbackBetter || composite.Direction == FeedbackWorse):
+		feedback.Outcome = composite.Direction
+	case streak >= 2:
+		feedback.Outcome = FeedbackStreak
+	}
+
+	template, ok := cfg.Messages[feedback.Outcome]
+	if !ok {
+		template = cfg.Messages[FeedbackCompleted]
+	}
+	message := strings.NewReplacer(
+		"{streak}", strconv.Itoa(streak),
+		"{first_name}", firstName,
+	).Replace(template)
+	// Tidy up after an empty name, as in "Well done, !"
+	message = strings.ReplaceAll(message, " ,", ",")
+	message = strings.ReplaceAll(message, ", !", "!")
+	feedback.Message = strings.TrimSpace(message)
+	return feedback
+}
+
+// roundedMean averages values, which must not be empty, to two decimals
+func roundedMean(values []float64) float64 {
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	return math.Round(sum/float64(len(values))*100) / 100
+}
@@ -0,0 +1,180 @@
+package services
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"sort"
+	"strconv"
+
+	"go.uber.org/zap"
+)
+
+// GeoLocation is a coarse, city-level location for an IP address
+type GeoLocation struct {
+	Country   string  `json:"country"`
+	City      string  `json:"city,omitempty"`
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// geoBlock maps a network to a location
+type geoBlock struct {
+	prefix   netip.Prefix
+	location GeoLocation
+}
+
+// GeoIPService resolves IP addresses using the offline MaxMind GeoLite2 City CSV database
+type GeoIPService struct {
+	blocks []geoBlock // Sorted by network start address
+	log    *zap.SugaredLogger
+}
+
+// NewGeoIPService loads the GeoLite2 locations file and one or more blocks files.
+// Missing files are skipped so IPv4-only installs still work.
+func NewGeoIPService(blocksFiles []string, locationsFile string, log *zap.SugaredLogger) (*GeoIPService, error) {
+	s := &GeoIPService{log: log.Named("geoip")}
+
+	locations, err := loadGeoLocations(locationsFile)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, file := range blocksFiles {
+		blocks, err := loadGeoBlocks(file, locations)
+		if errors.Is(err, os.ErrNotExist) {
+			s.log.Warnw("GeoIP blocks file not found, skipping", "file", file)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		s.blocks = append(s.blocks, blocks...)
+	}
+
+	if len(s.blocks) == 0 {
+		return nil, fmt.Errorf("no GeoIP blocks loaded")
+	}
+
+	sort.Slice(s.blocks, func(i, j int) bool {
+		return s.blocks[i].prefix.Addr().Less(s.blocks[j].prefix.Addr())
+	})
+
+	s.log.Infow("GeoIP database loaded", "networks", len(s.blocks))
+	return s, nil
+}
+
+// Lookup returns the location for an IP address, or nil for private,
+// unparseable, or unknown addresses
+func (s *GeoIPService) Lookup(ip string) *GeoLocation {
+	if s == nil {
+		return nil
+	}
+
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return nil
+	}
+	addr = addr.Unmap()
+	if addr.IsPrivate() || addr.IsLoopback() || addr.IsLinkLocalUnicast() {
+		return nil
+	}
+
+	// Find the last block starting at or before the address
+	i := sort.Search(len(s.blocks), func(i int) bool {
+		return addr.Less(s.blocks[i].prefix.Addr())
+	})
+	if i == 0 || !s.blocks[i-1].prefix.Contains(addr) {
+		return nil
+	}
+
+	location := s.blocks[i-1].location
+	return &location
+}
+
+// loadGeoLocations reads geoname IDs to country and city names
+func loadGeoLocations(path string) (map[string]GeoLocation, error) {
+	locations := make(map[string]GeoLocation)
+
+	err := readCSV(path, func(row func(string) string) {
+		locations[row("geoname_id")] = GeoLocation{
+			Country: row("country_iso_code"),
+			City:    row("city_name"),
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load GeoIP locations: %w", err)
+	}
+	return locations, nil
+}
+
+// loadGeoBlocks reads networks and their coordinates
+func loadGeoBlocks(path string, locations map[string]GeoLocation) ([]geoBlock, error) {
+	var blocks []geoBlock
+
+	err := readCSV(path, func(row func(string) string) {
+		prefix, err := netip.ParsePrefix(row("network"))
+		if err != nil {
+			return
+		}
+
+		geonameID := row("geoname_id")
+		if geonameID == "" {
+			geonameID = row("registered_country_geoname_id")
+		}
+
+		location := locations[geonameID]
+		location.Latitude, _ = strconv.ParseFloat(row("latitude"), 64)
+		location.Longitude, _ = strconv.ParseFloat(row("longitude"), 64)
+		if location.Country == "" {
+			return
+		}
+
+		blocks = append(blocks, geoBlock{prefix: prefix.Masked(), location: location})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load GeoIP blocks from %s: %w", path, err)
+	}
+	return blocks, nil
+}
+
+// readCSV calls fn for every data row with a lookup by header column name
+func readCSV(path string, fn func(row func(string) string)) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.ReuseRecord = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return err
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[name] = i
+	}
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		fn(func(name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return record[i]
+			}
+			return ""
+		})
+	}
+}
@@ -0,0 +1,195 @@
+package services
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/andevellicus/crapp/internal/config"
+)
+
+// Insight kinds
+const (
+	InsightMetric  = "metric"  // An interaction or cognitive test metric
+	InsightSymptom = "symptom" // Answers to a rating question
+)
+
+// Insight directions
+const (
+	InsightHigher = "higher"
+	InsightLower  = "lower"
+	InsightSteady = "steady"
+)
+
+// InsightMetrics are the metrics participants get insights about. They are
+// the ones with a plain meaning; every other metric stays on the charts.
+var InsightMetrics = []string{"typing_speed", "click_precision", "reaction_time", "part_b_time", "highest_span"}
+
+// InsightSeries is one series to describe, split into the daily values of its
+// baseline and recent windows
+type InsightSeries struct {
+	Kind     string
+	Key      string // Metric key or question ID
+	Label    string // Question title, for symptoms; metrics are named by the catalog
+	Baseline []float64
+	Recent   []float64
+}
+
+// Insight describes how a series' recent window compares with its baseline
+type Insight struct {
+	Kind         string   `json:"kind"`
+	Key          string   `json:"key"`
+	Direction    string   `json:"direction"`
+	Change       float64  `json:"change"` // Percent for metrics, points for symptoms
+	BaselineMean float64  `json:"baseline_mean"`
+	RecentMean   float64  `json:"recent_mean"`
+	BaselineDays int      `json:"baseline_days"`
+	RecentDays   int      `json:"recent_days"`
+	EffectSize   *float64 `json:"effect_size"`
+	PValue       *float64 `json:"p_value"`
+	Statement    string   `json:"statement"`
+}
+
+// DescribeTrend compares a series' recent window with its baseline. It
+// returns nil when either window has too few days to say anything. The
+// change only counts as higher or lower when Welch's t-test finds it
+// significant, its effect size reaches the minimum, and it is large enough in
+// percent, for metrics, or in points, for symptoms. Anything less is steady,
+// as is a metric whose baseline is zero, since its change has no percentage.
+func DescribeTrend(series InsightSeries, cfg *config.InsightsConfig) *Insight {
+	if len(series.Baseline) < max(cfg.MinBaselineDays, 2) || len(series.Recent) < max(cfg.MinRecentDays, 2) {
+		return nil
+	}
+
+	comparison := ComparePeriods(series.Baseline, series.Recent, cfg.Alpha)
+	insight := &Insight{
+		Kind:         series.Kind,
+		Key:          series.Key,
+		Direction:    InsightSteady,
+		BaselineMean: *comparison.A.Mean,
+		RecentMean:   *comparison.B.Mean,
+		BaselineDays: comparison.A.N,
+		RecentDays:   comparison.B.N,
+		EffectSize:   comparison.EffectSize,
+		PValue:       comparison.PValue,
+	}
+
+	diff := *comparison.Difference
+	large := false
+	if series.Kind == InsightMetric {
+		if insight.BaselineMean != 0 {
+			insight.Change = diff / math.Abs(insight.BaselineMean) * 100
+			large = math.Abs(insight.Change) >= cfg.MinPercentChange
+		}
+	} else {
+		insight.Change = diff
+		large = math.Abs(diff) >= cfg.MinPointChange
+	}
+
+	if large && comparison.Significant &&
+		comparison.EffectSize != nil && math.Abs(*comparison.EffectSize) >= cfg.MinEffectSize {
+		insight.Direction = InsightHigher
+		if diff < 0 {
+			insight.Direction = InsightLower
+		}
+	}
+	return insight
+}
+
+// insightCatalog holds the statement templates and metric names of each
+// language. Templates fill in {label}, {period}, {percent} and {points}.
+var insightCatalog = map[string]map[string]string{
+	"en": {
+		"metric_higher":  "Your {label} has been {percent}% above your usual level {period}.",
+		"metric_lower":   "Your {label} has been {percent}% below your usual level {period}.",
+		"metric_steady":  "Your {label} has been close to your usual level {period}.",
+		"symptom_higher": "Your {label} ratings have been higher than usual {period}, by {points} points on average.",
+		"symptom_lower":  "Your {label} ratings have been lower than usual {period}, by {points} points on average.",
+		"symptom_steady": "Your {label} ratings have been close to usual {period}.",
+		"period_week":    "this week",
+		"period_days":    "over the last {days} days",
+		"disclaimer": "These summaries compare your recent entries with your own earlier ones. " +
+			"They are not a diagnosis. Talk to your care team about anything that worries you.",
+
+		"metric:typing_speed":    "typing speed",
+		"metric:click_precision": "click precision",
+		"metric:reaction_time":   "reaction time",
+		"metric:part_b_time":     "time on the number and letter trail",
+		"metric:highest_span":    "longest remembered digit sequence",
+	},
+	"es": {
+		"metric_higher":  "Tu {label} ha estado un {percent}% por encima de tu nivel habitual {period}.",
+		"metric_lower":   "Tu {label} ha estado un {percent}% por debajo de tu nivel habitual {period}.",
+		"metric_steady":  "Tu {label} ha estado cerca de tu nivel habitual {period}.",
+		"symptom_higher": "Tus valoraciones de {label} han sido más altas de lo habitual {period}, {points} puntos de media.",
+		"symptom_lower":  "Tus valoraciones de {label} han sido más bajas de lo habitual {period}, {points} puntos de media.",
+		"symptom_steady": "Tus valoraciones de {label} han estado cerca de lo habitual {period}.",
+		"period_week":    "esta semana",
+		"period_days":    "en los últimos {days} días",
+		"disclaimer": "Estos resúmenes comparan tus registros recientes con los tuyos anteriores. " +
+			"No son un diagnóstico. Habla con tu equipo de atención sobre cualquier cosa que te preocupe.",
+
+		"metric:typing_speed":    "velocidad de escritura",
+		"metric:click_precision": "precisión al hacer clic",
+		"metric:reaction_time":   "tiempo de reacción",
+		"metric:part_b_time":     "tiempo en el recorrido de números y letras",
+		"metric:highest_span":    "secuencia de dígitos más larga recordada",
+	},
+}
+
+// decimalCommaLanguages write decimals with a comma
+var decimalCommaLanguages = map[string]bool{"es": true}
+
+// InsightLanguage picks the catalog language for a request: the requested
+// language if the catalog has it, otherwise the first supported language in
+// an Accept-Language header, otherwise English
+func InsightLanguage(requested, acceptLanguage string) string {
+	candidates := []string{requested}
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, _, _ := strings.Cut(strings.TrimSpace(part), ";")
+		candidates = append(candidates, tag)
+	}
+	for _, tag := range candidates {
+		primary, _, _ := strings.Cut(strings.ToLower(tag), "-")
+		if _, ok := insightCatalog[primary]; ok {
+			return primary
+		}
+	}
+	return "en"
+}
+
+// InsightDisclaimer is the note shown alongside insights in a language
+func InsightDisclaimer(language string) string {
+	return insightCatalog[language]["disclaimer"]
+}
+
+// InsightStatement words an insight in a language. Symptom labels are the
+// question titles; metric names come from the catalog.
+func InsightStatement(insight *Insight, label string, recentDays int, language string) string {
+	messages := insightCatalog[language]
+
+	if insight.Kind == InsightMetric {
+		if name, ok := messages["metric:"+insight.Key]; ok {
+			label = name
+		}
+	} else {
+		label = strings.ToLower(label)
+	}
+
+	period := messages["period_week"]
+	if recentDays != 7 {
+		period = strings.ReplaceAll(messages["period_days"], "{days}", strconv.Itoa(recentDays))
+	}
+
+	points := strconv.FormatFloat(math.Abs(insight.Change), 'f', 1, 64)
+	if decimalCommaLanguages[language] {
+		points = strings.ReplaceAll(points, ".", ",")
+	}
+
+	return strings.NewReplacer(
+		"{label}", label,
+		"{period}", period,
+		"{percent}", strconv.Itoa(int(math.Round(math.Abs(insight.Change)))),
+		"{points}", points,
+	).Replace(messages[insight.Kind+"_"+insight.Direction])
+}
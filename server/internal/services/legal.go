@@ -0,0 +1,122 @@
+package services
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/andevellicus/crapp/internal/config"
+	"github.com/andevellicus/crapp/internal/models"
+	"github.com/andevellicus/crapp/internal/repository"
+	"go.uber.org/zap"
+)
+
+// LegalDocument is a versioned terms-of-service or privacy policy document
+type LegalDocument struct {
+	Type    string `json:"type"`
+	Version string `json:"version"`
+	Content string `json:"content"`
+}
+
+// LegalService serves the current legal documents and tracks user acceptance
+type LegalService struct {
+	repo      *repository.Repository
+	log       *zap.SugaredLogger
+	config    *config.LegalConfig
+	documents map[string]*LegalDocument
+}
+
+// NewLegalService creates a new legal service, loading the configured documents
+func NewLegalService(repo *repository.Repository, log *zap.SugaredLogger, cfg *config.LegalConfig) *LegalService {
+	service := &LegalService{
+		repo:      repo,
+		log:       log.Named("legal"),
+		config:    cfg,
+		documents: make(map[string]*LegalDocument),
+	}
+
+	service.loadDocument(models.PolicyTerms, cfg.TermsVersion, cfg.TermsFile)
+	service.loadDocument(models.PolicyPrivacy, cfg.PrivacyVersion, cfg.PrivacyFile)
+
+	return service
+}
+
+// IsEnforced reports whether API access requires accepting the current documents
+func (s *LegalService) IsEnforced() bool {
+	return s.config.Enforce
+}
+
+// GetDocument returns the current version of a document
+func (s *LegalService) GetDocument(documentType string) (*LegalDocument, bool) {
+	doc, ok := s.documents[documentType]
+	return doc, ok
+}
+
+// CurrentVersion returns the version users must accept for a document type
+func (s *LegalService) CurrentVersion(documentType string) string {
+	switch documentType {
+	case models.PolicyTerms:
+		return s.config.TermsVersion
+	case models.PolicyPrivacy:
+		return s.config.PrivacyVersion
+	}
+	return ""
+}
+
+// PendingDocuments lists the document types whose current version the user has not accepted
+func (s *LegalService) PendingDocuments(email string) ([]string, error) {
+	pending := []string{}
+	for _, documentType := range []string{models.PolicyTerms, models.PolicyPrivacy} {
+		version := s.CurrentVersion(documentType)
+		if version == "" {
+			continue
+		}
+
+		accepted, err := s.repo.PolicyAcceptances.HasAccepted(email, documentType, version)
+		if err != nil {
+			return nil, err
+		}
+		if !accepted {
+			pending = append(pending, documentType)
+		}
+	}
+	return pending, nil
+}
+
+// Accept records acceptance of a document, which must be the current version
+func (s *LegalService) Accept(email, documentType, version, ipAddress, userAgent string) error {
+	current := s.CurrentVersion(documentType)
+	if current == "" {
+		return fmt.Errorf("unknown document type: %s", documentType)
+	}
+	if version != current {
+		return fmt.Errorf("version %s of %s is no longer current", version, documentType)
+	}
+
+	return s.repo.PolicyAcceptances.Record(&models.PolicyAcceptance{
+		UserEmail:    email,
+		DocumentType: documentType,
+		Version:      version,
+		IPAddress:    ipAddress,
+		UserAgent:    userAgent,
+	})
+}
+
+// loadDocument reads a document's content from disk
+func (s *LegalService) loadDocument(documentType, version, path string) {
+	if version == "" || path == "" {
+		return
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		s.log.Warnw("Failed to read legal document", "type", documentType, "path", path, "error", err)
+		return
+	}
+
+	s.documents[documentType] = &LegalDocument{
+		Type:    documentType,
+		Version: version,
+		Content: string(content),
+	}
+	s.log.Infow("Loaded legal document", "type", documentType, "version", version)
+}
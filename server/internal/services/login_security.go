@@ -0,0 +1,108 @@
+package services
+
+import (
+	"math"
+	"time"
+
+	"github.com/andevellicus/crapp/internal/config"
+	"github.com/andevellicus/crapp/internal/repository"
+	"go.uber.org/zap"
+)
+
+// Suspicious login reasons
+const (
+	LoginReasonNewCountry       = "new_country"
+	LoginReasonImpossibleTravel = "impossible_travel"
+)
+
+// LoginRisk is the outcome of assessing a login attempt
+type LoginRisk struct {
+	Location   *GeoLocation `json:"location,omitempty"`
+	Suspicious bool         `json:"suspicious"`
+	Reasons    []string     `json:"reasons,omitempty"`
+}
+
+// LoginSecurityService flags logins from new countries or with impossible travel
+type LoginSecurityService struct {
+	repo   *repository.Repository
+	geo    *GeoIPService
+	config *config.LoginSecurityConfig
+	log    *zap.SugaredLogger
+}
+
+// NewLoginSecurityService creates a new login security service. Detection is
+// disabled if the GeoIP database cannot be loaded.
+func NewLoginSecurityService(repo *repository.Repository, log *zap.SugaredLogger, cfg *config.LoginSecurityConfig) *LoginSecurityService {
+	s := &LoginSecurityService{
+		repo:   repo,
+		config: cfg,
+		log:    log.Named("login-security"),
+	}
+
+	if cfg.GeoIPEnabled {
+		geo, err := NewGeoIPService(cfg.GeoIPBlocksFiles, cfg.GeoIPLocationsFile, log)
+		if err != nil {
+			s.log.Warnw("GeoIP database unavailable, suspicious login detection disabled", "error", err)
+		} else {
+			s.geo = geo
+		}
+	}
+
+	return s
+}
+
+// RequiresConfirmation reports whether suspicious logins must be confirmed by email
+func (s *LoginSecurityService) RequiresConfirmation() bool {
+	return s.geo != nil && s.config.RequireConfirmation
+}
+
+// ConfirmationTTL returns how long a login confirmation link stays valid
+func (s *LoginSecurityService) ConfirmationTTL() time.Duration {
+	return time.Duration(s.config.ConfirmationMinutes) * time.Minute
+}
+
+// Assess geolocates a login and compares it with the user's previous logins
+func (s *LoginSecurityService) Assess(email, ip string) *LoginRisk {
+	risk := &LoginRisk{Location: s.geo.Lookup(ip)}
+	if risk.Location == nil {
+		return risk
+	}
+
+	previous, err := s.repo.AuditEvents.GetLastLocatedLogin(email)
+	if err != nil || previous == nil {
+		// First located login establishes the baseline
+		return risk
+	}
+
+	seen, err := s.repo.AuditEvents.HasLoginFromCountry(email, risk.Location.Country)
+	if err == nil && !seen {
+		risk.Reasons = append(risk.Reasons, LoginReasonNewCountry)
+	}
+
+	if previous.Latitude != nil && previous.Longitude != nil && s.config.MaxTravelSpeedKmh > 0 {
+		distance := haversineKm(*previous.Latitude, *previous.Longitude, risk.Location.Latitude, risk.Location.Longitude)
+		hours := time.Since(previous.CreatedAt).Hours()
+		// Ignore short hops that fall within city-level accuracy
+		if distance > 100 && (hours <= 0 || distance/hours > s.config.MaxTravelSpeedKmh) {
+			risk.Reasons = append(risk.Reasons, LoginReasonImpossibleTravel)
+		}
+	}
+
+	risk.Suspicious = len(risk.Reasons) > 0
+	if risk.Suspicious {
+		s.log.Infow("Suspicious login detected", "email", email, "country", risk.Location.Country, "reasons", risk.Reasons)
+	}
+	return risk
+}
+
+// haversineKm returns the great-circle distance between two coordinates
+func haversineKm(lat1, lon1, lat2, lon2 float64) float64 {
+	const earthRadiusKm = 6371.0
+	toRad := func(deg float64) float64 { return deg * math.Pi / 180 }
+
+	dLat := toRad(lat2 - lat1)
+	dLon := toRad(lon2 - lon1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRad(lat1))*math.Cos(toRad(lat2))*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return earthRadiusKm * 2 * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+}
@@ -0,0 +1,113 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+
+	"github.com/andevellicus/crapp/internal/config"
+	"github.com/andevellicus/crapp/internal/repository"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// Paths of the endpoints that count reminder clicks and opens
+const (
+	TrackClickPath = "/api/notifications/track/click"
+	TrackOpenPath  = "/api/notifications/track/open"
+)
+
+// NotificationTracker makes the signed links that count how often reminders
+// are opened and clicked. A nil tracker tracks nothing.
+type NotificationTracker struct {
+	repo *repository.Repository
+	log  *zap.SugaredLogger
+	key  []byte
+	cfg  *config.ReminderConfig
+}
+
+// NewNotificationTracker creates a new notification tracker. Links are
+// signed with the secret so their message IDs and destinations can't be
+// forged.
+func NewNotificationTracker(repo *repository.Repository, log *zap.SugaredLogger, secret string, cfg *config.ReminderConfig) *NotificationTracker {
+	return &NotificationTracker{
+		repo: repo,
+		log:  log.Named("tracking"),
+		key:  []byte(secret),
+		cfg:  cfg,
+	}
+}
+
+// NewMessage returns an ID for a reminder about to be sent, or empty when
+// nothing is tracked
+func (t *NotificationTracker) NewMessage() string {
+	if t == nil || (!t.cfg.TrackClicks && !t.cfg.TrackOpens) {
+		return ""
+	}
+	return uuid.NewString()
+}
+
+// Sent records a tracked message as sent to a user
+func (t *NotificationTracker) Sent(messageID, channel, email string) {
+	if t == nil || messageID == "" {
+		return
+	}
+	if err := t.repo.NotificationEvents.RecordSent(messageID, channel, email); err != nil {
+		t.log.Warnw("Failed to record sent reminder", "error", err, "channel", channel)
+	}
+}
+
+// ClickURL returns a server-relative link that counts a click on a message
+// and then redirects to a path in the app. With no path, the link only
+// counts the click. It is empty when clicks aren't tracked.
+func (t *NotificationTracker) ClickURL(messageID, path string) string {
+	if t == nil || messageID == "" || !t.cfg.TrackClicks {
+		return ""
+	}
+	query := url.Values{}
+	query.Set("m", messageID)
+	if path != "" {
+		query.Set("to", path)
+	}
+	query.Set("sig", t.sign("click", messageID, path))
+	return TrackClickPath + "?" + query.Encode()
+}
+
+// OpenURL returns a server-relative tracking pixel that counts a message
+// being opened. It is empty when opens aren't tracked.
+func (t *NotificationTracker) OpenURL(messageID string) string {
+	if t == nil || messageID == "" || !t.cfg.TrackOpens {
+		return ""
+	}
+	query := url.Values{}
+	query.Set("m", messageID)
+	query.Set("sig", t.sign("open", messageID, ""))
+	return TrackOpenPath + "?" + query.Encode()
+}
+
+// VerifyClick checks a click link's signature
+func (t *NotificationTracker) VerifyClick(messageID, path, signature string) bool {
+	return t != nil && messageID != "" && hmac.Equal([]byte(signature), []byte(t.sign("click", messageID, path)))
+}
+
+// VerifyOpen checks a tracking pixel's signature
+func (t *NotificationTracker) VerifyOpen(messageID, signature string) bool {
+	return t != nil && messageID != "" && hmac.Equal([]byte(signature), []byte(t.sign("open", messageID, "")))
+}
+
+// Record records events for a message, such as it being clicked
+func (t *NotificationTracker) Record(messageID string, kinds ...string) {
+	for _, kind := range kinds {
+		if err := t.repo.NotificationEvents.Record(messageID, kind); err != nil {
+			t.log.Warnw("Failed to record reminder engagement", "error", err, "kind", kind)
+			return
+		}
+	}
+}
+
+func (t *NotificationTracker) sign(purpose, messageID, path string) string {
+	mac := hmac.New(sha256.New, t.key)
+	mac.Write([]byte(purpose + "\n" + messageID + "\n" + path))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,152 @@
+package services
+
+import "math"
+
+// PeriodSummary describes a series' values within one period
+type PeriodSummary struct {
+	N    int      `json:"n"`
+	Mean *float64 `json:"mean"`
+	SD   *float64 `json:"sd"` // Sample standard deviation; needs two points
+}
+
+// PeriodComparison compares the values of two periods, such as before and
+// after a medication change
+type PeriodComparison struct {
+	A          PeriodSummary `json:"a"`
+	B          PeriodSummary `json:"b"`
+	Difference *float64      `json:"difference"` // Mean of B minus mean of A
+
+	// Hedges' g: the difference in pooled standard deviations, corrected for
+	// small samples. Around 0.2 is small, 0.5 medium and 0.8 large.
+	EffectSize *float64 `json:"effect_size"`
+
+	// Welch's t-test, which doesn't assume equal variances
+	Test             string   `json:"test"`
+	T                *float64 `json:"t"`
+	DegreesOfFreedom *float64 `json:"degrees_of_freedom"`
+	PValue           *float64 `json:"p_value"` // Two-sided
+	Significant      bool     `json:"significant"`
+	Alpha            float64  `json:"alpha"`
+}
+
+// ComparePeriods summarizes two samples and tests whether their means
+// differ. Statistics that need more data than a period has are left empty.
+func ComparePeriods(a, b []float64, alpha float64) *PeriodComparison {
+	result := &PeriodComparison{
+		A:     summarize(a),
+		B:     summarize(b),
+		Test:  "welch_t",
+		Alpha: alpha,
+	}
+	if result.A.Mean == nil || result.B.Mean == nil {
+		return result
+	}
+	diff := *result.B.Mean - *result.A.Mean
+	result.Difference = &diff
+
+	if result.A.SD == nil || result.B.SD == nil {
+		return result
+	}
+	na, nb := float64(result.A.N), float64(result.B.N)
+	sa, sb := *result.A.SD, *result.B.SD
+	va, vb := sa*sa, sb*sb
+
+	pooled := math.Sqrt(((na-1)*va + (nb-1)*vb) / (na + nb - 2))
+	if pooled > 0 {
+		correction := 1 - 3/(4*(na+nb)-9)
+		g := diff / pooled * correction
+		result.EffectSize = &g
+	}
+
+	se := math.Sqrt(va/na + vb/nb)
+	if se == 0 {
+		return result
+	}
+	t := diff / se
+	df := math.Pow(va/na+vb/nb, 2) / (math.Pow(va/na, 2)/(na-1) + math.Pow(vb/nb, 2)/(nb-1))
+	p := studentTwoSided(t, df)
+	result.T = &t
+	result.DegreesOfFreedom = &df
+	result.PValue = &p
+	result.Significant = p < alpha
+	return result
+}
+
+func summarize(values []float64) PeriodSummary {
+	summary := PeriodSummary{N: len(values)}
+	if len(values) == 0 {
+		return summary
+	}
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	mean := sum / float64(len(values))
+	summary.Mean = &mean
+
+	if len(values) > 1 {
+		var squares float64
+		for _, v := range values {
+			squares += (v - mean) * (v - mean)
+		}
+		sd := math.Sqrt(squares / float64(len(values)-1))
+		summary.SD = &sd
+	}
+	return summary
+}
+
+// studentTwoSided is the two-sided p-value of t under Student's t
+// distribution with df degrees of freedom
+func studentTwoSided(t, df float64) float64 {
+	return regularizedBeta(df/(df+t*t), df/2, 0.5)
+}
+
+// regularizedBeta is the regularized incomplete beta function I_x(a, b),
+// evaluated with Lentz's continued fraction
+func regularizedBeta(x, a, b float64) float64 {
+	if x <= 0 {
+		return 0
+	}
+	if x >= 1 {
+		return 1
+	}
+	// The continued fraction converges quickly only below the mean
+	if x > (a+1)/(a+b+2) {
+		return 1 - regularizedBeta(1-x, b, a)
+	}
+
+	la, _ := math.Lgamma(a)
+	lb, _ := math.Lgamma(b)
+	lab, _ := math.Lgamma(a + b)
+	front := math.Exp(lab-la-lb+a*math.Log(x)+b*math.Log(1-x)) / a
+
+	const tiny = 1e-30
+	f, c, d := 1.0, 1.0, 0.0
+	for i := 0; i <= 200; i++ {
+		m := float64(i / 2)
+		var numerator float64
+		switch {
+		case i == 0:
+			numerator = 1
+		case i%2 == 0:
+			numerator = m * (b - m) * x / ((a + 2*m - 1) * (a + 2*m))
+		default:
+			numerator = -(a + m) * (a + b + m) * x / ((a + 2*m) * (a + 2*m + 1))
+		}
+
+		d = 1 + numerator*d
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		d = 1 / d
+		c = 1 + numerator/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		f *= c * d
+		if math.Abs(1-c*d) < 1e-12 {
+			break
+		}
+	}
+	return front * (f - 1)
+}
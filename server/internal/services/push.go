@@ -3,33 +3,67 @@ package services
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"net/http"
+	"net/url"
 	"strings"
+	"sync"
+	"time"
 
 	webpush "github.com/SherClockHolmes/webpush-go"
+	"github.com/andevellicus/crapp/internal/config"
+	"github.com/andevellicus/crapp/internal/models"
 	"github.com/andevellicus/crapp/internal/repository"
+	"github.com/andevellicus/crapp/internal/utils"
 	"go.uber.org/zap"
 )
 
+// ErrSubscriptionGone means the push service no longer accepts a
+// subscription and it should be removed
+var ErrSubscriptionGone = errors.New("push subscription expired")
+
 // PushService handles push notifications
 type PushService struct {
 	repo         *repository.Repository
 	log          *zap.SugaredLogger
 	vapidPublic  string
 	vapidPrivate string
+	sender       *utils.Resilient
+	client       *http.Client
+	workers      int
+	batchSize    int
+	achievements *AchievementService // Adds streak progress to reminders; nil leaves them as they are
+	reminders    *ReminderRenderer
+	tracker      *NotificationTracker // Counts reminder clicks; nil tracks nothing
 }
 
 // NewPushService creates a new push notification service
-func NewPushService(repo *repository.Repository, log *zap.SugaredLogger, vapidPublic, vapidPrivate string) *PushService {
+func NewPushService(repo *repository.Repository, log *zap.SugaredLogger, vapidPublic, vapidPrivate string, policy *config.OutboundPolicy, reminders *config.ReminderConfig, renderer *ReminderRenderer, tracker *NotificationTracker) *PushService {
+	var achievements *AchievementService
+	if reminders.ShowProgress {
+		achievements = NewAchievementService(repo, log)
+	}
 	return &PushService{
 		repo:         repo,
 		log:          log,
 		vapidPublic:  vapidPublic,
 		vapidPrivate: vapidPrivate,
+		sender:       newResilient("web-push", policy, log.Named("push")),
+		client:       &http.Client{Timeout: time.Duration(policy.TimeoutSeconds) * time.Second},
+		workers:      max(reminders.PushWorkers, 1),
+		batchSize:    max(reminders.PushBatchSize, 1),
+		achievements: achievements,
+		reminders:    renderer,
+		tracker:      tracker,
 	}
 }
 
+// Failing reports whether recent deliveries have been failing
+func (s *PushService) Failing() bool {
+	return s.sender.Failing()
+}
+
 // GetVAPIDPublicKey returns the public VAPID key for subscriptions
 func (s *PushService) GetVAPIDPublicKey() string {
 	return s.vapidPublic
@@ -40,8 +74,80 @@ func (s *PushService) SaveSubscription(userEmail string, subscription string) er
 	return s.repo.Users.SavePushSubscription(userEmail, subscription)
 }
 
+// NotificationAction is a button shown on a push notification
+type NotificationAction struct {
+	Action string `json:"action"`
+	Title  string `json:"title"`
+}
+
+// reminderActions are offered on every assessment reminder. The service
+// worker opens the deep link for "start" and calls the snooze endpoint for
+// "snooze".
+var reminderActions = []NotificationAction{
+	{Action: "start", Title: "Start assessment"},
+	{Action: "snooze", Title: "Snooze 1 hour"},
+}
+
 // SendNotification sends a push notification to a user
 func (s *PushService) SendNotification(email string, title, body string) error {
+	return s.send(email, title, body, "/", "", nil, webpush.UrgencyNormal)
+}
+
+// SendUrgentNotification sends a high-urgency push notification, which push
+// services deliver straight away even to devices saving battery
+func (s *PushService) SendUrgentNotification(email, title, body, link string) error {
+	return s.send(email, title, body, link, "", nil, webpush.UrgencyHigh)
+}
+
+// SendReminderNotification sends an assessment reminder with start and
+// snooze actions. The notification links straight to the user's unfinished
+// form, if they have one. Unless their study has its own copy, it mentions
+// their streak when progress is shown.
+func (s *PushService) SendReminderNotification(email string) error {
+	content, err := s.reminders.Render(email, models.NotificationChannelPush)
+	if err != nil {
+		return err
+	}
+	body := content.Body
+	if s.achievements != nil && !content.Custom {
+		if nudge := s.achievements.ReminderNudge(email); nudge != "" {
+			body = nudge
+		}
+	}
+	messageID := s.tracker.NewMessage()
+	err = s.send(email,
+		content.Subject,
+		body,
+		s.reminderURL(email),
+		s.tracker.ClickURL(messageID, ""),
+		reminderActions,
+		webpush.UrgencyNormal)
+	if err != nil {
+		return err
+	}
+	s.tracker.Sent(messageID, models.NotificationChannelPush, email)
+	return nil
+}
+
+// reminderURL deep-links to the user's active form state so the form
+// resumes where they left off
+func (s *PushService) reminderURL(email string) string {
+	state, err := s.repo.FormStates.GetUserActiveFormState(email, repository.FormStateScope{})
+	if err != nil || state == nil {
+		return "/"
+	}
+	return "/?state=" + url.QueryEscape(state.ID)
+}
+
+// send delivers one notification payload to a user's subscription. A
+// tracking URL is called back by the service worker when the notification is
+// clicked.
+func (s *PushService) send(email, title, body, link, trackURL string, actions []NotificationAction, urgency webpush.Urgency) error {
+	// Without a VAPID key, as in demo mode, push is turned off
+	if s.vapidPrivate == "" {
+		return fmt.Errorf("push notifications are disabled")
+	}
+
 	normalizedEmail := strings.ToLower(email)
 	// Get user's subscription
 	sub, err := s.repo.Users.GetPushSubscription(normalizedEmail)
@@ -60,14 +166,22 @@ func (s *PushService) SendNotification(email string, title, body string) error {
 	}
 
 	// Create notification payload
+	data := map[string]string{
+		"url":        link,
+		"snooze_url": "/api/reminders/snooze",
+	}
+	if trackURL != "" {
+		data["track_url"] = trackURL
+	}
 	message := map[string]any{
 		"title": title,
 		"body":  body,
 		"icon":  "/static/icons/icon-192x192.png",
 		"badge": "/static/icons/badge-96x96.png",
-		"data": map[string]string{
-			"url": "/",
-		},
+		"data":  data,
+	}
+	if len(actions) > 0 {
+		message["actions"] = actions
 	}
 
 	// Convert to JSON
@@ -76,51 +190,159 @@ func (s *PushService) SendNotification(email string, title, body string) error {
 		return err
 	}
 
-	// Send notification
-	resp, err := webpush.SendNotification(messageBytes, &subscription, &webpush.Options{
-		Subscriber:      "example@example.com", // Your contact info
-		VAPIDPublicKey:  s.vapidPublic,
-		VAPIDPrivateKey: s.vapidPrivate,
-		TTL:             30,
+	// Send notification. Push services signal temporary trouble with 429 and
+	// 5xx responses; other errors won't succeed on retry.
+	return s.sender.Do(func() error {
+		resp, err := webpush.SendNotification(messageBytes, &subscription, &webpush.Options{
+			HTTPClient:      s.client,
+			Subscriber:      "example@example.com", // Your contact info
+			VAPIDPublicKey:  s.vapidPublic,
+			VAPIDPrivateKey: s.vapidPrivate,
+			TTL:             30,
+			Urgency:         urgency,
+		})
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		switch {
+		case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+			return fmt.Errorf("push service returned %s", resp.Status)
+		case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+			return utils.Permanent(fmt.Errorf("%w: %s", ErrSubscriptionGone, resp.Status))
+		case resp.StatusCode >= 400:
+			return utils.Permanent(fmt.Errorf("push service rejected notification: %s", resp.Status))
+		}
+		return nil
 	})
+}
+
+// ReminderDispatchStats summarizes one push reminder run
+type ReminderDispatchStats struct {
+	Eligible int // Users whose preferences match the time slot
+	Sent     int
+	Failed   int
+	Pruned   int // Expired subscriptions removed after the push service rejected them
+	Skipped  int // Already completed today or already reminded
+}
+
+// SendReminderToAllEligibleUsers sends reminder notifications to all users
+// in a reminder slot based on their preferences. Users are dispatched in batches to a bounded
+// pool of workers; each send is limited by the push resilience policy.
+func (s *PushService) SendReminderToAllEligibleUsers(slot repository.ReminderSlot) (*ReminderDispatchStats, error) {
+	// Get all users with enabled reminders for this slot
+	users, err := s.repo.GetUsersForReminder(slot)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	defer resp.Body.Close()
 
-	return nil
+	stats := &ReminderDispatchStats{Eligible: len(users)}
+	var mu sync.Mutex
+	count := func(field *int) {
+		mu.Lock()
+		*field++
+		mu.Unlock()
+	}
+
+	for start := 0; start < len(users); start += s.batchSize {
+		end := min(start+s.batchSize, len(users))
+
+		jobs := make(chan *models.User)
+		var wg sync.WaitGroup
+		for range min(s.workers, end-start) {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for user := range jobs {
+					switch s.sendReminder(user, slot.Time) {
+					case reminderSent:
+						count(&stats.Sent)
+					case reminderFailed:
+						count(&stats.Failed)
+					case reminderPruned:
+						count(&stats.Pruned)
+					case reminderSkipped:
+						count(&stats.Skipped)
+					}
+				}
+			}()
+		}
+		for i := start; i < end; i++ {
+			jobs <- &users[i]
+		}
+		close(jobs)
+		wg.Wait()
+	}
+
+	return stats, nil
 }
 
-// SendReminderToAllEligibleUsers sends reminder notifications to all users based on their preferences
-func (s *PushService) SendReminderToAllEligibleUsers(reminderTime string) error {
-	// Get all users with enabled reminders for this time
-	users, err := s.repo.GetUsersForReminder(reminderTime)
+// reminderOutcome is the result of reminding one user
+type reminderOutcome int
+
+const (
+	reminderSent reminderOutcome = iota
+	reminderFailed
+	reminderPruned
+	reminderSkipped
+)
+
+// sendReminder sends one user's push reminder unless they've already
+// completed today's assessment or been reminded for this slot
+func (s *PushService) sendReminder(user *models.User, reminderTime string) reminderOutcome {
+	// Check if user has already completed today's assessment
+	completed, err := s.repo.Users.HasCompletedAssessment(user.Email)
 	if err != nil {
-		return err
+		s.log.Warnw("Failed to check assessment completion status",
+			"error", err, "user", user.Email)
+		return reminderFailed
 	}
 
-	for _, user := range users {
-		// Check if user has already completed today's assessment
-		completed, err := s.repo.Users.HasCompletedAssessment(user.Email)
-		if err != nil {
-			s.log.Warnw("Failed to check assessment completion status",
-				"error", err, "user", user.Email)
-			continue
-		}
+	// Skip push reminder if assessment is already completed
+	if completed {
+		s.log.Debugw("Skipping push reminder - assessment already completed",
+			"user", user.Email)
+		return reminderSkipped
+	}
 
-		// Skip push reminder if assessment is already completed
-		if completed {
-			s.log.Infow("Skipping push reminder - assessment already completed",
-				"user", user.Email)
-			continue
-		}
+	// Respect a skip or snooze the user set for today
+	day := s.repo.Users.AssessmentDayFor(user).Today()
+	override, err := s.repo.Reminders.GetOverride(user.Email, day)
+	if err != nil {
+		return reminderFailed
+	}
+	if override.Suppresses(time.Now()) {
+		s.log.Debugw("Skipping push reminder - skipped or snoozed by user",
+			"user", user.Email)
+		return reminderSkipped
+	}
 
-		if err := s.SendNotification(user.Email,
-			"Daily Symptom Report Reminder",
-			"Don't forget to complete your symptom report for today!"); err != nil {
-			log.Printf("Failed to send reminder to %s: %v", user.Email, err)
-		}
+	// The ledger stops duplicate sends after a reschedule or restart
+	claimed, err := s.repo.Reminders.Claim(user.Email, models.NotificationChannelPush, day, reminderTime)
+	if err != nil {
+		return reminderFailed
+	}
+	if !claimed {
+		s.log.Debugw("Skipping push reminder - already sent",
+			"user", user.Email, "time", reminderTime)
+		return reminderSkipped
 	}
 
-	return nil
+	err = s.SendReminderNotification(user.Email)
+	if err == nil {
+		return reminderSent
+	}
+
+	s.repo.Reminders.Release(user.Email, models.NotificationChannelPush, day, reminderTime)
+	if errors.Is(err, ErrSubscriptionGone) {
+		if err := s.repo.Users.ClearPushSubscription(user.Email); err != nil {
+			s.log.Warnw("Failed to remove expired push subscription", "error", err, "user", user.Email)
+			return reminderFailed
+		}
+		s.log.Infow("Removed expired push subscription", "user", user.Email)
+		return reminderPruned
+	}
+	s.log.Warnw("Failed to send push reminder", "error", err, "user", user.Email, "time", reminderTime)
+	return reminderFailed
 }
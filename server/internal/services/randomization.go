@@ -0,0 +1,172 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	mathrand "math/rand/v2"
+	"slices"
+	"strings"
+
+	"github.com/andevellicus/crapp/internal/models"
+	"github.com/andevellicus/crapp/internal/repository"
+	"go.uber.org/zap"
+)
+
+// Sexes accepted as a stratification factor
+var stratificationSexes = []string{"female", "male", "other"}
+
+// RandomizationService allocates study participants to arms using simple,
+// permuted-block, or stratified permuted-block randomization. Allocation
+// sequences are derived from the scheme's seed so they can be reproduced.
+type RandomizationService struct {
+	repo *repository.Repository
+	log  *zap.SugaredLogger
+}
+
+// NewRandomizationService creates a new randomization service
+func NewRandomizationService(repo *repository.Repository, log *zap.SugaredLogger) *RandomizationService {
+	return &RandomizationService{
+		repo: repo,
+		log:  log.Named("randomization"),
+	}
+}
+
+// Configure validates and stores a study's randomization scheme. A zero seed
+// is replaced with a random one.
+func (s *RandomizationService) Configure(scheme *models.RandomizationScheme, arms []string) error {
+	if len(arms) < 2 {
+		return errors.New("at least two arms are required")
+	}
+	for i, arm := range arms {
+		arms[i] = strings.TrimSpace(arm)
+		if arms[i] == "" || slices.Contains(arms[:i], arms[i]) {
+			return errors.New("arm codes must be non-empty and unique")
+		}
+	}
+
+	switch scheme.Method {
+	case models.RandomizationSimple:
+		scheme.BlockSize = 0
+	case models.RandomizationBlock, models.RandomizationStratified:
+		if scheme.BlockSize == 0 {
+			scheme.BlockSize = 2 * len(arms)
+		}
+		if scheme.BlockSize%len(arms) != 0 {
+			return fmt.Errorf("block size must be a multiple of the number of arms (%d)", len(arms))
+		}
+	default:
+		return fmt.Errorf("unknown randomization method %q", scheme.Method)
+	}
+
+	if scheme.Seed == 0 {
+		var buf [8]byte
+		if _, err := rand.Read(buf[:]); err != nil {
+			return fmt.Errorf("failed to generate seed: %w", err)
+		}
+		scheme.Seed = int64(binary.LittleEndian.Uint64(buf[:]) >> 1)
+	}
+
+	armsJSON, err := json.Marshal(arms)
+	if err != nil {
+		return err
+	}
+	scheme.Arms = string(armsJSON)
+
+	return s.repo.Randomization.SaveScheme(scheme)
+}
+
+// Randomize allocates a participant to an arm of the study. Age and sex are
+// required for stratified schemes and recorded when given.
+func (s *RandomizationService) Randomize(study *models.Study, email string, age *int, sex, allocatedBy string) (*models.ArmAllocation, error) {
+	scheme, err := s.repo.Randomization.GetScheme(study.ID)
+	if err != nil {
+		return nil, errors.New("study has no randomization scheme")
+	}
+
+	sex = strings.ToLower(strings.TrimSpace(sex))
+	if sex != "" && !slices.Contains(stratificationSexes, sex) {
+		return nil, fmt.Errorf("sex must be one of %s", strings.Join(stratificationSexes, ", "))
+	}
+
+	allocation := &models.ArmAllocation{
+		StudyID:     study.ID,
+		UserEmail:   email,
+		Age:         age,
+		Sex:         sex,
+		AllocatedBy: allocatedBy,
+	}
+
+	if scheme.Method == models.RandomizationStratified {
+		if age == nil || sex == "" {
+			return nil, errors.New("age and sex are required for stratified randomization")
+		}
+		allocation.Stratum = ageBand(*age) + "/" + sex
+	}
+
+	pick := func(scheme *models.RandomizationScheme, sequence int) (string, error) {
+		return armForSequence(scheme, allocation.Stratum, sequence)
+	}
+	if err := s.repo.Randomization.Allocate(allocation, pick); err != nil {
+		return nil, err
+	}
+
+	// Enrolling the participant lets dashboards report their arm
+	if err := s.repo.Users.SetStudy(email, study.Slug); err != nil {
+		s.log.Warnw("Failed to enroll randomized participant in study", "error", err, "study_id", study.ID)
+	}
+
+	s.log.Infow("Participant randomized",
+		"study_id", study.ID,
+		"method", allocation.Method,
+		"stratum", allocation.Stratum,
+		"sequence", allocation.Sequence)
+	return allocation, nil
+}
+
+// armForSequence deterministically picks the arm for a position in a stratum
+func armForSequence(scheme *models.RandomizationScheme, stratum string, sequence int) (string, error) {
+	var arms []string
+	if err := json.Unmarshal([]byte(scheme.Arms), &arms); err != nil || len(arms) == 0 {
+		return "", errors.New("invalid arms in randomization scheme")
+	}
+
+	if scheme.Method == models.RandomizationSimple {
+		rng := mathrand.New(mathrand.NewPCG(uint64(scheme.Seed), uint64(sequence)))
+		return arms[rng.IntN(len(arms))], nil
+	}
+
+	block := permutedBlock(arms, scheme.BlockSize, scheme.Seed, stratum, sequence/scheme.BlockSize)
+	return block[sequence%scheme.BlockSize], nil
+}
+
+// ageBand buckets an age for stratification
+func ageBand(age int) string {
+	switch {
+	case age < 40:
+		return "<40"
+	case age < 65:
+		return "40-64"
+	default:
+		return "65+"
+	}
+}
+
+// permutedBlock returns the shuffled block of arms containing a sequence number.
+// Each stratum draws its blocks from an independent stream of the seed.
+func permutedBlock(arms []string, blockSize int, seed int64, stratum string, block int) []string {
+	h := fnv.New64a()
+	h.Write([]byte(stratum))
+
+	list := make([]string, 0, blockSize)
+	for len(list) < blockSize {
+		list = append(list, arms...)
+	}
+
+	rng := mathrand.New(mathrand.NewPCG(uint64(seed)^h.Sum64(), uint64(block)))
+	rng.Shuffle(len(list), func(i, j int) { list[i], list[j] = list[j], list[i] })
+	return list
+}
@@ -0,0 +1,190 @@
+package services
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/andevellicus/crapp/internal/config"
+	"github.com/andevellicus/crapp/internal/models"
+	"github.com/andevellicus/crapp/internal/repository"
+	"go.uber.org/zap"
+)
+
+// Sign-up rejection reasons
+const (
+	SignupReasonDisposableEmail = "disposable_email"
+	SignupReasonIPLimit         = "ip_limit"
+	SignupReasonCaptcha         = "captcha_failed"
+)
+
+// Captcha verification endpoints
+var captchaVerifyURLs = map[string]string{
+	"turnstile": "https://challenges.cloudflare.com/turnstile/v0/siteverify",
+	"hcaptcha":  "https://api.hcaptcha.com/siteverify",
+}
+
+// SignupRejection explains why a registration was refused
+type SignupRejection struct {
+	Reason string
+}
+
+func (e *SignupRejection) Error() string {
+	return fmt.Sprintf("registration rejected: %s", e.Reason)
+}
+
+// RegistrationGuard screens sign-ups for disposable emails, per-IP abuse, and bots
+type RegistrationGuard struct {
+	repo       *repository.Repository
+	log        *zap.SugaredLogger
+	config     *config.RegistrationGuardConfig
+	active     bool
+	disposable map[string]bool
+	client     *http.Client
+}
+
+// NewRegistrationGuard creates a new registration guard for the given app environment
+func NewRegistrationGuard(repo *repository.Repository, log *zap.SugaredLogger, cfg *config.RegistrationGuardConfig, environment string) *RegistrationGuard {
+	g := &RegistrationGuard{
+		repo:       repo,
+		log:        log.Named("signup-guard"),
+		config:     cfg,
+		active:     slices.Contains(cfg.Environments, strings.ToLower(environment)),
+		disposable: make(map[string]bool),
+		client:     &http.Client{Timeout: 5 * time.Second},
+	}
+
+	if g.active && cfg.BlockDisposable {
+		g.loadDisposableDomains()
+	}
+
+	return g
+}
+
+// CaptchaConfig returns the public captcha settings the sign-up form needs
+func (g *RegistrationGuard) CaptchaConfig() (string, string) {
+	if !g.active || g.config.CaptchaProvider == "" || g.config.CaptchaProvider == "none" {
+		return "none", ""
+	}
+	return g.config.CaptchaProvider, g.config.CaptchaSiteKey
+}
+
+// Check screens a registration attempt, recording rejections. Returns a
+// *SignupRejection when the sign-up should be refused.
+func (g *RegistrationGuard) Check(email, ip, captchaToken string) error {
+	if !g.active {
+		return nil
+	}
+
+	if reason := g.rejectionReason(email, ip, captchaToken); reason != "" {
+		g.record(email, ip, models.SignupRejected, reason)
+		g.log.Infow("Rejected sign-up", "reason", reason, "ip", ip)
+		return &SignupRejection{Reason: reason}
+	}
+	return nil
+}
+
+// RecordAccepted counts a successful sign-up against the IP's cap
+func (g *RegistrationGuard) RecordAccepted(email, ip string) {
+	if g.active {
+		g.record(email, ip, models.SignupAccepted, "")
+	}
+}
+
+func (g *RegistrationGuard) rejectionReason(email, ip, captchaToken string) string {
+	if g.config.BlockDisposable {
+		if at := strings.LastIndex(email, "@"); at >= 0 && g.disposable[strings.ToLower(email[at+1:])] {
+			return SignupReasonDisposableEmail
+		}
+	}
+
+	if g.config.MaxSignupsPerIP > 0 {
+		since := time.Now().Add(-time.Duration(g.config.SignupWindowHours) * time.Hour)
+		count, err := g.repo.SignupAttempts.CountAcceptedFromIP(ip, since)
+		if err != nil {
+			g.log.Warnw("Error counting sign-ups for IP", "error", err, "ip", ip)
+		} else if count >= int64(g.config.MaxSignupsPerIP) {
+			return SignupReasonIPLimit
+		}
+	}
+
+	if provider, _ := g.CaptchaConfig(); provider != "none" {
+		if !g.verifyCaptcha(provider, captchaToken, ip) {
+			return SignupReasonCaptcha
+		}
+	}
+
+	return ""
+}
+
+// verifyCaptcha validates a Turnstile or hCaptcha response token
+func (g *RegistrationGuard) verifyCaptcha(provider, token, ip string) bool {
+	if token == "" {
+		return false
+	}
+
+	verifyURL, ok := captchaVerifyURLs[provider]
+	if !ok {
+		g.log.Errorw("Unknown captcha provider", "provider", provider)
+		return false
+	}
+
+	resp, err := g.client.PostForm(verifyURL, url.Values{
+		"secret":   {g.config.CaptchaSecretKey},
+		"response": {token},
+		"remoteip": {ip},
+	})
+	if err != nil {
+		g.log.Errorw("Captcha verification request failed", "error", err, "provider", provider)
+		return false
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Success bool `json:"success"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		g.log.Errorw("Error decoding captcha verification response", "error", err)
+		return false
+	}
+	return result.Success
+}
+
+func (g *RegistrationGuard) record(email, ip, outcome, reason string) {
+	attempt := &models.SignupAttempt{
+		Email:     email,
+		IPAddress: ip,
+		Outcome:   outcome,
+		Reason:    reason,
+	}
+	if err := g.repo.SignupAttempts.Record(attempt); err != nil {
+		g.log.Warnw("Failed to record sign-up attempt", "error", err)
+	}
+}
+
+// loadDisposableDomains reads the blocklist, ignoring blank lines and comments
+func (g *RegistrationGuard) loadDisposableDomains() {
+	file, err := os.Open(g.config.DisposableDomainsFile)
+	if err != nil {
+		g.log.Warnw("Disposable domain list unavailable", "error", err, "file", g.config.DisposableDomainsFile)
+		return
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		domain := strings.ToLower(strings.TrimSpace(scanner.Text()))
+		if domain == "" || strings.HasPrefix(domain, "#") {
+			continue
+		}
+		g.disposable[domain] = true
+	}
+
+	g.log.Infow("Loaded disposable email domains", "count", len(g.disposable))
+}
@@ -0,0 +1,167 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+
+	"github.com/andevellicus/crapp/internal/config"
+	"github.com/andevellicus/crapp/internal/models"
+	"github.com/andevellicus/crapp/internal/repository"
+	"go.uber.org/zap"
+)
+
+// ReminderVariables are the variables reminder copy may use, each written as
+// {{name}}
+var ReminderVariables = []string{"first_name", "streak", "days_since_last", "study_name", "app_name"}
+
+// ReminderChannels are the channels a reminder can be rendered for
+var ReminderChannels = []string{models.NotificationChannelEmail, models.NotificationChannelPush, models.ReminderChannelInApp}
+
+// reminderVariablePattern matches a variable in reminder copy
+var reminderVariablePattern = regexp.MustCompile(`\{\{\s*(\w+)\s*\}\}`)
+
+// defaultReminderTemplates is the copy for each channel when a study has no
+// template of its own
+var defaultReminderTemplates = map[string]models.ReminderTemplate{
+	models.NotificationChannelEmail: {
+		Subject: "Daily Assessment Reminder - {{app_name}}",
+		Body:    "Hi {{first_name}}, this is a reminder to complete your daily assessment on {{app_name}}.",
+	},
+	models.NotificationChannelPush: {
+		Subject: "Daily Symptom Report Reminder",
+		Body:    "Don't forget to complete your symptom report for today!",
+	},
+	models.ReminderChannelInApp: {
+		Subject: "Today's assessment",
+		Body:    "Hi {{first_name}}, today's assessment is still waiting. It only takes a few minutes.",
+	},
+}
+
+// ReminderContent is a reminder rendered for one user on one channel
+type ReminderContent struct {
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+	Custom  bool   `json:"custom"` // From the study's template rather than the default copy
+}
+
+// ReminderRenderer fills reminder copy with a user's details. Every channel
+// renders through it, so a study's templates read the same wherever the
+// reminder is seen.
+type ReminderRenderer struct {
+	repo     *repository.Repository
+	log      *zap.SugaredLogger
+	branding *config.BrandingConfig
+}
+
+// NewReminderRenderer creates a new reminder renderer
+func NewReminderRenderer(repo *repository.Repository, log *zap.SugaredLogger, branding *config.BrandingConfig) *ReminderRenderer {
+	return &ReminderRenderer{
+		repo:     repo,
+		log:      log.Named("reminder-content"),
+		branding: branding,
+	}
+}
+
+// Render returns a user's reminder for a channel, from their study's template
+// when it has one for the channel
+func (r *ReminderRenderer) Render(email, channel string) (ReminderContent, error) {
+	tmpl, ok := defaultReminderTemplates[channel]
+	if !ok {
+		return ReminderContent{}, fmt.Errorf("unknown reminder channel %q", channel)
+	}
+
+	user, err := r.repo.Users.GetByEmail(email)
+	if err != nil || user == nil {
+		return ReminderContent{}, fmt.Errorf("user not found: %s", email)
+	}
+
+	var study *models.Study
+	custom := false
+	if user.StudyID != "" {
+		study, err = r.repo.Organizations.FindStudy(user.OrganizationID, user.StudyID)
+		if err != nil {
+			r.log.Warnw("Failed to load study for reminder", "error", err, "study", user.StudyID)
+		}
+	}
+	if study != nil {
+		override, err := r.repo.ReminderTemplates.Find(study.ID, channel)
+		if err != nil {
+			r.log.Warnw("Failed to load reminder template", "error", err, "study_id", study.ID, "channel", channel)
+		} else if override != nil {
+			// A template without a subject keeps the default one
+			if override.Subject == "" {
+				override.Subject = tmpl.Subject
+			}
+			tmpl = *override
+			custom = true
+		}
+	}
+
+	values := r.variables(user, study)
+	return ReminderContent{
+		Subject: fillReminder(tmpl.Subject, values),
+		Body:    fillReminder(tmpl.Body, values),
+		Custom:  custom,
+	}, nil
+}
+
+// DefaultReminderTemplate returns the copy a channel uses when a study has no
+// template for it
+func DefaultReminderTemplate(channel string) models.ReminderTemplate {
+	return defaultReminderTemplates[channel]
+}
+
+// ValidateReminderTemplate reports the first variable in reminder copy that
+// isn't one of ReminderVariables
+func ValidateReminderTemplate(text string) error {
+	for _, match := range reminderVariablePattern.FindAllStringSubmatch(text, -1) {
+		if !slices.Contains(ReminderVariables, match[1]) {
+			return fmt.Errorf("unknown variable {{%s}}", match[1])
+		}
+	}
+	return nil
+}
+
+// variables returns the values of the reminder variables for a user. The
+// days since their last assessment is empty if they have never done one.
+func (r *ReminderRenderer) variables(user *models.User, study *models.Study) map[string]string {
+	firstName := user.FirstName
+	if firstName == "" {
+		firstName = "there"
+	}
+
+	days := r.repo.Users.AssessmentDayFor(user)
+	today := days.Today()
+	streak, err := r.repo.ForUser(user.Email).Assessments.StreakDays(user.Email, today)
+	if err != nil {
+		r.log.Warnw("Failed to count streak for reminder", "error", err, "email", user.Email)
+	}
+	daysSince := ""
+	if !user.LastAssessmentDate.IsZero() {
+		// Rounded, since a day may be an hour short or long across a clock change
+		since := math.Round(today.Sub(days.Of(user.LastAssessmentDate)).Hours() / 24)
+		daysSince = strconv.Itoa(int(since))
+	}
+	studyName := ""
+	if study != nil {
+		studyName = study.Name
+	}
+
+	return map[string]string{
+		"first_name":      firstName,
+		"streak":          strconv.Itoa(streak),
+		"days_since_last": daysSince,
+		"study_name":      studyName,
+		"app_name":        userBranding(r.repo, r.branding, user).ShortName,
+	}
+}
+
+// fillReminder replaces the variables in reminder copy with their values
+func fillReminder(text string, values map[string]string) string {
+	return reminderVariablePattern.ReplaceAllStringFunc(text, func(match string) string {
+		return values[reminderVariablePattern.FindStringSubmatch(match)[1]]
+	})
+}
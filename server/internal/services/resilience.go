@@ -0,0 +1,23 @@
+package services
+
+import (
+	"time"
+
+	"github.com/andevellicus/crapp/internal/config"
+	"github.com/andevellicus/crapp/internal/utils"
+	"go.uber.org/zap"
+)
+
+// newResilient wraps an outbound dependency with the configured retries,
+// timeout, and circuit breaker
+func newResilient(name string, policy *config.OutboundPolicy, log *zap.SugaredLogger) *utils.Resilient {
+	return utils.NewResilient(name,
+		utils.RetryPolicy{
+			MaxAttempts: policy.MaxAttempts,
+			BaseDelay:   time.Duration(policy.BaseDelayMs) * time.Millisecond,
+			MaxDelay:    time.Duration(policy.MaxDelayMs) * time.Millisecond,
+			Timeout:     time.Duration(policy.TimeoutSeconds) * time.Second,
+		},
+		utils.NewCircuitBreaker(policy.FailureThreshold, time.Duration(policy.CooldownSeconds)*time.Second),
+		log)
+}
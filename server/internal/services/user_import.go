@@ -0,0 +1,245 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/andevellicus/crapp/internal/models"
+	"github.com/andevellicus/crapp/internal/repository"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// inviteTokenMinutes is how long an imported participant's setup link stays valid
+const inviteTokenMinutes = 7 * 24 * 60
+
+// maxImportRows caps the size of a single import
+const maxImportRows = 10000
+
+// UserImportRow is one participant parsed from an import CSV
+type UserImportRow struct {
+	Row       int
+	Email     string
+	FirstName string
+	LastName  string
+	StudyID   string
+}
+
+// UserImportService creates participant accounts in bulk from CSV uploads
+type UserImportService struct {
+	repo         *repository.Repository
+	log          *zap.SugaredLogger
+	emailService *EmailService
+}
+
+// NewUserImportService creates a new user import service
+func NewUserImportService(repo *repository.Repository, log *zap.SugaredLogger, emailService *EmailService) *UserImportService {
+	return &UserImportService{
+		repo:         repo,
+		log:          log.Named("user-import"),
+		emailService: emailService,
+	}
+}
+
+// ParseCSV reads participant rows. The header must include an email column and
+// may include first_name, last_name, and study_id.
+func (s *UserImportService) ParseCSV(r io.Reader) ([]UserImportRow, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["email"]; !ok {
+		return nil, errors.New("CSV header must include an email column")
+	}
+
+	field := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	var rows []UserImportRow
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CSV row %d: %w", line, err)
+		}
+		if len(rows) >= maxImportRows {
+			return nil, fmt.Errorf("import is limited to %d rows", maxImportRows)
+		}
+
+		rows = append(rows, UserImportRow{
+			Row:       line,
+			Email:     strings.ToLower(field(record, "email")),
+			FirstName: field(record, "first_name"),
+			LastName:  field(record, "last_name"),
+			StudyID:   field(record, "study_id"),
+		})
+	}
+
+	if len(rows) == 0 {
+		return nil, errors.New("CSV contains no participants")
+	}
+	return rows, nil
+}
+
+// Start creates an import job and processes it in the background. Participants
+// are placed in orgID, or the deployment default organization when empty.
+func (s *UserImportService) Start(createdBy, orgID, mode string, rows []UserImportRow) (*models.UserImportJob, error) {
+	if s.emailService == nil {
+		return nil, errors.New("email service is required to notify imported participants")
+	}
+
+	job := &models.UserImportJob{
+		ID:        uuid.NewString(),
+		CreatedBy: createdBy,
+		Mode:      mode,
+		Status:    models.ImportPending,
+		TotalRows: len(rows),
+	}
+	if err := s.repo.UserImports.Create(job); err != nil {
+		return nil, err
+	}
+
+	go s.run(job, orgID, rows)
+
+	return job, nil
+}
+
+// run imports every row, saving progress as it goes
+func (s *UserImportService) run(job *models.UserImportJob, orgID string, rows []UserImportRow) {
+	defer func() {
+		if r := recover(); r != nil {
+			s.log.Errorw("User import panicked", "id", job.ID, "panic", r)
+			now := time.Now()
+			job.Status = models.ImportFailed
+			job.Error = fmt.Sprint(r)
+			job.CompletedAt = &now
+			s.repo.UserImports.UpdateProgress(job, nil)
+		}
+	}()
+
+	job.Status = models.ImportRunning
+	results := make([]models.UserImportRowResult, 0, len(rows))
+	seen := make(map[string]bool)
+
+	for i, row := range rows {
+		result := models.UserImportRowResult{Row: row.Row, Email: row.Email, Status: "created"}
+
+		if seen[row.Email] {
+			result.Status, result.Error = "failed", "duplicate email in file"
+		} else if err := s.importRow(row, orgID, job.Mode); err != nil {
+			result.Status, result.Error = "failed", err.Error()
+		}
+		seen[row.Email] = true
+
+		results = append(results, result)
+		job.ProcessedRows++
+		if result.Status == "created" {
+			job.SucceededRows++
+		} else {
+			job.FailedRows++
+		}
+
+		// Save progress periodically so large cohorts can be polled
+		if i%25 == 0 {
+			s.repo.UserImports.UpdateProgress(job, results)
+		}
+	}
+
+	now := time.Now()
+	job.Status = models.ImportCompleted
+	job.CompletedAt = &now
+	if err := s.repo.UserImports.UpdateProgress(job, results); err != nil {
+		s.log.Errorw("Failed to save import results", "id", job.ID, "error", err)
+	}
+
+	s.log.Infow("User import completed", "id", job.ID, "succeeded", job.SucceededRows, "failed", job.FailedRows)
+}
+
+// importRow creates one account and sends its invitation
+func (s *UserImportService) importRow(row UserImportRow, orgID, mode string) error {
+	if _, err := mail.ParseAddress(row.Email); err != nil || row.Email == "" {
+		return errors.New("invalid email address")
+	}
+
+	exists, err := s.repo.Users.UserExists(row.Email)
+	if err != nil {
+		return errors.New("error checking existing account")
+	}
+	if exists {
+		return errors.New("account already exists")
+	}
+
+	user := &models.User{
+		Email:          row.Email,
+		FirstName:      row.FirstName,
+		LastName:       row.LastName,
+		StudyID:        row.StudyID,
+		OrganizationID: orgID,
+		CreatedAt:      time.Now(),
+	}
+
+	var tempPassword string
+	if mode == models.ImportModeTemporaryPassword {
+		tempPassword, err = generateTemporaryPassword()
+		if err != nil {
+			return errors.New("error generating password")
+		}
+		user.Password, err = bcrypt.GenerateFromPassword([]byte(tempPassword), bcrypt.DefaultCost)
+		if err != nil {
+			return errors.New("error hashing password")
+		}
+	}
+
+	if err := s.repo.Users.Create(user); err != nil {
+		return fmt.Errorf("error creating account: %w", err)
+	}
+
+	if mode == models.ImportModeTemporaryPassword {
+		if err := s.emailService.SendTemporaryPasswordEmail(user.Email, user.FirstName, tempPassword); err != nil {
+			return errors.New("account created but email failed")
+		}
+		return nil
+	}
+
+	// Invited users set their own password through a long-lived reset link
+	token, err := s.repo.PasswordResetTokens.Create(user.Email, inviteTokenMinutes, "")
+	if err != nil {
+		return errors.New("account created but invitation link failed")
+	}
+	if err := s.emailService.SendInvitationEmail(user.Email, user.FirstName, token.Token); err != nil {
+		return errors.New("account created but email failed")
+	}
+	return nil
+}
+
+// generateTemporaryPassword returns a random 16-character password
+func generateTemporaryPassword() (string, error) {
+	buf := make([]byte, 12)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
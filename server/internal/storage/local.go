@@ -0,0 +1,164 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LocalURLPath is where the server serves signed local downloads
+const LocalURLPath = "/api/storage/"
+
+// LocalStorage keeps objects as files under a directory. Signed URLs point
+// back at the server, which checks the HMAC signature before serving them.
+type LocalStorage struct {
+	directory  string
+	prefix     string
+	signingKey []byte
+}
+
+// NewLocalStorage creates the directory if needed
+func NewLocalStorage(directory, prefix, signingKey string) (*LocalStorage, error) {
+	if signingKey == "" {
+		return nil, errors.New("local storage needs a signing key to sign download URLs")
+	}
+	if err := os.MkdirAll(directory, 0o750); err != nil {
+		return nil, fmt.Errorf("creating storage directory: %w", err)
+	}
+	return &LocalStorage{directory: directory, prefix: prefix, signingKey: []byte(signingKey)}, nil
+}
+
+func (s *LocalStorage) path(key string) (string, error) {
+	object, err := objectKey(s.prefix, key)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(s.directory, filepath.FromSlash(object)), nil
+}
+
+// Put writes the object to a temporary file and renames it into place so
+// readers never see a partial file
+func (s *LocalStorage) Put(_ context.Context, key string, body io.Reader, _ string) error {
+	target, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0o750); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(target), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, body); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), target)
+}
+
+// Get opens the object file
+func (s *LocalStorage) Get(_ context.Context, key string) (io.ReadCloser, error) {
+	target, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(target)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return file, err
+}
+
+// Delete removes the object file
+func (s *LocalStorage) Delete(_ context.Context, key string) error {
+	target, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(target); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// List walks the directory for objects under the prefix
+func (s *LocalStorage) List(_ context.Context, prefix string) ([]ObjectInfo, error) {
+	root := filepath.Join(s.directory, filepath.FromSlash(s.prefix))
+	var objects []ObjectInfo
+	err := filepath.WalkDir(root, func(file string, entry fs.DirEntry, err error) error {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		if err != nil || entry.IsDir() || strings.HasPrefix(entry.Name(), ".upload-") {
+			return err
+		}
+		rel, err := filepath.Rel(root, file)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		objects = append(objects, ObjectInfo{Key: key, Size: info.Size(), LastModified: info.ModTime()})
+		return nil
+	})
+	return objects, err
+}
+
+// SignedURL returns a server-relative download URL
+func (s *LocalStorage) SignedURL(key string, ttl time.Duration) (string, error) {
+	cleaned, err := cleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	expires := strconv.FormatInt(time.Now().Add(ttl).Unix(), 10)
+
+	query := url.Values{}
+	query.Set("expires", expires)
+	query.Set("signature", s.sign(cleaned, expires))
+	return LocalURLPath + (&url.URL{Path: cleaned}).EscapedPath() + "?" + query.Encode(), nil
+}
+
+// Verify checks a signed URL's expiry and signature
+func (s *LocalStorage) Verify(key, expires, signature string) error {
+	unix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil {
+		return errors.New("invalid expiry")
+	}
+	if time.Now().Unix() > unix {
+		return errors.New("link has expired")
+	}
+	if !hmac.Equal([]byte(signature), []byte(s.sign(key, expires))) {
+		return errors.New("invalid signature")
+	}
+	return nil
+}
+
+func (s *LocalStorage) sign(key, expires string) string {
+	mac := hmac.New(sha256.New, s.signingKey)
+	mac.Write([]byte(key + "\n" + expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
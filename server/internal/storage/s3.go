@@ -0,0 +1,396 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/andevellicus/crapp/internal/config"
+)
+
+// Longest lifetime S3 allows for a presigned URL
+const maxPresignTTL = 7 * 24 * time.Hour
+
+// S3Storage talks to S3-compatible object stores using Signature Version 4.
+// Google Cloud Storage's XML API accepts the same requests with HMAC keys.
+type S3Storage struct {
+	client    *http.Client
+	endpoint  *url.URL
+	bucket    string
+	region    string
+	pathStyle bool
+	accessKey string
+	secretKey string
+	prefix    string
+	headers   map[string]string // Sent with every upload, such as encryption settings
+}
+
+// NewS3Storage creates a client for an S3 bucket. Objects are encrypted at
+// rest with S3-managed keys (AES256) or a KMS key (aws:kms) when configured.
+func NewS3Storage(cfg *config.S3StorageConfig, prefix string) (*S3Storage, error) {
+	if cfg.Bucket == "" || cfg.AccessKeyID == "" || cfg.SecretAccessKey == "" {
+		return nil, errors.New("s3 storage needs a bucket, access key ID and secret access key")
+	}
+
+	endpoint := cfg.Endpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", cfg.Region)
+	}
+	parsed, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid s3 endpoint: %w", err)
+	}
+
+	headers := make(map[string]string)
+	switch cfg.Encryption {
+	case "":
+	case "AES256":
+		headers["x-amz-server-side-encryption"] = "AES256"
+	case "aws:kms":
+		headers["x-amz-server-side-encryption"] = "aws:kms"
+		if cfg.KMSKeyID != "" {
+			headers["x-amz-server-side-encryption-aws-kms-key-id"] = cfg.KMSKeyID
+		}
+	default:
+		return nil, fmt.Errorf("unknown s3 encryption %q", cfg.Encryption)
+	}
+
+	return &S3Storage{
+		client:    &http.Client{Timeout: 5 * time.Minute},
+		endpoint:  parsed,
+		bucket:    cfg.Bucket,
+		region:    cfg.Region,
+		pathStyle: cfg.PathStyle,
+		accessKey: cfg.AccessKeyID,
+		secretKey: cfg.SecretAccessKey,
+		prefix:    prefix,
+		headers:   headers,
+	}, nil
+}
+
+// NewGCSStorage creates a client for a Cloud Storage bucket using HMAC keys.
+// Objects are encrypted with the customer-managed KMS key when configured.
+func NewGCSStorage(cfg *config.GCSStorageConfig, prefix string) (*S3Storage, error) {
+	if cfg.Bucket == "" || cfg.AccessKeyID == "" || cfg.Secret == "" {
+		return nil, errors.New("gcs storage needs a bucket, HMAC access key ID and secret")
+	}
+
+	headers := make(map[string]string)
+	if cfg.KMSKeyName != "" {
+		headers["x-goog-encryption-kms-key-name"] = cfg.KMSKeyName
+	}
+
+	return &S3Storage{
+		client:    &http.Client{Timeout: 5 * time.Minute},
+		endpoint:  &url.URL{Scheme: "https", Host: "storage.googleapis.com"},
+		bucket:    cfg.Bucket,
+		region:    "auto",
+		pathStyle: true,
+		accessKey: cfg.AccessKeyID,
+		secretKey: cfg.Secret,
+		prefix:    prefix,
+		headers:   headers,
+	}, nil
+}
+
+// Put uploads the object in a single request
+func (s *S3Storage) Put(ctx context.Context, key string, body io.Reader, contentType string) error {
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+
+	headers := map[string]string{"content-type": contentType}
+	for name, value := range s.headers {
+		headers[name] = value
+	}
+
+	target, err := s.objectURL(key)
+	if err != nil {
+		return err
+	}
+	resp, err := s.do(ctx, http.MethodPut, target, headers, data)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// Get downloads the object
+func (s *S3Storage) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	target, err := s.objectURL(key)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.do(ctx, http.MethodGet, target, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// Delete removes the object
+func (s *S3Storage) Delete(ctx context.Context, key string) error {
+	target, err := s.objectURL(key)
+	if err != nil {
+		return err
+	}
+	resp, err := s.do(ctx, http.MethodDelete, target, nil, nil)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// listBucketResult is the ListObjectsV2 response body
+type listBucketResult struct {
+	Contents []struct {
+		Key          string
+		Size         int64
+		LastModified time.Time
+	}
+	IsTruncated           bool
+	NextContinuationToken string
+}
+
+// List pages through ListObjectsV2 results
+func (s *S3Storage) List(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	var objects []ObjectInfo
+	token := ""
+	for {
+		target := s.bucketURL()
+		query := url.Values{}
+		query.Set("list-type", "2")
+		query.Set("prefix", s.prefix+prefix)
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		target.RawQuery = canonicalQuery(query)
+
+		resp, err := s.do(ctx, http.MethodGet, target, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+		var page listBucketResult
+		err = xml.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("decoding bucket listing: %w", err)
+		}
+
+		for _, object := range page.Contents {
+			objects = append(objects, ObjectInfo{
+				Key:          strings.TrimPrefix(object.Key, s.prefix),
+				Size:         object.Size,
+				LastModified: object.LastModified,
+			})
+		}
+		if !page.IsTruncated || page.NextContinuationToken == "" {
+			return objects, nil
+		}
+		token = page.NextContinuationToken
+	}
+}
+
+// SignedURL returns a presigned GET URL
+func (s *S3Storage) SignedURL(key string, ttl time.Duration) (string, error) {
+	return s.signedURLAt(key, ttl, time.Now().UTC())
+}
+
+// signedURLAt presigns a GET URL as of a given time
+func (s *S3Storage) signedURLAt(key string, ttl time.Duration, now time.Time) (string, error) {
+	target, err := s.objectURL(key)
+	if err != nil {
+		return "", err
+	}
+	if ttl > maxPresignTTL {
+		ttl = maxPresignTTL
+	}
+
+	query := url.Values{}
+	query.Set("X-Amz-Algorithm", "AWS4-HMAC-SHA256")
+	query.Set("X-Amz-Credential", s.accessKey+"/"+s.scope(now))
+	query.Set("X-Amz-Date", now.Format("20060102T150405Z"))
+	query.Set("X-Amz-Expires", fmt.Sprintf("%d", int(ttl.Seconds())))
+	query.Set("X-Amz-SignedHeaders", "host")
+	target.RawQuery = canonicalQuery(query)
+
+	signature := s.signature(now, http.MethodGet, target, map[string]string{"host": target.Host}, "UNSIGNED-PAYLOAD")
+	target.RawQuery += "&X-Amz-Signature=" + signature
+	return target.String(), nil
+}
+
+// do sends a signed request and turns error responses into errors
+func (s *S3Storage) do(ctx context.Context, method string, target *url.URL, headers map[string]string, body []byte) (*http.Response, error) {
+	now := time.Now().UTC()
+	payloadHash := sha256.Sum256(body)
+	signed := map[string]string{
+		"host":                 target.Host,
+		"x-amz-date":           now.Format("20060102T150405Z"),
+		"x-amz-content-sha256": hex.EncodeToString(payloadHash[:]),
+	}
+	for name, value := range headers {
+		signed[name] = value
+	}
+	signature := s.signature(now, method, target, signed, signed["x-amz-content-sha256"])
+
+	req, err := http.NewRequestWithContext(ctx, method, target.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range signed {
+		if name != "host" {
+			req.Header.Set(name, value)
+		}
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.accessKey, s.scope(now), signedHeaderNames(signed), signature))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, ErrNotFound
+	}
+	if resp.StatusCode >= 300 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		resp.Body.Close()
+		return nil, fmt.Errorf("storage %s %s: %s: %s", method, target.Path, resp.Status, strings.TrimSpace(string(detail)))
+	}
+	return resp, nil
+}
+
+// bucketURL addresses the bucket in virtual-hosted or path style
+func (s *S3Storage) bucketURL() *url.URL {
+	target := *s.endpoint
+	if s.pathStyle {
+		target.Path = "/" + s.bucket
+	} else {
+		target.Host = s.bucket + "." + target.Host
+		target.Path = "/"
+	}
+	return &target
+}
+
+// objectURL addresses an object within the bucket
+func (s *S3Storage) objectURL(key string) (*url.URL, error) {
+	object, err := objectKey(s.prefix, key)
+	if err != nil {
+		return nil, err
+	}
+
+	target := s.bucketURL()
+	target.Path = strings.TrimSuffix(target.Path, "/") + "/" + object
+	target.RawPath = uriEncode(target.Path, false)
+	return target, nil
+}
+
+func (s *S3Storage) scope(now time.Time) string {
+	return now.Format("20060102") + "/" + s.region + "/s3/aws4_request"
+}
+
+// signature computes the Signature Version 4 signature for a request
+func (s *S3Storage) signature(now time.Time, method string, target *url.URL, headers map[string]string, payloadHash string) string {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(headers[name]) + "\n")
+	}
+
+	canonicalRequest := strings.Join([]string{
+		method,
+		target.EscapedPath(),
+		target.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaderNames(headers),
+		payloadHash,
+	}, "\n")
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		now.Format("20060102T150405Z"),
+		s.scope(now),
+		hex.EncodeToString(requestHash[:]),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.secretKey), now.Format("20060102"))
+	key = hmacSHA256(key, s.region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	return hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+func signedHeaderNames(headers map[string]string) string {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ";")
+}
+
+// canonicalQuery sorts and encodes query parameters as Signature Version 4 expects
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		for _, value := range query[key] {
+			parts = append(parts, uriEncode(key, true)+"="+uriEncode(value, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// uriEncode percent-encodes everything except unreserved characters, and
+// slashes unless encodeSlash is set
+func uriEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,82 @@
+// Package storage keeps generated files such as exports on local disk or in
+// S3 or Google Cloud Storage, and hands out signed download URLs
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/andevellicus/crapp/internal/config"
+	"go.uber.org/zap"
+)
+
+// ErrNotFound is returned when an object does not exist
+var ErrNotFound = errors.New("storage: object not found")
+
+// Storage reads and writes objects by key. Keys use forward slashes, such as
+// "exports/default/crapp-export.zip".
+type Storage interface {
+	// Put stores the object, replacing any existing object with the same key
+	Put(ctx context.Context, key string, body io.Reader, contentType string) error
+	// Get opens the object for reading; the caller closes it
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the object; deleting a missing object is not an error
+	Delete(ctx context.Context, key string) error
+	// List returns the objects whose keys start with the prefix
+	List(ctx context.Context, prefix string) ([]ObjectInfo, error)
+	// SignedURL returns a URL that downloads the object without credentials
+	// until the TTL passes
+	SignedURL(key string, ttl time.Duration) (string, error)
+}
+
+// ObjectInfo describes a stored object
+type ObjectInfo struct {
+	Key          string    `json:"key"`
+	Size         int64     `json:"size"`
+	LastModified time.Time `json:"last_modified"`
+}
+
+// New creates the configured storage backend
+func New(cfg *config.StorageConfig, jwtSecret string, log *zap.SugaredLogger) (Storage, error) {
+	log = log.Named("storage")
+
+	switch cfg.Backend {
+	case config.StorageLocal, "":
+		signingKey := cfg.Local.SigningKey
+		if signingKey == "" {
+			signingKey = jwtSecret
+		}
+		log.Infow("Using local storage", "directory", cfg.Local.Directory)
+		return NewLocalStorage(cfg.Local.Directory, cfg.Prefix, signingKey)
+	case config.StorageS3:
+		log.Infow("Using S3 storage", "bucket", cfg.S3.Bucket, "region", cfg.S3.Region, "encryption", cfg.S3.Encryption)
+		return NewS3Storage(&cfg.S3, cfg.Prefix)
+	case config.StorageGCS:
+		log.Infow("Using Google Cloud Storage", "bucket", cfg.GCS.Bucket, "kms", cfg.GCS.KMSKeyName != "")
+		return NewGCSStorage(&cfg.GCS, cfg.Prefix)
+	}
+	return nil, fmt.Errorf("unknown storage backend %q", cfg.Backend)
+}
+
+// cleanKey rejects keys that are empty or escape the storage root
+func cleanKey(key string) (string, error) {
+	cleaned := path.Clean("/" + key)[1:]
+	if cleaned == "" || cleaned != strings.TrimPrefix(key, "/") {
+		return "", fmt.Errorf("invalid storage key %q", key)
+	}
+	return cleaned, nil
+}
+
+// objectKey applies the configured prefix to a validated key
+func objectKey(prefix, key string) (string, error) {
+	cleaned, err := cleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	return prefix + cleaned, nil
+}
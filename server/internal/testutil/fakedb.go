@@ -0,0 +1,244 @@
+// Package testutil provides fakes for tests that exercise repositories
+// without a database
+package testutil
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/andevellicus/crapp/internal/models"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Responder answers one SQL statement with its result columns and rows. An
+// error is returned to the caller as a database error.
+type Responder func(query string, args []driver.Value) (columns []string, rows [][]driver.Value, err error)
+
+// OpenGorm returns a gorm DB using the Postgres dialect whose statements are
+// answered by respond, so repositories build the SQL they would in production
+func OpenGorm(tb testing.TB, respond Responder) *gorm.DB {
+	tb.Helper()
+	sqlDB := sql.OpenDB(connector{respond: respond})
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB}), &gorm.Config{
+		Logger:                 logger.Discard,
+		SkipDefaultTransaction: true,
+	})
+	if err != nil {
+		tb.Fatalf("opening fake database: %v", err)
+	}
+	tb.Cleanup(func() { sqlDB.Close() })
+	return db
+}
+
+// conditionPattern matches a "column = $n" condition in generated SQL
+var conditionPattern = regexp.MustCompile(`([\w."]+|LOWER\(\w+\))\s*=\s*\$(\d+)`)
+
+// Users answers lookups and counts on the users table from a fixed set of
+// users. Conditions on email, organization, admin flags, and legal hold are
+// supported; any other statement fails the query.
+func Users(users ...models.User) Responder {
+	return func(query string, args []driver.Value) ([]string, [][]driver.Value, error) {
+		if !strings.Contains(query, `FROM "users"`) {
+			return nil, nil, fmt.Errorf("unexpected query: %s", query)
+		}
+
+		matched := []models.User{}
+		for _, user := range users {
+			ok, err := userMatches(user, query, args)
+			if err != nil {
+				return nil, nil, err
+			}
+			if ok {
+				matched = append(matched, user)
+			}
+		}
+
+		if strings.HasPrefix(query, "SELECT count(*)") {
+			return []string{"count"}, [][]driver.Value{{int64(len(matched))}}, nil
+		}
+		columns := []string{"email", "first_name", "is_admin", "is_org_admin", "is_blinded_reviewer", "organization_id", "study_id", "timezone"}
+		rows := make([][]driver.Value, len(matched))
+		for i, u := range matched {
+			rows[i] = []driver.Value{u.Email, u.FirstName, u.IsAdmin, u.IsOrgAdmin, u.IsBlindedReviewer, u.OrganizationID, u.StudyID, u.Timezone}
+		}
+		return columns, rows, nil
+	}
+}
+
+func userMatches(user models.User, query string, args []driver.Value) (bool, error) {
+	for _, condition := range conditionPattern.FindAllStringSubmatch(query, -1) {
+		var index int
+		fmt.Sscanf(condition[2], "%d", &index)
+		if index < 1 || index > len(args) {
+			return false, fmt.Errorf("missing argument $%d", index)
+		}
+		value := fmt.Sprint(args[index-1])
+
+		column := strings.ReplaceAll(condition[1], `"`, "")
+		column = strings.TrimPrefix(column, "users.")
+		var ok bool
+		switch column {
+		case "email", "LOWER(email)":
+			ok = strings.EqualFold(user.Email, value)
+		case "organization_id":
+			ok = user.OrganizationID == value
+		case "is_admin":
+			ok = fmt.Sprint(user.IsAdmin) == value
+		case "is_org_admin":
+			ok = fmt.Sprint(user.IsOrgAdmin) == value
+		case "legal_hold":
+			ok = fmt.Sprint(user.LegalHold) == value
+		default:
+			return false, fmt.Errorf("unsupported condition %q in: %s", condition[0], query)
+		}
+		if !ok {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// Statement is one SQL statement sent to a fake database
+type Statement struct {
+	Query string
+	Args  []driver.Value
+}
+
+// Recorder keeps every statement sent to a fake database. Lookups on the
+// users table are answered by Users, tables given to Rows answer with their
+// rows, and everything else succeeds without rows, so repository code that
+// writes across many tables can run to the end.
+type Recorder struct {
+	users      Responder
+	tables     map[string]tableRows
+	mu         sync.Mutex
+	statements []Statement
+}
+
+type tableRows struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+// NewRecorder returns a Recorder that knows the given users
+func NewRecorder(users ...models.User) *Recorder {
+	return &Recorder{users: Users(users...), tables: map[string]tableRows{}}
+}
+
+// Rows makes every SELECT from a table answer with the given rows
+func (r *Recorder) Rows(table string, columns []string, values ...[]driver.Value) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.tables[table] = tableRows{columns: columns, values: values}
+}
+
+// Respond is the Recorder's Responder, for OpenGorm
+func (r *Recorder) Respond(query string, args []driver.Value) ([]string, [][]driver.Value, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.statements = append(r.statements, Statement{Query: query, Args: args})
+
+	if !strings.HasPrefix(query, "SELECT") {
+		return nil, nil, nil
+	}
+	if strings.Contains(query, `FROM "users"`) {
+		return r.users(query, args)
+	}
+	for table, answer := range r.tables {
+		if strings.Contains(query, fmt.Sprintf(`FROM "%s"`, table)) {
+			return answer.columns, answer.values, nil
+		}
+	}
+	return nil, nil, nil
+}
+
+// Statements returns the statements recorded so far
+func (r *Recorder) Statements() []Statement {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.statements)
+}
+
+// connector opens connections that hand every statement to a Responder
+type connector struct {
+	respond Responder
+}
+
+func (c connector) Connect(context.Context) (driver.Conn, error) {
+	return &conn{respond: c.respond}, nil
+}
+func (c connector) Driver() driver.Driver { return fakeDriver{} }
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(string) (driver.Conn, error) {
+	return nil, errors.New("fake database connections are opened through a connector")
+}
+
+type conn struct {
+	respond Responder
+}
+
+func (c *conn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("prepared statements are not supported")
+}
+func (c *conn) Close() error              { return nil }
+func (c *conn) Begin() (driver.Tx, error) { return tx{}, nil }
+
+func (c *conn) QueryContext(_ context.Context, query string, named []driver.NamedValue) (driver.Rows, error) {
+	columns, values, err := c.respond(query, plainArgs(named))
+	if err != nil {
+		return nil, err
+	}
+	return &rows{columns: columns, values: values}, nil
+}
+
+func (c *conn) ExecContext(_ context.Context, query string, named []driver.NamedValue) (driver.Result, error) {
+	_, values, err := c.respond(query, plainArgs(named))
+	if err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(len(values)), nil
+}
+
+func plainArgs(named []driver.NamedValue) []driver.Value {
+	args := make([]driver.Value, len(named))
+	for i, arg := range named {
+		args[i] = arg.Value
+	}
+	return args
+}
+
+type tx struct{}
+
+func (tx) Commit() error   { return nil }
+func (tx) Rollback() error { return nil }
+
+type rows struct {
+	columns []string
+	values  [][]driver.Value
+	next    int
+}
+
+func (r *rows) Columns() []string { return r.columns }
+func (r *rows) Close() error      { return nil }
+
+func (r *rows) Next(dest []driver.Value) error {
+	if r.next >= len(r.values) {
+		return io.EOF
+	}
+	copy(dest, r.values[r.next])
+	r.next++
+	return nil
+}
@@ -0,0 +1,92 @@
+package upload
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/andevellicus/crapp/internal/config"
+)
+
+// clamAVChunkSize is how much of the file is sent per INSTREAM chunk
+const clamAVChunkSize = 64 << 10
+
+// ClamAVScanner streams files to a clamd daemon with the INSTREAM command
+type ClamAVScanner struct {
+	address string
+	timeout time.Duration
+}
+
+// NewClamAVScanner creates a scanner for the clamd at the configured address
+func NewClamAVScanner(cfg *config.ClamAVConfig) *ClamAVScanner {
+	return &ClamAVScanner{
+		address: cfg.Address,
+		timeout: time.Duration(cfg.TimeoutSeconds) * time.Second,
+	}
+}
+
+// Name identifies the scanner
+func (s *ClamAVScanner) Name() string { return config.ScannerClamAV }
+
+// Scan sends the file in length-prefixed chunks and reads clamd's reply,
+// such as "stream: OK" or "stream: Eicar-Signature FOUND"
+func (s *ClamAVScanner) Scan(ctx context.Context, _ string, body io.Reader) (Verdict, error) {
+	dialer := net.Dialer{Timeout: s.timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", s.address)
+	if err != nil {
+		return Verdict{}, fmt.Errorf("connecting to clamd: %w", err)
+	}
+	defer conn.Close()
+	if s.timeout > 0 {
+		conn.SetDeadline(time.Now().Add(s.timeout))
+	}
+
+	if _, err := io.WriteString(conn, "zINSTREAM\x00"); err != nil {
+		return Verdict{}, fmt.Errorf("starting clamd stream: %w", err)
+	}
+	buf := make([]byte, clamAVChunkSize)
+	size := make([]byte, 4)
+	for {
+		n, readErr := body.Read(buf)
+		if n > 0 {
+			binary.BigEndian.PutUint32(size, uint32(n))
+			if _, err := conn.Write(size); err != nil {
+				return Verdict{}, fmt.Errorf("streaming to clamd: %w", err)
+			}
+			if _, err := conn.Write(buf[:n]); err != nil {
+				return Verdict{}, fmt.Errorf("streaming to clamd: %w", err)
+			}
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			return Verdict{}, readErr
+		}
+	}
+	// A zero-length chunk ends the stream
+	binary.BigEndian.PutUint32(size, 0)
+	if _, err := conn.Write(size); err != nil {
+		return Verdict{}, fmt.Errorf("ending clamd stream: %w", err)
+	}
+
+	reply, err := bufio.NewReader(conn).ReadString('\x00')
+	if err != nil && reply == "" {
+		return Verdict{}, fmt.Errorf("reading clamd reply: %w", err)
+	}
+	reply = strings.TrimSpace(strings.TrimSuffix(reply, "\x00"))
+	result := strings.TrimSpace(strings.TrimPrefix(reply, "stream:"))
+
+	switch {
+	case result == "OK":
+		return Verdict{Clean: true}, nil
+	case strings.HasSuffix(result, " FOUND"):
+		return Verdict{Threat: strings.TrimSuffix(result, " FOUND")}, nil
+	}
+	return Verdict{}, fmt.Errorf("clamd error: %s", reply)
+}
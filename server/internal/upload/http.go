@@ -0,0 +1,66 @@
+package upload
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/andevellicus/crapp/internal/config"
+)
+
+// HTTPScanner posts files to an external scanning API
+type HTTPScanner struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+// NewHTTPScanner creates a scanner for the configured API
+func NewHTTPScanner(cfg *config.ScanAPIConfig) *HTTPScanner {
+	return &HTTPScanner{
+		url:    cfg.URL,
+		token:  cfg.Token,
+		client: &http.Client{Timeout: time.Duration(cfg.TimeoutSeconds) * time.Second},
+	}
+}
+
+// Name identifies the scanner
+func (s *HTTPScanner) Name() string { return config.ScannerHTTP }
+
+// Scan sends the file as the request body, with its name in X-Filename, and
+// reads a {"clean": bool, "threat": "..."} reply
+func (s *HTTPScanner) Scan(ctx context.Context, filename string, body io.Reader) (Verdict, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, body)
+	if err != nil {
+		return Verdict{}, err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("X-Filename", filename)
+	if s.token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.token)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return Verdict{}, fmt.Errorf("calling scanning API: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return Verdict{}, fmt.Errorf("scanning API returned %s", resp.Status)
+	}
+
+	var result struct {
+		Clean  bool   `json:"clean"`
+		Threat string `json:"threat"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&result); err != nil {
+		return Verdict{}, fmt.Errorf("decoding scanning API reply: %w", err)
+	}
+	if !result.Clean && result.Threat == "" {
+		result.Threat = "rejected by scanning API"
+	}
+	return Verdict{Clean: result.Clean, Threat: result.Threat}, nil
+}
@@ -0,0 +1,260 @@
+// Package upload checks files users upload before they are used or stored:
+// their size and type are validated, they are passed through a virus scanner,
+// and files the scanner rejects are moved to quarantine in storage
+package upload
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/andevellicus/crapp/internal/config"
+	"github.com/andevellicus/crapp/internal/storage"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrEmpty is returned for an upload with no content
+	ErrEmpty = errors.New("upload: file is empty")
+	// ErrTooLarge is returned when an upload exceeds the size limit
+	ErrTooLarge = errors.New("upload: file is too large")
+	// ErrTypeNotAllowed is returned when an upload's type isn't accepted
+	ErrTypeNotAllowed = errors.New("upload: file type is not allowed")
+	// ErrQuarantined is returned when the scanner rejected an upload, or
+	// couldn't check it and the pipeline fails closed
+	ErrQuarantined = errors.New("upload: file was quarantined")
+)
+
+// Scanner checks a file for viruses or other abuse
+type Scanner interface {
+	// Name identifies the scanner in logs
+	Name() string
+	// Scan reads the file and reports whether it is clean. An error means the
+	// file could not be checked.
+	Scan(ctx context.Context, filename string, body io.Reader) (Verdict, error)
+}
+
+// Verdict is a scanner's finding
+type Verdict struct {
+	Clean  bool
+	Threat string // What was found, when not clean
+}
+
+// NoopScanner accepts every file
+type NoopScanner struct{}
+
+// Name identifies the scanner
+func (NoopScanner) Name() string { return config.ScannerNone }
+
+// Scan accepts the file without reading it
+func (NoopScanner) Scan(context.Context, string, io.Reader) (Verdict, error) {
+	return Verdict{Clean: true}, nil
+}
+
+// Pipeline validates and scans uploads
+type Pipeline struct {
+	cfg     *config.UploadsConfig
+	scanner Scanner
+	store   storage.Storage
+	log     *zap.SugaredLogger
+}
+
+// File is an upload that passed every check. It is buffered in a temporary
+// file, which Close removes.
+type File struct {
+	Name        string
+	ContentType string
+	Size        int64
+	SHA256      string
+	temp        *os.File
+}
+
+// Reader reads the file from the start
+func (f *File) Reader() (io.Reader, error) {
+	if _, err := f.temp.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return f.temp, nil
+}
+
+// Close removes the buffered copy
+func (f *File) Close() error {
+	f.temp.Close()
+	return os.Remove(f.temp.Name())
+}
+
+// New creates the upload pipeline with the configured scanner. Quarantined
+// files are kept in the store.
+func New(cfg *config.UploadsConfig, store storage.Storage, log *zap.SugaredLogger) (*Pipeline, error) {
+	log = log.Named("upload")
+
+	var scanner Scanner
+	switch cfg.Scanner {
+	case config.ScannerNone, "":
+		scanner = NoopScanner{}
+	case config.ScannerClamAV:
+		scanner = NewClamAVScanner(&cfg.ClamAV)
+	case config.ScannerHTTP:
+		if cfg.HTTP.URL == "" {
+			return nil, errors.New("the http upload scanner needs a URL")
+		}
+		scanner = NewHTTPScanner(&cfg.HTTP)
+	default:
+		return nil, fmt.Errorf("unknown upload scanner %q", cfg.Scanner)
+	}
+
+	log.Infow("Upload scanning configured", "scanner", scanner.Name(), "max_bytes", cfg.MaxBytes, "allowed_types", cfg.AllowedTypes)
+	return &Pipeline{cfg: cfg, scanner: scanner, store: store, log: log}, nil
+}
+
+// WithLimits returns a pipeline with its own size and type limits that
+// shares this pipeline's scanner and quarantine
+func (p *Pipeline) WithLimits(maxBytes int64, allowedTypes []string) *Pipeline {
+	cfg := *p.cfg
+	cfg.MaxBytes = maxBytes
+	cfg.AllowedTypes = allowedTypes
+	limited := *p
+	limited.cfg = &cfg
+	return &limited
+}
+
+// Accept buffers an upload, checks its size and type, and scans it. The
+// category, such as "imports", groups quarantined files. The caller closes
+// the returned file.
+func (p *Pipeline) Accept(ctx context.Context, category, filename string, body io.Reader) (*File, error) {
+	filename = path.Base(strings.ReplaceAll(filename, "\\", "/"))
+	if filename == "." || filename == ".." || filename == "/" {
+		filename = "upload"
+	}
+
+	temp, err := os.CreateTemp("", "crapp-upload-*")
+	if err != nil {
+		return nil, fmt.Errorf("buffering upload: %w", err)
+	}
+	file := &File{Name: filename, temp: temp}
+	accepted := false
+	defer func() {
+		if !accepted {
+			file.Close()
+		}
+	}()
+
+	hash := sha256.New()
+	limit := p.cfg.MaxBytes
+	size, err := io.Copy(io.MultiWriter(temp, hash), io.LimitReader(body, limit+1))
+	if err != nil {
+		return nil, fmt.Errorf("reading upload: %w", err)
+	}
+	switch {
+	case size == 0:
+		return nil, ErrEmpty
+	case limit > 0 && size > limit:
+		return nil, ErrTooLarge
+	}
+	file.Size = size
+	file.SHA256 = hex.EncodeToString(hash.Sum(nil))
+
+	head := make([]byte, 512)
+	n, _ := temp.ReadAt(head, 0)
+	file.ContentType = detectType(filename, head[:n])
+	if len(p.cfg.AllowedTypes) > 0 && !slices.Contains(p.cfg.AllowedTypes, file.ContentType) {
+		return nil, fmt.Errorf("%w: %s", ErrTypeNotAllowed, file.ContentType)
+	}
+
+	reader, err := file.Reader()
+	if err != nil {
+		return nil, err
+	}
+	verdict, err := p.scanner.Scan(ctx, filename, reader)
+	switch {
+	case err != nil && p.cfg.FailOpen:
+		p.log.Warnw("Upload scanner unavailable, accepting file", "error", err, "scanner", p.scanner.Name(), "file", filename, "sha256", file.SHA256)
+	case err != nil:
+		p.log.Errorw("Upload scanner unavailable", "error", err, "scanner", p.scanner.Name(), "file", filename)
+		p.quarantine(ctx, category, file, "scanner unavailable")
+		return nil, fmt.Errorf("%w: the file could not be scanned", ErrQuarantined)
+	case !verdict.Clean:
+		p.quarantine(ctx, category, file, verdict.Threat)
+		return nil, fmt.Errorf("%w: %s", ErrQuarantined, verdict.Threat)
+	}
+
+	accepted = true
+	return file, nil
+}
+
+// Store saves an accepted file under uploads/<category>/ and returns its key
+func (p *Pipeline) Store(ctx context.Context, category string, file *File) (string, error) {
+	if p.store == nil {
+		return "", errors.New("upload storage is not configured")
+	}
+	reader, err := file.Reader()
+	if err != nil {
+		return "", err
+	}
+	key := objectKey("uploads", category, file.Name)
+	if err := p.store.Put(ctx, key, reader, file.ContentType); err != nil {
+		return "", fmt.Errorf("storing upload: %w", err)
+	}
+	return key, nil
+}
+
+// quarantine keeps a rejected file under quarantine/<category>/ for review
+func (p *Pipeline) quarantine(ctx context.Context, category string, file *File, reason string) {
+	if p.store == nil {
+		p.log.Warnw("Rejected upload dropped, no storage for quarantine", "file", file.Name, "reason", reason, "sha256", file.SHA256)
+		return
+	}
+	reader, err := file.Reader()
+	if err != nil {
+		p.log.Errorw("Failed to read upload for quarantine", "error", err, "file", file.Name)
+		return
+	}
+	key := objectKey("quarantine", category, file.Name)
+	if err := p.store.Put(ctx, key, reader, "application/octet-stream"); err != nil {
+		p.log.Errorw("Failed to quarantine upload", "error", err, "file", file.Name, "reason", reason)
+		return
+	}
+	p.log.Warnw("Quarantined upload", "key", key, "reason", reason, "sha256", file.SHA256, "scanner", p.scanner.Name())
+}
+
+// objectKey places a file under root/category/date/ with a unique prefix
+func objectKey(root, category, filename string) string {
+	return fmt.Sprintf("%s/%s/%s/%s-%s", root, category, time.Now().UTC().Format("2006-01-02"), uuid.NewString(), filename)
+}
+
+// textTypes covers text formats the system MIME table may not know
+var textTypes = map[string]string{
+	".csv": "text/csv",
+	".tsv": "text/tab-separated-values",
+}
+
+// detectType sniffs a file's MIME type. Sniffing can't tell text formats
+// apart, so the extension refines plain text, for example into text/csv.
+func detectType(filename string, head []byte) string {
+	detected, _, err := mime.ParseMediaType(http.DetectContentType(head))
+	if err != nil {
+		return "application/octet-stream"
+	}
+	if detected == "text/plain" {
+		extension := strings.ToLower(path.Ext(filename))
+		if textType, ok := textTypes[extension]; ok {
+			return textType
+		}
+		byExtension, _, err := mime.ParseMediaType(mime.TypeByExtension(extension))
+		if err == nil && strings.HasPrefix(byExtension, "text/") {
+			return byExtension
+		}
+	}
+	return detected
+}
@@ -0,0 +1,68 @@
+package utils
+
+import (
+	"math"
+	"slices"
+)
+
+// Options of each cognitive test that are times in milliseconds, lengthened
+// for users who need extended timeouts
+var timedTestOptions = map[string][]string{
+	"cpt":        {"stimulusDuration", "interStimulusInterval"},
+	"tmt":        {"partATimeLimit", "partBTimeLimit"},
+	"digit_span": {"displayTimePerDigit", "interDigitInterval", "recallTimeout"},
+}
+
+// CognitiveAdjustment adapts cognitive test settings to a user's
+// accessibility preferences
+type CognitiveAdjustment struct {
+	TimeoutFactor float64 // Multiplies stimulus durations and time limits; 0 or 1 keeps them
+	TargetScale   float64 // Size hint for Trail Making Test targets; 0 or 1 keeps the default
+	ReducedMotion bool    // Asks the tests to leave out animations
+}
+
+// IsCognitiveTest reports whether a question type is a cognitive test
+func IsCognitiveTest(questionType string) bool {
+	_, ok := timedTestOptions[questionType]
+	return ok
+}
+
+// Apply returns the question with its test settings adjusted. Questions that
+// aren't cognitive tests are returned unchanged.
+func (a CognitiveAdjustment) Apply(q Question) Question {
+	timed, ok := timedTestOptions[q.Type]
+	if !ok {
+		return q
+	}
+
+	options := make([]QuestionOption, 0, len(q.Options)+2)
+	for _, option := range q.Options {
+		if a.TimeoutFactor > 0 && a.TimeoutFactor != 1 && slices.Contains(timed, option.Label) {
+			if ms := OptionNumber(option); !math.IsNaN(ms) {
+				option.Value = int(math.Round(ms * a.TimeoutFactor))
+			}
+		}
+		options = append(options, option)
+	}
+	if q.Type == "tmt" && a.TargetScale > 0 && a.TargetScale != 1 {
+		options = setOption(options, "targetScale", a.TargetScale)
+	}
+	if a.ReducedMotion {
+		options = setOption(options, "reducedMotion", true)
+	}
+
+	q.Options = options
+	return q
+}
+
+// setOption replaces the value of the option with a label, adding the
+// option if there is none
+func setOption(options []QuestionOption, label string, value any) []QuestionOption {
+	for i := range options {
+		if options[i].Label == label {
+			options[i].Value = value
+			return options
+		}
+	}
+	return append(options, QuestionOption{Value: value, Label: label})
+}
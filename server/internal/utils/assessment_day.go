@@ -0,0 +1,144 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// AssessmentDay assigns moments to the day of the assessment they count
+// towards. A day runs from its cutoff time until the next day's cutoff in the
+// configured time zone, so a report made shortly after midnight still counts
+// for the evening before. Cutoffs are wall-clock times, so a day is an hour
+// shorter or longer when the clocks change.
+type AssessmentDay struct {
+	location *time.Location
+	timezone string // IANA name used in queries, empty for the database session zone
+	cutoff   time.Duration
+}
+
+// NewAssessmentDay creates an assessment day for an IANA time zone and an
+// HH:MM cutoff. An empty time zone uses the server's local zone. Invalid
+// settings fall back to local midnight and are reported in the error.
+func NewAssessmentDay(timezone, cutoff string) (AssessmentDay, error) {
+	day := AssessmentDay{location: time.Local}
+
+	var errs []string
+	if timezone != "" {
+		loc, err := time.LoadLocation(timezone)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("invalid time zone %q", timezone))
+		} else {
+			day.location = loc
+			day.timezone = timezone
+		}
+	}
+
+	withCutoff, err := day.WithCutoff(cutoff)
+	if err != nil {
+		errs = append(errs, err.Error())
+	} else {
+		day = withCutoff
+	}
+
+	if len(errs) > 0 {
+		return day, fmt.Errorf("assessment day: %s", strings.Join(errs, "; "))
+	}
+	return day, nil
+}
+
+// WithCutoff returns a copy of the assessment day using another HH:MM cutoff,
+// such as one from a user's preferences. An empty cutoff keeps the current one.
+func (d AssessmentDay) WithCutoff(cutoff string) (AssessmentDay, error) {
+	if cutoff == "" {
+		return d, nil
+	}
+	t, err := time.Parse("15:04", cutoff)
+	if err != nil {
+		return d, fmt.Errorf("invalid cutoff time %q", cutoff)
+	}
+	d.cutoff = time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+	return d, nil
+}
+
+// InZone returns a copy of the assessment day in another IANA time zone, such
+// as a participant's own. An empty time zone keeps the current one.
+func (d AssessmentDay) InZone(timezone string) (AssessmentDay, error) {
+	if timezone == "" {
+		return d, nil
+	}
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return d, fmt.Errorf("invalid time zone %q", timezone)
+	}
+	d.location = loc
+	d.timezone = timezone
+	return d, nil
+}
+
+// Location returns the time zone assessment days are defined in
+func (d AssessmentDay) Location() *time.Location {
+	return d.location
+}
+
+// Of returns the assessment day a moment counts towards, as the first moment
+// of that date in the assessment time zone
+func (d AssessmentDay) Of(t time.Time) time.Time {
+	local := t.In(d.location)
+	day := d.date(local.Year(), local.Month(), local.Day())
+
+	// Compare with the start of the day rather than the time elapsed since
+	// midnight, which is off by an hour on the days the clocks change
+	if t.Before(d.Start(day)) {
+		day = d.date(local.Year(), local.Month(), local.Day()-1)
+	}
+	return day
+}
+
+// Today returns the current assessment day
+func (d AssessmentDay) Today() time.Time {
+	return d.Of(time.Now())
+}
+
+// AddDays returns the assessment day a number of days after another, or
+// before it for a negative number. Use it rather than AddDate, which lands
+// an hour early where the clocks skip midnight.
+func (d AssessmentDay) AddDays(day time.Time, days int) time.Time {
+	day = day.In(d.location)
+	return d.date(day.Year(), day.Month(), day.Day()+days)
+}
+
+// Start returns the moment an assessment day begins. A cutoff skipped when
+// the clocks spring forward begins the day as they jump, and a repeated
+// cutoff begins it at its first occurrence.
+func (d AssessmentDay) Start(day time.Time) time.Time {
+	day = day.In(d.location)
+	cutoffHour, cutoffMinute := int(d.cutoff/time.Hour), int(d.cutoff%time.Hour/time.Minute)
+	return WallClock(day.Year(), day.Month(), day.Day(), cutoffHour, cutoffMinute, d.location)
+}
+
+// Date parses a YYYY-MM-DD assessment day
+func (d AssessmentDay) Date(value string) (time.Time, error) {
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return t, err
+	}
+	return d.date(t.Year(), t.Month(), t.Day()), nil
+}
+
+// date returns the first moment of a date, which is not midnight where the
+// clocks skip it
+func (d AssessmentDay) date(year int, month time.Month, day int) time.Time {
+	return WallClock(year, month, day, 0, 0, d.location)
+}
+
+// SQL returns a Postgres expression for the assessment day of a timestamptz
+// column. The cutoff is taken off the local clock time, which matches Of
+// except during a repeated hour when the cutoff falls inside it.
+func (d AssessmentDay) SQL(column string) string {
+	local := fmt.Sprintf("(%s)::timestamp", column)
+	if d.timezone != "" {
+		local = fmt.Sprintf("(%s AT TIME ZONE '%s')", column, strings.ReplaceAll(d.timezone, "'", "''"))
+	}
+	return fmt.Sprintf("(%s - INTERVAL '%d minutes')::date", local, int(d.cutoff.Minutes()))
+}
@@ -0,0 +1,165 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func newTestDay(t *testing.T, zone, cutoff string) AssessmentDay {
+	t.Helper()
+	day, err := NewAssessmentDay(zone, cutoff)
+	if err != nil {
+		t.Fatalf("NewAssessmentDay(%q, %q): %v", zone, cutoff, err)
+	}
+	return day
+}
+
+func TestAssessmentDayOfAcrossClockChanges(t *testing.T) {
+	tests := []struct {
+		name   string
+		zone   string
+		cutoff string
+		at     time.Time
+		want   string
+	}{
+		// Time elapsed since midnight is an hour off the clock on these days,
+		// which would put these reports on the wrong side of the cutoff
+		{name: "after the cutoff on spring forward", zone: "America/New_York", cutoff: "04:00", at: utc(time.March, 8, 8, 30), want: "2026-03-08"},
+		{name: "before the cutoff on spring forward", zone: "America/New_York", cutoff: "04:00", at: utc(time.March, 8, 7, 30), want: "2026-03-07"},
+		{name: "before the cutoff on fall back", zone: "America/New_York", cutoff: "04:00", at: utc(time.November, 1, 8, 30), want: "2026-10-31"},
+		{name: "after the cutoff on fall back", zone: "America/New_York", cutoff: "04:00", at: utc(time.November, 1, 9, 0), want: "2026-11-01"},
+		{name: "London after the cutoff on spring forward", zone: "Europe/London", cutoff: "02:00", at: utc(time.March, 29, 1, 30), want: "2026-03-29"},
+		{name: "London first repeated hour", zone: "Europe/London", cutoff: "02:00", at: utc(time.October, 25, 0, 30), want: "2026-10-24"},
+		{name: "London second repeated hour", zone: "Europe/London", cutoff: "02:00", at: utc(time.October, 25, 1, 30), want: "2026-10-24"},
+		{name: "London after the cutoff on fall back", zone: "Europe/London", cutoff: "02:00", at: utc(time.October, 25, 2, 0), want: "2026-10-25"},
+		{name: "before a skipped midnight", zone: "America/Santiago", at: utc(time.September, 6, 3, 59), want: "2026-09-05"},
+		{name: "after a skipped midnight", zone: "America/Santiago", at: utc(time.September, 6, 4, 0), want: "2026-09-06"},
+		{name: "repeated hour before midnight", zone: "America/Santiago", at: utc(time.April, 5, 3, 30), want: "2026-04-04"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			day := newTestDay(t, tt.zone, tt.cutoff)
+			if got := day.Of(tt.at).Format("2006-01-02"); got != tt.want {
+				t.Errorf("Of(%v) = %s, want %s", tt.at.In(day.Location()), got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAssessmentDayStartAcrossClockChanges(t *testing.T) {
+	tests := []struct {
+		name      string
+		zone      string
+		cutoff    string
+		day       string
+		want      time.Time
+		wantHours float64 // Length of the day
+	}{
+		{name: "cutoff after the jump on spring forward", zone: "America/New_York", cutoff: "04:00", day: "2026-03-08", want: utc(time.March, 8, 8, 0), wantHours: 24},
+		{name: "day before spring forward", zone: "America/New_York", cutoff: "04:00", day: "2026-03-07", want: utc(time.March, 7, 9, 0), wantHours: 23},
+		{name: "day before fall back", zone: "America/New_York", cutoff: "04:00", day: "2026-10-31", want: utc(time.October, 31, 8, 0), wantHours: 25},
+		{name: "skipped cutoff starts as the clocks jump", zone: "America/New_York", cutoff: "02:30", day: "2026-03-08", want: utc(time.March, 8, 7, 0), wantHours: 23.5},
+		{name: "repeated cutoff starts at its first occurrence", zone: "America/New_York", cutoff: "01:30", day: "2026-11-01", want: utc(time.November, 1, 5, 30), wantHours: 25},
+		{name: "London day before spring forward", zone: "Europe/London", cutoff: "02:00", day: "2026-03-28", want: utc(time.March, 28, 2, 0), wantHours: 23},
+		{name: "London day before fall back", zone: "Europe/London", cutoff: "02:00", day: "2026-10-24", want: utc(time.October, 24, 1, 0), wantHours: 25},
+		{name: "skipped midnight", zone: "America/Santiago", day: "2026-09-06", want: utc(time.September, 6, 4, 0), wantHours: 23},
+		{name: "day before skipped midnight", zone: "America/Santiago", day: "2026-09-05", want: utc(time.September, 5, 4, 0), wantHours: 24},
+		{name: "day with a repeated hour", zone: "America/Santiago", day: "2026-04-04", want: utc(time.April, 4, 3, 0), wantHours: 25},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			day := newTestDay(t, tt.zone, tt.cutoff)
+			date, err := day.Date(tt.day)
+			if err != nil {
+				t.Fatal(err)
+			}
+			start := day.Start(date)
+			if !start.Equal(tt.want) {
+				t.Errorf("Start(%s) = %v, want %v", tt.day, start.UTC(), tt.want)
+			}
+			end := day.Start(day.AddDays(date, 1))
+			if hours := end.Sub(start).Hours(); hours != tt.wantHours {
+				t.Errorf("%s lasts %v hours, want %v", tt.day, hours, tt.wantHours)
+			}
+		})
+	}
+}
+
+// TestAssessmentDayOfMatchesStart checks every minute of the days around the
+// clock changes falls between the start of its assessment day and the next
+func TestAssessmentDayOfMatchesStart(t *testing.T) {
+	ranges := []struct {
+		zone, cutoff string
+		from         time.Time
+	}{
+		{"America/New_York", "04:00", utc(time.March, 7, 0, 0)},
+		{"America/New_York", "01:30", utc(time.October, 31, 0, 0)},
+		{"Europe/London", "01:30", utc(time.March, 28, 0, 0)},
+		{"Europe/London", "02:00", utc(time.October, 24, 0, 0)},
+		{"America/Santiago", "", utc(time.April, 3, 0, 0)},
+		{"America/Santiago", "", utc(time.September, 5, 0, 0)},
+	}
+
+	for _, r := range ranges {
+		day := newTestDay(t, r.zone, r.cutoff)
+		for at := r.from; at.Before(r.from.Add(72 * time.Hour)); at = at.Add(time.Minute) {
+			of := day.Of(at)
+			start, end := day.Start(of), day.Start(day.AddDays(of, 1))
+			if at.Before(start) || !at.Before(end) {
+				t.Fatalf("%s cutoff %q: %v is assigned to %s, which runs %v to %v",
+					r.zone, r.cutoff, at.In(day.Location()), of.Format("2006-01-02"), start, end)
+			}
+		}
+	}
+}
+
+func TestAssessmentDayDatesAcrossSkippedMidnight(t *testing.T) {
+	day := newTestDay(t, "America/Santiago", "")
+
+	date, err := day.Date("2026-09-06")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := date.Format("2006-01-02"); got != "2026-09-06" {
+		t.Errorf("Date(2026-09-06) = %v", date)
+	}
+	before, err := day.Date("2026-09-05")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := day.AddDays(before, 1); !got.Equal(date) {
+		t.Errorf("AddDays(2026-09-05, 1) = %v, want %v", got, date)
+	}
+	if got := day.AddDays(date, -1); !got.Equal(before) {
+		t.Errorf("AddDays(2026-09-06, -1) = %v, want %v", got, before)
+	}
+}
+
+func TestAssessmentDayInZone(t *testing.T) {
+	day := newTestDay(t, "UTC", "04:00")
+
+	local, err := day.InZone("Europe/London")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 03:30 UTC the day after fall back is 03:30 GMT, before the cutoff
+	if got := local.Of(utc(time.October, 26, 3, 30)).Format("2006-01-02"); got != "2026-10-25" {
+		t.Errorf("Of = %s, want 2026-10-25", got)
+	}
+	if _, err := day.InZone("Mars/Olympus"); err == nil {
+		t.Error("InZone accepted an unknown time zone")
+	}
+	if kept, _ := day.InZone(""); kept.Location() != day.Location() {
+		t.Error("InZone with no time zone changed the zone")
+	}
+}
+
+func TestAssessmentDaySQL(t *testing.T) {
+	day := newTestDay(t, "America/New_York", "04:00")
+	want := "((created_at AT TIME ZONE 'America/New_York') - INTERVAL '240 minutes')::date"
+	if got := day.SQL("created_at"); got != want {
+		t.Errorf("SQL = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,75 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// AssetVersions fingerprints built assets by content hash, so pages can link
+// to a URL that changes whenever the file does. Hashes are recomputed when a
+// file's modification time changes, which keeps rebuilds during development
+// visible without a restart.
+type AssetVersions struct {
+	mu     sync.Mutex
+	files  map[string]string // URL path to file path
+	hashes map[string]assetHash
+}
+
+type assetHash struct {
+	modTime time.Time
+	version string
+}
+
+// NewAssetVersions creates fingerprints for the given URL paths and files
+func NewAssetVersions(files map[string]string) *AssetVersions {
+	return &AssetVersions{
+		files:  files,
+		hashes: make(map[string]assetHash),
+	}
+}
+
+// Version returns the short content hash of the asset served at a URL path,
+// or "" when the path isn't fingerprinted or the file can't be read
+func (a *AssetVersions) Version(urlPath string) string {
+	file, ok := a.files[urlPath]
+	if !ok {
+		return ""
+	}
+	info, err := os.Stat(file)
+	if err != nil {
+		return ""
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if cached, ok := a.hashes[urlPath]; ok && cached.modTime.Equal(info.ModTime()) {
+		return cached.version
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return ""
+	}
+	version := hex.EncodeToString(h.Sum(nil))[:12]
+	a.hashes[urlPath] = assetHash{modTime: info.ModTime(), version: version}
+	return version
+}
+
+// URL returns the asset URL with its fingerprint as a query parameter
+func (a *AssetVersions) URL(urlPath string) string {
+	if version := a.Version(urlPath); version != "" {
+		return urlPath + "?v=" + version
+	}
+	return urlPath
+}
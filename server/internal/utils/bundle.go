@@ -0,0 +1,148 @@
+package utils
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"hash"
+	"io"
+	"time"
+
+	"github.com/andevellicus/crapp/internal/buildinfo"
+)
+
+// BundleManifestFile is the name of the manifest inside every bundle
+const BundleManifestFile = "manifest.json"
+
+// BundleManifest describes a downloadable bundle, so downstream pipelines
+// can check that it is complete before using it
+type BundleManifest struct {
+	Kind           string          `json:"kind"` // What the bundle holds, such as "analysis_export"
+	Generator      BundleGenerator `json:"generator"`
+	CreatedAt      time.Time       `json:"created_at"`
+	Filters        map[string]any  `json:"filters"` // What was asked for, such as the date range
+	Files          []BundleFile    `json:"files"`
+	DataDictionary any             `json:"data_dictionary,omitempty"`
+	// Set when manifest.sig holds a signature of this file
+	Signature *BundleSignature `json:"signature,omitempty"`
+}
+
+// BundleGenerator identifies the build that wrote a bundle
+type BundleGenerator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Commit  string `json:"commit,omitempty"`
+}
+
+// BundleFile lists one file in a bundle with its checksum
+type BundleFile struct {
+	Name   string `json:"name"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// Bundle writes a zip archive whose files are recorded, with their sizes and
+// SHA-256 checksums, in a manifest.json added when it is closed. When a
+// signer is set, the manifest's Ed25519 signature is added as manifest.sig.
+type Bundle struct {
+	archive  *zip.Writer
+	manifest BundleManifest
+	current  *bundleEntry
+}
+
+// bundleEntry counts and hashes a file as it is written
+type bundleEntry struct {
+	name string
+	w    io.Writer
+	hash hash.Hash
+	size int64
+}
+
+func (e *bundleEntry) Write(p []byte) (int, error) {
+	n, err := e.w.Write(p)
+	e.hash.Write(p[:n])
+	e.size += int64(n)
+	return n, err
+}
+
+// NewBundle starts a bundle of the given kind. Filters record the options it
+// was created with and may be nil.
+func NewBundle(w io.Writer, kind string, filters map[string]any) *Bundle {
+	if filters == nil {
+		filters = map[string]any{}
+	}
+	info := buildinfo.Get()
+	return &Bundle{
+		archive: zip.NewWriter(w),
+		manifest: BundleManifest{
+			Kind:      kind,
+			Generator: BundleGenerator{Name: "crapp", Version: info.Version, Commit: info.Commit},
+			CreatedAt: time.Now().UTC(),
+			Filters:   filters,
+			Files:     []BundleFile{},
+		},
+	}
+}
+
+// Create adds a file to the bundle. It must be fully written before the next
+// file is created.
+func (b *Bundle) Create(name string) (io.Writer, error) {
+	b.finishEntry()
+	w, err := b.archive.Create(name)
+	if err != nil {
+		return nil, err
+	}
+	b.current = &bundleEntry{name: name, w: w, hash: sha256.New()}
+	return b.current, nil
+}
+
+// SetDataDictionary describes the bundle's variables in the manifest
+func (b *Bundle) SetDataDictionary(dictionary any) {
+	b.manifest.DataDictionary = dictionary
+}
+
+// Close writes the manifest, and its signature when a signer is set, and
+// finishes the archive
+func (b *Bundle) Close() error {
+	b.finishEntry()
+
+	signer := currentBundleSigner()
+	if signer != nil {
+		b.manifest.Signature = &BundleSignature{Algorithm: "Ed25519", KeyID: signer.KeyID()}
+	}
+	manifest, err := json.MarshalIndent(b.manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	w, err := b.archive.Create(BundleManifestFile)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(manifest); err != nil {
+		return err
+	}
+	if signer != nil {
+		w, err := b.archive.Create(BundleSignatureFile)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(w, signer.Sign(manifest)); err != nil {
+			return err
+		}
+	}
+	return b.archive.Close()
+}
+
+func (b *Bundle) finishEntry() {
+	if b.current == nil {
+		return
+	}
+	b.manifest.Files = append(b.manifest.Files, BundleFile{
+		Name:   b.current.name,
+		Size:   b.current.size,
+		SHA256: hex.EncodeToString(b.current.hash.Sum(nil)),
+	})
+	b.current = nil
+}
@@ -0,0 +1,89 @@
+package utils
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"sync"
+)
+
+// BundleSignatureFile holds the signature of a bundle's manifest.json
+const BundleSignatureFile = "manifest.sig"
+
+// BundleSigner signs bundle manifests with an Ed25519 key. Because the
+// manifest lists every file's SHA-256 checksum, the signature covers the
+// whole bundle.
+type BundleSigner struct {
+	key   ed25519.PrivateKey
+	keyID string
+}
+
+// BundleSignature identifies the key a manifest is signed with
+type BundleSignature struct {
+	Algorithm string `json:"algorithm"`
+	KeyID     string `json:"key_id"`
+}
+
+var (
+	bundleSignerMu sync.RWMutex
+	bundleSigner   *BundleSigner
+)
+
+// NewBundleSigner creates a signer from a base64 Ed25519 seed. Without a
+// seed, one is derived from the fallback secret so the key survives restarts.
+func NewBundleSigner(seed, fallbackSecret string) (*BundleSigner, error) {
+	var seedBytes []byte
+	if seed != "" {
+		decoded, err := base64.StdEncoding.DecodeString(seed)
+		if err != nil {
+			return nil, fmt.Errorf("signing key is not valid base64: %w", err)
+		}
+		if len(decoded) != ed25519.SeedSize {
+			return nil, fmt.Errorf("signing key must be a %d-byte Ed25519 seed", ed25519.SeedSize)
+		}
+		seedBytes = decoded
+	} else {
+		if fallbackSecret == "" {
+			return nil, fmt.Errorf("no signing key or secret to derive one from")
+		}
+		derived := sha256.Sum256([]byte("crapp bundle signing\n" + fallbackSecret))
+		seedBytes = derived[:]
+	}
+
+	key := ed25519.NewKeyFromSeed(seedBytes)
+	fingerprint := sha256.Sum256(key.Public().(ed25519.PublicKey))
+	return &BundleSigner{
+		key:   key,
+		keyID: hex.EncodeToString(fingerprint[:8]),
+	}, nil
+}
+
+// PublicKey returns the key that verifies signatures
+func (s *BundleSigner) PublicKey() ed25519.PublicKey {
+	return s.key.Public().(ed25519.PublicKey)
+}
+
+// KeyID is a short fingerprint of the public key
+func (s *BundleSigner) KeyID() string {
+	return s.keyID
+}
+
+// Sign returns the base64 signature of data
+func (s *BundleSigner) Sign(data []byte) string {
+	return base64.StdEncoding.EncodeToString(ed25519.Sign(s.key, data))
+}
+
+// SetBundleSigner sets the signer used by every bundle written afterwards
+func SetBundleSigner(signer *BundleSigner) {
+	bundleSignerMu.Lock()
+	defer bundleSignerMu.Unlock()
+	bundleSigner = signer
+}
+
+func currentBundleSigner() *BundleSigner {
+	bundleSignerMu.RLock()
+	defer bundleSignerMu.RUnlock()
+	return bundleSigner
+}
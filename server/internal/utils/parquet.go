@@ -0,0 +1,374 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"time"
+)
+
+// ParquetType is the logical type of a Parquet column
+type ParquetType int
+
+// Supported column types. Timestamps are stored as UTC milliseconds.
+const (
+	ParquetString ParquetType = iota
+	ParquetInt64
+	ParquetDouble
+	ParquetBool
+	ParquetTimestamp
+)
+
+// ParquetColumn describes one column of a Parquet file. Values of optional
+// columns may be nil.
+type ParquetColumn struct {
+	Name     string
+	Type     ParquetType
+	Optional bool
+}
+
+// Parquet physical types, converted types, and enums from parquet.thrift
+const (
+	pqBoolean   = 0
+	pqInt64     = 2
+	pqDouble    = 5
+	pqByteArray = 6
+
+	pqConvertedUTF8            = 0
+	pqConvertedTimestampMillis = 9
+
+	pqRequired = 0
+	pqOptional = 1
+
+	pqEncodingPlain = 0
+	pqEncodingRLE   = 3
+	pqDataPage      = 0
+	pqUncompressed  = 0
+)
+
+// WriteParquet writes rows as a single row group Parquet file with
+// uncompressed, plain-encoded columns. Each row holds one value per column:
+// string, int64, float64, bool, or time.Time, matching the column type. The
+// metadata is stored in the file footer as key-value pairs.
+func WriteParquet(w io.Writer, columns []ParquetColumn, rows [][]any, metadata map[string]string) error {
+	var file bytes.Buffer
+	file.WriteString("PAR1")
+
+	chunks := make([]parquetChunk, len(columns))
+	for i, col := range columns {
+		page, err := encodeParquetPage(col, i, rows)
+		if err != nil {
+			return err
+		}
+
+		header := &thriftWriter{}
+		header.structBegin()
+		header.i32Field(1, pqDataPage)
+		header.i32Field(2, int32(len(page)))
+		header.i32Field(3, int32(len(page)))
+		header.structField(5)
+		header.i32Field(1, int32(len(rows)))
+		header.i32Field(2, pqEncodingPlain)
+		header.i32Field(3, pqEncodingRLE)
+		header.i32Field(4, pqEncodingRLE)
+		header.structEnd()
+		header.structEnd()
+
+		chunks[i] = parquetChunk{
+			offset: int64(file.Len()),
+			size:   int64(header.buf.Len() + len(page)),
+		}
+		file.Write(header.buf.Bytes())
+		file.Write(page)
+	}
+
+	footer := encodeParquetFooter(columns, chunks, int64(len(rows)), metadata)
+	file.Write(footer)
+	binary.Write(&file, binary.LittleEndian, uint32(len(footer)))
+	file.WriteString("PAR1")
+
+	_, err := w.Write(file.Bytes())
+	return err
+}
+
+// parquetChunk locates a column chunk within the file
+type parquetChunk struct {
+	offset int64
+	size   int64
+}
+
+// encodeParquetPage encodes one column as a data page: definition levels for
+// optional columns, then the non-null values
+func encodeParquetPage(col ParquetColumn, index int, rows [][]any) ([]byte, error) {
+	var page bytes.Buffer
+
+	if col.Optional {
+		levels := make([]bool, len(rows))
+		for r, row := range rows {
+			levels[r] = row[index] != nil
+		}
+		encoded := encodeDefinitionLevels(levels)
+		binary.Write(&page, binary.LittleEndian, uint32(len(encoded)))
+		page.Write(encoded)
+	}
+
+	var bits []bool
+	for r, row := range rows {
+		value := row[index]
+		if value == nil {
+			if !col.Optional {
+				return nil, fmt.Errorf("parquet column %s: row %d is null but the column is required", col.Name, r)
+			}
+			continue
+		}
+
+		var ok bool
+		switch col.Type {
+		case ParquetString:
+			var s string
+			if s, ok = value.(string); ok {
+				binary.Write(&page, binary.LittleEndian, uint32(len(s)))
+				page.WriteString(s)
+			}
+		case ParquetInt64:
+			var v int64
+			if v, ok = value.(int64); ok {
+				binary.Write(&page, binary.LittleEndian, v)
+			}
+		case ParquetDouble:
+			var v float64
+			if v, ok = value.(float64); ok {
+				binary.Write(&page, binary.LittleEndian, math.Float64bits(v))
+			}
+		case ParquetTimestamp:
+			var t time.Time
+			if t, ok = value.(time.Time); ok {
+				binary.Write(&page, binary.LittleEndian, t.UnixMilli())
+			}
+		case ParquetBool:
+			var b bool
+			if b, ok = value.(bool); ok {
+				bits = append(bits, b)
+			}
+		}
+		if !ok {
+			return nil, fmt.Errorf("parquet column %s: row %d has unexpected value type %T", col.Name, r, value)
+		}
+	}
+
+	// Booleans are bit-packed, least significant bit first
+	if col.Type == ParquetBool {
+		packed := make([]byte, (len(bits)+7)/8)
+		for i, b := range bits {
+			if b {
+				packed[i/8] |= 1 << (i % 8)
+			}
+		}
+		page.Write(packed)
+	}
+
+	return page.Bytes(), nil
+}
+
+// encodeDefinitionLevels run-length encodes 0/1 definition levels using the
+// RLE half of Parquet's RLE/bit-packing hybrid, with a bit width of one
+func encodeDefinitionLevels(levels []bool) []byte {
+	var out []byte
+	for i := 0; i < len(levels); {
+		run := 1
+		for i+run < len(levels) && levels[i+run] == levels[i] {
+			run++
+		}
+		out = binary.AppendUvarint(out, uint64(run)<<1)
+		if levels[i] {
+			out = append(out, 1)
+		} else {
+			out = append(out, 0)
+		}
+		i += run
+	}
+	return out
+}
+
+// encodeParquetFooter encodes the FileMetaData struct
+func encodeParquetFooter(columns []ParquetColumn, chunks []parquetChunk, numRows int64, metadata map[string]string) []byte {
+	t := &thriftWriter{}
+	t.structBegin()
+	t.i32Field(1, 1) // version
+
+	// Schema: a root element followed by one element per column
+	t.listField(2, thriftStruct, len(columns)+1)
+	t.structBegin()
+	t.stringField(4, "schema")
+	t.i32Field(5, int32(len(columns)))
+	t.structEnd()
+	for _, col := range columns {
+		physical, converted := parquetPhysicalType(col.Type)
+		repetition := int32(pqRequired)
+		if col.Optional {
+			repetition = pqOptional
+		}
+		t.structBegin()
+		t.i32Field(1, physical)
+		t.i32Field(3, repetition)
+		t.stringField(4, col.Name)
+		if converted >= 0 {
+			t.i32Field(6, converted)
+		}
+		t.structEnd()
+	}
+
+	t.i64Field(3, numRows)
+
+	// One row group holding every column
+	var totalSize int64
+	for _, chunk := range chunks {
+		totalSize += chunk.size
+	}
+	t.listField(4, thriftStruct, 1)
+	t.structBegin()
+	t.listField(1, thriftStruct, len(columns))
+	for i, col := range columns {
+		physical, _ := parquetPhysicalType(col.Type)
+		t.structBegin()
+		t.i64Field(2, chunks[i].offset)
+		t.structField(3)
+		t.i32Field(1, physical)
+		t.listField(2, thriftI32, 2)
+		t.writeVarint(zigzag(pqEncodingPlain))
+		t.writeVarint(zigzag(pqEncodingRLE))
+		t.listField(3, thriftBinary, 1)
+		t.writeBinary(col.Name)
+		t.i32Field(4, pqUncompressed)
+		t.i64Field(5, numRows)
+		t.i64Field(6, chunks[i].size)
+		t.i64Field(7, chunks[i].size)
+		t.i64Field(9, chunks[i].offset)
+		t.structEnd()
+		t.structEnd()
+	}
+	t.i64Field(2, totalSize)
+	t.i64Field(3, numRows)
+	t.structEnd()
+
+	if len(metadata) > 0 {
+		keys := make([]string, 0, len(metadata))
+		for key := range metadata {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		t.listField(5, thriftStruct, len(keys))
+		for _, key := range keys {
+			t.structBegin()
+			t.stringField(1, key)
+			t.stringField(2, metadata[key])
+			t.structEnd()
+		}
+	}
+
+	t.stringField(6, "crapp")
+	t.structEnd()
+	return t.buf.Bytes()
+}
+
+// parquetPhysicalType maps a column type to its physical and converted
+// types. A converted type of -1 means none.
+func parquetPhysicalType(typ ParquetType) (int32, int32) {
+	switch typ {
+	case ParquetString:
+		return pqByteArray, pqConvertedUTF8
+	case ParquetInt64:
+		return pqInt64, -1
+	case ParquetDouble:
+		return pqDouble, -1
+	case ParquetBool:
+		return pqBoolean, -1
+	case ParquetTimestamp:
+		return pqInt64, pqConvertedTimestampMillis
+	}
+	return pqByteArray, -1
+}
+
+// Thrift compact protocol type IDs
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// thriftWriter encodes structs with the Thrift compact protocol, which
+// Parquet uses for page headers and the file footer
+type thriftWriter struct {
+	buf       bytes.Buffer
+	lastField []int16
+}
+
+func (t *thriftWriter) structBegin() {
+	t.lastField = append(t.lastField, 0)
+}
+
+func (t *thriftWriter) structEnd() {
+	t.buf.WriteByte(0) // stop field
+	t.lastField = t.lastField[:len(t.lastField)-1]
+}
+
+func (t *thriftWriter) fieldHeader(id int16, typ byte) {
+	last := &t.lastField[len(t.lastField)-1]
+	if delta := id - *last; delta > 0 && delta <= 15 {
+		t.buf.WriteByte(byte(delta)<<4 | typ)
+	} else {
+		t.buf.WriteByte(typ)
+		t.writeVarint(zigzag(int64(id)))
+	}
+	*last = id
+}
+
+func (t *thriftWriter) i32Field(id int16, v int32) {
+	t.fieldHeader(id, thriftI32)
+	t.writeVarint(zigzag(int64(v)))
+}
+
+func (t *thriftWriter) i64Field(id int16, v int64) {
+	t.fieldHeader(id, thriftI64)
+	t.writeVarint(zigzag(v))
+}
+
+func (t *thriftWriter) stringField(id int16, s string) {
+	t.fieldHeader(id, thriftBinary)
+	t.writeBinary(s)
+}
+
+// structField starts a nested struct field; close it with structEnd
+func (t *thriftWriter) structField(id int16) {
+	t.fieldHeader(id, thriftStruct)
+	t.structBegin()
+}
+
+// listField starts a list field; the caller writes its elements
+func (t *thriftWriter) listField(id int16, elemType byte, size int) {
+	t.fieldHeader(id, thriftList)
+	if size < 15 {
+		t.buf.WriteByte(byte(size)<<4 | elemType)
+	} else {
+		t.buf.WriteByte(0xF0 | elemType)
+		t.writeVarint(uint64(size))
+	}
+}
+
+func (t *thriftWriter) writeBinary(s string) {
+	t.writeVarint(uint64(len(s)))
+	t.buf.WriteString(s)
+}
+
+func (t *thriftWriter) writeVarint(v uint64) {
+	t.buf.Write(binary.AppendUvarint(nil, v))
+}
+
+func zigzag(v int64) uint64 {
+	return uint64((v << 1) ^ (v >> 63))
+}
@@ -0,0 +1,597 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// The tests read files back with a decoder written from the Parquet and
+// Thrift compact protocol specifications rather than from the writer, so a
+// mistake in the writer's encoding shows up as a mismatch instead of being
+// repeated on both sides.
+
+func TestParquetRoundTrip(t *testing.T) {
+	columns := []ParquetColumn{
+		{Name: "user_email", Type: ParquetString},
+		{Name: "assessment_id", Type: ParquetInt64},
+		{Name: "score", Type: ParquetDouble, Optional: true},
+		{Name: "retrospective", Type: ParquetBool},
+		{Name: "submitted_at", Type: ParquetTimestamp},
+		{Name: "note", Type: ParquetString, Optional: true},
+	}
+	submitted := time.Date(2026, time.March, 8, 13, 45, 12, 345_000_000, time.UTC)
+	rows := [][]any{
+		{"a@example.com", int64(1), 2.5, true, submitted, "first"},
+		{"b@example.com", int64(-42), nil, false, submitted.Add(time.Hour), nil},
+		{"", int64(math.MaxInt64), math.Inf(-1), true, submitted.Add(-24 * time.Hour), ""},
+		{"ünïcødé ✓", int64(0), 0.1, false, time.Unix(0, 0).UTC(), strings.Repeat("x", 300)},
+	}
+	metadata := map[string]string{"crapp.study": "pilot", "crapp.exported_at": "2026-03-09"}
+
+	var buf bytes.Buffer
+	if err := WriteParquet(&buf, columns, rows, metadata); err != nil {
+		t.Fatalf("WriteParquet: %v", err)
+	}
+	file, err := readParquet(buf.Bytes())
+	if err != nil {
+		t.Fatalf("reading the file back: %v", err)
+	}
+
+	if file.numRows != int64(len(rows)) {
+		t.Errorf("file has %d rows, want %d", file.numRows, len(rows))
+	}
+	if !reflect.DeepEqual(file.columns, columns) {
+		t.Errorf("schema = %+v, want %+v", file.columns, columns)
+	}
+	if !reflect.DeepEqual(file.metadata, metadata) {
+		t.Errorf("metadata = %v, want %v", file.metadata, metadata)
+	}
+	if len(file.rows) != len(rows) {
+		t.Fatalf("read %d rows, want %d", len(file.rows), len(rows))
+	}
+	for r := range rows {
+		for c, col := range columns {
+			want, got := rows[r][c], file.rows[r][c]
+			if when, ok := want.(time.Time); ok {
+				// Timestamps are stored to the millisecond
+				want = when.Truncate(time.Millisecond)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("row %d, %s = %#v, want %#v", r, col.Name, got, want)
+			}
+		}
+	}
+}
+
+// TestParquetLongRuns covers files big enough for run lengths and list sizes
+// that don't fit in a single byte, and enough booleans to span several bytes
+func TestParquetLongRuns(t *testing.T) {
+	columns := []ParquetColumn{
+		{Name: "value", Type: ParquetInt64, Optional: true},
+		{Name: "flag", Type: ParquetBool},
+	}
+	var rows [][]any
+	for i := range 1000 {
+		var value any
+		if i%300 < 200 {
+			value = int64(i)
+		}
+		rows = append(rows, []any{value, i%3 == 0})
+	}
+	for i := range 20 {
+		columns = append(columns, ParquetColumn{Name: fmt.Sprintf("extra_%d", i), Type: ParquetDouble})
+		for r := range rows {
+			rows[r] = append(rows[r], float64(r*i))
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := WriteParquet(&buf, columns, rows, nil); err != nil {
+		t.Fatalf("WriteParquet: %v", err)
+	}
+	file, err := readParquet(buf.Bytes())
+	if err != nil {
+		t.Fatalf("reading the file back: %v", err)
+	}
+	if !reflect.DeepEqual(file.columns, columns) {
+		t.Errorf("schema = %+v, want %+v", file.columns, columns)
+	}
+	if !reflect.DeepEqual(file.rows, rows) {
+		t.Error("rows read back differ from the rows written")
+	}
+}
+
+func TestParquetEmpty(t *testing.T) {
+	columns := []ParquetColumn{{Name: "id", Type: ParquetInt64}, {Name: "note", Type: ParquetString, Optional: true}}
+
+	var buf bytes.Buffer
+	if err := WriteParquet(&buf, columns, nil, nil); err != nil {
+		t.Fatalf("WriteParquet: %v", err)
+	}
+	file, err := readParquet(buf.Bytes())
+	if err != nil {
+		t.Fatalf("reading the file back: %v", err)
+	}
+	if file.numRows != 0 || len(file.rows) != 0 {
+		t.Errorf("empty file has %d rows", file.numRows)
+	}
+}
+
+func TestParquetRejectsBadValues(t *testing.T) {
+	tests := []struct {
+		name   string
+		column ParquetColumn
+		value  any
+	}{
+		{name: "null in a required column", column: ParquetColumn{Name: "id", Type: ParquetInt64}, value: nil},
+		{name: "int where a string belongs", column: ParquetColumn{Name: "email", Type: ParquetString}, value: 3},
+		{name: "int instead of int64", column: ParquetColumn{Name: "id", Type: ParquetInt64, Optional: true}, value: 3},
+		{name: "string timestamp", column: ParquetColumn{Name: "at", Type: ParquetTimestamp}, value: "2026-01-01"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := WriteParquet(&buf, []ParquetColumn{tt.column}, [][]any{{tt.value}}, nil); err == nil {
+				t.Error("WriteParquet accepted the value")
+			}
+		})
+	}
+}
+
+// parquetFile is a Parquet file as read back by the tests
+type parquetFile struct {
+	columns  []ParquetColumn
+	numRows  int64
+	rows     [][]any
+	metadata map[string]string
+}
+
+// readParquet decodes the files WriteParquet writes: plain-encoded,
+// uncompressed data pages of flat columns
+func readParquet(data []byte) (*parquetFile, error) {
+	if len(data) < 12 || string(data[:4]) != "PAR1" || string(data[len(data)-4:]) != "PAR1" {
+		return nil, errors.New("missing PAR1 magic")
+	}
+	footerLen := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	footerStart := len(data) - 8 - footerLen
+	if footerStart < 4 {
+		return nil, errors.New("footer length runs past the start of the file")
+	}
+	footer := &thriftReader{buf: data[footerStart : len(data)-8]}
+	meta, err := footer.readStruct()
+	if err != nil {
+		return nil, fmt.Errorf("footer: %w", err)
+	}
+	if footer.pos != len(footer.buf) {
+		return nil, fmt.Errorf("footer has %d trailing bytes", len(footer.buf)-footer.pos)
+	}
+
+	// Readers reject files missing the fields parquet.thrift marks required
+	if err := meta.require("file metadata", 1, 2, 3, 4); err != nil {
+		return nil, err
+	}
+	file := &parquetFile{numRows: meta.i64(3), metadata: map[string]string{}}
+
+	schema := meta.list(2)
+	if len(schema) == 0 {
+		return nil, errors.New("footer has no schema")
+	}
+	if children := schema[0].(decodedStruct).i64(5); int(children) != len(schema)-1 {
+		return nil, fmt.Errorf("schema root has %d children, want %d", children, len(schema)-1)
+	}
+	physicalTypes := make([]int64, 0, len(schema)-1)
+	for _, element := range schema[1:] {
+		s := element.(decodedStruct)
+		col, err := columnFromSchema(s)
+		if err != nil {
+			return nil, err
+		}
+		file.columns = append(file.columns, col)
+		physicalTypes = append(physicalTypes, s.i64(1))
+	}
+
+	for _, kv := range meta.list(5) {
+		s := kv.(decodedStruct)
+		file.metadata[s.str(1)] = s.str(2)
+	}
+	if len(file.metadata) == 0 {
+		file.metadata = nil
+	}
+
+	rowGroups := meta.list(4)
+	if len(rowGroups) != 1 {
+		return nil, fmt.Errorf("file has %d row groups, want 1", len(rowGroups))
+	}
+	group := rowGroups[0].(decodedStruct)
+	if err := group.require("row group", 1, 2, 3); err != nil {
+		return nil, err
+	}
+	if group.i64(3) != file.numRows {
+		return nil, fmt.Errorf("row group has %d rows, file has %d", group.i64(3), file.numRows)
+	}
+	chunks := group.list(1)
+	if len(chunks) != len(file.columns) {
+		return nil, fmt.Errorf("row group has %d column chunks for %d columns", len(chunks), len(file.columns))
+	}
+
+	var totalSize int64
+	file.rows = make([][]any, file.numRows)
+	for r := range file.rows {
+		file.rows[r] = make([]any, len(file.columns))
+	}
+	for c, chunk := range chunks {
+		chunkMeta := chunk.(decodedStruct).strct(3)
+		col := file.columns[c]
+		if err := chunkMeta.require("column "+col.Name+" metadata", 1, 2, 3, 4, 5, 6, 7, 9); err != nil {
+			return nil, err
+		}
+		if chunkMeta.i64(1) != physicalTypes[c] {
+			return nil, fmt.Errorf("column %s chunk has physical type %d, schema has %d", col.Name, chunkMeta.i64(1), physicalTypes[c])
+		}
+		if path := chunkMeta.list(3); len(path) != 1 || path[0] != col.Name {
+			return nil, fmt.Errorf("column %s chunk has path %v", col.Name, path)
+		}
+		if codec := chunkMeta.i64(4); codec != 0 {
+			return nil, fmt.Errorf("column %s uses codec %d", col.Name, codec)
+		}
+		if chunkMeta.i64(5) != file.numRows {
+			return nil, fmt.Errorf("column %s chunk has %d values, want %d", col.Name, chunkMeta.i64(5), file.numRows)
+		}
+
+		offset, size := chunkMeta.i64(9), chunkMeta.i64(7)
+		if chunk.(decodedStruct).i64(2) != offset {
+			return nil, fmt.Errorf("column %s file offset and data page offset differ", col.Name)
+		}
+		if offset < 4 || offset+size > int64(footerStart) {
+			return nil, fmt.Errorf("column %s chunk at %d+%d is outside the data", col.Name, offset, size)
+		}
+		totalSize += size
+
+		values, err := readColumnChunk(data[offset:offset+size], col, physicalTypes[c], int(file.numRows))
+		if err != nil {
+			return nil, fmt.Errorf("column %s: %w", col.Name, err)
+		}
+		for r, value := range values {
+			file.rows[r][c] = value
+		}
+	}
+	if group.i64(2) != totalSize {
+		return nil, fmt.Errorf("row group size is %d, chunks add up to %d", group.i64(2), totalSize)
+	}
+	return file, nil
+}
+
+// columnFromSchema maps a schema element back to the column it describes
+func columnFromSchema(s decodedStruct) (ParquetColumn, error) {
+	if err := s.require("schema element", 4); err != nil {
+		return ParquetColumn{}, err
+	}
+	col := ParquetColumn{Name: s.str(4)}
+	switch s.i64(3) {
+	case 0:
+	case 1:
+		col.Optional = true
+	default:
+		return col, fmt.Errorf("column %s has repetition %d", col.Name, s.i64(3))
+	}
+
+	converted, hasConverted := s.fields[6]
+	switch physical := s.i64(1); {
+	case physical == 6 && hasConverted && converted == int64(0):
+		col.Type = ParquetString
+	case physical == 2 && !hasConverted:
+		col.Type = ParquetInt64
+	case physical == 2 && hasConverted && converted == int64(9):
+		col.Type = ParquetTimestamp
+	case physical == 5 && !hasConverted:
+		col.Type = ParquetDouble
+	case physical == 0 && !hasConverted:
+		col.Type = ParquetBool
+	default:
+		return col, fmt.Errorf("column %s has physical type %d, converted type %v", col.Name, physical, converted)
+	}
+	return col, nil
+}
+
+// readColumnChunk decodes a chunk holding one data page
+func readColumnChunk(chunk []byte, col ParquetColumn, physical int64, numRows int) ([]any, error) {
+	r := &thriftReader{buf: chunk}
+	header, err := r.readStruct()
+	if err != nil {
+		return nil, fmt.Errorf("page header: %w", err)
+	}
+	if err := header.require("page header", 1, 2, 3, 5); err != nil {
+		return nil, err
+	}
+	page := chunk[r.pos:]
+	if header.i64(1) != 0 {
+		return nil, fmt.Errorf("page type %d is not a data page", header.i64(1))
+	}
+	if int(header.i64(2)) != len(page) || int(header.i64(3)) != len(page) {
+		return nil, fmt.Errorf("page sizes %d and %d, page is %d bytes", header.i64(2), header.i64(3), len(page))
+	}
+	dataHeader := header.strct(5)
+	if err := dataHeader.require("data page header", 1, 2, 3, 4); err != nil {
+		return nil, err
+	}
+	if int(dataHeader.i64(1)) != numRows {
+		return nil, fmt.Errorf("page has %d values, want %d", dataHeader.i64(1), numRows)
+	}
+	if dataHeader.i64(2) != 0 {
+		return nil, fmt.Errorf("page encoding %d is not plain", dataHeader.i64(2))
+	}
+
+	defined := make([]bool, numRows)
+	for i := range defined {
+		defined[i] = true
+	}
+	if col.Optional {
+		if dataHeader.i64(3) != 3 {
+			return nil, fmt.Errorf("definition levels use encoding %d", dataHeader.i64(3))
+		}
+		if len(page) < 4 {
+			return nil, errors.New("page is too short for its definition levels")
+		}
+		levelsLen := int(binary.LittleEndian.Uint32(page))
+		if 4+levelsLen > len(page) {
+			return nil, errors.New("definition levels run past the page")
+		}
+		if defined, err = decodeHybrid(page[4:4+levelsLen], numRows); err != nil {
+			return nil, fmt.Errorf("definition levels: %w", err)
+		}
+		page = page[4+levelsLen:]
+	}
+
+	values := make([]any, numRows)
+	bit := 0
+	for i := range values {
+		if !defined[i] {
+			continue
+		}
+		switch physical {
+		case 0:
+			if bit/8 >= len(page) {
+				return nil, errors.New("booleans run past the page")
+			}
+			values[i] = page[bit/8]&(1<<(bit%8)) != 0
+			bit++
+		case 2:
+			if len(page) < 8 {
+				return nil, errors.New("int64 runs past the page")
+			}
+			v := int64(binary.LittleEndian.Uint64(page))
+			page = page[8:]
+			if col.Type == ParquetTimestamp {
+				values[i] = time.UnixMilli(v).UTC()
+			} else {
+				values[i] = v
+			}
+		case 5:
+			if len(page) < 8 {
+				return nil, errors.New("double runs past the page")
+			}
+			values[i] = math.Float64frombits(binary.LittleEndian.Uint64(page))
+			page = page[8:]
+		case 6:
+			if len(page) < 4 {
+				return nil, errors.New("byte array length runs past the page")
+			}
+			n := int(binary.LittleEndian.Uint32(page))
+			if 4+n > len(page) {
+				return nil, errors.New("byte array runs past the page")
+			}
+			values[i] = string(page[4 : 4+n])
+			page = page[4+n:]
+		}
+	}
+	if physical == 0 {
+		page = page[(bit+7)/8:]
+	}
+	if len(page) != 0 {
+		return nil, fmt.Errorf("page has %d bytes after its values", len(page))
+	}
+	return values, nil
+}
+
+// decodeHybrid decodes bit width one levels in the RLE/bit-packing hybrid
+// encoding, accepting both run kinds
+func decodeHybrid(data []byte, count int) ([]bool, error) {
+	var levels []bool
+	for len(levels) < count {
+		header, n := binary.Uvarint(data)
+		if n <= 0 {
+			return nil, errors.New("bad run header")
+		}
+		data = data[n:]
+		if header&1 == 0 {
+			// RLE run: the value follows in one byte
+			if len(data) < 1 {
+				return nil, errors.New("RLE run has no value")
+			}
+			for range header >> 1 {
+				levels = append(levels, data[0] == 1)
+			}
+			data = data[1:]
+			continue
+		}
+		// Bit-packed run of groups of eight values
+		groups := int(header >> 1)
+		if len(data) < groups {
+			return nil, errors.New("bit-packed run runs past the levels")
+		}
+		for i := range groups * 8 {
+			levels = append(levels, data[i/8]&(1<<(i%8)) != 0)
+		}
+		data = data[groups:]
+	}
+	if len(data) != 0 {
+		return nil, fmt.Errorf("levels have %d bytes left over", len(data))
+	}
+	return levels[:count], nil
+}
+
+// decodedStruct is a decoded Thrift struct: field ID to value. Integers are
+// int64, binaries are strings, lists are []any, and structs are decodedStruct.
+type decodedStruct struct {
+	fields map[int16]any
+}
+
+// require returns an error naming the first of the fields that is missing
+func (s decodedStruct) require(name string, ids ...int16) error {
+	for _, id := range ids {
+		if _, ok := s.fields[id]; !ok {
+			return fmt.Errorf("%s is missing required field %d", name, id)
+		}
+	}
+	return nil
+}
+
+func (s decodedStruct) i64(id int16) int64 {
+	v, _ := s.fields[id].(int64)
+	return v
+}
+
+func (s decodedStruct) str(id int16) string {
+	v, _ := s.fields[id].(string)
+	return v
+}
+
+func (s decodedStruct) list(id int16) []any {
+	v, _ := s.fields[id].([]any)
+	return v
+}
+
+func (s decodedStruct) strct(id int16) decodedStruct {
+	v, _ := s.fields[id].(decodedStruct)
+	return v
+}
+
+// thriftReader decodes the Thrift compact protocol
+type thriftReader struct {
+	buf []byte
+	pos int
+}
+
+func (r *thriftReader) readByte() (byte, error) {
+	if r.pos >= len(r.buf) {
+		return 0, io.ErrUnexpectedEOF
+	}
+	b := r.buf[r.pos]
+	r.pos++
+	return b, nil
+}
+
+func (r *thriftReader) varint() (uint64, error) {
+	v, n := binary.Uvarint(r.buf[r.pos:])
+	if n <= 0 {
+		return 0, errors.New("bad varint")
+	}
+	r.pos += n
+	return v, nil
+}
+
+func (r *thriftReader) zigzag() (int64, error) {
+	v, err := r.varint()
+	return int64(v>>1) ^ -int64(v&1), err
+}
+
+func (r *thriftReader) readStruct() (decodedStruct, error) {
+	s := decodedStruct{fields: map[int16]any{}}
+	var last int16
+	for {
+		header, err := r.readByte()
+		if err != nil {
+			return s, err
+		}
+		if header == 0 {
+			return s, nil
+		}
+		typ := header & 0x0F
+		id := last + int16(header>>4)
+		if header>>4 == 0 {
+			v, err := r.zigzag()
+			if err != nil {
+				return s, err
+			}
+			id = int16(v)
+		}
+		if id <= last {
+			return s, fmt.Errorf("field %d follows field %d", id, last)
+		}
+		last = id
+
+		var value any
+		switch typ {
+		case 1, 2: // Booleans carry their value in the type
+			value = typ == 1
+		default:
+			if value, err = r.readValue(typ); err != nil {
+				return s, fmt.Errorf("field %d: %w", id, err)
+			}
+		}
+		s.fields[id] = value
+	}
+}
+
+func (r *thriftReader) readValue(typ byte) (any, error) {
+	switch typ {
+	case 1, 2:
+		b, err := r.readByte()
+		return b == 1, err
+	case 3:
+		b, err := r.readByte()
+		return int64(int8(b)), err
+	case 4, 5, 6:
+		return r.zigzag()
+	case 7:
+		if r.pos+8 > len(r.buf) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		v := math.Float64frombits(binary.LittleEndian.Uint64(r.buf[r.pos:]))
+		r.pos += 8
+		return v, nil
+	case 8:
+		n, err := r.varint()
+		if err != nil {
+			return nil, err
+		}
+		if r.pos+int(n) > len(r.buf) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		s := string(r.buf[r.pos : r.pos+int(n)])
+		r.pos += int(n)
+		return s, nil
+	case 9, 10:
+		header, err := r.readByte()
+		if err != nil {
+			return nil, err
+		}
+		size := int(header >> 4)
+		if size == 15 {
+			n, err := r.varint()
+			if err != nil {
+				return nil, err
+			}
+			size = int(n)
+		}
+		list := make([]any, size)
+		for i := range list {
+			if list[i], err = r.readValue(header & 0x0F); err != nil {
+				return nil, err
+			}
+		}
+		return list, nil
+	case 12:
+		return r.readStruct()
+	}
+	return nil, fmt.Errorf("unknown type %d", typ)
+}
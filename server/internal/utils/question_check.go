@@ -0,0 +1,362 @@
+package utils
+
+import (
+	"fmt"
+	"maps"
+	"math"
+	"os"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Help media types by kind, by file extension
+var (
+	helpImageTypes   = []string{".png", ".jpg", ".jpeg", ".gif", ".webp", ".svg"}
+	helpVideoTypes   = []string{".mp4", ".webm"}
+	helpCaptionTypes = []string{".vtt"}
+)
+
+// Question types the form knows how to render
+var knownQuestionTypes = map[string]bool{
+	"radio":      true,
+	"dropdown":   true,
+	"text":       true,
+	"cpt":        true,
+	"tmt":        true,
+	"digit_span": true,
+}
+
+// Interaction metrics the server knows how to calculate
+var knownMetricsTypes = map[string]bool{
+	"mouse":      true,
+	"keyboard":   true,
+	"cpt":        true,
+	"tmt":        true,
+	"digit_span": true,
+}
+
+// QuestionProblem is one integrity problem in a questions file
+type QuestionProblem struct {
+	Line       int
+	QuestionID string
+	Message    string
+}
+
+// QuestionFileError lists every problem found in a questions file
+type QuestionFileError struct {
+	Path     string
+	Problems []QuestionProblem
+}
+
+func (e *QuestionFileError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s has %d problem(s):", e.Path, len(e.Problems))
+	for _, p := range e.Problems {
+		fmt.Fprintf(&b, "\n  %s:%d", e.Path, p.Line)
+		if p.QuestionID != "" {
+			fmt.Fprintf(&b, " [%s]", p.QuestionID)
+		}
+		fmt.Fprintf(&b, ": %s", p.Message)
+	}
+	return b.String()
+}
+
+// CheckQuestionsFile validates a questions file beyond parsing: unique IDs,
+// known types, options with values and labels, compiling patterns, metric
+// keys for symptom questions, defaults that match an option, a known
+// ordering scheme, and adaptive settings with core questions to serve. All
+// problems are reported together as a *QuestionFileError.
+func CheckQuestionsFile(path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read questions YAML file: %w", err)
+	}
+	return checkQuestions(path, content)
+}
+
+func checkQuestions(path string, content []byte) error {
+	var root yaml.Node
+	if err := yaml.Unmarshal(content, &root); err != nil {
+		return fmt.Errorf("failed to parse questions YAML file: %w", err)
+	}
+
+	report := &QuestionFileError{Path: path}
+	add := func(line int, id, format string, args ...any) {
+		report.Problems = append(report.Problems, QuestionProblem{Line: line, QuestionID: id, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if len(root.Content) == 0 || root.Content[0].Kind != yaml.MappingNode {
+		add(1, "", "file must be a mapping with a questions list")
+		return report
+	}
+	if ordering := mappingValue(root.Content[0], "ordering"); ordering != nil && !knownOrderings[ordering.Value] {
+		add(ordering.Line, "", "unknown ordering %q; use random, fixed, or latin_square", ordering.Value)
+	}
+	questions := mappingValue(root.Content[0], "questions")
+	if questions == nil || questions.Kind != yaml.SequenceNode || len(questions.Content) == 0 {
+		add(root.Content[0].Line, "", "no questions defined")
+		return report
+	}
+
+	coreQuestions := 0
+	showsResources := 0
+	firstSeen := make(map[string]int)
+	for _, node := range questions.Content {
+		var q Question
+		if err := node.Decode(&q); err != nil {
+			add(node.Line, "", "invalid question: %v", err)
+			continue
+		}
+		line := func(key string) int {
+			if value := mappingValue(node, key); value != nil {
+				return value.Line
+			}
+			return node.Line
+		}
+
+		// Identity
+		if q.ID == "" {
+			add(node.Line, "", "question has no id")
+		} else if first, ok := firstSeen[q.ID]; ok {
+			add(line("id"), q.ID, "duplicate id, first defined on line %d", first)
+		} else {
+			firstSeen[q.ID] = line("id")
+		}
+		if q.Title == "" {
+			add(node.Line, q.ID, "question has no title")
+		}
+
+		// Type and metrics
+		if !knownQuestionTypes[q.Type] {
+			add(line("type"), q.ID, "unknown type %q", q.Type)
+		}
+		if q.MetricsType != "" && !knownMetricsTypes[q.MetricsType] {
+			add(line("metrics_type"), q.ID, "unknown metrics_type %q", q.MetricsType)
+		}
+		if (q.Type == "radio" || q.Type == "dropdown") && q.MetricKey == "" {
+			add(node.Line, q.ID, "%s questions need a metric_key for charts and exports", q.Type)
+		}
+		switch q.Type {
+		case "cpt", "tmt", "digit_span":
+			if q.MetricsType != "" && q.MetricsType != q.Type {
+				add(line("metrics_type"), q.ID, "%s questions must use metrics_type %q", q.Type, q.Type)
+			}
+		}
+
+		// Options
+		options := mappingValue(node, "options")
+		if (q.Type == "radio" || q.Type == "dropdown") && (options == nil || len(options.Content) == 0) {
+			add(node.Line, q.ID, "%s questions need options", q.Type)
+		}
+		// Choice questions are answered by option value; cognitive tests read
+		// their settings by option label
+		choice := q.Type == "radio" || q.Type == "dropdown"
+		values := make(map[string]bool)
+		labels := make(map[string]bool)
+		if options != nil {
+			for _, option := range options.Content {
+				value := mappingValue(option, "value")
+				if value == nil || value.Tag == "!!null" || value.Value == "" {
+					add(option.Line, q.ID, "option has no value")
+					continue
+				}
+				label := mappingValue(option, "label")
+				if label == nil || label.Value == "" {
+					add(option.Line, q.ID, "option %q has no label", value.Value)
+				} else if !choice && labels[label.Value] {
+					add(label.Line, q.ID, "duplicate setting %q", label.Value)
+				}
+				if choice && values[value.Value] {
+					add(value.Line, q.ID, "duplicate option value %q", value.Value)
+				}
+				values[value.Value] = true
+				if label != nil {
+					labels[label.Value] = true
+				}
+			}
+		}
+		if q.Default != "" && options != nil && !values[q.Default] {
+			add(line("default_option"), q.ID, "default_option %q is not one of the option values", q.Default)
+		}
+
+		// Text validation
+		if q.Pattern != "" {
+			if _, err := regexp.Compile(q.Pattern); err != nil {
+				add(line("pattern"), q.ID, "pattern does not compile: %v", err)
+			}
+		}
+		if q.MinLength < 0 {
+			add(line("min_length"), q.ID, "min_length cannot be negative")
+		}
+		if q.MaxLength < 0 {
+			add(line("max_length"), q.ID, "max_length cannot be negative")
+		}
+		if q.MaxLength > 0 && q.MinLength > q.MaxLength {
+			add(line("min_length"), q.ID, "min_length %d is greater than max_length %d", q.MinLength, q.MaxLength)
+		}
+		if q.MinWords < 0 {
+			add(line("min_words"), q.ID, "min_words cannot be negative")
+		}
+		if q.MaxWords < 0 {
+			add(line("max_words"), q.ID, "max_words cannot be negative")
+		}
+		if q.MaxWords > 0 && q.MinWords > q.MaxWords {
+			add(line("min_words"), q.ID, "min_words %d is greater than max_words %d", q.MinWords, q.MaxWords)
+		}
+		if q.Honeypot {
+			if q.Type != "text" {
+				add(line("honeypot"), q.ID, "honeypot questions must be text questions")
+			}
+			if q.Required || q.Core || q.RedFlag != nil {
+				add(line("honeypot"), q.ID, "honeypot questions cannot be required, core, or red_flag")
+			}
+		}
+		if len(q.StudyOptions) > 0 {
+			if !IsCognitiveTest(q.Type) {
+				add(line("study_options"), q.ID, "study_options need a cognitive test question")
+			}
+			for _, study := range slices.Sorted(maps.Keys(q.StudyOptions)) {
+				for _, option := range q.StudyOptions[study] {
+					if !labels[option.Label] {
+						add(line("study_options"), q.ID, "study %s sets %q, which is not one of the test's settings", study, option.Label)
+					}
+				}
+			}
+		}
+		if q.EveryDays < 0 {
+			add(line("every_days"), q.ID, "every_days cannot be negative")
+		}
+		if q.Core {
+			coreQuestions++
+		}
+		if q.Help != nil {
+			for _, problem := range checkHelp(q.Help) {
+				add(line("help"), q.ID, "%s", problem)
+			}
+		}
+		if q.RedFlag != nil {
+			if !choice {
+				add(line("red_flag"), q.ID, "red_flag needs a radio or dropdown question")
+			} else if !slices.ContainsFunc(q.Options, func(o QuestionOption) bool { return q.RedFlag.Raised(OptionNumber(o)) }) {
+				add(line("red_flag"), q.ID, "no option reaches red_flag at_least %s", strconv.FormatFloat(q.RedFlag.AtLeast, 'f', -1, 64))
+			}
+			if q.RedFlag.ShowResources {
+				showsResources++
+			}
+		}
+	}
+
+	if showsResources > 0 {
+		var resources []CrisisResource
+		if node := mappingValue(root.Content[0], "crisis_resources"); node == nil || node.Decode(&resources) != nil || len(resources) == 0 {
+			add(root.Content[0].Line, "", "red_flag show_resources needs a crisis_resources list")
+		}
+		for _, r := range resources {
+			if r.Name == "" || (r.Phone == "" && r.URL == "") {
+				add(root.Content[0].Line, "", "each crisis resource needs a name and a phone or url")
+				break
+			}
+		}
+	}
+
+	if node := mappingValue(root.Content[0], "adaptive"); node != nil {
+		var adaptive AdaptiveConfig
+		switch {
+		case node.Decode(&adaptive) != nil:
+			add(node.Line, "", "adaptive must be a mapping with stable_days, max_change, and full_every_days")
+		case adaptive.StableDays <= 0:
+			add(node.Line, "", "adaptive stable_days must be positive")
+		case adaptive.MaxChange < 0 || adaptive.FullEveryDays < 0:
+			add(node.Line, "", "adaptive max_change and full_every_days cannot be negative")
+		case coreQuestions == 0:
+			add(node.Line, "", "adaptive mode needs at least one question marked core")
+		}
+	}
+
+	if len(report.Problems) > 0 {
+		return report
+	}
+	return nil
+}
+
+// checkHelp returns the problems with a question's help content
+func checkHelp(help *QuestionHelp) []string {
+	var problems []string
+	if help.Text == "" && len(help.Images) == 0 && len(help.Videos) == 0 {
+		problems = append(problems, "help has no text, images or videos")
+	}
+	for _, image := range help.Images {
+		if problem := checkHelpURL("image", image.URL, helpImageTypes); problem != "" {
+			problems = append(problems, problem)
+		}
+		if image.Alt == "" {
+			problems = append(problems, fmt.Sprintf("help image %q has no alt text", image.URL))
+		}
+	}
+	for _, video := range help.Videos {
+		if problem := checkHelpURL("video", video.URL, helpVideoTypes); problem != "" {
+			problems = append(problems, problem)
+		}
+		if video.Title == "" {
+			problems = append(problems, fmt.Sprintf("help video %q has no title", video.URL))
+		}
+		if video.Captions != "" {
+			if problem := checkHelpURL("captions", video.Captions, helpCaptionTypes); problem != "" {
+				problems = append(problems, problem)
+			}
+		}
+		if video.Poster != "" {
+			if problem := checkHelpURL("poster", video.Poster, helpImageTypes); problem != "" {
+				problems = append(problems, problem)
+			}
+		}
+	}
+	return problems
+}
+
+// checkHelpURL requires a help media URL to be a path on this server, which
+// the content security policy allows, with one of the given extensions
+func checkHelpURL(kind, url string, extensions []string) string {
+	switch {
+	case url == "":
+		return fmt.Sprintf("help %s has no url", kind)
+	case !strings.HasPrefix(url, "/") || strings.HasPrefix(url, "//") || strings.Contains(url, ".."):
+		return fmt.Sprintf("help %s url %q must be a path on this server, such as /static/help/example%s", kind, url, extensions[0])
+	case !slices.Contains(extensions, strings.ToLower(path.Ext(url))):
+		return fmt.Sprintf("help %s url %q must end in one of %s", kind, url, strings.Join(extensions, ", "))
+	}
+	return ""
+}
+
+// OptionNumber returns an option's value as a number, or NaN if it isn't one
+func OptionNumber(option QuestionOption) float64 {
+	switch v := option.Value.(type) {
+	case int:
+		return float64(v)
+	case float64:
+		return v
+	case string:
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			return f
+		}
+	}
+	return math.NaN()
+}
+
+// mappingValue returns the value node for a key in a YAML mapping
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package utils
+
+import "slices"
+
+// Question ordering schemes a questions file can select
+const (
+	OrderingRandom      = "random"       // Shuffled independently for every form (the default)
+	OrderingFixed       = "fixed"        // As listed in the file
+	OrderingLatinSquare = "latin_square" // Rows of a balanced Latin square, in turn across a user's assessments
+)
+
+var knownOrderings = map[string]bool{
+	OrderingRandom:      true,
+	OrderingFixed:       true,
+	OrderingLatinSquare: true,
+}
+
+// LatinSquareRows is the number of distinct orders in the balanced Latin
+// square for n questions. An odd number of questions needs each row and its
+// reverse to balance carryover, so the square has twice as many rows.
+func LatinSquareRows(n int) int {
+	if n%2 == 1 {
+		return 2 * n
+	}
+	return n
+}
+
+// LatinSquareOrder returns the question indexes for one row of a Williams
+// design. Across all rows, every question appears at every position equally
+// often and follows every other question equally often.
+func LatinSquareOrder(n, row int) []int {
+	order := make([]int, n)
+	if n == 0 {
+		return order
+	}
+	row %= LatinSquareRows(n)
+
+	// The first row is 0, 1, n-1, 2, n-2, ... and each later row adds one to
+	// every index, modulo n
+	for j := range order {
+		var base int
+		switch {
+		case j == 0:
+			base = 0
+		case j%2 == 1:
+			base = (j + 1) / 2
+		default:
+			base = n - j/2
+		}
+		order[j] = (base + row) % n
+	}
+	if row >= n {
+		slices.Reverse(order)
+	}
+	return order
+}
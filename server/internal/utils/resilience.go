@@ -0,0 +1,195 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// ErrCircuitOpen is returned without calling out while a circuit breaker is open
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// ErrCallTimeout is returned when a call takes longer than its timeout
+var ErrCallTimeout = errors.New("call timed out")
+
+// permanentError marks a failure that retrying cannot fix
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent wraps an error so Resilient.Do returns it without retrying. It
+// does not count against the circuit breaker, since the remote side answered.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// RetryPolicy controls retries and timeouts for an outbound call
+type RetryPolicy struct {
+	MaxAttempts int           // Total attempts, including the first
+	BaseDelay   time.Duration // Delay before the first retry, doubled each time
+	MaxDelay    time.Duration // Upper bound on a single delay
+	Timeout     time.Duration // Limit on a single attempt (0 disables)
+}
+
+// CircuitBreaker stops calls to a failing dependency for a cooldown period
+// after too many consecutive failures, then lets a single trial call through
+type CircuitBreaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	failures  int
+	openUntil time.Time
+	trial     bool
+}
+
+// NewCircuitBreaker creates a breaker that opens after threshold consecutive
+// failures. A threshold of 0 disables it.
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{threshold: threshold, cooldown: cooldown}
+}
+
+// allow reports whether a call may proceed
+func (b *CircuitBreaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.threshold <= 0 || b.failures < b.threshold {
+		return true
+	}
+	// Half-open: one trial call after the cooldown decides whether to close
+	if time.Now().After(b.openUntil) && !b.trial {
+		b.trial = true
+		return true
+	}
+	return false
+}
+
+// record notes a call's outcome, returning true when it opened the breaker
+func (b *CircuitBreaker) record(success bool) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	wasTrial := b.trial
+	b.trial = false
+	if success {
+		b.failures = 0
+		return false
+	}
+
+	b.failures++
+	if b.threshold > 0 && b.failures >= b.threshold {
+		b.openUntil = time.Now().Add(b.cooldown)
+		return b.failures == b.threshold || wasTrial
+	}
+	return false
+}
+
+// Failing reports whether enough consecutive calls have failed to open the
+// breaker. A disabled breaker is failing while its last call failed.
+func (b *CircuitBreaker) Failing() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.failures > 0 && b.failures >= b.threshold
+}
+
+// Resilient wraps calls to one dependency with timeouts, retries with
+// jittered exponential backoff, and a circuit breaker
+type Resilient struct {
+	name    string
+	policy  RetryPolicy
+	breaker *CircuitBreaker
+	log     *zap.SugaredLogger
+}
+
+// NewResilient creates a resilience wrapper for a named dependency
+func NewResilient(name string, policy RetryPolicy, breaker *CircuitBreaker, log *zap.SugaredLogger) *Resilient {
+	if policy.MaxAttempts < 1 {
+		policy.MaxAttempts = 1
+	}
+	return &Resilient{
+		name:    name,
+		policy:  policy,
+		breaker: breaker,
+		log:     log,
+	}
+}
+
+// Do runs fn until it succeeds, returns a permanent error, or runs out of
+// attempts. While the breaker is open it returns ErrCircuitOpen immediately.
+func (r *Resilient) Do(fn func() error) error {
+	var err error
+	for attempt := 1; attempt <= r.policy.MaxAttempts; attempt++ {
+		if !r.breaker.allow() {
+			return ErrCircuitOpen
+		}
+
+		err = r.call(fn)
+
+		var permanent *permanentError
+		if errors.As(err, &permanent) {
+			r.breaker.record(true)
+			return permanent.err
+		}
+		if opened := r.breaker.record(err == nil); opened {
+			r.log.Warnw("Circuit breaker opened", "dependency", r.name, "cooldown", r.breaker.cooldown, "error", err)
+		}
+		if err == nil {
+			return nil
+		}
+
+		if attempt < r.policy.MaxAttempts {
+			delay := r.backoff(attempt)
+			r.log.Debugw("Retrying failed call", "dependency", r.name, "attempt", attempt, "delay", delay, "error", err)
+			time.Sleep(delay)
+		}
+	}
+	return fmt.Errorf("%s failed after %d attempts: %w", r.name, r.policy.MaxAttempts, err)
+}
+
+// Failing reports whether the dependency's circuit breaker is failing
+func (r *Resilient) Failing() bool {
+	return r.breaker.Failing()
+}
+
+// call runs one attempt, abandoning it after the timeout
+func (r *Resilient) call(fn func() error) error {
+	if r.policy.Timeout <= 0 {
+		return fn()
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- fn() }()
+
+	timer := time.NewTimer(r.policy.Timeout)
+	defer timer.Stop()
+
+	select {
+	case err := <-done:
+		return err
+	case <-timer.C:
+		return ErrCallTimeout
+	}
+}
+
+// backoff returns the delay before a retry, jittered between half and one and
+// a half times the exponential delay
+func (r *Resilient) backoff(attempt int) time.Duration {
+	delay := r.policy.BaseDelay << (attempt - 1)
+	if r.policy.MaxDelay > 0 && (delay > r.policy.MaxDelay || delay <= 0) {
+		delay = r.policy.MaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int64N(int64(delay))) + delay/2
+}
@@ -1,10 +1,114 @@
 // internal/utils/sanitizer.go
 package utils
 
-import "github.com/microcosm-cc/bluemonday"
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"slices"
+	"strings"
 
-// SanitizeHTML sanitizes HTML input for safety
-func SanitizeHTML(input string) string {
-	p := bluemonday.UGCPolicy()
-	return p.Sanitize(input)
+	"github.com/microcosm-cc/bluemonday"
+)
+
+// Sanitization policies for user-entered text
+const (
+	SanitizeStrict   = "strict"   // Plain text: all markup is removed
+	SanitizeMarkdown = "markdown" // Limited markdown: HTML is removed, and links may only point at http, https or mailto
+)
+
+// IsSanitizePolicy reports whether a sanitization policy is supported
+func IsSanitizePolicy(policy string) bool {
+	return policy == SanitizeStrict || policy == SanitizeMarkdown
+}
+
+// Entities the strict policy produces that are harmless in plain text. < stays
+// escaped so stored text can never open a tag; markdown also needs > for quotes.
+var (
+	plainTextEntities = strings.NewReplacer("&amp;", "&", "&#34;", `"`, "&#39;", "'")
+	markdownEntities  = strings.NewReplacer("&amp;", "&", "&#34;", `"`, "&#39;", "'", "&gt;", ">")
+)
+
+// Markdown link targets: inline [text](target) and reference [label]: target
+var (
+	inlineLink    = regexp.MustCompile(`\]\(\s*((?:[^()\s]|\([^()\s]*\))*)`)
+	referenceLink = regexp.MustCompile(`(?m)^(\s{0,3}\[[^\]]+\]:\s*)(\S+)`)
+	linkScheme    = regexp.MustCompile(`^([a-zA-Z][a-zA-Z0-9+.-]*):`)
+)
+
+var safeLinkSchemes = []string{"http", "https", "mailto"}
+
+// Sanitizer cleans user-entered text with the policy configured for each
+// kind of input
+type Sanitizer struct {
+	answers string
+	notes   string
+	strict  *bluemonday.Policy
+}
+
+// NewSanitizer creates a sanitizer with the policies for free-text answers
+// and for notes. Unknown policies fall back to strict and are reported in
+// the error.
+func NewSanitizer(answers, notes string) (*Sanitizer, error) {
+	s := &Sanitizer{answers: answers, notes: notes, strict: bluemonday.StrictPolicy()}
+
+	var errs []string
+	if !IsSanitizePolicy(answers) {
+		errs = append(errs, fmt.Sprintf("invalid answer sanitization policy %q", answers))
+		s.answers = SanitizeStrict
+	}
+	if !IsSanitizePolicy(notes) {
+		errs = append(errs, fmt.Sprintf("invalid notes sanitization policy %q", notes))
+		s.notes = SanitizeStrict
+	}
+	if len(errs) > 0 {
+		return s, fmt.Errorf("%s; using strict", strings.Join(errs, ", "))
+	}
+	return s, nil
+}
+
+// Answer cleans a free-text answer to a question. Like all text passed
+// through the sanitizer, it is also normalized to NFC.
+func (s *Sanitizer) Answer(input string) string {
+	return s.apply(s.answers, input)
+}
+
+// Note cleans notes and descriptions written by staff
+func (s *Sanitizer) Note(input string) string {
+	return s.apply(s.notes, input)
+}
+
+// Text cleans short labels, such as device names, which are always plain text
+func (s *Sanitizer) Text(input string) string {
+	return s.apply(SanitizeStrict, input)
+}
+
+func (s *Sanitizer) apply(policy, input string) string {
+	text := s.strict.Sanitize(NormalizeText(input))
+	if policy != SanitizeMarkdown {
+		return plainTextEntities.Replace(text)
+	}
+
+	text = markdownEntities.Replace(text)
+	text = inlineLink.ReplaceAllStringFunc(text, func(match string) string {
+		target := inlineLink.FindStringSubmatch(match)[1]
+		if safeLink(target) {
+			return match
+		}
+		return "](#"
+	})
+	return referenceLink.ReplaceAllStringFunc(text, func(match string) string {
+		parts := referenceLink.FindStringSubmatch(match)
+		if safeLink(parts[2]) {
+			return match
+		}
+		return parts[1] + "#"
+	})
+}
+
+// safeLink reports whether a link target is relative or uses a safe scheme.
+// Entities are decoded first, as a markdown renderer would.
+func safeLink(target string) bool {
+	scheme := linkScheme.FindStringSubmatch(html.UnescapeString(target))
+	return scheme == nil || slices.Contains(safeLinkSchemes, strings.ToLower(scheme[1]))
 }
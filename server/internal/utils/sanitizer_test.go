@@ -0,0 +1,151 @@
+package utils
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// xssPayloads are inputs that try to run script when stored text is shown
+var xssPayloads = []struct {
+	name     string
+	input    string
+	markdown string // Expected output of the markdown policy
+	strict   string // Expected output of the strict policy
+}{
+	{name: "script tag", input: `<script>alert(1)</script>hello`,
+		markdown: "hello", strict: "hello"},
+	{name: "script breaking out of an attribute", input: `"><script>alert(1)</script>`,
+		markdown: `">`, strict: `"&gt;`},
+	{name: "nested script tags", input: `<scr<script>ipt>alert(1)</script>`,
+		markdown: "ipt>alert(1)", strict: "ipt&gt;alert(1)"},
+	{name: "img onerror", input: `<img src=x onerror=alert(1)>`,
+		markdown: "", strict: ""},
+	{name: "svg onload", input: `<svg onload=alert(1)>`,
+		markdown: "", strict: ""},
+	{name: "event handler on a container", input: `<div onmouseover="alert(1)">hover</div>`,
+		markdown: "hover", strict: "hover"},
+	{name: "html link to javascript", input: `<a href="javascript:alert(1)">click</a>`,
+		markdown: "click", strict: "click"},
+	{name: "iframe", input: `<iframe src="https://evil.example"></iframe>after`,
+		markdown: "after", strict: "after"},
+	{name: "style with expression", input: `<p style="background:url(javascript:alert(1))">text</p>`,
+		markdown: "text", strict: "text"},
+	{name: "entity encoded tag stays text", input: `&lt;script&gt;alert(1)&lt;/script&gt;`,
+		markdown: "&lt;script>alert(1)&lt;/script>", strict: "&lt;script&gt;alert(1)&lt;/script&gt;"},
+
+	// Markdown links are only followed by the markdown policy; strict output
+	// is shown as plain text, where a link target is inert
+	{name: "markdown javascript link", input: `[click](javascript:alert(1))`,
+		markdown: "[click](#)", strict: "[click](javascript:alert(1))"},
+	{name: "markdown javascript link in mixed case", input: `[click](JaVaScRiPt:alert(1))`,
+		markdown: "[click](#)", strict: "[click](JaVaScRiPt:alert(1))"},
+	{name: "markdown javascript link after whitespace", input: `[click]( javascript:alert(1))`,
+		markdown: "[click](#)", strict: "[click]( javascript:alert(1))"},
+	{name: "markdown javascript link with an encoded scheme", input: `[click](&#106;avascript:alert(1))`,
+		markdown: "[click](#)", strict: "[click](javascript:alert(1))"},
+	{name: "markdown vbscript link", input: `[x](vbscript:msgbox(1))`,
+		markdown: "[x](#)", strict: "[x](vbscript:msgbox(1))"},
+	{name: "markdown data link", input: `[click](data:text/html;base64,PHNjcmlwdD4=)`,
+		markdown: "[click](#)", strict: "[click](data:text/html;base64,PHNjcmlwdD4=)"},
+	{name: "markdown javascript reference", input: `[ref]: javascript:alert(1)`,
+		markdown: "[ref]: #", strict: "[ref]: javascript:alert(1)"},
+}
+
+// safeText is ordinary writing that both policies must keep
+var safeText = []struct {
+	name     string
+	input    string
+	markdown string
+	strict   string
+}{
+	{name: "comparison signs", input: `a < b & c > d`,
+		markdown: "a &lt; b & c > d", strict: "a &lt; b & c &gt; d"},
+	{name: "quotes and apostrophes", input: `Tom's "note"`,
+		markdown: `Tom's "note"`, strict: `Tom's "note"`},
+	{name: "markdown quote", input: `> quoted`,
+		markdown: "> quoted", strict: "&gt; quoted"},
+	{name: "https link", input: `[ok](https://example.com/a_(b))`,
+		markdown: "[ok](https://example.com/a_(b))", strict: "[ok](https://example.com/a_(b))"},
+	{name: "relative link", input: `[ok](/relative)`,
+		markdown: "[ok](/relative)", strict: "[ok](/relative)"},
+	{name: "mailto link", input: `[mail](mailto:a@b.c)`,
+		markdown: "[mail](mailto:a@b.c)", strict: "[mail](mailto:a@b.c)"},
+	{name: "https reference", input: `[ref]: https://example.com`,
+		markdown: "[ref]: https://example.com", strict: "[ref]: https://example.com"},
+}
+
+// unsafeMarkdownTarget finds link targets with a scheme other than http,
+// https or mailto in markdown output
+var unsafeMarkdownTarget = regexp.MustCompile(`(?i)(\]\(\s*|^\s*\[[^\]]+\]:\s*)(?:javascript|vbscript|data|file):`)
+
+func newTestSanitizer(t *testing.T, answers, notes string) *Sanitizer {
+	t.Helper()
+	s, err := NewSanitizer(answers, notes)
+	if err != nil {
+		t.Fatalf("NewSanitizer(%q, %q): %v", answers, notes, err)
+	}
+	return s
+}
+
+func TestSanitizerXSS(t *testing.T) {
+	s := newTestSanitizer(t, SanitizeMarkdown, SanitizeStrict)
+
+	for _, tt := range xssPayloads {
+		t.Run(tt.name, func(t *testing.T) {
+			markdown := s.Answer(tt.input)
+			if markdown != tt.markdown {
+				t.Errorf("markdown policy = %q, want %q", markdown, tt.markdown)
+			}
+			strict := s.Note(tt.input)
+			if strict != tt.strict {
+				t.Errorf("strict policy = %q, want %q", strict, tt.strict)
+			}
+
+			for policy, output := range map[string]string{"markdown": markdown, "strict": strict} {
+				if strings.Contains(output, "<") {
+					t.Errorf("%s policy left a tag open in %q", policy, output)
+				}
+			}
+			if unsafeMarkdownTarget.MatchString(markdown) {
+				t.Errorf("markdown policy kept an unsafe link target in %q", markdown)
+			}
+		})
+	}
+}
+
+func TestSanitizerKeepsSafeText(t *testing.T) {
+	s := newTestSanitizer(t, SanitizeMarkdown, SanitizeStrict)
+
+	for _, tt := range safeText {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := s.Answer(tt.input); got != tt.markdown {
+				t.Errorf("markdown policy = %q, want %q", got, tt.markdown)
+			}
+			if got := s.Note(tt.input); got != tt.strict {
+				t.Errorf("strict policy = %q, want %q", got, tt.strict)
+			}
+		})
+	}
+}
+
+func TestSanitizerTextIsAlwaysStrict(t *testing.T) {
+	s := newTestSanitizer(t, SanitizeMarkdown, SanitizeMarkdown)
+
+	for _, tt := range xssPayloads {
+		if got := s.Text(tt.input); got != tt.strict {
+			t.Errorf("Text(%q) = %q, want %q", tt.input, got, tt.strict)
+		}
+	}
+}
+
+func TestSanitizerUnknownPolicyFallsBackToStrict(t *testing.T) {
+	s, err := NewSanitizer("html", SanitizeMarkdown)
+	if err == nil {
+		t.Fatal("NewSanitizer accepted an unknown policy")
+	}
+	input := `[click](javascript:alert(1))<script>alert(1)</script>`
+	if got, want := s.Answer(input), "[click](javascript:alert(1))"; got != want {
+		t.Errorf("Answer = %q, want strict output %q", got, want)
+	}
+}
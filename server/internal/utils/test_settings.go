@@ -0,0 +1,44 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+)
+
+// ForStudy returns the question with a study's test settings in place of
+// the questionnaire's. Settings the study doesn't set are kept.
+func (q Question) ForStudy(studyID string) Question {
+	overrides := q.StudyOptions[studyID]
+	if len(overrides) == 0 {
+		return q
+	}
+
+	options := append([]QuestionOption(nil), q.Options...)
+	for _, option := range overrides {
+		options = setOption(options, option.Label, option.Value)
+	}
+	q.Options = options
+	return q
+}
+
+// TestSettings returns a cognitive test's option values by label, as
+// recorded with its results
+func TestSettings(q Question) map[string]any {
+	settings := make(map[string]any, len(q.Options))
+	for _, option := range q.Options {
+		settings[option.Label] = option.Value
+	}
+	return settings
+}
+
+// SettingsHash returns a SHA-256 hash of test settings. Map keys are
+// encoded in sorted order, so equal settings always hash the same.
+func SettingsHash(settings map[string]any) (string, error) {
+	encoded, err := json.Marshal(settings)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(encoded)
+	return hex.EncodeToString(sum[:]), nil
+}
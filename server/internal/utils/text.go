@@ -0,0 +1,66 @@
+// internal/utils/text.go
+package utils
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// NormalizeText puts text into Unicode NFC form, so the same answer typed on
+// different keyboards is stored, compared and counted the same way
+func NormalizeText(s string) string {
+	return norm.NFC.String(s)
+}
+
+// CountCharacters counts user-perceived characters. Combining marks, emoji
+// modifiers, variation selectors and zero-width-joined sequences count
+// together with the character they attach to, and a pair of regional
+// indicators counts as one flag.
+func CountCharacters(s string) int {
+	count := 0
+	joined := false
+	regional := false
+	var prev rune
+	for _, r := range s {
+		switch {
+		case joined:
+			// The character after a zero-width joiner belongs to the sequence
+			joined = false
+		case r == '\u200d':
+			joined = true
+		case extendsCharacter(r):
+		case prev == '\r' && r == '\n':
+		case isRegionalIndicator(r) && regional:
+			regional = false
+		default:
+			count++
+			regional = isRegionalIndicator(r)
+		}
+		if !isRegionalIndicator(r) {
+			regional = false
+		}
+		prev = r
+	}
+	return count
+}
+
+// CountWords counts runs of text separated by whitespace
+func CountWords(s string) int {
+	return len(strings.Fields(s))
+}
+
+// extendsCharacter reports whether a rune modifies the character before it
+// rather than starting a new one
+func extendsCharacter(r rune) bool {
+	return unicode.In(r, unicode.Mn, unicode.Me, unicode.Mc) ||
+		(r >= 0xfe00 && r <= 0xfe0f) || // Variation selectors
+		(r >= 0xe0100 && r <= 0xe01ef) || // Variation selectors supplement
+		(r >= 0x1f3fb && r <= 0x1f3ff) || // Emoji skin tone modifiers
+		(r >= 0xe0020 && r <= 0xe007f) // Tag characters in subdivision flags
+}
+
+func isRegionalIndicator(r rune) bool {
+	return r >= 0x1f1e6 && r <= 0x1f1ff
+}
@@ -0,0 +1,67 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	_ "image/png" // Register the PNG decoder
+	"io"
+)
+
+// thumbnailQuality is the JPEG quality of generated thumbnails
+const thumbnailQuality = 80
+
+// Thumbnail decodes a JPEG or PNG image and returns a JPEG no wider than
+// width pixels. Each thumbnail pixel averages the block of source pixels it
+// covers, which keeps downscaled photos smooth.
+func Thumbnail(r io.Reader, width int) ([]byte, error) {
+	src, _, err := image.Decode(r)
+	if err != nil {
+		return nil, fmt.Errorf("decoding image: %w", err)
+	}
+
+	bounds := src.Bounds()
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+	if srcW == 0 || srcH == 0 {
+		return nil, fmt.Errorf("image is empty")
+	}
+	if width <= 0 || width > srcW {
+		width = srcW
+	}
+	height := max(srcH*width/srcW, 1)
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := range height {
+		y0 := bounds.Min.Y + y*srcH/height
+		y1 := max(bounds.Min.Y+(y+1)*srcH/height, y0+1)
+		for x := range width {
+			x0 := bounds.Min.X + x*srcW/width
+			x1 := max(bounds.Min.X+(x+1)*srcW/width, x0+1)
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					pr, pg, pb, pa := src.At(sx, sy).RGBA()
+					r, g, b, a = r+uint64(pr), g+uint64(pg), b+uint64(pb), a+uint64(pa)
+					n++
+				}
+			}
+			// JPEG has no alpha, so transparent areas are drawn on white
+			white := n*0xffff - a
+			dst.SetRGBA(x, y, color.RGBA{
+				R: uint8((r + white) / n >> 8),
+				G: uint8((g + white) / n >> 8),
+				B: uint8((b + white) / n >> 8),
+				A: 0xff,
+			})
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: thumbnailQuality}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
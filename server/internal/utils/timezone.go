@@ -0,0 +1,55 @@
+package utils
+
+import "time"
+
+// ValidTimezone reports whether a name is an IANA time zone, such as one
+// detected by a participant's browser
+func ValidTimezone(name string) bool {
+	if name == "" || name == "Local" || len(name) > 64 {
+		return false
+	}
+	_, err := time.LoadLocation(name)
+	return err == nil
+}
+
+// WallClock returns the moment a wall-clock time occurs on a date in a time
+// zone. A time skipped when the clocks spring forward resolves to the moment
+// they jump, and a time repeated when they fall back resolves to its first
+// occurrence.
+func WallClock(year int, month time.Month, day, hour, min int, loc *time.Location) time.Time {
+	t := time.Date(year, month, day, hour, min, 0, 0, loc)
+
+	// time.Date shifts a skipped time by the length of the gap, in either direction
+	want := time.Date(year, month, day, hour, min, 0, 0, time.UTC)
+	got := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, time.UTC)
+	start, end := t.ZoneBounds()
+	if got.Before(want) && !end.IsZero() {
+		return end
+	}
+	if got.After(want) && !start.IsZero() {
+		return start
+	}
+
+	// time.Date may also pick either occurrence of a repeated time, so check
+	// whether the clock read this time under the zone's previous offset too
+	if !start.IsZero() {
+		_, offset := start.Add(-time.Nanosecond).Zone()
+		earlier := want.Add(-time.Duration(offset) * time.Second).In(loc)
+		if earlier.Before(start) && earlier.Hour() == hour && earlier.Minute() == min {
+			return earlier
+		}
+	}
+	return t
+}
+
+// NextWallClock returns the next moment after now that a daily wall-clock
+// time occurs in a time zone, so a daily time stays put on the clock when it
+// changes
+func NextWallClock(now time.Time, loc *time.Location, hour, min int) time.Time {
+	local := now.In(loc)
+	next := WallClock(local.Year(), local.Month(), local.Day(), hour, min, loc)
+	for days := 1; !next.After(now); days++ {
+		next = WallClock(local.Year(), local.Month(), local.Day()+days, hour, min, loc)
+	}
+	return next
+}
@@ -0,0 +1,123 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+// Clock changes in the zones under test, in 2026:
+//
+//	America/New_York  springs forward 8 Mar 02:00 -> 03:00, falls back 1 Nov 02:00 -> 01:00
+//	Europe/London     springs forward 29 Mar 01:00 -> 02:00, falls back 25 Oct 02:00 -> 01:00
+//	America/Santiago  falls back 4 Apr 24:00 -> 23:00, springs forward 6 Sep 00:00 -> 01:00
+func loadZone(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatalf("loading %s: %v", name, err)
+	}
+	return loc
+}
+
+func utc(month time.Month, day, hour, min int) time.Time {
+	return time.Date(2026, month, day, hour, min, 0, 0, time.UTC)
+}
+
+func TestWallClock(t *testing.T) {
+	tests := []struct {
+		name      string
+		zone      string
+		month     time.Month
+		day       int
+		hour, min int
+		want      time.Time
+	}{
+		{name: "ordinary time", zone: "America/New_York", month: time.March, day: 8, hour: 9, want: utc(time.March, 8, 13, 0)},
+		{name: "skipped time resolves to the jump", zone: "America/New_York", month: time.March, day: 8, hour: 2, min: 30, want: utc(time.March, 8, 7, 0)},
+		{name: "repeated time resolves to its first occurrence", zone: "America/New_York", month: time.November, day: 1, hour: 1, min: 30, want: utc(time.November, 1, 5, 30)},
+		{name: "London skipped time", zone: "Europe/London", month: time.March, day: 29, hour: 1, min: 30, want: utc(time.March, 29, 1, 0)},
+		{name: "London repeated time", zone: "Europe/London", month: time.October, day: 25, hour: 1, min: 30, want: utc(time.October, 25, 0, 30)},
+		{name: "skipped midnight", zone: "America/Santiago", month: time.September, day: 6, want: utc(time.September, 6, 4, 0)},
+		{name: "repeated hour before midnight", zone: "America/Santiago", month: time.April, day: 4, hour: 23, min: 30, want: utc(time.April, 5, 2, 30)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loc := loadZone(t, tt.zone)
+			if got := WallClock(2026, tt.month, tt.day, tt.hour, tt.min, loc); !got.Equal(tt.want) {
+				t.Errorf("WallClock = %v, want %v", got.UTC(), tt.want)
+			}
+		})
+	}
+}
+
+func TestNextWallClock(t *testing.T) {
+	tests := []struct {
+		name      string
+		zone      string
+		now       time.Time
+		hour, min int
+		want      time.Time
+	}{
+		{name: "later today", zone: "America/New_York", now: utc(time.March, 7, 12, 0), hour: 9, want: utc(time.March, 7, 14, 0)},
+		{name: "spring forward day is 23 hours away", zone: "America/New_York", now: utc(time.March, 7, 15, 0), hour: 9, want: utc(time.March, 8, 13, 0)},
+		{name: "fall back day is 25 hours away", zone: "America/New_York", now: utc(time.October, 31, 14, 0), hour: 9, want: utc(time.November, 1, 14, 0)},
+		{name: "skipped time fires as the clocks jump", zone: "America/New_York", now: utc(time.March, 7, 8, 0), hour: 2, min: 30, want: utc(time.March, 8, 7, 0)},
+		{name: "repeated time fires once", zone: "America/New_York", now: utc(time.November, 1, 5, 45), hour: 1, min: 30, want: utc(time.November, 2, 6, 30)},
+		{name: "London spring forward", zone: "Europe/London", now: utc(time.March, 28, 10, 0), hour: 9, want: utc(time.March, 29, 8, 0)},
+		{name: "London fall back", zone: "Europe/London", now: utc(time.October, 24, 9, 0), hour: 9, want: utc(time.October, 25, 9, 0)},
+		{name: "skipped midnight", zone: "America/Santiago", now: utc(time.September, 5, 12, 0), want: utc(time.September, 6, 4, 0)},
+		{name: "exactly now waits a day", zone: "Europe/London", now: utc(time.June, 1, 8, 0), hour: 9, want: utc(time.June, 2, 8, 0)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loc := loadZone(t, tt.zone)
+			if got := NextWallClock(tt.now, loc, tt.hour, tt.min); !got.Equal(tt.want) {
+				t.Errorf("NextWallClock = %v, want %v", got.UTC(), tt.want)
+			}
+		})
+	}
+}
+
+// TestNextWallClockDailyReminders follows a daily reminder from one firing to
+// the next through both clock changes, as the scheduler does
+func TestNextWallClockDailyReminders(t *testing.T) {
+	for _, zone := range []string{"America/New_York", "Europe/London", "America/Santiago"} {
+		t.Run(zone, func(t *testing.T) {
+			loc := loadZone(t, zone)
+			fire := NextWallClock(utc(time.January, 1, 0, 0), loc, 9, 0)
+
+			for i := 0; i < 365; i++ {
+				next := NextWallClock(fire, loc, 9, 0)
+				local := next.In(loc)
+				if local.Hour() != 9 || local.Minute() != 0 {
+					t.Fatalf("reminder after %v fires at %v", fire.In(loc), local)
+				}
+				if want := fire.In(loc).AddDate(0, 0, 1); local.Day() != want.Day() {
+					t.Fatalf("reminder after %v fires on %v, want %v", fire.In(loc), local, want)
+				}
+				if gap := next.Sub(fire); gap < 23*time.Hour || gap > 25*time.Hour {
+					t.Fatalf("reminder after %v is %v later", fire.In(loc), gap)
+				}
+				fire = next
+			}
+		})
+	}
+}
+
+func TestValidTimezone(t *testing.T) {
+	for name, want := range map[string]bool{
+		"America/New_York": true,
+		"Europe/London":    true,
+		"UTC":              true,
+		"":                 false,
+		"Local":            false,
+		"Mars/Olympus":     false,
+		"../../etc/passwd": false,
+	} {
+		if got := ValidTimezone(name); got != want {
+			t.Errorf("ValidTimezone(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
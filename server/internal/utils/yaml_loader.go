@@ -1,8 +1,11 @@
 package utils
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"os"
+	"sync"
 
 	"gopkg.in/yaml.v3"
 )
@@ -24,11 +27,92 @@ type Question struct {
 	MetricsType    string           `yaml:"metrics_type,omitempty" json:"metrics_type,omitempty"`
 	Required       bool             `yaml:"required" json:"required"`
 	Placeholder    string           `yaml:"placeholder,omitempty" json:"placeholder,omitempty"`
+	MinLength      int              `yaml:"min_length,omitempty" json:"min_length,omitempty"`
 	MaxLength      int              `yaml:"max_length,omitempty" json:"max_length,omitempty"`
+	MinWords       int              `yaml:"min_words,omitempty" json:"min_words,omitempty"`
+	MaxWords       int              `yaml:"max_words,omitempty" json:"max_words,omitempty"`
 	Pattern        string           `yaml:"pattern,omitempty" json:"pattern,omitempty"`
 	PatternMessage string           `yaml:"pattern_message,omitempty" json:"pattern_message,omitempty"`
 	Options        []QuestionOption `yaml:"options,omitempty" json:"options,omitempty"`
 	Default        string           `yaml:"default_option,omitempty" json:"default_option,omitempty"`
+
+	// Start from the user's previous answer instead of a blank question
+	PrefillPrevious bool `yaml:"prefill_previous,omitempty" json:"prefill_previous,omitempty"`
+
+	// Only include the question in a form once this many days have passed
+	// since the user last completed it; 0 includes it in every form
+	EveryDays int `yaml:"every_days,omitempty" json:"every_days,omitempty"`
+	// Core questions make up the short form served in adaptive mode
+	Core bool `yaml:"core,omitempty" json:"core,omitempty"`
+	// Answers at or above a level raise an alert as soon as they are submitted
+	RedFlag *RedFlag `yaml:"red_flag,omitempty" json:"red_flag,omitempty"`
+	// Instructions shown alongside the question
+	Help *QuestionHelp `yaml:"help,omitempty" json:"help,omitempty"`
+	// Honeypots are listed with the questionnaire but never shown in a form,
+	// so only a script answers them
+	Honeypot bool `yaml:"honeypot,omitempty" json:"honeypot,omitempty"`
+
+	// Cognitive test settings replaced for participants in a study, keyed by study ID
+	StudyOptions map[string][]QuestionOption `yaml:"study_options,omitempty" json:"-"`
+	// Hash of the test settings served, so results taken under the same
+	// settings can be compared
+	SettingsHash string `yaml:"-" json:"settings_hash,omitempty"`
+}
+
+// QuestionHelp holds a question's instructions. Media are served by the
+// application itself, so their URLs are paths such as /static/help/tmt.png.
+type QuestionHelp struct {
+	Text   string      `yaml:"text,omitempty" json:"text,omitempty"`
+	Images []HelpImage `yaml:"images,omitempty" json:"images,omitempty"`
+	Videos []HelpVideo `yaml:"videos,omitempty" json:"videos,omitempty"`
+}
+
+// HelpImage is an example image in a question's help
+type HelpImage struct {
+	URL     string `yaml:"url" json:"url"`
+	Alt     string `yaml:"alt" json:"alt"`
+	Caption string `yaml:"caption,omitempty" json:"caption,omitempty"`
+}
+
+// HelpVideo is an instruction video in a question's help
+type HelpVideo struct {
+	URL      string `yaml:"url" json:"url"`
+	Title    string `yaml:"title" json:"title"`
+	Captions string `yaml:"captions,omitempty" json:"captions,omitempty"` // WebVTT track
+	Poster   string `yaml:"poster,omitempty" json:"poster,omitempty"`
+}
+
+// RedFlag marks answers that need a clinician's attention straight away, such
+// as the most severe level of a symptom
+type RedFlag struct {
+	// Lowest answer value that raises the alert
+	AtLeast float64 `yaml:"at_least" json:"at_least"`
+	// Show the crisis resources in the submit response when raised
+	ShowResources bool `yaml:"show_resources,omitempty" json:"show_resources,omitempty"`
+}
+
+// Raised reports whether an answer reaches the red-flag level
+func (r *RedFlag) Raised(value float64) bool {
+	return r != nil && value >= r.AtLeast
+}
+
+// CrisisResource is a helpline or service shown to a user after a red-flag answer
+type CrisisResource struct {
+	Name        string `yaml:"name" json:"name"`
+	Description string `yaml:"description,omitempty" json:"description,omitempty"`
+	Phone       string `yaml:"phone,omitempty" json:"phone,omitempty"`
+	URL         string `yaml:"url,omitempty" json:"url,omitempty"`
+}
+
+// AdaptiveConfig shortens the form to its core questions while a user's
+// answers are stable
+type AdaptiveConfig struct {
+	// Days of answers that must be stable before short forms are served
+	StableDays int `yaml:"stable_days" json:"stable_days"`
+	// Largest change in a numeric answer over those days that still counts as stable
+	MaxChange float64 `yaml:"max_change" json:"max_change"`
+	// Serve the full form at least this often; defaults to stable_days
+	FullEveryDays int `yaml:"full_every_days,omitempty" json:"full_every_days,omitempty"`
 }
 
 // Reminder represents reminder settings
@@ -40,13 +124,20 @@ type Reminder struct {
 
 // QuestionsConfig represents the entire questions YAML file
 type QuestionsConfig struct {
-	Questions []Question `yaml:"questions" json:"questions"`
+	// How forms order the questions: random, fixed, or latin_square
+	Ordering string `yaml:"ordering,omitempty" json:"ordering,omitempty"`
+	// Optional adaptive length; nil always serves the full form
+	Adaptive *AdaptiveConfig `yaml:"adaptive,omitempty" json:"adaptive,omitempty"`
+	// Shown after red-flag answers whose questions ask for them
+	CrisisResources []CrisisResource `yaml:"crisis_resources,omitempty" json:"crisis_resources,omitempty"`
+	Questions       []Question       `yaml:"questions" json:"questions"`
 }
 
 // QuestionLoader loads and processes question definitions
 type QuestionLoader struct {
 	YAMLPath string
 	Config   QuestionsConfig
+	Checksum string // SHA-256 of the file, for cache validation
 }
 
 // NewQuestionLoader creates a new question loader
@@ -60,6 +151,11 @@ func NewQuestionLoader(yamlPath string) (*QuestionLoader, error) {
 		return nil, err
 	}
 
+	// Fail fast on files that parse but would break forms or metrics
+	if err := CheckQuestionsFile(yamlPath); err != nil {
+		return nil, err
+	}
+
 	// Update any missing metrics_type based on question type
 	for i := range loader.Config.Questions {
 		if loader.Config.Questions[i].MetricsType == "" {
@@ -89,6 +185,8 @@ func (q *QuestionLoader) LoadYAML() error {
 	if err != nil {
 		return fmt.Errorf("failed to parse questions YAML file: %w", err)
 	}
+	sum := sha256.Sum256(yamlFile)
+	q.Checksum = hex.EncodeToString(sum[:])
 
 	if len(q.Config.Questions) == 0 {
 		return fmt.Errorf("no questions defined in YAML file")
@@ -102,6 +200,14 @@ func (q *QuestionLoader) GetQuestions() []Question {
 	return q.Config.Questions
 }
 
+// Ordering returns the file's question ordering scheme, random by default
+func (q *QuestionLoader) Ordering() string {
+	if q.Config.Ordering == "" {
+		return OrderingRandom
+	}
+	return q.Config.Ordering
+}
+
 // GetQuestionByID gets a question by its ID
 func (q *QuestionLoader) GetQuestionByID(id string) *Question {
 	if id == "" {
@@ -148,3 +254,46 @@ func (q *QuestionLoader) GetQuestionsByMetricsType(metricsType string) []Questio
 	}
 	return filteredQuestions
 }
+
+// QuestionRegistry caches question loaders for organizations that define their
+// own questions file, falling back to the deployment default
+type QuestionRegistry struct {
+	defaultLoader *QuestionLoader
+	mu            sync.Mutex
+	loaders       map[string]*QuestionLoader
+}
+
+// NewQuestionRegistry creates a registry around the default question loader
+func NewQuestionRegistry(defaultLoader *QuestionLoader) *QuestionRegistry {
+	return &QuestionRegistry{
+		defaultLoader: defaultLoader,
+		loaders:       make(map[string]*QuestionLoader),
+	}
+}
+
+// Default returns the deployment-wide question loader
+func (r *QuestionRegistry) Default() *QuestionLoader {
+	return r.defaultLoader
+}
+
+// Get returns the loader for a questions file, loading it on first use. An
+// empty path returns the default loader.
+func (r *QuestionRegistry) Get(yamlPath string) (*QuestionLoader, error) {
+	if yamlPath == "" || yamlPath == r.defaultLoader.YAMLPath {
+		return r.defaultLoader, nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if loader, ok := r.loaders[yamlPath]; ok {
+		return loader, nil
+	}
+
+	loader, err := NewQuestionLoader(yamlPath)
+	if err != nil {
+		return nil, err
+	}
+	r.loaders[yamlPath] = loader
+	return loader, nil
+}
@@ -2,12 +2,16 @@
 package validation
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"reflect"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 	"github.com/go-playground/validator/v10"
 )
 
@@ -16,6 +20,10 @@ type APIValidator struct {
 	validator *validator.Validate
 }
 
+// useJSONNames makes gin's binding validator name fields by their JSON tags,
+// so errors from binding tags point at the same fields as validate tags
+var useJSONNames sync.Once
+
 // NewAPIValidator creates a new API validator
 func NewAPIValidator() *APIValidator {
 	v := validator.New()
@@ -23,12 +31,12 @@ func NewAPIValidator() *APIValidator {
 	v.RegisterValidation("datetime", validateDateTime)
 
 	// Register tag name function to use json tag names in errors
-	v.RegisterTagNameFunc(func(fld reflect.StructField) string {
-		name := strings.SplitN(fld.Tag.Get("json"), ",", 2)[0]
-		if name == "-" {
-			return ""
+	v.RegisterTagNameFunc(jsonFieldName)
+
+	useJSONNames.Do(func() {
+		if engine, ok := binding.Validator.Engine().(*validator.Validate); ok {
+			engine.RegisterTagNameFunc(jsonFieldName)
 		}
-		return name
 	})
 
 	return &APIValidator{
@@ -36,62 +44,195 @@ func NewAPIValidator() *APIValidator {
 	}
 }
 
+func jsonFieldName(fld reflect.StructField) string {
+	name := strings.SplitN(fld.Tag.Get("json"), ",", 2)[0]
+	if name == "-" {
+		return ""
+	}
+	return name
+}
+
 // ValidateStruct validates a struct using validator tags
 func (v *APIValidator) ValidateStruct(obj any) []ValidationError {
-	err := v.validator.Struct(obj)
-	if err == nil {
-		return nil
+	return fieldErrors(v.validator.Struct(obj))
+}
+
+// Bind validates and binds request data to a struct
+func (v *APIValidator) Bind(c *gin.Context, obj any) []ValidationError {
+	// Bind request to struct; this also checks binding tags
+	if err := c.ShouldBind(obj); err != nil {
+		return bindErrors(err)
+	}
+
+	// Validate struct
+	return v.ValidateStruct(obj)
+}
+
+// bindErrors describes why a request couldn't be bound
+func bindErrors(err error) []ValidationError {
+	var fieldErrs validator.ValidationErrors
+	if errors.As(err, &fieldErrs) {
+		return fieldErrors(fieldErrs)
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		path := strings.Split(typeErr.Field, ".")
+		return []ValidationError{{
+			Field:     path[0],
+			Pointer:   JSONPointer(path...),
+			Code:      CodeInvalidType,
+			Message:   fmt.Sprintf("Field '%s' must be %s, not %s", typeErr.Field, jsonTypeName(typeErr.Type), typeErr.Value),
+			ValueType: typeErr.Value,
+		}}
 	}
 
-	var errors []ValidationError
+	return []ValidationError{{
+		Field:   "request",
+		Pointer: "",
+		Code:    CodeMalformed,
+		Message: "Invalid request format",
+	}}
+}
+
+// fieldErrors converts validator errors into validation errors
+func fieldErrors(err error) []ValidationError {
+	var fieldErrs validator.ValidationErrors
+	if !errors.As(err, &fieldErrs) {
+		return nil
+	}
 
-	// Process validation errors
-	for _, err := range err.(validator.ValidationErrors) {
+	var result []ValidationError
+	for _, err := range fieldErrs {
 		field := err.Field()
 		tag := err.Tag()
+		sized := isSized(err.Kind())
+		unit := " items"
+		if err.Kind() == reflect.String {
+			unit = " characters long"
+		}
 
 		// Create error message based on validation tag
-		var message string
+		var code, message string
 		switch tag {
-		case "required":
+		case "required", "required_with", "required_without":
+			code = CodeRequired
 			message = fmt.Sprintf("Field '%s' is required", field)
 		case "email":
+			code = CodeEmail
 			message = fmt.Sprintf("Field '%s' must be a valid email address", field)
-		case "min":
-			message = fmt.Sprintf("Field '%s' must be at least %s characters long", field, err.Param())
-		case "max":
-			message = fmt.Sprintf("Field '%s' must be at most %s characters long", field, err.Param())
+		case "min", "gte":
+			code = CodeMin
+			message = fmt.Sprintf("Field '%s' must be at least %s", field, err.Param())
+			if sized {
+				code = CodeMinLength
+				message += unit
+			}
+		case "max", "lte":
+			code = CodeMax
+			message = fmt.Sprintf("Field '%s' must be at most %s", field, err.Param())
+			if sized {
+				code = CodeMaxLength
+				message += unit
+			}
+		case "oneof":
+			code = CodeInvalidOption
+			message = fmt.Sprintf("Field '%s' must be one of: %s", field, err.Param())
+		case "datetime", "numeric":
+			code = CodeInvalidFormat
+			message = fmt.Sprintf("Field '%s' has an invalid format", field)
 		case "phone":
+			code = CodeInvalidFormat
 			message = fmt.Sprintf("Field '%s' must be a valid phone number", field)
 		default:
+			code = tag
 			message = fmt.Sprintf("Validation failed for field '%s' on tag '%s'", field, tag)
 		}
 
-		errors = append(errors, ValidationError{
-			Field:   field,
-			Message: message,
-			Tag:     tag,
-			Value:   fmt.Sprintf("%v", err.Value()),
+		// A missing value has no type worth reporting
+		valueType := reflectValueType(err.Value())
+		if code == CodeRequired {
+			valueType = ""
+		}
+
+		path := namespacePath(err.Namespace())
+		top := field
+		if len(path) > 0 {
+			top = path[0]
+		}
+		result = append(result, ValidationError{
+			Field:     top,
+			Pointer:   JSONPointer(path...),
+			Code:      code,
+			Message:   message,
+			ValueType: valueType,
 		})
 	}
 
-	return errors
+	return result
 }
 
-// Bind validates and binds request data to a struct
-func (v *APIValidator) Bind(c *gin.Context, obj any) []ValidationError {
-	// Bind request to struct
-	if err := c.ShouldBind(obj); err != nil {
-		return []ValidationError{{
-			Field:   "request",
-			Message: "Invalid request format",
-			Tag:     "binding",
-			Value:   "",
-		}}
+// namespacePath splits a validator namespace such as
+// "LoginRequest.device_info[os]" into JSON path segments, dropping the
+// struct name
+func namespacePath(namespace string) []string {
+	namespace = strings.NewReplacer("[", ".", "]", "").Replace(namespace)
+	parts := strings.Split(namespace, ".")
+	if len(parts) > 1 {
+		parts = parts[1:]
+	}
+	path := parts[:0]
+	for _, part := range parts {
+		if part != "" {
+			path = append(path, part)
+		}
 	}
+	return path
+}
 
-	// Validate struct
-	return v.ValidateStruct(obj)
+// isSized reports whether min and max limit a value's length rather than its size
+func isSized(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.String, reflect.Slice, reflect.Array, reflect.Map:
+		return true
+	}
+	return false
+}
+
+// reflectValueType names the JSON type a Go value decodes from
+func reflectValueType(value any) string {
+	v := reflect.ValueOf(value)
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return "null"
+		}
+		v = v.Elem()
+	}
+	return jsonTypeName(v.Type())
+}
+
+// jsonTypeName names the JSON type for a Go type
+func jsonTypeName(t reflect.Type) string {
+	if t == nil {
+		return "null"
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	case reflect.Map, reflect.Struct:
+		return "object"
+	case reflect.Pointer:
+		return jsonTypeName(t.Elem())
+	}
+	return ""
 }
 
 // Custom validation functions
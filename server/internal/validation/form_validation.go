@@ -28,20 +28,14 @@ func (v *FormValidator) ValidateAnswer(questionID string, answer any) []Validati
 	// Get question definition
 	question := v.questionLoader.GetQuestionByID(questionID)
 	if question == nil {
-		errors = append(errors, ValidationError{
-			Field:   questionID,
-			Message: "Invalid question ID",
-		})
+		errors = append(errors, answerError(questionID, CodeUnknownField, "Invalid question ID", answer))
 		return errors
 	}
 
 	// Check required fields
 	if question.Required {
 		if IsEmptyAnswer(answer) {
-			errors = append(errors, ValidationError{
-				Field:   questionID,
-				Message: "This question is required",
-			})
+			errors = append(errors, answerError(questionID, CodeRequired, "This question is required", answer))
 			return errors
 		}
 	}
@@ -88,10 +82,7 @@ func (v *FormValidator) validateRadioAnswer(question *utils.Question, answer any
 	case int:
 		answerStr = fmt.Sprintf("%d", v)
 	default:
-		errors = append(errors, ValidationError{
-			Field:   question.ID,
-			Message: "Invalid answer type",
-		})
+		errors = append(errors, answerError(question.ID, CodeInvalidType, "Invalid answer type", answer))
 		return errors
 	}
 
@@ -118,10 +109,7 @@ func (v *FormValidator) validateRadioAnswer(question *utils.Question, answer any
 	}
 
 	if !valid {
-		errors = append(errors, ValidationError{
-			Field:   question.ID,
-			Message: "Invalid option selected",
-		})
+		errors = append(errors, answerError(question.ID, CodeInvalidOption, "Invalid option selected", answer))
 	}
 
 	return errors
@@ -156,10 +144,7 @@ func (v *FormValidator) validateDropdownAnswer(question *utils.Question, answer
 
 	// Required field check
 	if answerStr == "" && question.Required {
-		errors = append(errors, ValidationError{
-			Field:   question.ID,
-			Message: "This question is required",
-		})
+		errors = append(errors, answerError(question.ID, CodeRequired, "This question is required", answer))
 		return errors
 	}
 
@@ -186,10 +171,7 @@ func (v *FormValidator) validateDropdownAnswer(question *utils.Question, answer
 	}
 
 	if !valid && answerStr != "" {
-		errors = append(errors, ValidationError{
-			Field:   question.ID,
-			Message: "Invalid option selected",
-		})
+		errors = append(errors, answerError(question.ID, CodeInvalidOption, "Invalid option selected", answer))
 	}
 
 	return errors
@@ -206,27 +188,34 @@ func (v *FormValidator) validateTextAnswer(question *utils.Question, answer any)
 
 	str, ok := answer.(string)
 	if !ok {
-		errors = append(errors, ValidationError{
-			Field:   question.ID,
-			Message: "Answer must be text",
-		})
+		errors = append(errors, answerError(question.ID, CodeInvalidType, "Answer must be text", answer))
 		return errors
 	}
 
 	if question == nil {
-		errors = append(errors, ValidationError{
-			Field:   "Question",
-			Message: "Question is nil!",
-		})
+		errors = append(errors, answerError("Question", CodeUnknownField, "Question is nil!", answer))
 		return errors
 	}
 
-	// Check max length
-	if question.MaxLength > 0 && len(str) > question.MaxLength {
-		errors = append(errors, ValidationError{
-			Field:   question.ID,
-			Message: fmt.Sprintf("Text exceeds maximum length of %d characters", question.MaxLength),
-		})
+	// Lengths count characters as the user sees them, not bytes, and empty
+	// optional answers aren't held to the minimums
+	str = utils.NormalizeText(str)
+	if strings.TrimSpace(str) != "" {
+		length := utils.CountCharacters(str)
+		if question.MinLength > 0 && length < question.MinLength {
+			errors = append(errors, answerError(question.ID, CodeMinLength, fmt.Sprintf("Text must be at least %d characters", question.MinLength), answer))
+		}
+		words := utils.CountWords(str)
+		if question.MinWords > 0 && words < question.MinWords {
+			errors = append(errors, answerError(question.ID, CodeMinWords, fmt.Sprintf("Text must be at least %d words", question.MinWords), answer))
+		}
+	}
+
+	if question.MaxLength > 0 && utils.CountCharacters(str) > question.MaxLength {
+		errors = append(errors, answerError(question.ID, CodeMaxLength, fmt.Sprintf("Text exceeds maximum length of %d characters", question.MaxLength), answer))
+	}
+	if question.MaxWords > 0 && utils.CountWords(str) > question.MaxWords {
+		errors = append(errors, answerError(question.ID, CodeMaxWords, fmt.Sprintf("Text exceeds maximum of %d words", question.MaxWords), answer))
 	}
 
 	// Add pattern validation if defined in the question model
@@ -239,10 +228,7 @@ func (v *FormValidator) validateTextAnswer(question *utils.Question, answer any)
 			} else {
 				errorMessage = "Text does not match required format"
 			}
-			errors = append(errors, ValidationError{
-				Field:   question.ID,
-				Message: errorMessage,
-			})
+			errors = append(errors, answerError(question.ID, CodePattern, errorMessage, answer))
 		}
 	}
 
@@ -268,10 +254,7 @@ func (v *FormValidator) ValidateForm(answers map[string]any) ValidationResponse
 	for _, question := range questions {
 		if question.Required {
 			if _, exists := answers[question.ID]; !exists {
-				allErrors = append(allErrors, ValidationError{
-					Field:   question.ID,
-					Message: "This question is required",
-				})
+				allErrors = append(allErrors, answerError(question.ID, CodeRequired, "This question is required", nil))
 			}
 		}
 	}
@@ -283,9 +266,16 @@ func (v *FormValidator) ValidateForm(answers map[string]any) ValidationResponse
 		message = "Validation failed"
 	}
 
-	return ValidationResponse{
-		Valid:   valid,
-		Message: message,
-		Errors:  allErrors,
+	return NewValidationResponse(valid, message, allErrors)
+}
+
+// answerError describes a problem with the answer to a question
+func answerError(questionID, code, message string, answer any) ValidationError {
+	return ValidationError{
+		Field:     questionID,
+		Pointer:   JSONPointer("answers", questionID),
+		Code:      code,
+		Message:   message,
+		ValueType: ValueType(answer),
 	}
 }
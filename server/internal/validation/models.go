@@ -3,20 +3,28 @@ package validation
 
 import (
 	"encoding/json"
+	"time"
 )
 
 // Auth validation models
 type RegisterRequest struct {
-	Email     string `json:"email" validate:"required,email"`
-	Password  string `json:"password" validate:"required,min=8"`
-	FirstName string `json:"first_name" validate:"required"`
-	LastName  string `json:"last_name" validate:"required"`
+	Email          string `json:"email" validate:"required,email"`
+	Password       string `json:"password" validate:"required,min=8"`
+	FirstName      string `json:"first_name" validate:"required"`
+	LastName       string `json:"last_name" validate:"required"`
+	TermsVersion   string `json:"terms_version"`   // Version of the terms accepted at sign-up
+	PrivacyVersion string `json:"privacy_version"` // Version of the privacy policy accepted at sign-up
+	CaptchaToken   string `json:"captcha_token"`   // Turnstile/hCaptcha response, when enabled
+	OrganizationID string `json:"organization_id"` // Organization to join; the deployment default when empty
 }
 
 type LoginRequest struct {
 	Email      string         `json:"email" validate:"required,email"`
 	Password   string         `json:"password" validate:"required"`
 	DeviceInfo map[string]any `json:"device_info"`
+	// RememberMe keeps the session across browser restarts; when omitted the
+	// session is remembered
+	RememberMe *bool `json:"remember_me"`
 }
 
 type RefreshTokenRequest struct {
@@ -39,6 +47,7 @@ type RegisterDeviceRequest struct {
 	UserAgent  string         `json:"user_agent"`
 	OS         string         `json:"os"`
 	ScreenSize map[string]any `json:"screen_size"`
+	Timezone   string         `json:"timezone" validate:"omitempty,max=64"` // IANA zone detected by the browser
 }
 
 type RenameDeviceRequest struct {
@@ -54,6 +63,13 @@ type SaveAnswerRequest struct {
 	CPTData         json.RawMessage `json:"cpt_data,omitempty"`
 	TMTData         json.RawMessage `json:"tmt_data,omitempty"`
 	DigitSpanData   json.RawMessage `json:"digit_span_data,omitempty"`
+	IntegrityHash   string          `json:"integrity_hash,omitempty"` // Head of the hash chain over interaction_data
+	ClientTime      float64         `json:"client_time"`              // Device clock, in epoch milliseconds
+}
+
+// AmendAnswerRequest changes the answer to a question of a submitted assessment
+type AmendAnswerRequest struct {
+	Answer any `json:"answer"`
 }
 
 type SubmitFormRequest struct {
@@ -65,6 +81,9 @@ type SubmitFormRequest struct {
 	Latitude           *float64        `json:"latitude"`            // Use pointer for nullability
 	Longitude          *float64        `json:"longitude"`           // Use pointer for nullability
 	LocationError      *string         `json:"location_error"`      // Optional error message from frontend
+	PerfSessionID      string          `json:"perf_session_id"`     // Session of the performance beacons sent while filling in the form
+	ClientTime         float64         `json:"client_time"`         // Device clock, in epoch milliseconds
+	Nonce              string          `json:"nonce"`               // Echo of the nonce issued with the form
 }
 
 // Push validation models
@@ -77,11 +96,18 @@ type PushSubscriptionRequest struct {
 	ExpirationTime *int64 `json:"expirationTime,omitempty"`
 }
 
-// NotificationPreferencesRequest for the UpdatePreferences endpoint
+// NotificationChannelRequest holds the settings for one delivery channel.
+// Omitted types and reminder times keep their current values.
+type NotificationChannelRequest struct {
+	Enabled       bool            `json:"enabled"`
+	Types         map[string]bool `json:"types" validate:"omitempty,dive,keys,oneof=reminders weekly_summary security_alerts announcements,endkeys"`
+	ReminderTimes []string        `json:"reminder_times" validate:"omitempty,max=5,dive,datetime=15:04"`
+}
+
+// NotificationPreferencesRequest for the UpdatePreferences endpoint, keyed by channel
 type NotificationPreferencesRequest struct {
-	PushEnabled   bool     `json:"push_enabled"`
-	EmailEnabled  bool     `json:"email_enabled"`
-	ReminderTimes []string `json:"reminder_times" validate:"required,dive,datetime=15:04"`
+	Channels   map[string]NotificationChannelRequest `json:"channels" validate:"required,dive,keys,oneof=push email,endkeys"`
+	CutoffTime string                                `json:"cutoff_time" validate:"omitempty,datetime=15:04"`
 }
 
 // ForgotPasswordRequest represents a password reset request
@@ -89,9 +115,12 @@ type ForgotPasswordRequest struct {
 	Email string `json:"email" validate:"required,email"`
 }
 
-// ResetPasswordRequest represents a password reset submission
+// ResetPasswordRequest represents a password reset submission, using either
+// the emailed link token or the email address plus numeric code
 type ResetPasswordRequest struct {
-	Token       string `json:"token" validate:"required"`
+	Token       string `json:"token" validate:"required_without=Code"`
+	Email       string `json:"email" validate:"required_with=Code,omitempty,email"`
+	Code        string `json:"code" validate:"omitempty,numeric,max=12"`
 	NewPassword string `json:"new_password" validate:"required,min=8"`
 }
 
@@ -99,8 +128,270 @@ type DeleteAccountRequest struct {
 	Password string `json:"password" validate:"required"`
 }
 
-// AdminReminderRequest represents a request to send a reminder to a user
+// ReauthRequest re-enters the password before a sensitive action
+type ReauthRequest struct {
+	Password string `json:"password" validate:"required"`
+}
+
+// ReactivateAccountRequest resumes a paused account from the emailed link
+type ReactivateAccountRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// AccessibilityPreferencesRequest replaces the current user's accessibility preferences
+type AccessibilityPreferencesRequest struct {
+	LargeText        bool `json:"large_text"`
+	ReducedMotion    bool `json:"reduced_motion"`
+	ExtendedTimeouts bool `json:"extended_timeouts"`
+}
+
+// WithdrawStudyRequest withdraws the current user from their study
+type WithdrawStudyRequest struct {
+	Reason     string `json:"reason" binding:"max=2000"`
+	DeleteData bool   `json:"delete_data"` // Only honoured where the study leaves it to the participant
+}
+
+// ConfirmLoginRequest confirms a suspicious login from the emailed link
+type ConfirmLoginRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// SnoozeReminderRequest holds back today's reminders for a number of minutes.
+// Zero uses the configured snooze period.
+type SnoozeReminderRequest struct {
+	Minutes int `json:"minutes" binding:"omitempty,min=5,max=720"`
+}
+
+// AdminReminderRequest represents a request to send a reminder to one or more
+// users, now or at a later time. Recipients come from email, emails, or a
+// filter; an empty filter selects every participant in the admin's scope.
 type AdminReminderRequest struct {
-	Email  string `json:"email" binding:"required,email"`
-	Method string `json:"method" binding:"required,oneof=email push"` // "email" or "push"
+	Email         string               `json:"email" binding:"omitempty,email"`
+	Emails        []string             `json:"emails" binding:"omitempty,max=1000,dive,email"`
+	Filter        *AdminReminderFilter `json:"filter"`
+	Method        string               `json:"method" binding:"omitempty,oneof=email push"` // Single channel, kept for older clients
+	Channels      []string             `json:"channels" binding:"omitempty,max=2,dive,oneof=email push"`
+	SendAt        *time.Time           `json:"send_at"`        // Defaults to now
+	SkipCompleted bool                 `json:"skip_completed"` // Skip users who have done the day's assessment by send time
+}
+
+// AdminReminderFilter selects reminder recipients by study or inactivity
+type AdminReminderFilter struct {
+	StudyID      string `json:"study_id" binding:"omitempty,max=64"`
+	InactiveOnly bool   `json:"inactive_only"`
+}
+
+// ReminderTemplateRequest sets a study's reminder copy for one channel. An
+// empty subject keeps the default subject.
+type ReminderTemplateRequest struct {
+	Subject string `json:"subject" binding:"max=200"`
+	Body    string `json:"body" binding:"required,max=2000"`
+}
+
+// LifecycleOverrideRequest assigns a user to a study and sets their inactivity policy exemption
+type LifecycleOverrideRequest struct {
+	Email   string `json:"email" binding:"required,email"`
+	StudyID string `json:"study_id" binding:"max=64"`
+	Exempt  bool   `json:"exempt"`
+}
+
+// RetentionOverrideRequest places or releases a user's legal hold and sets
+// their own retention period; a retention_days of 0 removes it
+type RetentionOverrideRequest struct {
+	Email           string `json:"email" binding:"required,email"`
+	LegalHold       bool   `json:"legal_hold"`
+	RetentionDays   int    `json:"retention_days" binding:"min=0,max=36500"`
+	RetentionAction string `json:"retention_action" binding:"omitempty,oneof=anonymize purge"`
+	Reason          string `json:"reason" binding:"max=500"`
+}
+
+// AssignClinicianRequest sets the clinician notified about a user's red-flag
+// answers; an empty clinician_email removes the assignment
+type AssignClinicianRequest struct {
+	Email          string `json:"email" binding:"required,email"`
+	ClinicianEmail string `json:"clinician_email" binding:"omitempty,email"`
+}
+
+// StartKioskSessionRequest represents a clinician launching a proctored session for a patient
+type StartKioskSessionRequest struct {
+	PatientEmail string `json:"patient_email" validate:"required,email"`
+}
+
+// StartImpersonationRequest represents an admin asking to view the app as a participant
+type StartImpersonationRequest struct {
+	Email       string `json:"email" validate:"required,email"`
+	Reason      string `json:"reason" validate:"required,min=10,max=500"`
+	WriteAccess bool   `json:"write_access"`
+}
+
+// ImpersonationConsentRequest represents a participant's answer to an impersonation request
+type ImpersonationConsentRequest struct {
+	Approve bool `json:"approve"`
+}
+
+// InviteCaregiverRequest represents a participant inviting a caregiver
+type InviteCaregiverRequest struct {
+	CaregiverEmail string `json:"caregiver_email" validate:"required,email"`
+}
+
+// AcceptCaregiverInviteRequest represents a caregiver consenting to an invitation
+type AcceptCaregiverInviteRequest struct {
+	Token   string `json:"token" validate:"required"`
+	Consent bool   `json:"consent" validate:"required"` // Must be explicitly true
+}
+
+// AcceptPolicyRequest represents a user accepting a terms or privacy policy version
+type AcceptPolicyRequest struct {
+	DocumentType string `json:"document_type" validate:"required,oneof=terms privacy"`
+	Version      string `json:"version" validate:"required"`
+}
+
+// CreateOrganizationRequest registers a new organization
+type CreateOrganizationRequest struct {
+	ID              string          `json:"id" binding:"required,max=64"`
+	Name            string          `json:"name" binding:"required,max=200"`
+	QuestionsFile   string          `json:"questions_file"`
+	Branding        json.RawMessage `json:"branding"`
+	DedicatedSchema bool            `json:"dedicated_schema"` // Keep research data in the organization's own schema
+}
+
+// CreateStudyRequest adds a study to an organization
+type CreateStudyRequest struct {
+	Slug string `json:"slug" binding:"required,max=64"`
+	Name string `json:"name" binding:"required,max=200"`
+	// What happens to collected data on withdrawal: retain, choice (default) or delete
+	WithdrawalData string `json:"withdrawal_data" binding:"omitempty,oneof=retain choice delete"`
+}
+
+// AssignOrganizationRequest moves a user into an organization
+type AssignOrganizationRequest struct {
+	Email          string `json:"email" binding:"required,email"`
+	OrganizationID string `json:"organization_id" binding:"required,max=64"`
+	IsOrgAdmin     bool   `json:"is_org_admin"`
+	// Blinded reviewers get read-only dashboards with symptom values masked
+	IsBlindedReviewer bool `json:"is_blinded_reviewer"`
+}
+
+// ConfigureRandomizationRequest sets up a study's arm allocation scheme
+type ConfigureRandomizationRequest struct {
+	Method    string   `json:"method" binding:"required,oneof=simple block stratified"`
+	Arms      []string `json:"arms" binding:"required,min=2,max=10"`
+	BlockSize int      `json:"block_size" binding:"min=0,max=100"`
+	Seed      int64    `json:"seed"`       // Random when omitted
+	BlindArms *bool    `json:"blind_arms"` // Hide allocations from blinded reviewers; defaults to true
+}
+
+// RandomizeParticipantRequest allocates a participant to a study arm
+type RandomizeParticipantRequest struct {
+	Email string `json:"email" binding:"required,email"`
+	Age   *int   `json:"age" binding:"omitempty,min=0,max=130"`
+	Sex   string `json:"sex" binding:"omitempty,oneof=female male other"`
+}
+
+// ReportClinicalEventRequest logs a protocol deviation or adverse event
+type ReportClinicalEventRequest struct {
+	EventType   string `json:"event_type" binding:"required,oneof=protocol_deviation adverse_event"`
+	Email       string `json:"email" binding:"required,email"`
+	EventDate   string `json:"event_date" binding:"required"` // YYYY-MM-DD
+	Severity    string `json:"severity" binding:"required"`
+	Description string `json:"description" binding:"required,max=10000"`
+}
+
+// UpdateClinicalEventRequest records follow-up on a clinical event
+type UpdateClinicalEventRequest struct {
+	Severity       string `json:"severity"`
+	FollowUpStatus string `json:"follow_up_status" binding:"required,oneof=open in_progress resolved"`
+	FollowUpNotes  string `json:"follow_up_notes" binding:"max=10000"`
+}
+
+// SetLogLevelRequest temporarily changes the log level of a named logger, or
+// the default level when module is empty
+type SetLogLevelRequest struct {
+	Module  string `json:"module" binding:"max=100"`
+	Level   string `json:"level" binding:"required,oneof=debug info warn error"`
+	Minutes int    `json:"minutes" binding:"required,min=1,max=1440"`
+}
+
+// SetBodyLoggingRequest switches request/response body logging on for a while
+type SetBodyLoggingRequest struct {
+	Minutes int `json:"minutes" binding:"required,min=1"`
+}
+
+// SymptomThresholdRequest defines a threshold on a question's answers that
+// flags a participant once it is crossed for enough consecutive days
+type SymptomThresholdRequest struct {
+	Name            string   `json:"name" binding:"max=200"`
+	QuestionID      string   `json:"question_id" binding:"required,max=100"`
+	Operator        string   `json:"operator" binding:"required,oneof=gte gt lte lt"`
+	Value           *float64 `json:"value" binding:"required"`
+	ConsecutiveDays int      `json:"consecutive_days" binding:"required,min=1,max=90"`
+	Enabled         *bool    `json:"enabled"`         // Defaults to true
+	OrganizationID  string   `json:"organization_id"` // Global admins only; empty applies to every organization
+}
+
+// CustomMetricRequest defines a metric computed by a formula over the
+// interaction metrics recorded for a question. Keys can't be changed once
+// created.
+type CustomMetricRequest struct {
+	Key     string `json:"key" binding:"required,max=64"`
+	Label   string `json:"label" binding:"required,max=200"`
+	Group   string `json:"group" binding:"required,oneof=mouse keyboard timing"`
+	Formula string `json:"formula" binding:"required,max=500"`
+}
+
+// ChartViewRequest saves a chart configuration. A date range is either the
+// last range_days days or the dates between start_date and end_date.
+type ChartViewRequest struct {
+	Name                 string `json:"name" binding:"required,max=100"`
+	Symptom              string `json:"symptom" binding:"required,max=100"`
+	Metric               string `json:"metric" binding:"required,max=100"`
+	RangeDays            int    `json:"range_days" binding:"min=0,max=3650,excluded_with=StartDate EndDate"`
+	StartDate            string `json:"start_date" binding:"omitempty,datetime=2006-01-02"`
+	EndDate              string `json:"end_date" binding:"omitempty,datetime=2006-01-02"`
+	Bucket               string `json:"bucket" binding:"omitempty,oneof=day week month"`
+	IncludeRetrospective bool   `json:"include_retrospective"`
+	IsDefault            bool   `json:"is_default"`
+}
+
+// AttachmentExportsRequest includes an assessment's attachment in analysis
+// exports or leaves it out
+type AttachmentExportsRequest struct {
+	ExcludeFromExports *bool `json:"exclude_from_exports" binding:"required"`
+}
+
+// ClientErrorReport is one JavaScript error or unhandled promise rejection
+type ClientErrorReport struct {
+	Kind       string    `json:"kind" binding:"required,oneof=error unhandled_rejection"`
+	Message    string    `json:"message" binding:"required,max=2000"`
+	Stack      string    `json:"stack" binding:"max=10000"`
+	Source     string    `json:"source" binding:"max=1000"`
+	Line       int       `json:"line" binding:"min=0"`
+	Column     int       `json:"column" binding:"min=0"`
+	Route      string    `json:"route" binding:"max=1000"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+// ClientErrorBatchRequest carries the errors the PWA collected since it last
+// reported. The user agent defaults to the request's.
+type ClientErrorBatchRequest struct {
+	Release   string              `json:"release" binding:"max=64"`
+	UserAgent string              `json:"user_agent" binding:"max=500"`
+	Errors    []ClientErrorReport `json:"errors" binding:"required,min=1,max=25,dive"`
+}
+
+// PerfBeaconReport is one Web Vitals or timing measurement
+type PerfBeaconReport struct {
+	Metric     string    `json:"metric" binding:"required,oneof=lcp inp cls fcp ttfb long_task"`
+	Value      float64   `json:"value" binding:"min=0,max=600000"`
+	Context    string    `json:"context" binding:"max=40"`
+	Route      string    `json:"route" binding:"max=1000"`
+	RecordedAt time.Time `json:"recorded_at"`
+}
+
+// PerfBeaconBatchRequest carries the measurements of one page-load session.
+// The session ID is random and only identifies the session.
+type PerfBeaconBatchRequest struct {
+	SessionID string             `json:"session_id" binding:"required,min=16,max=64"`
+	Release   string             `json:"release" binding:"max=64"`
+	Beacons   []PerfBeaconReport `json:"beacons" binding:"required,min=1,max=100,dive"`
 }
@@ -1,35 +1,101 @@
 // internal/validation/validation.go
 package validation
 
+import (
+	"strings"
+)
+
+// Machine-readable validation error codes
+const (
+	CodeRequired      = "required"
+	CodeInvalidType   = "invalid_type"
+	CodeInvalidFormat = "invalid_format"
+	CodeInvalidOption = "invalid_option"
+	CodeMinLength     = "min_length"
+	CodeMaxLength     = "max_length"
+	CodeMinWords      = "min_words"
+	CodeMaxWords      = "max_words"
+	CodeMin           = "min"
+	CodeMax           = "max"
+	CodePattern       = "pattern"
+	CodeEmail         = "email"
+	CodeMalformed     = "malformed" // The body couldn't be parsed at all
+	CodeUnknownField  = "unknown_field"
+)
+
 // ValidationError represents a single validation error
 type ValidationError struct {
-	Field   string `json:"field"`
-	Message string `json:"message"`
-	Tag     string `json:"tag,omitempty"`
-	Value   string `json:"value,omitempty"`
+	Field     string `json:"field"`                // Top-level field, for focusing inputs
+	Pointer   string `json:"pointer"`              // JSON pointer to the offending value
+	Code      string `json:"code"`                 // One of the Code constants, or the validator tag
+	Message   string `json:"message"`              // Human-readable explanation
+	ValueType string `json:"value_type,omitempty"` // JSON type of the offending value
 }
 
-// ValidationResponse represents a complete validation response
+// ValidationResponse represents a complete validation response. Request and
+// form validation both fail with this envelope.
 type ValidationResponse struct {
 	Valid   bool              `json:"valid"`
+	Error   string            `json:"error,omitempty"` // Summary, read by the client like any other API error
 	Errors  []ValidationError `json:"errors,omitempty"`
 	Message string            `json:"message,omitempty"`
 	Field   string            `json:"field,omitempty"` // Field to focus on client-side
 }
 
-// NewValidationError creates a new validation error
-func NewValidationError(field, message string) ValidationError {
+// NewValidationError creates a new validation error for a top-level field
+func NewValidationError(field, code, message string) ValidationError {
 	return ValidationError{
 		Field:   field,
+		Pointer: JSONPointer(field),
+		Code:    code,
 		Message: message,
 	}
 }
 
 // NewValidationResponse creates a new validation response
 func NewValidationResponse(valid bool, message string, errors []ValidationError) ValidationResponse {
-	return ValidationResponse{
+	response := ValidationResponse{
 		Valid:   valid,
 		Message: message,
 		Errors:  errors,
 	}
+	if !valid {
+		response.Error = message
+		if len(errors) > 0 {
+			response.Field = errors[0].Field
+		}
+	}
+	return response
+}
+
+// JSONPointer builds an RFC 6901 pointer from path segments
+func JSONPointer(segments ...string) string {
+	var b strings.Builder
+	for _, segment := range segments {
+		b.WriteByte('/')
+		b.WriteString(pointerEscaper.Replace(segment))
+	}
+	return b.String()
+}
+
+var pointerEscaper = strings.NewReplacer("~", "~0", "/", "~1")
+
+// ValueType names the JSON type of a decoded value
+func ValueType(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case float64, float32, int, int64, int32, uint, uint64, uint32:
+		return "number"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	default:
+		return ""
+	}
 }